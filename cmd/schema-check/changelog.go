@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/guriandoro/pg_schema_check/pkg/changelog"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+	"github.com/jackc/pgx/v5"
+	"github.com/spf13/cobra"
+)
+
+// changelogDB is the connection string of the database whose change log is managed
+var changelogDB string
+
+// changelogCmd groups the subcommands managing the DDL change log used by --incremental and --watch
+var changelogCmd = &cobra.Command{
+	Use:   "changelog",
+	Short: "Manage the DDL change log used by --incremental and --watch",
+	Long: `Installs or removes the event triggers recording the DDL run on a database in the
+schema_check.ddl_log table. With the change log installed, --incremental fetches only the tables
+whose DDL changed since the schema was last fetched, --watch is notified of the DDL as it is
+committed, and the changes subcommand reports the DDL run over a time window. Event triggers can
+only be created by a superuser.`,
+}
+
+// changelogInstallCmd installs the change log
+var changelogInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the DDL change log in a database",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withChangelogConn(changelogDB, func(ctx context.Context, conn *pgx.Conn) error {
+			if err := changelog.Install(ctx, conn); err != nil {
+				return err
+			}
+			fmt.Println("Installed the change log in schema schema_check.")
+			return nil
+		})
+	},
+}
+
+// changelogUninstallCmd removes the change log
+var changelogUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the DDL change log and its entries from a database",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withChangelogConn(changelogDB, func(ctx context.Context, conn *pgx.Conn) error {
+			if err := changelog.Uninstall(ctx, conn); err != nil {
+				return err
+			}
+			fmt.Println("Removed the change log and schema schema_check.")
+			return nil
+		})
+	},
+}
+
+// withChangelogConn connects to a database and runs a function with the connection.
+//
+// Parameters:
+//   - connString: Connection string of the database
+//   - fn: Function to run with the connection
+//
+// Returns:
+//   - error: Any error that occurred while connecting, or returned by fn
+func withChangelogConn(connString string, fn func(ctx context.Context, conn *pgx.Conn) error) error {
+	ctx := context.Background()
+
+	var conn *pgx.Conn
+	err := retryPolicy().Do(ctx, func() error {
+		var err error
+		conn, err = schema.Connect(ctx, connString, sessionSettings())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	return fn(ctx, conn)
+}
+
+// init initializes the changelog subcommands and their flags
+func init() {
+	changelogCmd.PersistentFlags().StringVar(&changelogDB, "db", "", "Connection string of the database")
+	changelogCmd.MarkPersistentFlagRequired("db")
+	changelogCmd.AddCommand(changelogInstallCmd, changelogUninstallCmd)
+	rootCmd.AddCommand(changelogCmd)
+}
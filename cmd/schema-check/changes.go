@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/changelog"
+	"github.com/jackc/pgx/v5"
+	"github.com/spf13/cobra"
+)
+
+// Flags of the changes subcommand
+var (
+	changesDB         string // Connection string of the database whose changes are reported
+	changesSince      string // Start of the window, as a duration before now or a date
+	changesUntil      string // End of the window, as a duration before now or a date; empty for now
+	changesSchema     string // PostgreSQL schema whose changes are reported; empty for all
+	changesStatements bool   // Whether to print the statements run instead of the objects changed
+	changesJSON       bool   // Whether to print JSON instead of a table
+)
+
+// changesCmd reports the DDL run on a database over a time window
+var changesCmd = &cobra.Command{
+	Use:   "changes",
+	Short: "Report the DDL run on a database over a time window",
+	Long: `Reports what changed in a single database over a time window, from the DDL change log
+(see schema-check changelog install): every object created, altered, or dropped, when, and by
+which role. Where a comparison tells how two databases differ, this tells how one database came
+to be as it is.
+
+--since and --until take a duration before now (e.g., 7d or 12h), a date (2024-01-01), or a
+time (RFC 3339). --statements prints the statements run instead, in order, as a SQL script
+annotated with their time and role.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		now := time.Now()
+		since, err := parseTime("--since", changesSince, now)
+		if err != nil {
+			return err
+		}
+		until, err := parseTime("--until", changesUntil, now)
+		if err != nil {
+			return err
+		}
+		if !until.IsZero() && !until.After(since) {
+			return fmt.Errorf("--until must be later than --since")
+		}
+
+		return withChangelogConn(changesDB, func(ctx context.Context, conn *pgx.Conn) error {
+			changes, err := changelog.Between(ctx, conn, since, until, changesSchema)
+			if errors.Is(err, changelog.ErrNotInstalled) {
+				return fmt.Errorf("%w; install it with schema-check changelog install", err)
+			}
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case changesJSON:
+				if changes == nil {
+					changes = []changelog.Change{}
+				}
+				return printJSON(changes)
+			case len(changes) == 0:
+				fmt.Println("No changes logged in the window.")
+				return nil
+			case changesStatements:
+				return printStatements(os.Stdout, changes)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "LOGGED\tROLE\tCOMMAND\tTYPE\tOBJECT\tTABLE")
+			for _, change := range changes {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", change.LoggedAt.Local().Format(time.DateTime), orDash(change.Role),
+					change.CommandTag, orDash(change.ObjectType), orDash(change.ObjectIdentity), orDash(change.Table))
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			fmt.Printf("\n%d changes.\n", len(changes))
+			return nil
+		})
+	},
+}
+
+// printStatements writes the statements that made a list of changes as a SQL script, each
+// statement once, preceded by a comment with its time and role. A statement changing several
+// objects has an entry in the change log for each of them, all logged at once.
+//
+// Parameters:
+//   - w: Writer of the script
+//   - changes: Changes, in the order they were made
+//
+// Returns:
+//   - error: Any error that occurred while writing
+func printStatements(w io.Writer, changes []changelog.Change) error {
+	var b strings.Builder
+	for i, change := range changes {
+		if i > 0 {
+			previous := changes[i-1]
+			if previous.LoggedAt.Equal(change.LoggedAt) && previous.Role == change.Role && previous.Statement == change.Statement {
+				continue
+			}
+		}
+		fmt.Fprintf(&b, "-- %s by %s\n", change.LoggedAt.Local().Format(time.DateTime), orDash(change.Role))
+		statement := strings.TrimSpace(change.Statement)
+		if statement == "" {
+			// Entries logged by earlier releases of the change log have no statement
+			fmt.Fprintf(&b, "-- %s %s (statement not logged)\n\n", change.CommandTag, change.ObjectIdentity)
+			continue
+		}
+		if !strings.HasSuffix(statement, ";") {
+			statement += ";"
+		}
+		b.WriteString(statement + "\n\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// orDash returns a value, or "-" if it is empty, for the cells of tables.
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// init initializes the flags of the changes subcommand
+func init() {
+	changesCmd.Flags().StringVar(&changesDB, "db", "", "Connection string of the database")
+	changesCmd.Flags().StringVar(&changesSince, "since", "7d", "Report the changes made since this long ago (e.g., 7d or 12h) or this date (empty for all)")
+	changesCmd.Flags().StringVar(&changesUntil, "until", "", "Report the changes made before this long ago or this date (default now)")
+	changesCmd.Flags().StringVar(&changesSchema, "schema", "", "Report the changes of this PostgreSQL schema only (default all schemas)")
+	changesCmd.Flags().BoolVar(&changesStatements, "statements", false, "Print the statements run, as a SQL script, instead of the objects changed")
+	changesCmd.Flags().BoolVar(&changesJSON, "json", false, "Print JSON instead of a table")
+	changesCmd.MarkFlagRequired("db")
+	changesCmd.RegisterFlagCompletionFunc("schema", completeSchemas(flagDatabase(&changesDB)))
+	rootCmd.AddCommand(changesCmd)
+}
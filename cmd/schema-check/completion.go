@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/config"
+	"github.com/guriandoro/pg_schema_check/pkg/lint"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+	"github.com/guriandoro/pg_schema_check/pkg/snapshot"
+	"github.com/spf13/cobra"
+)
+
+// completionTimeout bounds the time a completion spends connecting to a database and reading
+// its catalog, so that a database that cannot be reached only delays the shell briefly.
+const completionTimeout = 3 * time.Second
+
+// completeEnvironments completes the names of the environments of the configuration file, for
+// --env.
+func completeEnvironments(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for name := range cfg.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return matching(names, toComplete, ""), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeDirections completes the directions of a comparison, for --direction.
+var completeDirections = cobra.FixedCompletions(
+	[]string{compare.DirectionSourceToTarget, compare.DirectionTargetToSource, compare.DirectionBoth},
+	cobra.ShellCompDirectiveNoFileComp)
+
+// completeLintRules completes the names of the registered lint rules, for lint --rules.
+func completeLintRules(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var names []string
+	for _, r := range lint.Rules() {
+		names = append(names, r.Name()+"\t"+r.Description())
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeTables returns a completion of the names of the tables of a database, read from its
+// catalog (or its snapshot file) as the flags typed so far give it. Nothing is completed when
+// no database can be worked out, or it cannot be read within completionTimeout.
+//
+// Parameters:
+//   - database: Returns the connection string or snapshot path of the database whose tables are completed, or empty
+//   - suffix: Appended to each name, in which case the shell adds no space after it (e.g., "=" for TABLE=VALUE flags)
+//
+// Returns:
+//   - func: Completion function for RegisterFlagCompletionFunc
+func completeTables(database func(cmd *cobra.Command) string, suffix string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		directive := cobra.ShellCompDirectiveNoFileComp
+		if suffix != "" {
+			directive |= cobra.ShellCompDirectiveNoSpace
+		}
+		connString := database(cmd)
+		if connString == "" {
+			return nil, directive
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+		defer cancel()
+		var names []string
+		if snapshot.IsSnapshotPath(connString) {
+			s, err := snapshot.Fetcher{Path: connString}.Fetch(ctx)
+			if err != nil {
+				return nil, directive
+			}
+			for name := range s.Tables {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+		} else {
+			conn, err := schema.Connect(ctx, connString, sessionSettings())
+			if err != nil {
+				return nil, directive
+			}
+			defer conn.Close(context.Background())
+			tables, err := schema.ListTables(ctx, conn, schema.FetchOptions{})
+			if err != nil {
+				return nil, directive
+			}
+			for _, table := range tables {
+				names = append(names, table.Name)
+			}
+		}
+		return matching(names, toComplete, suffix), directive
+	}
+}
+
+// completeSchemas returns a completion of the names of the PostgreSQL schemas of a database,
+// read from its catalog. Nothing is completed when the database is not given, or it cannot be
+// read within completionTimeout.
+//
+// Parameters:
+//   - database: Returns the connection string of the database whose schemas are completed, or empty
+//
+// Returns:
+//   - func: Completion function for RegisterFlagCompletionFunc
+func completeSchemas(database func(cmd *cobra.Command) string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		connString := database(cmd)
+		if connString == "" || snapshot.IsSnapshotPath(connString) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+		defer cancel()
+		conn, err := schema.Connect(ctx, connString, sessionSettings())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		defer conn.Close(context.Background())
+		names, err := schema.ListSchemas(ctx, conn)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return matching(names, toComplete, ""), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// comparedDatabase returns a function working out the connection string of a side of the
+// comparison from the flags typed so far: --source or --target, or else the side of the
+// environment of --env in the configuration file, with its secrets read.
+//
+// Parameters:
+//   - side: "source" or "target"
+//
+// Returns:
+//   - func: Function returning the connection string, or empty if it cannot be worked out
+func comparedDatabase(side string) func(cmd *cobra.Command) string {
+	return func(cmd *cobra.Command) string {
+		var profile config.Profile
+		if cmd.Flags().Changed("config") || envName != "" {
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				return ""
+			}
+			if profile, err = cfg.Resolve(envName); err != nil {
+				return ""
+			}
+		}
+		if cmd.Flags().Changed("source") {
+			profile.Source = sourceConnString
+		}
+		if cmd.Flags().Changed("target") {
+			profile.Target = targetConnString
+		}
+
+		// Only the secrets of the side needed are read
+		if side == "target" {
+			profile.Source, profile.Target = profile.Target, ""
+		} else {
+			profile.Target = ""
+		}
+		profile, err := profile.WithSecrets()
+		if err != nil {
+			return ""
+		}
+		return profile.Source
+	}
+}
+
+// changedDatabase returns the connection string of the side of the comparison that --direction
+// changes (see comparedDatabase), whose names --confirm-rename takes.
+func changedDatabase(cmd *cobra.Command) string {
+	if direction == compare.DirectionTargetToSource {
+		return comparedDatabase("source")(cmd)
+	}
+	return comparedDatabase("target")(cmd)
+}
+
+// flagDatabase returns a function returning the value of a flag giving a database, such as --db.
+//
+// Parameters:
+//   - value: Variable the flag is bound to
+//
+// Returns:
+//   - func: Function returning the connection string, or empty if the flag is not set
+func flagDatabase(value *string) func(cmd *cobra.Command) string {
+	return func(cmd *cobra.Command) string {
+		return *value
+	}
+}
+
+// matching returns the names starting with the text typed so far, each followed by a suffix.
+//
+// Parameters:
+//   - names: Candidate names
+//   - toComplete: Text typed so far
+//   - suffix: Appended to each name returned
+//
+// Returns:
+//   - []string: Completions
+func matching(names []string, toComplete, suffix string) []string {
+	var completions []string
+	for _, name := range names {
+		if strings.HasPrefix(name, toComplete) {
+			completions = append(completions, name+suffix)
+		}
+	}
+	return completions
+}
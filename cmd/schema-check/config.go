@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/guriandoro/pg_schema_check/pkg/config"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// configCmd groups the subcommands that work with the configuration file
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with the configuration file",
+}
+
+// configValidateCmd checks the configuration file and prints the effective settings of each environment
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the configuration file and print the resolved configuration",
+	Long: `Parses the configuration file, checks table pattern syntax, unknown keys, difference types
+and severities, suppression rules, environments missing a connection, and conflicting rules,
+then prints the effective configuration of every environment.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return err
+		}
+
+		// Report every problem found rather than stopping at the first one
+		problems := cfg.Validate()
+		if len(problems) > 0 {
+			fmt.Printf("Found %d problems in %s:\n\n", len(problems), configPath)
+			for _, problem := range problems {
+				fmt.Printf("  - %v\n", problem)
+			}
+			return fmt.Errorf("configuration file %s is invalid", configPath)
+		}
+
+		// Print the effective settings, with passwords masked
+		resolved := make(map[string]config.Profile)
+		defaults, _ := cfg.Resolve("")
+		resolved["(defaults)"] = defaults.Redacted()
+		for _, env := range cfg.EnvironmentNames() {
+			profile, _ := cfg.Resolve(env)
+			resolved[env] = profile.Redacted()
+		}
+
+		fmt.Printf("Configuration file %s is valid.\n\nResolved configuration:\n\n", configPath)
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		defer encoder.Close()
+		return encoder.Encode(resolved)
+	},
+}
+
+// init registers the config subcommands
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
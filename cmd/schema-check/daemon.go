@@ -0,0 +1,512 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/config"
+	"github.com/guriandoro/pg_schema_check/pkg/daemon"
+	"github.com/guriandoro/pg_schema_check/pkg/metrics"
+	"github.com/guriandoro/pg_schema_check/pkg/notify"
+	"github.com/guriandoro/pg_schema_check/pkg/results"
+	"github.com/guriandoro/pg_schema_check/pkg/server"
+	"github.com/guriandoro/pg_schema_check/pkg/suppress"
+	"github.com/spf13/cobra"
+)
+
+// Flags of the daemon subcommand
+var (
+	daemonOnce      bool          // Whether to run every check once and exit
+	daemonStateFile string        // Path of the state file, overriding the config file's
+	metricsListen   string        // Address the Prometheus metrics are served on; empty disables them
+	resultsDir      string        // Directory the latest result of each check or pair is kept in; empty keeps them in memory
+	daemonListen    string        // Address the dashboard and the results of the daemon are served on; empty disables them
+	reloadInterval  time.Duration // Time between two checks of the config file for changes; zero disables reloading
+)
+
+// defaultCheckName names the check of the top level of the config file, when it has no environments
+const defaultCheckName = "default"
+
+// daemonCmd runs the comparisons of the config file on a schedule
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run the comparisons of the config file on a schedule and notify of drift",
+	Long: `Runs the comparisons configured in the daemon section of the config file on their
+schedules, until stopped with Ctrl-C or SIGTERM. Each check compares an environment every
+interval (e.g., 15m) or on a cron schedule (e.g., "0 */6 * * *"), optionally delayed by a random
+jitter and kept out of blackout windows. Its outcome is kept in a state file, so that a restarted
+daemon resumes the schedules and does not notify again of drift it already reported. When a check finds differences after a clean run, a drift event is sent to
+the configured notifiers; when the differences are gone, a resolved event is sent.
+
+With --listen, the daemon also serves a web dashboard of the checks, showing their drift status,
+the trend of their differences, and the differences of their latest run by table, along with the
+/pairs endpoints of serve and its Prometheus metrics.
+
+The config file is checked for changes every --reload-interval and reloaded when it changes and
+is valid, restarting the checks with their schedules kept. Connection strings, notifier URLs, and
+the history database can reference secrets as ${file:PATH} (such as a mounted Kubernetes secret)
+or ${env:NAME}; they are read again on every run, so rotated credentials are picked up without a
+restart.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		cfg, err := loadValidConfig()
+		if err != nil {
+			return err
+		}
+		store, err := results.OpenStore(resultsDir)
+		if err != nil {
+			return err
+		}
+		m := metrics.New()
+		if metricsListen != "" {
+			if err := serveMetrics(ctx, metricsListen, m); err != nil {
+				return err
+			}
+		}
+		var dashboard *dashboardHandler
+		if daemonListen != "" {
+			if dashboard, err = serveDashboard(ctx); err != nil {
+				return err
+			}
+		}
+
+		for {
+			// A change of the config file stops the checks, which start again with the new
+			// configuration; the state file keeps their schedules
+			runCtx, cancel := context.WithCancel(ctx)
+			reloaded := make(chan *config.Config, 1)
+			if reloadInterval > 0 && !daemonOnce {
+				go watchConfig(runCtx, reloadInterval, reloaded, cancel)
+			}
+			err := runDaemon(runCtx, cfg, store, m, dashboard)
+			cancel()
+			if err != nil || ctx.Err() != nil {
+				return err
+			}
+			select {
+			case cfg = <-reloaded:
+				daemonLogf("Configuration file %s changed: reloading.", configPath)
+			default:
+				return nil
+			}
+		}
+	},
+}
+
+// runDaemon runs the checks of a configuration until the context is done, or once with --once.
+//
+// Parameters:
+//   - ctx: Context whose end stops the checks
+//   - cfg: Valid configuration defining the checks
+//   - store: Results recorded by the checks
+//   - m: Metrics of the checks
+//   - dashboard: Dashboard to show the checks on, or nil without --listen
+//
+// Returns:
+//   - error: Any error that occurred while setting up the checks or reading their state
+func runDaemon(ctx context.Context, cfg *config.Config, store *results.Store, m *metrics.Metrics, dashboard *dashboardHandler) error {
+	var settings config.Daemon
+	if cfg.Daemon != nil {
+		settings = *cfg.Daemon
+	}
+	checks, environments, err := daemonChecks(cfg)
+	if err != nil {
+		return err
+	}
+	notifiers, err := daemonNotifiers(settings.Notify)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := notify.CloseAll(notifiers); err != nil {
+			daemonLogf("Could not close the notifiers: %v", err)
+		}
+	}()
+	statePath := settings.StateFile
+	if daemonStateFile != "" {
+		statePath = daemonStateFile
+	}
+	if statePath == "" {
+		statePath = config.DefaultStateFile
+	}
+
+	db, err := openHistory(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if db != nil {
+		defer db.Close()
+	}
+	if dashboard != nil {
+		if err := dashboard.show(cfg, checks, environments, store, m); err != nil {
+			return err
+		}
+	}
+
+	d := &daemon.Daemon{
+		Checks:    checks,
+		Notifiers: notifiers,
+		StatePath: statePath,
+		Once:      daemonOnce,
+		Logf:      daemonLogf,
+		Compare: func(ctx context.Context, check string) (compare.DiffResult, error) {
+			started := time.Now()
+			differences, err := compareEnvironment(ctx, cfg, environments[check], func(side string, elapsed time.Duration) {
+				m.ObserveFetch(check, side, elapsed)
+			})
+			if ctx.Err() == nil {
+				m.ObserveRun(check, started, differences, err)
+				if saveErr := store.Save(results.NewResult(check, started, differences, err)); saveErr != nil {
+					fmt.Fprintf(os.Stderr, "Could not save the result of check %s: %v\n", check, saveErr)
+				}
+				if db != nil {
+					profile, _ := cfg.Resolve(environments[check])
+					recordHistory(db, check, profile, started, differences, err)
+				}
+			}
+			return differences, err
+		},
+	}
+	return d.Run(ctx)
+}
+
+// daemonLogf logs a message of the daemon on stderr, with the time.
+//
+// Parameters:
+//   - format: Format of the message, as for fmt.Printf
+//   - a: Arguments of the format
+func daemonLogf(format string, a ...any) {
+	fmt.Fprintf(os.Stderr, "%s %s\n", time.Now().Format(time.RFC3339), fmt.Sprintf(format, a...))
+}
+
+// watchConfig polls the config file every interval until the context is done. Once its contents
+// change and it is valid, the new configuration is sent on reloaded and cancel is called; an
+// invalid file is reported once and otherwise ignored, keeping the current configuration.
+// Polling rather than watching for events works with the symbolic links Kubernetes swaps when it
+// updates a mounted ConfigMap.
+//
+// Parameters:
+//   - ctx: Context whose end stops the polling
+//   - interval: Time between two reads of the file
+//   - reloaded: Channel the new configuration is sent on
+//   - cancel: Called once the new configuration is sent, to stop the checks
+func watchConfig(ctx context.Context, interval time.Duration, reloaded chan<- *config.Config, cancel context.CancelFunc) {
+	current := fileHash(configPath)
+	rejected := ""
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		hash := fileHash(configPath)
+		if hash == "" || hash == current || hash == rejected {
+			continue
+		}
+		cfg, err := config.Load(configPath)
+		if err == nil {
+			if problems := cfg.Validate(); len(problems) > 0 {
+				err = errors.Join(problems...)
+			}
+		}
+		if err != nil {
+			daemonLogf("Configuration file %s changed but is invalid, keeping the current configuration: %v", configPath, err)
+			rejected = hash
+			continue
+		}
+		reloaded <- cfg
+		cancel()
+		return
+	}
+}
+
+// fileHash returns the SHA-256 hash of the contents of a file.
+//
+// Parameters:
+//   - path: Path of the file
+//
+// Returns:
+//   - string: Hexadecimal hash, or empty if the file cannot be read
+func fileHash(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// loadValidConfig loads the configuration file of --config, printing its problems if it is
+// invalid.
+//
+// Returns:
+//   - *config.Config: Valid configuration
+//   - error: Any error that occurred while loading it, or an error if it is invalid
+func loadValidConfig() (*config.Config, error) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if problems := cfg.Validate(); len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "  - %v\n", problem)
+		}
+		return nil, fmt.Errorf("configuration file %s is invalid: run 'schema-check config validate' for details", configPath)
+	}
+	return cfg, nil
+}
+
+// dashboardHandler serves the dashboard of the checks of the current configuration, replaced
+// when the configuration is reloaded.
+type dashboardHandler struct {
+	handler atomic.Pointer[http.Handler] // Handler of the current checks; nil until they are shown
+}
+
+// show replaces the dashboard by one of the checks of a configuration, their results, and their
+// metrics. Checks are not run on request: they only run on their schedules.
+//
+// Parameters:
+//   - cfg: Configuration defining the environments of the checks
+//   - checks: Checks of the daemon
+//   - environments: Environment compared by each check, keyed by check name
+//   - store: Results recorded by the checks
+//   - m: Metrics of the checks
+//
+// Returns:
+//   - error: An error if the environment of a check is not defined
+func (d *dashboardHandler) show(cfg *config.Config, checks []daemon.Check, environments map[string]string, store *results.Store, m *metrics.Metrics) error {
+	opts := server.Options{Results: store, Metrics: m}
+	for _, check := range checks {
+		profile, err := cfg.Resolve(environments[check.Name])
+		if err != nil {
+			return err
+		}
+		redacted := profile.Redacted()
+		opts.Pairs = append(opts.Pairs, server.Pair{Name: check.Name, Source: redacted.Source, Target: redacted.Target})
+	}
+	var handler http.Handler = server.NewHandler(opts)
+	d.handler.Store(&handler)
+	return nil
+}
+
+// ServeHTTP serves a request with the dashboard of the current checks.
+func (d *dashboardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler := d.handler.Load()
+	if handler == nil {
+		http.Error(w, "starting", http.StatusServiceUnavailable)
+		return
+	}
+	(*handler).ServeHTTP(w, r)
+}
+
+// serveDashboard serves the dashboard of the checks over HTTP on --listen, in the background,
+// until the context is done.
+//
+// Parameters:
+//   - ctx: Context whose end stops serving
+//
+// Returns:
+//   - *dashboardHandler: Dashboard served, to show the checks of each configuration on
+//   - error: Any error that occurred while listening
+func serveDashboard(ctx context.Context) (*dashboardHandler, error) {
+	listener, err := net.Listen("tcp", daemonListen)
+	if err != nil {
+		return nil, fmt.Errorf("error listening: %w", err)
+	}
+	dashboard := &dashboardHandler{}
+	srv := &http.Server{Handler: dashboard, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	go func() {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "Stopped serving the dashboard: %v\n", err)
+		}
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving the dashboard on http://%s/.\n", listener.Addr())
+	return dashboard, nil
+}
+
+// daemonChecks converts the checks of the config file into those of the daemon.
+//
+// Parameters:
+//   - cfg: Configuration holding the checks
+//
+// Returns:
+//   - []daemon.Check: Checks to run
+//   - map[string]string: Environment compared by each check, keyed by check name
+//   - error: An error if a schedule or the start of a blackout window cannot be parsed
+func daemonChecks(cfg *config.Config) ([]daemon.Check, map[string]string, error) {
+	var checks []daemon.Check
+	environments := make(map[string]string)
+	for _, check := range cfg.DaemonChecks() {
+		name := check.Environment
+		if name == "" {
+			name = defaultCheckName
+		}
+
+		var schedule daemon.Schedule = daemon.Every(check.Interval)
+		if check.Schedule != "" {
+			cron, err := daemon.ParseCron(check.Schedule)
+			if err != nil {
+				return nil, nil, err
+			}
+			schedule = cron
+		}
+		blackout := make([]daemon.Window, 0, len(check.Blackout))
+		for _, window := range check.Blackout {
+			start, err := daemon.ParseCron(window.Start)
+			if err != nil {
+				return nil, nil, err
+			}
+			blackout = append(blackout, daemon.Window{Start: start, Duration: window.Duration})
+		}
+		checks = append(checks, daemon.Check{Name: name, Schedule: schedule, Jitter: check.Jitter, Blackout: blackout})
+		environments[name] = check.Environment
+	}
+	return checks, environments, nil
+}
+
+// daemonNotifiers creates the notifiers of the config file.
+//
+// Parameters:
+//   - settings: Notifiers of the config file, already validated
+//
+// Returns:
+//   - []notify.Notifier: Notifiers, each restricted to its events
+//   - error: Any error that occurred while reading the secrets of a notifier
+func daemonNotifiers(settings []config.Notifier) ([]notify.Notifier, error) {
+	var notifiers []notify.Notifier
+	for i, n := range settings {
+		var err error
+		if n.URL, err = config.ExpandSecrets(n.URL); err != nil {
+			return nil, fmt.Errorf("notifier #%d: url: %w", i+1, err)
+		}
+		if n.RoutingKey, err = config.ExpandSecrets(n.RoutingKey); err != nil {
+			return nil, fmt.Errorf("notifier #%d: routing_key: %w", i+1, err)
+		}
+		if n.APIKey, err = config.ExpandSecrets(n.APIKey); err != nil {
+			return nil, fmt.Errorf("notifier #%d: api_key: %w", i+1, err)
+		}
+		password := os.Getenv(n.PasswordEnv)
+		if n.PasswordFile != "" {
+			if password, err = config.ExpandSecrets("${file:" + n.PasswordFile + "}"); err != nil {
+				return nil, fmt.Errorf("notifier #%d: password_file: %w", i+1, err)
+			}
+		}
+
+		var notifier notify.Notifier
+		switch n.Type {
+		case notify.TypeWebhook:
+			notifier = notify.Webhook{URL: n.URL}
+		case notify.TypeSlack:
+			notifier = notify.Slack{URL: n.URL, ReportURL: n.ReportURL}
+		case notify.TypeTeams:
+			notifier = notify.Teams{URL: n.URL, ReportURL: n.ReportURL}
+		case notify.TypeEmail:
+			notifier = notify.Email{
+				Server:   n.SMTP,
+				Username: n.Username,
+				Password: password,
+				From:     n.From,
+				To:       n.To,
+				Format:   n.Format,
+			}
+		case notify.TypeCommand:
+			notifier = notify.Command{Command: n.Command}
+		case notify.TypePagerDuty:
+			notifier = &notify.PagerDuty{RoutingKey: n.RoutingKey, URL: n.URL, MinSeverity: n.MinSeverity, ReportURL: n.ReportURL}
+		case notify.TypeOpsgenie:
+			notifier = &notify.Opsgenie{APIKey: n.APIKey, URL: n.URL, MinSeverity: n.MinSeverity, ReportURL: n.ReportURL}
+		case notify.TypeKafka:
+			notifier = &notify.Kafka{Brokers: n.Brokers, Topic: n.Topic, Username: n.Username, Password: password, TLS: n.TLS}
+		case notify.TypeNATS:
+			notifier = &notify.NATS{URL: n.URL, Subject: n.Subject}
+		default:
+			continue
+		}
+		on := n.On
+		switch {
+		case len(on) > 0:
+		case n.Type == notify.TypePagerDuty || n.Type == notify.TypeOpsgenie:
+			// Incidents follow the severity of every run, not only the changes of status
+			on = []string{notify.EventDrift, notify.EventResolved, notify.EventReport}
+		case n.Type == notify.TypeKafka || n.Type == notify.TypeNATS:
+			// Event streams feed other systems, which pick the events they need
+			on = notify.Events
+		}
+		notifiers = append(notifiers, notify.Only(on, notifier))
+	}
+	return notifiers, nil
+}
+
+// compareEnvironment compares an environment of the config file, as the root command does with
+// --env, limited by --timeout.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - cfg: Configuration defining the environment
+//   - env: Name of the environment, or empty for the top level
+//   - onFetched: Called with the time taken to fetch each side, or nil
+//
+// Returns:
+//   - compare.DiffResult: Differences found, once the suppression rules are applied
+//   - error: Any error that occurred while fetching or comparing the schemas
+func compareEnvironment(ctx context.Context, cfg *config.Config, env string, onFetched func(side string, elapsed time.Duration)) (compare.DiffResult, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	profile, err := cfg.Resolve(env)
+	if err != nil {
+		return nil, err
+	}
+	// Secrets are read on every run, so that rotated credentials are picked up
+	if profile, err = profile.WithSecrets(); err != nil {
+		return nil, err
+	}
+	if profile.IgnoreMarker == nil {
+		profile.IgnoreMarker = &ignoreMarker
+	}
+	suppressor, err := suppress.New(profile.Suppress, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	differences, _, _, err := runComparison(ctx, profile, suppressor, false, onFetched)
+	return differences, err
+}
+
+// init initializes the flags of the daemon subcommand
+func init() {
+	daemonCmd.Flags().BoolVar(&daemonOnce, "once", false, "Run every check once, notifying as usual, and exit")
+	daemonCmd.Flags().StringVar(&daemonStateFile, "state-file", "", "File keeping the state of the checks, overriding the config file (default "+config.DefaultStateFile+")")
+	daemonCmd.Flags().StringVar(&metricsListen, "metrics-listen", "", "Serve Prometheus metrics of the checks on /metrics at this address (e.g., :9187)")
+	daemonCmd.Flags().StringVar(&daemonListen, "listen", "", "Serve a web dashboard of the checks, with their results and metrics, at this address (e.g., :8080)")
+	daemonCmd.Flags().StringVar(&historyDB, "history-db", "", "Record every run in the history kept in this PostgreSQL database (URL) or SQLite file (default history.database of the config file)")
+	daemonCmd.Flags().StringVar(&resultsDir, "results-dir", "", "Keep the latest result of each check in this directory, for serve --results-dir to return")
+	daemonCmd.Flags().DurationVar(&reloadInterval, "reload-interval", 30*time.Second, "Check the config file for changes this often and reload it when it changes (0 disables reloading)")
+	daemonCmd.Flags().DurationVar(&timeout, "timeout", 0, "Time limit of each run of a check, e.g. 5m (0 for no limit)")
+	rootCmd.AddCommand(daemonCmd)
+}
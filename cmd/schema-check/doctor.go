@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/config"
+	"github.com/guriandoro/pg_schema_check/pkg/doctor"
+	"github.com/guriandoro/pg_schema_check/pkg/snapshot"
+	"github.com/spf13/cobra"
+)
+
+// doctorFormat is the format of the report of the doctor subcommand
+var doctorFormat string
+
+// doctorCmd checks that a comparison can run, and what it will leave out
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that the source and target can be compared, and what the comparison will skip",
+	Long: `Connects to the source and target as a comparison would, with the same session settings,
+and checks each of them: that it can be reached, the engine and version of its server, the
+timeouts its sessions get (statement_timeout, lock_timeout), its search_path, that the compared
+schema exists and the role may use it, and that the catalog queries of a comparison succeed on it
+(the tables are listed, and the details of one of them read). Snapshot files are read instead.
+
+It then reports exactly which comparisons will be skipped or degraded, and why: features a server
+lacks or whose metadata the role cannot read, types and defaults shown differently because of the
+search_path, columns and keys hidden by information_schema, and tables whose details cannot be
+read. The command fails if a check fails, as the comparison would then fail too.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		if err := doctor.CheckFormat(doctorFormat); err != nil {
+			return err
+		}
+		profile, err := resolveProfile(cmd)
+		if err != nil {
+			return err
+		}
+		redacted := profile.Redacted()
+
+		// Both sides are checked at once, since they are independent
+		var source, target doctor.Side
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			source = diagnose(ctx, "source", profile.Source, redacted.Source, profile)
+		}()
+		go func() {
+			defer wg.Done()
+			target = diagnose(ctx, "target", profile.Target, redacted.Target, profile)
+		}()
+		wg.Wait()
+
+		report := doctor.NewReport(source, target)
+		if err := doctor.Write(os.Stdout, report, doctorFormat); err != nil {
+			return err
+		}
+		if report.Failed() {
+			return fmt.Errorf("%d checks failed, so the comparison cannot run", report.Count(doctor.StatusFailed))
+		}
+		return nil
+	},
+}
+
+// diagnose runs the checks of one side, reading snapshot files rather than connecting.
+//
+// Parameters:
+//   - ctx: Context bounding the checks
+//   - side: Name of the side ("source" or "target")
+//   - connString: Connection string or snapshot path of the side
+//   - location: Connection string with its password masked
+//   - profile: Effective settings of the run, whose table filters apply
+//
+// Returns:
+//   - doctor.Side: Outcome of the checks
+func diagnose(ctx context.Context, side, connString, location string, profile config.Profile) doctor.Side {
+	if snapshot.IsSnapshotPath(connString) {
+		return doctor.DiagnoseSnapshot(ctx, side, connString, snapshot.Fetcher{Path: connString})
+	}
+	return doctor.Diagnose(ctx, side, connString, location, doctor.Options{
+		Session:        sessionSettings(),
+		Fetch:          withTableFilters(fetchOptions(side), profile),
+		ConnectTimeout: connectTimeout,
+		Hint:           errorHint,
+	})
+}
+
+// init initializes the flags of the doctor subcommand
+func init() {
+	doctorCmd.Flags().StringVar(&sourceConnString, "source", "", "Source database connection string, or path of a snapshot file")
+	doctorCmd.Flags().StringVar(&targetConnString, "target", "", "Target database connection string, or path of a snapshot file")
+	doctorCmd.Flags().StringVar(&envName, "env", "", "Environment from the configuration file to check (e.g., prod)")
+	doctorCmd.Flags().StringVar(&doctorFormat, "format", doctor.FormatText, fmt.Sprintf("Format of the report (%s)", strings.Join(doctor.Formats, ", ")))
+	doctorCmd.Flags().DurationVar(&timeout, "timeout", 0, "Overall time limit for the checks, e.g. 1m (0 for no limit)")
+	doctorCmd.Flags().DurationVar(&connectTimeout, "connect-timeout", 30*time.Second, "Time limit for connecting to each database (0 for no limit)")
+	doctorCmd.RegisterFlagCompletionFunc("env", completeEnvironments)
+	doctorCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(doctor.Formats, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/spf13/cobra"
+)
+
+// explainCmd documents the types of difference
+var explainCmd = &cobra.Command{
+	Use:   "explain [CODE|TYPE]",
+	Short: "Explain a type of difference, by code (e.g., PSC104) or name",
+	Long: `Prints what a type of difference means, its typical causes, its default severity, and the
+statements usually needed to fix it, from the same documentation the comparison takes its default
+severities from. The type is given by its code (e.g., PSC104) or its name (e.g.,
+PartitionParentMismatch), in any case. Without an argument, lists every type with its code.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return listTypes(os.Stdout)
+		}
+		info, ok := compare.DescribeType(args[0])
+		if !ok {
+			if compare.IsKnownType(args[0]) {
+				return fmt.Errorf("%s is reported by a custom comparator, which does not document it", args[0])
+			}
+			return fmt.Errorf("unknown difference code or type '%s'; run 'schema-check explain' to list them", args[0])
+		}
+		printTypeInfo(os.Stdout, info)
+		return nil
+	},
+}
+
+// listTypes lists the documented types of difference with their codes and summaries.
+//
+// Parameters:
+//   - w: Writer the list is written to
+//
+// Returns:
+//   - error: Any error that occurred while writing the list
+func listTypes(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "CODE\tTYPE\tSEVERITY\tSUMMARY")
+	for _, info := range compare.TypeInfos() {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", info.Code, info.Type, info.DefaultSeverity, info.Summary)
+	}
+	return table.Flush()
+}
+
+// printTypeInfo prints the documentation of a type of difference.
+//
+// Parameters:
+//   - w: Writer the documentation is written to
+//   - info: Documentation of the type
+func printTypeInfo(w io.Writer, info compare.TypeInfo) {
+	generated := "no, the statements must be written by hand"
+	if info.Generated {
+		generated = "yes"
+	}
+	fmt.Fprintf(w, "%s %s\n\n", info.Code, info.Type)
+	fmt.Fprintf(w, "Object:            %s\n", info.ObjectKind)
+	fmt.Fprintf(w, "Default severity:  %s\n", info.DefaultSeverity)
+	fmt.Fprintf(w, "Fixed by --sql:    %s\n\n", generated)
+	fmt.Fprintf(w, "%s\n", info.Summary)
+	if len(info.Causes) > 0 {
+		fmt.Fprintf(w, "\nTypical causes:\n")
+		for _, cause := range info.Causes {
+			fmt.Fprintf(w, "  - %s\n", cause)
+		}
+	}
+	if len(info.Fix) > 0 {
+		fmt.Fprintf(w, "\nUsually fixed with:\n")
+		for _, fix := range info.Fix {
+			fmt.Fprintf(w, "  %s\n", fix)
+		}
+	}
+	fmt.Fprintf(w, "\nIts severity can be changed in the severity section of the configuration file (%s: warning).\n", info.Type)
+}
+
+// init registers the explain subcommand
+func init() {
+	rootCmd.AddCommand(explainCmd)
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/config"
+	"github.com/guriandoro/pg_schema_check/pkg/filter"
+	"github.com/guriandoro/pg_schema_check/pkg/fleet"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+	"github.com/guriandoro/pg_schema_check/pkg/suppress"
+	"github.com/spf13/cobra"
+)
+
+// Flags of the fleet subcommand
+var (
+	fleetGolden      string            // Connection string or snapshot file of the golden schema
+	fleetMembers     map[string]string // Connection strings of the members, keyed by name
+	fleetMembersFile string            // Path of a file listing the members, one NAME=CONNECTION per line
+	fleetFormat      string            // Format of the report of the fleet
+	fleetConcurrency int               // Number of members compared at once
+)
+
+// fleetCmd compares many databases with one golden schema
+var fleetCmd = &cobra.Command{
+	Use:   "fleet",
+	Short: "Compare a fleet of databases with one golden schema",
+	Long: `Compares every member of a fleet of databases, such as the shards or tenant databases of an
+application, with one golden schema, given as a database or a snapshot file, and reports which
+members deviate from it, sorted by number of differences, and which differences are found in how
+many members.
+
+Members are given with --member NAME=CONNECTION, and with --members-file, a file with one
+NAME=CONNECTION per line (blank lines and lines starting with # are ignored). Connection strings
+can reference secrets, as in the configuration file. The golden schema is read once, and
+--concurrency members are read and compared at once.
+
+The filters, severity overrides, and suppression rules of --env apply to every member, and the
+golden schema defaults to the source of that environment. The report is printed as text, markdown,
+json, or csv (a matrix with one row per member and one column per difference). The command fails
+if a member cannot be checked, or has more than --max-diffs differences.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		profile, err := fleetProfile(cmd)
+		if err != nil {
+			return err
+		}
+		members, err := fleetConnections()
+		if err != nil {
+			return err
+		}
+		if err := fleet.CheckFormat(fleetFormat); err != nil {
+			return err
+		}
+		suppressor, err := suppress.New(profile.Suppress, time.Now())
+		if err != nil {
+			return err
+		}
+
+		goldenFetcher, closeGolden, err := openFetcher(ctx, profile.Source, "golden", withTableFilters(fetchOptions("golden"), profile))
+		if err != nil {
+			return fmt.Errorf("error connecting to golden database: %w", err)
+		}
+		golden, err := goldenFetcher.Fetch(ctx)
+		closeGolden()
+		if err != nil {
+			return fmt.Errorf("error fetching golden schema: %w", err)
+		}
+
+		names := make([]string, 0, len(members))
+		for name := range members {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		report := fleet.Audit(ctx, profile.Redacted().Source, names, fleetConcurrency, func(ctx context.Context, member string) (compare.DiffResult, error) {
+			return compareMember(ctx, profile, suppressor, golden, member, members[member])
+		})
+
+		if err := fleet.Write(os.Stdout, report, fleetFormat); err != nil {
+			return err
+		}
+		if failed := report.Failed(); failed > 0 {
+			return fmt.Errorf("%d of %d members could not be checked", failed, len(report.Members))
+		}
+		if maxDiffs >= 0 {
+			for _, result := range report.Members {
+				if len(result.Differences) > maxDiffs {
+					return fmt.Errorf("member %s has %d differences, more than the allowed %d", result.Member, len(result.Differences), maxDiffs)
+				}
+			}
+		}
+		return nil
+	},
+}
+
+// fleetProfile builds the settings of the comparisons of the fleet: those of --env, if given,
+// with the golden schema as the source.
+//
+// Parameters:
+//   - cmd: The fleet command, used to find out which flags were set
+//
+// Returns:
+//   - config.Profile: Settings of the comparisons, with the golden schema as the source
+//   - error: Any error that occurred while loading the configuration or reading secrets, or if
+//     no golden schema is given
+func fleetProfile(cmd *cobra.Command) (config.Profile, error) {
+	var profile config.Profile
+	if cmd.Flags().Changed("config") || envName != "" {
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return profile, err
+		}
+		if profile, err = cfg.Resolve(envName); err != nil {
+			return profile, err
+		}
+	}
+	if cmd.Flags().Changed("golden") {
+		profile.Source = fleetGolden
+	}
+	profile.Target = ""
+	if cmd.Flags().Changed("direction") {
+		profile.Direction = direction
+	}
+	if !compare.IsValidDirection(profile.Direction) {
+		return profile, fmt.Errorf("unknown direction '%s': expected %s, %s, or %s", profile.Direction,
+			compare.DirectionSourceToTarget, compare.DirectionTargetToSource, compare.DirectionBoth)
+	}
+	if cmd.Flags().Changed("ignore-marker") || profile.IgnoreMarker == nil {
+		profile.IgnoreMarker = &ignoreMarker
+	}
+
+	profile, err := profile.WithSecrets()
+	if err != nil {
+		return profile, err
+	}
+	if profile.Source == "" {
+		return profile, fmt.Errorf("no golden schema given: use --golden, or --env to use the source of an environment")
+	}
+	return profile, nil
+}
+
+// fleetConnections gathers the members of the fleet from --member and --members-file, with the
+// secrets of their connection strings read.
+//
+// Returns:
+//   - map[string]string: Connection strings of the members, keyed by name
+//   - error: An error if the file cannot be read or is malformed, a member is given twice, a
+//     secret cannot be read, or no member is given
+func fleetConnections() (map[string]string, error) {
+	members := make(map[string]string, len(fleetMembers))
+	for name, connString := range fleetMembers {
+		members[name] = connString
+	}
+
+	if fleetMembersFile != "" {
+		file, err := os.Open(fleetMembersFile)
+		if err != nil {
+			return nil, fmt.Errorf("error opening members file: %w", err)
+		}
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			name, connString, found := strings.Cut(text, "=")
+			name, connString = strings.TrimSpace(name), strings.TrimSpace(connString)
+			if !found || name == "" || connString == "" {
+				return nil, fmt.Errorf("%s:%d: expected NAME=CONNECTION", fleetMembersFile, line)
+			}
+			if _, exists := members[name]; exists {
+				return nil, fmt.Errorf("%s:%d: member %s is given twice", fleetMembersFile, line, name)
+			}
+			members[name] = connString
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("error reading members file: %w", err)
+		}
+	}
+
+	if len(members) == 0 {
+		return nil, fmt.Errorf("no members given: use --member or --members-file")
+	}
+	for name, connString := range members {
+		expanded, err := config.ExpandSecrets(connString)
+		if err != nil {
+			return nil, fmt.Errorf("member %s: %w", name, err)
+		}
+		members[name] = expanded
+	}
+	return members, nil
+}
+
+// compareMember compares a member of the fleet with the golden schema, as the root command
+// compares a target with its source.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - profile: Settings of the comparison, with the golden schema as the source
+//   - suppressor: Compiled suppression rules of the profile
+//   - golden: Golden schema, left unchanged, as members are compared concurrently
+//   - member: Name of the member
+//   - connString: Connection string or snapshot file of the member
+//
+// Returns:
+//   - compare.DiffResult: Differences kept
+//   - error: Any error that occurred while fetching or comparing the schema of the member
+func compareMember(ctx context.Context, profile config.Profile, suppressor *suppress.Suppressor, golden *schema.Schema, member, connString string) (compare.DiffResult, error) {
+	fetcher, closeMember, err := openFetcher(ctx, connString, member, withTableFilters(fetchOptions(member), profile))
+	if err != nil {
+		return nil, fmt.Errorf("error connecting: %w", err)
+	}
+	defer closeMember()
+	target, err := fetcher.Fetch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching schema: %w", err)
+	}
+
+	source := golden.Clone()
+	if err := filterSchemas(profile, source, target); err != nil {
+		return nil, err
+	}
+	differences, err := compare.CompareSchemasContext(ctx, source, target, comparisonOptions(profile)...)
+	if err != nil {
+		return nil, err
+	}
+	kept, _, err := suppressor.Apply(differences)
+	return kept, err
+}
+
+// init initializes the flags of the fleet subcommand
+func init() {
+	fleetCmd.Flags().StringVar(&fleetGolden, "golden", "", "Connection string or snapshot file of the golden schema (default the source of --env)")
+	fleetCmd.Flags().StringToStringVar(&fleetMembers, "member", nil, "Member of the fleet, as NAME=CONNECTION (repeatable)")
+	fleetCmd.Flags().StringVar(&fleetMembersFile, "members-file", "", "File listing members of the fleet, one NAME=CONNECTION per line")
+	fleetCmd.Flags().StringVar(&envName, "env", "", "Environment from the configuration file whose settings are used (e.g., prod)")
+	fleetCmd.Flags().StringVar(&direction, "direction", compare.DirectionBoth, "Which side is authoritative: source-to-target (the golden schema), target-to-source (each member), or both")
+	fleetCmd.Flags().StringVar(&ignoreMarker, "ignore-marker", filter.DefaultIgnoreMarker, "Exclude tables and columns whose COMMENT contains this marker (empty to disable)")
+	fleetCmd.Flags().StringVar(&fleetFormat, "format", fleet.FormatText, fmt.Sprintf("Format of the report (%s)", strings.Join(fleet.Formats, ", ")))
+	fleetCmd.Flags().IntVar(&fleetConcurrency, "concurrency", 4, "Number of members compared at once")
+	fleetCmd.Flags().IntVar(&maxDiffs, "max-diffs", -1, "Fail when a member has more than this many differences (negative disables the check)")
+	fleetCmd.Flags().DurationVar(&timeout, "timeout", 0, "Overall time limit for the run, e.g. 5m (0 for no limit)")
+	fleetCmd.RegisterFlagCompletionFunc("env", completeEnvironments)
+	fleetCmd.RegisterFlagCompletionFunc("direction", completeDirections)
+	fleetCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(fleet.Formats, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.AddCommand(fleetCmd)
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/config"
+	"github.com/guriandoro/pg_schema_check/pkg/history"
+	"github.com/spf13/cobra"
+)
+
+// Flags of the history subcommands, and of the commands recording runs in the history
+var (
+	historyDB     string // PostgreSQL connection URL or SQLite file the history is kept in, overriding the config file's
+	historyPair   string // Comparison whose history is shown
+	historySince  string // Earliest start of the runs shown, as a duration before now or a date
+	historyLimit  int    // Maximum number of runs shown
+	historyTable  string // Table whose differences are shown
+	historyType   string // Type of the differences shown
+	historyAll    bool   // Whether differences no longer found are shown too
+	historyPeriod string // Period the runs are grouped by in trends: run, day, week, or a duration
+	historyJSON   bool   // Whether to print JSON instead of a table
+)
+
+// historyCmd groups the subcommands querying the history of differences
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Query the recorded history of differences",
+	Long: `Queries the history of differences kept in a PostgreSQL database or a SQLite file, which the
+comparisons record every run in when given --history-db or when the config file has a history
+section:
+
+  history:
+    database: /var/lib/schema-check/history.db
+
+Runs are recorded under the name of their environment (or "default"), by the root command, the
+daemon, and the pairs of serve. The subcommands show the runs of a comparison, when each of its
+differences first appeared, and how the number of differences trends over time.`,
+}
+
+// historyRunsCmd lists the runs of a comparison
+var historyRunsCmd = &cobra.Command{
+	Use:   "runs",
+	Short: "List the recorded runs of a comparison",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withHistory(func(ctx context.Context, db *history.DB) error {
+			since, err := parseSince(historySince, time.Now())
+			if err != nil {
+				return err
+			}
+			runs, err := db.Runs(ctx, historyPair, since, historyLimit)
+			if err != nil {
+				return err
+			}
+			if historyJSON {
+				return printJSON(runs)
+			}
+			if len(runs) == 0 {
+				fmt.Println("No runs recorded.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "STARTED\tPAIR\tDURATION\tERRORS\tWARNINGS\tINFO\tTOTAL\tHOST\tERROR")
+			for _, run := range runs {
+				// The differences of failed runs are unknown rather than zero
+				counts := fmt.Sprintf("%d\t%d\t%d\t%d", run.Summary[compare.SeverityError], run.Summary[compare.SeverityWarning],
+					run.Summary[compare.SeverityInfo], run.Differences)
+				if run.Failed() {
+					counts = "-\t-\t-\t-"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", run.Started.Local().Format(time.DateTime), run.Pair,
+					run.Duration.Round(time.Millisecond), counts, run.Host, run.Error)
+			}
+			return w.Flush()
+		})
+	},
+}
+
+// historyFirstSeenCmd shows when the differences of a comparison first appeared
+var historyFirstSeenCmd = &cobra.Command{
+	Use:   "first-seen",
+	Short: "Show when the differences of a comparison first appeared",
+	Long: `Shows the differences found by the latest successful run of a comparison, with when each was
+first found, when its current streak began (the difference may have come and gone before), and
+how many runs found it. With --all, differences that are no longer found are shown too.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withHistory(func(ctx context.Context, db *history.DB) error {
+			occurrences, err := db.Occurrences(ctx, historyPair, history.Match{Table: historyTable, Type: historyType})
+			if err != nil {
+				return err
+			}
+			if !historyAll {
+				present := occurrences[:0]
+				for _, o := range occurrences {
+					if o.Present {
+						present = append(present, o)
+					}
+				}
+				occurrences = present
+			}
+			if historyJSON {
+				return printJSON(occurrences)
+			}
+			if len(occurrences) == 0 {
+				fmt.Println("No differences recorded.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "FIRST SEEN\tSINCE\tLAST SEEN\tRUNS\tSEVERITY\tTYPE\tTABLE\tDESCRIPTION")
+			for _, o := range occurrences {
+				lastSeen := "(present)"
+				if !o.Present {
+					lastSeen = o.LastSeen.Local().Format(time.DateTime)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n", o.FirstSeen.Local().Format(time.DateTime),
+					o.Since.Local().Format(time.DateTime), lastSeen, o.Runs, o.Severity, o.Type, o.Table, o.Description)
+			}
+			return w.Flush()
+		})
+	},
+}
+
+// historyTrendCmd shows how the number of differences of a comparison trends over time
+var historyTrendCmd = &cobra.Command{
+	Use:   "trend",
+	Short: "Show how the number of differences of a comparison trends over time",
+	Long: `Groups the runs of a comparison by --period (day by default, or week, run, or a duration such
+as 6h) and shows, for each period, the differences found by its last successful run, the fewest
+and most found by its runs, and the change since the previous period.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withHistory(func(ctx context.Context, db *history.DB) error {
+			period, err := parsePeriod(historyPeriod)
+			if err != nil {
+				return err
+			}
+			since, err := parseSince(historySince, time.Now())
+			if err != nil {
+				return err
+			}
+			runs, err := db.Runs(ctx, historyPair, since, 0)
+			if err != nil {
+				return err
+			}
+			points := history.Trend(runs, period)
+			if historyJSON {
+				return printJSON(points)
+			}
+			if len(points) == 0 {
+				fmt.Println("No successful runs recorded.")
+				return nil
+			}
+
+			layout := time.DateOnly
+			if period <= 0 || period%(24*time.Hour) != 0 {
+				layout = time.DateTime
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "PERIOD\tRUNS\tFAILED\tERRORS\tWARNINGS\tINFO\tTOTAL\tMIN\tMAX\tCHANGE")
+			for i, point := range points {
+				change := ""
+				if i > 0 {
+					change = fmt.Sprintf("%+d", point.Differences-points[i-1].Differences)
+				}
+				start := point.Start.UTC().Format(layout)
+				if period <= 0 {
+					start = point.Start.Local().Format(layout)
+				}
+				fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%s\n", start, point.Runs, point.Failed,
+					point.Summary[compare.SeverityError], point.Summary[compare.SeverityWarning], point.Summary[compare.SeverityInfo],
+					point.Differences, point.Min, point.Max, change)
+			}
+			return w.Flush()
+		})
+	},
+}
+
+// withHistory opens the history of --db, or of the config file, and runs a function with it.
+//
+// Parameters:
+//   - fn: Function to run with the history
+//
+// Returns:
+//   - error: Any error that occurred while opening the history, or returned by fn
+func withHistory(fn func(ctx context.Context, db *history.DB) error) error {
+	ctx := context.Background()
+
+	target := historyDB
+	if target == "" {
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return fmt.Errorf("no history database given: use --db or set history.database in the config file (%w)", err)
+		}
+		target = historyDatabase(cfg)
+		if target == "" {
+			return fmt.Errorf("no history database given: use --db or set history.database in the config file")
+		}
+	}
+
+	target, err := config.ExpandSecrets(target)
+	if err != nil {
+		return err
+	}
+	db, err := history.Open(ctx, target)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return fn(ctx, db)
+}
+
+// historyDatabase returns the database runs are recorded in: the one of --history-db, or else
+// the one of the config file.
+//
+// Parameters:
+//   - cfg: Configuration, or nil if no config file is used
+//
+// Returns:
+//   - string: Connection URL or path of the history database; empty if runs are not recorded
+func historyDatabase(cfg *config.Config) string {
+	if historyDB != "" {
+		return historyDB
+	}
+	if cfg != nil && cfg.History != nil {
+		return cfg.History.Database
+	}
+	return ""
+}
+
+// openHistory opens the history runs are recorded in, if any.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - cfg: Configuration, or nil if no config file is used
+//
+// Returns:
+//   - *history.DB: Open history, or nil if runs are not recorded
+//   - error: Any error that occurred while opening it
+func openHistory(ctx context.Context, cfg *config.Config) (*history.DB, error) {
+	target, err := config.ExpandSecrets(historyDatabase(cfg))
+	if err != nil || target == "" {
+		return nil, err
+	}
+	return history.Open(ctx, target)
+}
+
+// recordHistory records a run of a comparison in the history, reporting on stderr if it could
+// not be recorded, so that the history never fails a comparison.
+//
+// Parameters:
+//   - db: History to record the run in
+//   - pair: Name of the comparison
+//   - profile: Settings of the comparison, whose connections are recorded with their passwords masked
+//   - started: When the run started
+//   - differences: Differences found
+//   - err: Error the run failed with, or nil
+func recordHistory(db *history.DB, pair string, profile config.Profile, started time.Time, differences compare.DiffResult, err error) {
+	redacted := profile.Redacted()
+	run := history.Run{
+		Pair:     pair,
+		Started:  started,
+		Duration: time.Since(started),
+		Source:   redacted.Source,
+		Target:   redacted.Target,
+	}
+	run.Host, _ = os.Hostname()
+	if err != nil {
+		run.Error = err.Error()
+		differences = nil
+	}
+
+	// The run is recorded even if its own context was cancelled by a timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, recordErr := db.Record(ctx, run, differences); recordErr != nil {
+		fmt.Fprintf(os.Stderr, "Could not record the run of %s in the history: %v\n", pair, recordErr)
+	}
+}
+
+// parseSince parses the earliest start of the runs shown: a duration before now (e.g., 12h or
+// 30d, where d stands for days), or a date (2006-01-02) or time (RFC 3339).
+//
+// Parameters:
+//   - value: Value to parse; empty returns the zero time, which shows every run
+//   - now: Current time
+//
+// Returns:
+//   - time.Time: Earliest start
+//   - error: An error if the value cannot be parsed
+func parseSince(value string, now time.Time) (time.Time, error) {
+	return parseTime("--since", value, now)
+}
+
+// parseTime parses the value of a flag giving a point in time: a duration before now (e.g., 12h
+// or 30d, where d stands for days), or a date (2006-01-02) or time (RFC 3339).
+//
+// Parameters:
+//   - flag: Name of the flag, for the error message
+//   - value: Value to parse; empty returns the zero time
+//   - now: Current time
+//
+// Returns:
+//   - time.Time: Point in time
+//   - error: An error if the value cannot be parsed
+func parseTime(flag, value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid %s '%s': expected a duration (e.g., 30d or 12h), a date (2006-01-02), or a time (RFC 3339)", flag, value)
+}
+
+// parsePeriod parses the period the runs are grouped by in trends.
+//
+// Parameters:
+//   - value: run, day, week, or a duration (e.g., 6h)
+//
+// Returns:
+//   - time.Duration: Length of the periods; zero makes each run its own period
+//   - error: An error if the value cannot be parsed
+func parsePeriod(value string) (time.Duration, error) {
+	switch value {
+	case "run":
+		return 0, nil
+	case "day":
+		return 24 * time.Hour, nil
+	case "week":
+		return 7 * 24 * time.Hour, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid --period '%s': expected run, day, week, or a duration (e.g., 6h)", value)
+}
+
+// printJSON prints a value as indented JSON on stdout.
+//
+// Parameters:
+//   - v: Value to print
+//
+// Returns:
+//   - error: Any error that occurred while encoding it
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// init initializes the history subcommands and their flags
+func init() {
+	historyCmd.PersistentFlags().StringVar(&historyDB, "db", "", "PostgreSQL connection URL or SQLite file of the history (default history.database of the config file)")
+	historyCmd.PersistentFlags().StringVar(&historyPair, "pair", defaultCheckName, "Comparison whose history is shown: its environment, or default")
+	historyCmd.PersistentFlags().BoolVar(&historyJSON, "json", false, "Print JSON instead of a table")
+	historyRunsCmd.Flags().StringVar(&historySince, "since", "", "Show the runs started since this long ago (e.g., 30d or 12h) or this date")
+	historyRunsCmd.Flags().IntVar(&historyLimit, "limit", 50, "Show at most this many runs, the latest ones (0 for all)")
+	historyFirstSeenCmd.Flags().StringVar(&historyTable, "table", "", "Show the differences of this table only")
+	historyFirstSeenCmd.Flags().StringVar(&historyType, "type", "", "Show the differences of this type only (e.g., ColumnTypeMismatch)")
+	historyFirstSeenCmd.Flags().BoolVar(&historyAll, "all", false, "Also show the differences no longer found")
+	historyTrendCmd.Flags().StringVar(&historySince, "since", "30d", "Show the runs started since this long ago (e.g., 30d or 12h) or this date (empty for all)")
+	historyTrendCmd.Flags().StringVar(&historyPeriod, "period", "day", "Group the runs by run, day, week, or a duration (e.g., 6h)")
+	historyCmd.AddCommand(historyRunsCmd, historyFirstSeenCmd, historyTrendCmd)
+	rootCmd.AddCommand(historyCmd)
+}
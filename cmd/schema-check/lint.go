@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/config"
+	"github.com/guriandoro/pg_schema_check/pkg/filter"
+	"github.com/guriandoro/pg_schema_check/pkg/lint"
+	"github.com/guriandoro/pg_schema_check/pkg/objstore"
+	"github.com/guriandoro/pg_schema_check/pkg/report"
+	"github.com/guriandoro/pg_schema_check/pkg/suppress"
+	"github.com/spf13/cobra"
+)
+
+// Flags of the lint subcommand
+var (
+	lintDB               string            // Connection string of the database to lint, or path of a snapshot file
+	lintSide             string            // Database of the environment to lint: source or target
+	lintRules            []string          // Names of the rules to run; empty runs all
+	lintSeverities       map[string]string // Severity of the issues of each rule
+	requireVarcharLength bool              // Whether character varying columns must declare a length
+)
+
+// lintCmd checks a single schema for design issues
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check the schema of a database for design issues",
+	Long: `Fetches the schema of a database (or reads a snapshot file) and checks it for design issues:
+tables without a primary key, foreign keys without a supporting index, duplicate indexes, and,
+with --require-varchar-length, character varying columns without a length. Issues are reported
+as warnings unless --severity says otherwise; the command fails if any issue has severity error.
+
+The database is given with --db, or taken from an environment of the configuration file with
+--env (its source, or its target with --side target). The table filters, ignore marker,
+severities, and suppression rules of the configuration file apply to the issues as they do to
+differences, and --severity overrides the severities of the file.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		renderer, err := report.Lookup(outputFormat)
+		if err != nil {
+			return err
+		}
+		if objstore.IsURI(reportOutput) {
+			if err := objstore.CheckOptions(reportOutput, objectOptions()); err != nil {
+				return err
+			}
+		}
+		profile, err := lintProfile(cmd)
+		if err != nil {
+			return err
+		}
+		suppressor, err := suppress.New(profile.Suppress, time.Now())
+		if err != nil {
+			return err
+		}
+		severities := make(map[string]string, len(profile.Severity)+len(lintSeverities))
+		for name, severity := range profile.Severity {
+			if lint.IsKnownRule(name) {
+				severities[name] = severity
+			}
+		}
+		for name, severity := range lintSeverities {
+			severities[name] = severity
+		}
+
+		fetcher, closeFetcher, err := openFetcher(ctx, lintDB, "", withTableFilters(fetchOptions("lint"), profile))
+		if err != nil {
+			return fmt.Errorf("error connecting to database: %w", err)
+		}
+		defer closeFetcher()
+
+		s, err := fetcher.Fetch(ctx)
+		if err != nil {
+			return fmt.Errorf("error fetching schema: %w", err)
+		}
+		if err := filter.Tables(profile.IncludeTables, profile.ExcludeTables, s); err != nil {
+			return err
+		}
+		filter.ExcludeTagged(*profile.IgnoreMarker, s)
+
+		issues, err := lint.Run(s, lint.Options{
+			Rules:                lintRules,
+			SeverityMap:          severities,
+			RequireVarcharLength: requireVarcharLength,
+		})
+		if err != nil {
+			return err
+		}
+		if issues, err = suppressDifferences(suppressor, issues); err != nil {
+			return err
+		}
+
+		if len(issues) == 0 && outputFormat == report.DefaultFormat && reportOutput == "" {
+			fmt.Println("No lint issues found.")
+			return nil
+		}
+		if err := writeReport(ctx, issues, renderer); err != nil {
+			return err
+		}
+		if issues.HasBlocking() {
+			return fmt.Errorf("found %d lint issues with severity error", issues.CountBySeverity()["error"])
+		}
+		return nil
+	},
+}
+
+// lintProfile builds the settings of the lint subcommand from the selected environment of the
+// configuration file, and sets lintDB to the database linted: that of --db, or else that of
+// --side in the environment.
+//
+// Parameters:
+//   - cmd: The lint command, used to find out which flags were set
+//
+// Returns:
+//   - config.Profile: Effective settings of the run
+//   - error: An error if the configuration cannot be loaded, a secret cannot be read, or no
+//     database is given
+func lintProfile(cmd *cobra.Command) (config.Profile, error) {
+	if lintSide != "source" && lintSide != "target" {
+		return config.Profile{}, fmt.Errorf("unknown side '%s': expected source or target", lintSide)
+	}
+
+	var profile config.Profile
+	if cmd.Flags().Changed("config") || envName != "" {
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return profile, err
+		}
+		if profile, err = cfg.Resolve(envName); err != nil {
+			return profile, err
+		}
+	}
+	if cmd.Flags().Changed("ignore-marker") || profile.IgnoreMarker == nil {
+		profile.IgnoreMarker = &ignoreMarker
+	}
+
+	// Only the secrets of the database linted are read
+	if cmd.Flags().Changed("db") {
+		profile.Source, profile.Target = lintDB, ""
+	} else if lintSide == "target" {
+		profile.Source, profile.Target = profile.Target, ""
+	} else {
+		profile.Target = ""
+	}
+	profile, err := profile.WithSecrets()
+	if err != nil {
+		return profile, err
+	}
+	if profile.Source == "" {
+		return profile, fmt.Errorf("no database given: use --db, or --env with an environment defining its %s", lintSide)
+	}
+	lintDB = profile.Source
+	return profile, nil
+}
+
+// init initializes the flags of the lint subcommand
+func init() {
+	lintCmd.Flags().StringVar(&lintDB, "db", "", "Connection string of the database to lint, or path of a snapshot file (default the database of --env)")
+	lintCmd.Flags().StringVar(&envName, "env", "", "Environment from the configuration file whose database, filters, severities, and suppression rules are used (e.g., prod)")
+	lintCmd.Flags().StringVar(&lintSide, "side", "source", "Database of the environment to lint: source or target")
+	lintCmd.Flags().StringVar(&ignoreMarker, "ignore-marker", filter.DefaultIgnoreMarker, "Exclude tables and columns whose COMMENT contains this marker (empty to disable)")
+	lintCmd.Flags().StringSliceVar(&lintRules, "rules", nil, "Rules to run, comma-separated (default all)")
+	lintCmd.Flags().StringToStringVar(&lintSeverities, "severity", nil, "Severity of the issues of each rule (e.g., NoPrimaryKey=error,DuplicateIndex=info)")
+	lintCmd.Flags().BoolVar(&requireVarcharLength, "require-varchar-length", false, "Report character varying columns declared without a length")
+	lintCmd.Flags().StringVar(&outputFormat, "format", report.DefaultFormat, fmt.Sprintf("Output format of the report (%s)", strings.Join(report.Formats(), ", ")))
+	lintCmd.Flags().StringVar(&reportOutput, "output", "", "Write the report to this file or object storage URI (s3://, gs://, or azblob://) instead of stdout")
+	lintCmd.Flags().StringVar(&sse, "sse", "", "Server-side encryption of the report written to S3: AES256 or aws:kms (default the bucket's)")
+	lintCmd.Flags().StringVar(&sseKMSKey, "sse-kms-key", "", "Key of the report written to object storage: AWS KMS key ID or ARN (with --sse aws:kms), Cloud KMS key name, or Azure encryption scope")
+	lintCmd.RegisterFlagCompletionFunc("env", completeEnvironments)
+	lintCmd.RegisterFlagCompletionFunc("side", cobra.FixedCompletions([]string{"source", "target"}, cobra.ShellCompDirectiveNoFileComp))
+	lintCmd.RegisterFlagCompletionFunc("rules", completeLintRules)
+	lintCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(report.Formats(), cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.AddCommand(lintCmd)
+}
@@ -5,12 +5,30 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
-	"github.com/agustin/postgres_schema_check/pkg/compare"
-	"github.com/agustin/postgres_schema_check/pkg/schema"
-	"github.com/jackc/pgx/v5"
+	"github.com/guriandoro/pg_schema_check/pkg/cache"
+	"github.com/guriandoro/pg_schema_check/pkg/changelog"
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/config"
+	"github.com/guriandoro/pg_schema_check/pkg/filter"
+	"github.com/guriandoro/pg_schema_check/pkg/objstore"
+	"github.com/guriandoro/pg_schema_check/pkg/patch"
+	"github.com/guriandoro/pg_schema_check/pkg/plan"
+	"github.com/guriandoro/pg_schema_check/pkg/report"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+	"github.com/guriandoro/pg_schema_check/pkg/snapshot"
+	"github.com/guriandoro/pg_schema_check/pkg/suppress"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/spf13/cobra"
 )
 
@@ -18,6 +36,72 @@ import (
 var (
 	sourceConnString string // Connection string for the source database
 	targetConnString string // Connection string for the target database
+	ignoreMarker     string // Comment marker that excludes tables and columns from the comparison
+	configPath       string // Path of the configuration file
+	envName          string // Name of the environment to load from the configuration file
+	collapseParts    bool   // Whether to compare partitioned parents only, skipping their partitions
+	compareOwners    bool   // Whether to report tables owned by different roles
+	extraSeverity    string // Severity of objects present only in the target
+	direction        string // Which side of the comparison is authoritative
+	outputFormat     string // Name of the format the differences are printed in
+	sqlPath          string // Path of the file the sync SQL script is written to; empty disables it
+	reportOutput     string // Path or object storage URI the report is written to; empty prints it
+	sse              string // Server-side encryption of the objects written to S3
+	sseKMSKey        string // KMS key, or Azure encryption scope, of the objects written to object storage
+
+	showProgress   bool          // Whether to report progress on stderr
+	timeout        time.Duration // Overall time limit of the run; zero means no limit
+	connectTimeout time.Duration // Time limit for connecting to each database; zero means no limit
+	fetchTimeout   time.Duration // Time limit for fetching both schemas; zero means no limit
+	compareTimeout time.Duration // Time limit for comparing the fetched schemas; zero means no limit
+	retries        int           // Number of times a connection or catalog query failing with a transient error is retried
+	retryBackoff   time.Duration // Wait before the first retry, doubled before each following one
+
+	statementTimeout time.Duration // statement_timeout of the sessions opened to each database; zero keeps the server's
+	lockTimeout      time.Duration // lock_timeout of the sessions opened to each database; zero keeps the server's
+	idleInTxTimeout  time.Duration // idle_in_transaction_session_timeout of the sessions; zero keeps the server's
+	pooler           string        // Connection pooler in front of the databases: auto, none, or transaction
+
+	fetchConcurrency int // Number of tables whose details are fetched at once
+	maxConnections   int // Maximum number of connections opened to each database; zero or negative means no limit
+	defaultPoolSize  int // Connections opened to each database; zero derives it from fetchConcurrency
+	sourcePoolSize   int // Connections opened to the source database; zero uses defaultPoolSize
+	targetPoolSize   int // Connections opened to the target database; zero uses defaultPoolSize
+	cursorSize       int // Rows read at a time through server-side cursors by the catalog queries; zero reads them in one go
+
+	maxQPS float64 // Maximum number of catalog queries per second sent to each database; zero means no limit
+	nice   bool    // Whether to go easy on busy servers: one connection, one table at a time, and niceQPS queries per second
+
+	cacheTTL    time.Duration // How long fetched schemas are reused from the cache; zero disables the cache
+	cacheDir    string        // Directory of the cache; empty uses cache.DefaultDir
+	noCache     bool          // Whether to fetch schemas even if the cache has them, refreshing it
+	incremental bool          // Whether to re-read only the tables whose DDL changed, using the change log
+
+	compareConcurrency int // Number of tables compared at once
+
+	lowMemory    bool // Whether to compare the schemas table by table instead of holding them in memory
+	batchSize    int  // Number of tables whose details are read at once with lowMemory
+	stageCatalog bool // Whether the catalog queries of table details are staged in temporary tables when reading in batches
+
+	rowCounts         string  // How the rows of the tables are counted and compared: estimate or exact; empty compares no counts
+	rowCountTolerance float64 // Fraction of the larger count by which the row counts of a table may differ unreported
+	rowCountMinDiff   int64   // Number of rows by which the row counts of a table may differ unreported
+
+	dataChecksums  string   // How the data of the tables is checksummed and compared: full or sample; empty compares no data
+	dataSampleRows int      // Number of rows hashed per table by sampled checksums
+	dataColumns    []string // Columns hashed for some tables, as TABLE=COLUMN,COLUMN
+
+	sizes bool // Whether the sizes of the tables and indexes, and the estimated bloat of the tables, are shown with their differences
+
+	detectRenames    bool     // Whether near-identical tables and columns missing on one side and extra on the other are reported as probable renames
+	confirmedRenames []string // Renames performed by the sync SQL, as OLD=NEW or TABLE.OLD=NEW
+	similarMatches   int      // Number of the most similar objects of the other side listed for each object missing or extra
+
+	watch         bool          // Whether to compare again whenever DDL on either database changes the schemas, printing what changed
+	watchDebounce time.Duration // Time the DDL must be quiet for before comparing again with watch
+
+	maxDiffs            int            // Number of differences above which the run fails; negative disables the check
+	maxDiffsPerSeverity map[string]int // Number of differences of each severity above which the run fails
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -26,68 +110,1131 @@ var rootCmd = &cobra.Command{
 	Short: "Compare PostgreSQL database schemas",
 	Long:  `A tool to compare the schema of two PostgreSQL databases and report differences.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Create a background context for database operations
-		ctx := context.Background()
+		// Create a context for database operations that is cancelled on Ctrl-C or SIGTERM,
+		// and once the overall timeout expires
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
 
-		// Connect to source database
-		sourceConn, err := pgx.Connect(ctx, sourceConnString)
+		// Work out the settings from the config file and the command-line flags
+		profile, err := resolveProfile(cmd)
 		if err != nil {
-			return fmt.Errorf("error connecting to source database: %w", err)
+			return err
 		}
-		defer sourceConn.Close(ctx)
 
-		// Connect to target database
-		targetConn, err := pgx.Connect(ctx, targetConnString)
+		// Select the output format before connecting, so that a misspelled format is reported early
+		renderer, err := report.Lookup(outputFormat)
 		if err != nil {
-			return fmt.Errorf("error connecting to target database: %w", err)
+			return err
+		}
+		for _, output := range []string{reportOutput, sqlPath} {
+			if objstore.IsURI(output) {
+				if err := objstore.CheckOptions(output, objectOptions()); err != nil {
+					return err
+				}
+			}
 		}
-		defer targetConn.Close(ctx)
 
-		// Fetch schema information from both databases
-		sourceSchema, err := schema.FetchSchema(ctx, sourceConn)
+		if lowMemory && (sqlPath != "" || cacheTTL > 0 || incremental) {
+			return fmt.Errorf("--low-memory cannot be combined with --sql, --cache-ttl, or --incremental, which need the whole schemas")
+		}
+		if rowCounts != "" {
+			if !slices.Contains(schema.RowCountModes, rowCounts) {
+				return fmt.Errorf("unknown --compare-rowcounts mode '%s': expected %s", rowCounts, strings.Join(schema.RowCountModes, " or "))
+			}
+			if lowMemory {
+				return fmt.Errorf("--compare-rowcounts cannot be combined with --low-memory, which reads no row counts")
+			}
+		}
+		if dataChecksums != "" {
+			if !slices.Contains(schema.DataChecksumModes, dataChecksums) {
+				return fmt.Errorf("unknown --compare-data mode '%s': expected %s", dataChecksums, strings.Join(schema.DataChecksumModes, " or "))
+			}
+			if lowMemory {
+				return fmt.Errorf("--compare-data cannot be combined with --low-memory, which reads no data")
+			}
+		}
+		if sizes && lowMemory {
+			return fmt.Errorf("--sizes cannot be combined with --low-memory, which reads no sizes")
+		}
+		if _, err := parseDataColumns(dataColumns); err != nil {
+			return err
+		}
+		if _, err := parseRenames(confirmedRenames); err != nil {
+			return err
+		}
+		if err := checkWatch(profile); err != nil {
+			return err
+		}
+
+		// Compile the suppression rules before connecting, so that mistakes are reported early
+		suppressor, err := suppress.New(profile.Suppress, time.Now())
 		if err != nil {
-			return fmt.Errorf("error fetching source schema: %w", err)
+			return err
+		}
+		for _, rule := range suppressor.Expired() {
+			owner := rule.Owner
+			if owner == "" {
+				owner = "none"
+			}
+			fmt.Fprintf(notices(), "Suppression rule '%s' (owner: %s) expired on %s; its differences are reported again.\n", rule.Expr, owner, rule.Expires)
 		}
 
-		targetSchema, err := schema.FetchSchema(ctx, targetConn)
+		// Open the history before connecting, so that a history that cannot be opened is reported early
+		var cfg *config.Config
+		if historyDB == "" && (cmd.Flags().Changed("config") || envName != "") {
+			if cfg, err = config.Load(configPath); err != nil {
+				return err
+			}
+		}
+		db, err := openHistory(ctx, cfg)
 		if err != nil {
-			return fmt.Errorf("error fetching target schema: %w", err)
+			return err
 		}
+		if db != nil {
+			defer db.Close()
+		}
+
+		// Diagrams draw the foreign keys of every table, as the SQL script needs every table
+		diagram, drawsSchemas := renderer.(report.SchemaRenderer)
 
-		// Compare the schemas and get a list of differences
-		differences := compare.CompareSchemas(sourceSchema, targetSchema)
+		started := time.Now()
+		differences, sourceSchema, targetSchema, err := runComparison(ctx, profile, suppressor, sqlPath != "" || drawsSchemas, nil)
+		if db != nil && !errors.Is(ctx.Err(), context.Canceled) {
+			pair := envName
+			if pair == "" {
+				pair = defaultCheckName
+			}
+			recordHistory(db, pair, profile, started, differences, err)
+		}
+		if err != nil {
+			return err
+		}
 
 		// Print the results
-		if len(differences) == 0 {
-			fmt.Println("No differences found between the schemas.")
-			return nil
+		if drawsSchemas {
+			renderer = diagram.WithSchemas(sourceSchema, targetSchema)
+		}
+		if err := writeReport(ctx, differences, renderer); err != nil {
+			return err
+		}
+		if sqlPath != "" {
+			p := patch.FromDifferences(differences, sourceSchema, targetSchema, profile.Direction)
+			if err := writeSQLFile(ctx, sqlPath, p); err != nil {
+				return err
+			}
+			fmt.Fprintf(notices(), "Wrote %d statements to %s.\n", len(p.Operations), sqlPath)
 		}
 
-		fmt.Printf("Found %d differences:\n\n", len(differences))
-		for _, diff := range differences {
-			fmt.Printf("[%s] %s: %s\n", diff.Type, diff.Table, diff.Description)
+		// With --watch, compare again on every change until stopped, failing on the last differences
+		if watch {
+			if differences, err = watchSchemas(ctx, profile, suppressor, differences); err != nil {
+				return err
+			}
 		}
 
-		return nil
+		return checkThresholds(differences)
 	},
 }
 
-// init initializes the command-line flags and marks them as required
+// runComparison fetches the source and target of a profile and compares them, dropping the
+// differences matched by its suppression rules.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - profile: Settings of the comparison
+//   - suppressor: Compiled suppression rules of the profile
+//   - wholeSchemas: Whether the details of every table are needed, as by the SQL script, rather
+//     than only those of the tables found on both sides
+//   - onFetched: Called with the time taken to fetch each side (source or target), or nil; not
+//     called with --low-memory, which fetches and compares at once
+//
+// Returns:
+//   - compare.DiffResult: Differences kept
+//   - *schema.Schema: Source schema, or nil with --low-memory
+//   - *schema.Schema: Target schema, or nil with --low-memory
+//   - error: Any error that occurred while fetching or comparing the schemas
+func runComparison(ctx context.Context, profile config.Profile, suppressor *suppress.Suppressor, wholeSchemas bool, onFetched func(side string, elapsed time.Duration)) (compare.DiffResult, *schema.Schema, *schema.Schema, error) {
+	// Open the source and target, which only list the tables kept by the filters
+	sourceFetcher, closeSource, err := openFetcher(ctx, profile.Source, "source", withTableFilters(fetchOptions("source"), profile))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error connecting to source database: %w", err)
+	}
+	defer closeSource()
+
+	targetFetcher, closeTarget, err := openFetcher(ctx, profile.Target, "target", withTableFilters(fetchOptions("target"), profile))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error connecting to target database: %w", err)
+	}
+	defer closeTarget()
+
+	// Compare the schemas and apply the configured severities to the differences
+	options := comparisonOptions(profile)
+	var sourceSchema, targetSchema *schema.Schema
+	var differences compare.DiffResult
+	if lowMemory {
+		// Tables are fetched and compared at once, so both time limits apply together, when both are set
+		var limit time.Duration
+		if fetchTimeout > 0 && compareTimeout > 0 {
+			limit = fetchTimeout + compareTimeout
+		}
+		streamCtx, cancelStream := phaseContext(ctx, limit)
+		differences, err = compareStreams(streamCtx, profile, sourceFetcher, targetFetcher, options)
+		err = phaseError(ctx, streamCtx, err, "fetching and comparing the schemas", "--fetch-timeout and --compare-timeout", limit)
+		cancelStream()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	} else {
+		// Tables found on one side only are reported from their names alone, so when both
+		// sides are databases, their tables are listed first and only the details of the
+		// tables that will be compared are fetched. The SQL script needs every table, to
+		// create missing ones. Listing the tables is part of fetching them
+		fetchCtx, cancelFetch := phaseContext(ctx, fetchTimeout)
+		if !wholeSchemas {
+			err = limitDetails(fetchCtx, profile, sourceFetcher, targetFetcher)
+		}
+
+		// Fetch schema information from both sides at once, since they are independent
+		if err == nil {
+			sourceSchema, targetSchema, err = fetchBoth(fetchCtx, sourceFetcher, targetFetcher, onFetched)
+		}
+		err = phaseError(ctx, fetchCtx, err, "fetching the schemas", "--fetch-timeout", fetchTimeout)
+		cancelFetch()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		// Drop tables left out by the filters and objects that teams have tagged as ignored
+		if err := filterSchemas(profile, sourceSchema, targetSchema); err != nil {
+			return nil, nil, nil, err
+		}
+
+		compareCtx, cancelCompare := phaseContext(ctx, compareTimeout)
+		differences, err = compare.CompareSchemasContext(compareCtx, sourceSchema, targetSchema, options...)
+		err = phaseError(ctx, compareCtx, err, "comparing the schemas", "--compare-timeout", compareTimeout)
+		cancelCompare()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	// Remove the differences matched by the suppression rules
+	differences, err = suppressDifferences(suppressor, differences)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return differences, sourceSchema, targetSchema, nil
+}
+
+// phaseContext returns a context for one phase of a comparison, bounded by its time limit in
+// addition to the deadline of ctx.
+//
+// Parameters:
+//   - ctx: Context of the whole run
+//   - limit: Time limit of the phase; zero or negative means no limit
+//
+// Returns:
+//   - context.Context: Context of the phase
+//   - context.CancelFunc: Function releasing the resources of the context once the phase is done
+func phaseContext(ctx context.Context, limit time.Duration) (context.Context, context.CancelFunc) {
+	if limit <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, limit)
+}
+
+// phaseError names the flag whose time limit stopped a phase of a comparison, when the phase
+// failed because its own deadline expired rather than that of the whole run or a cancellation.
+//
+// Parameters:
+//   - ctx: Context of the whole run
+//   - phaseCtx: Context of the phase, from phaseContext
+//   - err: Error the phase returned, or nil
+//   - phase: Description of the phase (e.g., "fetching the schemas")
+//   - flag: Flag setting the time limit of the phase
+//   - limit: Time limit of the phase
+//
+// Returns:
+//   - error: err, wrapped to name the time limit if it expired, or nil
+func phaseError(ctx, phaseCtx context.Context, err error, phase, flag string, limit time.Duration) error {
+	if err != nil && ctx.Err() == nil && errors.Is(phaseCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%s took longer than %s (%s): %w", phase, flag, limit, err)
+	}
+	return err
+}
+
+// comparisonOptions returns the options of the comparison of a profile: its per-table settings,
+// direction, and severity overrides, along with --compare-owners, --compare-concurrency, and the
+// row count thresholds.
+//
+// Parameters:
+//   - profile: Effective settings of the run
+//
+// Returns:
+//   - []compare.Option: Options of the comparison
+func comparisonOptions(profile config.Profile) []compare.Option {
+	// The renames were checked before connecting
+	renames, _ := parseRenames(confirmedRenames)
+	return []compare.Option{
+		compare.Options{
+			CompareOwners:         compareOwners,
+			Tables:                profile.TableOptions(),
+			Direction:             profile.Direction,
+			RowCountTolerance:     rowCountTolerance,
+			RowCountMinDifference: rowCountMinDiff,
+			DetectRenames:         detectRenames,
+			Renames:               renames,
+			SimilarMatches:        similarMatches,
+			OnPhaseStart: func(phase string) {
+				if showProgress {
+					fmt.Fprintf(os.Stderr, "[compare] %s\n", phase)
+				}
+			},
+		},
+		compare.WithSeverityMap(profile.SeverityOverrides()),
+		compare.WithConcurrency(compareConcurrency),
+	}
+}
+
+// suppressDifferences removes the differences matched by suppression rules, telling how many
+// were removed.
+//
+// Parameters:
+//   - suppressor: Compiled suppression rules of the profile
+//   - differences: Differences found by the comparison
+//
+// Returns:
+//   - compare.DiffResult: Differences kept
+//   - error: Any error that occurred while evaluating the rules
+func suppressDifferences(suppressor *suppress.Suppressor, differences compare.DiffResult) (compare.DiffResult, error) {
+	kept, suppressed, err := suppressor.Apply(differences)
+	if err != nil {
+		return nil, err
+	}
+	if suppressed > 0 {
+		fmt.Fprintf(notices(), "Suppressed %d differences matching suppression rules.\n", suppressed)
+	}
+	return kept, nil
+}
+
+// fetchBoth fetches the source and target schemas concurrently. If one of them fails, the
+// other is cancelled.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - source: Fetcher of the source schema
+//   - target: Fetcher of the target schema
+//   - onFetched: Called with the time taken to fetch each side that succeeded, or nil
+//
+// Returns:
+//   - *schema.Schema: Source schema
+//   - *schema.Schema: Target schema
+//   - error: The error of the side that failed first, naming that side
+func fetchBoth(ctx context.Context, source, target schema.Fetcher, onFetched func(side string, elapsed time.Duration)) (*schema.Schema, *schema.Schema, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	started := time.Now()
+	var targetSchema *schema.Schema
+	var targetErr error
+	var targetElapsed time.Duration
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		targetSchema, targetErr = target.Fetch(ctx)
+		targetElapsed = time.Since(started)
+		if targetErr != nil {
+			cancel()
+		}
+	}()
+
+	sourceSchema, sourceErr := source.Fetch(ctx)
+	sourceElapsed := time.Since(started)
+	if sourceErr != nil {
+		cancel()
+	}
+	<-done
+
+	if onFetched != nil {
+		if sourceErr == nil {
+			onFetched("source", sourceElapsed)
+		}
+		if targetErr == nil {
+			onFetched("target", targetElapsed)
+		}
+	}
+
+	// The side that failed is reported, rather than the cancellation it caused on the other side
+	if errors.Is(sourceErr, context.Canceled) && targetErr != nil && !errors.Is(targetErr, context.Canceled) {
+		sourceErr = nil
+	}
+	if sourceErr != nil {
+		return nil, nil, fmt.Errorf("error fetching source schema: %w", sourceErr)
+	}
+	if targetErr != nil {
+		return nil, nil, fmt.Errorf("error fetching target schema: %w", targetErr)
+	}
+	return sourceSchema, targetSchema, nil
+}
+
+// limitDetails lists the tables of both sides and sets up their fetchers to fetch the details of
+// the tables that will be compared only: those found on both sides that the filters, the ignore
+// marker, and --collapse-partitions keep. It does nothing unless both sides are read from a
+// database without the cache.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - profile: Effective settings of the run
+//   - source: Fetcher of the source schema
+//   - target: Fetcher of the target schema
+//
+// Returns:
+//   - error: Any error that occurred while listing the tables, naming the side
+func limitDetails(ctx context.Context, profile config.Profile, source, target schema.Fetcher) error {
+	sourceFetcher, sourceIsDB := source.(*schema.PgxFetcher)
+	targetFetcher, targetIsDB := target.(*schema.PgxFetcher)
+	if !sourceIsDB || !targetIsDB {
+		return nil
+	}
+
+	sourceTables, err := sourceFetcher.ListTables(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing source tables: %w", err)
+	}
+	targetTables, err := targetFetcher.ListTables(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing target tables: %w", err)
+	}
+
+	// byName looks tables up among those listed on the other side
+	byName := func(tables []schema.TableInfo) func(name string) (schema.TableInfo, bool) {
+		lookup := make(map[string]schema.TableInfo, len(tables))
+		for _, table := range tables {
+			lookup[table.Name] = table
+		}
+		return func(name string) (schema.TableInfo, bool) {
+			table, found := lookup[name]
+			return table, found
+		}
+	}
+	sourceFetcher.Options.Details = compared(profile, byName(targetTables))
+	targetFetcher.Options.Details = compared(profile, byName(sourceTables))
+	return nil
+}
+
+// compared returns a function reporting whether a table will be compared: whether it is found
+// on the other side, and is kept by the filters, the ignore marker, and --collapse-partitions.
+//
+// Parameters:
+//   - profile: Effective settings of the run
+//   - other: Function looking a table up by name on the other side
+//
+// Returns:
+//   - func(table schema.TableInfo) bool: Function reporting whether a table will be compared
+func compared(profile config.Profile, other func(name string) (schema.TableInfo, bool)) func(table schema.TableInfo) bool {
+	return func(table schema.TableInfo) bool {
+		counterpart, found := other(table.Name)
+		if !found || (collapseParts && table.PartitionOf != "") {
+			return false
+		}
+		if marker := *profile.IgnoreMarker; marker != "" &&
+			(strings.Contains(table.Comment, marker) || strings.Contains(counterpart.Comment, marker)) {
+			return false
+		}
+		// Malformed patterns are reported once the tables are filtered
+		keep, err := filter.Match(table.Name, profile.IncludeTables, profile.ExcludeTables)
+		return keep || err != nil
+	}
+}
+
+// filterSchemas drops the tables left out by the filters and --collapse-partitions, and the
+// objects tagged with the ignore marker, from both schemas.
+//
+// Parameters:
+//   - profile: Effective settings of the run
+//   - source: Source schema, modified in place
+//   - target: Target schema, modified in place
+//
+// Returns:
+//   - error: An error if a table pattern is malformed
+func filterSchemas(profile config.Profile, source, target *schema.Schema) error {
+	if err := filter.Tables(profile.IncludeTables, profile.ExcludeTables, source, target); err != nil {
+		return err
+	}
+	filter.ExcludeTagged(*profile.IgnoreMarker, source, target)
+	if collapseParts {
+		filter.CollapsePartitions(source, target)
+	}
+	return nil
+}
+
+// compareStreams compares the source and target table by table with --low-memory, so that only
+// the table-level properties of every table and the details of one batch of tables per side are
+// held in memory. Database sides are read --batch-size tables at a time, and only the details of
+// the tables that will be compared are read; snapshot files are read whole.
+//
+// Parameters:
+//   - ctx: Context for the database operations and the comparison
+//   - profile: Effective settings of the run
+//   - source: Fetcher of the source schema
+//   - target: Fetcher of the target schema
+//   - options: Options of the comparison
+//
+// Returns:
+//   - compare.DiffResult: Differences found, in table order
+//   - error: Any error that occurred while reading either side or comparing, naming the side
+func compareStreams(ctx context.Context, profile config.Profile, source, target schema.Fetcher, options []compare.Option) (compare.DiffResult, error) {
+	// The details of a table are only read once both sides have been listed, so each side can
+	// look its tables up in the outline of the other
+	var sourceStream, targetStream schema.TableStream
+	inOutline := func(stream *schema.TableStream) func(name string) (schema.TableInfo, bool) {
+		return func(name string) (schema.TableInfo, bool) {
+			table, found := (*stream).Outline().Tables[name]
+			return table, found
+		}
+	}
+	if f, ok := source.(*schema.PgxFetcher); ok {
+		f.Options.Details = compared(profile, inOutline(&targetStream))
+	}
+	if f, ok := target.(*schema.PgxFetcher); ok {
+		f.Options.Details = compared(profile, inOutline(&sourceStream))
+	}
+
+	var err error
+	sourceStream, err = openStream(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching source schema: %w", err)
+	}
+	defer closeStream(sourceStream)
+	targetStream, err = openStream(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching target schema: %w", err)
+	}
+	defer closeStream(targetStream)
+
+	// Tables are filtered as they are compared, since the schemas are never whole
+	options = append(options, compare.WithFilter(func(source, target *schema.Schema) error {
+		return filterSchemas(profile, source, target)
+	}))
+	stream, err := compare.CompareTableStreams(ctx, sourceStream, targetStream, options...)
+	if err != nil {
+		return nil, err
+	}
+	var differences compare.DiffResult
+	for diff := range stream.Differences {
+		differences = append(differences, diff)
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+	return differences, nil
+}
+
+// openStream lists the tables of one side of the comparison and returns a stream of them. Sides
+// that cannot be read a batch at a time, such as snapshot files, are read whole.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - fetcher: Fetcher of the side
+//
+// Returns:
+//   - schema.TableStream: Stream of the tables of the side
+//   - error: Any error that occurred while listing or fetching the tables
+func openStream(ctx context.Context, fetcher schema.Fetcher) (schema.TableStream, error) {
+	if streamer, ok := fetcher.(schema.TableStreamer); ok {
+		return streamer.StreamTables(ctx, batchSize)
+	}
+	s, err := fetcher.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return schema.NewSchemaStream(s), nil
+}
+
+// closeStream releases what a stream holds until it is read whole, such as the connection of
+// --stage-catalog, so that the pool of its side can be closed.
+//
+// Parameters:
+//   - stream: Stream to close
+func closeStream(stream schema.TableStream) {
+	if closer, ok := stream.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+// openFetcher creates the Fetcher used to read one side of the comparison. Paths of snapshot
+// files are read from disk; anything else is treated as a connection string, and connecting
+// is bounded by --connect-timeout in addition to the deadline of ctx. With --cache-ttl, a schema
+// cached recently enough is returned without connecting, and fetched schemas are cached. With
+// --incremental, the cached schema is brought up to date using the change log.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - connString: Connection string of the database, or path of a snapshot file
+//   - side: Name of the side being opened ("source", "target", or empty), used to pick its pool size
+//   - opts: Options controlling what is fetched from the database
+//
+// Returns:
+//   - schema.Fetcher: Fetcher for the database
+//   - func(): Function releasing the resources held by the fetcher
+//   - error: Any error that occurred while connecting
+func openFetcher(ctx context.Context, connString, side string, opts schema.FetchOptions) (schema.Fetcher, func(), error) {
+	if snapshot.IsSnapshotPath(connString) {
+		return snapshot.Fetcher{Path: connString}, func() {}, nil
+	}
+
+	// A schema fetched recently enough is reused without connecting at all
+	schemaCache, err := openCache()
+	if err != nil {
+		return nil, nil, err
+	}
+	key := cache.Key(connString, opts)
+	if !noCache {
+		if cached, age, err := schemaCache.Get(key); err == nil && cached != nil {
+			fmt.Fprintf(notices(), "Using the %s schema cached %s ago (--no-cache to fetch it again).\n", side, age.Round(time.Second))
+			return schema.StaticFetcher{Schema: cached}, func() {}, nil
+		}
+	}
+
+	connectCtx := ctx
+	if connectTimeout > 0 {
+		var cancel context.CancelFunc
+		connectCtx, cancel = context.WithTimeout(ctx, connectTimeout)
+		defer cancel()
+	}
+
+	// Tables fetched at once each need a connection of the pool
+	size := poolSize(side)
+	opts.Concurrency = min(opts.Concurrency, size)
+
+	var pool *pgxpool.Pool
+	err = retryPolicy().Do(connectCtx, func() error {
+		var err error
+		pool, err = schema.ConnectPool(connectCtx, connString, size, sessionSettings())
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Catalog queries are spaced out by --max-qps, shared by all the connections of the pool
+	conn := schema.RateLimit(pool, maxQPS)
+	switch {
+	case incremental:
+		return changelog.Fetcher{
+			Conn:    conn,
+			Options: opts,
+			Cache:   schemaCache,
+			Key:     key,
+			Refresh: noCache,
+			OnIncremental: func(changed, reused int) {
+				fmt.Fprintf(notices(), "Read %d changed tables of the %s schema again and reused %d.\n", changed, side, reused)
+			},
+			OnFull: func(reason string) {
+				fmt.Fprintf(notices(), "Read the whole %s schema: %s.\n", side, reason)
+			},
+		}, pool.Close, nil
+	case cacheTTL > 0:
+		fetcher := cache.Fetcher{Cache: schemaCache, Key: key, Fetcher: schema.NewPgxFetcher(conn, opts), Refresh: true}
+		return fetcher, pool.Close, nil
+	default:
+		return schema.NewPgxFetcher(conn, opts), pool.Close, nil
+	}
+}
+
+// openCache returns the cache of fetched schemas set up by --cache-ttl and --cache-dir. The cache
+// is disabled unless --cache-ttl or --incremental is set, which only the comparison accepts.
+//
+// Returns:
+//   - cache.Cache: Cache of fetched schemas
+//   - error: An error if the cache is enabled and its directory cannot be determined
+func openCache() (cache.Cache, error) {
+	if cacheTTL <= 0 && !incremental {
+		return cache.Cache{}, nil
+	}
+	dir := cacheDir
+	if dir == "" {
+		var err error
+		dir, err = cache.DefaultDir()
+		if err != nil {
+			return cache.Cache{}, err
+		}
+	}
+	return cache.Cache{Dir: dir, TTL: cacheTTL}, nil
+}
+
+// niceQPS is the number of catalog queries per second sent to each database with --nice.
+const niceQPS = 5
+
+// applyNice sets the defaults of --nice: one connection to each database, the details of one
+// table fetched at a time, and at most niceQPS catalog queries per second. Flags given
+// explicitly are kept.
+//
+// Parameters:
+//   - cmd: Command being run, whose flags tell which settings were given
+func applyNice(cmd *cobra.Command) {
+	if !nice {
+		return
+	}
+	if !cmd.Flags().Changed("fetch-concurrency") {
+		fetchConcurrency = 1
+	}
+	if !cmd.Flags().Changed("pool-size") {
+		defaultPoolSize = 1
+	}
+	if !cmd.Flags().Changed("max-qps") {
+		maxQPS = niceQPS
+	}
+}
+
+// poolSize returns the number of connections to open to the database of one side: its
+// --source-pool-size or --target-pool-size, or else --pool-size, or else enough connections for
+// --fetch-concurrency. The result is capped at --max-connections.
+//
+// Parameters:
+//   - side: Name of the side ("source", "target", or empty for the database of a subcommand)
+//
+// Returns:
+//   - int: Maximum number of connections of the side's pool
+func poolSize(side string) int {
+	size := max(fetchConcurrency, 1)
+	if defaultPoolSize > 0 {
+		size = defaultPoolSize
+	}
+	switch {
+	case side == "source" && sourcePoolSize > 0:
+		size = sourcePoolSize
+	case side == "target" && targetPoolSize > 0:
+		size = targetPoolSize
+	}
+
+	if maxConnections > 0 && size > maxConnections {
+		database := "the database"
+		if side != "" {
+			database = "the " + side + " database"
+		}
+		fmt.Fprintf(os.Stderr, "Limiting the connections to %s to %d (--max-connections).\n", database, maxConnections)
+		size = maxConnections
+	}
+	return size
+}
+
+// writeSQLFile writes the SQL script of a patch to a file or to object storage.
+//
+// Parameters:
+//   - ctx: Context for writing to object storage
+//   - path: Path of the file to create, or URI of the object to write
+//   - p: Patch to write
+//
+// Returns:
+//   - error: Any error that occurred while writing the file
+func writeSQLFile(ctx context.Context, path string, p patch.Patch) error {
+	file, err := createOutput(ctx, path)
+	if err != nil {
+		return fmt.Errorf("error creating SQL file: %w", err)
+	}
+	if err := p.WriteSQL(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// writeReport renders the differences to stdout, or to the file or object of --output.
+//
+// Parameters:
+//   - ctx: Context for writing to object storage
+//   - differences: Differences to render
+//   - renderer: Format of the report
+//
+// Returns:
+//   - error: Any error that occurred while rendering or writing the report
+func writeReport(ctx context.Context, differences compare.DiffResult, renderer compare.Renderer) error {
+	if reportOutput == "" {
+		return differences.Render(os.Stdout, renderer)
+	}
+	file, err := createOutput(ctx, reportOutput)
+	if err != nil {
+		return fmt.Errorf("error creating report file: %w", err)
+	}
+	if err := differences.Render(file, renderer); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote the report to %s.\n", reportOutput)
+	return nil
+}
+
+// createOutput creates a file, or an object in object storage written once it is closed, with
+// the encryption of --sse and --sse-kms-key.
+//
+// Parameters:
+//   - ctx: Context for writing to object storage
+//   - path: Path of the file, or URI of the object (s3://, gs://, or azblob://)
+//
+// Returns:
+//   - io.WriteCloser: File or object, whose Close reports whether it was written
+//   - error: An error if the file cannot be created, or the URI or encryption options are invalid
+func createOutput(ctx context.Context, path string) (io.WriteCloser, error) {
+	if objstore.IsURI(path) {
+		return objstore.NewWriter(ctx, path, objectOptions())
+	}
+	return os.Create(path)
+}
+
+// objectOptions returns the options of the objects written to object storage, as set by --sse
+// and --sse-kms-key.
+//
+// Returns:
+//   - objstore.Options: Encryption of the objects
+func objectOptions() objstore.Options {
+	return objstore.Options{SSE: sse, KMSKey: sseKMSKey}
+}
+
+// notices returns the writer informational messages are printed to. They go to stdout alongside
+// the text report, and to stderr with other formats so that the report can be parsed.
+//
+// Returns:
+//   - io.Writer: Writer for informational messages
+func notices() io.Writer {
+	if outputFormat == report.DefaultFormat {
+		return os.Stdout
+	}
+	return os.Stderr
+}
+
+// retryPolicy returns the policy for retrying connections and catalog queries that fail with
+// transient errors, as set by --retries and --retry-backoff. Retries are reported on stderr.
+//
+// Returns:
+//   - schema.RetryPolicy: Retry policy
+func retryPolicy() schema.RetryPolicy {
+	return schema.RetryPolicy{
+		MaxAttempts:    retries + 1,
+		InitialBackoff: retryBackoff,
+		MaxBackoff:     30 * time.Second,
+		OnRetry: func(attempt int, wait time.Duration, err error) {
+			fmt.Fprintf(os.Stderr, "Attempt %d failed (%v); retrying in %s.\n", attempt, err, wait)
+		},
+	}
+}
+
+// sessionSettings returns the timeouts set on the sessions reading the databases, as set by
+// --statement-timeout, --lock-timeout, and --idle-in-transaction-timeout. The session applying
+// a plan uses applySessionSettings instead.
+//
+// Returns:
+//   - schema.SessionSettings: Session timeouts
+func sessionSettings() schema.SessionSettings {
+	return schema.SessionSettings{
+		StatementTimeout:                statementTimeout,
+		LockTimeout:                     lockTimeout,
+		IdleInTransactionSessionTimeout: idleInTxTimeout,
+		Pooler:                          pooler,
+	}
+}
+
+// fetchOptions returns the options used to fetch one side of the comparison. With --progress,
+// they report the progress of the fetch on stderr.
+//
+// Parameters:
+//   - label: Name of the side being fetched (e.g., "source"), used in progress messages
+//
+// Returns:
+//   - schema.FetchOptions: Options for the fetch
+func fetchOptions(label string) schema.FetchOptions {
+	opts := schema.FetchOptions{
+		Retry: retryPolicy(), Concurrency: fetchConcurrency, CursorSize: cursorSize, Stage: stageCatalog,
+		RowCounts: rowCounts, DataChecksums: dataChecksums, DataSampleRows: dataSampleRows, Sizes: sizes,
+	}
+	// The columns were checked before connecting
+	opts.DataColumns, _ = parseDataColumns(dataColumns)
+	if !showProgress {
+		return opts
+	}
+
+	opts.OnPhaseStart = func(phase string) {
+		fmt.Fprintf(os.Stderr, "[%s] %s\n", label, phase)
+	}
+	opts.OnTableFetched = func(table schema.TableInfo, fetched, total int) {
+		fmt.Fprintf(os.Stderr, "[%s] fetched %d/%d: %s\n", label, fetched, total, table.Name)
+	}
+	return opts
+}
+
+// parseDataColumns parses the --data-columns settings, each naming a table and the columns of
+// it that are hashed (e.g., "orders=id,total").
+//
+// Parameters:
+//   - settings: Settings of --data-columns
+//
+// Returns:
+//   - map[string][]string: Columns hashed keyed by table name, or nil if there are no settings
+//   - error: An error if a setting is malformed
+func parseDataColumns(settings []string) (map[string][]string, error) {
+	if len(settings) == 0 {
+		return nil, nil
+	}
+	columns := make(map[string][]string, len(settings))
+	for _, setting := range settings {
+		table, list, ok := strings.Cut(setting, "=")
+		if !ok || table == "" || list == "" {
+			return nil, fmt.Errorf("invalid --data-columns setting '%s': expected TABLE=COLUMN,COLUMN", setting)
+		}
+		for _, column := range strings.Split(list, ",") {
+			if column = strings.TrimSpace(column); column != "" {
+				columns[table] = append(columns[table], column)
+			}
+		}
+	}
+	return columns, nil
+}
+
+// parseRenames parses the --confirm-rename settings, each mapping the old name of a table, or of
+// a column qualified by its table, to its new name (e.g., "orders.cust_id=customer_id").
+//
+// Parameters:
+//   - settings: Settings of --confirm-rename
+//
+// Returns:
+//   - map[string]string: New names keyed by old name, or nil if there are no settings
+//   - error: An error if a setting is malformed
+func parseRenames(settings []string) (map[string]string, error) {
+	if len(settings) == 0 {
+		return nil, nil
+	}
+	renames := make(map[string]string, len(settings))
+	for _, setting := range settings {
+		old, renamed, ok := strings.Cut(setting, "=")
+		if !ok || old == "" || renamed == "" || strings.HasSuffix(old, ".") {
+			return nil, fmt.Errorf("invalid --confirm-rename setting '%s': expected OLD=NEW or TABLE.OLD=NEW", setting)
+		}
+		renames[old] = renamed
+	}
+	return renames, nil
+}
+
+// withTableFilters sets the include and exclude patterns of the profile on fetch options, so that
+// the tables they leave out are never read.
+//
+// Parameters:
+//   - opts: Options used to fetch one side of the comparison
+//   - profile: Effective settings of the run
+//
+// Returns:
+//   - schema.FetchOptions: Options with the table filters set
+func withTableFilters(opts schema.FetchOptions, profile config.Profile) schema.FetchOptions {
+	opts.IncludeTables = profile.IncludeTables
+	opts.ExcludeTables = profile.ExcludeTables
+	return opts
+}
+
+// checkThresholds fails the run when the number of differences exceeds the limits given
+// with --max-diffs and --max-diffs-per-severity.
+//
+// Parameters:
+//   - differences: Differences found by the comparison
+//
+// Returns:
+//   - error: An error describing the first limit exceeded, or nil if all limits are respected
+func checkThresholds(differences compare.DiffResult) error {
+	if maxDiffs >= 0 && len(differences) > maxDiffs {
+		return fmt.Errorf("found %d differences, more than the allowed %d", len(differences), maxDiffs)
+	}
+
+	// Severities are checked from the most severe, so that the same limit is reported on every run
+	counts := differences.CountBySeverity()
+	for _, severity := range []string{compare.SeverityError, compare.SeverityWarning, compare.SeverityInfo, compare.SeverityIgnore} {
+		limit, ok := maxDiffsPerSeverity[severity]
+		if ok && counts[severity] > limit {
+			return fmt.Errorf("found %d differences with severity %s, more than the allowed %d", counts[severity], severity, limit)
+		}
+	}
+
+	return nil
+}
+
+// resolveProfile builds the effective settings for the run. Settings come from the selected
+// environment of the configuration file, and command-line flags that were explicitly set
+// take precedence over them.
+//
+// Parameters:
+//   - cmd: The command being executed, used to find out which flags were set
+//
+// Returns:
+//   - config.Profile: Effective settings for the run
+//   - error: Any error that occurred while loading the configuration or validating the settings
+func resolveProfile(cmd *cobra.Command) (config.Profile, error) {
+	var profile config.Profile
+
+	// The config file is only read when it was asked for, either directly or through an environment
+	if cmd.Flags().Changed("config") || envName != "" {
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return profile, err
+		}
+		profile, err = cfg.Resolve(envName)
+		if err != nil {
+			return profile, err
+		}
+	}
+
+	// Explicit command-line flags override the config file
+	if cmd.Flags().Changed("source") {
+		profile.Source = sourceConnString
+	}
+	if cmd.Flags().Changed("target") {
+		profile.Target = targetConnString
+	}
+	if cmd.Flags().Changed("direction") {
+		profile.Direction = direction
+	}
+	if !compare.IsValidDirection(profile.Direction) {
+		return profile, fmt.Errorf("unknown direction '%s': expected %s, %s, or %s", profile.Direction,
+			compare.DirectionSourceToTarget, compare.DirectionTargetToSource, compare.DirectionBoth)
+	}
+	for severity := range maxDiffsPerSeverity {
+		if !compare.IsValidSeverity(severity) {
+			return profile, fmt.Errorf("unknown severity '%s' in --max-diffs-per-severity", severity)
+		}
+	}
+	if cmd.Flags().Changed("extra-severity") {
+		profile.ExtraSeverity = extraSeverity
+	}
+	if cmd.Flags().Changed("ignore-marker") || profile.IgnoreMarker == nil {
+		profile.IgnoreMarker = &ignoreMarker
+	}
+
+	// References to secrets are read last, so that they can come from the flags as well
+	profile, err := profile.WithSecrets()
+	if err != nil {
+		return profile, err
+	}
+
+	if profile.Source == "" {
+		return profile, fmt.Errorf("no source database given: use --source or define it in the config file")
+	}
+	if profile.Target == "" {
+		return profile, fmt.Errorf("no target database given: use --target or define it in the config file")
+	}
+
+	return profile, nil
+}
+
+// init initializes the command-line flags
 func init() {
 	// Define command-line flags
 	rootCmd.Flags().StringVar(&sourceConnString, "source", "", "Source database connection string")
 	rootCmd.Flags().StringVar(&targetConnString, "target", "", "Target database connection string")
-	
-	// Mark flags as required
-	rootCmd.MarkFlagRequired("source")
-	rootCmd.MarkFlagRequired("target")
+	rootCmd.Flags().StringVar(&ignoreMarker, "ignore-marker", filter.DefaultIgnoreMarker, "Exclude tables and columns whose COMMENT contains this marker (empty to disable)")
+	rootCmd.Flags().BoolVar(&collapseParts, "collapse-partitions", false, "Compare only partitioned parents and their partition strategy, skipping individual partitions")
+	rootCmd.Flags().BoolVar(&compareOwners, "compare-owners", false, "Report tables, views, sequences, and functions owned by different roles")
+	rootCmd.Flags().StringVar(&extraSeverity, "extra-severity", "", "Severity of objects present only in the target: error, warning, info, or ignore")
+	rootCmd.Flags().StringVar(&direction, "direction", compare.DirectionBoth, "Which side is authoritative: source-to-target, target-to-source, or both")
+	rootCmd.Flags().StringVar(&outputFormat, "format", report.DefaultFormat, fmt.Sprintf("Output format of the report (%s)", strings.Join(report.Formats(), ", ")))
+	rootCmd.Flags().StringVar(&historyDB, "history-db", "", "Record the run in the history kept in this PostgreSQL database (URL) or SQLite file, under the name of --env (default history.database of the config file)")
+	rootCmd.Flags().StringVar(&sqlPath, "sql", "", "Write a SQL script reconciling the differences to this file or object storage URI (the target is changed, or the source with --direction target-to-source)")
+	rootCmd.Flags().StringVar(&reportOutput, "output", "", "Write the report to this file or object storage URI (s3://BUCKET/KEY, gs://BUCKET/OBJECT, or azblob://CONTAINER/BLOB) instead of stdout")
+	rootCmd.Flags().StringVar(&sse, "sse", "", "Server-side encryption of the objects written to S3: AES256 or aws:kms (default the bucket's)")
+	rootCmd.Flags().StringVar(&sseKMSKey, "sse-kms-key", "", "Key of the objects written to object storage: AWS KMS key ID or ARN (with --sse aws:kms), Cloud KMS key name, or Azure encryption scope")
+	rootCmd.PersistentFlags().BoolVar(&showProgress, "progress", false, "Report the progress of fetching and comparing on stderr")
+	rootCmd.Flags().DurationVar(&timeout, "timeout", 0, "Overall time limit for the run, e.g. 5m (0 for no limit)")
+	rootCmd.Flags().DurationVar(&connectTimeout, "connect-timeout", 30*time.Second, "Time limit for connecting to each database (0 for no limit)")
+	rootCmd.Flags().DurationVar(&fetchTimeout, "fetch-timeout", 0, "Time limit for fetching both schemas, once connected (0 for no limit)")
+	rootCmd.Flags().DurationVar(&compareTimeout, "compare-timeout", 0, "Time limit for comparing the fetched schemas (0 for no limit)")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", 2, "Number of times to retry connections and catalog queries that fail with transient errors")
+	rootCmd.PersistentFlags().DurationVar(&statementTimeout, "statement-timeout", schema.DefaultSessionSettings.StatementTimeout, "Time limit of each query reading the databases, such as catalog queries and row counts, set as statement_timeout (0 keeps the server's setting; apply uses --apply-timeout instead)")
+	rootCmd.PersistentFlags().DurationVar(&lockTimeout, "lock-timeout", schema.DefaultSessionSettings.LockTimeout, "Time limit of waiting for locks held by concurrent DDL, set as lock_timeout (0 keeps the server's setting)")
+	rootCmd.PersistentFlags().DurationVar(&idleInTxTimeout, "idle-in-transaction-timeout", schema.DefaultSessionSettings.IdleInTransactionSessionTimeout, "Time after which the server ends sessions left idle in a transaction, set as idle_in_transaction_session_timeout (0 keeps the server's setting)")
+	rootCmd.PersistentFlags().StringVar(&pooler, "pooler", schema.DefaultSessionSettings.Pooler, "Connection pooler in front of the databases: auto (detect PgBouncer), none, or transaction (a pooler sharing server sessions between transactions)")
+	rootCmd.PersistentFlags().IntVar(&fetchConcurrency, "fetch-concurrency", 1, "Number of tables whose details are fetched at once, each over its own connection")
+	rootCmd.PersistentFlags().IntVar(&defaultPoolSize, "pool-size", 0, "Connections opened to each database (default enough for --fetch-concurrency)")
+	rootCmd.Flags().IntVar(&sourcePoolSize, "source-pool-size", 0, "Connections opened to the source database (default --pool-size)")
+	rootCmd.Flags().IntVar(&targetPoolSize, "target-pool-size", 0, "Connections opened to the target database (default --pool-size)")
+	rootCmd.PersistentFlags().IntVar(&cursorSize, "cursor-size", 0, "Rows read at a time through server-side cursors by the catalog queries reading columns, keys, and indexes (0 reads each query in one go)")
+	rootCmd.PersistentFlags().BoolVar(&stageCatalog, "stage-catalog", false, "When reading tables in batches (--low-memory, snapshot --checkpoint), run the catalog queries of table details once into temporary tables and read each batch from them")
+	rootCmd.PersistentFlags().Float64Var(&maxQPS, "max-qps", 0, "Maximum number of catalog queries per second sent to each database (0 for no limit)")
+	rootCmd.PersistentFlags().BoolVar(&nice, "nice", false, fmt.Sprintf("Go easy on busy servers: one connection to each database and at most %d catalog queries per second, unless set otherwise", niceQPS))
+	rootCmd.PersistentFlags().IntVar(&maxConnections, "max-connections", 10, "Never open more than this many connections to a database (0 for no limit)")
+	rootCmd.Flags().DurationVar(&cacheTTL, "cache-ttl", 0, "Reuse schemas fetched from the same database within this long, e.g. 10m (0 disables the cache)")
+	rootCmd.Flags().StringVar(&cacheDir, "cache-dir", "", "Directory of the schema cache (default schema-check under the user cache directory)")
+	rootCmd.Flags().BoolVar(&noCache, "no-cache", false, "Fetch both schemas in full even if they are cached, refreshing the cache")
+	rootCmd.Flags().BoolVar(&incremental, "incremental", false, "Re-read only the tables whose DDL changed since the cached schemas, using the change log (see changelog install)")
+	rootCmd.Flags().IntVar(&compareConcurrency, "compare-concurrency", runtime.GOMAXPROCS(0), "Number of tables compared at once (the differences are reported in the same order whatever the setting)")
+	rootCmd.Flags().StringVar(&rowCounts, "compare-rowcounts", "", "Also compare the number of rows of each table, estimated from pg_class.reltuples (estimate) or counted with count(*) (exact), and report large discrepancies")
+	rootCmd.Flags().Float64Var(&rowCountTolerance, "rowcount-tolerance", 0.1, "With --compare-rowcounts, report tables whose row counts differ by more than this fraction of the larger count")
+	rootCmd.Flags().Int64Var(&rowCountMinDiff, "rowcount-min-diff", 1000, "With --compare-rowcounts, report tables whose row counts differ by more than this many rows")
+	rootCmd.Flags().BoolVar(&detectRenames, "detect-renames", false, "Report a table or column missing on one side with a near-identical one extra on the other as a probable rename")
+	rootCmd.Flags().StringArrayVar(&confirmedRenames, "confirm-rename", nil, "Confirm the rename of a table (OLD=NEW) or column (TABLE.OLD=NEW), which --sql then performs; OLD is the name in the schema being changed (repeatable)")
+	rootCmd.Flags().IntVar(&similarMatches, "show-similar", 0, "List this many of the most similar objects of the other side with each table, column, index, or foreign key missing or extra (0 lists none)")
+	rootCmd.Flags().StringVar(&dataChecksums, "compare-data", "", "Also compare checksums of the data of each table, of every row (full) or of the first --data-sample-rows rows by primary key (sample), and report the tables whose data differs")
+	rootCmd.Flags().IntVar(&dataSampleRows, "data-sample-rows", schema.DefaultDataSampleRows, "With --compare-data sample, number of rows of each table hashed, in primary key order")
+	rootCmd.Flags().StringArrayVar(&dataColumns, "data-columns", nil, "With --compare-data, hash only these columns of a table, as TABLE=COLUMN,COLUMN (repeatable; default every column)")
+	rootCmd.Flags().BoolVar(&watch, "watch", false, "After the comparison, compare again whenever DDL is committed on either database and print the differences that appeared or were resolved, until Ctrl-C (installs the change log; see changelog install)")
+	rootCmd.Flags().DurationVar(&watchDebounce, "watch-debounce", 2*time.Second, "With --watch, wait for the DDL to be quiet this long before comparing again")
+	rootCmd.Flags().BoolVar(&sizes, "sizes", false, "Also read the size of the tables and indexes (pg_total_relation_size) and estimate the bloat of the tables, and show them with the differences about them, to judge the cost of the migrations")
+	rootCmd.Flags().BoolVar(&lowMemory, "low-memory", false, "Compare the schemas table by table, reading the details of --batch-size tables at a time instead of holding both schemas in memory")
+	rootCmd.Flags().IntVar(&batchSize, "batch-size", schema.DefaultBatchSize, "Number of tables whose details are read at once with --low-memory")
+	rootCmd.PersistentFlags().DurationVar(&retryBackoff, "retry-backoff", 500*time.Millisecond, "Wait before the first retry, doubled before each following one")
+	rootCmd.Flags().IntVar(&maxDiffs, "max-diffs", -1, "Fail when more than this many differences are found (negative disables the check)")
+	rootCmd.Flags().StringToIntVar(&maxDiffsPerSeverity, "max-diffs-per-severity", nil, "Fail when more differences of a severity are found than allowed (e.g., error=0,warning=10)")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", config.DefaultPath, "Path of the configuration file")
+	rootCmd.Flags().StringVar(&envName, "env", "", "Environment from the configuration file to compare (e.g., prod)")
+
+	// Complete the values naming environments and tables from the config file and the catalog
+	rootCmd.RegisterFlagCompletionFunc("env", completeEnvironments)
+	rootCmd.RegisterFlagCompletionFunc("direction", completeDirections)
+	rootCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(report.Formats(), cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.RegisterFlagCompletionFunc("data-columns", completeTables(comparedDatabase("source"), "="))
+	rootCmd.RegisterFlagCompletionFunc("confirm-rename", completeTables(changedDatabase, ""))
 }
 
 // main is the entry point of the application
 func main() {
-	// Execute the root command and handle any errors
-	if err := rootCmd.Execute(); err != nil {
+	// Execute the root command and handle any errors, writing out the profiles either way
+	err := rootCmd.Execute()
+	stopProfiling()
+	if err != nil {
 		fmt.Println(err)
+		if hint := errorHint(err); hint != "" {
+			fmt.Println(hint)
+		}
 		os.Exit(1)
 	}
-} 
\ No newline at end of file
+}
+
+// errorHint suggests how to resolve an error, based on its class.
+//
+// Parameters:
+//   - err: Error the run failed with
+//
+// Returns:
+//   - string: Suggestion for the user, or empty if there is none for this kind of error
+func errorHint(err error) string {
+	switch {
+	case errors.Is(err, plan.ErrLocked):
+		return "Hint: another plan is being applied to this database; wait for it to finish, then make a new plan, as that one may have changed the schema."
+	case errors.Is(err, plan.ErrStale):
+		return "Hint: the schema was changed after the plan was made, so its statements may no longer be right; run 'schema-check plan' again, review the new plan, and apply it instead."
+	case errors.Is(err, schema.ErrConnectionPooler):
+		return "Hint: the database is reached through a connection pooler such as PgBouncer. If it pools transactions and was not detected, use --pooler transaction; if it has too many clients or times out waiting for a server session (max_client_conn, query_wait_timeout), lower --pool-size and --fetch-concurrency, or use --nice."
+	case errors.Is(err, schema.ErrConnectionFailed):
+		return "Hint: check that the host and port are reachable, that the server accepts connections, and that the credentials are correct; use --connect-timeout on slow networks."
+	case errors.Is(err, schema.ErrInsufficientPrivileges):
+		return "Hint: the role needs CONNECT on the database and USAGE on the compared schema; run the tool as a role with these privileges (e.g., the schema owner)."
+	case errors.Is(err, schema.ErrUnsupportedServerVersion):
+		return "Hint: the server lacks a catalog feature the tool relies on; PostgreSQL 10 or later is required."
+	case errors.Is(err, schema.ErrQueryTimeout):
+		return "Hint: a catalog query ran longer than --statement-timeout or waited longer than --lock-timeout behind concurrent DDL; retry once the DDL is done, or raise the timeouts."
+	}
+	return ""
+}
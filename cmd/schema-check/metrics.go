@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/metrics"
+)
+
+// serveMetrics serves the Prometheus metrics on /metrics at an address, in the background,
+// until the context is done.
+//
+// Parameters:
+//   - ctx: Context whose end stops serving
+//   - listen: Address to listen on
+//   - m: Metrics to serve
+//
+// Returns:
+//   - error: Any error that occurred while listening
+func serveMetrics(ctx context.Context, listen string, m *metrics.Metrics) error {
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("error listening for metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	go func() {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "Stopped serving metrics: %v\n", err)
+		}
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving metrics on %s/metrics.\n", listener.Addr())
+	return nil
+}
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/audit"
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/config"
+	"github.com/guriandoro/pg_schema_check/pkg/filter"
+	"github.com/guriandoro/pg_schema_check/pkg/patch"
+	"github.com/guriandoro/pg_schema_check/pkg/plan"
+	"github.com/guriandoro/pg_schema_check/pkg/report"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+	"github.com/guriandoro/pg_schema_check/pkg/snapshot"
+	"github.com/guriandoro/pg_schema_check/pkg/suppress"
+	"github.com/jackc/pgx/v5"
+	"github.com/spf13/cobra"
+)
+
+// Flags of the plan and apply subcommands
+var (
+	planOut       string        // Path of the plan file to write
+	applyAuditLog string        // Audit log the application of the plan is recorded in; empty disables it
+	applyLockKey  int64         // Key of the advisory lock held while applying the plan
+	applyTimeout  time.Duration // statement_timeout of the session applying the plan; 0 keeps the server's setting
+)
+
+// planCmd compares two databases and saves the changes reconciling them to a plan file
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Save the changes reconciling two schemas to a plan file",
+	Long: `Compares the source and target, as the root command does, prints the differences and the
+statements reconciling them, and saves both to a plan file along with the checksum of the schema
+they change: the target, or the source with --direction target-to-source.
+
+The plan is applied with 'schema-check apply', which runs exactly the statements shown here, and
+refuses to run them if the schema changed since the plan was made.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		profile, err := resolveProfile(cmd)
+		if err != nil {
+			return err
+		}
+		renderer, err := report.Lookup(outputFormat)
+		if err != nil {
+			return err
+		}
+		suppressor, err := suppress.New(profile.Suppress, time.Now())
+		if err != nil {
+			return err
+		}
+
+		// Plans are applied to the side they change, which must therefore be a database
+		changedSide, changed := plan.SideTarget, profile.Target
+		if profile.Direction == compare.DirectionTargetToSource {
+			changedSide, changed = plan.SideSource, profile.Source
+		}
+		if snapshot.IsSnapshotPath(changed) {
+			return fmt.Errorf("the %s is a snapshot file: a plan can only change a database", changedSide)
+		}
+
+		differences, saved, err := makePlan(ctx, profile, suppressor)
+		if err != nil {
+			return err
+		}
+
+		if err := differences.Render(os.Stdout, renderer); err != nil {
+			return err
+		}
+		printPlan(notices(), saved)
+		if err := plan.WriteFile(planOut, saved); err != nil {
+			return err
+		}
+		fmt.Fprintf(notices(), "Saved the plan to %s. Apply it with: schema-check apply %s\n", planOut, planOut)
+		return nil
+	},
+}
+
+// makePlan compares the source and target of a profile, as runComparison does with whole
+// schemas, and makes the plan reconciling them. The checksum of the side changed is taken as it
+// is read, before the ignore marker and --collapse-partitions filter it, so that apply can check
+// it by reading that side alone.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - profile: Settings of the comparison
+//   - suppressor: Compiled suppression rules of the profile
+//
+// Returns:
+//   - compare.DiffResult: Differences kept
+//   - *plan.Plan: Plan reconciling the differences
+//   - error: Any error that occurred while fetching or comparing the schemas
+func makePlan(ctx context.Context, profile config.Profile, suppressor *suppress.Suppressor) (compare.DiffResult, *plan.Plan, error) {
+	sourceFetcher, closeSource, err := openFetcher(ctx, profile.Source, "source", withTableFilters(fetchOptions("source"), profile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error connecting to source database: %w", err)
+	}
+	defer closeSource()
+
+	targetFetcher, closeTarget, err := openFetcher(ctx, profile.Target, "target", withTableFilters(fetchOptions("target"), profile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error connecting to target database: %w", err)
+	}
+	defer closeTarget()
+
+	sourceSchema, targetSchema, err := fetchBoth(ctx, sourceFetcher, targetFetcher, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if failed := len(sourceSchema.Errors) + len(targetSchema.Errors); failed > 0 {
+		return nil, nil, fmt.Errorf("error fetching schemas: %d tables could not be read, so the plan would be incomplete", failed)
+	}
+	changed := targetSchema
+	if profile.Direction == compare.DirectionTargetToSource {
+		changed = sourceSchema
+	}
+	beforeHash := changed.Checksum()
+
+	if err := filterSchemas(profile, sourceSchema, targetSchema); err != nil {
+		return nil, nil, err
+	}
+	differences, err := compare.CompareSchemasContext(ctx, sourceSchema, targetSchema, comparisonOptions(profile)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if differences, err = suppressDifferences(suppressor, differences); err != nil {
+		return nil, nil, err
+	}
+
+	saved, err := plan.New(differences, patch.FromDifferences(differences, sourceSchema, targetSchema, profile.Direction), beforeHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	redacted := profile.Redacted()
+	saved.Environment = envName
+	saved.Source, saved.Target = redacted.Source, redacted.Target
+	saved.Direction = profile.Direction
+	saved.IncludeTables, saved.ExcludeTables = profile.IncludeTables, profile.ExcludeTables
+	return differences, saved, nil
+}
+
+// printPlan prints the statements of a plan and the differences it leaves to be reconciled
+// manually.
+//
+// Parameters:
+//   - w: Writer the plan is printed to
+//   - p: Plan to print
+func printPlan(w io.Writer, p *plan.Plan) {
+	if len(p.Statements) == 0 {
+		fmt.Fprintf(w, "\nNo changes: the %s needs no statement to match.\n", p.ChangedSide())
+	} else {
+		fmt.Fprintf(w, "\nPlan: %d statements to run on the %s (%s):\n", len(p.Statements), p.ChangedSide(), p.Database())
+		for _, statement := range p.Statements {
+			fmt.Fprintf(w, "  %s\n", strings.ReplaceAll(statement, "\n", "\n  "))
+		}
+	}
+	if len(p.Unsupported) > 0 {
+		fmt.Fprintf(w, "\n%d differences must be reconciled manually:\n", len(p.Unsupported))
+		for _, diff := range p.Unsupported {
+			fmt.Fprintf(w, "  [%s] %s: %s\n", diff.Type, diff.ObjectName, strings.ReplaceAll(diff.Description, "\n", " "))
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// applyCmd applies a plan saved by the plan subcommand
+var applyCmd = &cobra.Command{
+	Use:   "apply PLAN",
+	Short: "Apply a plan file to the database it changes",
+	Long: `Runs the statements of a plan saved by 'schema-check plan' on the database it changes, in
+a single transaction, so that either all of them are applied or none is.
+
+The schema of the database is read again first, in the same transaction and with the table
+patterns of the plan, and the plan is refused if its checksum differs from the one recorded when
+the plan was made: changes made since then would otherwise be overwritten by statements that were
+never reviewed against them. Make a new plan in that case.
+
+Before anything else, the transaction takes a Postgres advisory lock (--lock-key), and the plan is
+refused if another session holds it: two CI runners applying plans to the same database at the
+same time would otherwise make overlapping changes. Every apply of a database must use the same
+key. The lock is held by the transaction, so it also works through poolers pooling transactions.
+
+The database is taken from --env, --source, or --target, as for the comparison; without any of
+them, from the environment the plan was made with. It must be the database of the plan.
+
+With --audit-log, the application is recorded, whatever its outcome, in an append-only audit log:
+a JSON lines file, or the schema_check.apply_audit table of a PostgreSQL database given by URL.
+
+The session applying the plan does not get --statement-timeout, which is meant for catalog
+queries: DDL such as CREATE INDEX or adding a validated constraint may run much longer. Set
+--apply-timeout to bound each of its statements instead.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		saved, err := plan.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		connString, err := applyDatabase(cmd, saved)
+		if err != nil {
+			return err
+		}
+
+		// Open the audit log before connecting, so that a log that cannot be opened is reported early
+		var auditLog audit.Log
+		if applyAuditLog != "" {
+			if auditLog, err = audit.Open(ctx, applyAuditLog); err != nil {
+				return err
+			}
+			defer auditLog.Close()
+		}
+
+		connectCtx := ctx
+		if connectTimeout > 0 {
+			var cancel context.CancelFunc
+			connectCtx, cancel = context.WithTimeout(ctx, connectTimeout)
+			defer cancel()
+		}
+		var conn *pgx.Conn
+		err = retryPolicy().Do(connectCtx, func() error {
+			var err error
+			conn, err = schema.Connect(connectCtx, connString, applySessionSettings())
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("error connecting to %s database: %w", saved.ChangedSide(), err)
+		}
+		defer conn.Close(context.Background())
+
+		record, err := applyPlan(ctx, conn, saved, applyLockKey)
+		record.Plan = args[0]
+		if auditLog != nil {
+			if appendErr := auditLog.Append(context.Background(), record); appendErr != nil {
+				err = errors.Join(err, appendErr)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Applied %d statements to the %s.\n", len(saved.Statements), saved.ChangedSide())
+		if len(saved.Unsupported) > 0 {
+			fmt.Printf("%d differences of the plan must still be reconciled manually.\n", len(saved.Unsupported))
+		}
+		return nil
+	},
+}
+
+// applySessionSettings returns the settings of the session applying a plan: those of the other
+// sessions, but with --apply-timeout as statement_timeout, since --statement-timeout is meant for
+// catalog queries and would cancel long-running DDL.
+//
+// Returns:
+//   - schema.SessionSettings: Session timeouts
+func applySessionSettings() schema.SessionSettings {
+	settings := sessionSettings()
+	settings.StatementTimeout = applyTimeout
+	return settings
+}
+
+// applyDatabase works out the connection string of the database a plan changes, from the flags
+// or else from the environment the plan was made with, and checks that it is the database of the
+// plan.
+//
+// Parameters:
+//   - cmd: The apply command, used to find out which flags were set
+//   - p: Plan to apply
+//
+// Returns:
+//   - string: Connection string of the database, with its secrets read
+//   - error: An error if no database is given, a secret cannot be read, or the database is not
+//     that of the plan
+func applyDatabase(cmd *cobra.Command, p *plan.Plan) (string, error) {
+	side := p.ChangedSide()
+	env := envName
+	if env == "" && !cmd.Flags().Changed(side) {
+		env = p.Environment
+	}
+
+	var profile config.Profile
+	if cmd.Flags().Changed("config") || env != "" {
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return "", err
+		}
+		if profile, err = cfg.Resolve(env); err != nil {
+			return "", err
+		}
+	}
+	if cmd.Flags().Changed("source") {
+		profile.Source = sourceConnString
+	}
+	if cmd.Flags().Changed("target") {
+		profile.Target = targetConnString
+	}
+
+	// Only the secrets of the database changed are read
+	if side == plan.SideSource {
+		profile.Target = ""
+	} else {
+		profile.Source = ""
+	}
+	profile, err := profile.WithSecrets()
+	if err != nil {
+		return "", err
+	}
+	connString, redacted := profile.Target, profile.Redacted().Target
+	if side == plan.SideSource {
+		connString, redacted = profile.Source, profile.Redacted().Source
+	}
+
+	if connString == "" {
+		return "", fmt.Errorf("no %s database given: use --%s or --env to give the database of the plan (%s)", side, side, p.Database())
+	}
+	if redacted != p.Database() {
+		return "", fmt.Errorf("the plan changes the %s %s, not %s", side, p.Database(), redacted)
+	}
+	return connString, nil
+}
+
+// applyPlan runs the statements of a plan in a transaction, once it has taken the advisory lock
+// of applies and checked that the schema is still the one the plan was made against. The schema
+// is read again once the transaction is committed, for the checksum after the changes.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - conn: Connection to the database the plan changes
+//   - p: Plan to apply
+//   - lockKey: Key of the advisory lock held while applying
+//
+// Returns:
+//   - audit.Record: Record of the application, finished, for the audit log
+//   - error: An error wrapping plan.ErrLocked if another apply holds the lock, plan.ErrStale if
+//     the schema changed since the plan was made, or any error that occurred while reading the
+//     schema or running a statement
+func applyPlan(ctx context.Context, conn *pgx.Conn, p *plan.Plan, lockKey int64) (audit.Record, error) {
+	record := audit.NewRecord(p.Database(), nil)
+	opts := fetchOptions(p.ChangedSide())
+	opts.IncludeTables, opts.ExcludeTables = p.IncludeTables, p.ExcludeTables
+	opts.Concurrency = 1 // Transactions cannot run queries concurrently
+	opts.StopOnError = true
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		err = fmt.Errorf("error starting transaction: %w", err)
+		record.Finish(nil, err)
+		return record, err
+	}
+	defer tx.Rollback(context.Background())
+
+	if err := plan.Lock(ctx, tx, lockKey); err != nil {
+		record.Statements = skipped(p.Statements)
+		record.Finish(nil, err)
+		return record, err
+	}
+
+	before, err := schema.Fetch(ctx, tx, opts)
+	if err != nil {
+		err = fmt.Errorf("error fetching %s schema: %w", p.ChangedSide(), err)
+		record.Finish(nil, err)
+		return record, err
+	}
+	record.BeforeHash = before.Checksum()
+	if err := p.Verify(before); err != nil {
+		record.Statements = skipped(p.Statements)
+		record.Finish(before, err)
+		return record, err
+	}
+
+	for i, statement := range p.Statements {
+		fmt.Printf("Running: %s\n", statement)
+		started := time.Now()
+		_, err := tx.Exec(ctx, statement)
+		outcome := audit.Statement{SQL: statement, Outcome: audit.OutcomeApplied, Duration: time.Since(started)}
+		if err != nil {
+			outcome.Outcome, outcome.Error = audit.OutcomeFailed, err.Error()
+			record.Statements = append(append(record.Statements, outcome), skipped(p.Statements[i+1:])...)
+			err = fmt.Errorf("error running statement %d of the plan, so none of them was applied: %w", i+1, err)
+			record.Finish(before, err)
+			return record, err
+		}
+		record.Statements = append(record.Statements, outcome)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		err = fmt.Errorf("error committing the statements of the plan: %w", err)
+		record.Finish(nil, err)
+		return record, err
+	}
+
+	after, err := schema.Fetch(ctx, conn, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read the %s schema after the changes: %v\n", p.ChangedSide(), err)
+		after = nil
+	}
+	record.Finish(after, nil)
+	return record, nil
+}
+
+// skipped returns the audit records of statements that were not run.
+//
+// Parameters:
+//   - statements: Statements not run
+//
+// Returns:
+//   - []audit.Statement: Statements with the skipped outcome
+func skipped(statements []string) []audit.Statement {
+	records := make([]audit.Statement, 0, len(statements))
+	for _, statement := range statements {
+		records = append(records, audit.Statement{SQL: statement, Outcome: audit.OutcomeSkipped})
+	}
+	return records
+}
+
+// init initializes the flags of the plan and apply subcommands
+func init() {
+	planCmd.Flags().StringVar(&sourceConnString, "source", "", "Source database connection string")
+	planCmd.Flags().StringVar(&targetConnString, "target", "", "Target database connection string")
+	planCmd.Flags().StringVar(&envName, "env", "", "Environment from the configuration file to compare (e.g., prod)")
+	planCmd.Flags().StringVar(&direction, "direction", compare.DirectionBoth, "Which side is authoritative: source-to-target, target-to-source, or both (the target is changed, or the source with target-to-source)")
+	planCmd.Flags().StringVar(&ignoreMarker, "ignore-marker", filter.DefaultIgnoreMarker, "Exclude tables and columns whose COMMENT contains this marker (empty to disable)")
+	planCmd.Flags().StringVar(&extraSeverity, "extra-severity", "", "Severity of objects present only in the target: error, warning, info, or ignore")
+	planCmd.Flags().StringVar(&outputFormat, "format", report.DefaultFormat, fmt.Sprintf("Output format of the differences (%s)", strings.Join(report.Formats(), ", ")))
+	planCmd.Flags().DurationVar(&timeout, "timeout", 0, "Overall time limit for the run, e.g. 5m (0 for no limit)")
+	planCmd.Flags().StringVar(&planOut, "out", "", "Path of the plan file to write")
+	planCmd.MarkFlagRequired("out")
+	planCmd.RegisterFlagCompletionFunc("env", completeEnvironments)
+	planCmd.RegisterFlagCompletionFunc("direction", completeDirections)
+	planCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(report.Formats(), cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.AddCommand(planCmd)
+
+	applyCmd.Flags().StringVar(&sourceConnString, "source", "", "Source database connection string, when the plan changes the source")
+	applyCmd.Flags().StringVar(&targetConnString, "target", "", "Target database connection string")
+	applyCmd.Flags().StringVar(&envName, "env", "", "Environment from the configuration file the database is taken from (default the environment of the plan)")
+	applyCmd.Flags().DurationVar(&timeout, "timeout", 0, "Overall time limit for the run, e.g. 5m (0 for no limit)")
+	applyCmd.Flags().StringVar(&applyAuditLog, "audit-log", "", "Record the application in this audit log: a JSON lines file, or a PostgreSQL database (URL) whose schema_check.apply_audit table is used")
+	applyCmd.Flags().DurationVar(&applyTimeout, "apply-timeout", 0, "Time limit of each statement applying the plan, set as statement_timeout instead of --statement-timeout (0 keeps the server's setting)")
+	applyCmd.Flags().Int64Var(&applyLockKey, "lock-key", plan.DefaultLockKey, "Key of the advisory lock held while applying; applies with the same key never run at the same time")
+	applyCmd.RegisterFlagCompletionFunc("env", completeEnvironments)
+	rootCmd.AddCommand(applyCmd)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+
+	"github.com/spf13/cobra"
+)
+
+// Flags enabling profiling, accepted by every command
+var (
+	cpuProfilePath string // Path of the CPU profile to write; empty disables it
+	memProfilePath string // Path of the heap profile to write when the command ends; empty disables it
+	tracePath      string // Path of the execution trace to write; empty disables it
+)
+
+// stopProfiling stops the profiles started by startProfiling and writes them out. It does
+// nothing until profiling has started.
+var stopProfiling = func() {}
+
+// startProfiling starts the CPU profile and the execution trace requested with --cpuprofile and
+// --trace, and sets up stopProfiling to stop them and write the heap profile requested with
+// --memprofile.
+//
+// Returns:
+//   - error: Any error that occurred while creating the files or starting the profiles
+func startProfiling() error {
+	var stops []func() error
+
+	if cpuProfilePath != "" {
+		file, err := os.Create(cpuProfilePath)
+		if err != nil {
+			return fmt.Errorf("error creating CPU profile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(file); err != nil {
+			file.Close()
+			return fmt.Errorf("error starting CPU profile: %w", err)
+		}
+		stops = append(stops, func() error {
+			pprof.StopCPUProfile()
+			return file.Close()
+		})
+	}
+
+	if tracePath != "" {
+		file, err := os.Create(tracePath)
+		if err != nil {
+			return fmt.Errorf("error creating trace: %w", err)
+		}
+		if err := trace.Start(file); err != nil {
+			file.Close()
+			return fmt.Errorf("error starting trace: %w", err)
+		}
+		stops = append(stops, func() error {
+			trace.Stop()
+			return file.Close()
+		})
+	}
+
+	if memProfilePath != "" {
+		stops = append(stops, func() error {
+			file, err := os.Create(memProfilePath)
+			if err != nil {
+				return fmt.Errorf("error creating heap profile: %w", err)
+			}
+			// Collect garbage first, so that the profile shows what is still in use
+			runtime.GC()
+			if err := pprof.WriteHeapProfile(file); err != nil {
+				file.Close()
+				return fmt.Errorf("error writing heap profile: %w", err)
+			}
+			return file.Close()
+		})
+	}
+
+	stopProfiling = func() {
+		for _, stop := range stops {
+			if err := stop(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+		stopProfiling = func() {}
+	}
+	return nil
+}
+
+// init initializes the profiling flags and, once the flags are parsed, applies --nice and starts
+// profiling
+func init() {
+	rootCmd.PersistentFlags().StringVar(&cpuProfilePath, "cpuprofile", "", "Write a CPU profile to this file, for go tool pprof")
+	rootCmd.PersistentFlags().StringVar(&memProfilePath, "memprofile", "", "Write a heap profile to this file when the command ends, for go tool pprof")
+	rootCmd.PersistentFlags().StringVar(&tracePath, "trace", "", "Write an execution trace to this file, for go tool trace")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		applyNice(cmd)
+		return startProfiling()
+	}
+}
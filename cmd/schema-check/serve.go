@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/metrics"
+	"github.com/guriandoro/pg_schema_check/pkg/results"
+	"github.com/guriandoro/pg_schema_check/pkg/server"
+	"github.com/guriandoro/pg_schema_check/pkg/server/schemacheckv1"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+// Flags of the serve subcommand
+var (
+	serveListen            string            // Address the service listens on
+	serveDatabases         map[string]string // Connection strings of the databases requests can refer to, keyed by name
+	allowConnectionStrings bool              // Whether requests can give connection strings of their own
+	requestTimeout         time.Duration     // Time limit of each request
+	serveGRPC              bool              // Whether to serve the gRPC service instead of the HTTP one
+)
+
+// serveCmd runs schema comparison as an HTTP service
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve schema comparisons over HTTP or gRPC",
+	Long: `Runs an HTTP service comparing schemas on request. POST /compare takes a JSON body naming a
+source and a target, each as one of the databases given with --database, as an inline snapshot,
+or (with --allow-connection-strings) as a connection string, and returns the differences in the
+JSON format of --format json. GET /metrics serves Prometheus metrics of the comparisons.
+
+With --config, the environments of the config file are also served as pairs: GET /pairs lists
+them with their latest results, POST /pairs/{name}/run compares a pair, GET
+/pairs/{name}/result returns its latest result, and GET /pairs/{name}/report?format=html
+downloads it as a report. Results are kept in --results-dir, which the daemon can share.
+
+With --grpc, serves the SchemaCheck gRPC service instead (see
+pkg/server/schemacheckv1/schemacheck.proto), whose Snapshot, Compare, and GenerateMigration
+calls accept the same sides, and the metrics are only served with --metrics-listen.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		opts := server.Options{
+			Connections:            serveDatabases,
+			AllowConnectionStrings: allowConnectionStrings,
+			Timeout:                requestTimeout,
+			Retry:                  retryPolicy(),
+			Session:                sessionSettings(),
+			Metrics:                metrics.New(),
+		}
+		if cmd.Flags().Changed("config") {
+			if err := servePairs(&opts); err != nil {
+				return err
+			}
+		}
+		if metricsListen != "" {
+			if err := serveMetrics(ctx, metricsListen, opts.Metrics); err != nil {
+				return err
+			}
+		}
+		if serveGRPC {
+			return serveGRPCService(ctx, opts)
+		}
+
+		srv := &http.Server{
+			Addr:              serveListen,
+			Handler:           server.NewHandler(opts),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		// Requests in flight are given a moment to finish when the service is stopped
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			srv.Shutdown(shutdownCtx)
+		}()
+
+		fmt.Fprintf(os.Stderr, "Listening on %s.\n", serveListen)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("error serving: %w", err)
+		}
+		return nil
+	},
+}
+
+// servePairs sets the options of the service to serve the environments of the config file as
+// pairs that requests can run by name, keeping their latest results in --results-dir and
+// recording their runs in the history, if any.
+//
+// Parameters:
+//   - opts: Options of the service, updated in place
+//
+// Returns:
+//   - error: Any error that occurred while loading the config file or opening the results
+func servePairs(opts *server.Options) error {
+	cfg, err := loadValidConfig()
+	if err != nil {
+		return err
+	}
+	store, err := results.OpenStore(resultsDir)
+	if err != nil {
+		return err
+	}
+
+	environments := make(map[string]string)
+	for _, env := range cfg.EnvironmentNames() {
+		environments[env] = env
+	}
+	if len(environments) == 0 {
+		environments[defaultCheckName] = ""
+	}
+	for _, name := range sortedNames(environments) {
+		profile, err := cfg.Resolve(environments[name])
+		if err != nil {
+			return err
+		}
+		redacted := profile.Redacted()
+		opts.Pairs = append(opts.Pairs, server.Pair{Name: name, Source: redacted.Source, Target: redacted.Target})
+	}
+
+	db, err := openHistory(context.Background(), cfg)
+	if err != nil {
+		return err
+	}
+
+	m := opts.Metrics
+	opts.Results = store
+	opts.RunPair = func(ctx context.Context, pair string) (compare.DiffResult, error) {
+		started := time.Now()
+		differences, err := compareEnvironment(ctx, cfg, environments[pair], func(side string, elapsed time.Duration) {
+			m.ObserveFetch(pair, side, elapsed)
+		})
+		if db != nil && !errors.Is(ctx.Err(), context.Canceled) {
+			profile, _ := cfg.Resolve(environments[pair])
+			recordHistory(db, pair, profile, started, differences, err)
+		}
+		return differences, err
+	}
+	return nil
+}
+
+// sortedNames returns the keys of a map in sorted order.
+func sortedNames(m map[string]string) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// serveGRPCService serves the SchemaCheck gRPC service on --listen until the context is done.
+//
+// Parameters:
+//   - ctx: Context whose end stops the service
+//   - opts: Options of the service
+//
+// Returns:
+//   - error: Any error that occurred while listening or serving
+func serveGRPCService(ctx context.Context, opts server.Options) error {
+	listener, err := net.Listen("tcp", serveListen)
+	if err != nil {
+		return fmt.Errorf("error listening: %w", err)
+	}
+
+	srv := grpc.NewServer(grpc.MaxRecvMsgSize(server.DefaultMaxBodyBytes))
+	schemacheckv1.RegisterSchemaCheckServer(srv, server.NewGRPCService(opts))
+
+	// Calls in flight are given a moment to finish when the service is stopped
+	go func() {
+		<-ctx.Done()
+		timer := time.AfterFunc(10*time.Second, srv.Stop)
+		defer timer.Stop()
+		srv.GracefulStop()
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving gRPC on %s.\n", listener.Addr())
+	if err := srv.Serve(listener); err != nil {
+		return fmt.Errorf("error serving: %w", err)
+	}
+	return nil
+}
+
+// init initializes the flags of the serve subcommand
+func init() {
+	serveCmd.Flags().StringVar(&serveListen, "listen", ":8080", "Address to listen on")
+	serveCmd.Flags().StringToStringVar(&serveDatabases, "database", nil, "Databases requests can compare, as name=connection-string pairs")
+	serveCmd.Flags().BoolVar(&allowConnectionStrings, "allow-connection-strings", false, "Accept connection strings in requests, letting callers reach any host the service can")
+	serveCmd.Flags().DurationVar(&requestTimeout, "request-timeout", 5*time.Minute, "Time limit of each request (0 for no limit)")
+	serveCmd.Flags().StringVar(&metricsListen, "metrics-listen", "", "Also serve Prometheus metrics on /metrics at this address, as needed with --grpc (e.g., :9187)")
+	serveCmd.Flags().StringVar(&historyDB, "history-db", "", "Record the runs of the pairs in the history kept in this PostgreSQL database (URL) or SQLite file (default history.database of the config file)")
+	serveCmd.Flags().StringVar(&resultsDir, "results-dir", "", "Keep the latest result of each pair of the config file in this directory (default in memory)")
+	serveCmd.Flags().BoolVar(&serveGRPC, "grpc", false, "Serve the gRPC service instead of the HTTP one")
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/guriandoro/pg_schema_check/pkg/cache"
+	"github.com/guriandoro/pg_schema_check/pkg/objstore"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+	"github.com/guriandoro/pg_schema_check/pkg/snapshot"
+	"github.com/spf13/cobra"
+)
+
+// Flags of the snapshot subcommand
+var (
+	snapshotDB         string // Connection string of the database to snapshot
+	snapshotOut        string // Path of the snapshot file to write
+	snapshotCheckpoint string // Path of the file recording the progress of the fetch; empty disables checkpoints
+)
+
+// snapshotCmd saves the schema of a database to a snapshot file
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save the schema of a database to a snapshot file",
+	Long: `Fetches the schema of a database and saves it to a snapshot file, which can later be
+passed to --source or --target in place of a connection string. Files ending in .bin or .snap
+use the compact binary format; any other file uses JSON. Adding .gz or .zst to the name
+compresses the file with gzip or zstd (e.g., app.json.zst). --out can also be an object storage
+URI (s3://BUCKET/KEY, gs://BUCKET/OBJECT, or azblob://CONTAINER/BLOB), encrypted as set by --sse
+and --sse-kms-key.
+
+With --checkpoint, the tables are read --batch-size at a time and recorded in the checkpoint
+file as they are read. If the snapshot is interrupted, running the same command again resumes
+from the checkpoint instead of starting over; the checkpoint is removed once the snapshot is
+saved.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		if objstore.IsURI(snapshotOut) {
+			if err := objstore.CheckOptions(snapshotOut, objectOptions()); err != nil {
+				return err
+			}
+		}
+
+		opts := fetchOptions("snapshot")
+		var checkpoint *snapshot.Checkpoint
+		if snapshotCheckpoint != "" && !snapshot.IsSnapshotPath(snapshotDB) {
+			var err error
+			checkpoint, err = openCheckpoint(&opts)
+			if err != nil {
+				return err
+			}
+			defer checkpoint.Close()
+		}
+
+		fetcher, closeFetcher, err := openFetcher(ctx, snapshotDB, "", opts)
+		if err != nil {
+			return fmt.Errorf("error connecting to database: %w", err)
+		}
+		defer closeFetcher()
+
+		s, err := fetchSnapshot(ctx, fetcher, checkpoint != nil)
+		if err != nil {
+			return fmt.Errorf("error fetching schema: %w", err)
+		}
+
+		if err := writeSnapshot(ctx, snapshotOut, s); err != nil {
+			return err
+		}
+		if checkpoint != nil {
+			if err := checkpoint.Remove(); err != nil {
+				return err
+			}
+		}
+
+		fmt.Printf("Saved %d tables to %s.\n", len(s.Tables), snapshotOut)
+		return nil
+	},
+}
+
+// writeSnapshot saves a schema to a snapshot file, or to object storage.
+//
+// Parameters:
+//   - ctx: Context for writing to object storage
+//   - path: Path of the snapshot file, or URI of the object, whose name chooses the format and compression
+//   - s: Schema to save
+//
+// Returns:
+//   - error: Any error that occurred while writing the snapshot
+func writeSnapshot(ctx context.Context, path string, s *schema.Schema) error {
+	if !objstore.IsURI(path) {
+		return snapshot.WriteFile(path, s)
+	}
+	object, err := objstore.NewWriter(ctx, path, objectOptions())
+	if err != nil {
+		return err
+	}
+	if err := snapshot.WriteForPath(object, path, s); err != nil {
+		return err
+	}
+	return object.Close()
+}
+
+// openCheckpoint opens the checkpoint of the snapshot, and sets fetch options so that the tables
+// it records are reused and the tables fetched are added to it.
+//
+// Parameters:
+//   - opts: Options of the fetch, updated in place
+//
+// Returns:
+//   - *snapshot.Checkpoint: Open checkpoint
+//   - error: Any error that occurred while opening the checkpoint
+func openCheckpoint(opts *schema.FetchOptions) (*snapshot.Checkpoint, error) {
+	checkpoint, err := snapshot.OpenCheckpoint(snapshotCheckpoint, cache.Key(snapshotDB, *opts))
+	if err != nil {
+		return nil, err
+	}
+	if resumed := len(checkpoint.Tables()); resumed > 0 {
+		fmt.Fprintf(notices(), "Resuming from %s: %d tables already fetched.\n", snapshotCheckpoint, resumed)
+	}
+
+	// A checkpoint that cannot be written only loses the ability to resume
+	opts.Reuse = checkpoint.Tables()
+	onTableFetched := opts.OnTableFetched
+	var writeErr error
+	opts.OnTableFetched = func(table schema.TableInfo, fetched, total int) {
+		if err := checkpoint.Add(table); err != nil && writeErr == nil {
+			writeErr = err
+			fmt.Fprintf(os.Stderr, "Could not record the progress of the snapshot (%v); it cannot be resumed past this point.\n", err)
+		}
+		if onTableFetched != nil {
+			onTableFetched(table, fetched, total)
+		}
+	}
+	return checkpoint, nil
+}
+
+// fetchSnapshot reads the schema to snapshot. With a checkpoint, the tables of databases are read
+// --batch-size at a time, so that each batch is recorded as soon as it is read.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - fetcher: Fetcher of the schema
+//   - checkpointed: Whether the progress of the fetch is recorded in a checkpoint
+//
+// Returns:
+//   - *schema.Schema: Schema read
+//   - error: Any error that occurred while reading it
+func fetchSnapshot(ctx context.Context, fetcher schema.Fetcher, checkpointed bool) (*schema.Schema, error) {
+	streamer, ok := fetcher.(schema.TableStreamer)
+	if !checkpointed || !ok {
+		return fetcher.Fetch(ctx)
+	}
+	stream, err := streamer.StreamTables(ctx, batchSize)
+	if err != nil {
+		return nil, err
+	}
+	return schema.Collect(ctx, stream)
+}
+
+// init initializes the flags of the snapshot subcommand
+func init() {
+	snapshotCmd.Flags().StringVar(&snapshotDB, "db", "", "Connection string of the database to snapshot")
+	snapshotCmd.Flags().StringVar(&snapshotOut, "out", "", "Path of the snapshot file to write (.json, or .bin/.snap for binary; add .gz or .zst to compress), or object storage URI (s3://, gs://, or azblob://)")
+	snapshotCmd.Flags().StringVar(&sse, "sse", "", "Server-side encryption of the snapshot written to S3: AES256 or aws:kms (default the bucket's)")
+	snapshotCmd.Flags().StringVar(&sseKMSKey, "sse-kms-key", "", "Key of the snapshot written to object storage: AWS KMS key ID or ARN (with --sse aws:kms), Cloud KMS key name, or Azure encryption scope")
+	snapshotCmd.Flags().StringVar(&snapshotCheckpoint, "checkpoint", "", "Record the progress of the fetch in this file, and resume from it if a previous run was interrupted")
+	snapshotCmd.Flags().IntVar(&batchSize, "batch-size", schema.DefaultBatchSize, "Number of tables whose details are read at once with --checkpoint")
+	snapshotCmd.MarkFlagRequired("db")
+	snapshotCmd.MarkFlagRequired("out")
+	rootCmd.AddCommand(snapshotCmd)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/audit"
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/config"
+	"github.com/guriandoro/pg_schema_check/pkg/filter"
+	"github.com/guriandoro/pg_schema_check/pkg/objstore"
+	"github.com/guriandoro/pg_schema_check/pkg/patch"
+	"github.com/guriandoro/pg_schema_check/pkg/suppress"
+	"github.com/guriandoro/pg_schema_check/pkg/tui"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// Flags of the tui subcommand
+var (
+	tuiGroupBy      string // Initial grouping of the differences
+	tuiExport       string // Path or URI the migration of the selected differences is written to
+	tuiAcceptReason string // Reason recorded in the suppression rules of accepted differences
+	tuiAcceptUntil  string // Expiry date of the suppression rules of accepted differences; empty never expires
+)
+
+// tuiCmd compares two databases and browses the differences in a terminal UI
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Browse the differences of a comparison in a terminal UI",
+	Long: `Compares the source and target, as the root command does, and lists the differences in a
+terminal UI, grouped by table, type of difference, or schema (tab cycles through them). Opening a
+difference shows the definitions of its table on both sides, next to each other, with the lines
+found on one side only highlighted.
+
+Differences selected with space (or the one under the cursor) can be:
+  - accepted with a: a suppression rule matching each of them is added to the configuration file
+    (--config), in the environment of --env if any, with --accept-reason as its reason, the
+    user as its owner, and --accept-until as its expiry date;
+  - exported with e: the statements reconciling them, as --sql would write them, are written to
+    --export, a file or an object storage URI.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		// Check for a terminal before connecting, so that a run in a pipeline fails early
+		if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+			return fmt.Errorf("the tui subcommand needs a terminal; use the root command for reports in pipelines")
+		}
+		if tuiGroupBy != tui.GroupTable && tuiGroupBy != tui.GroupType && tuiGroupBy != tui.GroupSchema {
+			return fmt.Errorf("unknown grouping '%s': expected %s, %s, or %s", tuiGroupBy, tui.GroupTable, tui.GroupType, tui.GroupSchema)
+		}
+		if tuiAcceptUntil != "" {
+			if _, err := time.Parse(suppress.DateFormat, tuiAcceptUntil); err != nil {
+				return fmt.Errorf("invalid --accept-until '%s': expected YYYY-MM-DD", tuiAcceptUntil)
+			}
+		}
+		if objstore.IsURI(tuiExport) {
+			if err := objstore.CheckOptions(tuiExport, objectOptions()); err != nil {
+				return err
+			}
+		}
+
+		profile, err := resolveProfile(cmd)
+		if err != nil {
+			return err
+		}
+		suppressor, err := suppress.New(profile.Suppress, time.Now())
+		if err != nil {
+			return err
+		}
+
+		differences, sourceSchema, targetSchema, err := runComparison(ctx, profile, suppressor, true, nil)
+		if err != nil {
+			return err
+		}
+		if len(differences) == 0 {
+			fmt.Println("No differences found.")
+			return nil
+		}
+
+		return tui.Run(ctx, differences, tui.Options{
+			Source:  sourceSchema,
+			Target:  targetSchema,
+			GroupBy: tuiGroupBy,
+			Accept:  acceptDifferences,
+			Export: func(selected compare.DiffResult) (string, error) {
+				p := patch.FromDifferences(selected, sourceSchema, targetSchema, profile.Direction)
+				if err := writeSQLFile(ctx, tuiExport, p); err != nil {
+					return "", err
+				}
+				message := fmt.Sprintf("Wrote %d statements to %s.", len(p.Operations), tuiExport)
+				if len(p.Unsupported) > 0 {
+					message += fmt.Sprintf(" %d differences must be reconciled manually.", len(p.Unsupported))
+				}
+				return message, nil
+			},
+		})
+	},
+}
+
+// acceptDifferences adds a suppression rule for each difference to the configuration file, in
+// the environment of --env if any, so that later comparisons no longer report them.
+//
+// Parameters:
+//   - differences: Differences accepted
+//
+// Returns:
+//   - string: Message telling where the rules were added
+//   - error: Any error that occurred while editing the configuration file
+func acceptDifferences(differences compare.DiffResult) (string, error) {
+	owner := audit.CurrentUser()
+	rules := make([]suppress.Rule, 0, len(differences))
+	for _, diff := range differences {
+		rule := suppress.RuleFor(diff)
+		rule.Reason, rule.Owner, rule.Expires = tuiAcceptReason, owner, tuiAcceptUntil
+		rules = append(rules, rule)
+	}
+	if err := config.AddSuppressRules(configPath, envName, rules); err != nil {
+		return "", err
+	}
+	where := configPath
+	if envName != "" {
+		where += " (environment " + envName + ")"
+	}
+	return fmt.Sprintf("Accepted %d differences in %s.", len(rules), where), nil
+}
+
+// init initializes the flags of the tui subcommand
+func init() {
+	tuiCmd.Flags().StringVar(&sourceConnString, "source", "", "Source database connection string, or path of a snapshot file")
+	tuiCmd.Flags().StringVar(&targetConnString, "target", "", "Target database connection string, or path of a snapshot file")
+	tuiCmd.Flags().StringVar(&envName, "env", "", "Environment from the configuration file to compare (e.g., prod); accepted differences are added to it")
+	tuiCmd.Flags().StringVar(&direction, "direction", compare.DirectionBoth, "Which side is authoritative: source-to-target, target-to-source, or both")
+	tuiCmd.Flags().StringVar(&ignoreMarker, "ignore-marker", filter.DefaultIgnoreMarker, "Exclude tables and columns whose COMMENT contains this marker (empty to disable)")
+	tuiCmd.Flags().StringVar(&extraSeverity, "extra-severity", "", "Severity of objects present only in the target: error, warning, info, or ignore")
+	tuiCmd.Flags().DurationVar(&timeout, "timeout", 0, "Overall time limit for the run, including browsing, e.g. 30m (0 for no limit)")
+	tuiCmd.Flags().StringVar(&tuiGroupBy, "group-by", tui.GroupTable, "Initial grouping of the differences: table, type, or schema")
+	tuiCmd.Flags().StringVar(&tuiExport, "export", "schema-check-migration.sql", "File or object storage URI the migration of the selected differences is exported to")
+	tuiCmd.Flags().StringVar(&sse, "sse", "", "Server-side encryption of the migration exported to S3: AES256 or aws:kms (default the bucket's)")
+	tuiCmd.Flags().StringVar(&sseKMSKey, "sse-kms-key", "", "Key of the migration exported to object storage: AWS KMS key ID or ARN (with --sse aws:kms), Cloud KMS key name, or Azure encryption scope")
+	tuiCmd.Flags().StringVar(&tuiAcceptReason, "accept-reason", "Accepted in schema-check tui", "Reason recorded in the suppression rules of accepted differences")
+	tuiCmd.Flags().StringVar(&tuiAcceptUntil, "accept-until", "", "Expiry date (YYYY-MM-DD) of the suppression rules of accepted differences (default never)")
+	tuiCmd.RegisterFlagCompletionFunc("env", completeEnvironments)
+	tuiCmd.RegisterFlagCompletionFunc("direction", completeDirections)
+	tuiCmd.RegisterFlagCompletionFunc("group-by", cobra.FixedCompletions([]string{tui.GroupTable, tui.GroupType, tui.GroupSchema}, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.AddCommand(tuiCmd)
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/changelog"
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/config"
+	"github.com/guriandoro/pg_schema_check/pkg/report"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+	"github.com/guriandoro/pg_schema_check/pkg/snapshot"
+	"github.com/guriandoro/pg_schema_check/pkg/suppress"
+	"github.com/jackc/pgx/v5"
+)
+
+// checkWatch checks that --watch can be used with the other settings of the run.
+//
+// Parameters:
+//   - profile: Settings of the comparison
+//
+// Returns:
+//   - error: An error if a setting rules out watching
+func checkWatch(profile config.Profile) error {
+	if !watch {
+		return nil
+	}
+	if snapshot.IsSnapshotPath(profile.Source) || snapshot.IsSnapshotPath(profile.Target) {
+		return fmt.Errorf("--watch needs two databases: snapshot files do not change")
+	}
+	if cacheTTL > 0 {
+		return fmt.Errorf("--watch cannot be combined with --cache-ttl, which would compare the cached schemas again (use --incremental to re-read only the changed tables)")
+	}
+	if outputFormat != report.DefaultFormat || reportOutput != "" {
+		return fmt.Errorf("--watch prints the changes as text on stdout, so it cannot be combined with --format or --output")
+	}
+	if watchDebounce < 0 {
+		return fmt.Errorf("--watch-debounce cannot be negative")
+	}
+	return nil
+}
+
+// watchSchemas compares the schemas again whenever DDL on either database changes them, until
+// the context ends, printing the differences that appeared and were resolved since the
+// previous comparison. The change log is installed, or updated, on both databases so that its
+// entries notify changelog.Channel, which a session of its own listens to on each database.
+// Comparisons wait for the DDL to have been quiet for --watch-debounce, so that a migration
+// running many statements is compared once; DDL on other PostgreSQL schemas is ignored.
+//
+// Parameters:
+//   - ctx: Context whose end stops watching
+//   - profile: Settings of the comparison
+//   - suppressor: Compiled suppression rules of the profile
+//   - differences: Differences found by the first comparison
+//
+// Returns:
+//   - compare.DiffResult: Differences found by the last successful comparison
+//   - error: Any error that occurred while listening; failed comparisons are reported and watching goes on
+func watchSchemas(ctx context.Context, profile config.Profile, suppressor *suppress.Suppressor, differences compare.DiffResult) (compare.DiffResult, error) {
+	schemaName := fetchOptions("").SchemaName
+	if schemaName == "" {
+		schemaName = schema.DefaultSchemaName
+	}
+
+	// Each side notifies the schemas of its changes; the first error stops watching
+	notified := make(chan string)
+	failed := make(chan error, 2)
+	listenCtx, cancel := context.WithCancel(ctx)
+	var conns []*pgx.Conn
+	var listeners sync.WaitGroup
+	defer func() {
+		// The connections are closed once nothing waits on them
+		cancel()
+		listeners.Wait()
+		for _, conn := range conns {
+			conn.Close(context.Background())
+		}
+	}()
+	for _, side := range []struct{ name, connString string }{{"source", profile.Source}, {"target", profile.Target}} {
+		conn, err := listenForDDL(ctx, side.name, side.connString)
+		if err != nil {
+			return differences, err
+		}
+		conns = append(conns, conn)
+		listeners.Add(1)
+		go func(name string, conn *pgx.Conn) {
+			defer listeners.Done()
+			for {
+				changed, err := changelog.Wait(listenCtx, conn)
+				if err != nil {
+					failed <- fmt.Errorf("error watching the %s database: %w", name, err)
+					return
+				}
+				if changed == schemaName || changed == "" {
+					select {
+					case notified <- name:
+					case <-listenCtx.Done():
+						return
+					}
+				}
+			}
+		}(side.name, conn)
+	}
+	fmt.Fprintf(os.Stdout, "\nWatching both databases for DDL on schema %s (Ctrl-C to stop).\n", schemaName)
+
+	pending := make(map[string]bool)
+	quiet := time.NewTimer(0)
+	<-quiet.C
+	for {
+		select {
+		case <-ctx.Done():
+			return differences, nil
+		case err := <-failed:
+			if ctx.Err() != nil {
+				return differences, nil
+			}
+			return differences, err
+		case side := <-notified:
+			pending[side] = true
+			quiet.Reset(watchDebounce)
+			continue
+		case <-quiet.C:
+		}
+
+		// DDL has been quiet for long enough: compare again
+		sides := make([]string, 0, len(pending))
+		for _, side := range []string{"source", "target"} {
+			if pending[side] {
+				sides = append(sides, side)
+			}
+		}
+		clear(pending)
+		fmt.Fprintf(os.Stdout, "\n[%s] DDL on the %s database; comparing again.\n", time.Now().Format(time.TimeOnly), strings.Join(sides, " and "))
+		current, _, _, err := runComparison(ctx, profile, suppressor, false, nil)
+		if ctx.Err() != nil {
+			return differences, nil
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stdout, "Comparison failed: %v\n", err)
+			continue
+		}
+		appeared, resolved := current.Changes(differences)
+		differences = current
+		printChanges(appeared, resolved, len(current))
+	}
+}
+
+// listenForDDL connects to a database on a session of its own and listens for the notifications
+// of its change log, installing or updating the change log first.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - side: Name of the side ("source" or "target")
+//   - connString: Connection string of the database
+//
+// Returns:
+//   - *pgx.Conn: Connection listening for the notifications, to close once done
+//   - error: Any error that occurred while connecting, installing, or listening
+func listenForDDL(ctx context.Context, side, connString string) (*pgx.Conn, error) {
+	var conn *pgx.Conn
+	err := retryPolicy().Do(ctx, func() error {
+		var err error
+		conn, err = schema.Connect(ctx, connString, sessionSettings())
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s database: %w", side, err)
+	}
+
+	// Installing needs a superuser; a change log installed earlier by one may be used as it is
+	if err := changelog.Install(ctx, conn); err != nil {
+		if _, posErr := changelog.Position(ctx, conn); posErr != nil {
+			conn.Close(ctx)
+			if errors.Is(posErr, changelog.ErrNotInstalled) {
+				return nil, fmt.Errorf("the change log of the %s database is needed by --watch; run changelog install as a superuser: %w", side, err)
+			}
+			return nil, err
+		}
+		fmt.Fprintf(os.Stdout, "Could not update the change log of the %s database (%v); if it predates --watch, run changelog install again as a superuser, or its DDL goes unnoticed.\n", side, err)
+	}
+	if err := changelog.Listen(ctx, conn); err != nil {
+		conn.Close(ctx)
+		return nil, fmt.Errorf("error watching the %s database: %w", side, err)
+	}
+	return conn, nil
+}
+
+// printChanges prints the differences that appeared and were resolved since the previous
+// comparison of --watch.
+//
+// Parameters:
+//   - appeared: Differences the previous comparison did not find
+//   - resolved: Differences of the previous comparison no longer found
+//   - total: Number of differences found by the comparison
+func printChanges(appeared, resolved compare.DiffResult, total int) {
+	if len(appeared) == 0 && len(resolved) == 0 {
+		fmt.Fprintf(os.Stdout, "No change: %d differences.\n", total)
+		return
+	}
+	fmt.Fprintf(os.Stdout, "%d new, %d resolved, %d differences in all:\n", len(appeared), len(resolved), total)
+	for _, diff := range appeared {
+		fmt.Fprintf(os.Stdout, "+ [%s] [%s] %s: %s\n", diff.Severity, diff.Type, watchSubject(diff), diff.Description)
+	}
+	for _, diff := range resolved {
+		fmt.Fprintf(os.Stdout, "- [%s] [%s] %s: %s\n", diff.Severity, diff.Type, watchSubject(diff), diff.Description)
+	}
+}
+
+// watchSubject returns what a difference printed by --watch is about: its table, or the object
+// itself for differences that are not about a table, as in the text report.
+func watchSubject(diff compare.Difference) string {
+	if diff.Table == "" {
+		return diff.ObjectName
+	}
+	return diff.Table
+}
@@ -0,0 +1,182 @@
+// Package audit provides functionality to keep an append-only audit log of the schema changes
+// applied to a database, as change-management processes require: who applied them and when,
+// every statement run with its outcome, and the checksums of the schema before and after.
+//
+// A Log is either a JSON lines file, appended to and synced after every record, or the
+// schema_check.apply_audit table of a PostgreSQL database, which a trigger keeps from being
+// updated or deleted from. Open picks one from its destination.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+	"github.com/jackc/pgx/v5"
+)
+
+// Outcomes of a statement and of a whole application of changes
+const (
+	OutcomeApplied = "applied" // The statement ran, or every statement did
+	OutcomeFailed  = "failed"  // The statement failed, or one of the statements did
+	OutcomeSkipped = "skipped" // The statement was not run, because an earlier one failed
+)
+
+// Statement is a statement of an application of changes, with its outcome.
+type Statement struct {
+	SQL      string        `json:"sql"`             // Statement run
+	Outcome  string        `json:"outcome"`         // Outcome of the statement: applied, failed, or skipped
+	Duration time.Duration `json:"duration"`        // Time the statement took, in nanoseconds
+	Error    string        `json:"error,omitempty"` // Error the statement failed with, if it failed
+}
+
+// Record is an application of changes to a database, as written to the audit log.
+type Record struct {
+	User       string      `json:"user"`                 // Operating system user who applied the changes
+	Host       string      `json:"host"`                 // Host the changes were applied from
+	Started    time.Time   `json:"started"`              // When the application started
+	Finished   time.Time   `json:"finished"`             // When the application finished
+	Target     string      `json:"target"`               // Database changed, with its password masked
+	Plan       string      `json:"plan,omitempty"`       // Plan the changes came from, if any
+	BeforeHash string      `json:"before_hash"`          // Checksum of the schema before the changes (see schema.Schema.Checksum)
+	AfterHash  string      `json:"after_hash,omitempty"` // Checksum of the schema after the changes; empty if it could not be read
+	Outcome    string      `json:"outcome"`              // Outcome of the application: applied or failed
+	Statements []Statement `json:"statements"`           // Statements of the application, in the order they were run
+	Error      string      `json:"error,omitempty"`      // Error the application failed with, if it failed
+}
+
+// NewRecord starts the record of an application of changes, by the current user on this host.
+//
+// Parameters:
+//   - target: Database changed, with its password masked
+//   - before: Schema of the database before the changes
+//
+// Returns:
+//   - Record: Record with its user, host, start, target, and checksum before the changes set
+func NewRecord(target string, before *schema.Schema) Record {
+	record := Record{User: CurrentUser(), Started: time.Now().UTC(), Target: target}
+	record.Host, _ = os.Hostname()
+	if before != nil {
+		record.BeforeHash = before.Checksum()
+	}
+	return record
+}
+
+// Finish completes a record once its statements have run, setting when it finished, its
+// outcome, and the checksum of the schema after the changes.
+//
+// Parameters:
+//   - after: Schema of the database after the changes, or nil if it could not be read
+//   - err: Error the application failed with, or nil
+func (r *Record) Finish(after *schema.Schema, err error) {
+	r.Finished = time.Now().UTC()
+	r.Outcome = OutcomeApplied
+	if err != nil {
+		r.Outcome, r.Error = OutcomeFailed, err.Error()
+	}
+	for _, statement := range r.Statements {
+		if statement.Outcome == OutcomeFailed {
+			r.Outcome = OutcomeFailed
+		}
+	}
+	if after != nil {
+		r.AfterHash = after.Checksum()
+	}
+}
+
+// CurrentUser returns the name of the operating system user running the process, or of the
+// USER environment variable when it cannot be looked up.
+//
+// Returns:
+//   - string: Name of the user; "unknown" if it cannot be found out
+func CurrentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
+}
+
+// Log is an append-only audit log.
+type Log interface {
+	// Append adds a record to the log, durably: once it returns, the record survives a crash.
+	Append(ctx context.Context, record Record) error
+	// Close releases the resources of the log.
+	Close() error
+}
+
+// Open opens the audit log at a destination.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - destination: PostgreSQL connection URL (postgres:// or postgresql://) of the database whose
+//     schema_check.apply_audit table the records are added to, or path of a JSON lines file
+//
+// Returns:
+//   - Log: Open audit log
+//   - error: Any error that occurred while connecting or creating the table
+func Open(ctx context.Context, destination string) (Log, error) {
+	if strings.HasPrefix(destination, "postgres://") || strings.HasPrefix(destination, "postgresql://") {
+		conn, err := pgx.Connect(ctx, destination)
+		if err != nil {
+			return nil, fmt.Errorf("error connecting to audit database: %w", err)
+		}
+		if err := Install(ctx, conn); err != nil {
+			conn.Close(ctx)
+			return nil, err
+		}
+		return &TableLog{Conn: conn, close: func() error { return conn.Close(context.Background()) }}, nil
+	}
+	if destination == "" {
+		return nil, fmt.Errorf("error opening audit log: no destination given")
+	}
+	return &FileLog{Path: destination}, nil
+}
+
+// FileLog is an audit log kept in a file, one JSON record per line. Records are only ever
+// appended, and the file is synced after each of them.
+type FileLog struct {
+	Path string // Path of the file, created if needed
+}
+
+// Append adds a record to the end of the file.
+//
+// Parameters:
+//   - ctx: Unused; writing to the file is not cancelled
+//   - record: Record to add
+//
+// Returns:
+//   - error: Any error that occurred while writing or syncing the file
+func (l *FileLog) Append(ctx context.Context, record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("error encoding audit record: %w", err)
+	}
+	file, err := os.OpenFile(l.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return fmt.Errorf("error opening audit log: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error writing audit log: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("error syncing audit log: %w", err)
+	}
+	return nil
+}
+
+// Close does nothing, as the file is only open while a record is appended.
+//
+// Returns:
+//   - error: Always nil
+func (l *FileLog) Close() error {
+	return nil
+}
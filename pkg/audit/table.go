@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Execer is the part of a PostgreSQL connection that the audit table needs. It is satisfied by
+// *pgx.Conn, *pgxpool.Pool, and pgx.Tx.
+type Execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// installSQL creates the audit table. Its trigger rejects updates and deletes, so that records
+// can only be added; only its owner can drop the trigger or the table.
+const installSQL = `
+CREATE SCHEMA IF NOT EXISTS schema_check;
+
+CREATE TABLE IF NOT EXISTS schema_check.apply_audit (
+	id bigserial PRIMARY KEY,
+	recorded_at timestamptz NOT NULL DEFAULT now(),
+	applied_by text NOT NULL,
+	host text NOT NULL,
+	started timestamptz NOT NULL,
+	finished timestamptz NOT NULL,
+	target text NOT NULL,
+	plan text NOT NULL,
+	before_hash text NOT NULL,
+	after_hash text NOT NULL,
+	outcome text NOT NULL,
+	statements jsonb NOT NULL,
+	error text NOT NULL
+);
+
+CREATE OR REPLACE FUNCTION schema_check.reject_audit_change() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+	RAISE EXCEPTION 'schema_check.apply_audit is append-only';
+END
+$$;
+
+DROP TRIGGER IF EXISTS apply_audit_append_only ON schema_check.apply_audit;
+CREATE TRIGGER apply_audit_append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON schema_check.apply_audit
+	FOR EACH STATEMENT EXECUTE PROCEDURE schema_check.reject_audit_change();
+`
+
+// Install creates the schema_check.apply_audit table and the trigger keeping it append-only,
+// or updates the trigger of an existing table. Records already added are kept.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//
+// Returns:
+//   - error: Any error that occurred while creating the objects
+func Install(ctx context.Context, conn Execer) error {
+	if _, err := conn.Exec(ctx, installSQL); err != nil {
+		return fmt.Errorf("error installing audit table: %w", err)
+	}
+	return nil
+}
+
+// TableLog is an audit log kept in the schema_check.apply_audit table of a PostgreSQL database,
+// created with Install.
+type TableLog struct {
+	Conn  Execer       // Connection to the database of the table
+	close func() error // Closes the connection opened by Open, if any
+}
+
+// Append inserts a record into the audit table.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - record: Record to add
+//
+// Returns:
+//   - error: Any error that occurred while inserting the record
+func (l *TableLog) Append(ctx context.Context, record Record) error {
+	statements, err := json.Marshal(record.Statements)
+	if err != nil {
+		return fmt.Errorf("error encoding audit record: %w", err)
+	}
+	_, err = l.Conn.Exec(ctx, `INSERT INTO schema_check.apply_audit
+	(applied_by, host, started, finished, target, plan, before_hash, after_hash, outcome, statements, error)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		record.User, record.Host, record.Started, record.Finished, record.Target, record.Plan,
+		record.BeforeHash, record.AfterHash, record.Outcome, string(statements), record.Error)
+	if err != nil {
+		return fmt.Errorf("error writing audit record: %w", err)
+	}
+	return nil
+}
+
+// Close closes the connection of a log opened with Open; the connection of a log built by the
+// caller is left to the caller.
+//
+// Returns:
+//   - error: Any error that occurred while closing the connection
+func (l *TableLog) Close() error {
+	if l.close == nil {
+		return nil
+	}
+	return l.close()
+}
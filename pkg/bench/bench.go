@@ -0,0 +1,95 @@
+package bench
+
+import (
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Sizes are the numbers of tables of the generated schemas the benchmarks are run with.
+var Sizes = []int{10000, 50000, 100000}
+
+// ShortSizes are the numbers of tables the benchmarks are run with in short mode (go test -short).
+var ShortSizes = []int{1000}
+
+// TableCounts returns the numbers of tables the benchmarks should generate schemas of: Sizes, or
+// ShortSizes in short mode.
+//
+// Returns:
+//   - []int: Numbers of tables, in increasing order
+func TableCounts() []int {
+	if testing.Short() {
+		return ShortSizes
+	}
+	return Sizes
+}
+
+// TrackPeakHeap samples the size of the heap until the returned function is called, which
+// reports the largest size seen as the peak-heap-MB metric of the benchmark.
+//
+// Parameters:
+//   - b: Benchmark being measured
+//
+// Returns:
+//   - func(): Function stopping the sampling and reporting the metric
+func TrackPeakHeap(b *testing.B) func() {
+	return trackPeakHeap(b, 0)
+}
+
+// TrackPeakHeapPerTable does as TrackPeakHeap, and also reports how much the heap grew at its
+// peak, divided by a number of tables, as the peak-heap-B/table metric. Comparing it across
+// numbers of tables shows how memory grows with the schema: a comparison holding the details of
+// one pair of tables at a time only grows with the names in the outline of the schemas.
+//
+// Parameters:
+//   - b: Benchmark being measured
+//   - tables: Number of tables of the schemas the benchmark handles
+//
+// Returns:
+//   - func(): Function stopping the sampling and reporting the metrics
+func TrackPeakHeapPerTable(b *testing.B, tables int) func() {
+	return trackPeakHeap(b, tables)
+}
+
+// trackPeakHeap samples the size of the heap until the returned function is called, which
+// reports the largest size seen, and its growth per table if the number of tables is positive.
+func trackPeakHeap(b *testing.B, tables int) func() {
+	runtime.GC()
+	samples := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(samples)
+	baseline := samples[0].Value.Uint64()
+	peak := baseline
+	sample := func() {
+		metrics.Read(samples)
+		peak = max(peak, samples[0].Value.Uint64())
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				sample()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		wg.Wait()
+		sample()
+		b.ReportMetric(float64(peak)/1e6, "peak-heap-MB")
+		if tables > 0 {
+			b.ReportMetric(float64(peak-baseline)/float64(tables), "peak-heap-B/table")
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package bench
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Catalog is a schema.Querier answering the catalog queries of PostgreSQL 14 and later from a
+// schema held in memory, so that fetching can be benchmarked without a database. Fetch from it
+// with the options of CatalogOptions, which skip the detection of the server.
+type Catalog struct {
+	schema *schema.Schema // Schema the catalog describes
+	names  []string       // Names of its tables, sorted
+}
+
+// CatalogOptions are the options to fetch from a Catalog with.
+var CatalogOptions = schema.FetchOptions{Dialect: schema.DialectPostgres, ServerVersion: 160000}
+
+// NewCatalog returns a catalog describing a schema.
+//
+// Parameters:
+//   - s: Schema the catalog describes
+//
+// Returns:
+//   - *Catalog: Catalog of the schema
+func NewCatalog(s *schema.Schema) *Catalog {
+	names := make([]string, 0, len(s.Tables))
+	for name := range s.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return &Catalog{schema: s, names: names}
+}
+
+// Query answers a catalog query, recognized by the catalog it reads, with the rows PostgreSQL
+// would return for the schema. The queries of table details honor the array of table names, and
+// the query listing the tables honors the regular expressions of the tables to include and exclude.
+func (c *Catalog) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	var rowsOf func(table schema.TableInfo) [][]any
+	listing := false
+	switch {
+	case strings.Contains(query, "pg_extension"):
+		return &rows{}, nil
+	case strings.Contains(query, "pg_sequence"):
+		return c.sequences(args...)
+	case strings.Contains(query, "pg_get_functiondef"):
+		return c.functions(args...)
+	case strings.Contains(query, "format_type"):
+		rowsOf = columnRows
+	case strings.Contains(query, "contype = 'p'"):
+		rowsOf = func(table schema.TableInfo) [][]any {
+			var data [][]any
+			for _, col := range table.PrimaryKeys {
+				data = append(data, []any{table.Name, col, table.PrimaryKeyName})
+			}
+			return data
+		}
+	case strings.Contains(query, "pg_index"):
+		rowsOf = func(table schema.TableInfo) [][]any {
+			var data [][]any
+			for _, idx := range table.Indexes {
+				positions := make([]int32, len(idx.Expressions))
+				expressions := make([]string, len(idx.Expressions))
+				for i, expression := range idx.Expressions {
+					positions[i] = int32(expression.Position)
+					expressions[i] = expression.Expression
+				}
+				data = append(data, []any{table.Name, idx.Name, idx.Columns, idx.Unique, positions, expressions,
+					idx.Include, idx.Predicate, idx.Method})
+			}
+			return data
+		}
+	case strings.Contains(query, "contype = 'f'"):
+		rowsOf = func(table schema.TableInfo) [][]any {
+			var data [][]any
+			for _, fk := range table.ForeignKeys {
+				data = append(data, []any{table.Name, fk.Name, fk.Columns, fk.ReferencedTable, fk.ReferencedColumns, fk.Definition})
+			}
+			return data
+		}
+	case strings.Contains(query, "pg_trigger"):
+		rowsOf = func(table schema.TableInfo) [][]any {
+			var data [][]any
+			for _, trigger := range table.Triggers {
+				data = append(data, []any{table.Name, trigger.Name, trigger.Timing, trigger.Events, trigger.Columns,
+					trigger.Level, trigger.When, trigger.Function})
+			}
+			return data
+		}
+	case strings.Contains(query, "relkind"):
+		listing = true
+		rowsOf = func(table schema.TableInfo) [][]any {
+			var view schema.ViewInfo
+			if table.View != nil {
+				view = *table.View
+			}
+			return [][]any{{table.Name, nullString(table.Comment), table.Owner, table.PartitionOf, table.PartitionKey,
+				nullString(view.Definition), view.Materialized, view.Populated, view.References}}
+		}
+	default:
+		return nil, fmt.Errorf("query not supported by the benchmark catalog: %s", query)
+	}
+
+	// The queries of table details take the names of the tables to read as $2, and the query
+	// listing the tables takes the expressions of the tables to include and exclude as $2 and $3
+	names := c.names
+	switch {
+	case listing && len(args) > 2:
+		var err error
+		if names, err = matching(c.names, args[1].([]string), args[2].([]string)); err != nil {
+			return nil, err
+		}
+	case len(args) > 1:
+		if only, ok := args[1].([]string); ok {
+			names = only
+		}
+	}
+	var data [][]any
+	for _, name := range names {
+		if table, exists := c.schema.Tables[name]; exists {
+			data = append(data, rowsOf(table)...)
+		}
+	}
+	return &rows{data: data}, nil
+}
+
+// sequences answers the query listing the sequences, which takes the expressions of the names to
+// include and exclude as $2 and $3, as the query listing the tables does.
+func (c *Catalog) sequences(args ...any) (pgx.Rows, error) {
+	names := make([]string, 0, len(c.schema.Sequences))
+	for name := range c.schema.Sequences {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(args) > 2 {
+		var err error
+		if names, err = matching(names, args[1].([]string), args[2].([]string)); err != nil {
+			return nil, err
+		}
+	}
+	var data [][]any
+	for _, name := range names {
+		seq := c.schema.Sequences[name]
+		data = append(data, []any{seq.Name, seq.DataType, seq.Start, seq.Increment, seq.Min, seq.Max, seq.Cache, seq.Cycle, seq.Owner})
+	}
+	return &rows{data: data}, nil
+}
+
+// functions answers the query listing the functions, which takes the expressions of the names to
+// include and exclude as $2 and $3, as the query listing the sequences does.
+func (c *Catalog) functions(args ...any) (pgx.Rows, error) {
+	signatures := make([]string, 0, len(c.schema.Functions))
+	for signature := range c.schema.Functions {
+		signatures = append(signatures, signature)
+	}
+	sort.Strings(signatures)
+	var data [][]any
+	for _, signature := range signatures {
+		fn := c.schema.Functions[signature]
+		if len(args) > 2 {
+			kept, err := matching([]string{fn.Name}, args[1].([]string), args[2].([]string))
+			if err != nil {
+				return nil, err
+			}
+			if len(kept) == 0 {
+				continue
+			}
+		}
+		data = append(data, []any{fn.Name, fn.Arguments, fn.Definition, fn.Owner})
+	}
+	return &rows{data: data}, nil
+}
+
+// matching returns the names matching no exclude expression and, if there are any, an include
+// expression.
+//
+// Parameters:
+//   - names: Names to filter
+//   - include: Regular expressions of the names to keep; empty keeps all names
+//   - exclude: Regular expressions of the names to leave out
+//
+// Returns:
+//   - []string: Names kept, in the order of names
+//   - error: An error if an expression is malformed
+func matching(names, include, exclude []string) ([]string, error) {
+	compile := func(expressions []string) ([]*regexp.Regexp, error) {
+		var compiled []*regexp.Regexp
+		for _, expression := range expressions {
+			re, err := regexp.Compile(expression)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression '%s': %w", expression, err)
+			}
+			compiled = append(compiled, re)
+		}
+		return compiled, nil
+	}
+	matches := func(expressions []*regexp.Regexp, name string) bool {
+		return slices.ContainsFunc(expressions, func(re *regexp.Regexp) bool { return re.MatchString(name) })
+	}
+
+	includeRE, err := compile(include)
+	if err != nil {
+		return nil, err
+	}
+	excludeRE, err := compile(exclude)
+	if err != nil {
+		return nil, err
+	}
+	var kept []string
+	for _, name := range names {
+		if (len(includeRE) == 0 || matches(includeRE, name)) && !matches(excludeRE, name) {
+			kept = append(kept, name)
+		}
+	}
+	return kept, nil
+}
+
+// columnRows returns the rows of the columns query for a table.
+func columnRows(table schema.TableInfo) [][]any {
+	var data [][]any
+	for _, col := range table.Columns {
+		nullable, identity := "NO", "NO"
+		if col.Nullable {
+			nullable = "YES"
+		}
+		if col.IsIdentity {
+			identity = "YES"
+		}
+		maxLength := sql.NullInt32{Int32: int32(col.MaxLength), Valid: col.MaxLength > 0}
+		data = append(data, []any{table.Name, col.Name, col.Type, nullable, nullString(col.Default), identity,
+			nullString(col.Comment), col.Compression, maxLength})
+	}
+	return data
+}
+
+// nullString returns a string as a nullable value, NULL if it is empty.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// rows is the result of a catalog query, as pgx.Rows.
+type rows struct {
+	data [][]any // Values of each row
+	next int     // Index of the next row
+}
+
+// Close does nothing, since the rows are held in memory.
+func (r *rows) Close() {}
+
+// Err returns nil, since reading rows from memory cannot fail.
+func (r *rows) Err() error { return nil }
+
+// CommandTag returns an empty command tag.
+func (r *rows) CommandTag() pgconn.CommandTag { return pgconn.CommandTag{} }
+
+// FieldDescriptions returns nil, since the columns are not described.
+func (r *rows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+
+// Next moves to the next row.
+func (r *rows) Next() bool {
+	r.next++
+	return r.next <= len(r.data)
+}
+
+// Values returns the values of the current row.
+func (r *rows) Values() ([]any, error) { return r.data[r.next-1], nil }
+
+// RawValues returns nil, since the values are not encoded.
+func (r *rows) RawValues() [][]byte { return nil }
+
+// Conn returns nil, since the rows do not come from a connection.
+func (r *rows) Conn() *pgx.Conn { return nil }
+
+// Scan copies the values of the current row into dest, which must have the types the schema
+// package scans catalog rows into.
+func (r *rows) Scan(dest ...any) error {
+	row := r.data[r.next-1]
+	if len(dest) != len(row) {
+		return fmt.Errorf("expected %d destinations, got %d", len(row), len(dest))
+	}
+	for i, d := range dest {
+		switch d := d.(type) {
+		case *string:
+			*d = row[i].(string)
+		case *bool:
+			*d = row[i].(bool)
+		case *int64:
+			*d = row[i].(int64)
+		case *[]string:
+			*d = append([]string(nil), row[i].([]string)...)
+		case *[]int32:
+			*d = append([]int32(nil), row[i].([]int32)...)
+		case *sql.NullString:
+			*d = row[i].(sql.NullString)
+		case *sql.NullInt32:
+			*d = row[i].(sql.NullInt32)
+		default:
+			return fmt.Errorf("unsupported destination type %T", d)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+// Package bench generates large synthetic schemas and measures how fetching and comparing them
+// performs, so that performance regressions can be caught and optimizations validated without a
+// database holding hundreds of thousands of tables.
+//
+// The benchmarks themselves live in the _test.go files of the packages they measure (schema,
+// snapshot, and compare), which generate their schemas with this package, and run with go test:
+//
+//	go test -run '^$' -bench 'Compare/Streaming/100000' ./pkg/compare
+package bench
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// Drift controls how the target of a generated pair of schemas differs from its source. Every
+// field gives the interval, in tables, between tables changed in that way; zero disables it.
+type Drift struct {
+	MissingEvery    int // Tables left out of the target
+	TypeChangeEvery int // Tables whose amount column has another type in the target
+	ExtraIndexEvery int // Tables with an extra index in the target
+}
+
+// DefaultDrift changes about one table in a hundred, as in two environments that mostly agree.
+var DefaultDrift = Drift{MissingEvery: 1000, TypeChangeEvery: 100, ExtraIndexEvery: 500}
+
+// TableName returns the name of the i-th generated table. Names sort in the order of i.
+//
+// Parameters:
+//   - i: Index of the table
+//
+// Returns:
+//   - string: Name of the table (e.g., "t000042")
+func TableName(i int) string {
+	return fmt.Sprintf("t%06d", i)
+}
+
+// Table generates the i-th table of a schema: eight columns, a primary key, two indexes, and a
+// foreign key to the previous table, with the changes of drift applied when generating a target.
+//
+// Parameters:
+//   - i: Index of the table
+//   - drift: Changes applied to the table, or nil for a source table
+//
+// Returns:
+//   - schema.TableInfo: Generated table
+func Table(i int, drift *Drift) schema.TableInfo {
+	name := TableName(i)
+	amountType := "numeric(12,2)"
+	if drift != nil && every(i, drift.TypeChangeEvery) {
+		amountType = "double precision"
+	}
+
+	table := schema.TableInfo{
+		Name: name,
+		Columns: []schema.ColumnInfo{
+			{Name: "id", Type: "bigint", IsIdentity: true},
+			{Name: "tenant_id", Type: "integer"},
+			{Name: "name", Type: "character varying(100)", MaxLength: 100},
+			{Name: "status", Type: "text", Nullable: true, Default: "'new'::text"},
+			{Name: "amount", Type: amountType, Nullable: true},
+			{Name: "payload", Type: "jsonb", Nullable: true},
+			{Name: "parent_id", Type: "bigint", Nullable: true},
+			{Name: "created_at", Type: "timestamp with time zone", Default: "now()"},
+		},
+		PrimaryKeys:    []string{"id"},
+		PrimaryKeyName: name + "_pkey",
+		Indexes: []schema.IndexInfo{
+			{Name: name + "_pkey", Columns: []string{"id"}, Unique: true, Method: "btree"},
+			{Name: name + "_tenant_created_idx", Columns: []string{"tenant_id", "created_at"}, Method: "btree"},
+			{Name: name + "_name_key", Columns: []string{"name"}, Unique: true, Method: "btree"},
+		},
+		Owner: "app",
+	}
+	if i > 0 {
+		table.ForeignKeys = []schema.ForeignKeyInfo{{
+			Name:              name + "_parent_id_fkey",
+			Columns:           []string{"parent_id"},
+			ReferencedTable:   TableName(i - 1),
+			ReferencedColumns: []string{"id"},
+			Definition:        fmt.Sprintf("FOREIGN KEY (parent_id) REFERENCES %s(id)", TableName(i-1)),
+		}}
+	}
+	if drift != nil && every(i, drift.ExtraIndexEvery) {
+		table.Indexes = append(table.Indexes, schema.IndexInfo{Name: name + "_status_idx", Columns: []string{"status"}, Method: "btree"})
+	}
+	return table
+}
+
+// Generate builds a schema of generated tables in memory.
+//
+// Parameters:
+//   - tables: Number of tables
+//   - drift: Changes applied to the tables, or nil for a source schema
+//
+// Returns:
+//   - *schema.Schema: Generated schema
+func Generate(tables int, drift *Drift) *schema.Schema {
+	s := emptySchema()
+	for i := 0; i < tables; i++ {
+		if drift != nil && every(i, drift.MissingEvery) {
+			continue
+		}
+		s.Tables[TableName(i)] = Table(i, drift)
+	}
+	return s
+}
+
+// Stream is a schema.TableStream generating its tables as they are read, so that the memory
+// the comparison itself needs can be measured.
+type Stream struct {
+	outline *schema.Schema // Generated tables, with their table-level properties only
+	tables  int            // Number of tables generated, including those left out by drift
+	drift   *Drift         // Changes applied to the tables, or nil for a source schema
+	next    int            // Index of the next table to generate
+}
+
+// NewStream returns a stream generating the tables of Generate one at a time.
+//
+// Parameters:
+//   - tables: Number of tables
+//   - drift: Changes applied to the tables, or nil for a source schema
+//
+// Returns:
+//   - *Stream: Stream of the generated tables
+func NewStream(tables int, drift *Drift) *Stream {
+	return &Stream{outline: outline(tables, drift), tables: tables, drift: drift}
+}
+
+// Outline returns the generated tables with their table-level properties only.
+func (s *Stream) Outline() *schema.Schema {
+	return s.outline
+}
+
+// Next generates the next table.
+func (s *Stream) Next(ctx context.Context) (schema.TableInfo, bool, error) {
+	for ; s.next < s.tables; s.next++ {
+		if s.drift != nil && every(s.next, s.drift.MissingEvery) {
+			continue
+		}
+		table := Table(s.next, s.drift)
+		s.next++
+		return table, true, nil
+	}
+	return schema.TableInfo{}, false, nil
+}
+
+// outline builds a schema holding the generated tables with their table-level properties only.
+//
+// Parameters:
+//   - tables: Number of tables
+//   - drift: Changes applied to the tables, or nil for a source schema
+//
+// Returns:
+//   - *schema.Schema: Outline of the generated schema
+func outline(tables int, drift *Drift) *schema.Schema {
+	s := emptySchema()
+	for i := 0; i < tables; i++ {
+		if drift != nil && every(i, drift.MissingEvery) {
+			continue
+		}
+		name := TableName(i)
+		s.Tables[name] = schema.TableInfo{Name: name, Owner: "app"}
+	}
+	return s
+}
+
+// emptySchema returns a schema without tables, as if fetched from a PostgreSQL 16 server.
+func emptySchema() *schema.Schema {
+	s := schema.NewSchema()
+	s.Name = schema.DefaultSchemaName
+	s.ServerVersion = 160000
+	return s
+}
+
+// every reports whether the i-th table is changed by a drift setting with the given interval.
+func every(i, interval int) bool {
+	return interval > 0 && i%interval == interval-1
+}
@@ -0,0 +1,250 @@
+// Package cache keeps recently fetched schemas on disk, so that comparisons repeated within a
+// short time (for example, while investigating an incident) do not query the database catalogs
+// every time.
+//
+// Entries are binary snapshot files named after a hash of the connection string and of the
+// options the schema was fetched with, so the cache never stores connection strings or
+// passwords in the clear.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+	"github.com/guriandoro/pg_schema_check/pkg/snapshot"
+)
+
+// Cache is a directory of cached schemas.
+type Cache struct {
+	Dir string        // Directory holding the entries; created when the first entry is written
+	TTL time.Duration // How long an entry stays fresh; with zero or negative, entries are stored but never fresh
+}
+
+// DefaultDir returns the directory used when none is configured: schema-check under the user's
+// cache directory (e.g., ~/.cache/schema-check on Linux).
+//
+// Returns:
+//   - string: Default cache directory
+//   - error: An error if the user's cache directory cannot be determined
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("error locating cache directory: %w", err)
+	}
+	return filepath.Join(dir, "schema-check"), nil
+}
+
+// Key identifies the schema fetched from a database with some options.
+//
+// Parameters:
+//   - connString: Connection string of the database
+//   - opts: Options the schema is fetched with; only those changing what is fetched are part of the key
+//
+// Returns:
+//   - string: Key of the entry, safe to use as a file name
+func Key(connString string, opts schema.FetchOptions) string {
+	schemaName := opts.SchemaName
+	if schemaName == "" {
+		schemaName = schema.DefaultSchemaName
+	}
+	key := fmt.Sprintf("%s\x00%s\x00%s\x00%d", connString, schemaName, opts.Dialect, opts.ServerVersion)
+
+	// Schemas fetched with table filters lack the tables left out
+	if len(opts.IncludeTables) > 0 || len(opts.ExcludeTables) > 0 {
+		key += fmt.Sprintf("\x00%q\x00%q", opts.IncludeTables, opts.ExcludeTables)
+	}
+	// Schemas fetched without row counts lack them
+	if opts.RowCounts != "" {
+		key += "\x00rows=" + opts.RowCounts
+	}
+	// Schemas fetched without data checksums, or with other settings, lack them
+	if opts.DataChecksums != "" {
+		key += fmt.Sprintf("\x00data=%s\x00%d\x00%v", opts.DataChecksums, opts.DataSampleRows, opts.DataColumns)
+	}
+	// Schemas fetched without sizes lack them
+	if opts.Sizes {
+		key += "\x00sizes"
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// path returns the file of an entry.
+func (c Cache) path(key string) string {
+	return filepath.Join(c.Dir, key+".snap")
+}
+
+// Load returns the entry cached under a key, however old it is.
+//
+// Parameters:
+//   - key: Key of the entry, as returned by Key
+//
+// Returns:
+//   - *snapshot.Snapshot: Cached entry, or nil if there is none
+//   - error: Any error that occurred while reading an existing entry
+func (c Cache) Load(key string) (*snapshot.Snapshot, error) {
+	snap, err := snapshot.ReadFile(c.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// Get returns the schema cached under a key, if it is still fresh.
+//
+// Parameters:
+//   - key: Key of the entry, as returned by Key
+//
+// Returns:
+//   - *schema.Schema: Cached schema, or nil if there is no fresh entry
+//   - time.Duration: Age of the entry
+//   - error: Any error that occurred while reading an existing entry
+func (c Cache) Get(key string) (*schema.Schema, time.Duration, error) {
+	if c.TTL <= 0 {
+		return nil, 0, nil
+	}
+
+	snap, err := c.Load(key)
+	if snap == nil || err != nil {
+		return nil, 0, err
+	}
+
+	age := time.Since(snap.CreatedAt)
+	if age > c.TTL {
+		return nil, age, nil
+	}
+	return snap.Schema, age, nil
+}
+
+// Put stores a schema under a key, replacing any previous entry. Schemas with tables that could
+// not be fetched are not stored, so that a transient failure is not served from the cache.
+//
+// Parameters:
+//   - key: Key of the entry, as returned by Key
+//   - s: Schema to store
+//
+// Returns:
+//   - error: Any error that occurred while writing the entry
+func (c Cache) Put(key string, s *schema.Schema) error {
+	if len(s.Errors) > 0 {
+		return nil
+	}
+	return c.write(key, ".snap", func(w io.Writer) error {
+		return snapshot.Write(w, s, snapshot.FormatBinary)
+	})
+}
+
+// Mark returns the position recorded with SetMark for an entry, such as the last change log
+// entry the cached schema reflects.
+//
+// Parameters:
+//   - key: Key of the entry, as returned by Key
+//
+// Returns:
+//   - int64: Recorded position
+//   - bool: False if no position was recorded or it cannot be read
+func (c Cache) Mark(key string) (int64, bool) {
+	data, err := os.ReadFile(filepath.Join(c.Dir, key+".mark"))
+	if err != nil {
+		return 0, false
+	}
+	mark, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return mark, true
+}
+
+// SetMark records a position along with an entry. It should be called after Put, with a
+// position taken before the schema was fetched, so that the position never gets ahead of the
+// cached schema.
+//
+// Parameters:
+//   - key: Key of the entry, as returned by Key
+//   - mark: Position to record
+//
+// Returns:
+//   - error: Any error that occurred while writing the position
+func (c Cache) SetMark(key string, mark int64) error {
+	return c.write(key, ".mark", func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, mark)
+		return err
+	})
+}
+
+// write replaces a file of an entry. The file is written to a temporary file first, so that
+// concurrent runs never read a partially written one.
+//
+// Parameters:
+//   - key: Key of the entry
+//   - ext: Extension of the file (e.g., ".snap")
+//   - fn: Function writing the contents of the file
+//
+// Returns:
+//   - error: Any error that occurred while writing the file
+func (c Cache) write(key, ext string, fn func(w io.Writer) error) error {
+	if err := os.MkdirAll(c.Dir, 0o700); err != nil {
+		return fmt.Errorf("error creating cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(c.Dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating cache entry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := fn(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(c.Dir, key+ext)); err != nil {
+		return fmt.Errorf("error writing cache entry: %w", err)
+	}
+	return nil
+}
+
+// Fetcher is a schema.Fetcher that serves a schema from the cache while it is fresh, and
+// otherwise fetches it and caches it.
+type Fetcher struct {
+	Cache   Cache          // Cache the schema is kept in
+	Key     string         // Key of the schema, as returned by Key
+	Fetcher schema.Fetcher // Fetcher used when the cache has no fresh entry
+	Refresh bool           // Whether to fetch the schema even if the cache has a fresh entry, refreshing it
+
+	OnHit func(age time.Duration) // Called when the schema is served from the cache, if not nil
+}
+
+// Fetch returns the cached schema if it is fresh, and otherwise fetches and caches it. Errors
+// reading or writing the cache are not fatal: the schema is then fetched, or returned uncached.
+func (f Fetcher) Fetch(ctx context.Context) (*schema.Schema, error) {
+	if !f.Refresh {
+		if cached, age, err := f.Cache.Get(f.Key); err == nil && cached != nil {
+			if f.OnHit != nil {
+				f.OnHit(age)
+			}
+			return cached, nil
+		}
+	}
+
+	fetched, err := f.Fetcher.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f.Cache.Put(f.Key, fetched)
+	return fetched, nil
+}
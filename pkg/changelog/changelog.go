@@ -0,0 +1,345 @@
+// Package changelog records the DDL run on a database with event triggers, so that a schema
+// fetched earlier can be brought up to date by re-reading only the tables whose DDL changed.
+//
+// Install creates the schema_check schema holding the ddl_log table and the event triggers
+// appending to it; it needs a superuser, as event triggers do. Fetcher then uses the log to
+// refresh a schema kept in a cache.Cache, and Listen and Wait let a client follow the changes
+// as they are committed.
+package changelog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrNotInstalled is returned (wrapped) when the change log is not installed in the database.
+var ErrNotInstalled = errors.New("change log not installed")
+
+// Execer is the part of a PostgreSQL connection that installing the change log needs. It is
+// satisfied by *pgx.Conn, *pgxpool.Pool, and pgx.Tx.
+type Execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// installSQL creates the change log. Event triggers run as part of the DDL statement that fired
+// them, so a statement that fails or is rolled back leaves no entry behind. Objects are mapped to
+// the table they belong to while they still exist; for dropped objects, only their names are left.
+// Each entry also records the role that ran the statement and the text of the statement (the
+// whole query string, for statements sent together), and notifies Channel with its schema once
+// the statement's transaction commits.
+const installSQL = `
+CREATE SCHEMA IF NOT EXISTS schema_check;
+
+CREATE TABLE IF NOT EXISTS schema_check.ddl_log (
+	id bigserial PRIMARY KEY,
+	logged_at timestamptz NOT NULL DEFAULT now(),
+	command_tag text NOT NULL,
+	object_type text,
+	schema_name text,
+	object_identity text,
+	object_name text,
+	table_name text
+);
+
+-- Columns added after the first release, for logs created by it
+ALTER TABLE schema_check.ddl_log
+	ADD COLUMN IF NOT EXISTS role_name text,
+	ADD COLUMN IF NOT EXISTS statement text;
+
+CREATE INDEX IF NOT EXISTS ddl_log_logged_at_idx ON schema_check.ddl_log (logged_at);
+
+CREATE OR REPLACE FUNCTION schema_check.log_ddl() RETURNS event_trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+	IF TG_EVENT = 'sql_drop' THEN
+		INSERT INTO schema_check.ddl_log (command_tag, object_type, schema_name, object_identity, object_name, table_name, role_name, statement)
+		SELECT TG_TAG, d.object_type, d.schema_name, d.object_identity, d.object_name,
+			CASE
+				WHEN d.object_type IN ('table', 'view', 'materialized view', 'foreign table') THEN d.object_name
+				WHEN d.object_type IN ('table column', 'table constraint', 'trigger', 'rule', 'policy') THEN d.address_names[2]
+			END,
+			session_user, current_query()
+		FROM pg_event_trigger_dropped_objects() d;
+	ELSE
+		INSERT INTO schema_check.ddl_log (command_tag, object_type, schema_name, object_identity, table_name, role_name, statement)
+		SELECT c.command_tag, c.object_type, c.schema_name, c.object_identity,
+			CASE
+				WHEN c.classid = 'pg_class'::regclass THEN (
+					SELECT coalesce(t.relname, r.relname)
+					FROM pg_class r
+					LEFT JOIN pg_index i ON i.indexrelid = r.oid
+					LEFT JOIN pg_class t ON t.oid = i.indrelid
+					WHERE r.oid = c.objid)
+				WHEN c.classid = 'pg_constraint'::regclass THEN (
+					SELECT r.relname
+					FROM pg_constraint k
+					JOIN pg_class r ON r.oid = k.conrelid
+					WHERE k.oid = c.objid)
+				WHEN c.classid = 'pg_trigger'::regclass THEN (
+					SELECT r.relname
+					FROM pg_trigger g
+					JOIN pg_class r ON r.oid = g.tgrelid
+					WHERE g.oid = c.objid)
+			END,
+			session_user, current_query()
+		FROM pg_event_trigger_ddl_commands() c;
+	END IF;
+END
+$$;
+
+CREATE OR REPLACE FUNCTION schema_check.notify_ddl() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+	PERFORM pg_notify('schema_check_ddl', coalesce(NEW.schema_name, ''));
+	RETURN NULL;
+END
+$$;
+
+DROP TRIGGER IF EXISTS schema_check_notify ON schema_check.ddl_log;
+CREATE TRIGGER schema_check_notify AFTER INSERT ON schema_check.ddl_log
+	FOR EACH ROW EXECUTE PROCEDURE schema_check.notify_ddl();
+
+DROP EVENT TRIGGER IF EXISTS schema_check_ddl_command_end;
+CREATE EVENT TRIGGER schema_check_ddl_command_end ON ddl_command_end
+	EXECUTE PROCEDURE schema_check.log_ddl();
+
+DROP EVENT TRIGGER IF EXISTS schema_check_sql_drop;
+CREATE EVENT TRIGGER schema_check_sql_drop ON sql_drop
+	EXECUTE PROCEDURE schema_check.log_ddl();
+`
+
+// uninstallSQL removes the change log and everything it recorded.
+const uninstallSQL = `
+DROP EVENT TRIGGER IF EXISTS schema_check_ddl_command_end;
+DROP EVENT TRIGGER IF EXISTS schema_check_sql_drop;
+DROP SCHEMA IF EXISTS schema_check CASCADE;
+`
+
+// Install creates the change log, or updates the event triggers of an existing one. Entries
+// already logged are kept. The role must be a superuser.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//
+// Returns:
+//   - error: Any error that occurred while creating the objects
+func Install(ctx context.Context, conn Execer) error {
+	if _, err := conn.Exec(ctx, installSQL); err != nil {
+		return fmt.Errorf("error installing change log: %w", err)
+	}
+	return nil
+}
+
+// Uninstall removes the event triggers and the schema_check schema, with the entries logged.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//
+// Returns:
+//   - error: Any error that occurred while dropping the objects
+func Uninstall(ctx context.Context, conn Execer) error {
+	if _, err := conn.Exec(ctx, uninstallSQL); err != nil {
+		return fmt.Errorf("error uninstalling change log: %w", err)
+	}
+	return nil
+}
+
+// Change is a DDL change recorded in the change log.
+type Change struct {
+	ID             int64     `json:"id"`                        // Position of the entry in the log
+	LoggedAt       time.Time `json:"logged_at"`                 // When the DDL statement ran
+	CommandTag     string    `json:"command_tag"`               // Statement that made the change (e.g., "ALTER TABLE")
+	ObjectType     string    `json:"object_type,omitempty"`     // Kind of object changed (e.g., "table", "index", "table column")
+	SchemaName     string    `json:"schema_name,omitempty"`     // PostgreSQL schema of the object; empty for objects outside schemas
+	ObjectIdentity string    `json:"object_identity,omitempty"` // Qualified name of the object (e.g., "public.orders")
+	ObjectName     string    `json:"object_name,omitempty"`     // Name of the object, for dropped objects only
+	Table          string    `json:"table,omitempty"`           // Name of the table the object is or belongs to; empty if unknown
+	Role           string    `json:"role,omitempty"`            // Role that ran the statement (session_user); empty for entries logged by earlier releases
+	Statement      string    `json:"statement,omitempty"`       // Text of the statement; empty for entries logged by earlier releases
+}
+
+// Position returns the position of the last entry of the change log, or zero if it is empty.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//
+// Returns:
+//   - int64: Position of the last entry
+//   - error: An error wrapping ErrNotInstalled if the change log is not installed, or any other query error
+func Position(ctx context.Context, conn schema.Querier) (int64, error) {
+	rows, err := conn.Query(ctx, `SELECT coalesce(max(id), 0) FROM schema_check.ddl_log`)
+	if err != nil {
+		return 0, logError(err)
+	}
+	defer rows.Close()
+
+	var position int64
+	if rows.Next() {
+		if err := rows.Scan(&position); err != nil {
+			return 0, fmt.Errorf("error scanning change log position: %w", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, logError(err)
+	}
+	return position, nil
+}
+
+// Since returns the changes logged after a position to objects of a PostgreSQL schema.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - after: Position after which changes are returned, as returned by Position
+//   - schemaName: PostgreSQL schema whose changes are returned
+//
+// Returns:
+//   - []Change: Changes, in the order they were made
+//   - error: An error wrapping ErrNotInstalled if the change log is not installed, or any other query error
+func Since(ctx context.Context, conn schema.Querier, after int64, schemaName string) ([]Change, error) {
+	return queryChanges(ctx, conn, `id > $1 AND schema_name = $2`, after, schemaName)
+}
+
+// Between returns the changes logged within a time window, for a report of what changed in a
+// database over that time rather than between two databases.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - from: Earliest time of the changes returned; the zero time returns them from the first
+//   - to: Time before which the changes are returned; the zero time returns them up to the last
+//   - schemaName: PostgreSQL schema whose changes are returned; empty returns the changes of every schema
+//
+// Returns:
+//   - []Change: Changes, in the order they were made
+//   - error: An error wrapping ErrNotInstalled if the change log is not installed, or any other query error
+func Between(ctx context.Context, conn schema.Querier, from, to time.Time, schemaName string) ([]Change, error) {
+	// Open ends of the window are passed as NULL
+	bound := func(t time.Time) *time.Time {
+		if t.IsZero() {
+			return nil
+		}
+		return &t
+	}
+	return queryChanges(ctx, conn, `($1::timestamptz IS NULL OR logged_at >= $1)
+			AND ($2::timestamptz IS NULL OR logged_at < $2)
+			AND ($3 = '' OR schema_name = $3)`, bound(from), bound(to), schemaName)
+}
+
+// queryChanges reads the entries of the change log matching a condition.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - where: Condition of the entries returned, over the columns of ddl_log
+//   - args: Arguments of the condition
+//
+// Returns:
+//   - []Change: Changes, in the order they were made
+//   - error: An error wrapping ErrNotInstalled if the change log is not installed, or any other query error
+func queryChanges(ctx context.Context, conn schema.Querier, where string, args ...any) ([]Change, error) {
+	rows, err := conn.Query(ctx, `
+		SELECT id, logged_at, command_tag, coalesce(object_type, ''), coalesce(schema_name, ''),
+			coalesce(object_identity, ''), coalesce(object_name, ''), coalesce(table_name, ''),
+			coalesce(role_name, ''), coalesce(statement, '')
+		FROM schema_check.ddl_log
+		WHERE `+where+`
+		ORDER BY id`, args...)
+	if err != nil {
+		return nil, logError(err)
+	}
+	defer rows.Close()
+
+	var changes []Change
+	for rows.Next() {
+		var change Change
+		if err := rows.Scan(&change.ID, &change.LoggedAt, &change.CommandTag, &change.ObjectType, &change.SchemaName,
+			&change.ObjectIdentity, &change.ObjectName, &change.Table, &change.Role, &change.Statement); err != nil {
+			return nil, fmt.Errorf("error scanning change: %w", err)
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, logError(err)
+	}
+	return changes, nil
+}
+
+// logError wraps an error reading the change log, in ErrNotInstalled if the log does not exist.
+func logError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && (pgErr.Code == "42P01" || pgErr.Code == "3F000") {
+		return fmt.Errorf("%w: %w", ErrNotInstalled, err)
+	}
+	return fmt.Errorf("error reading change log: %w", err)
+}
+
+// harmlessKinds are the kinds of objects whose changes never alter the tables of the schema
+// model, so they can be ignored when they cannot be tied to a table.
+var harmlessKinds = []string{
+	"aggregate", "cast", "conversion", "default acl", "event trigger", "foreign-data wrapper",
+	"function", "language", "operator", "operator class", "operator family", "policy",
+	"procedure", "publication", "publication relation", "rule", "server", "statistics object",
+	"subscription", "text search configuration", "text search dictionary", "text search parser",
+	"text search template", "transform", "user mapping",
+}
+
+// ChangedTables works out which tables of a previously fetched schema changes may have altered.
+// Changes are tied to tables by the change log where possible, and dropped indexes by their name
+// in the previous schema. Changes that cannot be tied to a table but may alter tables (e.g., to
+// types or domains used by columns) cannot be narrowed down.
+//
+// Parameters:
+//   - base: Schema fetched before the changes
+//   - changes: Changes made since, as returned by Since
+//
+// Returns:
+//   - map[string]bool: Names of the tables that may have changed
+//   - bool: False if the changes cannot be narrowed down to tables, so that the whole schema must be fetched again
+func ChangedTables(base *schema.Schema, changes []Change) (map[string]bool, bool) {
+	changed := make(map[string]bool)
+	for _, change := range changes {
+		switch {
+		case change.Table != "":
+			changed[change.Table] = true
+		case change.ObjectType == "index":
+			table, found := indexTable(base, change.ObjectName)
+			if !found {
+				return nil, false
+			}
+			changed[table] = true
+		case !slices.Contains(harmlessKinds, change.ObjectType):
+			return nil, false
+		}
+	}
+	return changed, true
+}
+
+// indexTable finds the table an index belongs to in a schema.
+//
+// Parameters:
+//   - s: Schema to search
+//   - indexName: Name of the index
+//
+// Returns:
+//   - string: Name of the table the index belongs to
+//   - bool: False if no table has an index with that name
+func indexTable(s *schema.Schema, indexName string) (string, bool) {
+	for name, table := range s.Tables {
+		for _, idx := range table.Indexes {
+			if idx.Name == indexName {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,113 @@
+package changelog
+
+import (
+	"context"
+	"errors"
+
+	"github.com/guriandoro/pg_schema_check/pkg/cache"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// Fetcher is a schema.Fetcher that keeps the schema of a database up to date in a cache using
+// the change log: the tables whose DDL changed since the cached schema was fetched are read
+// again, and the details of the others are reused. The whole schema is fetched instead when
+// there is no cached schema, when the change log is not installed or was reset, or when its
+// changes cannot be narrowed down to tables.
+type Fetcher struct {
+	Conn    schema.Querier      // Active PostgreSQL connection or pool
+	Options schema.FetchOptions // Options controlling what is fetched
+	Cache   cache.Cache         // Cache the schema and its change log position are kept in
+	Key     string              // Key of the schema, as returned by cache.Key
+	Refresh bool                // Whether to fetch the whole schema even if the cached one could be updated
+
+	// Hooks reporting how the schema was fetched. Either can be nil.
+	OnIncremental func(changed, reused int) // Called when only the changed tables are read
+	OnFull        func(reason string)       // Called when the whole schema is fetched, with the reason
+}
+
+// Fetch brings the cached schema up to date, or fetches the whole schema, and caches the result
+// along with the position of the change log it reflects.
+func (f Fetcher) Fetch(ctx context.Context) (*schema.Schema, error) {
+	// The position is taken before fetching, so that changes made during the fetch are read
+	// again next time
+	installed := true
+	position, err := Position(ctx, f.Conn)
+	if errors.Is(err, ErrNotInstalled) {
+		installed = false
+	} else if err != nil {
+		return nil, err
+	}
+
+	opts := f.Options
+	schemaName := opts.SchemaName
+	if schemaName == "" {
+		schemaName = schema.DefaultSchemaName
+	}
+
+	reason := ""
+	base, _ := f.Cache.Load(f.Key)
+	mark, marked := f.Cache.Mark(f.Key)
+	switch {
+	case !installed:
+		reason = "the change log is not installed"
+	case f.Refresh:
+		reason = "a refresh was requested"
+	case base == nil || !marked:
+		reason = "no cached schema to update"
+	case position < mark:
+		reason = "the change log was reset"
+	default:
+		changes, err := Since(ctx, f.Conn, mark, schemaName)
+		if err != nil {
+			return nil, err
+		}
+		changed, narrowed := ChangedTables(base.Schema, changes)
+		if !narrowed {
+			reason = "changes cannot be tied to tables"
+			break
+		}
+		opts.Reuse = make(map[string]schema.TableInfo, len(base.Schema.Tables))
+		for name, table := range base.Schema.Tables {
+			if !changed[name] {
+				opts.Reuse[name] = table
+			}
+		}
+	}
+
+	fetched, err := schema.Fetch(ctx, f.Conn, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Catalogs are read differently by other server versions, so details read from an older
+	// server are not mixed with fresh ones
+	if opts.Reuse != nil && fetched.ServerVersion != base.Schema.ServerVersion {
+		reason = "the server version changed"
+		opts.Reuse = nil
+		fetched, err = schema.Fetch(ctx, f.Conn, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Reuse != nil {
+		reused := 0
+		for name := range fetched.Tables {
+			if _, ok := opts.Reuse[name]; ok {
+				reused++
+			}
+		}
+		if f.OnIncremental != nil {
+			f.OnIncremental(len(fetched.Tables)-reused, reused)
+		}
+	} else if f.OnFull != nil {
+		f.OnFull(reason)
+	}
+
+	// A schema with tables that could not be read is not cached, and neither is its position,
+	// which would otherwise be recorded against the previous schema
+	if len(fetched.Errors) == 0 && f.Cache.Put(f.Key, fetched) == nil && installed {
+		f.Cache.SetMark(f.Key, position)
+	}
+	return fetched, nil
+}
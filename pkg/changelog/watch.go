@@ -0,0 +1,52 @@
+package changelog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Channel is the channel notified of each entry of the change log, with the PostgreSQL schema of
+// the object changed as payload (empty for objects outside schemas, such as extensions).
+// Notifications are delivered once the transaction of the DDL statement commits, and those sent
+// by one transaction with the same payload are delivered once.
+const Channel = "schema_check_ddl"
+
+// Listen subscribes a connection to the notifications of the change log. The connection must be
+// a session of its own: notifications are not delivered through a pooler in transaction mode.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Connection to subscribe, then passed to Wait
+//
+// Returns:
+//   - error: Any error that occurred while subscribing
+func Listen(ctx context.Context, conn *pgx.Conn) error {
+	if _, err := conn.Exec(ctx, "LISTEN "+Channel); err != nil {
+		return fmt.Errorf("error listening for DDL notifications: %w", err)
+	}
+	return nil
+}
+
+// Wait waits for the next notification of the change log on a connection subscribed by Listen.
+// The connection cannot be used for anything else while it waits.
+//
+// Parameters:
+//   - ctx: Context whose end stops the wait
+//   - conn: Connection subscribed by Listen
+//
+// Returns:
+//   - string: PostgreSQL schema of the object changed; empty for objects outside schemas
+//   - error: Any error that occurred while waiting, including the end of ctx
+func Wait(ctx context.Context, conn *pgx.Conn) (string, error) {
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return "", fmt.Errorf("error waiting for DDL notifications: %w", err)
+		}
+		if notification.Channel == Channel {
+			return notification.Payload, nil
+		}
+	}
+}
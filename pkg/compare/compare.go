@@ -1,66 +1,545 @@
 // Package compare provides functionality to compare PostgreSQL database schemas and identify differences
 // between them. It can detect differences in tables, columns, primary keys, indexes, and foreign keys.
+//
+// Use CompareSchemas with an Options value to compare two schemas, then ApplySeverityOverrides to
+// adjust the severity of the differences found. Besides the exported types and functions, the
+// names and codes of difference types are part of the supported API (see the root schemacheck
+// package): a released type keeps its name, code, and meaning, and new types may be added.
 package compare
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
 
-	"github.com/agustin/postgres_schema_check/pkg/schema"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+	"github.com/guriandoro/pg_schema_check/pkg/sqlnorm"
 )
 
 // Difference represents a single difference found between two database schemas.
-// It includes the type of difference, the affected table, and a human-readable description.
+// Besides the type of difference, the affected table, and a human-readable description, it
+// carries the affected object and the values on each side as separate fields, so that
+// programmatic consumers never have to parse the description.
 type Difference struct {
-	Type        string // Type of difference (e.g., "MissingTable", "ColumnTypeMismatch")
-	Table       string // Name of the table where the difference was found
-	Description string // Human-readable description of the difference
+	Type        string `json:"type"`                   // Type of difference (e.g., "MissingTable", "ColumnTypeMismatch")
+	Table       string `json:"table"`                  // Name of the table where the difference was found
+	Description string `json:"description"`            // Human-readable description of the difference
+	Severity    string `json:"severity"`               // Severity of the difference (e.g., "error", "warning")
+	ObjectKind  string `json:"object_kind"`            // Kind of object the difference is about (e.g., "table", "column")
+	SchemaName  string `json:"schema_name"`            // PostgreSQL schema the object belongs to
+	ObjectName  string `json:"object_name"`            // Name of the object the difference is about, or of its table for sub-objects
+	SubObject   string `json:"sub_object,omitempty"`   // Name of the sub-object (column, index, constraint) the difference is about, if any
+	SourceValue string `json:"source_value,omitempty"` // Value of the differing attribute in the source, if applicable
+	TargetValue string `json:"target_value,omitempty"` // Value of the differing attribute in the target, if applicable
+	Parent      string `json:"parent,omitempty"`       // Key of the difference this one results from (e.g., the missing table an FK refers to), if any
+	Similar     string `json:"similar,omitempty"`      // Objects of the other side most similar to an object missing or extra, with their similarity (e.g., "clients (83%)"), with Options.SimilarMatches
+	Size        int64  `json:"size,omitempty"`         // Bytes on disk the change reconciling the difference rewrites or scans, when the schema being changed has sizes (see schema.Schema.Sizes)
+	Bloat       int64  `json:"bloat,omitempty"`        // Estimated bytes of bloat of the table of Size, if known
+}
+
+// Kinds of object a difference can be about.
+const (
+	KindTable            = "table"             // A table and its table-level properties
+	KindColumn           = "column"            // A column of a table
+	KindPrimaryKey       = "primary_key"       // The primary key of a table
+	KindIndex            = "index"             // An index of a table
+	KindForeignKey       = "foreign_key"       // A foreign key constraint of a table
+	KindView             = "view"              // A view and its definition
+	KindMaterializedView = "materialized_view" // A materialized view, its definition, and whether it is populated
+	KindSequence         = "sequence"          // A sequence and its options
+	KindTrigger          = "trigger"           // A trigger of a table or view
+	KindFunction         = "function"          // A function or procedure and its definition
+	KindFeature          = "feature"           // A feature of the database server (e.g., partitioning)
+)
+
+// differenceTypes lists every type of difference that CompareSchemas can report, including
+// the types declared by comparators added with Register; it is guarded by registryMu.
+var differenceTypes = typeNames()
+
+// DifferenceTypes returns every type of difference that CompareSchemas can report, including
+// the types declared by comparators added with Register. The built-in types are documented by
+// DescribeType.
+//
+// Returns:
+//   - []string: Copy of the known types, built-in types first, then in registration order
+func DifferenceTypes() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return append([]string(nil), differenceTypes...)
+}
+
+// IsKnownType reports whether the given string is one of the DifferenceTypes.
+//
+// Parameters:
+//   - diffType: Difference type to check
+//
+// Returns:
+//   - bool: True if CompareSchemas can report differences of this type
+func IsKnownType(diffType string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return isKnownTypeLocked(diffType)
+}
+
+// isKnownTypeLocked reports whether a difference type is known; the caller must hold registryMu.
+func isKnownTypeLocked(diffType string) bool {
+	for _, known := range differenceTypes {
+		if known == diffType {
+			return true
+		}
+	}
+	return false
+}
+
+// Column attribute checks that can be skipped for individual tables.
+const (
+	CheckType        = "type"        // Compare column data types
+	CheckNullable    = "nullable"    // Compare column nullability
+	CheckDefault     = "default"     // Compare column default values
+	CheckIdentity    = "identity"    // Compare column identity settings
+	CheckCompression = "compression" // Compare column compression methods
+)
+
+// ColumnChecks lists every column attribute check that can be skipped.
+var ColumnChecks = []string{CheckType, CheckNullable, CheckDefault, CheckIdentity, CheckCompression}
+
+// Directions of a comparison, deciding which side is treated as authoritative.
+const (
+	DirectionBoth           = "both"             // Report drift on both sides (the default)
+	DirectionSourceToTarget = "source-to-target" // Source is authoritative: report what the target lacks or defines differently
+	DirectionTargetToSource = "target-to-source" // Target is authoritative: report what the source lacks or defines differently
+)
+
+// Options controls which optional checks CompareSchemas performs. An Options value can be
+// passed to CompareSchemas directly, or built up with the With* functions (see Option).
+type Options struct {
+	CompareOwners  bool                // Whether to report tables, views, sequences, and functions owned by different roles
+	Tables         []TableOptions      // Per-table adjustments, applied to every table matching their pattern
+	Direction      string              // Which side is authoritative; empty means DirectionBoth
+	IgnoreCase     bool                // Whether object names are compared case-insensitively
+	IgnoreDefaults bool                // Whether column default values are left out of the comparison
+	TypeNormalizer func(string) string // Function mapping column data types before they are compared; nil compares them as fetched
+	SeverityMap    map[string]string   // Severities keyed by difference type, applied as by ApplySeverityOverrides
+	Concurrency    int                 // Number of tables compared at once by the per-table comparators; zero or one compares them one at a time
+
+	// Thresholds of the row counts of tables (see schema.Schema.RowCounts) above which a table is
+	// reported with RowCountMismatch: the counts must differ by more than RowCountMinDifference
+	// rows, and by more than RowCountTolerance (e.g., 0.1 for 10%) of the larger count. Zero
+	// thresholds report every difference. Tables not counted on both sides are never reported.
+	RowCountTolerance     float64
+	RowCountMinDifference int64
+
+	// Renames of tables and columns. With DetectRenames, a table or column missing on one side
+	// and a near-identical one extra on the other are reported as a PossibleTableRename or
+	// PossibleColumnRename instead of two unrelated differences. Renames confirms renames, which
+	// are then reported as a TableRename or ColumnRename that the sync SQL performs: it maps the
+	// old name of a table, or the old name of a column qualified by its table ("orders.cust_id"),
+	// to its new name. The old name is the one in the schema being changed: the target, or the
+	// source with DirectionTargetToSource. Only column renames are detected in streamed comparisons.
+	DetectRenames bool
+	Renames       map[string]string
+
+	// Number of objects of the other side most similar to each table, column, index, or foreign
+	// key missing or extra that are listed in Difference.Similar and in its description, to help
+	// spot renames, copies, and near-duplicates. Zero lists none. Streamed comparisons, which hold
+	// one table of each side at a time, list no similar tables.
+	SimilarMatches int
+
+	// Filter removes objects from copies of the schemas before they are compared (e.g., with the
+	// functions of package filter). When tables are streamed, it is called with the outlines of
+	// the schemas first, then with each pair of tables in schemas of their own. It can be nil.
+	Filter func(source, target *schema.Schema) error
+
+	// Hooks called as the comparison progresses, so that embedding applications can report
+	// progress and stream differences to their own UIs. Any of them can be nil.
+	OnPhaseStart func(phase string)    // Called before each comparator runs, with its name
+	OnDifference func(diff Difference) // Called for each difference reported, in order
+
+	// Names of the tables present in both schemas whose definitions have different checksums,
+	// in name order: the only tables the PerTable comparators need to check. Set by prepare.
+	changed []string
+}
+
+// TableOptions adjusts the comparison of the tables whose names match Pattern.
+type TableOptions struct {
+	Pattern          string   // Glob pattern of the tables the options apply to (e.g., "legacy_*")
+	SkipColumnChecks []string // Column attribute checks to skip (e.g., "identity", "nullable")
+}
+
+// skippedColumnChecks collects the column attribute checks to skip for a table, from every
+// TableOptions whose pattern matches the table name.
+//
+// Parameters:
+//   - tableName: Name of the table being compared
+//
+// Returns:
+//   - map[string]bool: Set of column attribute checks to skip
+func (o Options) skippedColumnChecks(tableName string) map[string]bool {
+	skip := make(map[string]bool)
+	if o.IgnoreDefaults {
+		skip[CheckDefault] = true
+	}
+	for _, table := range o.Tables {
+		if matched, err := path.Match(table.Pattern, tableName); err != nil || !matched {
+			continue
+		}
+		for _, check := range table.SkipColumnChecks {
+			skip[check] = true
+		}
+	}
+	return skip
+}
+
+// CompareSchemas performs a comprehensive comparison between two database schemas by running
+// every registered Comparator. The built-in comparators check for differences in tables,
+// partitioning, columns, primary keys, indexes, and foreign keys, plus any optional checks
+// enabled in opts. Differences are grouped by table, in table name order.
+// Every difference is reported with SeverityError (SeverityInfo for the FeatureUnsupported
+// notices of features that only one side supports), unless WithSeverityMap assigns it another
+// severity. Entries of the severity map with an unknown severity are ignored, and the error
+// CompareSchemasContext would return for them is written to the standard logger.
+//
+// Parameters:
+//   - source: The source schema to compare from
+//   - target: The target schema to compare against
+//   - opts: Options value and/or With* options controlling the comparison
+//
+// Returns:
+//   - DiffResult: A list of all differences found between the schemas
+func CompareSchemas(source, target *schema.Schema, opts ...Option) DiffResult {
+	resolved := resolveOptions(opts)
+	if err := validateSeverityMap(resolved.SeverityMap); err != nil {
+		log.Printf("compare: ignoring severity overrides: %v", err)
+		valid := make(map[string]string, len(resolved.SeverityMap))
+		for diffType, severity := range resolved.SeverityMap {
+			if IsValidSeverity(severity) {
+				valid[diffType] = severity
+			}
+		}
+		resolved.SeverityMap = valid
+	}
+
+	differences, _ := CompareSchemasContext(context.Background(), source, target, resolved)
+	return differences
+}
+
+// CompareSchemasContext is like CompareSchemas, but stops between comparators once the
+// context is cancelled or its deadline passes.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the comparison
+//   - source: The source schema to compare from
+//   - target: The target schema to compare against
+//   - opts: Options value and/or With* options controlling the comparison
+//
+// Returns:
+//   - DiffResult: A list of all differences found between the schemas
+//   - error: The context's error if the comparison was cancelled, an error if the severity map
+//     uses an unknown severity, or the error returned by Options.Filter
+func CompareSchemasContext(ctx context.Context, source, target *schema.Schema, options ...Option) (DiffResult, error) {
+	c, err := prepare(source, target, options)
+	if err != nil {
+		return nil, err
+	}
+
+	differences := c.initial
+	for _, comparator := range Comparators() {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("comparison cancelled: %w", err)
+		}
+		if c.opts.OnPhaseStart != nil {
+			c.opts.OnPhaseStart(comparator.Name())
+		}
+		differences = append(differences, comparator.Compare(c.source, c.target, c.opts)...)
+	}
+	differences = detectRenames(differences, c.source, c.target, c.opts)
+	scoreSimilarity(differences, c.source, c.target, c.opts)
+	annotateSizes(differences, c.source, c.target, c.opts)
+
+	// Drop repeated differences and link the ones that are consequences of others
+	result := c.finish(differences)
+	linkRelated(result, result, c.source, c.target)
+
+	// Group the differences by table, keeping the order of the comparators within each table
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Table < result[j].Table
+	})
+
+	if c.opts.OnDifference != nil {
+		for _, diff := range result {
+			c.opts.OnDifference(diff)
+		}
+	}
+
+	return result, nil
+}
+
+// validateSeverityMap checks that every severity of a severity map is known.
+//
+// Parameters:
+//   - severityMap: Severities keyed by difference type
+//
+// Returns:
+//   - error: An error naming each entry with an unknown severity, in difference type order
+func validateSeverityMap(severityMap map[string]string) error {
+	var diffTypes []string
+	for diffType, severity := range severityMap {
+		if !IsValidSeverity(severity) {
+			diffTypes = append(diffTypes, diffType)
+		}
+	}
+	if len(diffTypes) == 0 {
+		return nil
+	}
+	sort.Strings(diffTypes)
+	invalid := make([]string, len(diffTypes))
+	for i, diffType := range diffTypes {
+		invalid[i] = fmt.Sprintf("unknown severity '%s' for difference type %s", severityMap[diffType], diffType)
+	}
+	return errors.New(strings.Join(invalid, "; "))
+}
+
+// comparison holds the inputs of a comparison, once they have been prepared by prepare.
+type comparison struct {
+	opts    Options        // Resolved options
+	source  *schema.Schema // Source schema, without failed tables and unsupported features
+	target  *schema.Schema // Target schema, without failed tables and unsupported features
+	initial []Difference   // FetchFailed and FeatureUnsupported differences found while preparing
 }
 
-// CompareSchemas performs a comprehensive comparison between two database schemas.
-// It checks for differences in tables, columns, primary keys, indexes, and foreign keys.
+// prepare resolves and validates the options of a comparison, and adjusts the schemas so that
+// only what can be compared on both sides is compared.
 //
 // Parameters:
 //   - source: The source schema to compare from
 //   - target: The target schema to compare against
+//   - options: Options value and/or With* options controlling the comparison
+//
+// Returns:
+//   - comparison: Prepared comparison
+//   - error: An error if the severity map uses an unknown severity, or returned by Options.Filter
+func prepare(source, target *schema.Schema, options []Option) (comparison, error) {
+	opts := resolveOptions(options)
+	if err := validateSeverityMap(opts.SeverityMap); err != nil {
+		return comparison{}, err
+	}
+	if opts.IgnoreCase {
+		source, target = foldCase(source), foldCase(target)
+	}
+	if opts.Filter != nil {
+		source, target = withTablesCopied(source), withTablesCopied(target)
+		if err := opts.Filter(source, target); err != nil {
+			return comparison{}, err
+		}
+	}
+
+	// Tables that could not be fetched on either side are reported as such, rather than
+	// compared against a table that is only partly known
+	source, target, differences := withoutFailedTables(source, target)
+
+	// Features that one side lacks are not compared, and are reported as such instead
+	var unsupported []Difference
+	source, target, unsupported = withoutUnsupportedFeatures(source, target)
+
+	// Identical tables cannot differ, so only the tables whose checksums differ are compared in detail
+	opts.changed = changedTables(source, target, opts.Concurrency)
+
+	return comparison{
+		opts:    opts,
+		source:  source,
+		target:  target,
+		initial: append(unsupported, differences...),
+	}, nil
+}
+
+// finish keeps only the differences relevant to the direction of the comparison, fills in the
+// default severity (unless the comparator chose one) and the fields that are common to all
+// comparators, applies the severity map, and drops repeated differences.
+//
+// Parameters:
+//   - differences: Differences reported by the comparators
 //
 // Returns:
-//   - []Difference: A list of all differences found between the schemas
-func CompareSchemas(source, target *schema.Schema) []Difference {
+//   - DiffResult: Differences to report, in their original order
+func (c comparison) finish(differences []Difference) DiffResult {
+	var result DiffResult
+	for _, diff := range differences {
+		if !inDirection(diff.Type, c.opts.Direction) {
+			continue
+		}
+		if diff.Severity == "" {
+			diff.Severity = DefaultSeverity(diff.Type)
+		}
+		if severity, exists := c.opts.SeverityMap[diff.Type]; exists {
+			diff.Severity = severity
+		}
+		if diff.Severity == SeverityIgnore {
+			continue
+		}
+		if diff.SchemaName == "" {
+			diff.SchemaName = c.source.Name
+		}
+		if diff.ObjectName == "" {
+			diff.ObjectName = diff.Table
+		}
+		result = append(result, diff)
+	}
+	return dedupe(result)
+}
+
+// changedTables finds the tables present in both schemas whose definitions differ, by comparing
+// their checksums.
+//
+// Parameters:
+//   - source: The source schema to compare from
+//   - target: The target schema to compare against
+//   - workers: Number of tables whose checksums are computed at once
+//
+// Returns:
+//   - []string: Names of the changed tables, in sorted order
+func changedTables(source, target *schema.Schema, workers int) []string {
+	var shared []string
+	for tableName := range source.Tables {
+		if _, exists := target.Tables[tableName]; exists {
+			shared = append(shared, tableName)
+		}
+	}
+	sort.Strings(shared)
+
+	same := make([]bool, len(shared))
+	parallelFor(len(shared), workers, func(i int) {
+		same[i] = source.Tables[shared[i]].SameDefinition(target.Tables[shared[i]])
+	})
+	changed := shared[:0]
+	for i, tableName := range shared {
+		if !same[i] {
+			changed = append(changed, tableName)
+		}
+	}
+	return changed
+}
+
+// withTablesCopied returns a shallow copy of a schema with its own map of tables, so that
+// tables can be removed or replaced without changing the schema given.
+//
+// Parameters:
+//   - s: Schema to copy
+//
+// Returns:
+//   - *schema.Schema: Copy of the schema
+func withTablesCopied(s *schema.Schema) *schema.Schema {
+	copied := *s
+	copied.Tables = make(map[string]schema.TableInfo, len(s.Tables))
+	for tableName, table := range s.Tables {
+		copied.Tables[tableName] = table
+	}
+	return &copied
+}
+
+// withoutFailedTables removes the tables that could not be fetched on either side from both
+// schemas, and reports a FetchFailed difference for each of them. The schemas given are left
+// untouched; copies are returned when tables had to be removed.
+//
+// Parameters:
+//   - source: The source schema to compare from
+//   - target: The target schema to compare against
+//
+// Returns:
+//   - *schema.Schema: Source schema without the failed tables
+//   - *schema.Schema: Target schema without the failed tables
+//   - []Difference: FetchFailed differences for the failed tables
+func withoutFailedTables(source, target *schema.Schema) (*schema.Schema, *schema.Schema, []Difference) {
+	if len(source.Errors) == 0 && len(target.Errors) == 0 {
+		return source, target, nil
+	}
+
+	var differences []Difference
+	failed := make(map[string]bool)
+	for _, side := range []struct {
+		name   string
+		schema *schema.Schema
+	}{{"source", source}, {"target", target}} {
+		for _, fetchErr := range side.schema.Errors {
+			failed[fetchErr.Table] = true
+			differences = append(differences, Difference{
+				Type:        "FetchFailed",
+				Table:       fetchErr.Table,
+				Description: fmt.Sprintf("Table could not be fetched from the %s and was not compared: %s", side.name, fetchErr.Message),
+				ObjectKind:  KindTable,
+			})
+		}
+	}
+
+	remove := func(s *schema.Schema) *schema.Schema {
+		copied := *s
+		copied.Tables = make(map[string]schema.TableInfo, len(s.Tables))
+		for tableName, table := range s.Tables {
+			if !failed[tableName] {
+				copied.Tables[tableName] = table
+			}
+		}
+		return &copied
+	}
+	return remove(source), remove(target), differences
+}
+
+// compareTables checks for tables that exist on only one side, and compares the table-level
+// properties (owner and partitioning) of the tables that exist on both.
+//
+// Parameters:
+//   - source: The source schema to compare from
+//   - target: The target schema to compare against
+//   - opts: Optional checks to perform
+//
+// Returns:
+//   - []Difference: List of differences found in the tables
+func compareTables(source, target *schema.Schema, opts Options) []Difference {
 	var differences []Difference
 
 	// Compare tables that exist in the source schema
-	for tableName, sourceTable := range source.Tables {
+	for _, tableName := range sortedTableNames(source) {
+		sourceTable := source.Tables[tableName]
 		targetTable, exists := target.Tables[tableName]
 		if !exists {
+			suffix, kind, noun := relation(sourceTable)
 			differences = append(differences, Difference{
-				Type:        "MissingTable",
+				Type:        "Missing" + suffix,
 				Table:       tableName,
-				Description: "Table exists in source but not in target",
+				ObjectKind:  kind,
+				Description: noun + " exists in source but not in target",
 			})
 			continue
 		}
 
-		// Compare all aspects of the table
-		columnDiffs := compareColumns(tableName, sourceTable.Columns, targetTable.Columns)
-		differences = append(differences, columnDiffs...)
-
-		pkDiffs := comparePrimaryKeys(tableName, sourceTable.PrimaryKeys, targetTable.PrimaryKeys)
-		differences = append(differences, pkDiffs...)
-
-		indexDiffs := compareIndexes(tableName, sourceTable.Indexes, targetTable.Indexes)
-		differences = append(differences, indexDiffs...)
+		if opts.CompareOwners && sourceTable.Owner != targetTable.Owner {
+			_, kind, noun := relation(sourceTable)
+			differences = append(differences, Difference{
+				Type:        "OwnerMismatch",
+				Table:       tableName,
+				ObjectKind:  kind,
+				SourceValue: sourceTable.Owner,
+				TargetValue: targetTable.Owner,
+				Description: fmt.Sprintf("%s has different owners: source=%s, target=%s", noun, sourceTable.Owner, targetTable.Owner),
+			})
+		}
 
-		fkDiffs := compareForeignKeys(tableName, sourceTable.ForeignKeys, targetTable.ForeignKeys)
-		differences = append(differences, fkDiffs...)
+		differences = append(differences, comparePartitioning(tableName, sourceTable, targetTable)...)
 	}
 
 	// Check for tables that exist only in the target schema
-	for tableName := range target.Tables {
+	for _, tableName := range sortedTableNames(target) {
 		if _, exists := source.Tables[tableName]; !exists {
+			suffix, kind, noun := relation(target.Tables[tableName])
 			differences = append(differences, Difference{
-				Type:        "ExtraTable",
+				Type:        "Extra" + suffix,
 				Table:       tableName,
-				Description: "Table exists in target but not in source",
+				ObjectKind:  kind,
+				Description: noun + " exists in target but not in source",
 			})
 		}
 	}
@@ -68,19 +547,112 @@ func CompareSchemas(source, target *schema.Schema) []Difference {
 	return differences
 }
 
+// sortedTableNames returns the names of the tables of a schema in sorted order.
+//
+// Parameters:
+//   - s: Schema to list the tables of
+//
+// Returns:
+//   - []string: Sorted table names
+func sortedTableNames(s *schema.Schema) []string {
+	names := make([]string, 0, len(s.Tables))
+	for name := range s.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsValidDirection reports whether the given string is a known comparison direction.
+//
+// Parameters:
+//   - direction: Direction to check
+//
+// Returns:
+//   - bool: True if the direction is known
+func IsValidDirection(direction string) bool {
+	switch direction {
+	case "", DirectionBoth, DirectionSourceToTarget, DirectionTargetToSource:
+		return true
+	}
+	return false
+}
+
+// inDirection reports whether a difference type is relevant to the direction of the comparison.
+// Objects that exist only in the non-authoritative side are not drift when that side is allowed
+// to be ahead, so they are dropped; differences in shared objects are always relevant.
+//
+// Parameters:
+//   - diffType: Type of the difference
+//   - direction: Direction of the comparison
+//
+// Returns:
+//   - bool: True if the difference should be reported
+func inDirection(diffType, direction string) bool {
+	switch direction {
+	case DirectionSourceToTarget:
+		return !strings.HasPrefix(diffType, "Extra")
+	case DirectionTargetToSource:
+		return !strings.HasPrefix(diffType, "Missing")
+	}
+	return true
+}
+
+// comparePartitioning compares the partitioning of a table between source and target schemas.
+// It checks for differences in the partition strategy and key, and in the parent table that
+// a partition is attached to.
+//
+// Parameters:
+//   - tableName: Name of the table being compared
+//   - source: Table in the source schema
+//   - target: Table in the target schema
+//
+// Returns:
+//   - []Difference: List of differences found in the partitioning
+func comparePartitioning(tableName string, source, target schema.TableInfo) []Difference {
+	var differences []Difference
+
+	if source.PartitionKey != target.PartitionKey {
+		differences = append(differences, Difference{
+			Type:        "PartitionKeyMismatch",
+			Table:       tableName,
+			ObjectKind:  KindTable,
+			SourceValue: source.PartitionKey,
+			TargetValue: target.PartitionKey,
+			Description: fmt.Sprintf("Table has different partition keys: source=%s, target=%s", source.PartitionKey, target.PartitionKey),
+		})
+	}
+
+	if source.PartitionOf != target.PartitionOf {
+		differences = append(differences, Difference{
+			Type:        "PartitionParentMismatch",
+			Table:       tableName,
+			ObjectKind:  KindTable,
+			SourceValue: source.PartitionOf,
+			TargetValue: target.PartitionOf,
+			Description: fmt.Sprintf("Table is a partition of different parents: source=%s, target=%s", source.PartitionOf, target.PartitionOf),
+		})
+	}
+
+	return differences
+}
+
 // compareColumns compares the columns of a table between source and target schemas.
 // It checks for missing columns, type mismatches, nullability differences,
-// default value differences, and identity column differences.
+// default value differences, identity column differences, and compression method differences,
+// except for the attribute checks that are skipped for the table.
 //
 // Parameters:
 //   - tableName: Name of the table being compared
 //   - source: List of columns in the source schema
 //   - target: List of columns in the target schema
+//   - opts: Options deciding which checks are skipped and how data types are compared
 //
 // Returns:
 //   - []Difference: List of differences found in the columns
-func compareColumns(tableName string, source, target []schema.ColumnInfo) []Difference {
+func compareColumns(tableName string, source, target []schema.ColumnInfo, opts Options) []Difference {
 	var differences []Difference
+	skip := opts.skippedColumnChecks(tableName)
 	sourceMap := make(map[string]schema.ColumnInfo)
 	targetMap := make(map[string]schema.ColumnInfo)
 
@@ -92,58 +664,92 @@ func compareColumns(tableName string, source, target []schema.ColumnInfo) []Diff
 		targetMap[col.Name] = col
 	}
 
-	// Check for missing or different columns in source
-	for name, sourceCol := range sourceMap {
+	// Check for missing or different columns in source, in the order of the source
+	for _, sourceCol := range source {
+		name := sourceCol.Name
 		targetCol, exists := targetMap[name]
 		if !exists {
 			differences = append(differences, Difference{
 				Type:        "MissingColumn",
 				Table:       tableName,
+				ObjectKind:  KindColumn,
+				SubObject:   name,
 				Description: fmt.Sprintf("Column '%s' exists in source but not in target", name),
 			})
 			continue
 		}
 
 		// Compare column properties
-		if sourceCol.Type != targetCol.Type {
+		if !skip[CheckType] && opts.normalizeType(sourceCol.Type) != opts.normalizeType(targetCol.Type) {
 			differences = append(differences, Difference{
 				Type:        "ColumnTypeMismatch",
 				Table:       tableName,
+				ObjectKind:  KindColumn,
+				SubObject:   name,
+				SourceValue: sourceCol.Type,
+				TargetValue: targetCol.Type,
 				Description: fmt.Sprintf("Column '%s' has different types: source=%s, target=%s", name, sourceCol.Type, targetCol.Type),
 			})
 		}
 
-		if sourceCol.Nullable != targetCol.Nullable {
+		if !skip[CheckNullable] && sourceCol.Nullable != targetCol.Nullable {
 			differences = append(differences, Difference{
 				Type:        "ColumnNullableMismatch",
 				Table:       tableName,
+				ObjectKind:  KindColumn,
+				SubObject:   name,
+				SourceValue: strconv.FormatBool(sourceCol.Nullable),
+				TargetValue: strconv.FormatBool(targetCol.Nullable),
 				Description: fmt.Sprintf("Column '%s' has different nullable settings: source=%v, target=%v", name, sourceCol.Nullable, targetCol.Nullable),
 			})
 		}
 
-		if sourceCol.Default != targetCol.Default {
+		if !skip[CheckDefault] && sourceCol.Default != targetCol.Default {
 			differences = append(differences, Difference{
 				Type:        "ColumnDefaultMismatch",
 				Table:       tableName,
+				ObjectKind:  KindColumn,
+				SubObject:   name,
+				SourceValue: sourceCol.Default,
+				TargetValue: targetCol.Default,
 				Description: fmt.Sprintf("Column '%s' has different default values: source=%s, target=%s", name, sourceCol.Default, targetCol.Default),
 			})
 		}
 
-		if sourceCol.IsIdentity != targetCol.IsIdentity {
+		if !skip[CheckIdentity] && sourceCol.IsIdentity != targetCol.IsIdentity {
 			differences = append(differences, Difference{
 				Type:        "ColumnIdentityMismatch",
 				Table:       tableName,
+				ObjectKind:  KindColumn,
+				SubObject:   name,
+				SourceValue: strconv.FormatBool(sourceCol.IsIdentity),
+				TargetValue: strconv.FormatBool(targetCol.IsIdentity),
 				Description: fmt.Sprintf("Column '%s' has different identity settings: source=%v, target=%v", name, sourceCol.IsIdentity, targetCol.IsIdentity),
 			})
 		}
+
+		if !skip[CheckCompression] && sourceCol.Compression != targetCol.Compression {
+			differences = append(differences, Difference{
+				Type:        "ColumnCompressionMismatch",
+				Table:       tableName,
+				ObjectKind:  KindColumn,
+				SubObject:   name,
+				SourceValue: sourceCol.Compression,
+				TargetValue: targetCol.Compression,
+				Description: fmt.Sprintf("Column '%s' has different compression methods: source=%s, target=%s", name, sourceCol.Compression, targetCol.Compression),
+			})
+		}
 	}
 
-	// Check for extra columns in target
-	for name := range targetMap {
+	// Check for extra columns in target, in the order of the target
+	for _, targetCol := range target {
+		name := targetCol.Name
 		if _, exists := sourceMap[name]; !exists {
 			differences = append(differences, Difference{
 				Type:        "ExtraColumn",
 				Table:       tableName,
+				ObjectKind:  KindColumn,
+				SubObject:   name,
 				Description: fmt.Sprintf("Column '%s' exists in target but not in source", name),
 			})
 		}
@@ -170,6 +776,9 @@ func comparePrimaryKeys(tableName string, source, target []string) []Difference
 		differences = append(differences, Difference{
 			Type:        "PrimaryKeyMismatch",
 			Table:       tableName,
+			ObjectKind:  KindPrimaryKey,
+			SourceValue: strings.Join(source, ","),
+			TargetValue: strings.Join(target, ","),
 			Description: fmt.Sprintf("Different number of primary key columns: source=%d, target=%d", len(source), len(target)),
 		})
 		return differences
@@ -181,6 +790,10 @@ func comparePrimaryKeys(tableName string, source, target []string) []Difference
 			differences = append(differences, Difference{
 				Type:        "PrimaryKeyMismatch",
 				Table:       tableName,
+				ObjectKind:  KindPrimaryKey,
+				SubObject:   strconv.Itoa(i + 1),
+				SourceValue: source[i],
+				TargetValue: target[i],
 				Description: fmt.Sprintf("Primary key column mismatch at position %d: source=%s, target=%s", i+1, source[i], target[i]),
 			})
 		}
@@ -190,7 +803,9 @@ func comparePrimaryKeys(tableName string, source, target []string) []Difference
 }
 
 // compareIndexes compares the indexes between source and target schemas.
-// It checks for missing indexes, uniqueness differences, and column differences.
+// It checks for missing indexes, uniqueness differences, key differences, and differences in
+// included columns, predicates, and access methods. Predicates are compared once normalized
+// (see sqlnorm.Normalize), and access methods only when both sides know them.
 //
 // Parameters:
 //   - tableName: Name of the table being compared
@@ -212,13 +827,16 @@ func compareIndexes(tableName string, source, target []schema.IndexInfo) []Diffe
 		targetMap[idx.Name] = idx
 	}
 
-	// Check for missing or different indexes in source
-	for name, sourceIdx := range sourceMap {
+	// Check for missing or different indexes in source, in the order of the source
+	for _, sourceIdx := range source {
+		name := sourceIdx.Name
 		targetIdx, exists := targetMap[name]
 		if !exists {
 			differences = append(differences, Difference{
 				Type:        "MissingIndex",
 				Table:       tableName,
+				ObjectKind:  KindIndex,
+				SubObject:   name,
 				Description: fmt.Sprintf("Index '%s' exists in source but not in target", name),
 			})
 			continue
@@ -229,25 +847,73 @@ func compareIndexes(tableName string, source, target []schema.IndexInfo) []Diffe
 			differences = append(differences, Difference{
 				Type:        "IndexUniqueMismatch",
 				Table:       tableName,
+				ObjectKind:  KindIndex,
+				SubObject:   name,
+				SourceValue: strconv.FormatBool(sourceIdx.Unique),
+				TargetValue: strconv.FormatBool(targetIdx.Unique),
 				Description: fmt.Sprintf("Index '%s' has different unique settings: source=%v, target=%v", name, sourceIdx.Unique, targetIdx.Unique),
 			})
 		}
 
-		if !compareStringSlices(sourceIdx.Columns, targetIdx.Columns) {
+		if sourceKeys, targetKeys := sourceIdx.Keys(), targetIdx.Keys(); !compareStringSlices(sourceKeys, targetKeys) {
 			differences = append(differences, Difference{
 				Type:        "IndexColumnsMismatch",
 				Table:       tableName,
-				Description: fmt.Sprintf("Index '%s' has different columns: source=%v, target=%v", name, sourceIdx.Columns, targetIdx.Columns),
+				ObjectKind:  KindIndex,
+				SubObject:   name,
+				SourceValue: strings.Join(sourceKeys, ","),
+				TargetValue: strings.Join(targetKeys, ","),
+				Description: fmt.Sprintf("Index '%s' has different columns: source=%v, target=%v", name, sourceKeys, targetKeys),
+			})
+		}
+
+		if !compareStringSlices(sourceIdx.Include, targetIdx.Include) {
+			differences = append(differences, Difference{
+				Type:        "IndexIncludeMismatch",
+				Table:       tableName,
+				ObjectKind:  KindIndex,
+				SubObject:   name,
+				SourceValue: strings.Join(sourceIdx.Include, ","),
+				TargetValue: strings.Join(targetIdx.Include, ","),
+				Description: fmt.Sprintf("Index '%s' includes different columns: source=%v, target=%v", name, sourceIdx.Include, targetIdx.Include),
+			})
+		}
+
+		if !sqlnorm.Equal(sourceIdx.Predicate, targetIdx.Predicate) {
+			differences = append(differences, Difference{
+				Type:        "IndexPredicateMismatch",
+				Table:       tableName,
+				ObjectKind:  KindIndex,
+				SubObject:   name,
+				SourceValue: sourceIdx.Predicate,
+				TargetValue: targetIdx.Predicate,
+				Description: fmt.Sprintf("Index '%s' has different predicates: source=%s, target=%s", name,
+					describeCondition(sourceIdx.Predicate), describeCondition(targetIdx.Predicate)),
+			})
+		}
+
+		if sourceIdx.Method != "" && targetIdx.Method != "" && sourceIdx.Method != targetIdx.Method {
+			differences = append(differences, Difference{
+				Type:        "IndexMethodMismatch",
+				Table:       tableName,
+				ObjectKind:  KindIndex,
+				SubObject:   name,
+				SourceValue: sourceIdx.Method,
+				TargetValue: targetIdx.Method,
+				Description: fmt.Sprintf("Index '%s' has different access methods: source=%s, target=%s", name, sourceIdx.Method, targetIdx.Method),
 			})
 		}
 	}
 
-	// Check for extra indexes in target
-	for name := range targetMap {
+	// Check for extra indexes in target, in the order of the target
+	for _, targetIdx := range target {
+		name := targetIdx.Name
 		if _, exists := sourceMap[name]; !exists {
 			differences = append(differences, Difference{
 				Type:        "ExtraIndex",
 				Table:       tableName,
+				ObjectKind:  KindIndex,
+				SubObject:   name,
 				Description: fmt.Sprintf("Index '%s' exists in target but not in source", name),
 			})
 		}
@@ -257,7 +923,8 @@ func compareIndexes(tableName string, source, target []schema.IndexInfo) []Diffe
 }
 
 // compareForeignKeys compares the foreign key constraints between source and target schemas.
-// It checks for missing foreign keys, referenced table differences, and column differences.
+// It checks for missing foreign keys, referenced table differences, column differences, and,
+// when both sides know the definitions of the constraints, differences in their options.
 //
 // Parameters:
 //   - tableName: Name of the table being compared
@@ -279,13 +946,16 @@ func compareForeignKeys(tableName string, source, target []schema.ForeignKeyInfo
 		targetMap[fk.Name] = fk
 	}
 
-	// Check for missing or different foreign keys in source
-	for name, sourceFK := range sourceMap {
+	// Check for missing or different foreign keys in source, in the order of the source
+	for _, sourceFK := range source {
+		name := sourceFK.Name
 		targetFK, exists := targetMap[name]
 		if !exists {
 			differences = append(differences, Difference{
 				Type:        "MissingForeignKey",
 				Table:       tableName,
+				ObjectKind:  KindForeignKey,
+				SubObject:   name,
 				Description: fmt.Sprintf("Foreign key '%s' exists in source but not in target", name),
 			})
 			continue
@@ -296,6 +966,10 @@ func compareForeignKeys(tableName string, source, target []schema.ForeignKeyInfo
 			differences = append(differences, Difference{
 				Type:        "ForeignKeyReferenceMismatch",
 				Table:       tableName,
+				ObjectKind:  KindForeignKey,
+				SubObject:   name,
+				SourceValue: sourceFK.ReferencedTable,
+				TargetValue: targetFK.ReferencedTable,
 				Description: fmt.Sprintf("Foreign key '%s' references different tables: source=%s, target=%s", name, sourceFK.ReferencedTable, targetFK.ReferencedTable),
 			})
 		}
@@ -304,6 +978,10 @@ func compareForeignKeys(tableName string, source, target []schema.ForeignKeyInfo
 			differences = append(differences, Difference{
 				Type:        "ForeignKeyColumnsMismatch",
 				Table:       tableName,
+				ObjectKind:  KindForeignKey,
+				SubObject:   name,
+				SourceValue: strings.Join(sourceFK.Columns, ","),
+				TargetValue: strings.Join(targetFK.Columns, ","),
 				Description: fmt.Sprintf("Foreign key '%s' has different columns: source=%v, target=%v", name, sourceFK.Columns, targetFK.Columns),
 			})
 		}
@@ -312,17 +990,37 @@ func compareForeignKeys(tableName string, source, target []schema.ForeignKeyInfo
 			differences = append(differences, Difference{
 				Type:        "ForeignKeyReferencedColumnsMismatch",
 				Table:       tableName,
+				ObjectKind:  KindForeignKey,
+				SubObject:   name,
+				SourceValue: strings.Join(sourceFK.ReferencedColumns, ","),
+				TargetValue: strings.Join(targetFK.ReferencedColumns, ","),
 				Description: fmt.Sprintf("Foreign key '%s' references different columns: source=%v, target=%v", name, sourceFK.ReferencedColumns, targetFK.ReferencedColumns),
 			})
 		}
+
+		if sourceFK.Definition != "" && targetFK.Definition != "" && sourceFK.Options() != targetFK.Options() {
+			differences = append(differences, Difference{
+				Type:        "ForeignKeyOptionsMismatch",
+				Table:       tableName,
+				ObjectKind:  KindForeignKey,
+				SubObject:   name,
+				SourceValue: sourceFK.Options(),
+				TargetValue: targetFK.Options(),
+				Description: fmt.Sprintf("Foreign key '%s' has different options: source=%s, target=%s", name,
+					describeCondition(sourceFK.Options()), describeCondition(targetFK.Options())),
+			})
+		}
 	}
 
-	// Check for extra foreign keys in target
-	for name := range targetMap {
+	// Check for extra foreign keys in target, in the order of the target
+	for _, targetFK := range target {
+		name := targetFK.Name
 		if _, exists := sourceMap[name]; !exists {
 			differences = append(differences, Difference{
 				Type:        "ExtraForeignKey",
 				Table:       tableName,
+				ObjectKind:  KindForeignKey,
+				SubObject:   name,
 				Description: fmt.Sprintf("Foreign key '%s' exists in target but not in source", name),
 			})
 		}
@@ -350,4 +1048,4 @@ func compareStringSlices(a, b []string) bool {
 		}
 	}
 	return true
-} 
\ No newline at end of file
+}
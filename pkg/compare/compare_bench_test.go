@@ -0,0 +1,62 @@
+package compare_test
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/guriandoro/pg_schema_check/pkg/bench"
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+)
+
+// BenchmarkCompare compares generated pairs of schemas differing by bench.DefaultDrift, at each
+// of bench.TableCounts, reporting allocations and how much the heap grew per table at its peak.
+// The schemas are generated within each iteration, so that the peak heap includes them as it
+// would a fetched schema:
+//   - InMemory compares two complete schemas with CompareSchemasContext;
+//   - Concurrent does the same with WithConcurrency, one table per CPU at a time;
+//   - Streaming compares them table by table with CompareTableStreams, holding the details of one
+//     pair of tables at a time, so its peak-heap-B/table stays well below that of InMemory and
+//     flat as the number of tables grows: only the outline of the schemas grows with them.
+func BenchmarkCompare(b *testing.B) {
+	for _, tables := range bench.TableCounts() {
+		b.Run(fmt.Sprintf("InMemory/%d", tables), func(b *testing.B) {
+			b.ReportAllocs()
+			defer bench.TrackPeakHeapPerTable(b, tables)()
+			for i := 0; i < b.N; i++ {
+				source, target := bench.Generate(tables, nil), bench.Generate(tables, &bench.DefaultDrift)
+				if _, err := compare.CompareSchemasContext(context.Background(), source, target); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("Concurrent/%d", tables), func(b *testing.B) {
+			b.ReportAllocs()
+			defer bench.TrackPeakHeapPerTable(b, tables)()
+			for i := 0; i < b.N; i++ {
+				source, target := bench.Generate(tables, nil), bench.Generate(tables, &bench.DefaultDrift)
+				if _, err := compare.CompareSchemasContext(context.Background(), source, target, compare.WithConcurrency(runtime.GOMAXPROCS(0))); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("Streaming/%d", tables), func(b *testing.B) {
+			b.ReportAllocs()
+			defer bench.TrackPeakHeapPerTable(b, tables)()
+			for i := 0; i < b.N; i++ {
+				stream, err := compare.CompareTableStreams(context.Background(), bench.NewStream(tables, nil), bench.NewStream(tables, &bench.DefaultDrift))
+				if err != nil {
+					b.Fatal(err)
+				}
+				for range stream.Differences {
+				}
+				if err := stream.Err(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,313 @@
+package compare_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// shop builds a schema of two tables, customers and orders, and lets a test case change the
+// orders table, which is selected when change is called.
+func shop(change func(b *schema.Builder)) *schema.Schema {
+	b := schema.NewBuilder().
+		Table("customers").
+		Column("id", "bigint", schema.NotNull()).
+		Column("email", "text", schema.NotNull()).
+		PrimaryKey("id").
+		UniqueIndex("customers_email_key", "email").
+		Table("orders").
+		Column("id", "bigint", schema.NotNull(), schema.Identity()).
+		Column("customer_id", "bigint", schema.NotNull()).
+		Column("status", "text", schema.Default("'new'::text")).
+		PrimaryKey("id").
+		Index("idx_orders_customer", "customer_id").
+		ForeignKey("fk_orders_customer", []string{"customer_id"}, "customers", []string{"id"})
+	if change != nil {
+		change(b)
+	}
+	return b.Build()
+}
+
+// describe lists the differences as "Type table.sub-object", in the order they are reported.
+func describe(differences compare.DiffResult) []string {
+	described := make([]string, 0, len(differences))
+	for _, diff := range differences {
+		name := diff.Table
+		if name == "" {
+			name = diff.ObjectName
+		}
+		if diff.SubObject != "" && diff.SubObject != name {
+			name += "." + diff.SubObject
+		}
+		described = append(described, diff.Type+" "+name)
+	}
+	return described
+}
+
+func TestCompareSchemas(t *testing.T) {
+	tests := []struct {
+		name   string
+		source *schema.Schema
+		target *schema.Schema
+		opts   []compare.Option
+		want   []string
+	}{
+		{
+			name:   "identical schemas",
+			source: shop(nil),
+			target: shop(nil),
+		},
+		{
+			name:   "missing table",
+			source: shop(func(b *schema.Builder) { b.Table("refunds").Column("id", "bigint") }),
+			target: shop(nil),
+			want:   []string{"MissingTable refunds"},
+		},
+		{
+			name:   "extra table",
+			source: shop(nil),
+			target: shop(func(b *schema.Builder) { b.Table("refunds").Column("id", "bigint") }),
+			want:   []string{"ExtraTable refunds"},
+		},
+		{
+			name:   "missing and extra columns",
+			source: shop(func(b *schema.Builder) { b.Column("total", "numeric") }),
+			target: shop(func(b *schema.Builder) { b.Column("notes", "text") }),
+			want:   []string{"MissingColumn orders.total", "ExtraColumn orders.notes"},
+		},
+		{
+			name:   "column type",
+			source: shop(func(b *schema.Builder) { b.Column("total", "numeric") }),
+			target: shop(func(b *schema.Builder) { b.Column("total", "double precision") }),
+			want:   []string{"ColumnTypeMismatch orders.total"},
+		},
+		{
+			name:   "column nullability",
+			source: shop(func(b *schema.Builder) { b.Column("total", "numeric", schema.NotNull()) }),
+			target: shop(func(b *schema.Builder) { b.Column("total", "numeric") }),
+			want:   []string{"ColumnNullableMismatch orders.total"},
+		},
+		{
+			name:   "column default",
+			source: shop(func(b *schema.Builder) { b.Column("total", "numeric", schema.Default("0")) }),
+			target: shop(func(b *schema.Builder) { b.Column("total", "numeric") }),
+			want:   []string{"ColumnDefaultMismatch orders.total"},
+		},
+		{
+			name:   "column default ignored",
+			source: shop(func(b *schema.Builder) { b.Column("total", "numeric", schema.Default("0")) }),
+			target: shop(func(b *schema.Builder) { b.Column("total", "numeric") }),
+			opts:   []compare.Option{compare.WithIgnoreDefaults()},
+		},
+		{
+			name:   "names differing in case",
+			source: shop(func(b *schema.Builder) { b.Column("Total", "numeric") }),
+			target: shop(func(b *schema.Builder) { b.Column("total", "numeric") }),
+			opts:   []compare.Option{compare.WithIgnoreCase()},
+		},
+		{
+			name:   "primary key",
+			source: shop(nil),
+			target: shop(func(b *schema.Builder) { b.PrimaryKey("id", "customer_id") }),
+			want:   []string{"PrimaryKeyMismatch orders"},
+		},
+		{
+			name:   "missing and extra indexes",
+			source: shop(func(b *schema.Builder) { b.Index("idx_orders_status", "status") }),
+			target: shop(func(b *schema.Builder) { b.Index("idx_orders_status_customer", "status", "customer_id") }),
+			want:   []string{"MissingIndex orders.idx_orders_status", "ExtraIndex orders.idx_orders_status_customer"},
+		},
+		{
+			name:   "index uniqueness",
+			source: shop(func(b *schema.Builder) { b.UniqueIndex("idx_orders_status", "status") }),
+			target: shop(func(b *schema.Builder) { b.Index("idx_orders_status", "status") }),
+			want:   []string{"IndexUniqueMismatch orders.idx_orders_status"},
+		},
+		{
+			name:   "index columns",
+			source: shop(func(b *schema.Builder) { b.Index("idx_orders_status", "status") }),
+			target: shop(func(b *schema.Builder) { b.Index("idx_orders_status", "status", "id") }),
+			want:   []string{"IndexColumnsMismatch orders.idx_orders_status"},
+		},
+		{
+			name:   "missing foreign key",
+			source: shop(nil),
+			target: schema.NewBuilder().
+				Table("customers").Column("id", "bigint", schema.NotNull()).Column("email", "text", schema.NotNull()).
+				PrimaryKey("id").UniqueIndex("customers_email_key", "email").
+				Table("orders").Column("id", "bigint", schema.NotNull(), schema.Identity()).
+				Column("customer_id", "bigint", schema.NotNull()).Column("status", "text", schema.Default("'new'::text")).
+				PrimaryKey("id").Index("idx_orders_customer", "customer_id").
+				Build(),
+			want: []string{"MissingForeignKey orders.fk_orders_customer"},
+		},
+		{
+			name:   "foreign key reference",
+			source: shop(func(b *schema.Builder) { b.ForeignKey("fk_orders_parent", []string{"id"}, "customers", []string{"id"}) }),
+			target: shop(func(b *schema.Builder) { b.ForeignKey("fk_orders_parent", []string{"id"}, "orders", []string{"id"}) }),
+			want:   []string{"ForeignKeyReferenceMismatch orders.fk_orders_parent"},
+		},
+		{
+			name: "view definitions formatted differently",
+			source: shop(func(b *schema.Builder) {
+				b.Table("open_orders").Column("id", "bigint").View("SELECT id FROM orders WHERE (status = 'new')")
+			}),
+			target: shop(func(b *schema.Builder) {
+				b.Table("open_orders").Column("id", "bigint").View(" select id\n   from orders\n  where status = 'new';")
+			}),
+		},
+		{
+			name: "view definitions",
+			source: shop(func(b *schema.Builder) {
+				b.Table("open_orders").Column("id", "bigint").View("SELECT id FROM orders WHERE status = 'new'")
+			}),
+			target: shop(func(b *schema.Builder) { b.Table("open_orders").Column("id", "bigint").View("SELECT id FROM orders") }),
+			want:   []string{"ViewDefinitionMismatch open_orders"},
+		},
+		{
+			name:   "missing view",
+			source: shop(func(b *schema.Builder) { b.Table("open_orders").Column("id", "bigint").View("SELECT id FROM orders") }),
+			target: shop(nil),
+			want:   []string{"MissingView open_orders"},
+		},
+		{
+			name: "trigger condition",
+			source: shop(func(b *schema.Builder) {
+				b.Trigger(schema.TriggerInfo{Name: "orders_audit", Timing: "AFTER", Events: []string{"UPDATE"}, Level: "ROW", When: "(new.status <> old.status)", Function: "audit()"})
+			}),
+			target: shop(func(b *schema.Builder) {
+				b.Trigger(schema.TriggerInfo{Name: "orders_audit", Timing: "AFTER", Events: []string{"UPDATE"}, Level: "ROW", When: "new.status IS DISTINCT FROM old.status", Function: "audit()"})
+			}),
+			want: []string{"TriggerConditionMismatch orders.orders_audit"},
+		},
+		{
+			name: "sequence options",
+			source: shop(func(b *schema.Builder) {
+				b.Sequence(schema.SequenceInfo{Name: "invoice_seq", Start: 1, Increment: 1, Min: 1, Max: 1000})
+			}),
+			target: shop(func(b *schema.Builder) {
+				b.Sequence(schema.SequenceInfo{Name: "invoice_seq", Start: 1, Increment: 10, Min: 1, Max: 1000})
+			}),
+			want: []string{"SequenceMismatch invoice_seq"},
+		},
+		{
+			name: "function bodies formatted differently",
+			source: shop(func(b *schema.Builder) {
+				b.Function(schema.FunctionInfo{Name: "order_count", Definition: "CREATE OR REPLACE FUNCTION public.order_count()\n RETURNS bigint\n LANGUAGE sql\nAS $function$ select count(*) from orders $function$\n"})
+			}),
+			target: shop(func(b *schema.Builder) {
+				b.Function(schema.FunctionInfo{Name: "order_count", Definition: "CREATE OR REPLACE FUNCTION public.order_count() RETURNS bigint LANGUAGE sql AS $$SELECT count(*) FROM orders;$$"})
+			}),
+		},
+		{
+			name: "function bodies",
+			source: shop(func(b *schema.Builder) {
+				b.Function(schema.FunctionInfo{Name: "total", Arguments: "integer, integer", Definition: "CREATE OR REPLACE FUNCTION public.total(a integer, b integer)\n RETURNS integer\n LANGUAGE sql\nAS $function$ SELECT (a + b) * 2 $function$\n"})
+			}),
+			target: shop(func(b *schema.Builder) {
+				b.Function(schema.FunctionInfo{Name: "total", Arguments: "integer, integer", Definition: "CREATE OR REPLACE FUNCTION public.total(a integer, b integer)\n RETURNS integer\n LANGUAGE sql\nAS $function$ SELECT a + b * 2 $function$\n"})
+			}),
+			want: []string{"FunctionDefinitionMismatch total(integer, integer)"},
+		},
+		{
+			name:   "missing function overload",
+			source: shop(func(b *schema.Builder) { b.Function(schema.FunctionInfo{Name: "total", Arguments: "integer"}) }),
+			target: shop(func(b *schema.Builder) { b.Function(schema.FunctionInfo{Name: "total", Arguments: "bigint"}) }),
+			want:   []string{"MissingFunction total(integer)", "ExtraFunction total(bigint)"},
+		},
+		{
+			name: "owners not compared",
+			source: shop(func(b *schema.Builder) {
+				b.Owner("app").Sequence(schema.SequenceInfo{Name: "invoice_seq", Owner: "app"})
+			}),
+			target: shop(func(b *schema.Builder) {
+				b.Owner("postgres").Sequence(schema.SequenceInfo{Name: "invoice_seq", Owner: "postgres"})
+			}),
+		},
+		{
+			name: "owners",
+			source: shop(func(b *schema.Builder) {
+				b.Owner("app").
+					Table("open_orders").Column("id", "bigint").View("SELECT id FROM orders").Owner("app").
+					Sequence(schema.SequenceInfo{Name: "invoice_seq", Owner: "app"}).
+					Function(schema.FunctionInfo{Name: "order_count", Owner: "app"})
+			}),
+			target: shop(func(b *schema.Builder) {
+				b.Owner("postgres").
+					Table("open_orders").Column("id", "bigint").View("SELECT id FROM orders").Owner("postgres").
+					Sequence(schema.SequenceInfo{Name: "invoice_seq", Owner: "postgres"}).
+					Function(schema.FunctionInfo{Name: "order_count", Owner: "postgres"})
+			}),
+			opts: []compare.Option{compare.Options{CompareOwners: true}},
+			want: []string{"SequenceOwnerMismatch invoice_seq", "FunctionOwnerMismatch order_count()", "OwnerMismatch open_orders", "OwnerMismatch orders"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := describe(compare.CompareSchemas(tt.source, tt.target, tt.opts...))
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompareSchemas() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareSchemasSeverities(t *testing.T) {
+	source := shop(func(b *schema.Builder) { b.Column("total", "numeric") })
+	target := shop(func(b *schema.Builder) { b.Column("notes", "text") })
+
+	tests := []struct {
+		name string
+		opts []compare.Option
+		want map[string]string
+	}{
+		{
+			name: "defaults",
+			want: map[string]string{"MissingColumn": compare.SeverityError, "ExtraColumn": compare.SeverityError},
+		},
+		{
+			name: "overridden",
+			opts: []compare.Option{compare.WithSeverityMap(map[string]string{"ExtraColumn": compare.SeverityInfo})},
+			want: map[string]string{"MissingColumn": compare.SeverityError, "ExtraColumn": compare.SeverityInfo},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string]string)
+			for _, diff := range compare.CompareSchemas(source, target, tt.opts...) {
+				got[diff.Type] = diff.Severity
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("severities = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func ExampleCompareSchemas() {
+	source := schema.NewBuilder().
+		Table("orders").
+		Column("id", "bigint", schema.NotNull()).
+		Column("total", "numeric").
+		PrimaryKey("id").
+		Build()
+	target := schema.NewBuilder().
+		Table("orders").
+		Column("id", "bigint", schema.NotNull()).
+		PrimaryKey("id").
+		Build()
+
+	for _, diff := range compare.CompareSchemas(source, target) {
+		fmt.Printf("[%s] %s: %s\n", diff.Type, diff.Table, diff.Description)
+	}
+	// Output:
+	// [MissingColumn] orders: Column 'total' exists in source but not in target
+}
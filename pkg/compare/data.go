@@ -0,0 +1,130 @@
+package compare
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// compareRowCounts compares the number of rows of the tables present in both schemas and counted
+// on both sides (see schema.FetchOptions.RowCounts). A table is reported when its counts differ
+// by more than opts.RowCountMinDifference rows and by more than opts.RowCountTolerance of the
+// larger count, so that the churn of a busy table or the imprecision of estimates is not.
+//
+// Parameters:
+//   - source: The source schema
+//   - target: The target schema
+//   - opts: Options holding the thresholds
+//
+// Returns:
+//   - []Difference: A RowCountMismatch difference for each table whose counts differ too much
+func compareRowCounts(source, target *schema.Schema, opts Options) []Difference {
+	var names []string
+	for name := range source.RowCounts {
+		if _, counted := target.RowCounts[name]; !counted {
+			continue
+		}
+		_, inSource := source.Tables[name]
+		_, inTarget := target.Tables[name]
+		if inSource && inTarget {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var differences []Difference
+	for _, name := range names {
+		sourceCount, targetCount := source.RowCounts[name], target.RowCounts[name]
+		difference := sourceCount - targetCount
+		if difference < 0 {
+			difference = -difference
+		}
+		relative := float64(difference) / float64(max(sourceCount, targetCount, 1))
+		if difference == 0 || difference <= opts.RowCountMinDifference || relative <= opts.RowCountTolerance {
+			continue
+		}
+		differences = append(differences, Difference{
+			Type:        "RowCountMismatch",
+			Table:       name,
+			ObjectKind:  KindTable,
+			SourceValue: strconv.FormatInt(sourceCount, 10),
+			TargetValue: strconv.FormatInt(targetCount, 10),
+			Description: fmt.Sprintf("Table has %d rows in source but %d in target (%.1f%% apart, %s)",
+				sourceCount, targetCount, relative*100, countedAs(source.RowCountMode, target.RowCountMode)),
+		})
+	}
+	return differences
+}
+
+// countedAs describes how the row counts of both sides were counted.
+func countedAs(sourceMode, targetMode string) string {
+	describe := func(mode string) string {
+		if mode == schema.RowCountsEstimate {
+			return "estimated from pg_class.reltuples"
+		}
+		return "counted exactly"
+	}
+	if sourceMode == targetMode {
+		return describe(sourceMode)
+	}
+	return fmt.Sprintf("source %s, target %s", describe(sourceMode), describe(targetMode))
+}
+
+// compareDataChecksums compares the checksums of the data of the tables present in both schemas
+// and checksummed on both sides (see schema.FetchOptions.DataChecksums). Tables whose checksums
+// cover different columns or samples are not compared, as their checksums always differ: the
+// columns missing on one side are reported by the comparison of columns.
+//
+// Parameters:
+//   - source: The source schema
+//   - target: The target schema
+//   - opts: Options of the comparison (unused)
+//
+// Returns:
+//   - []Difference: A DataMismatch difference for each table whose data differs
+func compareDataChecksums(source, target *schema.Schema, opts Options) []Difference {
+	var names []string
+	for name, sourceChecksum := range source.DataChecksums {
+		targetChecksum, checksummed := target.DataChecksums[name]
+		if !checksummed || sourceChecksum.Sample != targetChecksum.Sample || !slices.Equal(sourceChecksum.Columns, targetChecksum.Columns) {
+			continue
+		}
+		_, inSource := source.Tables[name]
+		_, inTarget := target.Tables[name]
+		if inSource && inTarget {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var differences []Difference
+	for _, name := range names {
+		sourceChecksum, targetChecksum := source.DataChecksums[name], target.DataChecksums[name]
+		if sourceChecksum.Rows == targetChecksum.Rows && sourceChecksum.Hash == targetChecksum.Hash {
+			continue
+		}
+		scope := "all rows"
+		if sourceChecksum.Sample > 0 {
+			scope = fmt.Sprintf("first %d rows by primary key", sourceChecksum.Sample)
+		}
+		differences = append(differences, Difference{
+			Type:        "DataMismatch",
+			Table:       name,
+			ObjectKind:  KindTable,
+			SourceValue: describeChecksum(sourceChecksum),
+			TargetValue: describeChecksum(targetChecksum),
+			Description: fmt.Sprintf("Table data differs: %d rows hashed in source, %d in target (%s; columns %s)",
+				sourceChecksum.Rows, targetChecksum.Rows, scope, strings.Join(sourceChecksum.Columns, ", ")),
+		})
+	}
+	return differences
+}
+
+// describeChecksum formats a data checksum as the number of rows hashed and their checksum.
+func describeChecksum(checksum schema.DataChecksum) string {
+	return fmt.Sprintf("%d rows, checksum %s", checksum.Rows, checksum.Hash)
+}
@@ -0,0 +1,99 @@
+package compare
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// compareHypertables compares the TimescaleDB settings of a table: whether it is a hypertable
+// or continuous aggregate, the dimensions it is partitioned by, and its compression and
+// materialization settings.
+//
+// Parameters:
+//   - tableName: Name of the table being compared
+//   - source: Table in the source schema
+//   - target: Table in the target schema
+//
+// Returns:
+//   - []Difference: A HypertableMismatch difference if the settings differ
+func compareHypertables(tableName string, source, target schema.TableInfo) []Difference {
+	sourceValue := describeHypertable(source.Hypertable)
+	targetValue := describeHypertable(target.Hypertable)
+	if sourceValue == targetValue {
+		return nil
+	}
+	return []Difference{{
+		Type:        "HypertableMismatch",
+		Table:       tableName,
+		ObjectKind:  KindTable,
+		SourceValue: sourceValue,
+		TargetValue: targetValue,
+		Description: fmt.Sprintf("Table has different TimescaleDB settings: source=%s, target=%s", sourceValue, targetValue),
+	}}
+}
+
+// compareDistribution compares the Citus distribution of a table: whether it is distributed,
+// a reference table, or local, its distribution column, and its shard count.
+//
+// Parameters:
+//   - tableName: Name of the table being compared
+//   - source: Table in the source schema
+//   - target: Table in the target schema
+//
+// Returns:
+//   - []Difference: A DistributionMismatch difference if the distributions differ
+func compareDistribution(tableName string, source, target schema.TableInfo) []Difference {
+	sourceValue := describeDistribution(source.Distribution)
+	targetValue := describeDistribution(target.Distribution)
+	if sourceValue == targetValue {
+		return nil
+	}
+	return []Difference{{
+		Type:        "DistributionMismatch",
+		Table:       tableName,
+		ObjectKind:  KindTable,
+		SourceValue: sourceValue,
+		TargetValue: targetValue,
+		Description: fmt.Sprintf("Table has different Citus distributions: source=%s, target=%s", sourceValue, targetValue),
+	}}
+}
+
+// describeHypertable formats TimescaleDB settings for display (e.g., "hypertable on
+// created_at (time, 7 days), compressed"); a nil value is a plain table.
+func describeHypertable(h *schema.HypertableInfo) string {
+	if h == nil {
+		return "plain table"
+	}
+
+	var desc string
+	if h.ContinuousAggregate {
+		desc = "continuous aggregate"
+		if h.MaterializedOnly {
+			desc += ", materialized only"
+		}
+	} else {
+		dimensions := make([]string, len(h.Dimensions))
+		for i, dim := range h.Dimensions {
+			dimensions[i] = dim.String()
+		}
+		desc = "hypertable on " + strings.Join(dimensions, ", ")
+	}
+	if h.Compression {
+		desc += ", compressed"
+	}
+	return desc
+}
+
+// describeDistribution formats a Citus distribution for display (e.g., "distributed by
+// tenant_id, 32 shards"); a nil value is a table Citus does not manage.
+func describeDistribution(d *schema.DistributionInfo) string {
+	switch {
+	case d == nil:
+		return "not distributed"
+	case d.Type == "distributed":
+		return fmt.Sprintf("distributed by %s, %d shards", d.Column, d.ShardCount)
+	}
+	return d.Type
+}
@@ -0,0 +1,150 @@
+package compare
+
+import (
+	"fmt"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// featureNames holds the display name of each feature of schema.Schema.UnsupportedFeatures.
+var featureNames = map[string]string{
+	schema.FeaturePartitioning:      "partitioning",
+	schema.FeatureIdentity:          "identity columns",
+	schema.FeatureCompression:       "column compression",
+	schema.FeatureIndexes:           "indexes",
+	schema.FeatureTimescale:         "TimescaleDB metadata",
+	schema.FeatureCitus:             "Citus metadata",
+	schema.FeatureMaterializedViews: "materialized views",
+	schema.FeatureSequences:         "sequences",
+	schema.FeatureTriggers:          "triggers",
+	schema.FeatureFunctions:         "functions",
+}
+
+// withoutUnsupportedFeatures clears the properties of the features that either side does not
+// support from both schemas, and leaves out their materialized views, sequences, and functions when either
+// side cannot read them, so that they are not reported as differences, and reports a FeatureUnsupported
+// difference for each feature supported on one side only. The schemas given are left
+// untouched; copies are returned when properties had to be cleared.
+//
+// Parameters:
+//   - source: The source schema to compare from
+//   - target: The target schema to compare against
+//
+// Returns:
+//   - *schema.Schema: Source schema without the unsupported features
+//   - *schema.Schema: Target schema without the unsupported features
+//   - []Difference: FeatureUnsupported differences, with SeverityInfo
+func withoutUnsupportedFeatures(source, target *schema.Schema) (*schema.Schema, *schema.Schema, []Difference) {
+	if len(source.UnsupportedFeatures) == 0 && len(target.UnsupportedFeatures) == 0 {
+		return source, target, nil
+	}
+
+	var differences []Difference
+	unsupported := make(map[string]bool)
+	for _, feature := range append(append([]string(nil), source.UnsupportedFeatures...), target.UnsupportedFeatures...) {
+		if unsupported[feature] {
+			continue
+		}
+		unsupported[feature] = true
+
+		sourceSupports, targetSupports := source.Supports(feature), target.Supports(feature)
+		if sourceSupports == targetSupports {
+			continue
+		}
+		side, lacking := "target", target
+		if !sourceSupports {
+			side, lacking = "source", source
+		}
+		name, exists := featureNames[feature]
+		if !exists {
+			name = feature
+		}
+		differences = append(differences, Difference{
+			Type:        "FeatureUnsupported",
+			Severity:    SeverityInfo,
+			ObjectKind:  KindFeature,
+			ObjectName:  feature,
+			SubObject:   feature,
+			SourceValue: supportLabel(sourceSupports),
+			TargetValue: supportLabel(targetSupports),
+			Description: fmt.Sprintf("Not compared: the %s%s does not support %s", side, describeServer(lacking), name),
+		})
+	}
+
+	strip := func(s *schema.Schema) *schema.Schema {
+		copied := *s
+		copied.Tables = make(map[string]schema.TableInfo, len(s.Tables))
+		for tableName, table := range s.Tables {
+			if unsupported[schema.FeatureMaterializedViews] && table.View != nil && table.View.Materialized {
+				continue
+			}
+			copied.Tables[tableName] = withoutFeatures(table, unsupported)
+		}
+		if unsupported[schema.FeatureSequences] {
+			copied.Sequences = nil
+		}
+		if unsupported[schema.FeatureFunctions] {
+			copied.Functions = nil
+		}
+		return &copied
+	}
+	return strip(source), strip(target), differences
+}
+
+// withoutFeatures clears the properties of the given features from a table.
+//
+// Parameters:
+//   - table: Table to clear
+//   - features: Set of features whose properties are cleared
+//
+// Returns:
+//   - schema.TableInfo: Copy of the table without the properties
+func withoutFeatures(table schema.TableInfo, features map[string]bool) schema.TableInfo {
+	if features[schema.FeaturePartitioning] {
+		table.PartitionKey = ""
+		table.PartitionOf = ""
+	}
+	if features[schema.FeatureIndexes] {
+		table.Indexes = nil
+	}
+	if features[schema.FeatureTimescale] {
+		table.Hypertable = nil
+	}
+	if features[schema.FeatureCitus] {
+		table.Distribution = nil
+	}
+	if features[schema.FeatureTriggers] {
+		table.Triggers = nil
+	}
+	if features[schema.FeatureIdentity] || features[schema.FeatureCompression] {
+		columns := make([]schema.ColumnInfo, len(table.Columns))
+		for i, col := range table.Columns {
+			if features[schema.FeatureIdentity] {
+				col.IsIdentity = false
+			}
+			if features[schema.FeatureCompression] {
+				col.Compression = ""
+			}
+			columns[i] = col
+		}
+		table.Columns = columns
+	}
+	return table
+}
+
+// supportLabel formats whether a side supports a feature, as shown in differences.
+func supportLabel(supported bool) string {
+	if supported {
+		return "supported"
+	}
+	return "unsupported"
+}
+
+// describeServer formats the server version of a schema for display (e.g., " (PostgreSQL
+// 9.6.24)"), or returns an empty string if it is unknown.
+func describeServer(s *schema.Schema) string {
+	if s.ServerVersion == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (PostgreSQL %s)", schema.FormatServerVersion(s.ServerVersion))
+}
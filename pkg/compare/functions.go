@@ -0,0 +1,89 @@
+package compare
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+	"github.com/guriandoro/pg_schema_check/pkg/sqlnorm"
+)
+
+// compareFunctions compares the functions and procedures of two schemas, matched by signature:
+// those missing on one side, and the definitions of those on both. Definitions are compared
+// once normalized (see sqlnorm.Normalize), as view definitions are, so that functions written
+// with other whitespace, comments, keyword case, or quoting do not differ. Owners are compared
+// with Options.CompareOwners.
+//
+// Parameters:
+//   - source: The source schema to compare from
+//   - target: The target schema to compare against
+//   - opts: Options controlling the comparison
+//
+// Returns:
+//   - []Difference: MissingFunction, ExtraFunction, FunctionDefinitionMismatch, and FunctionOwnerMismatch differences
+func compareFunctions(source, target *schema.Schema, opts Options) []Difference {
+	var differences []Difference
+	for _, signature := range sortedSignatures(source) {
+		sourceFn := source.Functions[signature]
+		targetFn, exists := target.Functions[signature]
+		if !exists {
+			differences = append(differences, Difference{
+				Type:        "MissingFunction",
+				ObjectKind:  KindFunction,
+				ObjectName:  signature,
+				SubObject:   signature,
+				Description: fmt.Sprintf("Function '%s' exists in source but not in target", signature),
+			})
+			continue
+		}
+
+		if opts.CompareOwners && sourceFn.Owner != targetFn.Owner {
+			differences = append(differences, Difference{
+				Type:        "FunctionOwnerMismatch",
+				ObjectKind:  KindFunction,
+				ObjectName:  signature,
+				SubObject:   signature,
+				SourceValue: sourceFn.Owner,
+				TargetValue: targetFn.Owner,
+				Description: fmt.Sprintf("Function '%s' has different owners: source=%s, target=%s", signature, sourceFn.Owner, targetFn.Owner),
+			})
+		}
+
+		if sqlnorm.Equal(sourceFn.Definition, targetFn.Definition) {
+			continue
+		}
+		sourceValue, targetValue := sqlnorm.Normalize(sourceFn.Definition), sqlnorm.Normalize(targetFn.Definition)
+		differences = append(differences, Difference{
+			Type:        "FunctionDefinitionMismatch",
+			ObjectKind:  KindFunction,
+			ObjectName:  signature,
+			SubObject:   signature,
+			SourceValue: sourceValue,
+			TargetValue: targetValue,
+			Description: fmt.Sprintf("Function '%s' has different definitions: source=%s, target=%s", signature, sourceValue, targetValue),
+		})
+	}
+
+	for _, signature := range sortedSignatures(target) {
+		if _, exists := source.Functions[signature]; !exists {
+			differences = append(differences, Difference{
+				Type:        "ExtraFunction",
+				ObjectKind:  KindFunction,
+				ObjectName:  signature,
+				SubObject:   signature,
+				Description: fmt.Sprintf("Function '%s' exists in target but not in source", signature),
+			})
+		}
+	}
+	return differences
+}
+
+// sortedSignatures returns the signatures of the functions of a schema in sorted order.
+func sortedSignatures(s *schema.Schema) []string {
+	signatures := make([]string, 0, len(s.Functions))
+	for signature := range s.Functions {
+		signatures = append(signatures, signature)
+	}
+	sort.Strings(signatures)
+	return signatures
+}
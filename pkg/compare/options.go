@@ -0,0 +1,201 @@
+package compare
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// Option configures a comparison. Options values are themselves an Option that replaces every
+// setting, so existing callers passing an Options struct keep working; the With* functions
+// adjust a single setting and can be combined with an Options value given before them:
+//
+//	compare.CompareSchemas(source, target,
+//		compare.Options{CompareOwners: true},
+//		compare.WithIgnoreDefaults(),
+//		compare.WithSeverityMap(map[string]string{"ExtraIndex": compare.SeverityWarning}),
+//	)
+type Option interface {
+	apply(*Options)
+}
+
+// optionFunc is an Option implemented by a function.
+type optionFunc func(*Options)
+
+// apply calls the function.
+func (f optionFunc) apply(o *Options) {
+	f(o)
+}
+
+// apply replaces every setting with those of the Options value.
+func (o Options) apply(target *Options) {
+	*target = o
+}
+
+// WithIgnoreCase compares the names of tables, columns, indexes, and constraints
+// case-insensitively, so that objects created with quoted mixed-case names match their
+// lower-case counterparts. Differences are reported with lower-case names.
+func WithIgnoreCase() Option {
+	return optionFunc(func(o *Options) {
+		o.IgnoreCase = true
+	})
+}
+
+// WithIgnoreDefaults skips the comparison of column default values in every table.
+func WithIgnoreDefaults() Option {
+	return optionFunc(func(o *Options) {
+		o.IgnoreDefaults = true
+	})
+}
+
+// WithTypeNormalizer maps every column data type through normalize before comparing, so that
+// equivalent spellings (e.g., "int4" and "integer") do not produce differences.
+func WithTypeNormalizer(normalize func(dataType string) string) Option {
+	return optionFunc(func(o *Options) {
+		o.TypeNormalizer = normalize
+	})
+}
+
+// WithSeverityMap assigns severities to the differences by type, as ApplySeverityOverrides does.
+// Differences whose severity is SeverityIgnore are dropped.
+func WithSeverityMap(severities map[string]string) Option {
+	return optionFunc(func(o *Options) {
+		o.SeverityMap = severities
+	})
+}
+
+// WithConcurrency compares up to n tables at once, as Options.Concurrency does. The differences
+// are reported in the same order whatever n is.
+func WithConcurrency(n int) Option {
+	return optionFunc(func(o *Options) {
+		o.Concurrency = n
+	})
+}
+
+// WithFilter removes objects from copies of the schemas before they are compared, as
+// Options.Filter does.
+func WithFilter(filter func(source, target *schema.Schema) error) Option {
+	return optionFunc(func(o *Options) {
+		o.Filter = filter
+	})
+}
+
+// resolveOptions applies the options in order to the zero Options.
+//
+// Parameters:
+//   - opts: Options to apply
+//
+// Returns:
+//   - Options: Resulting settings
+func resolveOptions(opts []Option) Options {
+	var resolved Options
+	for _, opt := range opts {
+		if opt != nil {
+			opt.apply(&resolved)
+		}
+	}
+	return resolved
+}
+
+// normalizeType returns the data type as compared, after applying the TypeNormalizer.
+func (o Options) normalizeType(dataType string) string {
+	if o.TypeNormalizer == nil {
+		return dataType
+	}
+	return o.TypeNormalizer(dataType)
+}
+
+// foldCase returns a copy of the schema with the names of every table, column, index,
+// constraint, trigger, and sequence in lower case, for comparisons with IgnoreCase.
+//
+// Parameters:
+//   - s: Schema to copy
+//
+// Returns:
+//   - *schema.Schema: Copy of the schema with lower-case names
+func foldCase(s *schema.Schema) *schema.Schema {
+	lower := func(names []string) []string {
+		for i, name := range names {
+			names[i] = strings.ToLower(name)
+		}
+		return names
+	}
+
+	copied := s.Clone()
+	folded := make(map[string]schema.TableInfo, len(copied.Tables))
+	for _, table := range copied.Tables {
+		table.Name = strings.ToLower(table.Name)
+		table.PartitionOf = strings.ToLower(table.PartitionOf)
+		for i := range table.Columns {
+			table.Columns[i].Name = strings.ToLower(table.Columns[i].Name)
+		}
+		lower(table.PrimaryKeys)
+		for i := range table.Indexes {
+			table.Indexes[i].Name = strings.ToLower(table.Indexes[i].Name)
+			lower(table.Indexes[i].Columns)
+		}
+		for i := range table.ForeignKeys {
+			fk := &table.ForeignKeys[i]
+			fk.Name = strings.ToLower(fk.Name)
+			fk.ReferencedTable = strings.ToLower(fk.ReferencedTable)
+			lower(fk.Columns)
+			lower(fk.ReferencedColumns)
+		}
+		for i := range table.Triggers {
+			table.Triggers[i].Name = strings.ToLower(table.Triggers[i].Name)
+			lower(table.Triggers[i].Columns)
+		}
+		folded[table.Name] = table
+	}
+	copied.Tables = folded
+	for i := range copied.Errors {
+		copied.Errors[i].Table = strings.ToLower(copied.Errors[i].Table)
+	}
+	if copied.RowCounts != nil {
+		counts := make(map[string]int64, len(copied.RowCounts))
+		for name, count := range copied.RowCounts {
+			counts[strings.ToLower(name)] = count
+		}
+		copied.RowCounts = counts
+	}
+	if copied.DataChecksums != nil {
+		checksums := make(map[string]schema.DataChecksum, len(copied.DataChecksums))
+		for name, checksum := range copied.DataChecksums {
+			sort.Strings(lower(checksum.Columns))
+			checksums[strings.ToLower(name)] = checksum
+		}
+		copied.DataChecksums = checksums
+	}
+	if copied.Sequences != nil {
+		sequences := make(map[string]schema.SequenceInfo, len(copied.Sequences))
+		for _, seq := range copied.Sequences {
+			seq.Name = strings.ToLower(seq.Name)
+			sequences[seq.Name] = seq
+		}
+		copied.Sequences = sequences
+	}
+	if copied.Functions != nil {
+		functions := make(map[string]schema.FunctionInfo, len(copied.Functions))
+		for _, fn := range copied.Functions {
+			fn.Name = strings.ToLower(fn.Name)
+			functions[fn.Signature()] = fn
+		}
+		copied.Functions = functions
+	}
+	if copied.Sizes != nil {
+		sizes := make(map[string]schema.TableSize, len(copied.Sizes))
+		for name, size := range copied.Sizes {
+			if size.Indexes != nil {
+				indexes := make(map[string]int64, len(size.Indexes))
+				for index, bytes := range size.Indexes {
+					indexes[strings.ToLower(index)] = bytes
+				}
+				size.Indexes = indexes
+			}
+			sizes[strings.ToLower(name)] = size
+		}
+		copied.Sizes = sizes
+	}
+	return copied
+}
@@ -0,0 +1,39 @@
+package compare
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// parallelFor calls fn with every index from 0 to n-1, up to workers calls at a time. Calls are
+// made in index order when workers is one or less; otherwise fn must be safe for concurrent use,
+// and its results should be stored by index to keep them in a deterministic order.
+//
+// Parameters:
+//   - n: Number of indexes
+//   - workers: Maximum number of concurrent calls
+//   - fn: Function to call with each index
+func parallelFor(n, workers int, fn func(i int)) {
+	workers = min(workers, n)
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	var (
+		next atomic.Int64 // Next index to call fn with
+		wg   sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int(next.Add(1)) - 1; i < n; i = int(next.Add(1)) - 1 {
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,216 @@
+package compare
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// Comparator compares one kind of object (tables, indexes, or a custom kind such as
+// row-level security policies) between two schemas. Comparators added with Register take part
+// in every call to CompareSchemas. Custom object kinds can be carried in schema.Schema.Extensions.
+//
+// Types of difference describing an object present on one side only should start with
+// "Missing" (only in source) or "Extra" (only in target), so that the comparison direction
+// and the extra_severity setting apply to them.
+type Comparator interface {
+	// Name returns the unique name of the comparator (e.g., "indexes").
+	Name() string
+
+	// DifferenceTypes returns every type of difference the comparator can report.
+	DifferenceTypes() []string
+
+	// Compare returns the differences found between the source and target schemas.
+	Compare(source, target *schema.Schema, opts Options) []Difference
+}
+
+// TableCompareFunc compares one aspect of a table that exists in both schemas.
+type TableCompareFunc func(tableName string, source, target schema.TableInfo, opts Options) []Difference
+
+// tableComparator is a Comparator that runs a TableCompareFunc on every table present in both schemas.
+type tableComparator struct {
+	name  string           // Unique name of the comparator
+	types []string         // Types of difference the comparator can report
+	fn    TableCompareFunc // Comparison run on each table
+}
+
+// PerTable creates a Comparator that runs fn on every table present in both schemas, in table
+// name order. It is the simplest way to add checks of custom table properties. Tables whose
+// definitions are identical on both sides (see schema.TableInfo.Checksum) are skipped, since
+// comparing them cannot find differences. With Options.Concurrency above one, fn is called for
+// several tables at once, and must then be safe for concurrent use.
+//
+// Parameters:
+//   - name: Unique name of the comparator
+//   - types: Types of difference fn can report
+//   - fn: Comparison to run on each table
+//
+// Returns:
+//   - Comparator: Comparator ready to be registered
+func PerTable(name string, types []string, fn TableCompareFunc) Comparator {
+	return tableComparator{name: name, types: types, fn: fn}
+}
+
+// Name returns the unique name of the comparator.
+func (c tableComparator) Name() string {
+	return c.name
+}
+
+// DifferenceTypes returns every type of difference the comparator can report.
+func (c tableComparator) DifferenceTypes() []string {
+	return c.types
+}
+
+// Compare runs the comparison on every table present in both schemas whose definitions differ,
+// up to opts.Concurrency tables at a time. The differences are returned in table name order
+// whatever the concurrency.
+func (c tableComparator) Compare(source, target *schema.Schema, opts Options) []Difference {
+	perTable := make([][]Difference, len(opts.changed))
+	parallelFor(len(opts.changed), opts.Concurrency, func(i int) {
+		tableName := opts.changed[i]
+		sourceTable, inSource := source.Tables[tableName]
+		targetTable, inTarget := target.Tables[tableName]
+		if inSource && inTarget {
+			perTable[i] = c.fn(tableName, sourceTable, targetTable, opts)
+		}
+	})
+
+	var differences []Difference
+	for _, tableDifferences := range perTable {
+		differences = append(differences, tableDifferences...)
+	}
+	return differences
+}
+
+// schemaComparator is a Comparator backed by a function comparing whole schemas.
+type schemaComparator struct {
+	name  string                                                         // Unique name of the comparator
+	types []string                                                       // Types of difference the comparator can report
+	fn    func(source, target *schema.Schema, opts Options) []Difference // Comparison of the schemas
+}
+
+// Name returns the unique name of the comparator.
+func (c schemaComparator) Name() string {
+	return c.name
+}
+
+// DifferenceTypes returns every type of difference the comparator can report.
+func (c schemaComparator) DifferenceTypes() []string {
+	return c.types
+}
+
+// Compare runs the comparison on the schemas.
+func (c schemaComparator) Compare(source, target *schema.Schema, opts Options) []Difference {
+	return c.fn(source, target, opts)
+}
+
+// registry holds the registered comparators, in registration order.
+var (
+	registryMu  sync.RWMutex
+	comparators []Comparator
+)
+
+// Register adds a comparator to the set run by CompareSchemas, after the ones already
+// registered. The types of difference it declares become known types for severity overrides
+// and configuration validation. Register panics if a comparator with the same name is
+// already registered, mirroring database/sql.Register.
+//
+// Parameters:
+//   - c: Comparator to register
+func Register(c Comparator) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, existing := range comparators {
+		if existing.Name() == c.Name() {
+			panic(fmt.Sprintf("compare: comparator %s is already registered", c.Name()))
+		}
+	}
+	comparators = append(comparators, c)
+
+	for _, diffType := range c.DifferenceTypes() {
+		if !isKnownTypeLocked(diffType) {
+			differenceTypes = append(differenceTypes, diffType)
+		}
+	}
+}
+
+// Comparators returns the registered comparators, in the order they run.
+//
+// Returns:
+//   - []Comparator: Registered comparators
+func Comparators() []Comparator {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return append([]Comparator(nil), comparators...)
+}
+
+// init registers the built-in comparators
+func init() {
+	Register(schemaComparator{
+		name:  "tables",
+		types: []string{"MissingTable", "ExtraTable", "MissingView", "ExtraView", "MissingMaterializedView", "ExtraMaterializedView", "OwnerMismatch", "PartitionKeyMismatch", "PartitionParentMismatch"},
+		fn:    compareTables,
+	})
+	Register(PerTable("columns",
+		[]string{"MissingColumn", "ExtraColumn", "ColumnTypeMismatch", "ColumnNullableMismatch", "ColumnDefaultMismatch", "ColumnIdentityMismatch", "ColumnCompressionMismatch"},
+		func(tableName string, source, target schema.TableInfo, opts Options) []Difference {
+			return compareColumns(tableName, source.Columns, target.Columns, opts)
+		}))
+	Register(PerTable("primary-keys",
+		[]string{"PrimaryKeyMismatch"},
+		func(tableName string, source, target schema.TableInfo, opts Options) []Difference {
+			return comparePrimaryKeys(tableName, source.PrimaryKeys, target.PrimaryKeys)
+		}))
+	Register(PerTable("indexes",
+		[]string{"MissingIndex", "ExtraIndex", "IndexUniqueMismatch", "IndexColumnsMismatch", "IndexIncludeMismatch", "IndexPredicateMismatch", "IndexMethodMismatch"},
+		func(tableName string, source, target schema.TableInfo, opts Options) []Difference {
+			return compareIndexes(tableName, source.Indexes, target.Indexes)
+		}))
+	Register(PerTable("foreign-keys",
+		[]string{"MissingForeignKey", "ExtraForeignKey", "ForeignKeyReferenceMismatch", "ForeignKeyColumnsMismatch", "ForeignKeyReferencedColumnsMismatch", "ForeignKeyOptionsMismatch"},
+		func(tableName string, source, target schema.TableInfo, opts Options) []Difference {
+			return compareForeignKeys(tableName, source.ForeignKeys, target.ForeignKeys)
+		}))
+	Register(PerTable("triggers",
+		[]string{"MissingTrigger", "ExtraTrigger", "TriggerTimingMismatch", "TriggerEventsMismatch", "TriggerLevelMismatch", "TriggerConditionMismatch", "TriggerFunctionMismatch"},
+		func(tableName string, source, target schema.TableInfo, opts Options) []Difference {
+			return compareTriggers(tableName, source.Triggers, target.Triggers)
+		}))
+	Register(PerTable("views",
+		[]string{"ViewDefinitionMismatch", "MaterializedViewPopulatedMismatch"},
+		func(tableName string, source, target schema.TableInfo, opts Options) []Difference {
+			return compareViews(tableName, source, target)
+		}))
+	Register(PerTable("timescaledb",
+		[]string{"HypertableMismatch"},
+		func(tableName string, source, target schema.TableInfo, opts Options) []Difference {
+			return compareHypertables(tableName, source, target)
+		}))
+	Register(PerTable("citus",
+		[]string{"DistributionMismatch"},
+		func(tableName string, source, target schema.TableInfo, opts Options) []Difference {
+			return compareDistribution(tableName, source, target)
+		}))
+	Register(schemaComparator{
+		name:  "sequences",
+		types: []string{"MissingSequence", "ExtraSequence", "SequenceMismatch", "SequenceOwnerMismatch"},
+		fn:    compareSequences,
+	})
+	Register(schemaComparator{
+		name:  "functions",
+		types: []string{"MissingFunction", "ExtraFunction", "FunctionDefinitionMismatch", "FunctionOwnerMismatch"},
+		fn:    compareFunctions,
+	})
+	Register(schemaComparator{
+		name:  "row-counts",
+		types: []string{"RowCountMismatch"},
+		fn:    compareRowCounts,
+	})
+	Register(schemaComparator{
+		name:  "data",
+		types: []string{"DataMismatch"},
+		fn:    compareDataChecksums,
+	})
+}
@@ -0,0 +1,144 @@
+package compare
+
+import "github.com/guriandoro/pg_schema_check/pkg/schema"
+
+// Key identifies the object and aspect a difference is about. Differences that describe the
+// same thing have the same key, and Difference.Parent refers to another difference by its key.
+//
+// Returns:
+//   - string: Key of the difference (e.g., "MissingColumn/orders/customer_id")
+func (d Difference) Key() string {
+	return d.Type + "/" + d.Table + "/" + d.SubObject
+}
+
+// dedupe removes differences identical to one reported before them, which can happen when
+// custom comparators overlap with the built-in ones.
+//
+// Parameters:
+//   - differences: Differences to deduplicate
+//
+// Returns:
+//   - []Difference: Differences in their original order, each reported once
+func dedupe(differences []Difference) []Difference {
+	seen := make(map[Difference]bool, len(differences))
+	var unique []Difference
+	for _, diff := range differences {
+		if seen[diff] {
+			continue
+		}
+		seen[diff] = true
+		unique = append(unique, diff)
+	}
+	return unique
+}
+
+// linkRelated sets the Parent of every difference that is a consequence of another one, so
+// that reports can show it under its cause instead of as an unrelated entry:
+//   - a foreign key, or a partition's parent, referring to a table missing on one side is
+//     linked to that table's MissingTable or ExtraTable difference;
+//   - an index, foreign key, or primary key using a column missing on one side is linked to
+//     that column's MissingColumn or ExtraColumn difference;
+//   - those referring to a renamed table or using a renamed column, by either name, are linked
+//     to the difference about the rename.
+//
+// Parameters:
+//   - differences: Differences to link, modified in place
+//   - causes: Differences that can be the cause of others; usually differences itself
+//   - source: The source schema the differences were found in
+//   - target: The target schema the differences were found in
+func linkRelated(differences, causes []Difference, source, target *schema.Schema) {
+	tables := make(map[string]string)  // Key of the Missing/ExtraTable difference, by table
+	columns := make(map[string]string) // Key of the Missing/ExtraColumn difference, by table and column
+	for _, diff := range causes {
+		switch diff.Type {
+		case "MissingTable", "ExtraTable":
+			tables[diff.Table] = diff.Key()
+		case "MissingColumn", "ExtraColumn":
+			columns[diff.Table+"."+diff.SubObject] = diff.Key()
+		case "TableRename", "PossibleTableRename":
+			tables[diff.SourceValue], tables[diff.TargetValue] = diff.Key(), diff.Key()
+		case "ColumnRename", "PossibleColumnRename":
+			columns[diff.Table+"."+diff.SourceValue], columns[diff.Table+"."+diff.TargetValue] = diff.Key(), diff.Key()
+		}
+	}
+	if len(tables) == 0 && len(columns) == 0 {
+		return
+	}
+
+	// columnParent returns the key of the difference about the first missing column used
+	columnParent := func(tableName string, cols []string) string {
+		for _, col := range cols {
+			if key, exists := columns[tableName+"."+col]; exists {
+				return key
+			}
+		}
+		return ""
+	}
+
+	for i, diff := range differences {
+		if diff.Parent != "" {
+			continue
+		}
+
+		switch diff.ObjectKind {
+		case KindForeignKey:
+			for _, s := range []*schema.Schema{source, target} {
+				fk, ok := findForeignKey(s, diff.Table, diff.SubObject)
+				if !ok {
+					continue
+				}
+				if key, exists := tables[fk.ReferencedTable]; exists && fk.ReferencedTable != diff.Table {
+					differences[i].Parent = key
+				} else if key := columnParent(diff.Table, fk.Columns); key != "" {
+					differences[i].Parent = key
+				}
+				break
+			}
+		case KindIndex:
+			for _, s := range []*schema.Schema{source, target} {
+				if idx, ok := findIndex(s, diff.Table, diff.SubObject); ok {
+					differences[i].Parent = columnParent(diff.Table, idx.Columns)
+					break
+				}
+			}
+		case KindPrimaryKey:
+			for _, s := range []*schema.Schema{source, target} {
+				if table, ok := s.Tables[diff.Table]; ok {
+					if key := columnParent(diff.Table, table.PrimaryKeys); key != "" {
+						differences[i].Parent = key
+						break
+					}
+				}
+			}
+		case KindTable:
+			if diff.Type == "PartitionParentMismatch" {
+				for _, parent := range []string{diff.SourceValue, diff.TargetValue} {
+					if key, exists := tables[parent]; exists {
+						differences[i].Parent = key
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+// findForeignKey returns a foreign key of a table in a schema.
+func findForeignKey(s *schema.Schema, tableName, name string) (schema.ForeignKeyInfo, bool) {
+	for _, fk := range s.Tables[tableName].ForeignKeys {
+		if fk.Name == name {
+			return fk, true
+		}
+	}
+	return schema.ForeignKeyInfo{}, false
+}
+
+// findIndex returns an index of a table in a schema.
+func findIndex(s *schema.Schema, tableName, name string) (schema.IndexInfo, bool) {
+	for _, idx := range s.Tables[tableName].Indexes {
+		if idx.Name == name {
+			return idx, true
+		}
+	}
+	return schema.IndexInfo{}, false
+}
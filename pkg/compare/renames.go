@@ -0,0 +1,283 @@
+package compare
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// MinRenameSimilarity is the share of columns two tables must have in common, by name and type,
+// for a table missing on one side and one extra on the other to be reported as a probable rename.
+const MinRenameSimilarity = 0.8
+
+// columnSeparator separates the name of a table from the name of its column in the names of
+// columns paired by renames, as no identifier can contain it.
+const columnSeparator = "\x00"
+
+// detectRenames replaces pairs of differences about a table or column missing on one side and
+// another extra on the other by a single difference about its rename: a TableRename or
+// ColumnRename when Options.Renames confirms it, or else, with Options.DetectRenames, a
+// PossibleTableRename or PossibleColumnRename when their structures are near-identical. A table
+// is paired with the table of the other side sharing most of its columns, if at least
+// MinRenameSimilarity of them and no other table shares as many; a column with the only column
+// of the other side of the same table with the same type, nullability, and identity.
+//
+// Parameters:
+//   - differences: Differences reported by the comparators
+//   - source: The source schema the differences were found in
+//   - target: The target schema the differences were found in
+//   - opts: Options of the comparison
+//
+// Returns:
+//   - []Difference: Differences with the renames in place of the pairs they replace
+func detectRenames(differences []Difference, source, target *schema.Schema, opts Options) []Difference {
+	if !opts.DetectRenames && len(opts.Renames) == 0 {
+		return differences
+	}
+
+	// Confirmed renames, as pairs of the source and target names
+	confirmed := make(map[string]string)
+	for old, renamed := range opts.Renames {
+		table, oldColumn, isColumn := strings.Cut(old, ".")
+		if isColumn {
+			old = oldColumn
+		}
+		sourceName, targetName := renamed, old
+		if opts.Direction == DirectionTargetToSource {
+			sourceName, targetName = old, renamed
+		}
+		if isColumn {
+			sourceName, targetName = table+columnSeparator+sourceName, table+columnSeparator+targetName
+		}
+		confirmed[sourceName] = targetName
+	}
+
+	var missing, extra []string
+	for _, diff := range differences {
+		switch diff.Type {
+		case "MissingTable":
+			missing = append(missing, diff.Table)
+		case "ExtraTable":
+			extra = append(extra, diff.Table)
+		case "MissingColumn":
+			missing = append(missing, diff.Table+columnSeparator+diff.SubObject)
+		case "ExtraColumn":
+			extra = append(extra, diff.Table+columnSeparator+diff.SubObject)
+		}
+	}
+	if len(missing) == 0 || len(extra) == 0 {
+		return differences
+	}
+
+	// Pairs of the source and target names of renamed objects, confirmed first
+	renames := make(map[string]rename)
+	renamedTo := make(map[string]bool)
+	for _, sourceName := range missing {
+		if targetName, ok := confirmed[sourceName]; ok && slices.Contains(extra, targetName) && !renamedTo[targetName] {
+			renames[sourceName] = rename{target: targetName, confirmed: true}
+			renamedTo[targetName] = true
+		}
+	}
+	if opts.DetectRenames {
+		for sourceName, r := range probableRenames(missing, extra, renames, renamedTo, source, target, opts) {
+			renames[sourceName] = r
+		}
+	}
+	if len(renames) == 0 {
+		return differences
+	}
+
+	var result []Difference
+	for _, diff := range differences {
+		switch diff.Type {
+		case "MissingTable", "MissingColumn":
+			name := diff.Table
+			if diff.Type == "MissingColumn" {
+				name += columnSeparator + diff.SubObject
+			}
+			if r, ok := renames[name]; ok {
+				result = append(result, renameDifference(diff, r, opts.Direction))
+				continue
+			}
+		case "ExtraTable", "ExtraColumn":
+			name := diff.Table
+			if diff.Type == "ExtraColumn" {
+				name += columnSeparator + diff.SubObject
+			}
+			if renamedTo[name] {
+				continue
+			}
+		}
+		result = append(result, diff)
+	}
+	return result
+}
+
+// rename is an object of the source paired with an object of the target it was renamed from or to.
+type rename struct {
+	target     string  // Name of the object in the target, qualified by its table for columns
+	confirmed  bool    // Whether Options.Renames confirms the rename
+	similarity float64 // Share of columns the tables have in common, for probable table renames
+}
+
+// probableRenames pairs the objects missing on one side with the near-identical objects extra on
+// the other, leaving out those already paired.
+//
+// Parameters:
+//   - missing: Names of the tables, and table-qualified names of the columns, missing from the target
+//   - extra: Names of the tables, and table-qualified names of the columns, extra in the target
+//   - renames: Renames already paired, keyed by source name
+//   - renamedTo: Target names already paired, to which the new pairs are added
+//   - source: The source schema
+//   - target: The target schema
+//   - opts: Options of the comparison, whose type normalizer applies
+//
+// Returns:
+//   - map[string]rename: Probable renames, keyed by source name
+func probableRenames(missing, extra []string, renames map[string]rename, renamedTo map[string]bool, source, target *schema.Schema, opts Options) map[string]rename {
+	// Similarity of each unpaired missing object with each unpaired extra one; zero if they cannot match
+	similarity := func(sourceName, targetName string) float64 {
+		sourceTable, sourceColumn, sourceIsColumn := strings.Cut(sourceName, columnSeparator)
+		targetTable, targetColumn, targetIsColumn := strings.Cut(targetName, columnSeparator)
+		if sourceIsColumn != targetIsColumn {
+			return 0
+		}
+		if !sourceIsColumn {
+			return columnOverlap(source.Tables[sourceName], target.Tables[targetName], opts)
+		}
+		if sourceTable != targetTable {
+			return 0
+		}
+		sourceCol, ok := findColumn(source.Tables[sourceTable], sourceColumn)
+		targetCol, found := findColumn(target.Tables[targetTable], targetColumn)
+		if !ok || !found || opts.normalizeType(sourceCol.Type) != opts.normalizeType(targetCol.Type) ||
+			sourceCol.Nullable != targetCol.Nullable || sourceCol.IsIdentity != targetCol.IsIdentity {
+			return 0
+		}
+		return 1
+	}
+
+	// best returns the candidate matching a name best, if it is the only one that good
+	best := func(name string, candidates []string, score func(candidate string) float64) (string, float64) {
+		var bestName string
+		var bestScore float64
+		unique := false
+		for _, candidate := range candidates {
+			switch s := score(candidate); {
+			case s > bestScore:
+				bestName, bestScore, unique = candidate, s, true
+			case s == bestScore && s > 0:
+				unique = false
+			}
+		}
+		if !unique || bestScore < MinRenameSimilarity {
+			return "", 0
+		}
+		return bestName, bestScore
+	}
+
+	var unpairedMissing, unpairedExtra []string
+	for _, name := range missing {
+		if _, paired := renames[name]; !paired {
+			unpairedMissing = append(unpairedMissing, name)
+		}
+	}
+	for _, name := range extra {
+		if !renamedTo[name] {
+			unpairedExtra = append(unpairedExtra, name)
+		}
+	}
+	sort.Strings(unpairedMissing)
+
+	// Objects are paired when each is the other's only best match
+	probable := make(map[string]rename)
+	for _, sourceName := range unpairedMissing {
+		targetName, score := best(sourceName, unpairedExtra, func(candidate string) float64 {
+			return similarity(sourceName, candidate)
+		})
+		if targetName == "" || renamedTo[targetName] {
+			continue
+		}
+		if back, _ := best(targetName, unpairedMissing, func(candidate string) float64 {
+			return similarity(candidate, targetName)
+		}); back != sourceName {
+			continue
+		}
+		probable[sourceName] = rename{target: targetName, similarity: score}
+		renamedTo[targetName] = true
+	}
+	return probable
+}
+
+// columnOverlap returns the share of the columns of two tables that both have, with the same
+// name and type, out of the columns either has.
+func columnOverlap(source, target schema.TableInfo, opts Options) float64 {
+	columns := make(map[string]bool, len(source.Columns))
+	for _, col := range source.Columns {
+		columns[col.Name+" "+opts.normalizeType(col.Type)] = true
+	}
+	shared := 0
+	for _, col := range target.Columns {
+		if columns[col.Name+" "+opts.normalizeType(col.Type)] {
+			shared++
+		}
+	}
+	all := len(source.Columns) + len(target.Columns) - shared
+	if all == 0 {
+		return 0
+	}
+	return float64(shared) / float64(all)
+}
+
+// renameDifference returns the difference reporting a rename in place of the difference about
+// the object missing from the target.
+//
+// Parameters:
+//   - missing: MissingTable or MissingColumn difference of the renamed object
+//   - r: Rename of the object
+//   - direction: Direction of the comparison, which tells the old name from the new one
+//
+// Returns:
+//   - Difference: TableRename, ColumnRename, PossibleTableRename, or PossibleColumnRename difference
+func renameDifference(missing Difference, r rename, direction string) Difference {
+	diff := Difference{Table: missing.Table, ObjectKind: missing.ObjectKind, SubObject: missing.SubObject}
+	kind, detail := "Table", ""
+	diff.SourceValue, diff.TargetValue = missing.Table, r.target
+	if missing.Type == "MissingColumn" {
+		kind = "Column"
+		_, targetColumn, _ := strings.Cut(r.target, columnSeparator)
+		diff.SourceValue, diff.TargetValue = missing.SubObject, targetColumn
+	}
+
+	// The schema being changed has the old name
+	old, renamed := diff.TargetValue, diff.SourceValue
+	if direction == DirectionTargetToSource {
+		old, renamed = renamed, old
+	}
+	switch {
+	case r.confirmed:
+		diff.Type = kind + "Rename"
+		diff.Description = fmt.Sprintf("%s renamed: %s → %s", kind, old, renamed)
+		return diff
+	case kind == "Table":
+		detail = fmt.Sprintf("%.0f%% of columns match", r.similarity*100)
+	default:
+		detail = "same type, nullability, and identity"
+	}
+	diff.Type = "Possible" + kind + "Rename"
+	diff.Description = fmt.Sprintf("%s probably renamed: %s → %s (%s); confirm it to have it renamed by the sync SQL", kind, old, renamed, detail)
+	return diff
+}
+
+// findColumn returns a column of a table.
+func findColumn(table schema.TableInfo, name string) (schema.ColumnInfo, bool) {
+	for _, col := range table.Columns {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return schema.ColumnInfo{}, false
+}
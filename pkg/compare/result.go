@@ -0,0 +1,173 @@
+package compare
+
+import (
+	"io"
+	"sort"
+)
+
+// DiffResult is the list of differences returned by a comparison, with helpers to query it.
+// Its underlying type is []Difference, so it can be used anywhere a slice of differences is expected.
+type DiffResult []Difference
+
+// Renderer is implemented by the output formats a DiffResult can be rendered in.
+type Renderer interface {
+	// Render writes the differences to w.
+	Render(result DiffResult, w io.Writer) error
+}
+
+// Filter returns the differences for which keep returns true.
+//
+// Parameters:
+//   - keep: Function deciding whether a difference is kept
+//
+// Returns:
+//   - DiffResult: Differences that were kept, in their original order
+func (r DiffResult) Filter(keep func(Difference) bool) DiffResult {
+	var filtered DiffResult
+	for _, diff := range r {
+		if keep(diff) {
+			filtered = append(filtered, diff)
+		}
+	}
+	return filtered
+}
+
+// TopLevel returns the differences that are not a consequence of another difference in the
+// result, that is, those without a Parent or whose parent is not part of the result (e.g.,
+// because it was suppressed).
+//
+// Returns:
+//   - DiffResult: Top-level differences, in their original order
+func (r DiffResult) TopLevel() DiffResult {
+	keys := make(map[string]bool, len(r))
+	for _, diff := range r {
+		keys[diff.Key()] = true
+	}
+	return r.Filter(func(diff Difference) bool {
+		return diff.Parent == "" || !keys[diff.Parent]
+	})
+}
+
+// Children returns the differences that are a consequence of the given one. Each call scans the
+// whole result; use ChildrenByParent to look up the children of many differences.
+//
+// Parameters:
+//   - parent: Difference whose consequences are returned
+//
+// Returns:
+//   - DiffResult: Differences whose Parent is the key of parent, in their original order
+func (r DiffResult) Children(parent Difference) DiffResult {
+	key := parent.Key()
+	return r.Filter(func(diff Difference) bool {
+		return diff.Parent == key
+	})
+}
+
+// ChildrenByParent groups the differences that are a consequence of another one by the key of
+// the difference they result from (see Difference.Key), in one pass over the result.
+//
+// Returns:
+//   - map[string]DiffResult: Differences keyed by their Parent, each in their original order
+func (r DiffResult) ChildrenByParent() map[string]DiffResult {
+	children := make(map[string]DiffResult)
+	for _, diff := range r {
+		if diff.Parent != "" {
+			children[diff.Parent] = append(children[diff.Parent], diff)
+		}
+	}
+	return children
+}
+
+// GroupByTable groups the differences by the table they were found in.
+//
+// Returns:
+//   - map[string]DiffResult: Differences keyed by table name, each in their original order
+func (r DiffResult) GroupByTable() map[string]DiffResult {
+	groups := make(map[string]DiffResult)
+	for _, diff := range r {
+		groups[diff.Table] = append(groups[diff.Table], diff)
+	}
+	return groups
+}
+
+// Tables returns the names of the tables that have at least one difference, sorted.
+//
+// Returns:
+//   - []string: Sorted table names
+func (r DiffResult) Tables() []string {
+	var tables []string
+	for table := range r.GroupByTable() {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// CountBySeverity counts the differences of each severity level.
+//
+// Returns:
+//   - map[string]int: Number of differences keyed by severity level
+func (r DiffResult) CountBySeverity() map[string]int {
+	return CountBySeverity(r)
+}
+
+// HasBlocking reports whether any difference has SeverityError, meaning that it must be fixed.
+//
+// Returns:
+//   - bool: True if at least one difference is blocking
+func (r DiffResult) HasBlocking() bool {
+	for _, diff := range r {
+		if diff.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Changes compares the differences with those of an earlier comparison of the same databases.
+// Two differences are the same if they are about the same object and aspect (see
+// Difference.Key) with the same values on both sides, so that a column whose type changes again
+// counts as a new difference, but a change of severity or description does not.
+//
+// Parameters:
+//   - previous: Differences found by the earlier comparison
+//
+// Returns:
+//   - DiffResult: Differences that the earlier comparison did not find
+//   - DiffResult: Differences of the earlier comparison that are no longer found
+func (r DiffResult) Changes(previous DiffResult) (DiffResult, DiffResult) {
+	identity := func(diff Difference) string {
+		return diff.Key() + "\x00" + diff.SourceValue + "\x00" + diff.TargetValue
+	}
+	seen := make(map[string]bool, len(previous))
+	for _, diff := range previous {
+		seen[identity(diff)] = true
+	}
+	var appeared DiffResult
+	found := make(map[string]bool, len(r))
+	for _, diff := range r {
+		found[identity(diff)] = true
+		if !seen[identity(diff)] {
+			appeared = append(appeared, diff)
+		}
+	}
+	var resolved DiffResult
+	for _, diff := range previous {
+		if !found[identity(diff)] {
+			resolved = append(resolved, diff)
+		}
+	}
+	return appeared, resolved
+}
+
+// Render writes the differences to w in the format implemented by renderer.
+//
+// Parameters:
+//   - w: Writer the differences are written to
+//   - renderer: Output format to use
+//
+// Returns:
+//   - error: Any error that occurred while rendering
+func (r DiffResult) Render(w io.Writer, renderer Renderer) error {
+	return renderer.Render(r, w)
+}
@@ -0,0 +1,122 @@
+package compare
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// compareSequences compares the sequences of two schemas: those missing on one side, and the
+// options of those on both: data type, start, increment, minimum, maximum, cache, and cycle.
+// The data type and cache are only compared when both sides report them, and the owners only
+// with Options.CompareOwners.
+//
+// Parameters:
+//   - source: The source schema to compare from
+//   - target: The target schema to compare against
+//   - opts: Options controlling the comparison
+//
+// Returns:
+//   - []Difference: MissingSequence, ExtraSequence, SequenceMismatch, and SequenceOwnerMismatch differences
+func compareSequences(source, target *schema.Schema, opts Options) []Difference {
+	var differences []Difference
+	for _, name := range sortedSequenceNames(source) {
+		sourceSeq := source.Sequences[name]
+		targetSeq, exists := target.Sequences[name]
+		if !exists {
+			differences = append(differences, Difference{
+				Type:        "MissingSequence",
+				ObjectKind:  KindSequence,
+				ObjectName:  name,
+				SubObject:   name,
+				Description: fmt.Sprintf("Sequence '%s' exists in source but not in target", name),
+			})
+			continue
+		}
+
+		if opts.CompareOwners && sourceSeq.Owner != targetSeq.Owner {
+			differences = append(differences, Difference{
+				Type:        "SequenceOwnerMismatch",
+				ObjectKind:  KindSequence,
+				ObjectName:  name,
+				SubObject:   name,
+				SourceValue: sourceSeq.Owner,
+				TargetValue: targetSeq.Owner,
+				Description: fmt.Sprintf("Sequence '%s' has different owners: source=%s, target=%s", name, sourceSeq.Owner, targetSeq.Owner),
+			})
+		}
+
+		sourceValue, targetValue := sequenceOptions(sourceSeq, targetSeq)
+		if sourceValue == "" {
+			continue
+		}
+		differences = append(differences, Difference{
+			Type:        "SequenceMismatch",
+			ObjectKind:  KindSequence,
+			ObjectName:  name,
+			SubObject:   name,
+			SourceValue: sourceValue,
+			TargetValue: targetValue,
+			Description: fmt.Sprintf("Sequence '%s' has different options: source=%s, target=%s", name, sourceValue, targetValue),
+		})
+	}
+
+	for _, name := range sortedSequenceNames(target) {
+		if _, exists := source.Sequences[name]; !exists {
+			differences = append(differences, Difference{
+				Type:        "ExtraSequence",
+				ObjectKind:  KindSequence,
+				ObjectName:  name,
+				SubObject:   name,
+				Description: fmt.Sprintf("Sequence '%s' exists in target but not in source", name),
+			})
+		}
+	}
+	return differences
+}
+
+// sequenceOptions formats the options that differ between two sequences for display (e.g.,
+// "increment 1, cache 20"), one for each side.
+//
+// Parameters:
+//   - source: Sequence in the source schema
+//   - target: Sequence in the target schema
+//
+// Returns:
+//   - string: Options of the source sequence that differ; empty if none do
+//   - string: Options of the target sequence that differ
+func sequenceOptions(source, target schema.SequenceInfo) (string, string) {
+	var sourceOptions, targetOptions []string
+	add := func(name, sourceValue, targetValue string) {
+		if sourceValue != targetValue {
+			sourceOptions = append(sourceOptions, name+" "+sourceValue)
+			targetOptions = append(targetOptions, name+" "+targetValue)
+		}
+	}
+
+	if source.DataType != "" && target.DataType != "" {
+		add("type", source.DataType, target.DataType)
+	}
+	add("start", strconv.FormatInt(source.Start, 10), strconv.FormatInt(target.Start, 10))
+	add("increment", strconv.FormatInt(source.Increment, 10), strconv.FormatInt(target.Increment, 10))
+	add("minvalue", strconv.FormatInt(source.Min, 10), strconv.FormatInt(target.Min, 10))
+	add("maxvalue", strconv.FormatInt(source.Max, 10), strconv.FormatInt(target.Max, 10))
+	if source.Cache != 0 && target.Cache != 0 {
+		add("cache", strconv.FormatInt(source.Cache, 10), strconv.FormatInt(target.Cache, 10))
+	}
+	add("cycle", strconv.FormatBool(source.Cycle), strconv.FormatBool(target.Cycle))
+	return strings.Join(sourceOptions, ", "), strings.Join(targetOptions, ", ")
+}
+
+// sortedSequenceNames returns the names of the sequences of a schema in sorted order.
+func sortedSequenceNames(s *schema.Schema) []string {
+	names := make([]string, 0, len(s.Sequences))
+	for name := range s.Sequences {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
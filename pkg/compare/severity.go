@@ -0,0 +1,98 @@
+package compare
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity levels that can be assigned to a difference.
+const (
+	SeverityError   = "error"   // Difference that must be fixed; the default for every difference
+	SeverityWarning = "warning" // Difference worth reviewing but not necessarily fixing
+	SeverityInfo    = "info"    // Difference reported for information only
+	SeverityIgnore  = "ignore"  // Difference removed from the report entirely
+)
+
+// IsValidSeverity reports whether the given string is a known severity level.
+//
+// Parameters:
+//   - severity: Severity level to check
+//
+// Returns:
+//   - bool: True if the severity level is known
+func IsValidSeverity(severity string) bool {
+	switch severity {
+	case SeverityError, SeverityWarning, SeverityInfo, SeverityIgnore:
+		return true
+	}
+	return false
+}
+
+// ApplySeverityOverrides assigns the configured severity to every difference whose type has an
+// override, and drops the differences whose severity ends up being SeverityIgnore.
+//
+// Parameters:
+//   - differences: Differences to update
+//   - overrides: Severity levels keyed by difference type (e.g., "ExtraIndex": "warning")
+//
+// Returns:
+//   - DiffResult: Differences with the overrides applied
+//   - error: An error if any override uses an unknown severity level
+func ApplySeverityOverrides(differences []Difference, overrides map[string]string) (DiffResult, error) {
+	for diffType, severity := range overrides {
+		if !IsValidSeverity(severity) {
+			return nil, fmt.Errorf("unknown severity '%s' for difference type %s", severity, diffType)
+		}
+	}
+
+	var result DiffResult
+	for _, diff := range differences {
+		if severity, exists := overrides[diff.Type]; exists {
+			diff.Severity = severity
+		}
+		if diff.Severity == SeverityIgnore {
+			continue
+		}
+		result = append(result, diff)
+	}
+
+	return result, nil
+}
+
+// ExtraSeverityOverrides returns severity overrides that assign the given severity to every
+// difference type describing an object present only in the target (ExtraTable, ExtraColumn, ...).
+// This supports workflows where the target may legitimately be ahead of the source and only
+// missing objects matter.
+//
+// Parameters:
+//   - severity: Severity to assign to Extra* differences; empty returns no overrides
+//
+// Returns:
+//   - map[string]string: Severity overrides keyed by difference type
+func ExtraSeverityOverrides(severity string) map[string]string {
+	overrides := make(map[string]string)
+	if severity == "" {
+		return overrides
+	}
+	for _, diffType := range DifferenceTypes() {
+		if strings.HasPrefix(diffType, "Extra") {
+			overrides[diffType] = severity
+		}
+	}
+	return overrides
+}
+
+// CountBySeverity counts the differences of each severity level.
+//
+// Parameters:
+//   - differences: Differences to count
+//
+// Returns:
+//   - map[string]int: Number of differences keyed by severity level
+func CountBySeverity(differences []Difference) map[string]int {
+	counts := make(map[string]int)
+	for _, diff := range differences {
+		counts[diff.Severity]++
+	}
+	return counts
+}
@@ -0,0 +1,183 @@
+package compare
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// MinSimilarity is the similarity an object of the other side must have with a missing or extra
+// object to be listed among its most similar objects.
+const MinSimilarity = 0.3
+
+// candidate is an object of the other side scored against a missing or extra object.
+type candidate struct {
+	name  string  // Name of the object
+	score float64 // Similarity with the missing or extra object, from 0 to 1
+}
+
+// scoreSimilarity lists, in the differences about tables, columns, indexes, and foreign keys
+// missing on one side or extra on the other, the Options.SimilarMatches objects of the other
+// side most similar to them, so that renames, copies, and near-duplicates stand out. Tables are
+// scored against every table of the other side, and the other objects against the objects of the
+// same table. The scores weigh the likeness of the names with that of the definitions:
+//   - tables: the columns they share, by name and type;
+//   - columns: their types, nullability, and defaults;
+//   - indexes: their columns and uniqueness;
+//   - foreign keys: their columns and referenced tables.
+//
+// Parameters:
+//   - differences: Differences to complete, modified in place
+//   - source: The source schema the differences were found in
+//   - target: The target schema the differences were found in
+//   - opts: Options of the comparison
+func scoreSimilarity(differences []Difference, source, target *schema.Schema, opts Options) {
+	if opts.SimilarMatches <= 0 {
+		return
+	}
+	for i, diff := range differences {
+		// The object is on one side; the candidates are on the other
+		var other string
+		own, others := source, target
+		switch {
+		case strings.HasPrefix(diff.Type, "Missing"):
+			other = "target"
+		case strings.HasPrefix(diff.Type, "Extra"):
+			own, others, other = target, source, "source"
+		default:
+			continue
+		}
+
+		var candidates []candidate
+		switch diff.Type {
+		case "MissingTable", "ExtraTable":
+			table := own.Tables[diff.Table]
+			for name, otherTable := range others.Tables {
+				score := 0.3*nameSimilarity(diff.Table, name) + 0.7*columnOverlap(table, otherTable, opts)
+				candidates = append(candidates, candidate{name, score})
+			}
+		case "MissingColumn", "ExtraColumn":
+			col, ok := findColumn(own.Tables[diff.Table], diff.SubObject)
+			if !ok {
+				continue
+			}
+			for _, otherCol := range others.Tables[diff.Table].Columns {
+				score := 0.5*nameSimilarity(col.Name, otherCol.Name) +
+					0.3*same(opts.normalizeType(col.Type) == opts.normalizeType(otherCol.Type)) +
+					0.1*same(col.Nullable == otherCol.Nullable) + 0.1*same(col.Default == otherCol.Default)
+				candidates = append(candidates, candidate{otherCol.Name, score})
+			}
+		case "MissingIndex", "ExtraIndex":
+			idx, ok := findIndex(own, diff.Table, diff.SubObject)
+			if !ok {
+				continue
+			}
+			for _, otherIdx := range others.Tables[diff.Table].Indexes {
+				score := 0.3*nameSimilarity(idx.Name, otherIdx.Name) + 0.6*setOverlap(idx.Columns, otherIdx.Columns) +
+					0.1*same(idx.Unique == otherIdx.Unique)
+				candidates = append(candidates, candidate{otherIdx.Name, score})
+			}
+		case "MissingForeignKey", "ExtraForeignKey":
+			fk, ok := findForeignKey(own, diff.Table, diff.SubObject)
+			if !ok {
+				continue
+			}
+			for _, otherFK := range others.Tables[diff.Table].ForeignKeys {
+				score := 0.3*nameSimilarity(fk.Name, otherFK.Name) + 0.4*setOverlap(fk.Columns, otherFK.Columns) +
+					0.3*same(fk.ReferencedTable == otherFK.ReferencedTable)
+				candidates = append(candidates, candidate{otherFK.Name, score})
+			}
+		}
+
+		if similar := bestMatches(candidates, opts.SimilarMatches); similar != "" {
+			differences[i].Similar = similar
+			differences[i].Description += fmt.Sprintf("; most similar in %s: %s", other, similar)
+		}
+	}
+}
+
+// bestMatches formats the best candidates, most similar first, leaving out those less similar
+// than MinSimilarity.
+//
+// Parameters:
+//   - candidates: Scored objects of the other side
+//   - n: Maximum number of candidates listed
+//
+// Returns:
+//   - string: Names of the best candidates with their similarity (e.g., "clients (83%)"), or
+//     empty if none is similar enough
+func bestMatches(candidates []candidate, n int) string {
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	var listed []string
+	for _, c := range candidates {
+		if len(listed) == n || c.score < MinSimilarity {
+			break
+		}
+		listed = append(listed, fmt.Sprintf("%s (%.0f%%)", c.name, c.score*100))
+	}
+	return strings.Join(listed, ", ")
+}
+
+// nameSimilarity returns how alike two names are, from 0 to 1: one minus their edit distance
+// divided by the length of the longer name.
+func nameSimilarity(a, b string) float64 {
+	first, second := []rune(a), []rune(b)
+	longest := max(len(first), len(second))
+	if longest == 0 {
+		return 1
+	}
+
+	// Levenshtein distance, keeping one row of the table
+	row := make([]int, len(second)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(first); i++ {
+		diagonal := row[0]
+		row[0] = i
+		for j := 1; j <= len(second); j++ {
+			substitution := diagonal
+			if first[i-1] != second[j-1] {
+				substitution++
+			}
+			diagonal = row[j]
+			row[j] = min(row[j]+1, row[j-1]+1, substitution)
+		}
+	}
+	return 1 - float64(row[len(second)])/float64(longest)
+}
+
+// setOverlap returns the share of the names of two lists that both hold, out of the names either
+// holds.
+func setOverlap(a, b []string) float64 {
+	names := make(map[string]bool, len(a))
+	for _, name := range a {
+		names[name] = true
+	}
+	shared := 0
+	for _, name := range b {
+		if names[name] {
+			shared++
+		}
+	}
+	all := len(names) + len(b) - shared
+	if all == 0 {
+		return 0
+	}
+	return float64(shared) / float64(all)
+}
+
+// same returns 1 if a property is the same on both objects, and 0 otherwise.
+func same(equal bool) float64 {
+	if equal {
+		return 1
+	}
+	return 0
+}
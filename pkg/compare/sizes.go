@@ -0,0 +1,65 @@
+package compare
+
+import (
+	"fmt"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// annotateSizes records in the differences about the objects of a table the size on disk of
+// what the change reconciling them rewrites or scans, in the schema being changed (the target,
+// or the source with DirectionTargetToSource), if its sizes were read (see schema.Schema.Sizes):
+//   - indexes: the size of the index, or of its table if the index is missing, as building it
+//     reads the table;
+//   - other objects: the size of their table, with its indexes, and its estimated bloat.
+//
+// The size is also appended to the description, so that the cost of the migrations shows in
+// every report. Tables missing from the schema being changed have no size there.
+//
+// Parameters:
+//   - differences: Differences to complete, modified in place
+//   - source: The source schema the differences were found in
+//   - target: The target schema the differences were found in
+//   - opts: Options of the comparison, whose direction tells the schema being changed
+func annotateSizes(differences []Difference, source, target *schema.Schema, opts Options) {
+	changed := target
+	if opts.Direction == DirectionTargetToSource {
+		changed = source
+	}
+	if len(changed.Sizes) == 0 {
+		return
+	}
+	for i, diff := range differences {
+		size, ok := changed.Sizes[diff.Table]
+		if !ok {
+			continue
+		}
+		if diff.ObjectKind == KindIndex {
+			if bytes, ok := size.Indexes[diff.SubObject]; ok {
+				differences[i].Size = bytes
+				differences[i].Description += fmt.Sprintf("; index is %s", formatBytes(bytes))
+				continue
+			}
+		}
+		differences[i].Size, differences[i].Bloat = size.Bytes, size.Bloat
+		if size.Bloat > 0 {
+			differences[i].Description += fmt.Sprintf("; table is %s (%s estimated bloat)", formatBytes(size.Bytes), formatBytes(size.Bloat))
+		} else {
+			differences[i].Description += fmt.Sprintf("; table is %s", formatBytes(size.Bytes))
+		}
+	}
+}
+
+// formatBytes formats a size in bytes with the units of pg_size_pretty (e.g., "12 MB").
+func formatBytes(bytes int64) string {
+	units := []string{"bytes", "kB", "MB", "GB", "TB", "PB"}
+	value, unit := float64(bytes), 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 || value >= 100 {
+		return fmt.Sprintf("%.0f %s", value, units[unit])
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
@@ -0,0 +1,291 @@
+package compare
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// Stream is a comparison in progress, started by CompareSchemasStream or CompareTableStreams.
+type Stream struct {
+	Differences <-chan Difference // Differences as they are found; closed when the comparison ends
+	err         error             // Error that ended the comparison, set before Differences is closed
+}
+
+// Err returns the error that ended the comparison early, or nil if it completed. It must only
+// be called once Differences has been closed.
+//
+// Returns:
+//   - error: The context's error if the comparison was cancelled, the error a table stream
+//     failed with, or nil
+func (s *Stream) Err() error {
+	return s.err
+}
+
+// CompareSchemasStream compares two schemas like CompareSchemasContext, but emits the
+// differences on a channel table by table as they are found, so that large comparisons can be
+// rendered incrementally. Cancelling the context stops the comparison at the next table (or
+// while waiting for the channel to be read) and closes the channel.
+//
+// The differences and their order are those of CompareSchemasContext, except that each
+// comparator sees one table at a time, and that the differences comparators find in
+// schema.Schema.Extensions, which are not about a single table, are emitted last.
+// Options.OnDifference is called as each difference is emitted.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the comparison
+//   - source: The source schema to compare from
+//   - target: The target schema to compare against
+//   - opts: Options value and/or With* options controlling the comparison
+//
+// Returns:
+//   - *Stream: Comparison in progress; its Differences channel must be drained, or ctx cancelled
+//   - error: An error if the severity map uses an unknown severity, or returned by Options.Filter
+func CompareSchemasStream(ctx context.Context, source, target *schema.Schema, opts ...Option) (*Stream, error) {
+	resolved := resolveOptions(opts)
+	if resolved.IgnoreCase {
+		source, target = foldCase(source), foldCase(target)
+		resolved.IgnoreCase = false
+	}
+	return CompareTableStreams(ctx, schema.NewSchemaStream(source), schema.NewSchemaStream(target), resolved)
+}
+
+// CompareTableStreams compares two schemas read table by table, like CompareSchemasStream, so
+// that only one table of each side has to be held in memory with all its details at any time,
+// besides the outlines of the schemas. Tables missing on one side are found from the outlines
+// up front; the others are compared as both streams reach them. The streams are read from a
+// goroutine of their own until the comparison ends.
+//
+// Comparators see one table at a time, with the outlines' Extensions compared last. The
+// comparison of names ignoring case (Options.IgnoreCase) is not supported, as it would change
+// the order tables are streamed in.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the comparison and the reading of the streams
+//   - source: Stream of the tables of the source schema
+//   - target: Stream of the tables of the target schema
+//   - opts: Options value and/or With* options controlling the comparison
+//
+// Returns:
+//   - *Stream: Comparison in progress; its Differences channel must be drained, or ctx cancelled
+//   - error: An error if the options are not supported or the severity map uses an unknown
+//     severity, or the error returned by Options.Filter for the outlines
+func CompareTableStreams(ctx context.Context, source, target schema.TableStream, opts ...Option) (*Stream, error) {
+	resolved := resolveOptions(opts)
+	if resolved.IgnoreCase {
+		return nil, fmt.Errorf("comparing names ignoring case is not supported when streaming tables")
+	}
+	c, err := prepare(source.Outline(), target.Outline(), []Option{resolved})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan Difference)
+	stream := &Stream{Differences: out}
+	go func() {
+		defer close(out)
+		stream.err = c.stream(ctx, &cursor{stream: source}, &cursor{stream: target}, out)
+	}()
+	return stream, nil
+}
+
+// stream runs a comparison prepared with the outlines of two schemas one table at a time,
+// reading the tables from their streams and sending the differences to out.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the comparison
+//   - sourceTables: Cursor over the stream of the source tables
+//   - targetTables: Cursor over the stream of the target tables
+//   - out: Channel the differences are sent to
+//
+// Returns:
+//   - error: The context's error if the comparison was cancelled, the error a stream failed
+//     with, or nil
+func (c comparison) stream(ctx context.Context, sourceTables, targetTables *cursor, out chan<- Difference) error {
+	comparators := Comparators()
+	send := func(differences []Difference) error {
+		for _, diff := range differences {
+			if c.opts.OnDifference != nil {
+				c.opts.OnDifference(diff)
+			}
+			select {
+			case out <- diff:
+			case <-ctx.Done():
+				return fmt.Errorf("comparison cancelled: %w", ctx.Err())
+			}
+		}
+		return nil
+	}
+
+	// Tables missing on one side are found up front, so that the differences of other tables
+	// that result from them are linked to them as in CompareSchemasContext
+	causes := make(map[string][]Difference)
+	for _, diff := range c.finish(compareTables(c.source, c.target, c.opts)) {
+		if diff.Type == "MissingTable" || diff.Type == "ExtraTable" {
+			causes[diff.Table] = append(causes[diff.Table], diff)
+		}
+	}
+
+	// Differences that are not about a table sort before all others; those about tables that
+	// could not be fetched are found again with the table
+	var general []Difference
+	for _, diff := range c.finish(c.initial) {
+		if diff.Table == "" {
+			general = append(general, diff)
+		}
+	}
+	if err := send(general); err != nil {
+		return err
+	}
+
+	names := make(map[string]bool)
+	for _, s := range []*schema.Schema{c.source, c.target, sourceTables.stream.Outline(), targetTables.stream.Outline()} {
+		for _, fetchErr := range s.Errors {
+			names[fetchErr.Table] = true
+		}
+	}
+	for _, s := range []*schema.Schema{c.source, c.target} {
+		for name := range s.Tables {
+			names[name] = true
+		}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("comparison cancelled: %w", err)
+		}
+		source, err := sourceTables.seek(ctx, name)
+		if err != nil {
+			return fmt.Errorf("error reading source tables: %w", err)
+		}
+		target, err := targetTables.seek(ctx, name)
+		if err != nil {
+			return fmt.Errorf("error reading target tables: %w", err)
+		}
+
+		// The pair of tables is prepared like whole schemas, with the differences about
+		// unsupported features already reported above
+		tc, err := prepare(source, target, []Option{c.opts})
+		if err != nil {
+			return err
+		}
+		var differences []Difference
+		for _, diff := range tc.initial {
+			if diff.Type == "FetchFailed" {
+				differences = append(differences, diff)
+			}
+		}
+		for _, comparator := range comparators {
+			differences = append(differences, comparator.Compare(tc.source, tc.target, tc.opts)...)
+		}
+		differences = detectRenames(differences, tc.source, tc.target, tc.opts)
+		scoreSimilarity(differences, tc.source, tc.target, tc.opts)
+		annotateSizes(differences, tc.source, tc.target, tc.opts)
+		result := c.finish(differences)
+		linkRelated(result, append(relatedCauses(causes, tc.source, tc.target), result...), tc.source, tc.target)
+		if err := send(result); err != nil {
+			return err
+		}
+	}
+
+	// Sequences, functions, and custom object kinds are compared last, without tables
+	if len(c.source.Extensions) == 0 && len(c.target.Extensions) == 0 && len(c.source.Sequences) == 0 && len(c.target.Sequences) == 0 &&
+		len(c.source.Functions) == 0 && len(c.target.Functions) == 0 {
+		return nil
+	}
+	source, target := *c.source, *c.target
+	source.Tables, target.Tables = map[string]schema.TableInfo{}, map[string]schema.TableInfo{}
+	source.Errors, target.Errors = nil, nil
+	var differences []Difference
+	for _, comparator := range comparators {
+		differences = append(differences, comparator.Compare(&source, &target, c.opts)...)
+	}
+	return send(c.finish(differences))
+}
+
+// relatedCauses returns the differences about missing tables that differences about a pair of
+// tables can result from: those about the tables their foreign keys refer to and their parents.
+//
+// Parameters:
+//   - causes: MissingTable and ExtraTable differences, by table
+//   - source: Schema holding the source table
+//   - target: Schema holding the target table
+//
+// Returns:
+//   - []Difference: Differences the differences of the pair can be linked to
+func relatedCauses(causes map[string][]Difference, source, target *schema.Schema) []Difference {
+	var related []Difference
+	for _, s := range []*schema.Schema{source, target} {
+		for _, table := range s.Tables {
+			related = append(related, causes[table.PartitionOf]...)
+			for _, fk := range table.ForeignKeys {
+				related = append(related, causes[fk.ReferencedTable]...)
+			}
+		}
+	}
+	return related
+}
+
+// cursor reads a TableStream in step with the names of the tables being compared.
+type cursor struct {
+	stream  schema.TableStream             // Stream being read
+	table   schema.TableInfo               // Table read last
+	read    bool                           // Whether a table has been read
+	done    bool                           // Whether the stream has returned every table
+	checked int                            // Number of the outline's Errors already collected in failed
+	failed  map[string][]schema.FetchError // Errors of the tables not reached yet that could not be fetched
+}
+
+// seek reads the stream up to a table, and returns a schema holding only that table, if the
+// stream has it, and the errors it could not be fetched with, if any. Tables of the stream
+// before it are skipped.
+//
+// Parameters:
+//   - ctx: Context controlling the reading of the stream
+//   - name: Name of the table; names must be given in increasing order
+//
+// Returns:
+//   - *schema.Schema: Shallow copy of the outline restricted to the table, without Extensions
+//   - error: Any error the stream failed with
+func (c *cursor) seek(ctx context.Context, name string) (*schema.Schema, error) {
+	for !c.done && (!c.read || c.table.Name < name) {
+		table, ok, err := c.stream.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			c.done = true
+			break
+		}
+		c.table, c.read = table, true
+	}
+
+	// Tables are only recorded as failed once the stream has moved past them
+	outline := c.stream.Outline()
+	if c.failed == nil {
+		c.failed = make(map[string][]schema.FetchError)
+	}
+	for _, fetchErr := range outline.Errors[c.checked:] {
+		c.failed[fetchErr.Table] = append(c.failed[fetchErr.Table], fetchErr)
+	}
+	c.checked = len(outline.Errors)
+
+	single := *outline
+	single.Tables = make(map[string]schema.TableInfo, 1)
+	if c.read && c.table.Name == name {
+		single.Tables[name] = c.table
+	}
+	single.Errors = c.failed[name]
+	delete(c.failed, name)
+	single.Extensions = nil
+	single.Sequences = nil
+	single.Functions = nil
+	return &single, nil
+}
@@ -0,0 +1,129 @@
+package compare
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+	"github.com/guriandoro/pg_schema_check/pkg/sqlnorm"
+)
+
+// compareTriggers compares the triggers of a table between source and target schemas: those
+// missing on one side, and the timing, events, level, WHEN condition, and called function of
+// those on both. Conditions and functions are compared once normalized (see
+// sqlnorm.Normalize), as view definitions are.
+//
+// Parameters:
+//   - tableName: Name of the table being compared
+//   - source: List of triggers in the source schema
+//   - target: List of triggers in the target schema
+//
+// Returns:
+//   - []Difference: List of differences found in the triggers
+func compareTriggers(tableName string, source, target []schema.TriggerInfo) []Difference {
+	var differences []Difference
+	sourceMap := make(map[string]schema.TriggerInfo)
+	targetMap := make(map[string]schema.TriggerInfo)
+
+	// Create maps for efficient trigger lookup
+	for _, trigger := range source {
+		sourceMap[trigger.Name] = trigger
+	}
+	for _, trigger := range target {
+		targetMap[trigger.Name] = trigger
+	}
+
+	// Check for missing or different triggers in source, in the order of their names
+	for _, name := range sortedTriggerNames(sourceMap) {
+		sourceTrigger := sourceMap[name]
+		targetTrigger, exists := targetMap[name]
+		if !exists {
+			differences = append(differences, Difference{
+				Type:        "MissingTrigger",
+				Table:       tableName,
+				ObjectKind:  KindTrigger,
+				SubObject:   name,
+				Description: fmt.Sprintf("Trigger '%s' exists in source but not in target", name),
+			})
+			continue
+		}
+
+		mismatch := func(diffType, property, sourceValue, targetValue string) {
+			differences = append(differences, Difference{
+				Type:        diffType,
+				Table:       tableName,
+				ObjectKind:  KindTrigger,
+				SubObject:   name,
+				SourceValue: sourceValue,
+				TargetValue: targetValue,
+				Description: fmt.Sprintf("Trigger '%s' has different %s: source=%s, target=%s", name, property, sourceValue, targetValue),
+			})
+		}
+
+		// Compare trigger properties
+		if sourceTrigger.Timing != targetTrigger.Timing {
+			mismatch("TriggerTimingMismatch", "timing", sourceTrigger.Timing, targetTrigger.Timing)
+		}
+		if sourceEvents, targetEvents := describeEvents(sourceTrigger), describeEvents(targetTrigger); sourceEvents != targetEvents {
+			mismatch("TriggerEventsMismatch", "events", sourceEvents, targetEvents)
+		}
+		if sourceTrigger.Level != targetTrigger.Level {
+			mismatch("TriggerLevelMismatch", "levels", sourceTrigger.Level, targetTrigger.Level)
+		}
+		if !sqlnorm.Equal(sourceTrigger.When, targetTrigger.When) {
+			mismatch("TriggerConditionMismatch", "WHEN conditions", describeCondition(sourceTrigger.When), describeCondition(targetTrigger.When))
+		}
+		if !sqlnorm.Equal(sourceTrigger.Function, targetTrigger.Function) {
+			mismatch("TriggerFunctionMismatch", "functions", sourceTrigger.Function, targetTrigger.Function)
+		}
+	}
+
+	// Check for extra triggers in target, in the order of their names
+	for _, name := range sortedTriggerNames(targetMap) {
+		if _, exists := sourceMap[name]; !exists {
+			differences = append(differences, Difference{
+				Type:        "ExtraTrigger",
+				Table:       tableName,
+				ObjectKind:  KindTrigger,
+				SubObject:   name,
+				Description: fmt.Sprintf("Trigger '%s' exists in target but not in source", name),
+			})
+		}
+	}
+
+	return differences
+}
+
+// sortedTriggerNames returns the names of a set of triggers in sorted order.
+func sortedTriggerNames(triggers map[string]schema.TriggerInfo) []string {
+	names := make([]string, 0, len(triggers))
+	for name := range triggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// describeEvents formats the events a trigger fires on as CREATE TRIGGER declares them (e.g.,
+// "INSERT OR UPDATE OF status, total").
+func describeEvents(trigger schema.TriggerInfo) string {
+	events := make([]string, len(trigger.Events))
+	for i, event := range trigger.Events {
+		events[i] = event
+		if event == "UPDATE" && len(trigger.Columns) > 0 {
+			events[i] += " OF " + strings.Join(trigger.Columns, ", ")
+		}
+	}
+	return strings.Join(events, " OR ")
+}
+
+// describeCondition formats an optional clause for display, such as the WHEN condition of a
+// trigger, the predicate of an index, or the options of a foreign key, or returns "none" if it
+// is empty.
+func describeCondition(when string) string {
+	if when == "" {
+		return "none"
+	}
+	return when
+}
@@ -0,0 +1,621 @@
+package compare
+
+import (
+	"sort"
+	"strings"
+)
+
+// TypeInfo documents a type of difference: its stable code, what it means, why it typically
+// happens, and how it is usually fixed. The comparison takes the default severity of each type
+// from it, and 'schema-check explain' prints it.
+type TypeInfo struct {
+	Type            string   // Name of the type (e.g., "MissingColumn")
+	Code            string   // Stable code of the type (e.g., "PSC201"), which never changes once released
+	ObjectKind      string   // Kind of object the differences of the type are about (see the Kind constants)
+	DefaultSeverity string   // Severity of the differences of the type unless the comparator or an override sets one
+	Summary         string   // What a difference of the type means
+	Causes          []string // Typical causes of the difference
+	Fix             []string // Statements usually reconciling the difference, with <placeholders>, or advice when no statement applies
+	Generated       bool     // Whether --sql and plan generate the statements reconciling the difference
+}
+
+// typeInfos documents the built-in types of difference, in the order of DifferenceTypes. Codes
+// are grouped by the kind of object: PSC0xx for the comparison itself, PSC1xx for tables, PSC2xx
+// for columns, PSC3xx for primary keys and indexes, PSC4xx for foreign keys, PSC5xx for the
+// metadata of extensions, PSC6xx for views and materialized views, PSC7xx for sequences,
+// PSC8xx for triggers, and PSC9xx for functions.
+var typeInfos = []TypeInfo{
+	{
+		Type: "FetchFailed", Code: "PSC001", ObjectKind: KindTable, DefaultSeverity: SeverityError,
+		Summary: "The details of a table could not be read from one side, so the table was not compared.",
+		Causes: []string{
+			"The table was dropped or altered while the schema was being read",
+			"A catalog query timed out (--statement-timeout) or was cancelled by a lock timeout (--lock-timeout)",
+			"The user lacks privileges on the table or its schema",
+		},
+		Fix: []string{"Run the comparison again, with a longer --statement-timeout if the catalog is busy, or grant the user USAGE on the schema of the table."},
+	},
+	{
+		Type: "FeatureUnsupported", Code: "PSC002", ObjectKind: KindFeature, DefaultSeverity: SeverityInfo,
+		Summary: "One side does not support a feature of the schema model (such as partitioning or identity columns), so that feature was not compared on either side.",
+		Causes: []string{
+			"The servers run different major versions of PostgreSQL",
+			"One side is Amazon Redshift or CockroachDB, which lack some catalogs",
+			"An extension is installed on one side only, or its metadata cannot be read",
+		},
+		Fix: []string{"Upgrade the older server, or accept the difference; no statement reconciles it."},
+	},
+	{
+		Type: "MissingTable", Code: "PSC101", ObjectKind: KindTable, DefaultSeverity: SeverityError,
+		Summary: "A table of the source does not exist in the target.",
+		Causes: []string{
+			"A migration creating the table was not run on the target",
+			"The table was dropped from the target by hand",
+			"The table is excluded from the target's search by --include-tables or --exclude-tables patterns that differ between environments",
+		},
+		Fix:       []string{"CREATE TABLE <table> (<columns>, PRIMARY KEY (<columns>));", "CREATE INDEX <index> ON <table> (<columns>);", "ALTER TABLE <table> ADD CONSTRAINT <foreign_key> FOREIGN KEY (<columns>) REFERENCES <referenced_table> (<columns>);"},
+		Generated: true,
+	},
+	{
+		Type: "ExtraTable", Code: "PSC102", ObjectKind: KindTable, DefaultSeverity: SeverityError,
+		Summary: "A table of the target does not exist in the source.",
+		Causes: []string{
+			"The target is ahead of the source: a migration was run on it first",
+			"A temporary or backup table was left behind in the target",
+			"A migration dropping the table was not run on the target",
+		},
+		Fix:       []string{"DROP TABLE <table>;", "Or, when the target may be ahead of the source, lower the severity with --extra-severity."},
+		Generated: true,
+	},
+	{
+		Type: "PartitionKeyMismatch", Code: "PSC103", ObjectKind: KindTable, DefaultSeverity: SeverityError,
+		Summary: "A table is partitioned differently on the two sides: by another strategy or key, or partitioned on one side only.",
+		Causes: []string{
+			"The table was converted to a partitioned table on one side only",
+			"The partition key was changed by a migration run on one side only",
+		},
+		Fix: []string{"The partitioning of a table cannot be altered: create a partitioned table with PARTITION BY <strategy> (<key>), move the rows into it, and swap the names."},
+	},
+	{
+		Type: "PartitionParentMismatch", Code: "PSC104", ObjectKind: KindTable, DefaultSeverity: SeverityError,
+		Summary: "A table is a partition of different parents on the two sides, or a partition on one side only.",
+		Causes: []string{
+			"The partition was detached from its parent on one side",
+			"The table was attached to another partitioned table on one side",
+		},
+		Fix: []string{"ALTER TABLE <parent> DETACH PARTITION <table>;", "ALTER TABLE <parent> ATTACH PARTITION <table> FOR VALUES <bounds>;"},
+	},
+	{
+		Type: "OwnerMismatch", Code: "PSC105", ObjectKind: KindTable, DefaultSeverity: SeverityError,
+		Summary: "A table, view, or materialized view is owned by different roles on the two sides (only with --compare-owners).",
+		Causes: []string{
+			"The relation was created by another role, such as a superuser running a migration by hand",
+			"Ownership was reassigned on one side only",
+		},
+		Fix:       []string{"ALTER TABLE <table> OWNER TO <role>;"},
+		Generated: true,
+	},
+	{
+		Type: "RowCountMismatch", Code: "PSC106", ObjectKind: KindTable, DefaultSeverity: SeverityWarning,
+		Summary: "A table holds a markedly different number of rows on the two sides (only with --compare-rowcounts).",
+		Causes: []string{
+			"A replica is lagging behind, or stopped replicating the table",
+			"Rows were loaded, deleted, or purged on one side only",
+			"The estimates are stale: the table was not vacuumed or analyzed since its rows changed",
+		},
+		Fix: []string{"Check the replication of the table, or run ANALYZE <table>; on both sides and compare again; no statement reconciles the data."},
+	},
+	{
+		Type: "DataMismatch", Code: "PSC107", ObjectKind: KindTable, DefaultSeverity: SeverityWarning,
+		Summary: "The data of a table differs between the two sides: its rows, or the values of the columns hashed, do not match (only with --compare-data).",
+		Causes: []string{
+			"A replica is lagging behind, or stopped replicating the table",
+			"Rows were inserted, updated, or deleted on one side only",
+			"A column has a different type on each side, so its values are written out differently",
+		},
+		Fix: []string{"Find the differing rows by comparing the table's rows by primary key, and copy them from the authoritative side; no statement reconciles the data."},
+	},
+	{
+		Type: "PossibleTableRename", Code: "PSC108", ObjectKind: KindTable, DefaultSeverity: SeverityError,
+		Summary: "A table missing on one side has a near-identical table extra on the other, so it was probably renamed (only with --detect-renames).",
+		Causes: []string{
+			"A migration renaming the table was run on one side only",
+			"The table was recreated under a new name, such as by a tool swapping it with a rebuilt copy",
+		},
+		Fix: []string{"Confirm the rename with --confirm-rename <old>=<new> to have the sync SQL rename the table.", "ALTER TABLE <old> RENAME TO <new>;"},
+	},
+	{
+		Type: "TableRename", Code: "PSC109", ObjectKind: KindTable, DefaultSeverity: SeverityError,
+		Summary: "A table has another name on each side, as confirmed with --confirm-rename.",
+		Causes: []string{
+			"A migration renaming the table was run on one side only",
+		},
+		Fix:       []string{"ALTER TABLE <old> RENAME TO <new>;"},
+		Generated: true,
+	},
+	{
+		Type: "MissingColumn", Code: "PSC201", ObjectKind: KindColumn, DefaultSeverity: SeverityError,
+		Summary: "A column of a table of the source does not exist in the same table of the target.",
+		Causes: []string{
+			"A migration adding the column was not run on the target",
+			"The column was dropped from the target by hand",
+		},
+		Fix:       []string{"ALTER TABLE <table> ADD COLUMN <column> <type> [NOT NULL] [DEFAULT <expression>];"},
+		Generated: true,
+	},
+	{
+		Type: "ExtraColumn", Code: "PSC202", ObjectKind: KindColumn, DefaultSeverity: SeverityError,
+		Summary: "A column of a table of the target does not exist in the same table of the source.",
+		Causes: []string{
+			"The target is ahead of the source: a migration adding the column was run on it first",
+			"A migration dropping the column was not run on the target",
+		},
+		Fix:       []string{"ALTER TABLE <table> DROP COLUMN <column>;"},
+		Generated: true,
+	},
+	{
+		Type: "ColumnTypeMismatch", Code: "PSC203", ObjectKind: KindColumn, DefaultSeverity: SeverityError,
+		Summary: "A column has different data types on the two sides, including different lengths or precisions.",
+		Causes: []string{
+			"A migration widening the column (such as integer to bigint) was run on one side only",
+			"The column was created with another type by hand",
+		},
+		Fix:       []string{"ALTER TABLE <table> ALTER COLUMN <column> TYPE <type> [USING <column>::<type>];"},
+		Generated: true,
+	},
+	{
+		Type: "ColumnNullableMismatch", Code: "PSC204", ObjectKind: KindColumn, DefaultSeverity: SeverityError,
+		Summary: "A column accepts NULL values on one side and is NOT NULL on the other.",
+		Causes: []string{
+			"A migration adding or dropping the NOT NULL constraint was run on one side only",
+			"SET NOT NULL failed on one side because of existing NULL values",
+		},
+		Fix:       []string{"ALTER TABLE <table> ALTER COLUMN <column> SET NOT NULL;", "ALTER TABLE <table> ALTER COLUMN <column> DROP NOT NULL;"},
+		Generated: true,
+	},
+	{
+		Type: "ColumnDefaultMismatch", Code: "PSC205", ObjectKind: KindColumn, DefaultSeverity: SeverityError,
+		Summary: "A column has different default expressions on the two sides, or a default on one side only.",
+		Causes: []string{
+			"A migration changing the default was run on one side only",
+			"The default refers to a sequence of another name, as after restoring a dump with renamed sequences",
+		},
+		Fix:       []string{"ALTER TABLE <table> ALTER COLUMN <column> SET DEFAULT <expression>;", "ALTER TABLE <table> ALTER COLUMN <column> DROP DEFAULT;"},
+		Generated: true,
+	},
+	{
+		Type: "ColumnIdentityMismatch", Code: "PSC206", ObjectKind: KindColumn, DefaultSeverity: SeverityError,
+		Summary: "A column is an identity column on one side only.",
+		Causes: []string{
+			"A serial column was converted to an identity column on one side only",
+			"The table was recreated from a dump of an older release",
+		},
+		Fix: []string{"ALTER TABLE <table> ALTER COLUMN <column> ADD GENERATED BY DEFAULT AS IDENTITY;", "ALTER TABLE <table> ALTER COLUMN <column> DROP IDENTITY;"},
+	},
+	{
+		Type: "ColumnCompressionMismatch", Code: "PSC207", ObjectKind: KindColumn, DefaultSeverity: SeverityError,
+		Summary: "A column is stored with different compression methods on the two sides (PostgreSQL 14 and later).",
+		Causes: []string{
+			"The compression method was set on one side only",
+			"The servers have different default_toast_compression settings",
+		},
+		Fix: []string{"ALTER TABLE <table> ALTER COLUMN <column> SET COMPRESSION <method>;"},
+	},
+	{
+		Type: "PossibleColumnRename", Code: "PSC208", ObjectKind: KindColumn, DefaultSeverity: SeverityError,
+		Summary: "A column missing from a table on one side is the only column extra in it on the other with the same type, nullability, and identity, so it was probably renamed (only with --detect-renames).",
+		Causes: []string{
+			"A migration renaming the column was run on one side only",
+			"The column was dropped and another one added in its place",
+		},
+		Fix: []string{"Confirm the rename with --confirm-rename <table>.<old>=<new> to have the sync SQL rename the column.", "ALTER TABLE <table> RENAME COLUMN <old> TO <new>;"},
+	},
+	{
+		Type: "ColumnRename", Code: "PSC209", ObjectKind: KindColumn, DefaultSeverity: SeverityError,
+		Summary: "A column of a table has another name on each side, as confirmed with --confirm-rename.",
+		Causes: []string{
+			"A migration renaming the column was run on one side only",
+		},
+		Fix:       []string{"ALTER TABLE <table> RENAME COLUMN <old> TO <new>;"},
+		Generated: true,
+	},
+	{
+		Type: "PrimaryKeyMismatch", Code: "PSC301", ObjectKind: KindPrimaryKey, DefaultSeverity: SeverityError,
+		Summary: "A table has a primary key on different columns on the two sides, or a primary key on one side only.",
+		Causes: []string{
+			"A migration changing the primary key was run on one side only",
+			"The table was created without its primary key on one side",
+		},
+		Fix:       []string{"ALTER TABLE <table> DROP CONSTRAINT <table>_pkey;", "ALTER TABLE <table> ADD PRIMARY KEY (<columns>);"},
+		Generated: true,
+	},
+	{
+		Type: "MissingIndex", Code: "PSC302", ObjectKind: KindIndex, DefaultSeverity: SeverityError,
+		Summary: "An index of a table of the source does not exist on the same table of the target.",
+		Causes: []string{
+			"A migration creating the index was not run on the target",
+			"CREATE INDEX CONCURRENTLY failed on the target, leaving no index or an invalid one",
+		},
+		Fix:       []string{"CREATE [UNIQUE] INDEX <index> ON <table> (<columns>);"},
+		Generated: true,
+	},
+	{
+		Type: "ExtraIndex", Code: "PSC303", ObjectKind: KindIndex, DefaultSeverity: SeverityError,
+		Summary: "An index of a table of the target does not exist on the same table of the source.",
+		Causes: []string{
+			"An index was added by hand to the target, such as to speed up reports on a replica",
+			"A migration dropping the index was not run on the target",
+		},
+		Fix:       []string{"DROP INDEX <index>;", "Or lower the severity of ExtraIndex when the target legitimately carries more indexes."},
+		Generated: true,
+	},
+	{
+		Type: "IndexUniqueMismatch", Code: "PSC304", ObjectKind: KindIndex, DefaultSeverity: SeverityError,
+		Summary: "An index enforces uniqueness on one side only.",
+		Causes: []string{
+			"The index was recreated as unique, or not unique, on one side only",
+			"Creating the unique index failed on one side because of duplicate values, and a plain index was created instead",
+		},
+		Fix:       []string{"DROP INDEX <index>;", "CREATE [UNIQUE] INDEX <index> ON <table> (<columns>);"},
+		Generated: true,
+	},
+	{
+		Type: "IndexColumnsMismatch", Code: "PSC305", ObjectKind: KindIndex, DefaultSeverity: SeverityError,
+		Summary: "An index is keyed on different columns or expressions, or the same ones in another order, on the two sides.",
+		Causes: []string{
+			"The index was recreated with other columns or expressions on one side only",
+			"Two indexes of different definitions were given the same name",
+		},
+		Fix:       []string{"DROP INDEX <index>;", "CREATE [UNIQUE] INDEX <index> ON <table> (<columns>);"},
+		Generated: true,
+	},
+	{
+		Type: "IndexIncludeMismatch", Code: "PSC306", ObjectKind: KindIndex, DefaultSeverity: SeverityError,
+		Summary: "An index includes different non-key columns (INCLUDE) on the two sides.",
+		Causes: []string{
+			"The index was recreated as a covering index on one side only",
+			"One side runs a release older than PostgreSQL 11, which has no INCLUDE clause",
+		},
+		Fix:       []string{"DROP INDEX <index>;", "CREATE [UNIQUE] INDEX <index> ON <table> (<columns>) INCLUDE (<columns>);"},
+		Generated: true,
+	},
+	{
+		Type: "IndexPredicateMismatch", Code: "PSC307", ObjectKind: KindIndex, DefaultSeverity: SeverityError,
+		Summary: "An index is partial on one side only, or has different WHERE conditions on the two sides.",
+		Causes: []string{
+			"The index was recreated with another condition on one side only",
+			"A partial unique index was replaced by a full one, or the other way around",
+		},
+		Fix:       []string{"DROP INDEX <index>;", "CREATE [UNIQUE] INDEX <index> ON <table> (<columns>) WHERE <condition>;"},
+		Generated: true,
+	},
+	{
+		Type: "IndexMethodMismatch", Code: "PSC308", ObjectKind: KindIndex, DefaultSeverity: SeverityError,
+		Summary: "An index uses different access methods (such as btree and gin) on the two sides.",
+		Causes: []string{
+			"The index was recreated with another access method on one side only",
+			"An extension providing the access method is installed on one side only",
+		},
+		Fix:       []string{"DROP INDEX <index>;", "CREATE [UNIQUE] INDEX <index> ON <table> USING <method> (<columns>);"},
+		Generated: true,
+	},
+	{
+		Type: "MissingForeignKey", Code: "PSC401", ObjectKind: KindForeignKey, DefaultSeverity: SeverityError,
+		Summary: "A foreign key constraint of a table of the source does not exist on the same table of the target.",
+		Causes: []string{
+			"A migration adding the constraint was not run on the target",
+			"The constraint was dropped from the target to load data, and never added back",
+		},
+		Fix:       []string{"ALTER TABLE <table> ADD CONSTRAINT <foreign_key> FOREIGN KEY (<columns>) REFERENCES <referenced_table> (<columns>);"},
+		Generated: true,
+	},
+	{
+		Type: "ExtraForeignKey", Code: "PSC402", ObjectKind: KindForeignKey, DefaultSeverity: SeverityError,
+		Summary: "A foreign key constraint of a table of the target does not exist on the same table of the source.",
+		Causes: []string{
+			"The target is ahead of the source: a migration adding the constraint was run on it first",
+			"A migration dropping the constraint was not run on the target",
+		},
+		Fix:       []string{"ALTER TABLE <table> DROP CONSTRAINT <foreign_key>;"},
+		Generated: true,
+	},
+	{
+		Type: "ForeignKeyReferenceMismatch", Code: "PSC403", ObjectKind: KindForeignKey, DefaultSeverity: SeverityError,
+		Summary: "A foreign key constraint refers to different tables on the two sides.",
+		Causes: []string{
+			"The referenced table was renamed or replaced on one side only",
+			"The constraint was recreated to point to another table on one side only",
+		},
+		Fix:       []string{"ALTER TABLE <table> DROP CONSTRAINT <foreign_key>;", "ALTER TABLE <table> ADD CONSTRAINT <foreign_key> FOREIGN KEY (<columns>) REFERENCES <referenced_table> (<columns>);"},
+		Generated: true,
+	},
+	{
+		Type: "ForeignKeyColumnsMismatch", Code: "PSC404", ObjectKind: KindForeignKey, DefaultSeverity: SeverityError,
+		Summary:   "A foreign key constraint is on different columns of its table on the two sides.",
+		Causes:    []string{"The constraint was recreated on other columns on one side only"},
+		Fix:       []string{"ALTER TABLE <table> DROP CONSTRAINT <foreign_key>;", "ALTER TABLE <table> ADD CONSTRAINT <foreign_key> FOREIGN KEY (<columns>) REFERENCES <referenced_table> (<columns>);"},
+		Generated: true,
+	},
+	{
+		Type: "ForeignKeyReferencedColumnsMismatch", Code: "PSC405", ObjectKind: KindForeignKey, DefaultSeverity: SeverityError,
+		Summary:   "A foreign key constraint refers to different columns of the referenced table on the two sides.",
+		Causes:    []string{"The constraint was recreated to refer to another unique key on one side only"},
+		Fix:       []string{"ALTER TABLE <table> DROP CONSTRAINT <foreign_key>;", "ALTER TABLE <table> ADD CONSTRAINT <foreign_key> FOREIGN KEY (<columns>) REFERENCES <referenced_table> (<columns>);"},
+		Generated: true,
+	},
+	{
+		Type: "ForeignKeyOptionsMismatch", Code: "PSC406", ObjectKind: KindForeignKey, DefaultSeverity: SeverityError,
+		Summary: "A foreign key constraint has different referential actions (ON DELETE, ON UPDATE), match type, or deferrability on the two sides.",
+		Causes: []string{
+			"The constraint was recreated with other actions on one side only",
+			"The constraint was made deferrable on one side only",
+		},
+		Fix:       []string{"ALTER TABLE <table> DROP CONSTRAINT <foreign_key>;", "ALTER TABLE <table> ADD CONSTRAINT <foreign_key> FOREIGN KEY (<columns>) REFERENCES <referenced_table> (<columns>) <options>;"},
+		Generated: true,
+	},
+	{
+		Type: "HypertableMismatch", Code: "PSC501", ObjectKind: KindTable, DefaultSeverity: SeverityError,
+		Summary: "A TimescaleDB hypertable or continuous aggregate has different settings on the two sides: its dimensions, chunk interval, compression, or materialization.",
+		Causes: []string{
+			"create_hypertable, set_chunk_time_interval, or a compression policy was run on one side only",
+			"The table is a hypertable on one side and a plain table on the other",
+		},
+		Fix: []string{"SELECT set_chunk_time_interval('<table>', INTERVAL '<interval>');", "ALTER TABLE <table> SET (timescaledb.compress, timescaledb.compress_segmentby = '<columns>');"},
+	},
+	{
+		Type: "DistributionMismatch", Code: "PSC502", ObjectKind: KindTable, DefaultSeverity: SeverityError,
+		Summary: "A Citus table is distributed differently on the two sides: by another column or colocation group, or as a reference table on one side only.",
+		Causes: []string{
+			"create_distributed_table or create_reference_table was run on one side only",
+			"The table was distributed by another column on one side",
+		},
+		Fix: []string{"SELECT alter_distributed_table('<table>', distribution_column := '<column>');", "SELECT create_reference_table('<table>');"},
+	},
+	{
+		Type: "MissingView", Code: "PSC601", ObjectKind: KindView, DefaultSeverity: SeverityError,
+		Summary: "A view of the source does not exist in the target.",
+		Causes: []string{
+			"A migration creating the view was not run on the target",
+			"The view was dropped from the target, or dropped with CASCADE along with a table it reads",
+		},
+		Fix:       []string{"CREATE OR REPLACE VIEW <view> AS <query>;"},
+		Generated: true,
+	},
+	{
+		Type: "ExtraView", Code: "PSC602", ObjectKind: KindView, DefaultSeverity: SeverityError,
+		Summary: "A view of the target does not exist in the source.",
+		Causes: []string{
+			"The target is ahead of the source: a migration was run on it first",
+			"A view created by hand for reporting or debugging was left behind in the target",
+		},
+		Fix:       []string{"DROP VIEW <view>;", "Or, when the target may be ahead of the source, lower the severity with --extra-severity."},
+		Generated: true,
+	},
+	{
+		Type: "ViewDefinitionMismatch", Code: "PSC603", ObjectKind: KindView, DefaultSeverity: SeverityError,
+		Summary: "A view or materialized view has a different query on the two sides, once whitespace, keyword case, and quoting are ignored, or the relation is of a different kind (table, view, or materialized view) on each side.",
+		Causes: []string{
+			"CREATE OR REPLACE VIEW was run on one side only, or a materialized view was recreated with another query on one side",
+			"A table the view reads was changed, and the view was recreated on one side only",
+			"The relation was replaced by a table, view, or materialized view of another kind on one side",
+		},
+		Fix:       []string{"CREATE OR REPLACE VIEW <view> AS <query>;", "Or DROP VIEW <view>; and CREATE VIEW <view> AS <query>; when the columns of the view change, which CREATE OR REPLACE VIEW does not allow.", "DROP MATERIALIZED VIEW <view>; CREATE MATERIALIZED VIEW <view> AS <query>; for a materialized view, then its indexes."},
+		Generated: true,
+	},
+	{
+		Type: "MissingMaterializedView", Code: "PSC604", ObjectKind: KindMaterializedView, DefaultSeverity: SeverityError,
+		Summary: "A materialized view of the source does not exist in the target.",
+		Causes: []string{
+			"A migration creating the materialized view was not run on the target",
+			"The materialized view was dropped from the target, or dropped with CASCADE along with a table it reads",
+		},
+		Fix:       []string{"CREATE MATERIALIZED VIEW <view> AS <query> WITH DATA;", "CREATE INDEX <index> ON <view> (<columns>);"},
+		Generated: true,
+	},
+	{
+		Type: "ExtraMaterializedView", Code: "PSC605", ObjectKind: KindMaterializedView, DefaultSeverity: SeverityError,
+		Summary: "A materialized view of the target does not exist in the source.",
+		Causes: []string{
+			"The target is ahead of the source: a migration was run on it first",
+			"A materialized view created by hand for reporting was left behind in the target",
+		},
+		Fix:       []string{"DROP MATERIALIZED VIEW <view>;", "Or, when the target may be ahead of the source, lower the severity with --extra-severity."},
+		Generated: true,
+	},
+	{
+		Type: "MaterializedViewPopulatedMismatch", Code: "PSC606", ObjectKind: KindMaterializedView, DefaultSeverity: SeverityWarning,
+		Summary: "A materialized view holds data on one side only, so querying it fails on the side where it is not populated.",
+		Causes: []string{
+			"The materialized view was created WITH NO DATA, or refreshed WITH NO DATA, and not refreshed since on one side",
+			"A restore or migration created the materialized view without refreshing it",
+		},
+		Fix:       []string{"REFRESH MATERIALIZED VIEW <view>;", "Or REFRESH MATERIALIZED VIEW <view> WITH NO DATA; when the source is not populated."},
+		Generated: true,
+	},
+	{
+		Type: "MissingSequence", Code: "PSC701", ObjectKind: KindSequence, DefaultSeverity: SeverityError,
+		Summary: "A sequence of the source does not exist in the target.",
+		Causes: []string{
+			"A migration creating the sequence was not run on the target",
+			"The column the sequence backs was created as serial or identity on one side and with a plain default on the other",
+		},
+		Fix: []string{"CREATE SEQUENCE <sequence> AS <type> START <start> INCREMENT <increment> MINVALUE <min> MAXVALUE <max> CACHE <cache>;"},
+	},
+	{
+		Type: "ExtraSequence", Code: "PSC702", ObjectKind: KindSequence, DefaultSeverity: SeverityError,
+		Summary: "A sequence of the target does not exist in the source.",
+		Causes: []string{
+			"The target is ahead of the source: a migration was run on it first",
+			"The sequence was left behind in the target when the column it backed was dropped or changed",
+		},
+		Fix: []string{"DROP SEQUENCE <sequence>;", "Or, when the target may be ahead of the source, lower the severity with --extra-severity."},
+	},
+	{
+		Type: "SequenceMismatch", Code: "PSC703", ObjectKind: KindSequence, DefaultSeverity: SeverityError,
+		Summary: "A sequence has different options on the two sides: data type, start, increment, minimum, maximum, cache, or cycle. The value the sequence has reached is not compared.",
+		Causes: []string{
+			"The sequence was created with other options on one side, for example by hand or by another release of an ORM",
+			"ALTER SEQUENCE was run on one side only",
+			"The column the sequence backs was changed to another integer type on one side",
+		},
+		Fix: []string{"ALTER SEQUENCE <sequence> AS <type> INCREMENT <increment> MINVALUE <min> MAXVALUE <max> START <start> CACHE <cache> [NO] CYCLE;"},
+	},
+	{
+		Type: "SequenceOwnerMismatch", Code: "PSC704", ObjectKind: KindSequence, DefaultSeverity: SeverityError,
+		Summary: "A sequence is owned by different roles on the two sides (only with --compare-owners).",
+		Causes: []string{
+			"The sequence, or the table whose serial column it backs, was created by another role on one side",
+			"Ownership was reassigned on one side only",
+		},
+		Fix: []string{"ALTER SEQUENCE <sequence> OWNER TO <role>;"},
+	},
+	{
+		Type: "MissingTrigger", Code: "PSC801", ObjectKind: KindTrigger, DefaultSeverity: SeverityError,
+		Summary: "A trigger of a table of the source does not exist on the same table of the target.",
+		Causes: []string{
+			"A migration creating the trigger was not run on the target",
+			"The trigger was dropped or disabled on the target to load data, and never created again",
+		},
+		Fix: []string{"CREATE TRIGGER <trigger> <timing> <events> ON <table> FOR EACH <level> [WHEN (<condition>)] EXECUTE FUNCTION <function>;"},
+	},
+	{
+		Type: "ExtraTrigger", Code: "PSC802", ObjectKind: KindTrigger, DefaultSeverity: SeverityError,
+		Summary: "A trigger of a table of the target does not exist on the same table of the source.",
+		Causes: []string{
+			"The target is ahead of the source: a migration creating the trigger was run on it first",
+			"A trigger was added by hand to the target, such as for auditing or replication",
+		},
+		Fix: []string{"DROP TRIGGER <trigger> ON <table>;", "Or, when the target may be ahead of the source, lower the severity with --extra-severity."},
+	},
+	{
+		Type: "TriggerTimingMismatch", Code: "PSC803", ObjectKind: KindTrigger, DefaultSeverity: SeverityError,
+		Summary: "A trigger fires at different times on the two sides: BEFORE, AFTER, or INSTEAD OF the event.",
+		Causes: []string{
+			"The trigger was recreated with another timing on one side only",
+			"Two triggers of different definitions were given the same name",
+		},
+		Fix: []string{"CREATE OR REPLACE TRIGGER <trigger> <timing> <events> ON <table> FOR EACH <level> [WHEN (<condition>)] EXECUTE FUNCTION <function>;"},
+	},
+	{
+		Type: "TriggerEventsMismatch", Code: "PSC804", ObjectKind: KindTrigger, DefaultSeverity: SeverityError,
+		Summary: "A trigger fires on different events on the two sides: INSERT, UPDATE, DELETE, or TRUNCATE, or updates of different columns.",
+		Causes: []string{
+			"The trigger was recreated to fire on other events on one side only",
+			"The columns of an UPDATE OF trigger were changed on one side only",
+		},
+		Fix: []string{"CREATE OR REPLACE TRIGGER <trigger> <timing> <events> ON <table> FOR EACH <level> [WHEN (<condition>)] EXECUTE FUNCTION <function>;"},
+	},
+	{
+		Type: "TriggerLevelMismatch", Code: "PSC805", ObjectKind: KindTrigger, DefaultSeverity: SeverityError,
+		Summary: "A trigger fires for each row on one side and once per statement on the other.",
+		Causes: []string{
+			"The trigger was recreated as a row-level or statement-level trigger on one side only",
+		},
+		Fix: []string{"CREATE OR REPLACE TRIGGER <trigger> <timing> <events> ON <table> FOR EACH <level> [WHEN (<condition>)] EXECUTE FUNCTION <function>;"},
+	},
+	{
+		Type: "TriggerConditionMismatch", Code: "PSC806", ObjectKind: KindTrigger, DefaultSeverity: SeverityError,
+		Summary: "A trigger has different WHEN conditions on the two sides, or a condition on one side only. Conditions are compared once normalized.",
+		Causes: []string{
+			"The condition of the trigger was changed on one side only",
+		},
+		Fix: []string{"CREATE OR REPLACE TRIGGER <trigger> <timing> <events> ON <table> FOR EACH <level> [WHEN (<condition>)] EXECUTE FUNCTION <function>;"},
+	},
+	{
+		Type: "TriggerFunctionMismatch", Code: "PSC807", ObjectKind: KindTrigger, DefaultSeverity: SeverityError,
+		Summary: "A trigger calls different functions, or passes them different arguments, on the two sides. The bodies of the functions are not compared.",
+		Causes: []string{
+			"The trigger was recreated to call another function on one side only",
+			"The function is in a schema on the search_path of one side only, so it is shown qualified on the other",
+		},
+		Fix: []string{"CREATE OR REPLACE TRIGGER <trigger> <timing> <events> ON <table> FOR EACH <level> [WHEN (<condition>)] EXECUTE FUNCTION <function>;"},
+	},
+	{
+		Type: "MissingFunction", Code: "PSC901", ObjectKind: KindFunction, DefaultSeverity: SeverityError,
+		Summary: "A function or procedure of the source does not exist in the target with the same argument types.",
+		Causes: []string{
+			"A migration creating the function was not run on the target",
+			"The function was created with other argument types on the target, which makes it another function",
+		},
+		Fix: []string{"Run the definition of the function in the source on the target: CREATE OR REPLACE FUNCTION <function>(<arguments>) ...;"},
+	},
+	{
+		Type: "ExtraFunction", Code: "PSC902", ObjectKind: KindFunction, DefaultSeverity: SeverityError,
+		Summary: "A function or procedure of the target does not exist in the source with the same argument types.",
+		Causes: []string{
+			"The target is ahead of the source: a migration was run on it first",
+			"An older overload of the function was left behind in the target when its arguments changed",
+		},
+		Fix: []string{"DROP FUNCTION <function>(<arguments>);", "Or, when the target may be ahead of the source, lower the severity with --extra-severity."},
+	},
+	{
+		Type: "FunctionDefinitionMismatch", Code: "PSC903", ObjectKind: KindFunction, DefaultSeverity: SeverityError,
+		Summary: "A function or procedure has different definitions on the two sides once normalized: its return type, language, options, or body. Whitespace, comments, keyword case, and redundant parentheses do not count.",
+		Causes: []string{
+			"CREATE OR REPLACE FUNCTION was run on one side only, for example as a hotfix",
+			"A migration changing the function was not run on the target",
+		},
+		Fix: []string{"Run the definition of the function in the source on the target: CREATE OR REPLACE FUNCTION <function>(<arguments>) ...;"},
+	},
+	{
+		Type: "FunctionOwnerMismatch", Code: "PSC904", ObjectKind: KindFunction, DefaultSeverity: SeverityError,
+		Summary: "A function or procedure is owned by different roles on the two sides (only with --compare-owners). The owner of a SECURITY DEFINER function is the role it runs as.",
+		Causes: []string{
+			"The function was created by another role, such as a superuser running a migration by hand",
+			"Ownership was reassigned on one side only",
+		},
+		Fix: []string{"ALTER FUNCTION <function>(<arguments>) OWNER TO <role>;"},
+	},
+}
+
+// typeNames returns the names of the documented types of difference, in order.
+func typeNames() []string {
+	names := make([]string, 0, len(typeInfos))
+	for _, info := range typeInfos {
+		names = append(names, info.Type)
+	}
+	return names
+}
+
+// DescribeType returns the documentation of a type of difference, looked up by name or by code,
+// ignoring case.
+//
+// Parameters:
+//   - typeOrCode: Name of the type (e.g., "MissingColumn") or its code (e.g., "PSC201")
+//
+// Returns:
+//   - TypeInfo: Documentation of the type
+//   - bool: False if no built-in type has this name or code
+func DescribeType(typeOrCode string) (TypeInfo, bool) {
+	for _, info := range typeInfos {
+		if strings.EqualFold(info.Type, typeOrCode) || strings.EqualFold(info.Code, typeOrCode) {
+			return info, true
+		}
+	}
+	return TypeInfo{}, false
+}
+
+// TypeInfos returns the documentation of every built-in type of difference, sorted by code.
+//
+// Returns:
+//   - []TypeInfo: Documentation of the types
+func TypeInfos() []TypeInfo {
+	infos := append([]TypeInfo(nil), typeInfos...)
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Code < infos[j].Code })
+	return infos
+}
+
+// DefaultSeverity returns the severity of the differences of a type unless the comparator or a
+// severity override sets one: SeverityError, except for types documented otherwise.
+//
+// Parameters:
+//   - diffType: Type of difference
+//
+// Returns:
+//   - string: Default severity of the type
+func DefaultSeverity(diffType string) string {
+	if info, ok := DescribeType(diffType); ok && info.Type == diffType {
+		return info.DefaultSeverity
+	}
+	return SeverityError
+}
@@ -0,0 +1,110 @@
+package compare
+
+import (
+	"fmt"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+	"github.com/guriandoro/pg_schema_check/pkg/sqlnorm"
+)
+
+// compareViews compares a relation that is a view or materialized view on either side: its
+// kind, its definition, and whether a materialized view is populated. The definitions are
+// compared once normalized (see sqlnorm.Normalize), so that views written with other
+// whitespace, keyword case, or quoting, as servers of different versions print them, do not
+// differ.
+//
+// Parameters:
+//   - tableName: Name of the relation being compared
+//   - source: Relation in the source schema
+//   - target: Relation in the target schema
+//
+// Returns:
+//   - []Difference: A ViewDefinitionMismatch difference if the kinds or the definitions differ,
+//     and a MaterializedViewPopulatedMismatch difference if a materialized view is populated on
+//     one side only
+func compareViews(tableName string, source, target schema.TableInfo) []Difference {
+	if source.View == nil && target.View == nil {
+		return nil
+	}
+
+	sourceKind, targetKind := relationKind(source.View), relationKind(target.View)
+	if sourceKind != targetKind {
+		return []Difference{{
+			Type:        "ViewDefinitionMismatch",
+			Table:       tableName,
+			ObjectKind:  viewKind(source.View, target.View),
+			SourceValue: sourceKind,
+			TargetValue: targetKind,
+			Description: fmt.Sprintf("Relation is of different kinds: source=%s, target=%s", sourceKind, targetKind),
+		}}
+	}
+
+	_, kind, noun := relation(source)
+	var differences []Difference
+	if !sqlnorm.Equal(source.View.Definition, target.View.Definition) {
+		sourceValue, targetValue := sqlnorm.Normalize(source.View.Definition), sqlnorm.Normalize(target.View.Definition)
+		differences = append(differences, Difference{
+			Type:        "ViewDefinitionMismatch",
+			Table:       tableName,
+			ObjectKind:  kind,
+			SourceValue: sourceValue,
+			TargetValue: targetValue,
+			Description: fmt.Sprintf("%s has different definitions: source=%s, target=%s", noun, sourceValue, targetValue),
+		})
+	}
+	if source.View.Materialized && source.View.Populated != target.View.Populated {
+		sourceValue, targetValue := describePopulated(source.View.Populated), describePopulated(target.View.Populated)
+		differences = append(differences, Difference{
+			Type:        "MaterializedViewPopulatedMismatch",
+			Table:       tableName,
+			ObjectKind:  KindMaterializedView,
+			SourceValue: sourceValue,
+			TargetValue: targetValue,
+			Description: fmt.Sprintf("Materialized view is populated on one side only: source=%s, target=%s", sourceValue, targetValue),
+		})
+	}
+	return differences
+}
+
+// relation returns what a relation is, for the differences about it: the suffix of the types
+// of difference about it missing on one side ("Table", "View", or "MaterializedView"), its kind
+// of object, and its display name.
+func relation(table schema.TableInfo) (string, string, string) {
+	switch {
+	case table.View == nil:
+		return "Table", KindTable, "Table"
+	case table.View.Materialized:
+		return "MaterializedView", KindMaterializedView, "Materialized view"
+	}
+	return "View", KindView, "View"
+}
+
+// relationKind returns the kind of a relation, given its view definition, for display: "table",
+// "view", or "materialized view".
+func relationKind(v *schema.ViewInfo) string {
+	switch {
+	case v == nil:
+		return "table"
+	case v.Materialized:
+		return "materialized view"
+	}
+	return "view"
+}
+
+// viewKind returns the kind of object of a difference about a relation that is a view on at
+// least one side: KindMaterializedView if it is a materialized view on either side, and
+// KindView otherwise.
+func viewKind(source, target *schema.ViewInfo) string {
+	if (source != nil && source.Materialized) || (target != nil && target.Materialized) {
+		return KindMaterializedView
+	}
+	return KindView
+}
+
+// describePopulated formats whether a materialized view holds data, for display.
+func describePopulated(populated bool) string {
+	if populated {
+		return "populated"
+	}
+	return "not populated"
+}
@@ -0,0 +1,193 @@
+// Package config provides functionality to load schema-check settings from a YAML configuration file.
+// A configuration file can define several environments (e.g., dev, staging, prod), each bundling
+// the connections, filters, and severity overrides used to compare that environment.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/suppress"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is the configuration file used when no other path is given.
+const DefaultPath = "schema-check.yaml"
+
+// Profile holds the settings used for a single comparison. The top level of the
+// configuration file is itself a profile, providing defaults for every environment.
+type Profile struct {
+	Source        string            `yaml:"source,omitempty"`         // Connection string for the source database
+	Target        string            `yaml:"target,omitempty"`         // Connection string for the target database
+	IgnoreMarker  *string           `yaml:"ignore_marker,omitempty"`  // Comment marker that excludes objects; nil keeps the default
+	IncludeTables []string          `yaml:"include_tables,omitempty"` // Glob patterns of tables to compare; empty compares all tables
+	ExcludeTables []string          `yaml:"exclude_tables,omitempty"` // Glob patterns of tables to leave out of the comparison
+	Severity      map[string]string `yaml:"severity,omitempty"`       // Severity overrides keyed by difference type
+	ExtraSeverity string            `yaml:"extra_severity,omitempty"` // Severity of objects present only in the target, unless overridden by type
+	Direction     string            `yaml:"direction,omitempty"`      // Which side is authoritative: source-to-target, target-to-source, or both
+	Suppress      []suppress.Rule   `yaml:"suppress,omitempty"`       // Expression-based rules of differences to suppress
+	Tables        map[string]Table  `yaml:"tables,omitempty"`         // Per-table settings keyed by table glob pattern
+}
+
+// Table holds the settings that apply to the tables matching a pattern.
+type Table struct {
+	SkipColumnChecks []string `yaml:"skip_column_checks,omitempty"` // Column attribute checks to skip (type, nullable, default, identity, compression)
+}
+
+// Config represents the contents of a configuration file.
+type Config struct {
+	Profile      `yaml:",inline"`   // Defaults shared by all environments
+	Environments map[string]Profile `yaml:"environments,omitempty"` // Environments keyed by name
+	Daemon       *Daemon            `yaml:"daemon,omitempty"`       // Settings of the daemon subcommand, if it is used
+	History      *History           `yaml:"history,omitempty"`      // Database the differences of every run are recorded in, if any
+}
+
+// Load reads and parses the configuration file at the given path. Unknown keys are
+// rejected, so that a misspelled setting is not silently ignored.
+//
+// Parameters:
+//   - path: Path of the YAML configuration file
+//
+// Returns:
+//   - *Config: Parsed configuration
+//   - error: Any error that occurred while reading or parsing the file
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	var cfg Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// Resolve returns the effective profile for the named environment, layering the
+// environment's settings on top of the top-level defaults. An empty name returns
+// the defaults alone.
+//
+// Parameters:
+//   - env: Name of the environment to resolve
+//
+// Returns:
+//   - Profile: Effective settings for the environment
+//   - error: An error if the environment is not defined
+func (c *Config) Resolve(env string) (Profile, error) {
+	resolved := c.Profile.clone()
+	if env == "" {
+		return resolved, nil
+	}
+
+	profile, exists := c.Environments[env]
+	if !exists {
+		return Profile{}, fmt.Errorf("environment '%s' is not defined in the config file", env)
+	}
+
+	// Settings defined by the environment replace the defaults
+	if profile.Source != "" {
+		resolved.Source = profile.Source
+	}
+	if profile.Target != "" {
+		resolved.Target = profile.Target
+	}
+	if profile.IgnoreMarker != nil {
+		resolved.IgnoreMarker = profile.IgnoreMarker
+	}
+	if profile.Direction != "" {
+		resolved.Direction = profile.Direction
+	}
+	if profile.ExtraSeverity != "" {
+		resolved.ExtraSeverity = profile.ExtraSeverity
+	}
+	if profile.IncludeTables != nil {
+		resolved.IncludeTables = profile.IncludeTables
+	}
+	if profile.ExcludeTables != nil {
+		resolved.ExcludeTables = profile.ExcludeTables
+	}
+
+	// Suppression rules of the environment are added to the shared ones
+	resolved.Suppress = append(resolved.Suppress, profile.Suppress...)
+
+	// Per-table settings are merged, with the environment replacing a pattern's defaults
+	for pattern, table := range profile.Tables {
+		if resolved.Tables == nil {
+			resolved.Tables = make(map[string]Table)
+		}
+		resolved.Tables[pattern] = table
+	}
+
+	// Severity overrides are merged, with the environment taking precedence
+	for diffType, severity := range profile.Severity {
+		if resolved.Severity == nil {
+			resolved.Severity = make(map[string]string)
+		}
+		resolved.Severity[diffType] = severity
+	}
+
+	return resolved, nil
+}
+
+// clone returns a copy of the profile that can be modified without affecting the original.
+func (p Profile) clone() Profile {
+	clone := p
+	clone.Suppress = append([]suppress.Rule(nil), p.Suppress...)
+	if p.Tables != nil {
+		clone.Tables = make(map[string]Table, len(p.Tables))
+		for pattern, table := range p.Tables {
+			clone.Tables[pattern] = table
+		}
+	}
+	if p.Severity != nil {
+		clone.Severity = make(map[string]string, len(p.Severity))
+		for diffType, severity := range p.Severity {
+			clone.Severity[diffType] = severity
+		}
+	}
+	return clone
+}
+
+// TableOptions converts the per-table settings into the options used by the comparison,
+// ordered by pattern so that the result is deterministic.
+//
+// Returns:
+//   - []compare.TableOptions: Per-table comparison options
+func (p Profile) TableOptions() []compare.TableOptions {
+	patterns := make([]string, 0, len(p.Tables))
+	for pattern := range p.Tables {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	var options []compare.TableOptions
+	for _, pattern := range patterns {
+		options = append(options, compare.TableOptions{
+			Pattern:          pattern,
+			SkipColumnChecks: p.Tables[pattern].SkipColumnChecks,
+		})
+	}
+	return options
+}
+
+// SeverityOverrides returns the severity of every difference type that the profile overrides.
+// Overrides of a specific difference type take precedence over ExtraSeverity.
+//
+// Returns:
+//   - map[string]string: Severity overrides keyed by difference type
+func (p Profile) SeverityOverrides() map[string]string {
+	overrides := compare.ExtraSeverityOverrides(p.ExtraSeverity)
+	for diffType, severity := range p.Severity {
+		overrides[diffType] = severity
+	}
+	return overrides
+}
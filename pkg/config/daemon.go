@@ -0,0 +1,270 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/daemon"
+	"github.com/guriandoro/pg_schema_check/pkg/notify"
+)
+
+// DefaultStateFile is the file the daemon keeps its state in when no other is configured.
+const DefaultStateFile = "schema-check-state.json"
+
+// Daemon holds the settings of the daemon subcommand: which environments it compares, how
+// often, and who it tells when drift appears or resolves.
+type Daemon struct {
+	Interval  time.Duration `yaml:"interval,omitempty"`   // Time between the runs of each check, unless it has its own schedule
+	Schedule  string        `yaml:"schedule,omitempty"`   // Cron expression of the runs of each check, in place of interval
+	Jitter    time.Duration `yaml:"jitter,omitempty"`     // Longest random delay of the runs of each check, unless it has its own
+	Blackout  []Window      `yaml:"blackout,omitempty"`   // Windows during which no check runs, unless it has its own
+	StateFile string        `yaml:"state_file,omitempty"` // File keeping the outcome of the last run of each check; empty uses DefaultStateFile
+	Checks    []Check       `yaml:"checks,omitempty"`     // Comparisons to run; empty compares every environment
+	Notify    []Notifier    `yaml:"notify,omitempty"`     // Where to send notifications of drift
+}
+
+// Check is a comparison run by the daemon.
+type Check struct {
+	Environment string        `yaml:"environment"`        // Environment to compare
+	Interval    time.Duration `yaml:"interval,omitempty"` // Time between runs, overriding the daemon's
+	Schedule    string        `yaml:"schedule,omitempty"` // Cron expression of the runs, overriding the daemon's
+	Jitter      time.Duration `yaml:"jitter,omitempty"`   // Longest random delay of the runs, overriding the daemon's
+	Blackout    []Window      `yaml:"blackout,omitempty"` // Windows during which the check does not run, overriding the daemon's
+}
+
+// Window is a blackout window of the daemon, such as business hours, during which runs are
+// postponed to its end.
+type Window struct {
+	Start    string        `yaml:"start"`    // Cron expression of the times the window starts at
+	Duration time.Duration `yaml:"duration"` // Length of the window
+}
+
+// Notifier is a destination of the notifications of the daemon.
+type Notifier struct {
+	Type      string `yaml:"type"`                 // Kind of notifier: webhook, slack, teams, email, command, pagerduty, opsgenie, kafka, or nats
+	URL       string `yaml:"url,omitempty"`        // URL the events are posted to, for webhooks, Slack, and Teams, API endpoint of PagerDuty and Opsgenie, or URL of the NATS server; can reference secrets
+	ReportURL string `yaml:"report_url,omitempty"` // Link to the full report in Slack and Teams messages and incidents; {check} is replaced by the check
+	Command   string `yaml:"command,omitempty"`    // Shell command run for each event, for commands
+
+	SMTP         string   `yaml:"smtp,omitempty"`          // Address of the SMTP server as host:port, for email
+	Username     string   `yaml:"username,omitempty"`      // User the SMTP server or the Kafka brokers (with SASL/PLAIN) are logged in as; empty connects without authentication
+	PasswordEnv  string   `yaml:"password_env,omitempty"`  // Environment variable holding the password of the user, kept out of the file
+	PasswordFile string   `yaml:"password_file,omitempty"` // File holding the password of the user, such as a mounted secret, in place of password_env
+	From         string   `yaml:"from,omitempty"`          // Sender of the emails
+	To           []string `yaml:"to,omitempty"`            // Recipients of the emails
+	Format       string   `yaml:"format,omitempty"`        // Format of the emailed report: html (the default) or markdown
+
+	RoutingKey  string `yaml:"routing_key,omitempty"`  // Integration key of the PagerDuty service; can reference secrets
+	APIKey      string `yaml:"api_key,omitempty"`      // Key of the Opsgenie API integration; can reference secrets
+	MinSeverity string `yaml:"min_severity,omitempty"` // Least severity of the differences opening an incident: error (the default), warning, or info
+
+	Brokers []string `yaml:"brokers,omitempty"` // Bootstrap brokers of Kafka, as host:port
+	Topic   string   `yaml:"topic,omitempty"`   // Kafka topic the events are published to; {check} and {event} are replaced by the check and the kind of event
+	Subject string   `yaml:"subject,omitempty"` // NATS subject the events are published to, with the same replacements as topic
+	TLS     bool     `yaml:"tls,omitempty"`     // Whether to connect to the Kafka brokers with TLS
+
+	On []string `yaml:"on,omitempty"` // Events notified (started, difference, drift, resolved, report); empty notifies drift and resolved, and report as well for incidents, or every event for kafka and nats
+}
+
+// DaemonChecks returns the checks of the daemon, each with its effective schedule, jitter, and
+// blackout windows: its own, or the daemon's. Without configured checks, every environment is
+// checked, or the top level when there are no environments.
+//
+// Returns:
+//   - []Check: Checks to run, each with Interval or Schedule set
+func (c *Config) DaemonChecks() []Check {
+	var settings Daemon
+	if c.Daemon != nil {
+		settings = *c.Daemon
+	}
+
+	checks := settings.Checks
+	if len(checks) == 0 {
+		for _, env := range c.EnvironmentNames() {
+			checks = append(checks, Check{Environment: env})
+		}
+		if len(checks) == 0 {
+			checks = []Check{{}}
+		}
+	}
+
+	resolved := make([]Check, len(checks))
+	for i, check := range checks {
+		if check.Interval == 0 && check.Schedule == "" {
+			check.Interval, check.Schedule = settings.Interval, settings.Schedule
+		}
+		if check.Jitter == 0 {
+			check.Jitter = settings.Jitter
+		}
+		if check.Blackout == nil {
+			check.Blackout = settings.Blackout
+		}
+		resolved[i] = check
+	}
+	return resolved
+}
+
+// validate checks the settings of the daemon.
+//
+// Parameters:
+//   - c: Configuration the daemon belongs to, whose environments the checks refer to
+//
+// Returns:
+//   - []error: Every problem found in the daemon settings
+func (d Daemon) validate(c *Config) []error {
+	var problems []error
+
+	if d.Interval < 0 {
+		problems = append(problems, fmt.Errorf("daemon: interval must not be negative"))
+	}
+	if d.Interval > 0 && d.Schedule != "" {
+		problems = append(problems, fmt.Errorf("daemon: interval and schedule cannot both be set"))
+	}
+	if err := validateSchedule(d.Schedule); err != nil {
+		problems = append(problems, fmt.Errorf("daemon: %w", err))
+	}
+	problems = append(problems, validateTiming("daemon", d.Jitter, d.Blackout)...)
+
+	seen := make(map[string]bool)
+	for _, check := range d.Checks {
+		location := fmt.Sprintf("daemon: check '%s'", check.Environment)
+		if _, exists := c.Environments[check.Environment]; !exists {
+			problems = append(problems, fmt.Errorf("%s: environment is not defined", location))
+		}
+		if seen[check.Environment] {
+			problems = append(problems, fmt.Errorf("%s: is defined more than once", location))
+		}
+		seen[check.Environment] = true
+		if check.Interval < 0 {
+			problems = append(problems, fmt.Errorf("%s: interval must not be negative", location))
+		}
+		if check.Interval > 0 && check.Schedule != "" {
+			problems = append(problems, fmt.Errorf("%s: interval and schedule cannot both be set", location))
+		}
+		if err := validateSchedule(check.Schedule); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", location, err))
+		}
+		problems = append(problems, validateTiming(location, check.Jitter, check.Blackout)...)
+	}
+	for _, check := range c.DaemonChecks() {
+		if check.Interval == 0 && check.Schedule == "" {
+			problems = append(problems, fmt.Errorf("daemon: check '%s' has no interval or schedule", check.Environment))
+		}
+	}
+
+	for i, n := range d.Notify {
+		location := fmt.Sprintf("daemon: notify #%d", i+1)
+		switch n.Type {
+		case notify.TypeWebhook, notify.TypeSlack, notify.TypeTeams:
+			if n.URL == "" {
+				problems = append(problems, fmt.Errorf("%s: %s notifier has no url", location, n.Type))
+			}
+		case notify.TypeKafka:
+			if len(n.Brokers) == 0 || n.Topic == "" {
+				problems = append(problems, fmt.Errorf("%s: kafka notifier needs brokers and topic", location))
+			}
+			if n.PasswordEnv != "" && n.PasswordFile != "" {
+				problems = append(problems, fmt.Errorf("%s: password_env and password_file cannot both be set", location))
+			}
+		case notify.TypeNATS:
+			if n.URL == "" || n.Subject == "" {
+				problems = append(problems, fmt.Errorf("%s: nats notifier needs url and subject", location))
+			}
+		case notify.TypeEmail:
+			if n.SMTP == "" || n.From == "" || len(n.To) == 0 {
+				problems = append(problems, fmt.Errorf("%s: email notifier needs smtp, from, and to", location))
+			}
+			if n.PasswordEnv != "" && n.PasswordFile != "" {
+				problems = append(problems, fmt.Errorf("%s: password_env and password_file cannot both be set", location))
+			}
+			if n.Format != "" && n.Format != notify.EmailHTML && n.Format != notify.EmailMarkdown {
+				problems = append(problems, fmt.Errorf("%s: unknown format '%s' (expected %s or %s)", location, n.Format, notify.EmailHTML, notify.EmailMarkdown))
+			}
+		case notify.TypeCommand:
+			if n.Command == "" {
+				problems = append(problems, fmt.Errorf("%s: command notifier has no command", location))
+			}
+		case notify.TypePagerDuty:
+			if n.RoutingKey == "" {
+				problems = append(problems, fmt.Errorf("%s: pagerduty notifier has no routing_key", location))
+			}
+		case notify.TypeOpsgenie:
+			if n.APIKey == "" {
+				problems = append(problems, fmt.Errorf("%s: opsgenie notifier has no api_key", location))
+			}
+		default:
+			problems = append(problems, fmt.Errorf("%s: unknown type '%s' (expected one of %s)", location, n.Type, strings.Join(notify.Types, ", ")))
+		}
+		if err := checkSecretRefs(n.URL); err != nil {
+			problems = append(problems, fmt.Errorf("%s: url: %w", location, err))
+		}
+		if err := checkSecretRefs(n.RoutingKey); err != nil {
+			problems = append(problems, fmt.Errorf("%s: routing_key: %w", location, err))
+		}
+		if err := checkSecretRefs(n.APIKey); err != nil {
+			problems = append(problems, fmt.Errorf("%s: api_key: %w", location, err))
+		}
+		if n.MinSeverity != "" && (!compare.IsValidSeverity(n.MinSeverity) || n.MinSeverity == compare.SeverityIgnore) {
+			problems = append(problems, fmt.Errorf("%s: unknown min_severity '%s' (expected error, warning, or info)", location, n.MinSeverity))
+		}
+		for _, event := range n.On {
+			if !notify.IsKnownEvent(event) {
+				problems = append(problems, fmt.Errorf("%s: unknown event '%s' (expected one of %s)", location, event, strings.Join(notify.Events, ", ")))
+			}
+		}
+	}
+
+	return problems
+}
+
+// validateSchedule checks that a cron expression parses and selects a time that exists, unlike
+// February 30.
+//
+// Parameters:
+//   - schedule: Cron expression, or empty if none is set
+//
+// Returns:
+//   - error: An error if the expression is malformed or never selects a time
+func validateSchedule(schedule string) error {
+	if schedule == "" {
+		return nil
+	}
+	cron, err := daemon.ParseCron(schedule)
+	if err != nil {
+		return err
+	}
+	if cron.Next(time.Now()).IsZero() {
+		return fmt.Errorf("schedule '%s' never runs", schedule)
+	}
+	return nil
+}
+
+// validateTiming checks the jitter and the blackout windows of the daemon or of a check.
+//
+// Parameters:
+//   - location: Where the settings are, prefixed to the problems
+//   - jitter: Longest random delay of the runs
+//   - blackout: Blackout windows of the runs
+//
+// Returns:
+//   - []error: Every problem found in the settings
+func validateTiming(location string, jitter time.Duration, blackout []Window) []error {
+	var problems []error
+	if jitter < 0 {
+		problems = append(problems, fmt.Errorf("%s: jitter must not be negative", location))
+	}
+	for i, window := range blackout {
+		windowLocation := fmt.Sprintf("%s: blackout #%d", location, i+1)
+		if window.Start == "" {
+			problems = append(problems, fmt.Errorf("%s: has no start", windowLocation))
+		} else if err := validateSchedule(window.Start); err != nil {
+			problems = append(problems, fmt.Errorf("%s: start: %w", windowLocation, err))
+		}
+		if window.Duration <= 0 {
+			problems = append(problems, fmt.Errorf("%s: duration must be positive", windowLocation))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/guriandoro/pg_schema_check/pkg/suppress"
+	"gopkg.in/yaml.v3"
+)
+
+// AddSuppressRules appends suppression rules to a configuration file, at the top level or in an
+// environment, creating the file if it does not exist. The file is edited as a YAML document,
+// so its comments and the order of its settings are kept, though its indentation is normalized.
+//
+// Parameters:
+//   - path: Path of the YAML configuration file
+//   - env: Environment the rules are added to; empty adds them to the top level
+//   - rules: Rules to append to the suppress list
+//
+// Returns:
+//   - error: An error if the file cannot be parsed, the environment is not defined in it, or
+//     the file cannot be written
+func AddSuppressRules(path, env string, rules []suppress.Rule) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("error parsing config file %s: the top level is not a mapping", path)
+	}
+
+	profile := root
+	if env != "" {
+		environments := mappingValue(root, "environments")
+		if environments != nil {
+			profile = mappingValue(environments, env)
+		}
+		if environments == nil || profile == nil || profile.Kind != yaml.MappingNode {
+			return fmt.Errorf("environment '%s' is not defined in %s", env, path)
+		}
+	}
+
+	list := mappingValue(profile, "suppress")
+	if list == nil {
+		list = &yaml.Node{Kind: yaml.SequenceNode}
+		profile.Content = append(profile.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "suppress"}, list)
+	}
+	if list.Kind != yaml.SequenceNode {
+		return fmt.Errorf("error parsing config file %s: suppress is not a list", path)
+	}
+	list.Style = 0 // Flow-style lists would put every rule on one line
+	for _, rule := range rules {
+		var node yaml.Node
+		if err := node.Encode(rule); err != nil {
+			return fmt.Errorf("error encoding suppression rule: %w", err)
+		}
+		list.Content = append(list.Content, &node)
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("error encoding config file: %w", err)
+	}
+	encoder.Close()
+	return writeFile(path, out.Bytes())
+}
+
+// mappingValue returns the value of a key of a YAML mapping.
+//
+// Parameters:
+//   - mapping: Mapping node to look in
+//   - key: Key to find
+//
+// Returns:
+//   - *yaml.Node: Value of the key, or nil if the mapping does not have it
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// writeFile replaces a file at once, so that a failure never leaves it half-written. The mode of
+// an existing file is kept.
+//
+// Parameters:
+//   - path: Path of the file
+//   - data: New content of the file
+//
+// Returns:
+//   - error: Any error that occurred while writing the file
+func writeFile(path string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// History holds the settings of the history of differences: the database every run of a
+// comparison is recorded in, for the history subcommands to query.
+type History struct {
+	Database string `yaml:"database"` // PostgreSQL connection URL or path of a SQLite file, which can reference secrets
+}
+
+// validate checks the settings of the history.
+//
+// Returns:
+//   - []error: Every problem found in the history settings
+func (h History) validate() []error {
+	if strings.TrimSpace(h.Database) == "" {
+		return []error{fmt.Errorf("history: database is not set")}
+	}
+	if err := checkSecretRefs(h.Database); err != nil {
+		return []error{fmt.Errorf("history: database: %w", err)}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/secrets"
+)
+
+// secretRef matches the references to secrets in the settings that can hold credentials:
+// ${file:PATH}, ${env:NAME}, ${aws:SECRET}, and ${gcp:NAME}, and any other ${kind:...} so that
+// unknown kinds are reported.
+var secretRef = regexp.MustCompile(`\$\{([a-z]+):([^}]*)\}`)
+
+// secretTimeout limits the time taken to read a secret from a cloud secret manager.
+const secretTimeout = 30 * time.Second
+
+// unknownSecretKind is the error returned for references of an unknown kind.
+const unknownSecretKind = "unknown secret reference ${%s:...}: expected ${file:PATH}, ${env:NAME}, ${aws:SECRET}, or ${gcp:NAME}"
+
+// ExpandSecrets replaces the references to secrets in a setting by their values, read when it
+// is called, so that rotated secrets are picked up by the next comparison:
+//
+//   - ${file:PATH} is replaced by the contents of the file, without their trailing newline, as
+//     for the keys of a Kubernetes secret mounted as a directory
+//   - ${env:NAME} is replaced by the value of the environment variable
+//   - ${aws:SECRET} is replaced by the value of the secret of AWS Secrets Manager with this ARN
+//     or name, and ${gcp:NAME} by that of the secret of GCP Secret Manager with this resource
+//     name; both can end with #FIELD to read a field of a JSON secret (see secrets.Read)
+//
+// A setting can be a reference alone (source: ${file:/var/run/secrets/db/source}), which is
+// replaced as it is, or embed one in a connection string, where the value is escaped so that
+// any password can be embedded (see escapeSecret): percent-encoded in a postgres:// URL
+// (postgresql://app:${file:/var/run/secrets/db/password}@db:5432/app), and quoted in a
+// keyword/value connection string (host=db user=app password=${env:DB_PASSWORD}).
+//
+// Parameters:
+//   - value: Setting to expand
+//
+// Returns:
+//   - string: Setting with its references replaced
+//   - error: An error if a secret cannot be read, a variable is not set, or a kind is unknown
+func ExpandSecrets(value string) (string, error) {
+	var expanded strings.Builder
+	last := 0
+	for _, match := range secretRef.FindAllStringSubmatchIndex(value, -1) {
+		secret, err := readSecret(value[match[2]:match[3]], value[match[4]:match[5]])
+		if err != nil {
+			return "", err
+		}
+		expanded.WriteString(value[last:match[0]])
+		expanded.WriteString(escapeSecret(value, match[0], match[1], secret))
+		last = match[1]
+	}
+	expanded.WriteString(value[last:])
+	return expanded.String(), nil
+}
+
+// keywordValue matches the start of a keyword/value connection string (e.g., "host=db").
+var keywordValue = regexp.MustCompile(`^\s*[A-Za-z_]+\s*=`)
+
+// escapeSecret returns the value of a secret as it must be written in place of its reference
+// in a setting:
+//   - a reference making up the whole setting is replaced by the value as it is;
+//   - in a postgres:// or postgresql:// URL, the value is percent-encoded, except in the host
+//     and port, so that passwords holding characters such as @, /, :, or # keep their meaning;
+//   - in a keyword/value connection string, the value is quoted if the reference makes up the
+//     whole value of a keyword, and its quotes and backslashes escaped otherwise;
+//   - in other settings, the value is inserted as it is.
+//
+// Parameters:
+//   - value: Setting holding the reference
+//   - start: Position of the reference in the setting
+//   - end: Position after the reference
+//   - secret: Value of the secret
+//
+// Returns:
+//   - string: Text replacing the reference
+func escapeSecret(value string, start, end int, secret string) string {
+	if start == 0 && end == len(value) {
+		return secret
+	}
+
+	// References are masked, as they can hold characters with a meaning in URLs, such as / or #
+	masked := secretRef.ReplaceAllStringFunc(value, func(ref string) string {
+		return strings.Repeat("x", len(ref))
+	})
+	lower := strings.ToLower(masked)
+	switch {
+	case strings.HasPrefix(lower, "postgres://") || strings.HasPrefix(lower, "postgresql://"):
+		authority := strings.Index(masked, "://") + len("://")
+		authorityEnd := len(masked)
+		if i := strings.IndexAny(masked[authority:], "/?#"); i >= 0 {
+			authorityEnd = authority + i
+		}
+		host := authority
+		if i := strings.LastIndex(masked[authority:authorityEnd], "@"); i >= 0 {
+			host = authority + i + 1
+		}
+		if start >= host && start < authorityEnd {
+			return secret
+		}
+		return strings.ReplaceAll(url.QueryEscape(secret), "+", "%20")
+
+	case keywordValue.MatchString(masked):
+		escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(secret)
+		quoted := false
+		for i := 0; i < start; i++ {
+			switch masked[i] {
+			case '\\':
+				i++
+			case '\'':
+				quoted = !quoted
+			}
+		}
+		wholeValue := strings.HasSuffix(strings.TrimRight(masked[:start], " \t\n\r"), "=") &&
+			(end == len(masked) || strings.ContainsRune(" \t\n\r", rune(masked[end])))
+		if !quoted && wholeValue {
+			return "'" + escaped + "'"
+		}
+		return escaped
+
+	default:
+		return secret
+	}
+}
+
+// readSecret reads the value of a reference to a secret.
+//
+// Parameters:
+//   - kind: Kind of the reference: file, env, aws, or gcp
+//   - name: Path of the file, name of the variable, or reference to the cloud secret
+//
+// Returns:
+//   - string: Value of the secret
+//   - error: An error if it cannot be read or the kind is unknown
+func readSecret(kind, name string) (string, error) {
+	switch kind {
+	case "file":
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return "", fmt.Errorf("error reading secret: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case "env":
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("error reading secret: environment variable %s is not set", name)
+		}
+		return value, nil
+	case secrets.KindAWS, secrets.KindGCP:
+		ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+		defer cancel()
+		return secrets.Read(ctx, kind, name)
+	default:
+		return "", fmt.Errorf(unknownSecretKind, kind)
+	}
+}
+
+// checkSecretRefs checks that the references to secrets of a setting are well formed, without
+// reading them, as the secrets may only be available where the comparisons run.
+//
+// Parameters:
+//   - value: Setting to check
+//
+// Returns:
+//   - error: An error if a reference has an unknown kind, is empty, or is malformed
+func checkSecretRefs(value string) error {
+	for _, match := range secretRef.FindAllStringSubmatch(value, -1) {
+		if match[1] != "file" && match[1] != "env" && !secrets.IsKnownKind(match[1]) {
+			return fmt.Errorf(unknownSecretKind, match[1])
+		}
+		if match[2] == "" {
+			return fmt.Errorf("secret reference ${%s:} is empty", match[1])
+		}
+		if secrets.IsKnownKind(match[1]) {
+			if err := secrets.Check(match[1], match[2]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WithSecrets returns a copy of the profile with the references to secrets in its connection
+// strings expanded (see ExpandSecrets). Resolve leaves them as they are, so that resolved
+// profiles can be printed and validated where the secrets are not available; profiles are
+// expanded right before connecting.
+//
+// Returns:
+//   - Profile: Profile with its connection strings expanded
+//   - error: Any error that occurred while reading a secret
+func (p Profile) WithSecrets() (Profile, error) {
+	var err error
+	if p.Source, err = ExpandSecrets(p.Source); err != nil {
+		return p, fmt.Errorf("source: %w", err)
+	}
+	if p.Target, err = ExpandSecrets(p.Target); err != nil {
+		return p, fmt.Errorf("target: %w", err)
+	}
+	return p, nil
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestExpandSecretsEscapesConnectionStrings(t *testing.T) {
+	const password = `p@ss:w/rd#1 'q' \ %+?`
+	t.Setenv("SCHEMA_CHECK_TEST_PASSWORD", password)
+	t.Setenv("SCHEMA_CHECK_TEST_HOST", "db.example.com:6432")
+
+	tests := []struct {
+		name       string
+		connString string
+	}{
+		{"URL userinfo", "postgresql://app:${env:SCHEMA_CHECK_TEST_PASSWORD}@db:5432/app"},
+		{"URL query", "postgres://app@db:5432/app?password=${env:SCHEMA_CHECK_TEST_PASSWORD}"},
+		{"URL with a secret host", "postgresql://app:${env:SCHEMA_CHECK_TEST_PASSWORD}@${env:SCHEMA_CHECK_TEST_HOST}/app"},
+		{"keyword/value", "host=db user=app password=${env:SCHEMA_CHECK_TEST_PASSWORD} dbname=app"},
+		{"keyword/value at the end", "host=db user=app dbname=app password = ${env:SCHEMA_CHECK_TEST_PASSWORD}"},
+		{"keyword/value quoted", "host=db user=app password='${env:SCHEMA_CHECK_TEST_PASSWORD}' dbname=app"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expanded, err := ExpandSecrets(tt.connString)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config, err := pgconn.ParseConfig(expanded)
+			if err != nil {
+				t.Fatalf("ParseConfig(%q): %v", expanded, err)
+			}
+			if config.Password != password || config.User != "app" || config.Database != "app" {
+				t.Errorf("ParseConfig(%q) = user %q, password %q, database %q; want app, %q, app",
+					expanded, config.User, config.Password, config.Database, password)
+			}
+		})
+	}
+}
+
+func TestExpandSecretsEscapesMountedFiles(t *testing.T) {
+	const password = "p@ss w/rd"
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte(password+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, connString := range []string{
+		"postgresql://app:${file:" + path + "}@db:5432/app",
+		"host=db user=app dbname=app password=${file:" + path + "}",
+	} {
+		expanded, err := ExpandSecrets(connString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config, err := pgconn.ParseConfig(expanded)
+		if err != nil {
+			t.Fatalf("ParseConfig(%q): %v", expanded, err)
+		}
+		if config.Password != password {
+			t.Errorf("ParseConfig(%q).Password = %q, want %q", expanded, config.Password, password)
+		}
+	}
+}
+
+func TestExpandSecretsWholeSetting(t *testing.T) {
+	const url = "postgresql://app:p%40ss@db:5432/app"
+	t.Setenv("SCHEMA_CHECK_TEST_URL", url)
+
+	expanded, err := ExpandSecrets("${env:SCHEMA_CHECK_TEST_URL}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expanded != url {
+		t.Errorf("ExpandSecrets() = %q, want %q", expanded, url)
+	}
+}
@@ -0,0 +1,196 @@
+package daemon
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule decides when a check runs next.
+type Schedule interface {
+	// Next returns the first time after the given one that the check should run, or the zero
+	// time if it never runs again.
+	Next(after time.Time) time.Time
+}
+
+// Every is a schedule running a check at a fixed interval.
+type Every time.Duration
+
+// Next returns the time one interval after the given one.
+func (e Every) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(e))
+}
+
+// Cron is a schedule given as a cron expression of five fields: minute, hour, day of month,
+// month, and day of week (0 or 7 being Sunday). Each field is *, a value, a range (1-5), a
+// list (1,15), or any of these with a step (*/15, 0-30/10). As in cron, when both the day of
+// month and the day of week are restricted, a day matching either one is selected. The
+// shorthands @hourly, @daily, @weekly, and @monthly are also accepted. Times are matched in the
+// location of the time passed to Next.
+type Cron struct {
+	expr    string // Expression the schedule was parsed from
+	minute  uint64 // Minutes selected, one bit each
+	hour    uint64 // Hours selected
+	dom     uint64 // Days of the month selected
+	month   uint64 // Months selected
+	dow     uint64 // Days of the week selected, Sunday being 0
+	anyDay  bool   // Whether the day of month is unrestricted
+	anyWeek bool   // Whether the day of week is unrestricted
+}
+
+// cronShorthands are the named schedules accepted in place of an expression.
+var cronShorthands = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// cronFields are the bounds of each field of a cron expression.
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// ParseCron parses a cron expression.
+//
+// Parameters:
+//   - expr: Expression of five fields, or a shorthand such as @daily
+//
+// Returns:
+//   - *Cron: Parsed schedule
+//   - error: An error if the expression is malformed or a value is out of range
+func ParseCron(expr string) (*Cron, error) {
+	spec := strings.TrimSpace(expr)
+	if shorthand, ok := cronShorthands[spec]; ok {
+		spec = shorthand
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid schedule '%s': expected %d fields (minute hour day-of-month month day-of-week)", expr, len(cronFields))
+	}
+
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule '%s': %s: %w", expr, cronFields[i].name, err)
+		}
+		sets[i] = set
+	}
+
+	// Sunday can be written as 0 or 7
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+	return &Cron{
+		expr:    expr,
+		minute:  sets[0],
+		hour:    sets[1],
+		dom:     sets[2],
+		month:   sets[3],
+		dow:     sets[4],
+		anyDay:  fields[2] == "*",
+		anyWeek: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField parses one field of a cron expression into the set of values it selects.
+//
+// Parameters:
+//   - field: Field to parse
+//   - min: Smallest value of the field
+//   - max: Largest value of the field
+//
+// Returns:
+//   - uint64: Values selected, one bit each
+//   - error: An error if the field is malformed or a value is out of range
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step '%s'", stepText)
+			}
+		}
+
+		low, high := min, max
+		if rng != "*" {
+			lowText, highText, isRange := strings.Cut(rng, "-")
+			var err error
+			if low, err = strconv.Atoi(lowText); err != nil {
+				return 0, fmt.Errorf("invalid value '%s'", lowText)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(highText); err != nil {
+					return 0, fmt.Errorf("invalid value '%s'", highText)
+				}
+			} else if hasStep {
+				// A single value with a step runs from that value to the end of the field
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("'%s' is out of range %d-%d", rng, min, max)
+		}
+
+		for v := low; v <= high; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// String returns the expression the schedule was parsed from.
+func (c *Cron) String() string {
+	return c.expr
+}
+
+// Next returns the first minute after the given time selected by the expression, looking up to
+// five years ahead.
+func (c *Cron) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether the day of a time is selected by the day of month and day of week.
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.anyDay && c.anyWeek:
+		return true
+	case c.anyDay:
+		return dow
+	case c.anyWeek:
+		return dom
+	default:
+		return dom || dow
+	}
+}
@@ -0,0 +1,221 @@
+// Package daemon provides functionality to run schema comparisons on a schedule, remembering
+// their outcome between runs and notifying when drift appears or resolves.
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/notify"
+)
+
+// Check is a comparison run on a schedule.
+type Check struct {
+	Name     string        // Name of the check, such as the environment it compares
+	Schedule Schedule      // When the check runs
+	Jitter   time.Duration // Longest random delay added to each scheduled run, so that checks due at the same time spread out; zero runs them on time
+	Blackout []Window      // Windows during which the check does not run; runs due in one are postponed to its end
+}
+
+// CompareFunc runs the comparison of a check.
+//
+// Parameters:
+//   - ctx: Context of the run, cancelled when the daemon stops
+//   - check: Name of the check
+//
+// Returns:
+//   - compare.DiffResult: Differences found
+//   - error: Any error that occurred while comparing
+type CompareFunc func(ctx context.Context, check string) (compare.DiffResult, error)
+
+// Daemon runs checks on their schedules until it is stopped. A check with no recorded run, or
+// whose next run was due while the daemon was down, runs as soon as the daemon starts, or at the
+// end of the blackout window it starts in; after that, each check is scheduled from the end of
+// its previous run, delayed by its jitter and postponed out of its blackout windows. Checks run
+// one at a time.
+//
+// Every run sends a started event to the notifiers when it starts. Successful runs are compared
+// with the previous successful run of their check, so that known drift is not notified again at
+// every run: a run finding differences its previous run did not (as the first run of a check
+// with differences does) sends a drift event, and a run no longer finding some differences of
+// its previous run sends a resolved event. Every successful run also sends a difference event
+// for each difference it found, and then a report event; notifiers receive started, difference,
+// and report events only if they ask for them (see notify.Only).
+// Runs that fail are recorded and logged, but leave the status of the check unchanged, and
+// notifications that fail are logged without being retried.
+type Daemon struct {
+	Checks    []Check                       // Checks to run
+	Compare   CompareFunc                   // Runs the comparison of a check
+	Notifiers []notify.Notifier             // Destinations of the drift and resolved events
+	StatePath string                        // File the state of the checks is kept in
+	Once      bool                          // Whether to run every check once and return, ignoring the schedules
+	Logf      func(format string, a ...any) // Logs the runs and failures; nil discards them
+}
+
+// Run runs the checks until the context is cancelled, or once each with Once.
+//
+// Parameters:
+//   - ctx: Context whose end stops the daemon, interrupting the run in progress
+//
+// Returns:
+//   - error: Any error that occurred while reading the state file
+func (d *Daemon) Run(ctx context.Context) error {
+	state, err := LoadState(d.StatePath)
+	if err != nil {
+		return err
+	}
+
+	if d.Once {
+		for _, check := range d.Checks {
+			if ctx.Err() != nil {
+				break
+			}
+			d.runCheck(ctx, state, check)
+		}
+		return nil
+	}
+
+	// Work out the first run of each check from the state of the previous runs
+	now := time.Now()
+	next := make([]time.Time, len(d.Checks))
+	for i, check := range d.Checks {
+		next[i] = outsideBlackout(check, now)
+		if previous, ok := state.Checks[check.Name]; ok && !previous.LastRun.IsZero() {
+			if due := nextRun(check, previous.LastRun); due.After(now) || due.IsZero() {
+				next[i] = due
+			}
+		}
+		d.logf("Check %s: next run at %s.", check.Name, describeTime(next[i]))
+	}
+
+	for {
+		// Wait for the earliest check due
+		earliest := -1
+		for i, due := range next {
+			if !due.IsZero() && (earliest < 0 || due.Before(next[earliest])) {
+				earliest = i
+			}
+		}
+		if earliest < 0 {
+			d.logf("No check is scheduled to run again.")
+			<-ctx.Done()
+			return nil
+		}
+		timer := time.NewTimer(time.Until(next[earliest]))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		check := d.Checks[earliest]
+		d.runCheck(ctx, state, check)
+		if ctx.Err() != nil {
+			return nil
+		}
+		next[earliest] = nextRun(check, time.Now())
+		d.logf("Check %s: next run at %s.", check.Name, describeTime(next[earliest]))
+	}
+}
+
+// runCheck runs a check, records its outcome in the state file, and sends the events its
+// outcome calls for. A run interrupted by the end of the context is not recorded.
+//
+// Parameters:
+//   - ctx: Context of the run
+//   - state: State of the checks, updated in place
+//   - check: Check to run
+func (d *Daemon) runCheck(ctx context.Context, state *State, check Check) {
+	started := time.Now()
+	d.logf("Check %s: running.", check.Name)
+	d.notify(ctx, notify.Event{Kind: notify.EventStarted, Check: check.Name, Time: started})
+	differences, err := d.Compare(ctx, check.Name)
+	if ctx.Err() != nil {
+		return
+	}
+
+	current := state.Checks[check.Name]
+	if current == nil {
+		current = &CheckState{}
+		state.Checks[check.Name] = current
+	}
+	previous := current.Status
+	current.LastRun = started
+
+	var events []notify.Event
+	if err != nil {
+		current.Error = err.Error()
+		d.logf("Check %s: failed: %v", check.Name, err)
+	} else {
+		status := StatusClean
+		if len(differences) > 0 {
+			status = StatusDrift
+		}
+		if status != previous {
+			current.Since = started
+		}
+		appeared, resolved := differences.Changes(current.Found)
+		if previous == StatusDrift && current.Found == nil {
+			// The state was saved before differences were remembered: only the status can be compared
+			appeared, resolved = nil, nil
+		}
+		current.Status = status
+		current.Differences = len(differences)
+		current.Summary = differences.CountBySeverity()
+		current.Found = differences
+		current.Error = ""
+		d.logf("Check %s: %d differences, %d new, %d resolved.", check.Name, len(differences), len(appeared), len(resolved))
+
+		for _, diff := range differences {
+			events = append(events, notify.Event{Kind: notify.EventDifference, Check: check.Name, Time: started,
+				Summary: map[string]int{diff.Severity: 1}, Differences: compare.DiffResult{diff}})
+		}
+		if len(appeared) > 0 {
+			events = append(events, notify.Event{Kind: notify.EventDrift, Check: check.Name, Time: started, Summary: current.Summary, Differences: differences, Appeared: appeared})
+		}
+		if len(resolved) > 0 || (status == StatusClean && previous == StatusDrift) {
+			events = append(events, notify.Event{Kind: notify.EventResolved, Check: check.Name, Time: started, Summary: current.Summary, Differences: differences, Resolved: resolved})
+		}
+		events = append(events, notify.Event{Kind: notify.EventReport, Check: check.Name, Time: started, Summary: current.Summary, Differences: differences})
+	}
+
+	if err := state.Save(d.StatePath); err != nil {
+		d.logf("Could not save the state of the daemon: %v", err)
+	}
+	for _, event := range events {
+		d.notify(ctx, event)
+	}
+}
+
+// notify sends an event to every notifier, logging the ones that fail.
+//
+// Parameters:
+//   - ctx: Context of the notifications
+//   - event: Event to send
+func (d *Daemon) notify(ctx context.Context, event notify.Event) {
+	if event.Kind == notify.EventDrift || event.Kind == notify.EventResolved {
+		d.logf("Check %s: notifying %s.", event.Check, event.Kind)
+	}
+	for _, notifier := range d.Notifiers {
+		if err := notifier.Notify(ctx, event); err != nil {
+			d.logf("Check %s: could not send %s notification: %v", event.Check, event.Kind, err)
+		}
+	}
+}
+
+// logf logs a message if the daemon has a logger.
+func (d *Daemon) logf(format string, a ...any) {
+	if d.Logf != nil {
+		d.Logf(format, a...)
+	}
+}
+
+// describeTime formats the time of a run for the log, the zero time meaning never.
+func describeTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,94 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+)
+
+// Outcomes of the last successful run of a check.
+const (
+	StatusClean = "clean" // The comparison found no differences
+	StatusDrift = "drift" // The comparison found differences
+)
+
+// CheckState is what the daemon remembers of a check between runs.
+type CheckState struct {
+	LastRun     time.Time          `json:"last_run"`          // When the check last ran, successfully or not
+	Status      string             `json:"status,omitempty"`  // Outcome of the last successful run; empty if it never succeeded
+	Since       time.Time          `json:"since,omitempty"`   // When the status was first seen
+	Differences int                `json:"differences"`       // Number of differences found by the last successful run
+	Summary     map[string]int     `json:"summary,omitempty"` // Number of differences of each severity found by the last successful run
+	Found       compare.DiffResult `json:"found,omitempty"`   // Differences found by the last successful run, to tell which ones the next run sees appear or resolve
+	Error       string             `json:"error,omitempty"`   // Error the last run failed with, if it failed
+}
+
+// State is what the daemon remembers of every check, kept in a JSON file so that a restarted
+// daemon neither runs checks before they are due nor notifies again of differences it already
+// reported.
+type State struct {
+	Checks map[string]*CheckState `json:"checks"` // State of each check, keyed by name
+}
+
+// LoadState reads the state file of the daemon. A missing file is an empty state, as on the
+// first start of the daemon.
+//
+// Parameters:
+//   - path: Path of the state file
+//
+// Returns:
+//   - *State: State read
+//   - error: Any error that occurred while reading or parsing the file
+func LoadState(path string) (*State, error) {
+	state := &State{Checks: map[string]*CheckState{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state file: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("error parsing state file %s: %w", path, err)
+	}
+	if state.Checks == nil {
+		state.Checks = map[string]*CheckState{}
+	}
+	return state, nil
+}
+
+// Save writes the state file, replacing it at once so that a crash never leaves it half-written.
+//
+// Parameters:
+//   - path: Path of the state file
+//
+// Returns:
+//   - error: Any error that occurred while writing the file
+func (s *State) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	return nil
+}
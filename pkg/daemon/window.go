@@ -0,0 +1,77 @@
+package daemon
+
+import (
+	"math/rand"
+	"time"
+)
+
+// maxPostponements bounds how many blackout windows a run is pushed past, so that windows
+// covering every time make a check never run instead of looping forever.
+const maxPostponements = 1000
+
+// Window is a blackout window: a period, starting at every time selected by a cron expression,
+// during which a check does not run.
+type Window struct {
+	Start    *Cron         // Times the window starts at
+	Duration time.Duration // Length of the window
+}
+
+// end returns the end of the window containing a time, if any.
+//
+// Parameters:
+//   - t: Time to check
+//
+// Returns:
+//   - time.Time: End of the window holding t, or the zero time if t is outside the window
+func (w Window) end(t time.Time) time.Time {
+	// The latest start before t is the first one after t - Duration, if it is not after t
+	start := w.Start.Next(t.Add(-w.Duration))
+	if start.IsZero() || start.After(t) {
+		return time.Time{}
+	}
+	return start.Add(w.Duration)
+}
+
+// nextRun returns when a check runs next: at the next time of its schedule, delayed by a random
+// jitter, and postponed to the end of any blackout window it falls in.
+//
+// Parameters:
+//   - check: Check to schedule
+//   - after: Time the next run is looked for after
+//
+// Returns:
+//   - time.Time: Time of the next run, or the zero time if the check never runs again
+func nextRun(check Check, after time.Time) time.Time {
+	next := check.Schedule.Next(after)
+	if next.IsZero() {
+		return next
+	}
+	if check.Jitter > 0 {
+		next = next.Add(time.Duration(rand.Int63n(int64(check.Jitter))))
+	}
+	return outsideBlackout(check, next)
+}
+
+// outsideBlackout postpones a time out of the blackout windows of a check.
+//
+// Parameters:
+//   - check: Check whose blackout windows are avoided
+//   - t: Time a run is due at
+//
+// Returns:
+//   - time.Time: t, or the end of the blackout windows it falls in, or the zero time if the
+//     windows never end
+func outsideBlackout(check Check, t time.Time) time.Time {
+	for i := 0; i < maxPostponements; i++ {
+		postponed := false
+		for _, window := range check.Blackout {
+			if end := window.end(t); !end.IsZero() {
+				t, postponed = end, true
+			}
+		}
+		if !postponed {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,606 @@
+// Package doctor checks, before a comparison, that the source and target can be read as the
+// comparison needs: that they can be reached, which server they run, which timeouts and
+// search_path their sessions get, whether the role can read the compared schema and its catalog,
+// and, from all of these, which comparisons will be skipped or degraded, and why.
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+	"github.com/jackc/pgx/v5"
+)
+
+// Statuses of a check.
+const (
+	StatusOK      = "ok"      // Nothing was found that affects the comparison
+	StatusWarning = "warning" // The comparison can run, but something may degrade it or make it fail
+	StatusFailed  = "failed"  // The comparison cannot run
+	StatusSkipped = "skipped" // The check was not run, as an earlier check of the side failed
+)
+
+// Names of the checks, in the order they are run.
+const (
+	CheckConnection = "connection"  // The database can be reached and logged into, or the snapshot read
+	CheckServer     = "server"      // The engine and version of the server are supported
+	CheckTimeouts   = "timeouts"    // The timeouts of the sessions suit catalog queries
+	CheckSearchPath = "search_path" // The search_path of the sessions does not change how the catalog is read
+	CheckSchema     = "schema"      // The compared schema exists and the role may use it
+	CheckCatalog    = "catalog"     // The catalog queries of a comparison succeed on the schema
+	CheckFeatures   = "features"    // Which features of the schema model can be read
+)
+
+// Statuses of a comparison in Report.Comparisons.
+const (
+	ComparisonSkipped  = "skipped"  // The comparison is not made, and its types of difference never reported
+	ComparisonDegraded = "degraded" // The comparison is made, but may report differences that are not there, or miss some
+)
+
+// minStatementTimeout is the statement_timeout below which catalog queries of large schemas
+// risk being cancelled.
+const minStatementTimeout = 30 * time.Second
+
+// Check is the outcome of one check of a side.
+type Check struct {
+	Name    string `json:"name"`           // Name of the check (see the Check constants)
+	Status  string `json:"status"`         // Status of the check (see the Status constants)
+	Message string `json:"message"`        // What was found
+	Hint    string `json:"hint,omitempty"` // How to resolve a warning or failure, if known
+}
+
+// Side is the outcome of the checks of the source or the target.
+type Side struct {
+	Name                string         `json:"name"`                           // Name of the side ("source" or "target")
+	Location            string         `json:"location"`                       // Connection string, with its password masked, or path of the snapshot
+	Snapshot            bool           `json:"snapshot,omitempty"`             // Whether the side is a snapshot file rather than a database
+	Schema              string         `json:"schema"`                         // PostgreSQL schema compared
+	Dialect             schema.Dialect `json:"dialect,omitempty"`              // Engine of the server, if it could be reached
+	ServerVersion       int            `json:"server_version,omitempty"`       // Version of a PostgreSQL or Aurora server, as in server_version_num
+	SearchPath          []string       `json:"search_path,omitempty"`          // Schemas searched by the sessions, including implicit ones, in order; empty if unknown
+	UnsupportedFeatures []string       `json:"unsupported_features,omitempty"` // Features that cannot be read (see the schema.Feature constants)
+	UnprivilegedTables  int            `json:"unprivileged_tables,omitempty"`  // Tables whose columns and keys information_schema hides from the role, on engines read through it
+	FailedTables        []string       `json:"failed_tables,omitempty"`        // Tables whose details could not be read, among those read
+	Checks              []Check        `json:"checks"`                         // Outcome of each check, in the order they were run
+}
+
+// Comparison is a comparison that the outcome of the checks skips or degrades.
+type Comparison struct {
+	Name   string   `json:"name"`           // What is compared (e.g., "identity columns")
+	Status string   `json:"status"`         // ComparisonSkipped or ComparisonDegraded
+	Types  []string `json:"types"`          // Types of difference affected; empty for every type
+	Reason string   `json:"reason"`         // Why the comparison is skipped or degraded
+	Hint   string   `json:"hint,omitempty"` // How to avoid it, if possible
+}
+
+// Report is the outcome of the checks of both sides, with the comparisons that it skips or degrades.
+type Report struct {
+	Source      Side         `json:"source"`      // Checks of the source
+	Target      Side         `json:"target"`      // Checks of the target
+	Comparisons []Comparison `json:"comparisons"` // Comparisons skipped or degraded; empty if every comparison runs in full
+}
+
+// Options controls how the databases are checked. They should match those of the comparison
+// being prepared, so that the checks see the sessions it would get.
+type Options struct {
+	Session        schema.SessionSettings // Timeouts and pooling of the sessions
+	Fetch          schema.FetchOptions    // Schema, table filters, and retries of the catalog queries
+	ConnectTimeout time.Duration          // Time limit for connecting; zero means no limit
+	Hint           func(err error) string // Suggests how to resolve the error of a failed check; nil gives no hints
+}
+
+// hint returns the suggestion of the Hint function for an error, if any.
+func (o Options) hint(err error) string {
+	if o.Hint == nil {
+		return ""
+	}
+	hint := strings.TrimPrefix(o.Hint(err), "Hint: ")
+	if hint == "" {
+		return ""
+	}
+	return strings.ToUpper(hint[:1]) + hint[1:]
+}
+
+// schemaName returns the PostgreSQL schema compared.
+func (o Options) schemaName() string {
+	if o.Fetch.SchemaName == "" {
+		return schema.DefaultSchemaName
+	}
+	return o.Fetch.SchemaName
+}
+
+// Failed reports whether a check of the side failed, so that it cannot be compared.
+func (s Side) Failed() bool {
+	return s.failure() != nil
+}
+
+// failure returns the first failed check of the side, or nil if none failed.
+func (s Side) failure() *Check {
+	for i := range s.Checks {
+		if s.Checks[i].Status == StatusFailed {
+			return &s.Checks[i]
+		}
+	}
+	return nil
+}
+
+// Failed reports whether a check of either side failed, so that the comparison cannot run.
+func (r *Report) Failed() bool {
+	return r.Source.Failed() || r.Target.Failed()
+}
+
+// Count returns the number of checks of both sides with a status.
+//
+// Parameters:
+//   - status: One of the Status constants
+//
+// Returns:
+//   - int: Number of checks with the status
+func (r *Report) Count(status string) int {
+	count := 0
+	for _, check := range append(slices.Clone(r.Source.Checks), r.Target.Checks...) {
+		if check.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Server describes the server of the side (e.g., "PostgreSQL 16.2"), or returns "unknown
+// server" if it could not be reached, or was not recorded in its snapshot.
+func (s Side) Server() string {
+	switch s.Dialect {
+	case schema.DialectCockroach:
+		return "CockroachDB"
+	case schema.DialectRedshift:
+		return "Amazon Redshift"
+	case schema.DialectAurora:
+		return "Aurora PostgreSQL " + schema.FormatServerVersion(s.ServerVersion)
+	}
+	if s.ServerVersion == 0 {
+		return "unknown server"
+	}
+	return "PostgreSQL " + schema.FormatServerVersion(s.ServerVersion)
+}
+
+// add records the outcome of a check.
+func (s *Side) add(name, status, message, hint string) {
+	s.Checks = append(s.Checks, Check{Name: name, Status: status, Message: message, Hint: hint})
+}
+
+// Diagnose connects to a database as a comparison would, and runs the checks of one side on it.
+// A failed connection skips the other checks, as does a server that is not supported.
+//
+// Parameters:
+//   - ctx: Context bounding the checks
+//   - name: Name of the side ("source" or "target")
+//   - connString: PostgreSQL connection string of the database
+//   - location: Connection string with its password masked, as shown in the report
+//   - opts: Settings of the comparison being prepared
+//
+// Returns:
+//   - Side: Outcome of the checks
+func Diagnose(ctx context.Context, name, connString, location string, opts Options) Side {
+	side := Side{Name: name, Location: location, Schema: opts.schemaName()}
+
+	connectCtx := ctx
+	if opts.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		connectCtx, cancel = context.WithTimeout(ctx, opts.ConnectTimeout)
+		defer cancel()
+	}
+	started := time.Now()
+	var conn *pgx.Conn
+	err := opts.Fetch.Retry.Do(connectCtx, func() error {
+		var err error
+		conn, err = schema.Connect(connectCtx, connString, opts.Session)
+		return err
+	})
+	if err != nil {
+		side.add(CheckConnection, StatusFailed, err.Error(), opts.hint(err))
+		side.skipRemaining(CheckServer, CheckTimeouts, CheckSearchPath, CheckSchema, CheckCatalog, CheckFeatures)
+		return side
+	}
+	defer conn.Close(context.Background())
+	pooled := opts.Session.Pooler == schema.PoolerTransaction
+	if detected, _ := schema.PoolerDetected(connString); detected && opts.Session.Pooler == schema.PoolerAuto {
+		pooled = true
+	}
+	message := fmt.Sprintf("Connected in %s", time.Since(started).Round(time.Millisecond))
+	if pooled {
+		message += ", through a pooler sharing server sessions between transactions"
+	}
+	side.add(CheckConnection, StatusOK, message, "")
+
+	if !side.checkServer(ctx, conn, opts) {
+		side.skipRemaining(CheckTimeouts, CheckSearchPath, CheckSchema, CheckCatalog, CheckFeatures)
+		return side
+	}
+	side.checkTimeouts(ctx, conn, opts, pooled)
+	side.checkSearchPath(ctx, conn)
+	side.checkSchema(ctx, conn)
+	side.checkCatalog(ctx, conn, opts)
+	return side
+}
+
+// DiagnoseSnapshot reads a snapshot as a comparison would, and reports what it can tell about
+// the side: the server it was taken from, the features that could not be read then, and the
+// tables whose details could not be read. Checks that need a live session are left out.
+//
+// Parameters:
+//   - ctx: Context bounding the read
+//   - name: Name of the side ("source" or "target")
+//   - path: Path of the snapshot, as shown in the report
+//   - fetcher: Fetcher reading the snapshot
+//
+// Returns:
+//   - Side: Outcome of the checks
+func DiagnoseSnapshot(ctx context.Context, name, path string, fetcher schema.Fetcher) Side {
+	side := Side{Name: name, Location: path, Snapshot: true}
+	s, err := fetcher.Fetch(ctx)
+	if err != nil {
+		side.add(CheckConnection, StatusFailed, err.Error(), "Check the path of the snapshot, and that it was written by the snapshot subcommand.")
+		side.skipRemaining(CheckServer, CheckCatalog, CheckFeatures)
+		return side
+	}
+	side.Schema, side.ServerVersion = s.Name, s.ServerVersion
+	message := fmt.Sprintf("Read the snapshot, with %d tables", len(s.Tables))
+	if s.Name != "" {
+		message = fmt.Sprintf("Read the snapshot of schema %s, with %d tables", s.Name, len(s.Tables))
+	}
+	side.add(CheckConnection, StatusOK, message, "")
+	if s.ServerVersion == 0 {
+		side.add(CheckServer, StatusWarning, "The snapshot does not record the version of its server",
+			"Take the snapshot again with a current release, so that features the server lacks are told apart from missing objects.")
+	} else {
+		side.add(CheckServer, StatusOK, side.Server(), "")
+	}
+	side.checkFetchErrors(s)
+	side.checkFeatures(s.UnsupportedFeatures)
+	return side
+}
+
+// skipRemaining records checks that were not run, as an earlier check failed.
+func (s *Side) skipRemaining(names ...string) {
+	for _, name := range names {
+		s.add(name, StatusSkipped, "Not checked, as an earlier check failed", "")
+	}
+}
+
+// checkServer identifies the engine and version of the server, and checks that its catalog can
+// be read.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Open session
+//   - opts: Settings of the comparison being prepared
+//
+// Returns:
+//   - bool: False if the check failed, so the remaining checks cannot be run
+func (s *Side) checkServer(ctx context.Context, conn *pgx.Conn, opts Options) bool {
+	dialect := opts.Fetch.Dialect
+	if dialect == "" {
+		var err error
+		if dialect, err = schema.DetectDialect(ctx, conn); err != nil {
+			s.add(CheckServer, StatusFailed, err.Error(), opts.hint(err))
+			return false
+		}
+	}
+	s.Dialect = dialect
+	if dialect == schema.DialectPostgres || dialect == schema.DialectAurora {
+		version := opts.Fetch.ServerVersion
+		if version == 0 {
+			var err error
+			if version, err = schema.DetectServerVersion(ctx, conn); err != nil {
+				s.add(CheckServer, StatusFailed, err.Error(), opts.hint(err))
+				return false
+			}
+		}
+		s.ServerVersion = version
+	}
+
+	unsupported, err := schema.UnsupportedFeatures(dialect, s.ServerVersion)
+	if err != nil {
+		s.add(CheckServer, StatusFailed, fmt.Sprintf("%s: %v", s.Server(), err), opts.hint(err))
+		return false
+	}
+	s.UnsupportedFeatures = unsupported
+	s.add(CheckServer, StatusOK, s.Server(), "")
+	return true
+}
+
+// Names of the session settings checked by checkTimeouts.
+const (
+	settingStatementTimeout = "statement_timeout"
+	settingLockTimeout      = "lock_timeout"
+	settingIdleTimeout      = "idle_in_transaction_session_timeout"
+)
+
+// checkTimeouts reads the timeouts of the session, and checks that they are those asked for,
+// that catalog queries have enough time, and that they do not wait indefinitely behind DDL.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Open session
+//   - opts: Settings of the comparison being prepared
+//   - pooled: Whether the session is pooled by transaction, so its timeouts were not set
+func (s *Side) checkTimeouts(ctx context.Context, conn *pgx.Conn, opts Options, pooled bool) {
+	settings, err := readTimeouts(ctx, conn)
+	if err != nil {
+		s.add(CheckTimeouts, StatusWarning, fmt.Sprintf("The timeouts of the session could not be read: %v", err), "")
+		return
+	}
+
+	requested := map[string]time.Duration{
+		settingStatementTimeout: opts.Session.StatementTimeout,
+		settingLockTimeout:      opts.Session.LockTimeout,
+		settingIdleTimeout:      opts.Session.IdleInTransactionSessionTimeout,
+	}
+	var found, issues, hints []string
+	for _, name := range []string{settingStatementTimeout, settingLockTimeout, settingIdleTimeout} {
+		value, known := settings[name]
+		if !known {
+			continue
+		}
+		found = append(found, fmt.Sprintf("%s %s", name, formatTimeout(value)))
+		// Timeouts are set in milliseconds, rounded up
+		if asked := requested[name]; asked > 0 && value != (asked+time.Millisecond-1).Truncate(time.Millisecond) && !pooled {
+			issues = append(issues, fmt.Sprintf("%s is %s rather than the %s asked for, as the server refused the setting", name, formatTimeout(value), asked))
+		}
+	}
+	if value, known := settings[settingStatementTimeout]; known && value > 0 && value < minStatementTimeout {
+		issues = append(issues, fmt.Sprintf("statement_timeout is only %s, which may cancel the catalog queries of large schemas", value))
+		hints = append(hints, "Raise statement_timeout (--statement-timeout), or compare the schema in batches (--low-memory).")
+	}
+	if value, known := settings[settingLockTimeout]; known && value == 0 {
+		issues = append(issues, "lock_timeout is not set, so catalog queries can wait indefinitely behind concurrent DDL, blocking the queries queued after it")
+		hints = append(hints, "Set lock_timeout (--lock-timeout).")
+	}
+	if pooled {
+		found = append(found, "left as the pooler's server sessions have them, as sessions pooled by transaction cannot set their own")
+		if len(issues) > 0 {
+			hints = append(hints, "Through a pooler sharing server sessions, set the timeouts on the role (ALTER ROLE ... SET) or rely on the pooler's query_timeout.")
+		}
+	}
+
+	message := "The session has " + strings.Join(found, ", ")
+	if len(found) == 0 {
+		message = "The server has none of the timeouts of a comparison"
+	}
+	if len(issues) > 0 {
+		s.add(CheckTimeouts, StatusWarning, message+"; "+strings.Join(issues, "; "), strings.Join(hints, " "))
+		return
+	}
+	s.add(CheckTimeouts, StatusOK, message, "")
+}
+
+// readTimeouts reads the timeouts the server knows of the session.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Open session
+//
+// Returns:
+//   - map[string]time.Duration: Timeouts keyed by setting name; zero means none
+//   - error: Any error that occurred during the query
+func readTimeouts(ctx context.Context, conn *pgx.Conn) (map[string]time.Duration, error) {
+	rows, err := conn.Query(ctx, `
+	SELECT name, setting
+	FROM pg_catalog.pg_settings
+	WHERE name IN ('statement_timeout', 'lock_timeout', 'idle_in_transaction_session_timeout')
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := make(map[string]time.Duration)
+	for rows.Next() {
+		var name, setting string
+		if err := rows.Scan(&name, &setting); err != nil {
+			return nil, err
+		}
+		// The three settings are in milliseconds
+		milliseconds, err := strconv.ParseInt(setting, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected value '%s' of %s", setting, name)
+		}
+		settings[name] = time.Duration(milliseconds) * time.Millisecond
+	}
+	return settings, rows.Err()
+}
+
+// formatTimeout formats a timeout, telling when it is disabled.
+func formatTimeout(value time.Duration) string {
+	if value == 0 {
+		return "0 (none)"
+	}
+	return value.String()
+}
+
+// checkSearchPath reads the schemas the session searches, and checks that none comes before
+// pg_catalog, which would let its objects shadow the catalog functions the queries call.
+// Whether the compared schema is on it decides whether its types, sequences, and functions are
+// shown qualified with its name, which the report compares across both sides.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Open session
+func (s *Side) checkSearchPath(ctx context.Context, conn *pgx.Conn) {
+	if s.Dialect == schema.DialectRedshift {
+		s.add(CheckSearchPath, StatusOK, "Not checked on Amazon Redshift", "")
+		return
+	}
+	var setting string
+	var schemas []string
+	err := conn.QueryRow(ctx, "SELECT current_setting('search_path'), current_schemas(true)::text[]").Scan(&setting, &schemas)
+	if err != nil {
+		s.add(CheckSearchPath, StatusWarning, fmt.Sprintf("The search_path of the session could not be read: %v", err), "")
+		return
+	}
+	s.SearchPath = schemas
+
+	message := fmt.Sprintf("search_path is '%s'", setting)
+	if slices.Contains(schemas, s.Schema) {
+		message += fmt.Sprintf("; %s is on it, so its types, sequences, and functions are shown without their schema", s.Schema)
+	} else {
+		message += fmt.Sprintf("; %s is not on it, so its types, sequences, and functions are shown qualified with their schema", s.Schema)
+	}
+	catalog := slices.Index(schemas, "pg_catalog")
+	if catalog > 0 && schemas[0] != "pg_temp" && !strings.HasPrefix(schemas[0], "pg_temp_") {
+		s.add(CheckSearchPath, StatusWarning,
+			fmt.Sprintf("%s; schema %s comes before pg_catalog, so its functions and types can shadow those the catalog queries rely on", message, schemas[0]),
+			"Leave pg_catalog out of search_path, so that it is searched first, or list it first.")
+		return
+	}
+	s.add(CheckSearchPath, StatusOK, message, "")
+}
+
+// checkSchema checks that the compared schema exists, and that the role may use it.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Open session
+func (s *Side) checkSchema(ctx context.Context, conn *pgx.Conn) {
+	var usage bool
+	err := conn.QueryRow(ctx, "SELECT has_schema_privilege(nspname, 'USAGE') FROM pg_catalog.pg_namespace WHERE nspname = $1", s.Schema).Scan(&usage)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		s.add(CheckSchema, StatusWarning, fmt.Sprintf("Schema %s does not exist, so every table of the other side will be reported as missing or extra", s.Schema),
+			"Check the name of the schema, and the database of the connection string.")
+	case err != nil:
+		s.add(CheckSchema, StatusWarning, fmt.Sprintf("The privileges on schema %s could not be read: %v", s.Schema, err), "")
+	case !usage:
+		s.add(CheckSchema, StatusWarning, fmt.Sprintf("The role lacks USAGE on schema %s, so catalog queries resolving its objects by name may fail", s.Schema),
+			fmt.Sprintf("GRANT USAGE ON SCHEMA %s TO the role, or run the comparison as the owner of the schema.", s.Schema))
+	default:
+		s.add(CheckSchema, StatusOK, fmt.Sprintf("Schema %s exists, and the role has USAGE on it", s.Schema), "")
+	}
+}
+
+// checkCatalog runs the catalog queries of a comparison on the compared schema: the tables are
+// listed as a comparison lists them, and the details of the first one are read, so that the
+// queries of each kind of object run at least once without reading the whole schema. The
+// features found unreadable are then checked.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Open session
+//   - opts: Settings of the comparison being prepared
+func (s *Side) checkCatalog(ctx context.Context, conn *pgx.Conn, opts Options) {
+	fetchOpts := opts.Fetch
+	fetchOpts.Dialect, fetchOpts.ServerVersion = s.Dialect, s.ServerVersion
+	fetchOpts.StopOnError, fetchOpts.Concurrency, fetchOpts.Stage = false, 1, false
+	fetchOpts.OnPhaseStart, fetchOpts.OnTableFetched, fetchOpts.Reuse = nil, nil, nil
+	sample := ""
+	fetchOpts.Details = func(table schema.TableInfo) bool {
+		if sample == "" {
+			sample = table.Name
+		}
+		return table.Name == sample
+	}
+
+	started := time.Now()
+	fetched, err := schema.Fetch(ctx, conn, fetchOpts)
+	if err != nil {
+		s.add(CheckCatalog, StatusFailed, err.Error(), opts.hint(err))
+		s.skipRemaining(CheckFeatures)
+		return
+	}
+	elapsed := time.Since(started).Round(time.Millisecond)
+
+	switch {
+	case len(fetched.Tables) == 0 && len(fetched.Errors) == 0:
+		s.add(CheckCatalog, StatusWarning, fmt.Sprintf("Schema %s has no tables kept by the table filters (%s)", s.Schema, elapsed),
+			"Check the name of the schema and the include and exclude patterns.")
+	case len(fetched.Errors) > 0:
+		s.checkFetchErrors(fetched)
+	default:
+		s.add(CheckCatalog, StatusOK, fmt.Sprintf("Listed %d tables and read the columns, keys, and indexes of %s in %s",
+			len(fetched.Tables), sample, elapsed), "")
+	}
+
+	if s.Dialect == schema.DialectCockroach || s.Dialect == schema.DialectRedshift {
+		s.checkTablePrivileges(ctx, conn)
+	}
+	s.checkFeatures(fetched.UnsupportedFeatures)
+}
+
+// checkFetchErrors records the tables of a schema whose details could not be read.
+//
+// Parameters:
+//   - fetched: Schema read
+func (s *Side) checkFetchErrors(fetched *schema.Schema) {
+	if len(fetched.Errors) == 0 {
+		s.add(CheckCatalog, StatusOK, fmt.Sprintf("The details of all %d tables were read", len(fetched.Tables)), "")
+		return
+	}
+	for _, fetchErr := range fetched.Errors {
+		s.FailedTables = append(s.FailedTables, fetchErr.Table)
+	}
+	failed := fetched.Errors[0]
+	message := fmt.Sprintf("The details of %s could not be read (%s), so it will be reported as FetchFailed rather than compared", failed.Table, failed.Message)
+	if len(fetched.Errors) > 1 {
+		message = fmt.Sprintf("The details of %d tables could not be read, such as %s (%s), so they will be reported as FetchFailed rather than compared",
+			len(fetched.Errors), failed.Table, failed.Message)
+	}
+	s.add(CheckCatalog, StatusWarning, message, "Grant the role the privileges to read these tables, or check that they were not dropped while being read.")
+}
+
+// checkTablePrivileges counts the tables of the compared schema the role has no privilege on,
+// on engines whose columns and keys are read through information_schema, which hides them.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Open session
+func (s *Side) checkTablePrivileges(ctx context.Context, conn *pgx.Conn) {
+	var count int
+	err := conn.QueryRow(ctx, `
+	SELECT count(*)::int
+	FROM pg_catalog.pg_class c
+	JOIN pg_catalog.pg_namespace n
+		ON n.oid = c.relnamespace
+	WHERE n.nspname = $1
+		AND c.relkind = 'r'
+		AND NOT has_table_privilege(c.oid, 'SELECT, INSERT, UPDATE, DELETE, REFERENCES')
+`, s.Schema).Scan(&count)
+	if err != nil {
+		s.add(CheckCatalog, StatusWarning, fmt.Sprintf("The privileges of the role on the tables of %s could not be read: %v", s.Schema, err),
+			"information_schema hides the columns and keys of tables the role has no privilege on.")
+		return
+	}
+	s.UnprivilegedTables = count
+	if count > 0 {
+		s.add(CheckCatalog, StatusWarning, fmt.Sprintf("The role has no privilege on %d tables of %s, whose columns and keys information_schema hides", count, s.Schema),
+			fmt.Sprintf("GRANT SELECT ON ALL TABLES IN SCHEMA %s TO the role.", s.Schema))
+	}
+}
+
+// checkFeatures records the features of the schema model that cannot be read, warning about
+// those whose metadata exists but is not readable by the role.
+//
+// Parameters:
+//   - unsupported: Features that could not be read (see the schema.Feature constants)
+func (s *Side) checkFeatures(unsupported []string) {
+	s.UnsupportedFeatures = unsupported
+	if len(unsupported) == 0 {
+		s.add(CheckFeatures, StatusOK, "Every feature of the schema model can be read", "")
+		return
+	}
+
+	var names, hints []string
+	status := StatusOK
+	for _, feature := range orderedFeatures(unsupported) {
+		names = append(names, features[feature].name)
+		if hint := features[feature].unreadable; hint != "" {
+			status = StatusWarning
+			hints = append(hints, hint)
+		}
+	}
+	s.add(CheckFeatures, status, fmt.Sprintf("Not read: %s", strings.Join(names, ", ")), strings.Join(hints, " "))
+}
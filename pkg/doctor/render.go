@@ -0,0 +1,100 @@
+package doctor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Formats of the report of the checks.
+const (
+	FormatText = "text" // The checks of each side, then the comparisons skipped or degraded
+	FormatJSON = "json" // The Report as JSON
+)
+
+// Formats lists the formats of the report of the checks.
+var Formats = []string{FormatText, FormatJSON}
+
+// CheckFormat checks that a format of the report of the checks is known, so that a misspelled
+// format can be reported before connecting.
+//
+// Parameters:
+//   - format: Name of the format
+//
+// Returns:
+//   - error: An error listing the formats if the format is unknown
+func CheckFormat(format string) error {
+	for _, known := range Formats {
+		if known == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown doctor report format '%s': expected one of %s", format, strings.Join(Formats, ", "))
+}
+
+// Write renders the report of the checks.
+//
+// Parameters:
+//   - w: Writer the report is written to
+//   - r: Report to render
+//   - format: Format of the report (see Formats)
+//
+// Returns:
+//   - error: An error if the format is unknown, or any error that occurred while writing
+func Write(w io.Writer, r *Report, format string) error {
+	switch format {
+	case FormatText:
+		return writeText(w, r)
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(r); err != nil {
+			return fmt.Errorf("error writing doctor report: %w", err)
+		}
+		return nil
+	}
+	return CheckFormat(format)
+}
+
+// writeText renders the report as aligned text: the checks of each side with their hints, the
+// comparisons skipped or degraded, and a summary line.
+func writeText(w io.Writer, r *Report) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, side := range []Side{r.Source, r.Target} {
+		if i > 0 {
+			fmt.Fprintln(table)
+		}
+		fmt.Fprintf(table, "%s%s: %s\n", strings.ToUpper(side.Name[:1]), side.Name[1:], side.Location)
+		for _, check := range side.Checks {
+			fmt.Fprintf(table, "  %s\t%s\t%s\n", check.Status, check.Name, check.Message)
+			if check.Hint != "" {
+				fmt.Fprintf(table, "  \t\tHint: %s\n", check.Hint)
+			}
+		}
+	}
+
+	fmt.Fprintln(table)
+	if len(r.Comparisons) == 0 {
+		fmt.Fprintln(table, "Every comparison will run in full.")
+	} else {
+		fmt.Fprintln(table, "Comparisons skipped or degraded:")
+		for _, comparison := range r.Comparisons {
+			name := comparison.Name
+			if len(comparison.Types) > 0 {
+				name += " (" + strings.Join(comparison.Types, ", ") + ")"
+			}
+			fmt.Fprintf(table, "  %s\t%s: %s\n", comparison.Status, name, comparison.Reason)
+			if comparison.Hint != "" {
+				fmt.Fprintf(table, "  \tHint: %s\n", comparison.Hint)
+			}
+		}
+	}
+
+	fmt.Fprintf(table, "\n%d checks failed, %d with warnings.\n", r.Count(StatusFailed), r.Count(StatusWarning))
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("error writing doctor report: %w", err)
+	}
+	return nil
+}
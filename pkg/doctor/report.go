@@ -0,0 +1,157 @@
+package doctor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// feature describes a feature of the schema model that a server may lack, or whose metadata the
+// role may not be able to read.
+type feature struct {
+	name       string   // Display name of the feature
+	types      []string // Types of difference about the feature
+	installed  bool     // Whether the feature is unsupported because its metadata is unreadable, rather than absent from the server
+	unreadable string   // How to make the metadata readable, for features of extensions
+}
+
+// features describes the features of schema.Schema.UnsupportedFeatures.
+var features = map[string]feature{
+	schema.FeaturePartitioning: {name: "partitioning", types: []string{"PartitionKeyMismatch", "PartitionParentMismatch"}},
+	schema.FeatureIdentity:     {name: "identity columns", types: []string{"ColumnIdentityMismatch"}},
+	schema.FeatureCompression:  {name: "column compression", types: []string{"ColumnCompressionMismatch"}},
+	schema.FeatureIndexes:      {name: "indexes", types: []string{"MissingIndex", "ExtraIndex", "IndexUniqueMismatch", "IndexColumnsMismatch", "IndexIncludeMismatch", "IndexPredicateMismatch", "IndexMethodMismatch"}},
+	schema.FeatureTimescale: {
+		name: "TimescaleDB metadata", types: []string{"HypertableMismatch"}, installed: true,
+		unreadable: "Grant the role SELECT on the timescaledb_information views, or upgrade TimescaleDB to 2.0 or later.",
+	},
+	schema.FeatureCitus: {
+		name: "Citus metadata", types: []string{"DistributionMismatch"}, installed: true,
+		unreadable: "Grant the role SELECT on pg_dist_partition and pg_dist_shard.",
+	},
+	schema.FeatureMaterializedViews: {name: "materialized views", types: []string{"MissingMaterializedView", "ExtraMaterializedView", "MaterializedViewPopulatedMismatch"}},
+	schema.FeatureSequences:         {name: "sequences", types: []string{"MissingSequence", "ExtraSequence", "SequenceMismatch", "SequenceOwnerMismatch"}},
+	schema.FeatureTriggers: {name: "triggers", types: []string{
+		"MissingTrigger", "ExtraTrigger", "TriggerTimingMismatch", "TriggerEventsMismatch",
+		"TriggerLevelMismatch", "TriggerConditionMismatch", "TriggerFunctionMismatch",
+	}},
+	schema.FeatureFunctions: {name: "functions", types: []string{"MissingFunction", "ExtraFunction", "FunctionDefinitionMismatch", "FunctionOwnerMismatch"}},
+}
+
+// featureOrder is the order features are reported in.
+var featureOrder = []string{
+	schema.FeaturePartitioning, schema.FeatureIdentity, schema.FeatureCompression, schema.FeatureIndexes,
+	schema.FeatureTimescale, schema.FeatureCitus, schema.FeatureMaterializedViews, schema.FeatureSequences,
+	schema.FeatureTriggers, schema.FeatureFunctions,
+}
+
+// orderedFeatures returns the known features among some, in the order they are reported in.
+func orderedFeatures(some []string) []string {
+	var ordered []string
+	for _, name := range featureOrder {
+		if slices.Contains(some, name) {
+			ordered = append(ordered, name)
+		}
+	}
+	return ordered
+}
+
+// NewReport combines the checks of both sides, and works out which comparisons they skip or
+// degrade: every comparison when a side failed; those of the features a side cannot read; the
+// types and defaults of columns when the compared schema is on the search_path of one side only;
+// the columns and keys that information_schema hides from the role; and the tables whose
+// details could not be read.
+//
+// Parameters:
+//   - source: Checks of the source
+//   - target: Checks of the target
+//
+// Returns:
+//   - *Report: Report of both sides
+func NewReport(source, target Side) *Report {
+	r := &Report{Source: source, Target: target, Comparisons: []Comparison{}}
+	sides := []Side{source, target}
+
+	var failed []string
+	for _, side := range sides {
+		if check := side.failure(); check != nil {
+			failed = append(failed, fmt.Sprintf("the %s failed the %s check (%s)", side.Name, check.Name, check.Message))
+		}
+	}
+	if len(failed) > 0 {
+		r.Comparisons = append(r.Comparisons, Comparison{
+			Name: "everything", Status: ComparisonSkipped, Types: []string{}, Reason: strings.Join(failed, "; "),
+			Hint: "Resolve the failed checks; the comparison cannot run until then.",
+		})
+		return r
+	}
+
+	// Features that either side cannot read are compared on neither
+	for _, name := range featureOrder {
+		var reasons []string
+		for _, side := range sides {
+			if !slices.Contains(side.UnsupportedFeatures, name) {
+				continue
+			}
+			if features[name].installed {
+				reasons = append(reasons, fmt.Sprintf("the %s of the %s cannot be read", features[name].name, side.Name))
+			} else {
+				reasons = append(reasons, fmt.Sprintf("the %s (%s) has no %s", side.Name, side.Server(), features[name].name))
+			}
+		}
+		if len(reasons) > 0 {
+			r.Comparisons = append(r.Comparisons, Comparison{
+				Name: features[name].name, Status: ComparisonSkipped, Types: features[name].types,
+				Reason: strings.Join(reasons, "; "), Hint: features[name].unreadable,
+			})
+		}
+	}
+
+	// The catalog functions qualify the objects of schemas not on the search_path
+	if len(source.SearchPath) > 0 && len(target.SearchPath) > 0 {
+		onSource, onTarget := slices.Contains(source.SearchPath, source.Schema), slices.Contains(target.SearchPath, target.Schema)
+		if onSource != onTarget {
+			on, off := source, target
+			if onTarget {
+				on, off = target, source
+			}
+			r.Comparisons = append(r.Comparisons, Comparison{
+				Name: "column types, defaults, and trigger functions", Status: ComparisonDegraded,
+				Types: []string{"ColumnTypeMismatch", "ColumnDefaultMismatch", "TriggerFunctionMismatch"},
+				Reason: fmt.Sprintf("schema %s is on the search_path of the %s but not of the %s, so user-defined types, sequences, and functions are shown qualified on the %s only, and differ even when they match",
+					on.Schema, on.Name, off.Name, off.Name),
+				Hint: fmt.Sprintf("Give both sides the same search_path, for example with options=-csearch_path=%s in both connection strings.", on.Schema),
+			})
+		}
+	}
+
+	// information_schema only shows the role the columns and keys of tables it has privileges on
+	var hidden []string
+	for _, side := range sides {
+		if side.UnprivilegedTables > 0 {
+			hidden = append(hidden, fmt.Sprintf("information_schema hides the columns and keys of %d tables of the %s (%s) from the role", side.UnprivilegedTables, side.Name, side.Server()))
+		}
+	}
+	if len(hidden) > 0 {
+		r.Comparisons = append(r.Comparisons, Comparison{
+			Name: "columns and keys", Status: ComparisonDegraded,
+			Types:  []string{"MissingColumn", "ExtraColumn", "PrimaryKeyMismatch", "MissingForeignKey", "ExtraForeignKey"},
+			Reason: strings.Join(hidden, "; ") + ", so they are reported as missing",
+			Hint:   "Grant the role SELECT on every table of the compared schema.",
+		})
+	}
+
+	// Tables whose details could not be read are reported as FetchFailed instead
+	for _, side := range sides {
+		if len(side.FailedTables) > 0 {
+			r.Comparisons = append(r.Comparisons, Comparison{
+				Name: "tables " + strings.Join(side.FailedTables, ", "), Status: ComparisonSkipped, Types: []string{},
+				Reason: fmt.Sprintf("their details could not be read from the %s, so they are reported as FetchFailed", side.Name),
+				Hint:   "Grant the role the privileges to read these tables.",
+			})
+		}
+	}
+	return r
+}
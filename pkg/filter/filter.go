@@ -0,0 +1,167 @@
+// Package filter provides functionality to exclude database objects from a schema comparison.
+// Tables can be selected with include and exclude glob patterns, and objects can be excluded by
+// tagging them with a marker in their PostgreSQL COMMENT, which gives application teams a
+// self-service way to keep scratch objects out of the report.
+package filter
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// DefaultIgnoreMarker is the comment marker used to exclude objects when no other marker is configured.
+const DefaultIgnoreMarker = "schema-check:ignore"
+
+// ExcludeTagged removes every table and column whose COMMENT contains the given marker.
+// An object tagged in any of the schemas is removed from all of them, so that tagging it
+// on one side only does not turn it into a missing or extra object.
+//
+// Parameters:
+//   - marker: Text that must appear in the comment for the object to be excluded; empty disables the filter
+//   - schemas: Schemas to remove the tagged objects from
+func ExcludeTagged(marker string, schemas ...*schema.Schema) {
+	if marker == "" {
+		return
+	}
+
+	// Collect the tagged tables and columns from every schema first
+	ignoredTables := make(map[string]bool)
+	ignoredColumns := make(map[string]map[string]bool)
+	for _, s := range schemas {
+		for tableName, table := range s.Tables {
+			if strings.Contains(table.Comment, marker) {
+				ignoredTables[tableName] = true
+				continue
+			}
+			for _, col := range table.Columns {
+				if !strings.Contains(col.Comment, marker) {
+					continue
+				}
+				if ignoredColumns[tableName] == nil {
+					ignoredColumns[tableName] = make(map[string]bool)
+				}
+				ignoredColumns[tableName][col.Name] = true
+			}
+		}
+	}
+
+	// Remove the tagged objects from every schema
+	for _, s := range schemas {
+		for tableName, table := range s.Tables {
+			if ignoredTables[tableName] {
+				delete(s.Tables, tableName)
+				continue
+			}
+
+			columns, ok := ignoredColumns[tableName]
+			if !ok {
+				continue
+			}
+			var kept []schema.ColumnInfo
+			for _, col := range table.Columns {
+				if !columns[col.Name] {
+					kept = append(kept, col)
+				}
+			}
+			table.Columns = kept
+			s.Tables[tableName] = table
+		}
+	}
+}
+
+// CollapsePartitions removes every table that is a partition of another table, so that only
+// the partitioned parents are compared. Partition sets often differ legitimately (e.g., by
+// date range), which would otherwise produce a flood of per-partition differences.
+//
+// Parameters:
+//   - schemas: Schemas to remove the partitions from
+func CollapsePartitions(schemas ...*schema.Schema) {
+	for _, s := range schemas {
+		for tableName, table := range s.Tables {
+			if table.PartitionOf != "" {
+				delete(s.Tables, tableName)
+			}
+		}
+	}
+}
+
+// Tables keeps only the tables whose names match at least one include pattern (or every table
+// when no include patterns are given) and do not match any exclude pattern. Patterns use the
+// glob syntax of path.Match (e.g., "orders_*").
+//
+// Parameters:
+//   - include: Glob patterns of tables to keep; empty keeps all tables
+//   - exclude: Glob patterns of tables to remove
+//   - schemas: Schemas to remove the filtered tables from
+//
+// Returns:
+//   - error: An error if any of the patterns is malformed
+func Tables(include, exclude []string, schemas ...*schema.Schema) error {
+	for _, s := range schemas {
+		for tableName := range s.Tables {
+			keep, err := Match(tableName, include, exclude)
+			if err != nil {
+				return err
+			}
+			if !keep {
+				delete(s.Tables, tableName)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidatePattern checks that a table pattern uses valid glob syntax.
+//
+// Parameters:
+//   - pattern: Glob pattern to check
+//
+// Returns:
+//   - error: An error if the pattern is malformed
+func ValidatePattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid table pattern '%s': %w", pattern, err)
+	}
+	return nil
+}
+
+// Match reports whether a table name passes the include and exclude patterns of Tables.
+//
+// Parameters:
+//   - tableName: Name of the table to check
+//   - include: Glob patterns of tables to keep; empty keeps all tables
+//   - exclude: Glob patterns of tables to remove
+//
+// Returns:
+//   - bool: True if the table should be kept
+//   - error: An error if any of the patterns is malformed
+func Match(tableName string, include, exclude []string) (bool, error) {
+	included := len(include) == 0
+	for _, pattern := range include {
+		matched, err := path.Match(pattern, tableName)
+		if err != nil {
+			return false, fmt.Errorf("invalid include pattern '%s': %w", pattern, err)
+		}
+		if matched {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false, nil
+	}
+
+	for _, pattern := range exclude {
+		matched, err := path.Match(pattern, tableName)
+		if err != nil {
+			return false, fmt.Errorf("invalid exclude pattern '%s': %w", pattern, err)
+		}
+		if matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
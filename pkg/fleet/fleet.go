@@ -0,0 +1,178 @@
+// Package fleet provides functionality to audit a fleet of databases, such as the shards or
+// tenant databases of an application, against one golden schema in a single run, and to report
+// which members deviate from it, by how much, and in what.
+package fleet
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+)
+
+// Result is the outcome of the comparison of one member of the fleet with the golden schema.
+type Result struct {
+	Member      string             `json:"member"`          // Name of the member
+	Differences compare.DiffResult `json:"differences"`     // Differences from the golden schema; empty if the member could not be checked
+	Summary     map[string]int     `json:"summary"`         // Number of differences by severity
+	Error       string             `json:"error,omitempty"` // Error the member could not be checked with, if any
+}
+
+// Failed reports whether the member could not be checked.
+func (r Result) Failed() bool {
+	return r.Error != ""
+}
+
+// Deviation is a difference from the golden schema, with the members it was found in.
+type Deviation struct {
+	Key         string   `json:"key"`         // Key of the difference (see compare.Difference.Key)
+	Type        string   `json:"type"`        // Type of the difference
+	Table       string   `json:"table"`       // Table of the difference
+	SubObject   string   `json:"sub_object"`  // Column, index, or constraint of the difference, if any
+	Severity    string   `json:"severity"`    // Most severe severity the difference was found with
+	Description string   `json:"description"` // Description of the difference, as found in the first member listed
+	Members     []string `json:"members"`     // Members the difference was found in, in the order of the report
+}
+
+// Report is the result of an audit of a fleet: the members, the most deviating first, and the
+// deviations, the most widespread first.
+type Report struct {
+	Golden     string      `json:"golden"`     // Golden schema the members were compared with, with its password masked
+	Members    []Result    `json:"members"`    // Results of the members, by number of differences (most first), then name; members that could not be checked last
+	Deviations []Deviation `json:"deviations"` // Differences found in any member, by number of members (most first), then key
+}
+
+// Audit compares every member of a fleet with the golden schema, several at a time, and builds
+// the report of the fleet.
+//
+// Parameters:
+//   - ctx: Context for the comparisons; members not compared yet when it is cancelled fail with its error
+//   - golden: Name of the golden schema, with its password masked
+//   - members: Names of the members
+//   - concurrency: Number of members compared at once; zero or one compares them one by one
+//   - check: Function comparing a member with the golden schema, safe for concurrent use
+//
+// Returns:
+//   - *Report: Report of the fleet
+func Audit(ctx context.Context, golden string, members []string, concurrency int, check func(ctx context.Context, member string) (compare.DiffResult, error)) *Report {
+	results := make([]Result, len(members))
+	slots := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	for i, member := range members {
+		wg.Add(1)
+		go func(i int, member string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			result := Result{Member: member}
+			err := ctx.Err()
+			if err == nil {
+				result.Differences, err = check(ctx, member)
+			}
+			if err != nil {
+				result.Differences, result.Error = nil, err.Error()
+			} else {
+				result.Summary = result.Differences.CountBySeverity()
+			}
+			results[i] = result
+		}(i, member)
+	}
+	wg.Wait()
+	return NewReport(golden, results)
+}
+
+// NewReport builds the report of a fleet from the results of its members, sorting them and
+// gathering their deviations.
+//
+// Parameters:
+//   - golden: Name of the golden schema, with its password masked
+//   - results: Results of the members, in any order
+//
+// Returns:
+//   - *Report: Report of the fleet
+func NewReport(golden string, results []Result) *Report {
+	members := append([]Result(nil), results...)
+	sort.SliceStable(members, func(i, j int) bool {
+		a, b := members[i], members[j]
+		if a.Failed() != b.Failed() {
+			return b.Failed()
+		}
+		if len(a.Differences) != len(b.Differences) {
+			return len(a.Differences) > len(b.Differences)
+		}
+		return a.Member < b.Member
+	})
+	return &Report{Golden: golden, Members: members, Deviations: deviations(members)}
+}
+
+// deviations gathers the differences found in the members, each with the members it was found in.
+//
+// Parameters:
+//   - members: Results of the members, in the order of the report
+//
+// Returns:
+//   - []Deviation: Deviations by number of members (most first), then key
+func deviations(members []Result) []Deviation {
+	byKey := make(map[string]*Deviation)
+	for _, result := range members {
+		for _, diff := range result.Differences {
+			key := diff.Key()
+			deviation, seen := byKey[key]
+			if !seen {
+				deviation = &Deviation{Key: key, Type: diff.Type, Table: diff.Table, SubObject: diff.SubObject,
+					Severity: diff.Severity, Description: diff.Description}
+				byKey[key] = deviation
+			}
+			if severityRanks[diff.Severity] > severityRanks[deviation.Severity] {
+				deviation.Severity = diff.Severity
+			}
+			// A member can report several differences with the same key, such as custom ones
+			if n := len(deviation.Members); n == 0 || deviation.Members[n-1] != result.Member {
+				deviation.Members = append(deviation.Members, result.Member)
+			}
+		}
+	}
+
+	list := make([]Deviation, 0, len(byKey))
+	for _, deviation := range byKey {
+		list = append(list, *deviation)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if len(list[i].Members) != len(list[j].Members) {
+			return len(list[i].Members) > len(list[j].Members)
+		}
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
+
+// severityRanks orders the severities of differences, most severe last.
+var severityRanks = map[string]int{
+	compare.SeverityInfo:    1,
+	compare.SeverityWarning: 2,
+	compare.SeverityError:   3,
+}
+
+// Deviating returns the number of members with differences from the golden schema.
+func (r *Report) Deviating() int {
+	deviating := 0
+	for _, result := range r.Members {
+		if len(result.Differences) > 0 {
+			deviating++
+		}
+	}
+	return deviating
+}
+
+// Failed returns the number of members that could not be checked.
+func (r *Report) Failed() int {
+	failed := 0
+	for _, result := range r.Members {
+		if result.Failed() {
+			failed++
+		}
+	}
+	return failed
+}
@@ -0,0 +1,219 @@
+package fleet
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+)
+
+// Formats of the report of a fleet.
+const (
+	FormatText     = "text"     // Table of the members and list of the deviations
+	FormatMarkdown = "markdown" // Markdown tables of the members and of the deviations
+	FormatJSON     = "json"     // The Report as JSON
+	FormatCSV      = "csv"      // Matrix of the members by the deviations, one row per member
+)
+
+// Formats lists the formats of the report of a fleet.
+var Formats = []string{FormatText, FormatMarkdown, FormatJSON, FormatCSV}
+
+// maxListedMembers is the number of members listed with a deviation in the text and Markdown
+// reports; the others are counted.
+const maxListedMembers = 10
+
+// CheckFormat checks that a format of the report of a fleet is known, so that a misspelled format
+// can be reported before the fleet is audited.
+//
+// Parameters:
+//   - format: Name of the format
+//
+// Returns:
+//   - error: An error listing the formats if the format is unknown
+func CheckFormat(format string) error {
+	for _, known := range Formats {
+		if known == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown fleet report format '%s': expected one of %s", format, strings.Join(Formats, ", "))
+}
+
+// Write renders the report of a fleet.
+//
+// Parameters:
+//   - w: Writer the report is written to
+//   - r: Report to render
+//   - format: Format of the report (see Formats)
+//
+// Returns:
+//   - error: An error if the format is unknown, or any error that occurred while writing
+func Write(w io.Writer, r *Report, format string) error {
+	switch format {
+	case FormatText:
+		return writeText(w, r)
+	case FormatMarkdown:
+		return writeMarkdown(w, r)
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(r); err != nil {
+			return fmt.Errorf("error writing fleet report: %w", err)
+		}
+		return nil
+	case FormatCSV:
+		return writeCSV(w, r)
+	}
+	return CheckFormat(format)
+}
+
+// summaryLine returns the sentence summing up the audit.
+func summaryLine(r *Report) string {
+	line := fmt.Sprintf("%d of %d members deviate from %s", r.Deviating(), len(r.Members), r.Golden)
+	if failed := r.Failed(); failed > 0 {
+		line += fmt.Sprintf("; %d could not be checked", failed)
+	}
+	return line + "."
+}
+
+// membersList returns the members of a deviation, listing at most maxListedMembers of them.
+func membersList(members []string) string {
+	if len(members) <= maxListedMembers {
+		return strings.Join(members, ", ")
+	}
+	return fmt.Sprintf("%s, and %d more", strings.Join(members[:maxListedMembers], ", "), len(members)-maxListedMembers)
+}
+
+// memberCount returns a number of members, as "1 member" or "N members".
+func memberCount(n int) string {
+	if n == 1 {
+		return "1 member"
+	}
+	return fmt.Sprintf("%d members", n)
+}
+
+// deviationSubject returns what a deviation is about: its table, and its column, index, or
+// constraint if any.
+func deviationSubject(d Deviation) string {
+	if d.SubObject == "" {
+		return d.Table
+	}
+	return d.Table + "." + d.SubObject
+}
+
+// writeText renders the report as aligned text.
+func writeText(w io.Writer, r *Report) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "%s\n\n", summaryLine(r))
+	fmt.Fprintln(table, "MEMBER\tDIFFERENCES\tERRORS\tWARNINGS\tINFO\tSTATUS")
+	for _, result := range r.Members {
+		if result.Failed() {
+			fmt.Fprintf(table, "%s\t-\t-\t-\t-\tnot checked: %s\n", result.Member, result.Error)
+			continue
+		}
+		status := "matches"
+		if len(result.Differences) > 0 {
+			status = "deviates"
+		}
+		fmt.Fprintf(table, "%s\t%d\t%d\t%d\t%d\t%s\n", result.Member, len(result.Differences), result.Summary[compare.SeverityError],
+			result.Summary[compare.SeverityWarning], result.Summary[compare.SeverityInfo], status)
+	}
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("error writing fleet report: %w", err)
+	}
+
+	if len(r.Deviations) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "\nDeviations, by number of members:\n\n"); err != nil {
+		return fmt.Errorf("error writing fleet report: %w", err)
+	}
+	for _, d := range r.Deviations {
+		if _, err := fmt.Fprintf(w, "[%s] [%s] %s: %s: %s\n    %s\n", d.Severity, d.Type, deviationSubject(d),
+			memberCount(len(d.Members)), membersList(d.Members), d.Description); err != nil {
+			return fmt.Errorf("error writing fleet report: %w", err)
+		}
+	}
+	return nil
+}
+
+// markdownEscaper escapes the characters that would end a table cell or be read as markup.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, `|`, `\|`, "`", "\\`", `*`, `\*`, `_`, `\_`, `<`, `&lt;`, "\r\n", " ", "\n", " ",
+)
+
+// writeMarkdown renders the report as a Markdown document.
+func writeMarkdown(w io.Writer, r *Report) error {
+	var doc strings.Builder
+	fmt.Fprintf(&doc, "# Fleet audit report\n\n%s\n\n", markdownEscaper.Replace(summaryLine(r)))
+	doc.WriteString("| Member | Differences | Errors | Warnings | Info | Status |\n|--------|-------------|--------|----------|------|--------|\n")
+	for _, result := range r.Members {
+		member := markdownEscaper.Replace(result.Member)
+		if result.Failed() {
+			fmt.Fprintf(&doc, "| %s | - | - | - | - | not checked: %s |\n", member, markdownEscaper.Replace(result.Error))
+			continue
+		}
+		status := "matches"
+		if len(result.Differences) > 0 {
+			status = "**deviates**"
+		}
+		fmt.Fprintf(&doc, "| %s | %d | %d | %d | %d | %s |\n", member, len(result.Differences), result.Summary[compare.SeverityError],
+			result.Summary[compare.SeverityWarning], result.Summary[compare.SeverityInfo], status)
+	}
+
+	if len(r.Deviations) > 0 {
+		doc.WriteString("\n## Deviations\n\n| Severity | Type | Object | Members | Description |\n|----------|------|--------|---------|-------------|\n")
+		for _, d := range r.Deviations {
+			fmt.Fprintf(&doc, "| %s | %s | %s | %d: %s | %s |\n", d.Severity, d.Type, markdownEscaper.Replace(deviationSubject(d)),
+				len(d.Members), markdownEscaper.Replace(membersList(d.Members)), markdownEscaper.Replace(d.Description))
+		}
+	}
+
+	if _, err := io.WriteString(w, doc.String()); err != nil {
+		return fmt.Errorf("error writing fleet report: %w", err)
+	}
+	return nil
+}
+
+// writeCSV renders the report as a matrix, with one row per member and one column per deviation
+// after the counts, holding the severity of the deviation in the members where it was found.
+func writeCSV(w io.Writer, r *Report) error {
+	writer := csv.NewWriter(w)
+	header := []string{"member", "status", "differences", "errors", "warnings", "info"}
+	for _, d := range r.Deviations {
+		header = append(header, d.Key)
+	}
+	writer.Write(header)
+
+	for _, result := range r.Members {
+		if result.Failed() {
+			row := []string{result.Member, "not checked", "", "", "", ""}
+			writer.Write(append(row, make([]string, len(r.Deviations))...))
+			continue
+		}
+		status := "matches"
+		if len(result.Differences) > 0 {
+			status = "deviates"
+		}
+		row := []string{result.Member, status, fmt.Sprint(len(result.Differences)), fmt.Sprint(result.Summary[compare.SeverityError]),
+			fmt.Sprint(result.Summary[compare.SeverityWarning]), fmt.Sprint(result.Summary[compare.SeverityInfo])}
+		found := make(map[string]string, len(result.Differences))
+		for _, diff := range result.Differences {
+			found[diff.Key()] = diff.Severity
+		}
+		for _, d := range r.Deviations {
+			row = append(row, found[d.Key])
+		}
+		writer.Write(row)
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing fleet report: %w", err)
+	}
+	return nil
+}
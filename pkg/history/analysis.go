@@ -0,0 +1,179 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Occurrence is a difference found by the runs of a comparison, with when it was first and last
+// found. Differences are told apart by their key (see compare.Difference.Key), so a column whose
+// type changes again is still the same difference.
+type Occurrence struct {
+	Key         string    `json:"key"`         // Key of the difference
+	Type        string    `json:"type"`        // Type of the difference
+	Table       string    `json:"table"`       // Table of the difference
+	SubObject   string    `json:"sub_object"`  // Column, index, or constraint of the difference, if any
+	Severity    string    `json:"severity"`    // Severity of the difference, when it was last found
+	Description string    `json:"description"` // Description of the difference, when it was last found
+	FirstSeen   time.Time `json:"first_seen"`  // Start of the first run that found the difference
+	LastSeen    time.Time `json:"last_seen"`   // Start of the last run that found the difference
+	Since       time.Time `json:"since"`       // Start of the first run of the latest streak of runs finding the difference
+	Runs        int       `json:"runs"`        // Number of runs that found the difference
+	Present     bool      `json:"present"`     // Whether the latest successful run found the difference
+}
+
+// Match selects the differences of a comparison returned by Occurrences.
+type Match struct {
+	Table string // Table of the differences; empty matches every table
+	Type  string // Type of the differences; empty matches every type
+}
+
+// Occurrences returns the differences found by the runs of a comparison, with when they were
+// first and last found, the ones found by the latest successful run first, then by when they
+// were last found, latest first.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - pair: Name of the comparison
+//   - match: Differences to return
+//
+// Returns:
+//   - []Occurrence: Differences found by the runs
+//   - error: Any error that occurred while reading the history
+func (h *DB) Occurrences(ctx context.Context, pair string, match Match) ([]Occurrence, error) {
+	runs, err := h.Runs(ctx, pair, time.Time{}, 0)
+	if err != nil {
+		return nil, err
+	}
+	// Streaks are broken by successful runs only: a failed run says nothing of the differences
+	previous := make(map[int64]int64)
+	var lastRun int64
+	for _, run := range runs {
+		if run.Failed() {
+			continue
+		}
+		previous[run.ID] = lastRun
+		lastRun = run.ID
+	}
+
+	rows, err := h.db.QueryContext(ctx, `SELECT d.run_id, r.started, d.key, d.type, d.table_name, d.sub_object, d.severity, d.description
+	FROM schema_check_differences d
+	JOIN schema_check_runs r ON r.id = d.run_id
+	WHERE r.pair = $1 AND ($2 = '' OR d.table_name = $2) AND ($3 = '' OR d.type = $3)
+	ORDER BY r.started, r.id`, pair, match.Table, match.Type)
+	if err != nil {
+		return nil, fmt.Errorf("error reading differences: %w", err)
+	}
+	defer rows.Close()
+
+	byKey := make(map[string]*Occurrence)
+	lastFound := make(map[string]int64)
+	for rows.Next() {
+		var runID int64
+		var started time.Time
+		var o Occurrence
+		if err := rows.Scan(&runID, &started, &o.Key, &o.Type, &o.Table, &o.SubObject, &o.Severity, &o.Description); err != nil {
+			return nil, fmt.Errorf("error reading differences: %w", err)
+		}
+		started = started.UTC()
+
+		found, exists := byKey[o.Key]
+		if !exists {
+			o.FirstSeen = started
+			found = &o
+			byKey[o.Key] = found
+		}
+		if !exists || lastFound[o.Key] != previous[runID] {
+			found.Since = started
+		}
+		found.Severity, found.Description = o.Severity, o.Description
+		found.LastSeen = started
+		found.Runs++
+		found.Present = runID == lastRun
+		lastFound[o.Key] = runID
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading differences: %w", err)
+	}
+
+	occurrences := make([]Occurrence, 0, len(byKey))
+	for _, o := range byKey {
+		occurrences = append(occurrences, *o)
+	}
+	sort.Slice(occurrences, func(i, j int) bool {
+		a, b := occurrences[i], occurrences[j]
+		if a.Present != b.Present {
+			return a.Present
+		}
+		if !a.LastSeen.Equal(b.LastSeen) {
+			return a.LastSeen.After(b.LastSeen)
+		}
+		if !a.FirstSeen.Equal(b.FirstSeen) {
+			return a.FirstSeen.Before(b.FirstSeen)
+		}
+		return a.Key < b.Key
+	})
+	return occurrences, nil
+}
+
+// Point is the drift of a comparison over a period of time.
+type Point struct {
+	Start       time.Time      `json:"start"`       // Start of the period
+	Runs        int            `json:"runs"`        // Number of runs started in the period
+	Failed      int            `json:"failed"`      // Number of those runs that failed
+	Summary     map[string]int `json:"summary"`     // Number of differences of each severity found by the last successful run of the period
+	Differences int            `json:"differences"` // Number of differences found by the last successful run of the period
+	Min         int            `json:"min"`         // Fewest differences found by a successful run of the period
+	Max         int            `json:"max"`         // Most differences found by a successful run of the period
+}
+
+// Trend groups runs by period, giving the drift of their comparison over time. Periods start at
+// multiples of the period since the zero time, in UTC: days start at midnight and weeks on
+// Mondays. Periods without a successful run are left out, as their number of differences is
+// unknown.
+//
+// Parameters:
+//   - runs: Runs of a comparison, oldest first
+//   - period: Length of the periods; zero or negative makes each run its own period
+//
+// Returns:
+//   - []Point: Drift of each period with a successful run, oldest first
+func Trend(runs []Run, period time.Duration) []Point {
+	var points []Point
+	successful := make(map[int]bool) // Indexes of the points with a successful run
+	for _, run := range runs {
+		start := run.Started
+		if period > 0 {
+			start = start.Truncate(period)
+		}
+		if len(points) == 0 || period <= 0 || !start.Equal(points[len(points)-1].Start) {
+			points = append(points, Point{Start: start})
+		}
+		i := len(points) - 1
+		point := &points[i]
+
+		point.Runs++
+		if run.Failed() {
+			point.Failed++
+			continue
+		}
+		if !successful[i] {
+			point.Min, point.Max = run.Differences, run.Differences
+			successful[i] = true
+		}
+		point.Summary = run.Summary
+		point.Differences = run.Differences
+		point.Min = min(point.Min, run.Differences)
+		point.Max = max(point.Max, run.Differences)
+	}
+
+	known := points[:0]
+	for i, point := range points {
+		if successful[i] {
+			known = append(known, point)
+		}
+	}
+	return known
+}
@@ -0,0 +1,249 @@
+// Package history provides functionality to keep the differences of every run of a comparison in
+// a database, a PostgreSQL database or a SQLite file, so that the history of a schema's drift can
+// be queried after the fact: when a difference first appeared, and how the number of differences
+// trends over time.
+//
+// Open creates the tables of the history if needed; Record adds the outcome of a run to them.
+package history
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// Drivers of the databases a history can be kept in
+const (
+	driverPostgres = "pgx"    // PostgreSQL, through pgx
+	driverSQLite   = "sqlite" // SQLite, in pure Go
+)
+
+// Run is a run of a comparison recorded in the history, without its differences.
+type Run struct {
+	ID          int64          `json:"id"`               // Identifier of the run in the history
+	Pair        string         `json:"pair"`             // Name of the comparison, such as its environment
+	Started     time.Time      `json:"started"`          // When the run started
+	Duration    time.Duration  `json:"duration"`         // Time the run took, in nanoseconds
+	Host        string         `json:"host,omitempty"`   // Host the run ran on
+	Source      string         `json:"source,omitempty"` // Source of the comparison, with its password masked
+	Target      string         `json:"target,omitempty"` // Target of the comparison, with its password masked
+	Summary     map[string]int `json:"summary"`          // Number of differences of each severity
+	Differences int            `json:"differences"`      // Number of differences
+	Error       string         `json:"error,omitempty"`  // Error the run failed with, if it failed
+}
+
+// Failed reports whether the run failed, in which case its differences are unknown.
+//
+// Returns:
+//   - bool: True if the run ended with an error
+func (r Run) Failed() bool {
+	return r.Error != ""
+}
+
+// DB is a history of comparisons kept in a database. It is safe for concurrent use.
+type DB struct {
+	db     *sql.DB // Database the history is kept in
+	driver string  // Driver of the database: driverPostgres or driverSQLite
+}
+
+// Open opens the history kept in a database, creating its tables if they do not exist.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - target: PostgreSQL connection URL (postgres:// or postgresql://), or path of a SQLite file,
+//     optionally prefixed with sqlite://
+//
+// Returns:
+//   - *DB: Open history
+//   - error: Any error that occurred while connecting or creating the tables
+func Open(ctx context.Context, target string) (*DB, error) {
+	driver, dsn := driverSQLite, strings.TrimPrefix(target, "sqlite://")
+	if strings.HasPrefix(target, "postgres://") || strings.HasPrefix(target, "postgresql://") {
+		driver, dsn = driverPostgres, target
+	}
+	if dsn == "" {
+		return nil, fmt.Errorf("error opening history: no database given")
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening history: %w", err)
+	}
+	if driver == driverSQLite {
+		// SQLite allows a single writer, so concurrent runs take turns on one connection
+		db.SetMaxOpenConns(1)
+	}
+	h := &DB{db: db, driver: driver}
+	if err := h.createTables(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+// Close closes the connections to the database.
+//
+// Returns:
+//   - error: Any error that occurred while closing them
+func (h *DB) Close() error {
+	return h.db.Close()
+}
+
+// createTables creates the tables of the history and their indexes, if they do not exist.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//
+// Returns:
+//   - error: Any error that occurred while creating them
+func (h *DB) createTables(ctx context.Context) error {
+	id, timestamp := "bigserial PRIMARY KEY", "timestamptz"
+	if h.driver == driverSQLite {
+		id, timestamp = "INTEGER PRIMARY KEY AUTOINCREMENT", "TIMESTAMP"
+	}
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS schema_check_runs (
+	id %s,
+	pair text NOT NULL,
+	started %s NOT NULL,
+	duration_ms bigint NOT NULL,
+	host text NOT NULL,
+	source text NOT NULL,
+	target text NOT NULL,
+	errors integer NOT NULL,
+	warnings integer NOT NULL,
+	infos integer NOT NULL,
+	differences integer NOT NULL,
+	error text NOT NULL
+)`, id, timestamp),
+		`CREATE INDEX IF NOT EXISTS schema_check_runs_pair_started ON schema_check_runs (pair, started)`,
+		`CREATE TABLE IF NOT EXISTS schema_check_differences (
+	run_id bigint NOT NULL REFERENCES schema_check_runs (id) ON DELETE CASCADE,
+	key text NOT NULL,
+	type text NOT NULL,
+	table_name text NOT NULL,
+	sub_object text NOT NULL,
+	severity text NOT NULL,
+	description text NOT NULL
+)`,
+		`CREATE INDEX IF NOT EXISTS schema_check_differences_run_id ON schema_check_differences (run_id)`,
+	}
+	for _, statement := range statements {
+		if _, err := h.db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("error creating history tables: %w", err)
+		}
+	}
+	return nil
+}
+
+// Record adds a run and its differences to the history, at once.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - run: Run to record; its ID, summary, and number of differences are ignored, as they are
+//     taken from differences and the database
+//   - differences: Differences the run found
+//
+// Returns:
+//   - int64: Identifier of the run in the history
+//   - error: Any error that occurred while writing the run
+func (h *DB) Record(ctx context.Context, run Run, differences compare.DiffResult) (int64, error) {
+	summary := differences.CountBySeverity()
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error recording run: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `INSERT INTO schema_check_runs
+	(pair, started, duration_ms, host, source, target, errors, warnings, infos, differences, error)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	RETURNING id`,
+		run.Pair, run.Started.UTC(), run.Duration.Milliseconds(), run.Host, run.Source, run.Target,
+		summary[compare.SeverityError], summary[compare.SeverityWarning], summary[compare.SeverityInfo],
+		len(differences), run.Error).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("error recording run: %w", err)
+	}
+
+	if len(differences) > 0 {
+		insert, err := tx.PrepareContext(ctx, `INSERT INTO schema_check_differences
+	(run_id, key, type, table_name, sub_object, severity, description)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)`)
+		if err != nil {
+			return 0, fmt.Errorf("error recording differences: %w", err)
+		}
+		defer insert.Close()
+		for _, d := range differences {
+			if _, err := insert.ExecContext(ctx, id, d.Key(), d.Type, d.Table, d.SubObject, d.Severity, d.Description); err != nil {
+				return 0, fmt.Errorf("error recording differences: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error recording run: %w", err)
+	}
+	return id, nil
+}
+
+// Runs returns the runs of a comparison recorded in the history, oldest first.
+//
+// Parameters:
+//   - ctx: Context for the database operations
+//   - pair: Name of the comparison; empty returns the runs of every comparison
+//   - since: Earliest start of the runs returned; the zero time returns them all
+//   - limit: Maximum number of runs returned, the latest ones; zero or negative returns all of them
+//
+// Returns:
+//   - []Run: Runs of the comparison
+//   - error: Any error that occurred while reading the history
+func (h *DB) Runs(ctx context.Context, pair string, since time.Time, limit int) ([]Run, error) {
+	query := `SELECT id, pair, started, duration_ms, host, source, target, errors, warnings, infos, differences, error
+	FROM schema_check_runs
+	WHERE ($1 = '' OR pair = $1) AND started >= $2
+	ORDER BY started DESC, id DESC`
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	rows, err := h.db.QueryContext(ctx, query, pair, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("error reading runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []Run
+	for rows.Next() {
+		var run Run
+		var durationMS int64
+		var errors, warnings, infos int
+		if err := rows.Scan(&run.ID, &run.Pair, &run.Started, &durationMS, &run.Host, &run.Source, &run.Target,
+			&errors, &warnings, &infos, &run.Differences, &run.Error); err != nil {
+			return nil, fmt.Errorf("error reading runs: %w", err)
+		}
+		run.Started = run.Started.UTC()
+		run.Duration = time.Duration(durationMS) * time.Millisecond
+		run.Summary = map[string]int{
+			compare.SeverityError:   errors,
+			compare.SeverityWarning: warnings,
+			compare.SeverityInfo:    infos,
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading runs: %w", err)
+	}
+
+	// The latest runs are read first, so that the limit keeps them, and returned oldest first
+	for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+		runs[i], runs[j] = runs[j], runs[i]
+	}
+	return runs, nil
+}
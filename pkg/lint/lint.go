@@ -0,0 +1,183 @@
+// Package lint checks a single schema for design issues that a comparison cannot reveal, such as
+// tables without a primary key or foreign keys without a supporting index. Issues are reported
+// as compare.Difference values, so they share severities, filters, and output formats with the
+// differences found by comparisons.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// Rule checks a schema for one kind of issue.
+type Rule interface {
+	// Name returns the unique name of the rule, which is also the type of the issues it reports
+	// (e.g., "NoPrimaryKey").
+	Name() string
+
+	// Description returns a one-line summary of what the rule checks.
+	Description() string
+
+	// Check returns the issues found in the schema.
+	Check(s *schema.Schema, opts Options) []compare.Difference
+}
+
+// Options controls which rules run and how their issues are reported. The zero value runs every
+// rule with its default settings.
+type Options struct {
+	Rules                []string          // Names of the rules to run; empty runs every registered rule
+	SeverityMap          map[string]string // Severity of the issues of each rule, keyed by rule name; rules not listed report SeverityWarning
+	RequireVarcharLength bool              // Whether character varying columns must declare a length (UnboundedVarchar)
+}
+
+// registry holds the registered rules, in registration order.
+var (
+	registryMu sync.RWMutex
+	rules      []Rule
+)
+
+// Register adds a rule to the set run by Run. Register panics if a rule with the same name is
+// already registered.
+//
+// Parameters:
+//   - r: Rule to register
+func Register(r Rule) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, existing := range rules {
+		if existing.Name() == r.Name() {
+			panic(fmt.Sprintf("lint: rule %s is already registered", r.Name()))
+		}
+	}
+	rules = append(rules, r)
+}
+
+// Rules returns the registered rules, in the order they run.
+//
+// Returns:
+//   - []Rule: Registered rules
+func Rules() []Rule {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return append([]Rule(nil), rules...)
+}
+
+// IsKnownRule reports whether a rule with the given name is registered.
+//
+// Parameters:
+//   - name: Rule name to check
+//
+// Returns:
+//   - bool: True if the rule is registered
+func IsKnownRule(name string) bool {
+	for _, r := range Rules() {
+		if r.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Run checks a schema with the selected rules. Issues get SeverityWarning unless the severity
+// map assigns them another severity, and issues with SeverityIgnore are dropped. They are
+// grouped by table, in table name order, keeping the order of the rules within each table.
+//
+// Parameters:
+//   - s: Schema to check
+//   - opts: Options selecting the rules and the severities of their issues
+//
+// Returns:
+//   - compare.DiffResult: Issues found
+//   - error: An error if an unknown rule is selected or the severity map uses an unknown severity
+func Run(s *schema.Schema, opts Options) (compare.DiffResult, error) {
+	for name, severity := range opts.SeverityMap {
+		if !compare.IsValidSeverity(severity) {
+			return nil, fmt.Errorf("unknown severity '%s' for lint rule %s", severity, name)
+		}
+	}
+
+	selected := Rules()
+	if len(opts.Rules) > 0 {
+		byName := make(map[string]Rule, len(selected))
+		var names []string
+		for _, r := range selected {
+			byName[r.Name()] = r
+			names = append(names, r.Name())
+		}
+		selected = nil
+		for _, name := range opts.Rules {
+			r, exists := byName[name]
+			if !exists {
+				return nil, fmt.Errorf("unknown lint rule '%s' (expected one of %s)", name, strings.Join(names, ", "))
+			}
+			selected = append(selected, r)
+		}
+	}
+
+	var result compare.DiffResult
+	for _, r := range selected {
+		for _, issue := range r.Check(s, opts) {
+			issue.Type = r.Name()
+			issue.Severity = compare.SeverityWarning
+			if severity, exists := opts.SeverityMap[r.Name()]; exists {
+				issue.Severity = severity
+			}
+			if issue.Severity == compare.SeverityIgnore {
+				continue
+			}
+			if issue.SchemaName == "" {
+				issue.SchemaName = s.Name
+			}
+			if issue.ObjectName == "" {
+				issue.ObjectName = issue.Table
+			}
+			result = append(result, issue)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Table < result[j].Table
+	})
+	return result, nil
+}
+
+// ruleFunc is a Rule backed by a function.
+type ruleFunc struct {
+	name        string                                                    // Unique name of the rule
+	description string                                                    // One-line summary of the rule
+	check       func(s *schema.Schema, opts Options) []compare.Difference // Check run on the schema
+}
+
+// Name returns the unique name of the rule.
+func (r ruleFunc) Name() string {
+	return r.name
+}
+
+// Description returns a one-line summary of what the rule checks.
+func (r ruleFunc) Description() string {
+	return r.description
+}
+
+// Check runs the rule on the schema.
+func (r ruleFunc) Check(s *schema.Schema, opts Options) []compare.Difference {
+	return r.check(s, opts)
+}
+
+// NewRule creates a Rule from a function, which is the simplest way to add a custom rule.
+//
+// Parameters:
+//   - name: Unique name of the rule, used as the type of its issues
+//   - description: One-line summary of what the rule checks
+//   - check: Function returning the issues found in a schema
+//
+// Returns:
+//   - Rule: Rule ready to be registered
+func NewRule(name, description string, check func(s *schema.Schema, opts Options) []compare.Difference) Rule {
+	return ruleFunc{name: name, description: description, check: check}
+}
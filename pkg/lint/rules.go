@@ -0,0 +1,194 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// init registers the built-in rules
+func init() {
+	Register(NewRule("NoPrimaryKey", "Tables without a primary key", checkPrimaryKeys))
+	Register(NewRule("UnindexedForeignKey", "Foreign keys whose columns are not the leading columns of an index", checkForeignKeyIndexes))
+	Register(NewRule("UnboundedVarchar", "Character varying columns without a length, when require_varchar_length is set", checkVarcharLengths))
+	Register(NewRule("DuplicateIndex", "Indexes with the same columns and expressions, uniqueness, included columns, predicate, and access method as another index of the table", checkDuplicateIndexes))
+}
+
+// checkPrimaryKeys reports the tables without a primary key. Views, materialized views, and
+// continuous aggregates, which are views, are not expected to have one.
+//
+// Parameters:
+//   - s: Schema to check
+//   - opts: Lint options (unused)
+//
+// Returns:
+//   - []compare.Difference: One issue per table without a primary key
+func checkPrimaryKeys(s *schema.Schema, opts Options) []compare.Difference {
+	var issues []compare.Difference
+	for _, name := range sortedTableNames(s) {
+		table := s.Tables[name]
+		if len(table.PrimaryKeys) > 0 || table.View != nil || (table.Hypertable != nil && table.Hypertable.ContinuousAggregate) {
+			continue
+		}
+		issues = append(issues, compare.Difference{
+			Table:       name,
+			ObjectKind:  compare.KindPrimaryKey,
+			Description: "Table has no primary key",
+		})
+	}
+	return issues
+}
+
+// checkForeignKeyIndexes reports the foreign keys that no index supports, which makes deleting
+// or updating referenced rows scan the whole referencing table. An index supports a foreign key
+// when the foreign key's columns are its leading columns, in any order.
+//
+// Parameters:
+//   - s: Schema to check
+//   - opts: Lint options (unused)
+//
+// Returns:
+//   - []compare.Difference: One issue per unsupported foreign key
+func checkForeignKeyIndexes(s *schema.Schema, opts Options) []compare.Difference {
+	var issues []compare.Difference
+	for _, name := range sortedTableNames(s) {
+		table := s.Tables[name]
+		for _, fk := range table.ForeignKeys {
+			if hasLeadingIndex(table, fk.Columns) {
+				continue
+			}
+			issues = append(issues, compare.Difference{
+				Table:       name,
+				ObjectKind:  compare.KindForeignKey,
+				SubObject:   fk.Name,
+				Description: fmt.Sprintf("Foreign key '%s' on (%s) has no supporting index", fk.Name, strings.Join(fk.Columns, ", ")),
+			})
+		}
+	}
+	return issues
+}
+
+// hasLeadingIndex reports whether an index (or the primary key) of a table starts with the
+// given columns, in any order. Partial indexes, which only cover some rows, do not count.
+func hasLeadingIndex(table schema.TableInfo, columns []string) bool {
+	candidates := [][]string{table.PrimaryKeys}
+	for _, idx := range table.Indexes {
+		if idx.Predicate == "" {
+			candidates = append(candidates, idx.Keys())
+		}
+	}
+	for _, indexed := range candidates {
+		if len(indexed) < len(columns) {
+			continue
+		}
+		if sameColumns(indexed[:len(columns)], columns) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkVarcharLengths reports the character varying columns declared without a length, when
+// the policy requires one. The columns of views, which take their types from the query, are
+// left out.
+//
+// Parameters:
+//   - s: Schema to check
+//   - opts: Lint options; nothing is reported unless RequireVarcharLength is set
+//
+// Returns:
+//   - []compare.Difference: One issue per unbounded column
+func checkVarcharLengths(s *schema.Schema, opts Options) []compare.Difference {
+	if !opts.RequireVarcharLength {
+		return nil
+	}
+
+	var issues []compare.Difference
+	for _, name := range sortedTableNames(s) {
+		if s.Tables[name].View != nil {
+			continue
+		}
+		for _, col := range s.Tables[name].Columns {
+			if (col.Type != "character varying" && col.Type != "varchar") || col.MaxLength > 0 {
+				continue
+			}
+			issues = append(issues, compare.Difference{
+				Table:       name,
+				ObjectKind:  compare.KindColumn,
+				SubObject:   col.Name,
+				Description: fmt.Sprintf("Column '%s' is character varying without a length", col.Name),
+			})
+		}
+	}
+	return issues
+}
+
+// checkDuplicateIndexes reports the indexes that have the same keys, in the same order, and the
+// same uniqueness, included columns, predicate, and access method as another index of their table. The first index by name is kept, and
+// the others are reported as its duplicates.
+//
+// Parameters:
+//   - s: Schema to check
+//   - opts: Lint options (unused)
+//
+// Returns:
+//   - []compare.Difference: One issue per duplicate index
+func checkDuplicateIndexes(s *schema.Schema, opts Options) []compare.Difference {
+	var issues []compare.Difference
+	for _, name := range sortedTableNames(s) {
+		indexes := append([]schema.IndexInfo(nil), s.Tables[name].Indexes...)
+		sort.Slice(indexes, func(i, j int) bool {
+			return indexes[i].Name < indexes[j].Name
+		})
+
+		first := make(map[string]string) // Name of the first index of each definition
+		for _, idx := range indexes {
+			definition := strings.Join([]string{strconv.FormatBool(idx.Unique), strings.Join(idx.Keys(), ","),
+				strings.Join(idx.Include, ","), idx.Predicate, idx.Method}, "\x00")
+			original, exists := first[definition]
+			if !exists {
+				first[definition] = idx.Name
+				continue
+			}
+			issues = append(issues, compare.Difference{
+				Table:       name,
+				ObjectKind:  compare.KindIndex,
+				SubObject:   idx.Name,
+				Description: fmt.Sprintf("Index '%s' duplicates index '%s' on (%s)", idx.Name, original, strings.Join(idx.Keys(), ", ")),
+			})
+		}
+	}
+	return issues
+}
+
+// sameColumns reports whether two lists hold the same columns, in any order.
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[string]int, len(a))
+	for _, col := range a {
+		counts[col]++
+	}
+	for _, col := range b {
+		if counts[col] == 0 {
+			return false
+		}
+		counts[col]--
+	}
+	return true
+}
+
+// sortedTableNames returns the names of the tables of a schema in sorted order.
+func sortedTableNames(s *schema.Schema) []string {
+	names := make([]string, 0, len(s.Tables))
+	for name := range s.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,141 @@
+// Package metrics provides Prometheus metrics of the comparisons run by the daemon and the
+// comparison service, so that drift can be alerted on and graphed: the differences found by the
+// last run of each pair of schemas, when it ran, and how long fetching and comparing took.
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Results of a run, as labelled in schema_check_runs_total.
+const (
+	ResultSuccess = "success" // The comparison completed
+	ResultError   = "error"   // The comparison failed
+)
+
+// Metrics holds the metrics of the comparisons, in a registry of their own. Its methods are safe
+// for concurrent use.
+type Metrics struct {
+	registry      *prometheus.Registry     // Registry served by Handler
+	differences   *prometheus.GaugeVec     // Differences found by the last successful run of each pair
+	lastRun       *prometheus.GaugeVec     // Time of the last run of each pair
+	lastSuccess   *prometheus.GaugeVec     // Time of the last successful run of each pair
+	runs          *prometheus.CounterVec   // Number of runs of each pair, by result
+	runDuration   *prometheus.HistogramVec // Time taken by the runs of each pair
+	fetchDuration *prometheus.HistogramVec // Time taken by fetching each side of each pair
+
+	mu     sync.Mutex                    // Protects series
+	series map[string]map[[2]string]bool // Severity and type of the differences reported for each pair
+}
+
+// New creates the metrics, registered along with the metrics of the Go runtime and the process.
+//
+// Returns:
+//   - *Metrics: Metrics, all empty
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		differences: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "schema_check_differences_total",
+			Help: "Number of differences found by the last successful comparison of a pair of schemas, by severity and type.",
+		}, []string{"pair", "severity", "type"}),
+		lastRun: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "schema_check_last_run_timestamp_seconds",
+			Help: "Unix time of the last comparison of a pair of schemas, successful or not.",
+		}, []string{"pair"}),
+		lastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "schema_check_last_success_timestamp_seconds",
+			Help: "Unix time of the last successful comparison of a pair of schemas.",
+		}, []string{"pair"}),
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "schema_check_runs_total",
+			Help: "Number of comparisons of a pair of schemas, by result.",
+		}, []string{"pair", "result"}),
+		runDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "schema_check_run_duration_seconds",
+			Help:    "Time taken by the comparisons of a pair of schemas, fetching included.",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 14),
+		}, []string{"pair"}),
+		fetchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "schema_check_fetch_duration_seconds",
+			Help:    "Time taken by fetching one side of a pair of schemas.",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 14),
+		}, []string{"pair", "side"}),
+		series: make(map[string]map[[2]string]bool),
+	}
+	m.registry.MustRegister(
+		m.differences, m.lastRun, m.lastSuccess, m.runs, m.runDuration, m.fetchDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler returns the HTTP handler serving the metrics in the Prometheus exposition format, to
+// be mounted on /metrics.
+//
+// Returns:
+//   - http.Handler: Handler of the metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRun records a comparison of a pair of schemas. After a successful run, the differences
+// of the pair are replaced by the ones found, the severities and types no longer found dropping
+// to zero; a failed run leaves them as they were.
+//
+// Parameters:
+//   - pair: Name of the pair of schemas, such as the environment compared
+//   - started: When the run started
+//   - differences: Differences found
+//   - err: Error the run failed with, or nil
+func (m *Metrics) ObserveRun(pair string, started time.Time, differences compare.DiffResult, err error) {
+	finished := time.Now()
+	m.runDuration.WithLabelValues(pair).Observe(finished.Sub(started).Seconds())
+	m.lastRun.WithLabelValues(pair).Set(float64(finished.Unix()))
+	if err != nil {
+		m.runs.WithLabelValues(pair, ResultError).Inc()
+		return
+	}
+	m.runs.WithLabelValues(pair, ResultSuccess).Inc()
+	m.lastSuccess.WithLabelValues(pair).Set(float64(finished.Unix()))
+
+	counts := make(map[[2]string]int)
+	for _, diff := range differences {
+		counts[[2]string{diff.Severity, diff.Type}]++
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seen := m.series[pair]
+	if seen == nil {
+		seen = make(map[[2]string]bool)
+		m.series[pair] = seen
+	}
+	for key := range seen {
+		if counts[key] == 0 {
+			m.differences.WithLabelValues(pair, key[0], key[1]).Set(0)
+		}
+	}
+	for key, count := range counts {
+		m.differences.WithLabelValues(pair, key[0], key[1]).Set(float64(count))
+		seen[key] = true
+	}
+}
+
+// ObserveFetch records the time taken by fetching one side of a pair of schemas.
+//
+// Parameters:
+//   - pair: Name of the pair of schemas
+//   - side: Side fetched: source or target
+//   - elapsed: Time taken by the fetch
+func (m *Metrics) ObserveFetch(pair, side string, elapsed time.Duration) {
+	m.fetchDuration.WithLabelValues(pair, side).Observe(elapsed.Seconds())
+}
@@ -0,0 +1,108 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Slack posts a summary of each event to a Slack incoming webhook: the number of differences by
+// severity, the first of those that appeared or were resolved, the tables with the most
+// differences, and a link to the full report.
+type Slack struct {
+	URL       string       // URL of the incoming webhook
+	ReportURL string       // Link to the full report, in which {check} is replaced by the name of the check; empty leaves it out
+	Client    *http.Client // Client sending the requests; nil uses http.DefaultClient
+}
+
+// slackMessage is the body of a message posted to a Slack incoming webhook.
+type slackMessage struct {
+	Text   string       `json:"text"`   // Plain text, shown in notifications
+	Blocks []slackBlock `json:"blocks"` // Formatted message
+}
+
+// slackBlock is a block of a Slack message.
+type slackBlock struct {
+	Type string     `json:"type"` // Kind of block: header or section
+	Text *slackText `json:"text"` // Text of the block
+}
+
+// slackText is the text of a Slack block.
+type slackText struct {
+	Type string `json:"type"` // Format of the text: plain_text or mrkdwn
+	Text string `json:"text"` // Text
+}
+
+// Notify posts the summary of the event.
+func (s Slack) Notify(ctx context.Context, event Event) error {
+	headline := title(event)
+	blocks := []slackBlock{{Type: "header", Text: &slackText{"plain_text", headline}}}
+	if counts := severityCounts(event); counts != "" {
+		blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{"mrkdwn", "*Differences:* " + counts}})
+	}
+	if heading, lines := changes(event); len(lines) > 0 {
+		blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{"mrkdwn", "*" + heading + ":*\n• " + strings.Join(lines, "\n• ")}})
+	}
+	if tables := mostDiffering(event); len(tables) > 0 {
+		var lines []string
+		for _, table := range tables {
+			lines = append(lines, fmt.Sprintf("• `%s`: %d", table.Table, table.Count))
+		}
+		blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{"mrkdwn", "*Tables with the most differences:*\n" + strings.Join(lines, "\n")}})
+	}
+	if s.ReportURL != "" {
+		blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{"mrkdwn", fmt.Sprintf("<%s|Full report>", reportLink(s.ReportURL, event))}})
+	}
+	return postJSON(ctx, s.Client, s.URL, slackMessage{Text: headline, Blocks: blocks})
+}
+
+// Teams posts a summary of each event to a Microsoft Teams incoming webhook or workflow, as an
+// Adaptive Card: the number of differences by severity, the first of those that appeared or were
+// resolved, the tables with the most differences, and a link to the full report.
+type Teams struct {
+	URL       string       // URL of the incoming webhook or workflow
+	ReportURL string       // Link to the full report, in which {check} is replaced by the name of the check; empty leaves it out
+	Client    *http.Client // Client sending the requests; nil uses http.DefaultClient
+}
+
+// Notify posts the summary of the event.
+func (t Teams) Notify(ctx context.Context, event Event) error {
+	body := []map[string]any{{
+		"type": "TextBlock", "text": title(event), "weight": "bolder", "size": "medium", "wrap": true,
+	}}
+	if counts := severityCounts(event); counts != "" {
+		body = append(body, map[string]any{"type": "TextBlock", "text": "Differences: " + counts, "wrap": true})
+	}
+	if heading, lines := changes(event); len(lines) > 0 {
+		body = append(body, map[string]any{"type": "TextBlock", "text": heading + ":\n\n- " + strings.Join(lines, "\n- "), "wrap": true})
+	}
+	if tables := mostDiffering(event); len(tables) > 0 {
+		var facts []map[string]string
+		for _, table := range tables {
+			facts = append(facts, map[string]string{"title": table.Table, "value": fmt.Sprint(table.Count)})
+		}
+		body = append(body,
+			map[string]any{"type": "TextBlock", "text": "Tables with the most differences:", "wrap": true},
+			map[string]any{"type": "FactSet", "facts": facts},
+		)
+	}
+
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+	if t.ReportURL != "" {
+		card["actions"] = []map[string]string{{"type": "Action.OpenUrl", "title": "Full report", "url": reportLink(t.ReportURL, event)}}
+	}
+	message := map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content":     card,
+		}},
+	}
+	return postJSON(ctx, t.Client, t.URL, message)
+}
@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Command runs a shell command for each event. The event is written as JSON to the command's
+// standard input, and its main fields are set in the environment as SCHEMA_CHECK_EVENT,
+// SCHEMA_CHECK_CHECK, and SCHEMA_CHECK_DIFFERENCES (the number of differences).
+type Command struct {
+	Command string // Command run with sh -c
+}
+
+// Notify runs the command, failing if it exits with a non-zero status.
+func (c Command) Notify(ctx context.Context, event Event) error {
+	input, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error encoding event: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.Command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(),
+		"SCHEMA_CHECK_EVENT="+event.Kind,
+		"SCHEMA_CHECK_CHECK="+event.Check,
+		"SCHEMA_CHECK_DIFFERENCES="+strconv.Itoa(len(event.Differences)),
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("error running notification command: %w: %s", err, msg)
+		}
+		return fmt.Errorf("error running notification command: %w", err)
+	}
+	return nil
+}
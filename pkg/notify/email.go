@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/report"
+)
+
+// Formats of the report sent by email.
+const (
+	EmailHTML     = "html"     // Report as an HTML page
+	EmailMarkdown = "markdown" // Report as a Markdown document, sent as plain text
+)
+
+// Email sends the report of each event by SMTP to a list of recipients. The message is sent
+// with STARTTLS when the server offers it, and authenticates with PLAIN when a username is set,
+// which net/smtp only allows over TLS or to localhost.
+type Email struct {
+	Server   string   // Address of the SMTP server, as host:port
+	Username string   // User to authenticate as; empty sends without authentication
+	Password string   // Password of the user
+	From     string   // Address the message is sent from
+	To       []string // Addresses the message is sent to
+	Format   string   // Format of the report: html (the default) or markdown
+}
+
+// Notify sends the report of the event, with its title as the subject.
+func (e Email) Notify(ctx context.Context, event Event) error {
+	var body bytes.Buffer
+	var renderer compare.Renderer = report.HTML{}
+	contentType := "text/html; charset=UTF-8"
+	if e.Format == EmailMarkdown {
+		renderer, contentType = report.Markdown{}, "text/plain; charset=UTF-8"
+	}
+	if err := renderer.Render(event.Differences, &body); err != nil {
+		return fmt.Errorf("error rendering report: %w", err)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", title(event)))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: %s\r\n", contentType)
+	fmt.Fprintf(&msg, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	// Quoted-printable keeps the lines of long reports within the limits of SMTP
+	encoder := quotedprintable.NewWriter(&msg)
+	encoder.Write(body.Bytes())
+	encoder.Close()
+
+	var auth smtp.Auth
+	if e.Username != "" {
+		host, _, err := net.SplitHostPort(e.Server)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP server address '%s': %w", e.Server, err)
+		}
+		auth = smtp.PlainAuth("", e.Username, e.Password, host)
+	}
+
+	// net/smtp has no context, so the context only bounds the wait for the message to be sent
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(e.Server, auth, e.From, e.To, msg.Bytes())
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("error sending email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,286 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+)
+
+// Default endpoints of the incident management APIs.
+const (
+	PagerDutyURL = "https://events.pagerduty.com/v2/enqueue" // PagerDuty Events API v2
+	OpsgenieURL  = "https://api.opsgenie.com/v2/alerts"      // Opsgenie Alert API; https://api.eu.opsgenie.com/v2/alerts for EU accounts
+)
+
+// incidentSource is the source reported with incidents and alerts.
+const incidentSource = "schema-check"
+
+// severityRanks orders the severities of differences, most severe last.
+var severityRanks = map[string]int{
+	compare.SeverityInfo:    1,
+	compare.SeverityWarning: 2,
+	compare.SeverityError:   3,
+}
+
+// Actions on an incident called for by an event.
+const (
+	incidentNone    = ""        // The incident is left as it is
+	incidentOpen    = "open"    // An incident is opened, or kept open
+	incidentResolve = "resolve" // The incident is resolved
+)
+
+// incidents tracks which checks have an open incident, so that an incident is opened once when
+// drift is found and resolved once when it goes away, however many events the runs send. After a
+// restart, nothing is known of the incidents opened before, so the first run of a check opens or
+// resolves its incident again; the dedup key of the check makes this harmless.
+type incidents struct {
+	mu   sync.Mutex
+	open map[string]bool // Whether each check has an open incident; absent when unknown
+}
+
+// action returns what an event calls for: opening an incident when it has differences at least
+// as severe as the minimum, and resolving it otherwise, unless that was already done. Started
+// and difference events call for nothing.
+//
+// Parameters:
+//   - event: Event of a check
+//   - minSeverity: Least severity of the differences opening an incident; empty for error
+//
+// Returns:
+//   - string: Action on the incident of the check: incidentOpen, incidentResolve, or incidentNone
+//   - string: Most severe severity of the differences at least as severe as the minimum, if any
+func (i *incidents) action(event Event, minSeverity string) (string, string) {
+	if event.Kind == EventStarted || event.Kind == EventDifference {
+		// Neither tells the outcome of a run
+		return incidentNone, ""
+	}
+	severity := alarmingSeverity(event, minSeverity)
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	open, known := i.open[event.Check]
+	switch {
+	case severity != "" && !(known && open):
+		return incidentOpen, severity
+	case severity == "" && !(known && !open):
+		return incidentResolve, ""
+	}
+	return incidentNone, severity
+}
+
+// record remembers the state of the incident of a check once an action on it succeeded.
+//
+// Parameters:
+//   - check: Name of the check
+//   - open: Whether the incident is open
+func (i *incidents) record(check string, open bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.open == nil {
+		i.open = make(map[string]bool)
+	}
+	i.open[check] = open
+}
+
+// alarmingSeverity returns the most severe severity of the differences of an event, if it is at
+// least as severe as the minimum.
+//
+// Parameters:
+//   - event: Event of a check
+//   - minSeverity: Least severity that is alarming; empty for error
+//
+// Returns:
+//   - string: Most severe severity of the differences, or empty if none is alarming
+func alarmingSeverity(event Event, minSeverity string) string {
+	if minSeverity == "" {
+		minSeverity = compare.SeverityError
+	}
+	for _, severity := range []string{compare.SeverityError, compare.SeverityWarning, compare.SeverityInfo} {
+		if event.Summary[severity] > 0 && severityRanks[severity] >= severityRanks[minSeverity] {
+			return severity
+		}
+	}
+	return ""
+}
+
+// incidentKey returns the key identifying the incident of a check, the same for every run of the
+// check, so that the incident management service deduplicates the alerts of a database pair.
+func incidentKey(check string) string {
+	return incidentSource + ":" + check
+}
+
+// incidentSummary returns the one-line summary of the incident opened for an event.
+func incidentSummary(event Event) string {
+	return fmt.Sprintf("Schema drift in %s: %s", event.Check, severityCounts(event))
+}
+
+// incidentDetails returns the details attached to the incident opened for an event: the number
+// of differences, of each severity, and of the tables with the most differences.
+func incidentDetails(event Event) map[string]string {
+	details := map[string]string{
+		"check":       event.Check,
+		"differences": fmt.Sprint(len(event.Differences)),
+	}
+	for severity, count := range event.Summary {
+		details[severity+"s"] = fmt.Sprint(count)
+	}
+	var tables []string
+	for _, table := range mostDiffering(event) {
+		tables = append(tables, fmt.Sprintf("%s (%d)", table.Table, table.Count))
+	}
+	if len(tables) > 0 {
+		details["most_differing_tables"] = strings.Join(tables, ", ")
+	}
+	return details
+}
+
+// PagerDuty opens a PagerDuty incident through the Events API v2 when a check finds differences
+// at least as severe as MinSeverity, and resolves it when a later run finds none. The dedup key
+// of the incident is that of the check, so every run of a pair maps to the same incident.
+//
+// A PagerDuty notifier must receive the report events as well as the drift and resolved ones
+// (see Only), so that drift becoming more or less severe opens or resolves the incident. It
+// keeps track of the incidents it opened, so it must be used by pointer.
+type PagerDuty struct {
+	RoutingKey  string       // Integration key of the PagerDuty service
+	URL         string       // Endpoint of the Events API; empty uses PagerDutyURL
+	MinSeverity string       // Least severity of the differences opening an incident: error (the default), warning, or info
+	ReportURL   string       // Link to the full report attached to incidents, in which {check} is replaced by the name of the check
+	Client      *http.Client // Client sending the requests; nil uses http.DefaultClient
+
+	incidents incidents // Incidents opened, by check
+}
+
+// pagerDutyEvent is an event of the PagerDuty Events API v2.
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`       // Integration key of the service
+	EventAction string            `json:"event_action"`      // Action: trigger or resolve
+	DedupKey    string            `json:"dedup_key"`         // Key of the incident
+	Payload     *pagerDutyPayload `json:"payload,omitempty"` // Description of the incident, when triggering
+	Links       []pagerDutyLink   `json:"links,omitempty"`   // Links attached to the incident
+}
+
+// pagerDutyPayload describes a PagerDuty incident.
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`        // One-line summary of the incident
+	Source        string            `json:"source"`         // Affected system: the check
+	Severity      string            `json:"severity"`       // Severity: critical, error, warning, or info
+	Component     string            `json:"component"`      // Affected component
+	Class         string            `json:"class"`          // Kind of incident
+	CustomDetails map[string]string `json:"custom_details"` // Details shown with the incident
+}
+
+// pagerDutyLink is a link attached to a PagerDuty incident.
+type pagerDutyLink struct {
+	Href string `json:"href"` // URL of the link
+	Text string `json:"text"` // Text of the link
+}
+
+// Notify triggers or resolves the incident of the check, if the event calls for it.
+func (p *PagerDuty) Notify(ctx context.Context, event Event) error {
+	action, severity := p.incidents.action(event, p.MinSeverity)
+	if action == incidentNone {
+		return nil
+	}
+
+	message := pagerDutyEvent{RoutingKey: p.RoutingKey, EventAction: "resolve", DedupKey: incidentKey(event.Check)}
+	if action == incidentOpen {
+		message.EventAction = "trigger"
+		message.Payload = &pagerDutyPayload{
+			Summary:       incidentSummary(event),
+			Source:        event.Check,
+			Severity:      severity,
+			Component:     "schema",
+			Class:         "schema drift",
+			CustomDetails: incidentDetails(event),
+		}
+		if p.ReportURL != "" {
+			message.Links = []pagerDutyLink{{Href: reportLink(p.ReportURL, event), Text: "Full report"}}
+		}
+	}
+
+	endpoint := p.URL
+	if endpoint == "" {
+		endpoint = PagerDutyURL
+	}
+	if err := postJSON(ctx, p.Client, endpoint, message); err != nil {
+		return fmt.Errorf("error sending %s event to PagerDuty: %w", message.EventAction, err)
+	}
+	p.incidents.record(event.Check, action == incidentOpen)
+	return nil
+}
+
+// Opsgenie creates an Opsgenie alert when a check finds differences at least as severe as
+// MinSeverity, and closes it when a later run finds none. The alias of the alert is that of the
+// check, so every run of a pair maps to the same alert.
+//
+// As PagerDuty, an Opsgenie notifier must receive the report events, and must be used by pointer.
+type Opsgenie struct {
+	APIKey      string       // Key of an API integration of Opsgenie
+	URL         string       // Endpoint of the Alert API; empty uses OpsgenieURL
+	MinSeverity string       // Least severity of the differences creating an alert: error (the default), warning, or info
+	ReportURL   string       // Link to the full report added to the description of alerts, in which {check} is replaced by the name of the check
+	Client      *http.Client // Client sending the requests; nil uses http.DefaultClient
+
+	incidents incidents // Alerts created, by check
+}
+
+// opsgeniePriorities maps the severities of differences to the priorities of alerts.
+var opsgeniePriorities = map[string]string{
+	compare.SeverityError:   "P2",
+	compare.SeverityWarning: "P3",
+	compare.SeverityInfo:    "P4",
+}
+
+// Notify creates or closes the alert of the check, if the event calls for it.
+func (o *Opsgenie) Notify(ctx context.Context, event Event) error {
+	action, severity := o.incidents.action(event, o.MinSeverity)
+	if action == incidentNone {
+		return nil
+	}
+
+	endpoint := strings.TrimSuffix(o.URL, "/")
+	if endpoint == "" {
+		endpoint = OpsgenieURL
+	}
+	header := http.Header{"Authorization": {"GenieKey " + o.APIKey}}
+	alias := incidentKey(event.Check)
+
+	if action == incidentResolve {
+		body := map[string]string{"source": incidentSource, "note": "No more differences found by " + incidentSource}
+		closeURL := endpoint + "/" + url.PathEscape(alias) + "/close?identifierType=alias"
+		if err := postJSONWithHeader(ctx, o.Client, closeURL, header, body); err != nil {
+			return fmt.Errorf("error closing Opsgenie alert: %w", err)
+		}
+		o.incidents.record(event.Check, false)
+		return nil
+	}
+
+	description := fmt.Sprintf("%d differences (%s) between the databases of %s.", len(event.Differences), severityCounts(event), event.Check)
+	if o.ReportURL != "" {
+		description += "\n\nFull report: " + reportLink(o.ReportURL, event)
+	}
+	message := incidentSummary(event)
+	if len(message) > 130 {
+		message = message[:127] + "..."
+	}
+	body := map[string]any{
+		"message":     message,
+		"alias":       alias,
+		"description": description,
+		"priority":    opsgeniePriorities[severity],
+		"source":      incidentSource,
+		"entity":      event.Check,
+		"tags":        []string{incidentSource, "schema-drift"},
+		"details":     incidentDetails(event),
+	}
+	if err := postJSONWithHeader(ctx, o.Client, endpoint, header, body); err != nil {
+		return fmt.Errorf("error creating Opsgenie alert: %w", err)
+	}
+	o.incidents.record(event.Check, true)
+	return nil
+}
@@ -0,0 +1,137 @@
+// Package notify provides functionality to tell people and systems about schema drift found by
+// scheduled comparisons, by posting events to webhooks, Slack, or Microsoft Teams, emailing
+// reports, opening incidents in PagerDuty or Opsgenie, publishing events to Kafka or NATS, or
+// handing events to shell commands.
+package notify
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+)
+
+// Kinds of event.
+const (
+	EventStarted    = "started"    // A comparison started
+	EventDifference = "difference" // A comparison found a difference, sent once for each difference of every run
+	EventDrift      = "drift"      // A comparison found differences its previous run did not
+	EventResolved   = "resolved"   // Differences found by the previous run of a comparison are gone
+	EventReport     = "report"     // A comparison completed, whatever it found
+)
+
+// Events lists the kinds of event.
+var Events = []string{EventStarted, EventDifference, EventDrift, EventResolved, EventReport}
+
+// DefaultEvents are the kinds of event sent to notifiers that do not choose theirs: the changes
+// of the outcome of comparisons, but not the report of every run.
+var DefaultEvents = []string{EventDrift, EventResolved}
+
+// Kinds of notifier.
+const (
+	TypeWebhook   = "webhook"   // Posts events as JSON to a URL
+	TypeSlack     = "slack"     // Posts a summary of each event to a Slack incoming webhook
+	TypeTeams     = "teams"     // Posts a summary of each event to a Microsoft Teams webhook
+	TypeEmail     = "email"     // Sends the report of each event by SMTP
+	TypeCommand   = "command"   // Runs a shell command for each event
+	TypePagerDuty = "pagerduty" // Opens and resolves PagerDuty incidents
+	TypeOpsgenie  = "opsgenie"  // Creates and closes Opsgenie alerts
+	TypeKafka     = "kafka"     // Publishes events as JSON to a Kafka topic
+	TypeNATS      = "nats"      // Publishes events as JSON to a NATS subject
+)
+
+// Types lists the kinds of notifier.
+var Types = []string{TypeWebhook, TypeSlack, TypeTeams, TypeEmail, TypeCommand, TypePagerDuty, TypeOpsgenie, TypeKafka, TypeNATS}
+
+// Event is a change in the outcome of a scheduled comparison.
+type Event struct {
+	Kind        string             `json:"event"`                 // Kind of event: started, difference, drift, resolved, or report
+	Check       string             `json:"check"`                 // Name of the comparison, such as its environment
+	Time        time.Time          `json:"time"`                  // When the comparison that changed ran
+	Summary     map[string]int     `json:"summary"`               // Number of differences of each severity
+	Differences compare.DiffResult `json:"differences,omitempty"` // Differences found, empty once resolved or when started; the one found for difference events
+	Appeared    compare.DiffResult `json:"appeared,omitempty"`    // Differences the previous run did not find, for drift events
+	Resolved    compare.DiffResult `json:"resolved,omitempty"`    // Differences of the previous run no longer found, for resolved events
+}
+
+// Notifier sends events to a destination.
+type Notifier interface {
+	// Notify sends an event, returning once it has been delivered.
+	Notify(ctx context.Context, event Event) error
+}
+
+// IsKnownEvent reports whether a name is one of the kinds of event.
+//
+// Parameters:
+//   - kind: Name to check
+//
+// Returns:
+//   - bool: True if the name is a kind of event
+func IsKnownEvent(kind string) bool {
+	for _, event := range Events {
+		if event == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// CloseAll closes the notifiers that keep connections open between events, such as Kafka and
+// NATS, once they are no longer used.
+//
+// Parameters:
+//   - notifiers: Notifiers to close; those without connections are left alone
+//
+// Returns:
+//   - error: The first error that occurred while closing a notifier
+func CloseAll(notifiers []Notifier) error {
+	var first error
+	for _, notifier := range notifiers {
+		if closer, ok := notifier.(io.Closer); ok {
+			if err := closer.Close(); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// Only restricts a notifier to some kinds of event, dropping the others.
+//
+// Parameters:
+//   - kinds: Kinds of event to send; empty sends DefaultEvents
+//   - notifier: Notifier to restrict
+//
+// Returns:
+//   - Notifier: Notifier sending only the given kinds of event
+func Only(kinds []string, notifier Notifier) Notifier {
+	if len(kinds) == 0 {
+		kinds = DefaultEvents
+	}
+	return filtered{kinds: kinds, next: notifier}
+}
+
+// filtered is a notifier that only passes some kinds of event on.
+type filtered struct {
+	kinds []string // Kinds of event passed on
+	next  Notifier // Notifier the events are passed to
+}
+
+// Close closes the notifier the events are passed to, if it keeps connections open.
+func (f filtered) Close() error {
+	if closer, ok := f.next.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Notify passes the event on if it is of one of the kinds kept.
+func (f filtered) Notify(ctx context.Context, event Event) error {
+	for _, kind := range f.kinds {
+		if kind == event.Kind {
+			return f.next.Notify(ctx, event)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package notify
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+)
+
+// topTables is the number of tables with the most differences listed in chat messages.
+const topTables = 5
+
+// topChanges is the number of appeared or resolved differences listed in chat messages.
+const topChanges = 5
+
+// tableCount is the number of differences of a table.
+type tableCount struct {
+	Table string // Name of the table
+	Count int    // Number of differences about it
+}
+
+// title returns the headline of an event in chat messages and email subjects.
+func title(event Event) string {
+	switch event.Kind {
+	case EventStarted:
+		return fmt.Sprintf("Schema check of %s started", event.Check)
+	case EventDifference:
+		if len(event.Differences) > 0 {
+			return fmt.Sprintf("Schema difference in %s: %s", event.Check, event.Differences[0].Description)
+		}
+		return fmt.Sprintf("Schema difference in %s", event.Check)
+	case EventResolved:
+		if len(event.Differences) > 0 {
+			return fmt.Sprintf("%d schema differences resolved in %s, %d left", len(event.Resolved), event.Check, len(event.Differences))
+		}
+		return fmt.Sprintf("Schema drift resolved in %s", event.Check)
+	case EventReport:
+		return fmt.Sprintf("Schema check of %s: %d differences", event.Check, len(event.Differences))
+	default:
+		if len(event.Appeared) > 0 && len(event.Appeared) < len(event.Differences) {
+			return fmt.Sprintf("Schema drift in %s: %d new differences, %d in all", event.Check, len(event.Appeared), len(event.Differences))
+		}
+		return fmt.Sprintf("Schema drift in %s: %d differences", event.Check, len(event.Differences))
+	}
+}
+
+// changes lists the descriptions of the differences that appeared or were resolved, the first
+// few of each, for drift and resolved events that do not concern every difference found.
+//
+// Parameters:
+//   - event: Event to describe
+//
+// Returns:
+//   - string: Heading of the list, such as "New differences", or empty if there is none
+//   - []string: Descriptions of the differences, ending with how many more there are, if any
+func changes(event Event) (string, []string) {
+	heading, differences := "New differences", event.Appeared
+	if event.Kind == EventResolved {
+		heading, differences = "Resolved differences", event.Resolved
+	}
+	if len(differences) == 0 || (event.Kind == EventDrift && len(differences) == len(event.Differences)) {
+		return "", nil
+	}
+	var lines []string
+	for _, diff := range differences[:min(len(differences), topChanges)] {
+		lines = append(lines, diff.Description)
+	}
+	if len(differences) > topChanges {
+		lines = append(lines, fmt.Sprintf("and %d more", len(differences)-topChanges))
+	}
+	return heading, lines
+}
+
+// severityCounts lists the number of differences of each severity, most severe first, as
+// "3 errors, 1 warning".
+func severityCounts(event Event) string {
+	var parts []string
+	for _, severity := range []string{compare.SeverityError, compare.SeverityWarning, compare.SeverityInfo} {
+		if count := event.Summary[severity]; count > 0 {
+			noun := severity
+			if count > 1 {
+				noun += "s"
+			}
+			parts = append(parts, fmt.Sprintf("%d %s", count, noun))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// mostDiffering returns the tables with the most differences, most first, ties by name.
+func mostDiffering(event Event) []tableCount {
+	counts := make(map[string]int)
+	for _, diff := range event.Differences {
+		counts[diff.Table]++
+	}
+	tables := make([]tableCount, 0, len(counts))
+	for table, count := range counts {
+		tables = append(tables, tableCount{table, count})
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		if tables[i].Count != tables[j].Count {
+			return tables[i].Count > tables[j].Count
+		}
+		return tables[i].Table < tables[j].Table
+	})
+	if len(tables) > topTables {
+		tables = tables[:topTables]
+	}
+	return tables
+}
+
+// reportLink returns the link to the full report of an event, replacing {check} in the
+// configured URL with the name of the check.
+func reportLink(reportURL string, event Event) string {
+	return strings.ReplaceAll(reportURL, "{check}", url.PathEscape(event.Check))
+}
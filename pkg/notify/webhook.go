@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Webhook posts events as JSON to a URL.
+type Webhook struct {
+	URL    string       // URL the events are posted to
+	Client *http.Client // Client sending the requests; nil uses http.DefaultClient
+}
+
+// Notify posts the event to the URL, failing unless the response has a 2xx status.
+func (w Webhook) Notify(ctx context.Context, event Event) error {
+	return postJSON(ctx, w.Client, w.URL, event)
+}
+
+// postJSON posts a value as JSON to a URL.
+//
+// Parameters:
+//   - ctx: Context of the request
+//   - client: Client sending the request; nil uses http.DefaultClient
+//   - url: URL to post to
+//   - body: Value to encode as the body
+//
+// Returns:
+//   - error: Any error that occurred, including a response without a 2xx status
+func postJSON(ctx context.Context, client *http.Client, url string, body any) error {
+	return postJSONWithHeader(ctx, client, url, nil, body)
+}
+
+// postJSONWithHeader posts a value as JSON to a URL, with additional headers such as those
+// authenticating the request.
+//
+// Parameters:
+//   - ctx: Context of the request
+//   - client: Client sending the request; nil uses http.DefaultClient
+//   - url: URL to post to
+//   - header: Headers added to the request; nil adds none
+//   - body: Value to encode as the body
+//
+// Returns:
+//   - error: Any error that occurred, including a response without a 2xx status
+func postJSONWithHeader(ctx context.Context, client *http.Client, url string, header http.Header, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("error encoding notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("error creating webhook request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error posting to webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %s", resp.Status)
+	}
+	return nil
+}
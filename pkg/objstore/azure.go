@@ -0,0 +1,138 @@
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables holding the account of Azure Blob Storage and its credentials.
+const (
+	azureAccountEnv  = "AZURE_STORAGE_ACCOUNT"   // Name of the storage account
+	azureKeyEnv      = "AZURE_STORAGE_KEY"       // Key of the storage account, base64-encoded
+	azureSASTokenEnv = "AZURE_STORAGE_SAS_TOKEN" // Shared access signature allowing to write blobs, used in place of the key
+)
+
+// azureAPIVersion is the version of the Blob Storage REST API requests are made with.
+const azureAPIVersion = "2021-08-06"
+
+// putAzure writes a block blob to Azure Blob Storage, in the account named by
+// AZURE_STORAGE_ACCOUNT, authenticated with AZURE_STORAGE_SAS_TOKEN or AZURE_STORAGE_KEY.
+//
+// Parameters:
+//   - ctx: Context for the request
+//   - object: Location of the blob
+//   - data: Content of the blob
+//   - opts: Encryption scope and content type of the blob
+//
+// Returns:
+//   - error: An error if the account or its credentials are not set, or the blob cannot be written
+func putAzure(ctx context.Context, object Object, data []byte, opts Options) error {
+	account := os.Getenv(azureAccountEnv)
+	if account == "" {
+		return fmt.Errorf("no storage account: set %s", azureAccountEnv)
+	}
+	key, sasToken := os.Getenv(azureKeyEnv), strings.TrimPrefix(os.Getenv(azureSASTokenEnv), "?")
+	if key == "" && sasToken == "" {
+		return fmt.Errorf("no credentials for storage account %s: set %s or %s", account, azureSASTokenEnv, azureKeyEnv)
+	}
+
+	segments := strings.Split(object.Key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	blobPath := "/" + url.PathEscape(object.Bucket) + "/" + strings.Join(segments, "/")
+	endpoint := "https://" + account + ".blob.core.windows.net" + blobPath
+	if sasToken != "" {
+		endpoint += "?" + sasToken
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", opts.ContentType)
+	request.Header.Set("x-ms-blob-type", "BlockBlob")
+	request.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	request.Header.Set("x-ms-version", azureAPIVersion)
+	if opts.KMSKey != "" {
+		request.Header.Set("x-ms-encryption-scope", opts.KMSKey)
+	}
+	if sasToken == "" {
+		signature, err := azureSharedKey(request, account, key, len(data), blobPath)
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Authorization", "SharedKey "+account+":"+signature)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusCreated {
+		io.Copy(io.Discard, response.Body)
+		return nil
+	}
+
+	var result struct {
+		Message string `xml:"Message"` // Description of the error
+	}
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if xml.Unmarshal(body, &result) != nil || result.Message == "" {
+		return fmt.Errorf("Azure Blob Storage responded with status %s", response.Status)
+	}
+	return fmt.Errorf("Azure Blob Storage responded with status %s: %s", response.Status, strings.TrimSpace(strings.SplitN(result.Message, "\n", 2)[0]))
+}
+
+// azureSharedKey signs a request with the key of a storage account (Shared Key authorization).
+//
+// Parameters:
+//   - request: Request to sign, with every x-ms- header set
+//   - account: Name of the storage account
+//   - key: Key of the storage account, base64-encoded
+//   - length: Length of the body of the request
+//   - blobPath: Escaped path of the blob, starting with its container
+//
+// Returns:
+//   - string: Signature of the request
+//   - error: An error if the key is not valid base64
+func azureSharedKey(request *http.Request, account, key string, length int, blobPath string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %w", azureKeyEnv, err)
+	}
+
+	var headers []string
+	for name := range request.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-ms-") {
+			headers = append(headers, lower+":"+strings.TrimSpace(request.Header.Get(name)))
+		}
+	}
+	sort.Strings(headers)
+
+	contentLength := ""
+	if length > 0 {
+		contentLength = strconv.Itoa(length)
+	}
+	// Verb, then Content-Encoding, Content-Language, Content-Length, Content-MD5, Content-Type,
+	// Date, If-Modified-Since, If-Match, If-None-Match, If-Unmodified-Since, and Range
+	fields := []string{request.Method, "", "", contentLength, "", request.Header.Get("Content-Type"), "", "", "", "", "", ""}
+	stringToSign := strings.Join(fields, "\n") + "\n" + strings.Join(headers, "\n") + "\n/" + account + blobPath
+
+	mac := hmac.New(sha256.New, decoded)
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
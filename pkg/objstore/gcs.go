@@ -0,0 +1,75 @@
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"golang.org/x/oauth2/google"
+)
+
+// gcsEndpoint is the base URL of the uploads of the Cloud Storage JSON API.
+const gcsEndpoint = "https://storage.googleapis.com/upload/storage/v1/b/"
+
+// gcsClient is the HTTP client authenticated with the application default credentials, created
+// the first time an object is written to Cloud Storage.
+var gcsClient struct {
+	once   sync.Once
+	client *http.Client
+	err    error
+}
+
+// putGCS writes an object to Cloud Storage with a simple upload.
+//
+// Parameters:
+//   - ctx: Context for the request
+//   - object: Location of the object
+//   - data: Content of the object
+//   - opts: Cloud KMS key and content type of the object
+//
+// Returns:
+//   - error: Any error that occurred while finding credentials or writing the object
+func putGCS(ctx context.Context, object Object, data []byte, opts Options) error {
+	gcsClient.once.Do(func() {
+		gcsClient.client, gcsClient.err = google.DefaultClient(context.Background(), "https://www.googleapis.com/auth/devstorage.read_write")
+	})
+	if gcsClient.err != nil {
+		return fmt.Errorf("error finding GCP credentials: %w", gcsClient.err)
+	}
+
+	query := url.Values{"uploadType": {"media"}, "name": {object.Key}}
+	if opts.KMSKey != "" {
+		query.Set("kmsKeyName", opts.KMSKey)
+	}
+	endpoint := gcsEndpoint + url.PathEscape(object.Bucket) + "/o?" + query.Encode()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", opts.ContentType)
+	response, err := gcsClient.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusOK {
+		io.Copy(io.Discard, response.Body)
+		return nil
+	}
+
+	var result struct {
+		Error struct {
+			Message string `json:"message"` // Description of the error
+		} `json:"error"`
+	}
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if json.Unmarshal(body, &result) != nil || result.Error.Message == "" {
+		return fmt.Errorf("Cloud Storage responded with status %s", response.Status)
+	}
+	return fmt.Errorf("Cloud Storage responded with status %s: %s", response.Status, result.Error.Message)
+}
@@ -0,0 +1,202 @@
+// Package objstore provides functionality to write reports and snapshots to object storage,
+// Amazon S3, Google Cloud Storage, and Azure Blob Storage, named by URIs, so that runs on
+// machines without persistent disks can keep their outputs:
+//
+//	s3://BUCKET/KEY
+//	gs://BUCKET/OBJECT
+//	azblob://CONTAINER/BLOB (in the storage account named by AZURE_STORAGE_ACCOUNT)
+//
+// Credentials are found the way the tools of each provider find them: the environment, shared
+// configuration files, and instance metadata on AWS; application default credentials on GCP.
+// Azure Blob Storage is authenticated with a SAS token (AZURE_STORAGE_SAS_TOKEN) or the key of
+// the account (AZURE_STORAGE_KEY).
+//
+// Objects are written in one request once their content is complete, so they are held in memory
+// until then.
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+)
+
+// Schemes of the URIs of objects.
+const (
+	SchemeS3    = "s3"     // Amazon S3
+	SchemeGCS   = "gs"     // Google Cloud Storage
+	SchemeAzure = "azblob" // Azure Blob Storage
+)
+
+// Server-side encryption of S3 objects.
+const (
+	SSES3  = "AES256"  // Keys managed by S3
+	SSEKMS = "aws:kms" // Keys managed by AWS KMS, the default key of the account unless Options.KMSKey is set
+)
+
+// Options controls how objects are written. The zero value writes them with the default
+// encryption of their bucket or container.
+type Options struct {
+	SSE         string // Server-side encryption of S3 objects: SSES3 or SSEKMS; empty uses the default of the bucket
+	KMSKey      string // Key objects are encrypted with: the ID or ARN of an AWS KMS key (with SSEKMS), the resource name of a Cloud KMS key, or the name of an Azure encryption scope
+	ContentType string // Content type of the objects; empty guesses it from the extension of the name
+}
+
+// Object is the location of an object parsed from a URI.
+type Object struct {
+	Scheme string // Scheme of the URI: SchemeS3, SchemeGCS, or SchemeAzure
+	Bucket string // Bucket, or container for Azure
+	Key    string // Name of the object in the bucket
+}
+
+// String returns the URI of the object.
+func (o Object) String() string {
+	return o.Scheme + "://" + o.Bucket + "/" + o.Key
+}
+
+// IsURI reports whether a path names an object in object storage rather than a local file.
+//
+// Parameters:
+//   - path: Path or URI to check
+//
+// Returns:
+//   - bool: True if the path starts with s3://, gs://, or azblob://
+func IsURI(path string) bool {
+	for _, scheme := range []string{SchemeS3, SchemeGCS, SchemeAzure} {
+		if strings.HasPrefix(path, scheme+"://") {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse parses the URI of an object.
+//
+// Parameters:
+//   - uri: URI of the object, such as s3://bucket/reports/drift.html
+//
+// Returns:
+//   - Object: Location of the object
+//   - error: An error if the URI has an unknown scheme, or no bucket or object name
+func Parse(uri string) (Object, error) {
+	scheme, rest, found := strings.Cut(uri, "://")
+	if !found || !IsURI(uri) {
+		return Object{}, fmt.Errorf("invalid object URI '%s': expected s3://BUCKET/KEY, gs://BUCKET/OBJECT, or azblob://CONTAINER/BLOB", uri)
+	}
+	bucket, key, _ := strings.Cut(rest, "/")
+	if bucket == "" || key == "" || strings.HasSuffix(key, "/") {
+		return Object{}, fmt.Errorf("invalid object URI '%s': expected a bucket and an object name", uri)
+	}
+	return Object{Scheme: scheme, Bucket: bucket, Key: key}, nil
+}
+
+// CheckOptions checks that the encryption options can be used to write an object.
+//
+// Parameters:
+//   - uri: URI of the object
+//   - opts: Options of the write
+//
+// Returns:
+//   - error: An error if the URI is invalid or the options do not apply to its provider
+func CheckOptions(uri string, opts Options) error {
+	object, err := Parse(uri)
+	if err != nil {
+		return err
+	}
+	switch {
+	case opts.SSE != "" && opts.SSE != SSES3 && opts.SSE != SSEKMS:
+		return fmt.Errorf("unknown server-side encryption '%s': expected %s or %s", opts.SSE, SSES3, SSEKMS)
+	case opts.SSE != "" && object.Scheme != SchemeS3:
+		return fmt.Errorf("server-side encryption %s only applies to S3; %s objects are always encrypted, with the KMS key or encryption scope given, if any", opts.SSE, object.Scheme)
+	case object.Scheme == SchemeS3 && opts.KMSKey != "" && opts.SSE != SSEKMS:
+		return fmt.Errorf("a KMS key is only used by S3 with server-side encryption %s", SSEKMS)
+	}
+	return nil
+}
+
+// Put writes an object, replacing any object of the same name.
+//
+// Parameters:
+//   - ctx: Context for the request
+//   - uri: URI of the object
+//   - data: Content of the object
+//   - opts: Encryption and content type of the object
+//
+// Returns:
+//   - error: An error if the URI or options are invalid, or the object cannot be written
+func Put(ctx context.Context, uri string, data []byte, opts Options) error {
+	if err := CheckOptions(uri, opts); err != nil {
+		return err
+	}
+	object, _ := Parse(uri)
+	if opts.ContentType == "" {
+		opts.ContentType = contentType(object.Key)
+	}
+
+	var err error
+	switch object.Scheme {
+	case SchemeS3:
+		err = putS3(ctx, object, data, opts)
+	case SchemeGCS:
+		err = putGCS(ctx, object, data, opts)
+	case SchemeAzure:
+		err = putAzure(ctx, object, data, opts)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing %s: %w", object, err)
+	}
+	return nil
+}
+
+// contentType guesses the content type of an object from its name. Compressed snapshots are
+// stored as the compressed type, not as the type of their content.
+func contentType(key string) string {
+	if guessed := mime.TypeByExtension(path.Ext(key)); guessed != "" {
+		return guessed
+	}
+	switch path.Ext(key) {
+	case ".md":
+		return "text/markdown; charset=utf-8"
+	case ".zst", ".zstd":
+		return "application/zstd"
+	}
+	return "application/octet-stream"
+}
+
+// Writer collects the content of an object, and writes the object when it is closed.
+type Writer struct {
+	ctx  context.Context // Context of the request writing the object
+	uri  string          // URI of the object
+	opts Options         // Options of the write
+	buf  bytes.Buffer    // Content written so far
+}
+
+// NewWriter returns a writer of an object. Nothing is sent until Close is called.
+//
+// Parameters:
+//   - ctx: Context for the request writing the object
+//   - uri: URI of the object
+//   - opts: Encryption and content type of the object
+//
+// Returns:
+//   - *Writer: Writer of the object
+//   - error: An error if the URI or options are invalid
+func NewWriter(ctx context.Context, uri string, opts Options) (*Writer, error) {
+	if err := CheckOptions(uri, opts); err != nil {
+		return nil, err
+	}
+	return &Writer{ctx: ctx, uri: uri, opts: opts}, nil
+}
+
+// Write adds to the content of the object.
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+// Close writes the object with the content written so far.
+func (w *Writer) Close() error {
+	return Put(w.ctx, w.uri, w.buf.Bytes(), w.opts)
+}
@@ -0,0 +1,61 @@
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// s3Client is the S3 client, created the first time an object is written to S3, as loading the
+// AWS configuration can take requests to the instance metadata service.
+var s3Client struct {
+	once   sync.Once
+	client *s3.Client
+	err    error
+}
+
+// putS3 writes an object to S3, in the region of the AWS configuration (such as AWS_REGION).
+//
+// Parameters:
+//   - ctx: Context for the request
+//   - object: Location of the object
+//   - data: Content of the object
+//   - opts: Encryption and content type of the object
+//
+// Returns:
+//   - error: Any error that occurred while loading the AWS configuration or writing the object
+func putS3(ctx context.Context, object Object, data []byte, opts Options) error {
+	s3Client.once.Do(func() {
+		cfg, err := config.LoadDefaultConfig(context.Background())
+		if err != nil {
+			s3Client.err = fmt.Errorf("error loading AWS configuration: %w", err)
+			return
+		}
+		s3Client.client = s3.NewFromConfig(cfg)
+	})
+	if s3Client.err != nil {
+		return s3Client.err
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:        aws.String(object.Bucket),
+		Key:           aws.String(object.Key),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+		ContentType:   aws.String(opts.ContentType),
+	}
+	if opts.SSE != "" {
+		input.ServerSideEncryption = types.ServerSideEncryption(opts.SSE)
+	}
+	if opts.KMSKey != "" {
+		input.SSEKMSKeyId = aws.String(opts.KMSKey)
+	}
+	_, err := s3Client.client.PutObject(ctx, input)
+	return err
+}
@@ -0,0 +1,641 @@
+// Package patch provides a structured model of the changes needed to reconcile two schemas.
+// A Patch is derived from the differences found by a comparison, and lists operations such as
+// AddColumn or DropIndex that both the SQL generator of this package and external tooling can
+// consume, so that detecting differences is decoupled from deciding how to render or apply them.
+//
+// The exported types and functions of this package are part of the supported API (see the root
+// schemacheck package), and so are the Kind of each operation and its JSON fields; the exact text
+// of the statements the SQL generator writes may change in any release.
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// Operation is a single change to a schema. The concrete types are the structs of this package
+// (AddColumn, DropIndex, ...); they carry the data the change needs and no rendering logic.
+type Operation interface {
+	// Kind returns the name of the operation type (e.g., "AddColumn").
+	Kind() string
+	// TableName returns the name of the table the operation changes.
+	TableName() string
+}
+
+// CreateTable creates a table with its columns and primary key. Its indexes and foreign keys
+// are created by separate operations.
+type CreateTable struct {
+	Table  string           `json:"table"`  // Name of the table
+	Schema string           `json:"schema"` // PostgreSQL schema of the table
+	Info   schema.TableInfo `json:"info"`   // Definition of the table
+}
+
+// DropTable drops a table.
+type DropTable struct {
+	Table  string `json:"table"`  // Name of the table
+	Schema string `json:"schema"` // PostgreSQL schema of the table
+}
+
+// RenameTable renames a table.
+type RenameTable struct {
+	Table   string `json:"table"`    // Current name of the table
+	Schema  string `json:"schema"`   // PostgreSQL schema of the table
+	NewName string `json:"new_name"` // Name the table is given
+}
+
+// AlterTableOwner changes the role owning a table.
+type AlterTableOwner struct {
+	Table  string `json:"table"`  // Name of the table
+	Schema string `json:"schema"` // PostgreSQL schema of the table
+	Owner  string `json:"owner"`  // Role that should own the table
+}
+
+// AddColumn adds a column to a table.
+type AddColumn struct {
+	Table  string            `json:"table"`  // Name of the table
+	Schema string            `json:"schema"` // PostgreSQL schema of the table
+	Column schema.ColumnInfo `json:"column"` // Definition of the column
+}
+
+// DropColumn drops a column from a table.
+type DropColumn struct {
+	Table  string `json:"table"`  // Name of the table
+	Schema string `json:"schema"` // PostgreSQL schema of the table
+	Column string `json:"column"` // Name of the column
+}
+
+// RenameColumn renames a column of a table.
+type RenameColumn struct {
+	Table   string `json:"table"`    // Name of the table
+	Schema  string `json:"schema"`   // PostgreSQL schema of the table
+	Column  string `json:"column"`   // Current name of the column
+	NewName string `json:"new_name"` // Name the column is given
+}
+
+// AlterColumnType changes the data type of a column.
+type AlterColumnType struct {
+	Table  string `json:"table"`  // Name of the table
+	Schema string `json:"schema"` // PostgreSQL schema of the table
+	Column string `json:"column"` // Name of the column
+	Type   string `json:"type"`   // New data type
+}
+
+// AlterColumnNullable adds or removes the NOT NULL constraint of a column.
+type AlterColumnNullable struct {
+	Table    string `json:"table"`    // Name of the table
+	Schema   string `json:"schema"`   // PostgreSQL schema of the table
+	Column   string `json:"column"`   // Name of the column
+	Nullable bool   `json:"nullable"` // Whether the column should accept NULL values
+}
+
+// AlterColumnDefault sets or removes the default value of a column.
+type AlterColumnDefault struct {
+	Table   string `json:"table"`   // Name of the table
+	Schema  string `json:"schema"`  // PostgreSQL schema of the table
+	Column  string `json:"column"`  // Name of the column
+	Default string `json:"default"` // New default expression; empty removes the default
+}
+
+// DropPrimaryKey drops the primary key of a table by the name of its constraint. Without a name,
+// as in plans saved by earlier releases, PostgreSQL's default name (<table>_pkey) is assumed.
+type DropPrimaryKey struct {
+	Table  string `json:"table"`          // Name of the table
+	Schema string `json:"schema"`         // PostgreSQL schema of the table
+	Name   string `json:"name,omitempty"` // Name of the primary key constraint
+}
+
+// AddPrimaryKey adds a primary key to a table, with the name of its constraint if known.
+type AddPrimaryKey struct {
+	Table   string   `json:"table"`          // Name of the table
+	Schema  string   `json:"schema"`         // PostgreSQL schema of the table
+	Columns []string `json:"columns"`        // Columns of the primary key, in order
+	Name    string   `json:"name,omitempty"` // Name of the primary key constraint; empty lets PostgreSQL name it
+}
+
+// CreateIndex creates an index.
+type CreateIndex struct {
+	Table  string           `json:"table"`  // Name of the table
+	Schema string           `json:"schema"` // PostgreSQL schema of the table
+	Index  schema.IndexInfo `json:"index"`  // Definition of the index
+}
+
+// DropIndex drops an index.
+type DropIndex struct {
+	Table  string `json:"table"`  // Name of the table
+	Schema string `json:"schema"` // PostgreSQL schema of the table
+	Index  string `json:"index"`  // Name of the index
+}
+
+// AddForeignKey adds a foreign key constraint.
+type AddForeignKey struct {
+	Table      string                `json:"table"`       // Name of the table
+	Schema     string                `json:"schema"`      // PostgreSQL schema of the table
+	ForeignKey schema.ForeignKeyInfo `json:"foreign_key"` // Definition of the constraint
+}
+
+// DropForeignKey drops a foreign key constraint.
+type DropForeignKey struct {
+	Table      string `json:"table"`       // Name of the table
+	Schema     string `json:"schema"`      // PostgreSQL schema of the table
+	ForeignKey string `json:"foreign_key"` // Name of the constraint
+}
+
+// CreateView creates a view from its query, replacing any view of the same name, or creates a
+// materialized view. The indexes of a materialized view are created by separate operations.
+type CreateView struct {
+	Table  string          `json:"table"`  // Name of the view
+	Schema string          `json:"schema"` // PostgreSQL schema of the view
+	View   schema.ViewInfo `json:"view"`   // Definition of the view
+}
+
+// DropView drops a view or materialized view.
+type DropView struct {
+	Table        string `json:"table"`                  // Name of the view
+	Schema       string `json:"schema"`                 // PostgreSQL schema of the view
+	Materialized bool   `json:"materialized,omitempty"` // Whether the view is a materialized view
+}
+
+// RefreshMaterializedView refreshes a materialized view, or empties it.
+type RefreshMaterializedView struct {
+	Table    string `json:"table"`     // Name of the materialized view
+	Schema   string `json:"schema"`    // PostgreSQL schema of the materialized view
+	WithData bool   `json:"with_data"` // Whether the materialized view is populated, rather than emptied
+}
+
+func (CreateTable) Kind() string             { return "CreateTable" }
+func (DropTable) Kind() string               { return "DropTable" }
+func (RenameTable) Kind() string             { return "RenameTable" }
+func (AlterTableOwner) Kind() string         { return "AlterTableOwner" }
+func (AddColumn) Kind() string               { return "AddColumn" }
+func (DropColumn) Kind() string              { return "DropColumn" }
+func (RenameColumn) Kind() string            { return "RenameColumn" }
+func (AlterColumnType) Kind() string         { return "AlterColumnType" }
+func (AlterColumnNullable) Kind() string     { return "AlterColumnNullable" }
+func (AlterColumnDefault) Kind() string      { return "AlterColumnDefault" }
+func (DropPrimaryKey) Kind() string          { return "DropPrimaryKey" }
+func (AddPrimaryKey) Kind() string           { return "AddPrimaryKey" }
+func (CreateIndex) Kind() string             { return "CreateIndex" }
+func (DropIndex) Kind() string               { return "DropIndex" }
+func (AddForeignKey) Kind() string           { return "AddForeignKey" }
+func (DropForeignKey) Kind() string          { return "DropForeignKey" }
+func (CreateView) Kind() string              { return "CreateView" }
+func (DropView) Kind() string                { return "DropView" }
+func (RefreshMaterializedView) Kind() string { return "RefreshMaterializedView" }
+
+func (o CreateTable) TableName() string             { return o.Table }
+func (o DropTable) TableName() string               { return o.Table }
+func (o RenameTable) TableName() string             { return o.Table }
+func (o AlterTableOwner) TableName() string         { return o.Table }
+func (o AddColumn) TableName() string               { return o.Table }
+func (o DropColumn) TableName() string              { return o.Table }
+func (o RenameColumn) TableName() string            { return o.Table }
+func (o AlterColumnType) TableName() string         { return o.Table }
+func (o AlterColumnNullable) TableName() string     { return o.Table }
+func (o AlterColumnDefault) TableName() string      { return o.Table }
+func (o DropPrimaryKey) TableName() string          { return o.Table }
+func (o AddPrimaryKey) TableName() string           { return o.Table }
+func (o CreateIndex) TableName() string             { return o.Table }
+func (o DropIndex) TableName() string               { return o.Table }
+func (o AddForeignKey) TableName() string           { return o.Table }
+func (o DropForeignKey) TableName() string          { return o.Table }
+func (o CreateView) TableName() string              { return o.Table }
+func (o DropView) TableName() string                { return o.Table }
+func (o RefreshMaterializedView) TableName() string { return o.Table }
+
+// order gives the position of each operation type in a patch, so that objects are dropped
+// before the objects they depend on, and created after them. Renames come first, as the other
+// operations refer to the objects by their new names. Views are dropped before the tables and
+// columns they read, and created once those exist, before the indexes of materialized views.
+var order = map[string]int{
+	"RenameTable":             0,
+	"RenameColumn":            1,
+	"DropView":                2,
+	"DropForeignKey":          3,
+	"DropIndex":               4,
+	"DropPrimaryKey":          5,
+	"DropColumn":              6,
+	"DropTable":               7,
+	"CreateTable":             8,
+	"AlterTableOwner":         9,
+	"AddColumn":               10,
+	"AlterColumnType":         11,
+	"AlterColumnDefault":      12,
+	"AlterColumnNullable":     13,
+	"AddPrimaryKey":           14,
+	"CreateView":              15,
+	"CreateIndex":             16,
+	"AddForeignKey":           17,
+	"RefreshMaterializedView": 18,
+}
+
+// Patch is the ordered list of operations that makes the schema being changed match the
+// authoritative one, together with the differences it could not express as operations.
+type Patch struct {
+	Operations  []Operation          // Operations to perform, in the order they must be applied
+	Unsupported []compare.Difference // Differences that must be reconciled manually (e.g., partitioning changes)
+}
+
+// FromDifferences builds the patch that reconciles the differences found between two schemas.
+// Only the given differences are considered, so differences removed by filters, severity
+// overrides, or suppression rules are not reconciled either.
+//
+// The direction decides which schema is changed: with compare.DirectionTargetToSource the source
+// is changed to match the target; otherwise the target is changed to match the source.
+//
+// Parameters:
+//   - differences: Differences to reconcile, as returned by a comparison of source and target
+//   - source: The source schema of the comparison
+//   - target: The target schema of the comparison
+//   - direction: Direction of the comparison (see compare.Options.Direction)
+//
+// Returns:
+//   - Patch: Operations reconciling the differences
+func FromDifferences(differences compare.DiffResult, source, target *schema.Schema, direction string) Patch {
+	b := builder{desired: source, current: target, seen: make(map[string]bool), recreated: make(map[string]bool)}
+	if direction == compare.DirectionTargetToSource {
+		b.desired, b.current = target, source
+		b.flipped = true
+	}
+
+	for _, diff := range differences {
+		if !b.add(diff) {
+			b.patch.Unsupported = append(b.patch.Unsupported, diff)
+		}
+	}
+
+	// The indexes of recreated materialized views are dropped along with them, and their data
+	// is created as desired
+	operations := b.patch.Operations[:0]
+	for _, op := range b.patch.Operations {
+		switch op.(type) {
+		case DropIndex, RefreshMaterializedView:
+			if b.recreated[op.TableName()] {
+				continue
+			}
+		}
+		operations = append(operations, op)
+	}
+	b.patch.Operations = operations
+
+	// Tables and views are created after the tables and views they depend on (such as partitions
+	// after their parent, and views after the tables they read) and dropped before them
+	created, dropped := tableRanks(b.desired), tableRanks(b.current)
+	sort.SliceStable(b.patch.Operations, func(i, j int) bool {
+		first, second := b.patch.Operations[i], b.patch.Operations[j]
+		if first.Kind() != second.Kind() {
+			return order[first.Kind()] < order[second.Kind()]
+		}
+		switch first.Kind() {
+		case "CreateTable", "CreateView":
+			return created[first.TableName()] < created[second.TableName()]
+		case "DropTable", "DropView":
+			return dropped[first.TableName()] > dropped[second.TableName()]
+		}
+		return false
+	})
+	return b.patch
+}
+
+// tableRanks gives the position of each table and view of a schema in its dependency order.
+//
+// Parameters:
+//   - s: Schema whose tables and views are ranked
+//
+// Returns:
+//   - map[string]int: Position of each table and view, lower for those others depend on; empty
+//     if the dependencies form a cycle
+func tableRanks(s *schema.Schema) map[string]int {
+	ranks := make(map[string]int)
+	ordered, err := s.DependencyGraph().Order()
+	if err != nil {
+		return ranks
+	}
+	for i, ref := range ordered {
+		if ref.Kind == schema.ObjectTable || ref.Kind == schema.ObjectView {
+			ranks[ref.Table] = i
+		}
+	}
+	return ranks
+}
+
+// builder accumulates the operations of a patch.
+type builder struct {
+	desired   *schema.Schema          // Schema the changed schema must end up matching
+	current   *schema.Schema          // Schema being changed
+	flipped   bool                    // Whether the source is the schema being changed
+	seen      map[string]bool         // Operations already added, keyed by kind, table, and object
+	recreated map[string]bool         // Materialized views dropped and created again, by name
+	graph     *schema.DependencyGraph // Dependency graph of the schema being changed, built when a view is recreated
+	patch     Patch                   // Patch being built
+}
+
+// add appends the operations reconciling a difference.
+//
+// Parameters:
+//   - diff: Difference to reconcile
+//
+// Returns:
+//   - bool: False if the difference cannot be expressed as operations
+func (b *builder) add(diff compare.Difference) bool {
+	diffType := diff.Type
+	if b.flipped {
+		// Objects missing from the target are extra in the schema being changed, and vice versa
+		switch {
+		case strings.HasPrefix(diffType, "Missing"):
+			diffType = "Extra" + strings.TrimPrefix(diffType, "Missing")
+		case strings.HasPrefix(diffType, "Extra"):
+			diffType = "Missing" + strings.TrimPrefix(diffType, "Extra")
+		}
+	}
+
+	schemaName := b.current.Name
+	desiredTable, inDesired := b.desired.Tables[diff.Table]
+	currentTable, inCurrent := b.current.Tables[diff.Table]
+
+	// The columns of a view follow from its query, and a view has no primary key: differences
+	// in them are reconciled by recreating the view, unless the relation is a table on one side
+	if desiredTable.View != nil || currentTable.View != nil {
+		switch {
+		case diff.ObjectKind == compare.KindColumn, diffType == "PrimaryKeyMismatch":
+			return b.recreateView(diff.Table, schemaName, desiredTable, currentTable)
+		}
+	}
+
+	switch diffType {
+	case "MissingTable":
+		if !inDesired {
+			return false
+		}
+		b.emit(diff.Table, CreateTable{Table: diff.Table, Schema: schemaName, Info: desiredTable})
+		for _, idx := range desiredTable.Indexes {
+			if !isPrimaryKeyIndex(desiredTable, idx) {
+				b.emit(idx.Name, CreateIndex{Table: diff.Table, Schema: schemaName, Index: idx})
+			}
+		}
+		for _, fk := range desiredTable.ForeignKeys {
+			b.emit(fk.Name, AddForeignKey{Table: diff.Table, Schema: schemaName, ForeignKey: fk})
+		}
+	case "ExtraTable":
+		b.emit(diff.Table, DropTable{Table: diff.Table, Schema: schemaName})
+
+	case "MissingView", "MissingMaterializedView":
+		if !inDesired || desiredTable.View == nil {
+			return false
+		}
+		b.createView(diff.Table, schemaName, desiredTable)
+	case "ExtraView", "ExtraMaterializedView":
+		if !inCurrent || currentTable.View == nil {
+			return false
+		}
+		b.emit(diff.Table, DropView{Table: diff.Table, Schema: schemaName, Materialized: currentTable.View.Materialized})
+	case "ViewDefinitionMismatch":
+		if !inDesired || !inCurrent || desiredTable.View == nil || currentTable.View == nil {
+			return false
+		}
+		if !desiredTable.View.Materialized {
+			// CREATE OR REPLACE VIEW keeps the view's grants and the objects depending on it
+			b.emit(diff.Table, CreateView{Table: diff.Table, Schema: schemaName, View: *desiredTable.View})
+			break
+		}
+		return b.recreateView(diff.Table, schemaName, desiredTable, currentTable)
+	case "MaterializedViewPopulatedMismatch":
+		if !inDesired || desiredTable.View == nil {
+			return false
+		}
+		b.emit(diff.Table, RefreshMaterializedView{Table: diff.Table, Schema: schemaName, WithData: desiredTable.View.Populated})
+	case "OwnerMismatch":
+		if !inDesired {
+			return false
+		}
+		b.emit(diff.Table, AlterTableOwner{Table: diff.Table, Schema: schemaName, Owner: desiredTable.Owner})
+
+	case "TableRename", "ColumnRename":
+		// The schema being changed has the old name
+		old, renamed := diff.TargetValue, diff.SourceValue
+		if b.flipped {
+			old, renamed = renamed, old
+		}
+		if diffType == "TableRename" {
+			b.emit(old, RenameTable{Table: old, Schema: schemaName, NewName: renamed})
+		} else {
+			b.emit(old, RenameColumn{Table: diff.Table, Schema: schemaName, Column: old, NewName: renamed})
+		}
+
+	case "MissingColumn":
+		col, ok := findColumn(desiredTable, diff.SubObject)
+		if !ok {
+			return false
+		}
+		b.emit(col.Name, AddColumn{Table: diff.Table, Schema: schemaName, Column: col})
+	case "ExtraColumn":
+		b.emit(diff.SubObject, DropColumn{Table: diff.Table, Schema: schemaName, Column: diff.SubObject})
+	case "ColumnTypeMismatch", "ColumnNullableMismatch", "ColumnDefaultMismatch":
+		col, ok := findColumn(desiredTable, diff.SubObject)
+		if !ok {
+			return false
+		}
+		switch diffType {
+		case "ColumnTypeMismatch":
+			b.emit(col.Name, AlterColumnType{Table: diff.Table, Schema: schemaName, Column: col.Name, Type: col.Type})
+		case "ColumnNullableMismatch":
+			b.emit(col.Name, AlterColumnNullable{Table: diff.Table, Schema: schemaName, Column: col.Name, Nullable: col.Nullable})
+		default:
+			b.emit(col.Name, AlterColumnDefault{Table: diff.Table, Schema: schemaName, Column: col.Name, Default: col.Default})
+		}
+
+	case "PrimaryKeyMismatch":
+		if !inDesired || !inCurrent {
+			return false
+		}
+		if len(currentTable.PrimaryKeys) > 0 {
+			b.emit(diff.Table, DropPrimaryKey{Table: diff.Table, Schema: schemaName, Name: currentTable.PrimaryKeyName})
+		}
+		if len(desiredTable.PrimaryKeys) > 0 {
+			b.emit(diff.Table, AddPrimaryKey{Table: diff.Table, Schema: schemaName, Columns: desiredTable.PrimaryKeys, Name: desiredTable.PrimaryKeyName})
+		}
+
+	case "MissingIndex", "IndexUniqueMismatch", "IndexColumnsMismatch", "IndexIncludeMismatch", "IndexPredicateMismatch", "IndexMethodMismatch":
+		idx, ok := findIndex(desiredTable, diff.SubObject)
+		if !ok {
+			return false
+		}
+		if diffType != "MissingIndex" {
+			b.emit(idx.Name, DropIndex{Table: diff.Table, Schema: schemaName, Index: idx.Name})
+		}
+		b.emit(idx.Name, CreateIndex{Table: diff.Table, Schema: schemaName, Index: idx})
+	case "ExtraIndex":
+		b.emit(diff.SubObject, DropIndex{Table: diff.Table, Schema: schemaName, Index: diff.SubObject})
+
+	case "MissingForeignKey", "ForeignKeyReferenceMismatch", "ForeignKeyColumnsMismatch", "ForeignKeyReferencedColumnsMismatch", "ForeignKeyOptionsMismatch":
+		fk, ok := findForeignKey(desiredTable, diff.SubObject)
+		if !ok {
+			return false
+		}
+		if diffType != "MissingForeignKey" {
+			b.emit(fk.Name, DropForeignKey{Table: diff.Table, Schema: schemaName, ForeignKey: fk.Name})
+		}
+		b.emit(fk.Name, AddForeignKey{Table: diff.Table, Schema: schemaName, ForeignKey: fk})
+	case "ExtraForeignKey":
+		b.emit(diff.SubObject, DropForeignKey{Table: diff.Table, Schema: schemaName, ForeignKey: diff.SubObject})
+
+	case "FeatureUnsupported":
+		// Nothing to reconcile: the feature cannot be used on one of the sides
+
+	default:
+		// Partitioning, identity, compression, fetch failures, unconfirmed renames, and custom
+		// comparators' differences
+		return false
+	}
+	return true
+}
+
+// emit appends an operation unless an identical one was already added, which happens when
+// several differences (e.g., an index's uniqueness and columns) lead to the same change.
+//
+// Parameters:
+//   - object: Name of the object the operation is about, to tell operations apart
+//   - op: Operation to append
+func (b *builder) emit(object string, op Operation) {
+	key := op.Kind() + "\x00" + op.TableName() + "\x00" + object
+	if b.seen[key] {
+		return
+	}
+	b.seen[key] = true
+	b.patch.Operations = append(b.patch.Operations, op)
+}
+
+// createView appends the operations creating a view, and the indexes of a materialized view.
+//
+// Parameters:
+//   - name: Name of the view
+//   - schemaName: PostgreSQL schema of the view
+//   - desired: Definition of the view in the schema the changed schema must end up matching
+func (b *builder) createView(name, schemaName string, desired schema.TableInfo) {
+	b.emit(name, CreateView{Table: name, Schema: schemaName, View: *desired.View})
+	for _, idx := range desired.Indexes {
+		b.emit(idx.Name, CreateIndex{Table: name, Schema: schemaName, Index: idx})
+	}
+}
+
+// recreateView appends the operations dropping a view and creating it again from its desired
+// definition, which reconciles differences that CREATE OR REPLACE VIEW cannot, such as in the
+// columns of a view or the query of a materialized view. The views reading it, which cannot
+// outlive it, are recreated as well.
+//
+// Parameters:
+//   - name: Name of the view
+//   - schemaName: PostgreSQL schema of the view
+//   - desired: Definition of the relation in the schema the changed schema must end up matching
+//   - current: Definition of the relation in the schema being changed
+//
+// Returns:
+//   - bool: False if the relation is not a view of the same kind on both sides
+func (b *builder) recreateView(name, schemaName string, desired, current schema.TableInfo) bool {
+	if desired.View == nil || current.View == nil || desired.View.Materialized != current.View.Materialized {
+		return false
+	}
+	b.emit(name, DropView{Table: name, Schema: schemaName, Materialized: current.View.Materialized})
+	b.createView(name, schemaName, desired)
+	if desired.View.Materialized {
+		b.recreated[name] = true
+	}
+
+	if b.graph == nil {
+		b.graph = b.current.DependencyGraph()
+	}
+	for _, ref := range b.graph.Dependents(schema.ViewRef(name)) {
+		dependent, exists := b.desired.Tables[ref.Table]
+		if ref.Kind != schema.ObjectView || !exists || dependent.View == nil {
+			// Views absent from the desired schema are dropped by their own difference
+			continue
+		}
+		b.emit(ref.Table, DropView{Table: ref.Table, Schema: schemaName, Materialized: b.current.Tables[ref.Table].View.Materialized})
+		b.createView(ref.Table, schemaName, dependent)
+		if dependent.View.Materialized {
+			b.recreated[ref.Table] = true
+		}
+	}
+	return true
+}
+
+// findColumn returns the column of a table with the given name.
+func findColumn(table schema.TableInfo, name string) (schema.ColumnInfo, bool) {
+	for _, col := range table.Columns {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return schema.ColumnInfo{}, false
+}
+
+// findIndex returns the index of a table with the given name.
+func findIndex(table schema.TableInfo, name string) (schema.IndexInfo, bool) {
+	for _, idx := range table.Indexes {
+		if idx.Name == name {
+			return idx, true
+		}
+	}
+	return schema.IndexInfo{}, false
+}
+
+// findForeignKey returns the foreign key of a table with the given name.
+func findForeignKey(table schema.TableInfo, name string) (schema.ForeignKeyInfo, bool) {
+	for _, fk := range table.ForeignKeys {
+		if fk.Name == name {
+			return fk, true
+		}
+	}
+	return schema.ForeignKeyInfo{}, false
+}
+
+// isPrimaryKeyIndex reports whether an index is the one backing the table's primary key, which
+// is created together with the table.
+func isPrimaryKeyIndex(table schema.TableInfo, idx schema.IndexInfo) bool {
+	if !idx.Unique || len(table.PrimaryKeys) == 0 || len(idx.Columns) != len(table.PrimaryKeys) || len(idx.Expressions) > 0 || idx.Predicate != "" {
+		return false
+	}
+	columns := make(map[string]bool, len(idx.Columns))
+	for _, col := range idx.Columns {
+		columns[col] = true
+	}
+	for _, col := range table.PrimaryKeys {
+		if !columns[col] {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalJSON encodes the patch for external tooling, tagging each operation with its kind:
+//
+//	{"operations": [{"kind": "AddColumn", "operation": {...}}], "unsupported": [...]}
+func (p Patch) MarshalJSON() ([]byte, error) {
+	type taggedOperation struct {
+		Kind      string    `json:"kind"`
+		Operation Operation `json:"operation"`
+	}
+	doc := struct {
+		Operations  []taggedOperation    `json:"operations"`
+		Unsupported []compare.Difference `json:"unsupported"`
+	}{
+		Operations:  make([]taggedOperation, 0, len(p.Operations)),
+		Unsupported: p.Unsupported,
+	}
+	for _, op := range p.Operations {
+		doc.Operations = append(doc.Operations, taggedOperation{Kind: op.Kind(), Operation: op})
+	}
+	if doc.Unsupported == nil {
+		doc.Unsupported = []compare.Difference{}
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding patch: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,226 @@
+package patch
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+	"github.com/jackc/pgx/v5"
+)
+
+// SQL renders an operation as a PostgreSQL DDL statement. Identifiers are always quoted, while
+// data types, default expressions, index expressions and predicates, and the options of foreign
+// keys are used verbatim, as they were read from the catalog.
+//
+// Parameters:
+//   - op: Operation to render
+//
+// Returns:
+//   - string: DDL statement, terminated by a semicolon
+//   - error: An error if the operation is not one of the types of this package
+func SQL(op Operation) (string, error) {
+	switch o := op.(type) {
+	case CreateTable:
+		var defs []string
+		for _, col := range o.Info.Columns {
+			defs = append(defs, columnDefinition(col.Name, col.Type, col.Nullable, col.Default, col.IsIdentity))
+		}
+		if len(o.Info.PrimaryKeys) > 0 {
+			defs = append(defs, primaryKeyDefinition(o.Info.PrimaryKeyName, o.Info.PrimaryKeys))
+		}
+		return fmt.Sprintf("CREATE TABLE %s (\n    %s\n);", table(o.Schema, o.Table), strings.Join(defs, ",\n    ")), nil
+	case DropTable:
+		return fmt.Sprintf("DROP TABLE %s;", table(o.Schema, o.Table)), nil
+	case RenameTable:
+		return fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", table(o.Schema, o.Table), ident(o.NewName)), nil
+	case AlterTableOwner:
+		return fmt.Sprintf("ALTER TABLE %s OWNER TO %s;", table(o.Schema, o.Table), ident(o.Owner)), nil
+	case AddColumn:
+		col := o.Column
+		return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", table(o.Schema, o.Table),
+			columnDefinition(col.Name, col.Type, col.Nullable, col.Default, col.IsIdentity)), nil
+	case DropColumn:
+		return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", table(o.Schema, o.Table), ident(o.Column)), nil
+	case RenameColumn:
+		return fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s;", table(o.Schema, o.Table), ident(o.Column), ident(o.NewName)), nil
+	case AlterColumnType:
+		return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s;", table(o.Schema, o.Table), ident(o.Column), o.Type), nil
+	case AlterColumnNullable:
+		action := "SET NOT NULL"
+		if o.Nullable {
+			action = "DROP NOT NULL"
+		}
+		return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s;", table(o.Schema, o.Table), ident(o.Column), action), nil
+	case AlterColumnDefault:
+		action := "DROP DEFAULT"
+		if o.Default != "" {
+			action = "SET DEFAULT " + o.Default
+		}
+		return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s;", table(o.Schema, o.Table), ident(o.Column), action), nil
+	case DropPrimaryKey:
+		name := o.Name
+		if name == "" {
+			name = o.Table + "_pkey"
+		}
+		return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", table(o.Schema, o.Table), ident(name)), nil
+	case AddPrimaryKey:
+		return fmt.Sprintf("ALTER TABLE %s ADD %s;", table(o.Schema, o.Table), primaryKeyDefinition(o.Name, o.Columns)), nil
+	case CreateIndex:
+		unique := ""
+		if o.Index.Unique {
+			unique = "UNIQUE "
+		}
+		return fmt.Sprintf("CREATE %sINDEX %s ON %s%s;", unique, ident(o.Index.Name), table(o.Schema, o.Table), indexDefinition(o.Index)), nil
+	case DropIndex:
+		return fmt.Sprintf("DROP INDEX %s;", table(o.Schema, o.Index)), nil
+	case AddForeignKey:
+		fk := o.ForeignKey
+		options := ""
+		if fk.Options() != "" {
+			options = " " + fk.Options()
+		}
+		return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)%s;",
+			table(o.Schema, o.Table), ident(fk.Name), identList(fk.Columns),
+			table(o.Schema, fk.ReferencedTable), identList(fk.ReferencedColumns), options), nil
+	case DropForeignKey:
+		return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", table(o.Schema, o.Table), ident(o.ForeignKey)), nil
+	case CreateView:
+		// pg_get_viewdef ends the query with a semicolon
+		query := strings.TrimSuffix(strings.TrimSpace(o.View.Definition), ";")
+		if !o.View.Materialized {
+			return fmt.Sprintf("CREATE OR REPLACE VIEW %s AS\n%s;", table(o.Schema, o.Table), query), nil
+		}
+		data := "WITH DATA"
+		if !o.View.Populated {
+			data = "WITH NO DATA"
+		}
+		return fmt.Sprintf("CREATE MATERIALIZED VIEW %s AS\n%s\n%s;", table(o.Schema, o.Table), query, data), nil
+	case DropView:
+		if o.Materialized {
+			return fmt.Sprintf("DROP MATERIALIZED VIEW %s;", table(o.Schema, o.Table)), nil
+		}
+		return fmt.Sprintf("DROP VIEW %s;", table(o.Schema, o.Table)), nil
+	case RefreshMaterializedView:
+		if !o.WithData {
+			return fmt.Sprintf("REFRESH MATERIALIZED VIEW %s WITH NO DATA;", table(o.Schema, o.Table)), nil
+		}
+		return fmt.Sprintf("REFRESH MATERIALIZED VIEW %s;", table(o.Schema, o.Table)), nil
+	}
+	return "", fmt.Errorf("unsupported patch operation %T", op)
+}
+
+// Statements renders every operation of the patch as a DDL statement, in order.
+//
+// Returns:
+//   - []string: DDL statements
+//   - error: An error if any operation cannot be rendered
+func (p Patch) Statements() ([]string, error) {
+	statements := make([]string, 0, len(p.Operations))
+	for _, op := range p.Operations {
+		statement, err := SQL(op)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, statement)
+	}
+	return statements, nil
+}
+
+// WriteSQL writes the patch as a SQL script. The differences that cannot be reconciled
+// automatically are listed in comments at the top, so that they are not overlooked.
+//
+// Parameters:
+//   - w: Writer the script is written to
+//
+// Returns:
+//   - error: Any error that occurred while rendering or writing the script
+func (p Patch) WriteSQL(w io.Writer) error {
+	statements, err := p.Statements()
+	if err != nil {
+		return err
+	}
+
+	var script strings.Builder
+	if len(p.Unsupported) > 0 {
+		script.WriteString("-- The following differences must be reconciled manually:\n")
+		for _, diff := range p.Unsupported {
+			fmt.Fprintf(&script, "--   [%s] %s: %s\n", diff.Type, diff.ObjectName, strings.ReplaceAll(diff.Description, "\n", " "))
+		}
+		script.WriteString("\n")
+	}
+	for _, statement := range statements {
+		script.WriteString(statement)
+		script.WriteString("\n")
+	}
+
+	if _, err := io.WriteString(w, script.String()); err != nil {
+		return fmt.Errorf("error writing SQL script: %w", err)
+	}
+	return nil
+}
+
+// columnDefinition renders the definition of a column, as used by CREATE TABLE and ADD COLUMN.
+func columnDefinition(name, dataType string, nullable bool, defaultExpr string, identity bool) string {
+	def := ident(name) + " " + dataType
+	if identity {
+		def += " GENERATED BY DEFAULT AS IDENTITY"
+	} else if defaultExpr != "" {
+		def += " DEFAULT " + defaultExpr
+	}
+	if !nullable {
+		def += " NOT NULL"
+	}
+	return def
+}
+
+// primaryKeyDefinition renders a primary key constraint, named if the name is known.
+func primaryKeyDefinition(name string, columns []string) string {
+	def := fmt.Sprintf("PRIMARY KEY (%s)", identList(columns))
+	if name != "" {
+		def = "CONSTRAINT " + ident(name) + " " + def
+	}
+	return def
+}
+
+// indexDefinition renders what follows the table in CREATE INDEX: the access method unless it is
+// the default btree, the keys, with quoted columns and parenthesized expressions, the included
+// columns, and the predicate.
+func indexDefinition(idx schema.IndexInfo) string {
+	var def strings.Builder
+	if idx.Method != "" && idx.Method != "btree" {
+		def.WriteString(" USING " + idx.Method)
+	}
+
+	fmt.Fprintf(&def, " (%s)", strings.Join(idx.FormatKeys(ident), ", "))
+
+	if len(idx.Include) > 0 {
+		fmt.Fprintf(&def, " INCLUDE (%s)", identList(idx.Include))
+	}
+	if idx.Predicate != "" {
+		def.WriteString(" WHERE " + idx.Predicate)
+	}
+	return def.String()
+}
+
+// table renders a schema-qualified name.
+func table(schemaName, name string) string {
+	if schemaName == "" {
+		return ident(name)
+	}
+	return pgx.Identifier{schemaName, name}.Sanitize()
+}
+
+// ident renders a quoted identifier.
+func ident(name string) string {
+	return pgx.Identifier{name}.Sanitize()
+}
+
+// identList renders a comma-separated list of quoted identifiers.
+func identList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = ident(name)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,86 @@
+package plan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultLockKey is the key of the advisory lock taken while applying plans, unless another one
+// is configured: the bytes of "pgschchk".
+const DefaultLockKey int64 = 0x706773636863686b
+
+// ErrLocked is returned (wrapped) by Lock when another session holds the advisory lock, as while
+// another plan is being applied to the same database.
+var ErrLocked = errors.New("another apply holds the advisory lock")
+
+// Lock takes the transaction-level advisory lock with the given key, without waiting, so that
+// two applications of plans to the same database never run at the same time. The lock is held
+// until the transaction ends, and works through connection poolers pooling transactions, which
+// session-level locks do not.
+//
+// Parameters:
+//   - ctx: Context for the queries
+//   - tx: Transaction the statements of the plan are run in
+//   - key: Key of the advisory lock; every apply of a database must use the same one
+//
+// Returns:
+//   - error: An error wrapping ErrLocked, naming the session holding the lock when it is visible,
+//     if the lock is held elsewhere; or an error from the query
+func Lock(ctx context.Context, tx pgx.Tx, key int64) error {
+	var acquired bool
+	if err := tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1)", key).Scan(&acquired); err != nil {
+		return fmt.Errorf("error taking advisory lock %d: %w", key, err)
+	}
+	if acquired {
+		return nil
+	}
+	if holder := lockHolder(ctx, tx, key); holder != "" {
+		return fmt.Errorf("%w (key %d), held by %s", ErrLocked, key, holder)
+	}
+	return fmt.Errorf("%w (key %d)", ErrLocked, key)
+}
+
+// lockHolder describes the session holding an advisory lock, from pg_locks and
+// pg_stat_activity. Bigint keys are split into the classid (high 32 bits) and objid (low 32 bits)
+// of pg_locks, with an objsubid of 1.
+//
+// Parameters:
+//   - ctx: Context for the query
+//   - tx: Transaction to query in
+//   - key: Key of the advisory lock
+//
+// Returns:
+//   - string: Process ID, application, client address, and start of the transaction of the
+//     holder, or an empty string if they cannot be read
+func lockHolder(ctx context.Context, tx pgx.Tx, key int64) string {
+	var pid int
+	var application, client string
+	var since *time.Time
+	err := tx.QueryRow(ctx, `
+		SELECT l.pid, COALESCE(a.application_name, ''), COALESCE(host(a.client_addr), ''), a.xact_start
+		FROM pg_locks l
+		LEFT JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory' AND l.granted AND l.objsubid = 1
+		  AND l.classid::bigint = $1 AND l.objid::bigint = $2
+		LIMIT 1`, int64(uint64(key)>>32), int64(uint64(key)&0xffffffff)).Scan(&pid, &application, &client, &since)
+	if err != nil {
+		return ""
+	}
+
+	details := []string{fmt.Sprintf("process %d", pid)}
+	if application != "" {
+		details = append(details, "application "+application)
+	}
+	if client != "" {
+		details = append(details, "from "+client)
+	}
+	if since != nil {
+		details = append(details, "since "+since.Format(time.RFC3339))
+	}
+	return strings.Join(details, ", ")
+}
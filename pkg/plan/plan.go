@@ -0,0 +1,225 @@
+// Package plan provides functionality to save the changes reconciling two schemas to a plan file
+// and to check, before applying them, that the database they change is still as it was when the
+// plan was made.
+//
+// A plan captures the differences found, the statements generated to reconcile them, and the
+// checksum of the schema being changed (see schema.Schema.Checksum). Applying a plan runs exactly
+// the statements it holds, which were reviewed when it was made, and only after Verify has found
+// the schema unchanged, so that changes made since then are never overwritten by a stale plan.
+//
+// Plans are written in a binary (gob) format prefixed with a magic number and a format version,
+// which is checked when they are read, as for binary snapshots.
+package plan
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/audit"
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/patch"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// FormatVersion is the version of the plan format written by this release.
+const FormatVersion = 1
+
+// ErrUnsupportedVersion is returned (wrapped) when a plan has a format version that this release
+// cannot read, either because it is missing or because it was written by a newer release.
+var ErrUnsupportedVersion = errors.New("unsupported plan format version")
+
+// ErrStale is returned (wrapped) by Verify when the schema a plan changes is no longer the one
+// the plan was made against.
+var ErrStale = errors.New("plan is stale")
+
+// magic prefixes every plan file, so that other files passed as a plan are rejected.
+var magic = []byte("PGSCPLAN")
+
+// Sides of the comparison a plan can change.
+const (
+	SideSource = "source" // The source is changed to match the target (direction target-to-source)
+	SideTarget = "target" // The target is changed to match the source
+)
+
+// Plan is the set of changes reconciling two schemas, as saved to plan files.
+type Plan struct {
+	FormatVersion int                  // Version of the plan format
+	CreatedAt     time.Time            // When the plan was made
+	CreatedBy     string               // Operating system user who made the plan
+	Host          string               // Host the plan was made on
+	Environment   string               // Environment of the configuration file the databases came from, if any
+	Source        string               // Source of the comparison, with its password masked
+	Target        string               // Target of the comparison, with its password masked
+	Direction     string               // Direction of the comparison (see compare.Options.Direction)
+	IncludeTables []string             // Patterns of the tables the schemas were read with
+	ExcludeTables []string             // Patterns of the tables left out when reading the schemas
+	BeforeHash    string               // Checksum of the schema of the side changed, as read when the plan was made
+	Differences   []compare.Difference // Differences found by the comparison
+	Statements    []string             // Statements reconciling the differences, in the order they must be run
+	Unsupported   []compare.Difference // Differences that must be reconciled manually
+}
+
+// New makes the plan of a patch, by the current user on this host. The connection strings of the
+// source and target must already have their passwords masked, as they are saved in the plan.
+//
+// Parameters:
+//   - differences: Differences the patch was built from
+//   - p: Patch reconciling the differences
+//   - beforeHash: Checksum of the schema of the side changed by the patch, as read from the
+//     database with the table patterns of the plan and before any other filter, so that reading it
+//     the same way when applying the plan gives the same checksum
+//
+// Returns:
+//   - *Plan: Plan with everything but the environment, the databases, the direction, and the
+//     table patterns set
+//   - error: An error if an operation of the patch cannot be rendered as a statement
+func New(differences compare.DiffResult, p patch.Patch, beforeHash string) (*Plan, error) {
+	statements, err := p.Statements()
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		CreatedBy:     audit.CurrentUser(),
+		BeforeHash:    beforeHash,
+		Differences:   differences,
+		Statements:    statements,
+		Unsupported:   p.Unsupported,
+	}
+	plan.Host, _ = os.Hostname()
+	return plan, nil
+}
+
+// ChangedSide returns the side of the comparison the plan changes.
+//
+// Returns:
+//   - string: SideSource with direction target-to-source, SideTarget otherwise
+func (p *Plan) ChangedSide() string {
+	if p.Direction == compare.DirectionTargetToSource {
+		return SideSource
+	}
+	return SideTarget
+}
+
+// Database returns the database the plan changes, with its password masked.
+//
+// Returns:
+//   - string: Source or target of the plan, as ChangedSide tells
+func (p *Plan) Database() string {
+	if p.ChangedSide() == SideSource {
+		return p.Source
+	}
+	return p.Target
+}
+
+// Verify checks that a schema is still the one the plan was made against.
+//
+// Parameters:
+//   - current: Schema of the side changed, read the way the schema of the plan was (see New)
+//
+// Returns:
+//   - error: An error wrapping ErrStale if the schema changed since the plan was made
+func (p *Plan) Verify(current *schema.Schema) error {
+	if hash := current.Checksum(); hash != p.BeforeHash {
+		return fmt.Errorf("%w: the %s changed since the plan was made on %s (schema checksum %.12s, planned against %.12s)",
+			ErrStale, p.ChangedSide(), p.CreatedAt.Format(time.RFC3339), hash, p.BeforeHash)
+	}
+	return nil
+}
+
+// Write encodes a plan.
+//
+// Parameters:
+//   - w: Writer the plan is written to
+//   - p: Plan to save
+//
+// Returns:
+//   - error: Any error that occurred while encoding or writing the plan
+func Write(w io.Writer, p *Plan) error {
+	if _, err := w.Write(magic); err != nil {
+		return fmt.Errorf("error writing plan: %w", err)
+	}
+	if err := gob.NewEncoder(w).Encode(p); err != nil {
+		return fmt.Errorf("error encoding plan: %w", err)
+	}
+	return nil
+}
+
+// Read decodes a plan.
+//
+// Parameters:
+//   - r: Reader the plan is read from
+//
+// Returns:
+//   - *Plan: Decoded plan
+//   - error: An error if the data is not a plan, is malformed, or uses an unsupported format
+//     version (wrapping ErrUnsupportedVersion)
+func Read(r io.Reader) (*Plan, error) {
+	buffered := bufio.NewReader(r)
+	header, _ := buffered.Peek(len(magic))
+	if !bytes.Equal(header, magic) {
+		return nil, fmt.Errorf("error reading plan: not a plan file")
+	}
+	if _, err := buffered.Discard(len(magic)); err != nil {
+		return nil, fmt.Errorf("error reading plan: %w", err)
+	}
+
+	var p Plan
+	if err := gob.NewDecoder(buffered).Decode(&p); err != nil {
+		return nil, fmt.Errorf("error decoding plan: %w", err)
+	}
+	switch {
+	case p.FormatVersion <= 0:
+		return nil, fmt.Errorf("%w: plan has no format version", ErrUnsupportedVersion)
+	case p.FormatVersion > FormatVersion:
+		return nil, fmt.Errorf("%w: version %d was written by a newer release (this release reads up to version %d)", ErrUnsupportedVersion, p.FormatVersion, FormatVersion)
+	}
+	return &p, nil
+}
+
+// WriteFile saves a plan to a file.
+//
+// Parameters:
+//   - path: Path of the plan file to create
+//   - p: Plan to save
+//
+// Returns:
+//   - error: Any error that occurred while writing the file
+func WriteFile(path string, p *Plan) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating plan file: %w", err)
+	}
+	if err := Write(file, p); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error writing plan file: %w", err)
+	}
+	return nil
+}
+
+// ReadFile loads a plan from a file.
+//
+// Parameters:
+//   - path: Path of the plan file
+//
+// Returns:
+//   - *Plan: Loaded plan
+//   - error: An error if the file cannot be read or is not a valid plan
+func ReadFile(path string) (*Plan, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening plan file: %w", err)
+	}
+	defer file.Close()
+	return Read(file)
+}
@@ -0,0 +1,79 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+)
+
+// GitLabCodeQuality is a compare.Renderer producing a GitLab Code Quality report: a JSON array
+// of issues that GitLab shows in the merge request widget when a CI job publishes it as its
+// codequality report artifact.
+type GitLabCodeQuality struct {
+	Path string // File the issues are reported on; empty reports each on its schema and table (e.g., public/orders)
+}
+
+// codeQualityIssue is an issue of a GitLab Code Quality report.
+type codeQualityIssue struct {
+	Description string              `json:"description"` // Description shown in the widget
+	CheckName   string              `json:"check_name"`  // Type of the difference
+	Fingerprint string              `json:"fingerprint"` // Identifier telling the issue apart across pipelines
+	Severity    string              `json:"severity"`    // Severity: info, minor, major, critical, or blocker
+	Location    codeQualityLocation `json:"location"`    // Where the issue is reported
+}
+
+// codeQualityLocation is the location of an issue of a GitLab Code Quality report.
+type codeQualityLocation struct {
+	Path  string `json:"path"` // File of the issue
+	Lines struct {
+		Begin int `json:"begin"` // First line of the issue
+	} `json:"lines"`
+}
+
+// codeQualitySeverities maps the severities of differences to those of GitLab.
+var codeQualitySeverities = map[string]string{
+	compare.SeverityError:   "major",
+	compare.SeverityWarning: "minor",
+	compare.SeverityInfo:    "info",
+}
+
+// Render writes the differences to w as a GitLab Code Quality report. The fingerprint of an
+// issue depends on its type, schema, object, and sub-object only, so that GitLab recognizes the
+// same difference in the reports of the source and target branches of a merge request, even if
+// the values that differ changed.
+func (g GitLabCodeQuality) Render(differences compare.DiffResult, w io.Writer) error {
+	issues := make([]codeQualityIssue, 0, len(differences))
+	for _, diff := range differences {
+		issue := codeQualityIssue{
+			Description: diff.Description,
+			CheckName:   diff.Type,
+			Fingerprint: codeQualityFingerprint(diff),
+			Severity:    codeQualitySeverities[diff.Severity],
+		}
+		if issue.Severity == "" {
+			issue.Severity = "major"
+		}
+		issue.Location.Path = g.Path
+		if issue.Location.Path == "" {
+			issue.Location.Path = diff.SchemaName + "/" + subject(diff)
+			if diff.SchemaName == "" {
+				issue.Location.Path = subject(diff)
+			}
+		}
+		issue.Location.Lines.Begin = 1
+		issues = append(issues, issue)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(issues)
+}
+
+// codeQualityFingerprint returns the fingerprint of a difference in a GitLab Code Quality report.
+func codeQualityFingerprint(diff compare.Difference) string {
+	sum := sha256.Sum256([]byte(diff.SchemaName + "\x00" + diff.ObjectName + "\x00" + diff.Key()))
+	return hex.EncodeToString(sum[:16])
+}
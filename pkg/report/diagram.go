@@ -0,0 +1,222 @@
+package report
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// SchemaRenderer is implemented by the output formats that also draw the schemas the
+// differences were found in, such as the diagrams of Dot and Mermaid. Callers holding the
+// schemas pass them with WithSchemas before rendering; without them, such formats render what
+// the differences alone tell.
+type SchemaRenderer interface {
+	compare.Renderer
+
+	// WithSchemas returns a renderer drawing the given schemas, either of which may be nil.
+	WithSchemas(source, target *schema.Schema) compare.Renderer
+}
+
+// Statuses of the tables, lines, and relationships of a diagram.
+const (
+	statusSame    = ""        // Identical on both sides
+	statusAdded   = "added"   // In the source only: missing from the target
+	statusRemoved = "removed" // In the target only: extra in the target
+	statusChanged = "changed" // On both sides, with differences
+)
+
+// diagram is the graph of the tables drawn by Dot and Mermaid: the tables with differences, the
+// tables related to them by foreign keys, and those foreign keys.
+type diagram struct {
+	tables []diagramTable // Tables drawn, sorted by name
+	edges  []diagramEdge  // Foreign keys drawn, sorted by table and name
+}
+
+// diagramTable is a table of a diagram.
+type diagramTable struct {
+	name   string        // Name of the table
+	status string        // Status of the table
+	lines  []diagramLine // Objects of the table that differ, in report order
+}
+
+// diagramLine is an object of a table of a diagram that differs (e.g., a column missing from the target).
+type diagramLine struct {
+	status string // Status of the object
+	text   string // Description of the object (e.g., "column status text")
+}
+
+// diagramEdge is a foreign key of a diagram.
+type diagramEdge struct {
+	from   string // Table of the foreign key
+	to     string // Table referenced
+	name   string // Name of the foreign key
+	status string // Status of the foreign key
+}
+
+// marker returns the sign a status is written with in the lines of a diagram.
+func marker(status string) string {
+	switch status {
+	case statusAdded:
+		return "+"
+	case statusRemoved:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// diffStatus returns the status of the object a difference is about.
+func diffStatus(diff compare.Difference) string {
+	switch {
+	case strings.HasPrefix(diff.Type, "Missing"):
+		return statusAdded
+	case strings.HasPrefix(diff.Type, "Extra"):
+		return statusRemoved
+	default:
+		return statusChanged
+	}
+}
+
+// buildDiagram works out the graph of the differences. Differences that are not about a table,
+// such as those about server features, are left out. Foreign keys are only drawn when the
+// schemas are known, as the differences do not tell which table each references.
+//
+// Parameters:
+//   - differences: Differences to draw
+//   - source: Source schema the differences were found in, or nil
+//   - target: Target schema the differences were found in, or nil
+//
+// Returns:
+//   - diagram: Graph of the tables with differences and of those related to them
+func buildDiagram(differences compare.DiffResult, source, target *schema.Schema) diagram {
+	tables := make(map[string]*diagramTable)
+	table := func(name string) *diagramTable {
+		if tables[name] == nil {
+			tables[name] = &diagramTable{name: name}
+		}
+		return tables[name]
+	}
+
+	// Objects that differ, with the types of their differences when they only changed
+	type object struct{ table, kind, name string }
+	changes := make(map[object][]string)
+	var order []object
+	changedKeys := make(map[string]bool)
+	for _, diff := range differences {
+		if diff.Table == "" {
+			continue
+		}
+		t := table(diff.Table)
+		status := diffStatus(diff)
+		if diff.SubObject == "" && (diff.Type == "MissingTable" || diff.Type == "ExtraTable" || diff.Type == "MissingView" || diff.Type == "ExtraView" ||
+			diff.Type == "MissingMaterializedView" || diff.Type == "ExtraMaterializedView") {
+			t.status = status
+			continue
+		}
+		if t.status == statusSame {
+			t.status = statusChanged
+		}
+		if diff.ObjectKind == compare.KindForeignKey {
+			changedKeys[diff.Table+"\x00"+diff.SubObject] = true
+		}
+		if status != statusChanged {
+			text := strings.TrimSpace(strings.ReplaceAll(diff.ObjectKind, "_", " ") + " " + diff.SubObject)
+			if colType := columnType(diff, source, target); colType != "" {
+				text += " " + colType
+			}
+			t.lines = append(t.lines, diagramLine{status: status, text: text})
+			continue
+		}
+		key := object{diff.Table, diff.ObjectKind, diff.SubObject}
+		if diff.SubObject == "" {
+			key.kind = ""
+		}
+		if _, seen := changes[key]; !seen {
+			order = append(order, key)
+		}
+		changes[key] = append(changes[key], diff.Type)
+	}
+	for _, key := range order {
+		text := strings.Join(changes[key], ", ")
+		if key.name != "" {
+			text = strings.ReplaceAll(key.kind, "_", " ") + " " + key.name + ": " + text
+		}
+		t := tables[key.table]
+		t.lines = append(t.lines, diagramLine{status: statusChanged, text: text})
+	}
+
+	// Foreign keys touching a table with differences, and the tables they relate it to
+	var d diagram
+	if source != nil || target != nil {
+		type fk struct{ from, name string }
+		referenced := make(map[fk]string)
+		sides := make(map[fk]int)
+		for side, s := range []*schema.Schema{source, target} {
+			if s == nil {
+				continue
+			}
+			for name, t := range s.Tables {
+				for _, key := range t.ForeignKeys {
+					k := fk{name, key.Name}
+					referenced[k] = key.ReferencedTable
+					sides[k] |= 1 << side
+				}
+			}
+		}
+		for k, to := range referenced {
+			status := statusSame
+			switch {
+			case source != nil && target != nil && sides[k] == 1:
+				status = statusAdded
+			case source != nil && target != nil && sides[k] == 2:
+				status = statusRemoved
+			case changedKeys[k.from+"\x00"+k.name]:
+				status = statusChanged
+			}
+			if status == statusSame && tables[k.from] == nil && tables[to] == nil {
+				continue
+			}
+			d.edges = append(d.edges, diagramEdge{from: k.from, to: to, name: k.name, status: status})
+		}
+		for _, edge := range d.edges {
+			table(edge.from)
+			table(edge.to)
+		}
+		sort.Slice(d.edges, func(i, j int) bool {
+			if d.edges[i].from != d.edges[j].from {
+				return d.edges[i].from < d.edges[j].from
+			}
+			return d.edges[i].name < d.edges[j].name
+		})
+	}
+
+	for _, t := range tables {
+		d.tables = append(d.tables, *t)
+	}
+	sort.Slice(d.tables, func(i, j int) bool { return d.tables[i].name < d.tables[j].name })
+	return d
+}
+
+// columnType returns the type of the column a difference about a column missing or extra is
+// about, from the schema holding it, or empty if unknown.
+func columnType(diff compare.Difference, source, target *schema.Schema) string {
+	s := source
+	switch diff.Type {
+	case "MissingColumn":
+	case "ExtraColumn":
+		s = target
+	default:
+		return ""
+	}
+	if s == nil {
+		return ""
+	}
+	for _, col := range s.Tables[diff.Table].Columns {
+		if col.Name == diff.SubObject {
+			return col.Type
+		}
+	}
+	return ""
+}
@@ -0,0 +1,79 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// diagramColors are the colors of the statuses in the diagrams: the color of the lines and
+// relationships, and the fill color of the tables.
+var diagramColors = map[string]struct{ line, fill string }{
+	statusSame:    {"#57606a", "#f6f8fa"},
+	statusAdded:   {"#1a7f37", "#dafbe1"},
+	statusRemoved: {"#cf222e", "#ffebe9"},
+	statusChanged: {"#9a6700", "#fff8c5"},
+}
+
+// Dot is a compare.Renderer and SchemaRenderer producing a Graphviz graph of the differences,
+// to be rendered with dot (e.g., dot -Tsvg). It draws the tables with differences, listing the
+// objects of each that differ, and, when the schemas are known, the tables related to them by
+// foreign keys and those foreign keys, colored by status: green for objects missing from the
+// target, red for objects extra in the target, and yellow for objects that differ.
+type Dot struct {
+	Source *schema.Schema // Source schema the differences were found in, or nil
+	Target *schema.Schema // Target schema the differences were found in, or nil
+}
+
+// WithSchemas returns a Dot renderer drawing the given schemas.
+func (Dot) WithSchemas(source, target *schema.Schema) compare.Renderer {
+	return Dot{Source: source, Target: target}
+}
+
+// Render writes the graph of the differences to w in the DOT language.
+func (g Dot) Render(differences compare.DiffResult, w io.Writer) error {
+	d := buildDiagram(differences, g.Source, g.Target)
+
+	var b strings.Builder
+	b.WriteString("digraph schema_diff {\n")
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=plaintext, fontname=\"Helvetica\"];\n")
+	b.WriteString("\tedge [fontname=\"Helvetica\", fontsize=10];\n")
+	if len(d.tables) == 0 {
+		b.WriteString("\tlabel=\"No differences found between the schemas.\";\n")
+	} else {
+		b.WriteString("\tlabel=<<font color=\"#1a7f37\">+ missing in target</font>&nbsp;&nbsp;<font color=\"#cf222e\">- extra in target</font>&nbsp;&nbsp;<font color=\"#9a6700\">~ different</font>>;\n")
+	}
+
+	for _, t := range d.tables {
+		colors := diagramColors[t.status]
+		fmt.Fprintf(&b, "\t%s [label=<<table border=\"1\" cellborder=\"0\" cellspacing=\"0\" cellpadding=\"4\" color=%q>", dotID(t.name), colors.line)
+		fmt.Fprintf(&b, "<tr><td bgcolor=%q><b>%s</b></td></tr>", colors.fill, html.EscapeString(t.name))
+		for _, line := range t.lines {
+			fmt.Fprintf(&b, "<tr><td align=\"left\"><font color=%q>%s %s</font></td></tr>",
+				diagramColors[line.status].line, marker(line.status), html.EscapeString(line.text))
+		}
+		b.WriteString("</table>>];\n")
+	}
+	for _, edge := range d.edges {
+		style := "solid"
+		if edge.status == statusRemoved {
+			style = "dashed"
+		}
+		fmt.Fprintf(&b, "\t%s -> %s [label=%s, color=%q, fontcolor=%q, style=%s];\n",
+			dotID(edge.from), dotID(edge.to), dotID(edge.name), diagramColors[edge.status].line, diagramColors[edge.status].line, style)
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotID quotes a name as a DOT identifier.
+func dotID(name string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(name) + `"`
+}
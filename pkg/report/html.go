@@ -0,0 +1,51 @@
+package report
+
+import (
+	"html/template"
+	"io"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+)
+
+// htmlTemplate is the page written by HTML. html/template escapes every value, so object
+// names and comments cannot inject markup into the page.
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Schema comparison report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { background: #eee; }
+.error { color: #b00020; }
+.warning { color: #b26a00; }
+.info { color: #00589b; }
+</style>
+</head>
+<body>
+<h1>Schema comparison report</h1>
+{{- if not .Differences}}
+<p>No differences found between the schemas.</p>
+{{- else}}
+<p>Found {{len .Differences}} differences.</p>
+<table>
+<tr><th>Severity</th><th>Type</th><th>Table</th><th>Description</th></tr>
+{{- range .Differences}}
+<tr><td class="{{.Severity}}">{{.Severity}}</td><td>{{.Type}}</td><td>{{.Table}}</td><td>{{.Description}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+// HTML is a compare.Renderer producing a standalone HTML page, suitable for publishing as a
+// CI artifact.
+type HTML struct{}
+
+// Render writes the differences to w as an HTML page.
+func (HTML) Render(differences compare.DiffResult, w io.Writer) error {
+	return htmlTemplate.Execute(w, struct{ Differences compare.DiffResult }{differences})
+}
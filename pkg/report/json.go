@@ -0,0 +1,39 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+)
+
+// JSON is a compare.Renderer producing a machine-readable report, for consumption by CI
+// pipelines and other tooling.
+type JSON struct {
+	Indent bool // Whether to indent the output for readability
+}
+
+// jsonReport is the document written by JSON.
+type jsonReport struct {
+	Count       int                  `json:"count"`       // Total number of differences
+	Summary     map[string]int       `json:"summary"`     // Number of differences keyed by severity level
+	Differences []compare.Difference `json:"differences"` // Differences, in report order
+}
+
+// Render writes the differences to w as a single JSON document.
+func (j JSON) Render(differences compare.DiffResult, w io.Writer) error {
+	doc := jsonReport{
+		Count:       len(differences),
+		Summary:     differences.CountBySeverity(),
+		Differences: differences,
+	}
+	if doc.Differences == nil {
+		doc.Differences = []compare.Difference{}
+	}
+
+	encoder := json.NewEncoder(w)
+	if j.Indent {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder.Encode(doc)
+}
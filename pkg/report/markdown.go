@@ -0,0 +1,41 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+)
+
+// Markdown is a compare.Renderer producing a Markdown document with a table of the differences,
+// suitable for emails, pull request comments, and wikis.
+type Markdown struct{}
+
+// Render writes the differences to w as a Markdown document.
+func (Markdown) Render(differences compare.DiffResult, w io.Writer) error {
+	if len(differences) == 0 {
+		_, err := fmt.Fprint(w, "# Schema comparison report\n\nNo differences found between the schemas.\n")
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "# Schema comparison report\n\nFound %d differences.\n\n| Severity | Type | Table | Description |\n|----------|------|-------|-------------|\n", len(differences)); err != nil {
+		return err
+	}
+	for _, diff := range differences {
+		if _, err := fmt.Fprintf(w, "| %s | %s | %s | %s |\n", markdownCell(diff.Severity), markdownCell(diff.Type), markdownCell(subject(diff)), markdownCell(diff.Description)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markdownEscaper escapes the characters that would end a table cell or be read as markup.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, `|`, `\|`, "`", "\\`", `*`, `\*`, `_`, `\_`, `<`, `&lt;`, "\r\n", " ", "\n", " ",
+)
+
+// markdownCell escapes a value for a cell of a Markdown table.
+func markdownCell(value string) string {
+	return markdownEscaper.Replace(value)
+}
@@ -0,0 +1,85 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// Mermaid is a compare.Renderer and SchemaRenderer producing a Mermaid flowchart of the
+// differences, which GitHub, GitLab, and many wikis render in Markdown inside a mermaid code
+// block. It draws the same graph as Dot, with the same colors.
+type Mermaid struct {
+	Source *schema.Schema // Source schema the differences were found in, or nil
+	Target *schema.Schema // Target schema the differences were found in, or nil
+}
+
+// WithSchemas returns a Mermaid renderer drawing the given schemas.
+func (Mermaid) WithSchemas(source, target *schema.Schema) compare.Renderer {
+	return Mermaid{Source: source, Target: target}
+}
+
+// Render writes the flowchart of the differences to w.
+func (m Mermaid) Render(differences compare.DiffResult, w io.Writer) error {
+	d := buildDiagram(differences, m.Source, m.Target)
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	if len(d.tables) == 0 {
+		b.WriteString("\tnone[\"No differences found between the schemas.\"]\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	// Tables are named by their position, as table names may hold any character
+	ids := make(map[string]string, len(d.tables))
+	for i, t := range d.tables {
+		ids[t.name] = fmt.Sprintf("t%d", i)
+		label := "<b>" + mermaidText(t.name) + "</b>"
+		for _, line := range t.lines {
+			label += fmt.Sprintf("<br/><span style='color:%s'>%s %s</span>", diagramColors[line.status].line, marker(line.status), mermaidText(line.text))
+		}
+		fmt.Fprintf(&b, "\t%s[\"%s\"]\n", ids[t.name], label)
+	}
+	for _, edge := range d.edges {
+		arrow := "-->"
+		if edge.status == statusRemoved {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "\t%s %s|\"%s\"| %s\n", ids[edge.from], arrow, mermaidText(edge.name), ids[edge.to])
+	}
+
+	for _, status := range []string{statusSame, statusAdded, statusRemoved, statusChanged} {
+		colors := diagramColors[status]
+		fmt.Fprintf(&b, "\tclassDef %s fill:%s,stroke:%s\n", mermaidClass(status), colors.fill, colors.line)
+	}
+	for _, t := range d.tables {
+		fmt.Fprintf(&b, "\tclass %s %s\n", ids[t.name], mermaidClass(t.status))
+	}
+	for i, edge := range d.edges {
+		fmt.Fprintf(&b, "\tlinkStyle %d stroke:%s,color:%s\n", i, diagramColors[edge.status].line, diagramColors[edge.status].line)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidClass returns the name of the class of the tables of a status.
+func mermaidClass(status string) string {
+	if status == statusSame {
+		return "same"
+	}
+	return status
+}
+
+// mermaidEscaper escapes the characters that would end a label or be read as markup, with the
+// entity codes of Mermaid.
+var mermaidEscaper = strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;", "\n", " ")
+
+// mermaidText escapes text for a label of a flowchart.
+func mermaidText(text string) string {
+	return mermaidEscaper.Replace(text)
+}
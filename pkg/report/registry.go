@@ -0,0 +1,86 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+)
+
+// DefaultFormat is the output format used when no other format is selected.
+const DefaultFormat = "text"
+
+var (
+	registryMu sync.RWMutex                    // Guards renderers
+	renderers  = map[string]compare.Renderer{} // Registered output formats keyed by name
+)
+
+func init() {
+	Register("text", Text{})
+	Register("json", JSON{Indent: true})
+	Register("html", HTML{})
+	Register("markdown", Markdown{})
+	Register("gitlab-codequality", GitLabCodeQuality{})
+	Register("dot", Dot{})
+	Register("mermaid", Mermaid{})
+}
+
+// Register makes an output format available under the given name, so that it can be selected
+// with Lookup (and with the --format flag of the CLI) without modifying the CLI. It panics if
+// the name is empty or already registered, which indicates a programming error.
+//
+// Parameters:
+//   - name: Name the format is selected by (e.g., "markdown")
+//   - renderer: Renderer implementing the format
+func Register(name string, renderer compare.Renderer) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if name == "" {
+		panic("report: Register called with an empty format name")
+	}
+	if _, exists := renderers[name]; exists {
+		panic(fmt.Sprintf("report: format '%s' is already registered", name))
+	}
+	renderers[name] = renderer
+}
+
+// Lookup returns the renderer registered under the given name.
+//
+// Parameters:
+//   - name: Name of the output format
+//
+// Returns:
+//   - compare.Renderer: Renderer implementing the format
+//   - error: An error listing the available formats if the name is not registered
+func Lookup(name string) (compare.Renderer, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	renderer, exists := renderers[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown output format '%s' (available: %v)", name, formatsLocked())
+	}
+	return renderer, nil
+}
+
+// Formats returns the names of the registered output formats, sorted.
+//
+// Returns:
+//   - []string: Sorted format names
+func Formats() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return formatsLocked()
+}
+
+// formatsLocked returns the sorted format names. The caller must hold registryMu.
+func formatsLocked() []string {
+	names := make([]string, 0, len(renderers))
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,66 @@
+// Package report provides functionality to render the differences found by a schema comparison
+// in a human-readable form.
+//
+// The exported types and functions of this package are part of the supported API (see the root
+// schemacheck package), and so are the names of the formats and the fields of the JSON format;
+// the wording of the text formats may change in any release.
+package report
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+)
+
+// Render writes a human-readable report of the differences to w, one line per difference.
+// It is equivalent to rendering the differences with Text.
+//
+// Parameters:
+//   - w: Writer the report is written to
+//   - differences: Differences to report
+//
+// Returns:
+//   - error: Any error that occurred while writing the report
+func Render(w io.Writer, differences []compare.Difference) error {
+	return Text{}.Render(differences, w)
+}
+
+// Text is a compare.Renderer producing the human-readable report printed by the CLI.
+type Text struct{}
+
+// Render writes a human-readable report of the differences to w, one line per difference.
+// Differences resulting from another difference are indented under it.
+func (Text) Render(differences compare.DiffResult, w io.Writer) error {
+	if len(differences) == 0 {
+		_, err := fmt.Fprintln(w, "No differences found between the schemas.")
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "Found %d differences:\n\n", len(differences)); err != nil {
+		return err
+	}
+	// Differences resulting from another one are listed under it
+	children := differences.ChildrenByParent()
+	for _, diff := range differences.TopLevel() {
+		if _, err := fmt.Fprintf(w, "[%s] [%s] %s: %s\n", diff.Severity, diff.Type, subject(diff), diff.Description); err != nil {
+			return err
+		}
+		for _, child := range children[diff.Key()] {
+			if _, err := fmt.Fprintf(w, "    [%s] [%s] %s: %s\n", child.Severity, child.Type, subject(child), child.Description); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// subject returns what a line of the text report is about: the table of the difference, or the
+// object itself for differences that are not about a table (e.g., server features).
+func subject(diff compare.Difference) string {
+	if diff.Table == "" {
+		return diff.ObjectName
+	}
+	return diff.Table
+}
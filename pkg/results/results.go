@@ -0,0 +1,270 @@
+// Package results provides functionality to keep the latest result of each configured
+// comparison and the history of its runs, so that the comparison service can return them
+// without running the comparison again, and reports can be downloaded after the fact.
+package results
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+)
+
+// Result is the outcome of a run of a configured comparison.
+type Result struct {
+	Pair        string             `json:"pair"`            // Name of the comparison, such as its environment
+	Started     time.Time          `json:"started"`         // When the run started
+	Duration    time.Duration      `json:"duration"`        // Time the run took, in nanoseconds
+	Summary     map[string]int     `json:"summary"`         // Number of differences of each severity
+	Differences compare.DiffResult `json:"differences"`     // Differences found
+	Error       string             `json:"error,omitempty"` // Error the run failed with, if it failed
+}
+
+// Entry is a result without its differences, as kept in the history of a comparison.
+type Entry struct {
+	Started     time.Time      `json:"started"`         // When the run started
+	Duration    time.Duration  `json:"duration"`        // Time the run took, in nanoseconds
+	Summary     map[string]int `json:"summary"`         // Number of differences of each severity
+	Differences int            `json:"differences"`     // Number of differences
+	Error       string         `json:"error,omitempty"` // Error the run failed with, if it failed
+}
+
+// Entry returns the result without its differences.
+//
+// Returns:
+//   - Entry: Summary of the result
+func (r Result) Entry() Entry {
+	return Entry{Started: r.Started, Duration: r.Duration, Summary: r.Summary, Differences: len(r.Differences), Error: r.Error}
+}
+
+// maxMemoryHistory is the number of entries kept in the history of each comparison by stores
+// without a directory.
+const maxMemoryHistory = 1000
+
+// Store holds the latest result of each comparison and the history of its runs, in files when
+// it has a directory, so that results outlive the process and can be shared between the daemon
+// and the comparison service, and in memory otherwise. Each comparison has a JSON file of its
+// latest result and a JSON lines file of its history, one entry per run. It is safe for
+// concurrent use.
+type Store struct {
+	dir     string             // Directory of the result files; empty keeps results in memory only
+	mu      sync.Mutex         // Protects latest and history, and appends to history files
+	latest  map[string]Result  // Latest result of each comparison, keyed by name, without a directory
+	history map[string][]Entry // Latest entries of the history of each comparison, without a directory
+}
+
+// OpenStore opens a store.
+//
+// Parameters:
+//   - dir: Directory of the result files, created if needed; empty keeps results in memory only
+//
+// Returns:
+//   - *Store: Open store
+//   - error: Any error that occurred while creating the directory
+func OpenStore(dir string) (*Store, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("error creating results directory: %w", err)
+		}
+	}
+	return &Store{dir: dir, latest: make(map[string]Result), history: make(map[string][]Entry)}, nil
+}
+
+// Save records a result as the latest of its comparison, and adds it to its history.
+//
+// Parameters:
+//   - result: Result to record
+//
+// Returns:
+//   - error: Any error that occurred while writing the result files
+func (s *Store) Save(result Result) error {
+	if s.dir == "" {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.latest[result.Pair] = result
+		history := append(s.history[result.Pair], result.Entry())
+		if len(history) > maxMemoryHistory {
+			history = history[len(history)-maxMemoryHistory:]
+		}
+		s.history[result.Pair] = history
+		return nil
+	}
+	if err := s.appendHistory(result); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("error encoding result: %w", err)
+	}
+	// The file is replaced at once, so that readers in other processes never see half of it
+	tmp, err := os.CreateTemp(s.dir, ".result-*")
+	if err != nil {
+		return fmt.Errorf("error writing result: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing result: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing result: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, fileName(result.Pair))); err != nil {
+		return fmt.Errorf("error writing result: %w", err)
+	}
+	return nil
+}
+
+// Latest returns the latest result of a comparison, as last saved by any process sharing the
+// directory.
+//
+// Parameters:
+//   - pair: Name of the comparison
+//
+// Returns:
+//   - Result: Latest result
+//   - bool: False if the comparison has no result yet
+//   - error: Any error that occurred while reading the result file
+func (s *Store) Latest(pair string) (Result, bool, error) {
+	if s.dir == "" {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		result, ok := s.latest[pair]
+		return result, ok, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.dir, fileName(pair)))
+	if errors.Is(err, os.ErrNotExist) {
+		return Result{}, false, nil
+	}
+	if err != nil {
+		return Result{}, false, fmt.Errorf("error reading result: %w", err)
+	}
+	var result Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		return Result{}, false, fmt.Errorf("error parsing result of %s: %w", pair, err)
+	}
+	return result, true, nil
+}
+
+// appendHistory adds a result to the history file of its comparison.
+//
+// Parameters:
+//   - result: Result to add
+//
+// Returns:
+//   - error: Any error that occurred while writing the history file
+func (s *Store) appendHistory(result Result) error {
+	line, err := json.Marshal(result.Entry())
+	if err != nil {
+		return fmt.Errorf("error encoding history: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, err := os.OpenFile(filepath.Join(s.dir, historyName(result.Pair)), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("error opening history: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error writing history: %w", err)
+	}
+	return nil
+}
+
+// History returns the latest entries of the history of a comparison, oldest first.
+//
+// Parameters:
+//   - pair: Name of the comparison
+//   - limit: Maximum number of entries returned; zero or negative returns all of them
+//
+// Returns:
+//   - []Entry: Entries of the history
+//   - error: Any error that occurred while reading the history file
+func (s *Store) History(pair string, limit int) ([]Entry, error) {
+	var entries []Entry
+	if s.dir == "" {
+		s.mu.Lock()
+		entries = append(entries, s.history[pair]...)
+		s.mu.Unlock()
+	} else {
+		file, err := os.Open(filepath.Join(s.dir, historyName(pair)))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading history: %w", err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var entry Entry
+			// A line cut short by a crash is skipped
+			if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+				entries = append(entries, entry)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("error reading history: %w", err)
+		}
+	}
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries, nil
+}
+
+// NewResult builds the result of a run.
+//
+// Parameters:
+//   - pair: Name of the comparison
+//   - started: When the run started
+//   - differences: Differences found
+//   - err: Error the run failed with, or nil
+//
+// Returns:
+//   - Result: Result of the run, finished now
+func NewResult(pair string, started time.Time, differences compare.DiffResult, err error) Result {
+	result := Result{
+		Pair:        pair,
+		Started:     started,
+		Duration:    time.Since(started),
+		Summary:     differences.CountBySeverity(),
+		Differences: differences,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if result.Differences == nil {
+		result.Differences = compare.DiffResult{}
+	}
+	return result
+}
+
+// fileName returns the name of the result file of a comparison, escaping the characters that
+// cannot appear in file names.
+func fileName(pair string) string {
+	return escapeName(pair) + ".json"
+}
+
+// historyName returns the name of the history file of a comparison.
+func historyName(pair string) string {
+	return escapeName(pair) + ".history.jsonl"
+}
+
+// escapeName escapes the characters of the name of a comparison that cannot appear in file names.
+func escapeName(pair string) string {
+	return strings.ReplaceAll(url.PathEscape(pair), "%", "_")
+}
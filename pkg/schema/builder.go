@@ -0,0 +1,243 @@
+package schema
+
+// Builder constructs Schema values programmatically, so that code consuming schemas (such as
+// comparison logic and its tests) can be exercised without a live database:
+//
+//	s := schema.NewBuilder().
+//		Table("orders").
+//		Column("id", "bigint", schema.NotNull(), schema.Identity()).
+//		Column("customer_id", "bigint", schema.NotNull()).
+//		PrimaryKey("id").
+//		Index("idx_orders_customer", "customer_id").
+//		ForeignKey("fk_orders_customer", []string{"customer_id"}, "customers", []string{"id"}).
+//		Build()
+//
+// Methods describing table contents apply to the table most recently selected with Table,
+// and panic if no table has been selected.
+type Builder struct {
+	schema  *Schema // Schema being built
+	current string  // Name of the table that table-level methods apply to
+}
+
+// ColumnOption adjusts a column added with Builder.Column.
+type ColumnOption func(*ColumnInfo)
+
+// NewBuilder creates a Builder for an empty schema named DefaultSchemaName.
+//
+// Returns:
+//   - *Builder: Builder ready to add tables
+func NewBuilder() *Builder {
+	s := NewSchema()
+	s.Name = DefaultSchemaName
+	return &Builder{schema: s}
+}
+
+// SchemaName sets the name of the PostgreSQL schema being built.
+func (b *Builder) SchemaName(name string) *Builder {
+	b.schema.Name = name
+	return b
+}
+
+// Table adds a table to the schema, or selects it if it already exists, so that the following
+// calls apply to it.
+func (b *Builder) Table(name string) *Builder {
+	if _, exists := b.schema.Tables[name]; !exists {
+		b.schema.Tables[name] = TableInfo{Name: name}
+	}
+	b.current = name
+	return b
+}
+
+// Column adds a column to the current table. Columns are nullable unless NotNull is given.
+func (b *Builder) Column(name, dataType string, opts ...ColumnOption) *Builder {
+	col := ColumnInfo{Name: name, Type: dataType, Nullable: true}
+	for _, opt := range opts {
+		opt(&col)
+	}
+	return b.update(func(t *TableInfo) {
+		t.Columns = append(t.Columns, col)
+	})
+}
+
+// PrimaryKey sets the primary key columns of the current table, in order, with the constraint
+// name PostgreSQL gives it by default (<table>_pkey).
+func (b *Builder) PrimaryKey(columns ...string) *Builder {
+	return b.NamedPrimaryKey(b.current+"_pkey", columns...)
+}
+
+// NamedPrimaryKey sets the primary key columns of the current table, in order, and the name of
+// its constraint.
+func (b *Builder) NamedPrimaryKey(name string, columns ...string) *Builder {
+	return b.update(func(t *TableInfo) {
+		t.PrimaryKeys = columns
+		t.PrimaryKeyName = name
+	})
+}
+
+// Index adds a non-unique index on the given columns to the current table.
+func (b *Builder) Index(name string, columns ...string) *Builder {
+	return b.update(func(t *TableInfo) {
+		t.Indexes = append(t.Indexes, IndexInfo{Name: name, Columns: columns})
+	})
+}
+
+// UniqueIndex adds a unique index on the given columns to the current table.
+func (b *Builder) UniqueIndex(name string, columns ...string) *Builder {
+	return b.update(func(t *TableInfo) {
+		t.Indexes = append(t.Indexes, IndexInfo{Name: name, Columns: columns, Unique: true})
+	})
+}
+
+// ForeignKey adds a foreign key constraint to the current table.
+func (b *Builder) ForeignKey(name string, columns []string, referencedTable string, referencedColumns []string) *Builder {
+	return b.update(func(t *TableInfo) {
+		t.ForeignKeys = append(t.ForeignKeys, ForeignKeyInfo{
+			Name:              name,
+			Columns:           columns,
+			ReferencedTable:   referencedTable,
+			ReferencedColumns: referencedColumns,
+		})
+	})
+}
+
+// Trigger adds a trigger to the current table.
+func (b *Builder) Trigger(trigger TriggerInfo) *Builder {
+	return b.update(func(t *TableInfo) {
+		t.Triggers = append(t.Triggers, trigger)
+	})
+}
+
+// Owner sets the role owning the current table.
+func (b *Builder) Owner(owner string) *Builder {
+	return b.update(func(t *TableInfo) {
+		t.Owner = owner
+	})
+}
+
+// Comment sets the COMMENT of the current table.
+func (b *Builder) Comment(comment string) *Builder {
+	return b.update(func(t *TableInfo) {
+		t.Comment = comment
+	})
+}
+
+// PartitionBy sets the partition strategy and key of the current table (e.g., "RANGE (created_at)").
+func (b *Builder) PartitionBy(partitionKey string) *Builder {
+	return b.update(func(t *TableInfo) {
+		t.PartitionKey = partitionKey
+	})
+}
+
+// PartitionOf marks the current table as a partition of the given parent table.
+func (b *Builder) PartitionOf(parent string) *Builder {
+	return b.update(func(t *TableInfo) {
+		t.PartitionOf = parent
+	})
+}
+
+// Hypertable marks the current table as a TimescaleDB hypertable partitioned by the given dimensions.
+func (b *Builder) Hypertable(dimensions ...DimensionInfo) *Builder {
+	return b.update(func(t *TableInfo) {
+		t.Hypertable = &HypertableInfo{Dimensions: dimensions}
+	})
+}
+
+// Distribution sets the Citus distribution of the current table.
+func (b *Builder) Distribution(distribution DistributionInfo) *Builder {
+	return b.update(func(t *TableInfo) {
+		t.Distribution = &distribution
+	})
+}
+
+// View marks the current table as a view with the given query, reading the given tables and
+// views.
+func (b *Builder) View(definition string, references ...string) *Builder {
+	return b.update(func(t *TableInfo) {
+		t.View = &ViewInfo{Definition: definition, References: references}
+	})
+}
+
+// MaterializedView marks the current table as a materialized view with the given query, whether
+// it holds data, and the tables and views it reads.
+func (b *Builder) MaterializedView(definition string, populated bool, references ...string) *Builder {
+	return b.update(func(t *TableInfo) {
+		t.View = &ViewInfo{Definition: definition, Materialized: true, Populated: populated, References: references}
+	})
+}
+
+// Sequence adds a sequence to the schema, replacing any sequence of the same name. It does not
+// change the current table.
+func (b *Builder) Sequence(seq SequenceInfo) *Builder {
+	if b.schema.Sequences == nil {
+		b.schema.Sequences = make(map[string]SequenceInfo)
+	}
+	b.schema.Sequences[seq.Name] = seq
+	return b
+}
+
+// Function adds a function to the schema, replacing any function of the same signature. It does
+// not change the current table.
+func (b *Builder) Function(fn FunctionInfo) *Builder {
+	if b.schema.Functions == nil {
+		b.schema.Functions = make(map[string]FunctionInfo)
+	}
+	b.schema.Functions[fn.Signature()] = fn
+	return b
+}
+
+// Build returns the schema built so far. The builder should not be used afterwards.
+func (b *Builder) Build() *Schema {
+	return b.schema
+}
+
+// update applies a change to the current table.
+//
+// Parameters:
+//   - change: Function modifying the current table
+//
+// Returns:
+//   - *Builder: The builder, for chaining
+func (b *Builder) update(change func(*TableInfo)) *Builder {
+	if b.current == "" {
+		panic("schema: Builder table method called before Table")
+	}
+	table := b.schema.Tables[b.current]
+	change(&table)
+	b.schema.Tables[b.current] = table
+	return b
+}
+
+// NotNull marks a column as NOT NULL.
+func NotNull() ColumnOption {
+	return func(c *ColumnInfo) {
+		c.Nullable = false
+	}
+}
+
+// Default sets the default value expression of a column.
+func Default(expr string) ColumnOption {
+	return func(c *ColumnInfo) {
+		c.Default = expr
+	}
+}
+
+// Identity marks a column as an identity column.
+func Identity() ColumnOption {
+	return func(c *ColumnInfo) {
+		c.IsIdentity = true
+	}
+}
+
+// ColumnComment sets the COMMENT of a column.
+func ColumnComment(comment string) ColumnOption {
+	return func(c *ColumnInfo) {
+		c.Comment = comment
+	}
+}
+
+// Length sets the declared length of a character or character varying column.
+func Length(n int) ColumnOption {
+	return func(c *ColumnInfo) {
+		c.MaxLength = n
+	}
+}
@@ -0,0 +1,848 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Dialect identifies the database engine a schema is fetched from. Engines that speak the
+// PostgreSQL protocol differ in their catalogs, so the catalog queries are chosen by dialect.
+type Dialect string
+
+// Supported dialects.
+const (
+	DialectPostgres  Dialect = "postgres"          // PostgreSQL, and compatible engines with the same catalog
+	DialectAurora    Dialect = "aurora-postgresql" // Amazon Aurora PostgreSQL, whose catalog matches PostgreSQL's
+	DialectCockroach Dialect = "cockroachdb"       // CockroachDB
+	DialectRedshift  Dialect = "redshift"          // Amazon Redshift, based on PostgreSQL 8.0
+)
+
+// DetectDialect identifies the engine behind a connection from its version string.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active connection or pool
+//
+// Returns:
+//   - Dialect: Dialect of the engine; DialectPostgres unless another engine is recognized
+//   - error: Any error that occurred while querying the version
+func DetectDialect(ctx context.Context, conn Querier) (Dialect, error) {
+	var version string
+	if err := queryValue(ctx, conn, "SELECT version()", &version); err != nil {
+		return "", fmt.Errorf("error detecting server version: %w", err)
+	}
+
+	switch {
+	case strings.Contains(version, "CockroachDB"):
+		return DialectCockroach, nil
+	case strings.Contains(version, "Redshift"):
+		return DialectRedshift, nil
+	}
+
+	// Aurora reports the version of PostgreSQL it is compatible with, but provides its own
+	// aurora_version() function
+	var aurora bool
+	if err := queryValue(ctx, conn, "SELECT EXISTS (SELECT 1 FROM pg_catalog.pg_proc WHERE proname = 'aurora_version')", &aurora); err != nil {
+		return "", fmt.Errorf("error detecting Aurora: %w", err)
+	}
+	if aurora {
+		return DialectAurora, nil
+	}
+	return DialectPostgres, nil
+}
+
+// MinServerVersion is the oldest PostgreSQL release whose schema can be fetched, as in
+// server_version_num.
+const MinServerVersion = 90600
+
+// DetectServerVersion reads the version of the server behind a connection.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active connection or pool
+//
+// Returns:
+//   - int: Server version, as in server_version_num (e.g., 160002 for PostgreSQL 16.2)
+//   - error: Any error that occurred while querying the version
+func DetectServerVersion(ctx context.Context, conn Querier) (int, error) {
+	var version int
+	if err := queryValue(ctx, conn, "SELECT current_setting('server_version_num')::int", &version); err != nil {
+		return 0, fmt.Errorf("error detecting server version: %w", err)
+	}
+	return version, nil
+}
+
+// FormatServerVersion formats a server_version_num value as a release number (e.g., "16.2"
+// for 160002, "9.6.24" for 90624).
+//
+// Parameters:
+//   - version: Server version, as in server_version_num
+//
+// Returns:
+//   - string: Release number
+func FormatServerVersion(version int) string {
+	if version >= 100000 {
+		return fmt.Sprintf("%d.%d", version/10000, version%10000)
+	}
+	return fmt.Sprintf("%d.%d.%d", version/10000, version/100%100, version%100)
+}
+
+// queryValue runs a query returning a single value, releasing the connection before returning
+// so that it can run the next query.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active connection or pool
+//   - query: Query returning one row with one column
+//   - dest: Pointer the value is scanned into
+//
+// Returns:
+//   - error: Any error that occurred while running the query, or if it returned no rows
+func queryValue(ctx context.Context, conn Querier, query string, dest any) error {
+	rows, err := conn.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("query returned no rows")
+	}
+	if err := rows.Scan(dest); err != nil {
+		return err
+	}
+	rows.Close()
+	return rows.Err()
+}
+
+// catalog holds the catalog queries used to fetch a schema from one dialect, and the
+// adjustments that make the fetched tables comparable across dialects.
+type catalog struct {
+	tablesQuery      string                 // Lists the tables matching no exclude and, if any, an include regular expression: name, comment, owner, partition parent, partition key, view definition, materialized, populated, relations the view reads
+	columnsQuery     string                 // Lists the columns of tables: table, name, type, nullable, default, identity, comment, compression, length
+	primaryKeysQuery string                 // Lists the primary key columns of tables: table, column, constraint name, in order
+	indexesQuery     string                 // Lists the indexes of tables: table, name, key columns, unique, positions and text of key expressions, included columns, predicate, access method
+	foreignKeysQuery string                 // Lists the foreign keys of tables: table, name, columns, referenced table and columns, definition
+	triggersQuery    string                 // Lists the triggers of tables: table, name, timing, events, columns, level, condition, function; empty if the dialect has none
+	sequencesQuery   string                 // Lists the sequences of a schema (see readSequences); empty if the dialect has none
+	functionsQuery   string                 // Lists the functions of a schema (see readFunctions); empty if the dialect has none
+	normalize        func(table *TableInfo) // Adjusts a fetched table, if not nil
+	noArrays         bool                   // Whether the dialect lacks arrays: column lists are returned as comma-separated text, details are read for one table or all, and tables are filtered after listing
+	extensions       bool                   // Whether TimescaleDB and Citus metadata can be fetched
+	staging          bool                   // Whether the queries of table details can be staged in temporary tables (see stage)
+	rowEstimates     bool                   // Whether row counts can be estimated from pg_class.reltuples
+	dataChecksums    bool                   // Whether the data of tables can be checksummed with md5 and bit string casts
+	sizes            bool                   // Whether the sizes of tables and indexes can be read with pg_total_relation_size and estimated from pg_stats
+	unsupported      []string               // Features the dialect or version lacks, which are not fetched
+}
+
+// catalogFor returns the catalog queries of a dialect and server version.
+//
+// Parameters:
+//   - dialect: Dialect of the database
+//   - version: Server version of a PostgreSQL or Aurora database, as in server_version_num
+//     (e.g., 160002); ignored for other dialects
+//
+// Returns:
+//   - catalog: Catalog queries for the dialect and version
+//   - error: An error if the dialect is not supported, or if the version is older than
+//     MinServerVersion (wrapping ErrUnsupportedServerVersion)
+func catalogFor(dialect Dialect, version int) (catalog, error) {
+	cat := catalog{
+		tablesQuery:      postgresTablesQuery,
+		columnsQuery:     postgresColumnsQuery,
+		primaryKeysQuery: postgresPrimaryKeysQuery,
+		indexesQuery:     postgresIndexesQuery,
+		foreignKeysQuery: postgresForeignKeysQuery,
+		triggersQuery:    postgresTriggersQuery,
+		sequencesQuery:   postgresSequencesQuery,
+		functionsQuery:   postgresFunctionsQuery,
+	}
+
+	switch dialect {
+	case DialectPostgres, DialectAurora:
+		cat.extensions = true
+		cat.staging = true
+		cat.rowEstimates = true
+		cat.dataChecksums = true
+		cat.sizes = true
+		switch {
+		case version < MinServerVersion:
+			return catalog{}, fmt.Errorf("%w: server version %s is older than %s", ErrUnsupportedServerVersion,
+				FormatServerVersion(version), FormatServerVersion(MinServerVersion))
+		case version < 100000:
+			// Declarative partitioning and identity columns were added in PostgreSQL 10
+			cat.tablesQuery = postgres96TablesQuery
+			cat.columnsQuery = postgres96ColumnsQuery
+			cat.indexesQuery = postgres10IndexesQuery
+			cat.sequencesQuery = postgres96SequencesQuery
+			cat.functionsQuery = postgres10FunctionsQuery
+			cat.unsupported = []string{FeaturePartitioning, FeatureIdentity, FeatureCompression}
+		case version >= 140000:
+			// Per-column compression methods were added in PostgreSQL 14
+			cat.columnsQuery = postgres14ColumnsQuery
+		case version < 110000:
+			// Included columns of indexes and pg_proc.prokind were added in PostgreSQL 11
+			cat.indexesQuery = postgres10IndexesQuery
+			cat.functionsQuery = postgres10FunctionsQuery
+			cat.unsupported = []string{FeatureCompression}
+		default:
+			cat.unsupported = []string{FeatureCompression}
+		}
+	case DialectRedshift:
+		// Redshift has no partitioning, identity metadata, regclass casts, arrays, indexes, triggers,
+		// sequences, or pg_get_functiondef
+		cat.tablesQuery = redshiftTablesQuery
+		cat.columnsQuery = redshiftColumnsQuery
+		cat.primaryKeysQuery = redshiftPrimaryKeysQuery
+		cat.indexesQuery = ""
+		cat.foreignKeysQuery = redshiftForeignKeysQuery
+		cat.triggersQuery = ""
+		cat.sequencesQuery = ""
+		cat.functionsQuery = ""
+		cat.noArrays = true
+		cat.unsupported = []string{FeaturePartitioning, FeatureIdentity, FeatureCompression, FeatureIndexes, FeatureMaterializedViews, FeatureTriggers, FeatureSequences, FeatureFunctions}
+	case DialectCockroach:
+		// CockroachDB has no declarative partitioning metadata in pg_catalog, lacks some of the
+		// catalog functions the PostgreSQL queries use, including pg_get_triggerdef and
+		// pg_get_functiondef, and adds a hidden rowid column to tables created without a primary
+		// key
+		cat.tablesQuery = cockroachTablesQuery
+		cat.columnsQuery = cockroachColumnsQuery
+		cat.primaryKeysQuery = informationSchemaPrimaryKeysQuery
+		cat.indexesQuery = informationSchemaIndexesQuery
+		cat.foreignKeysQuery = informationSchemaForeignKeysQuery
+		cat.triggersQuery = ""
+		cat.sequencesQuery = cockroachSequencesQuery
+		cat.functionsQuery = ""
+		cat.normalize = normalizeCockroachTable
+		cat.unsupported = []string{FeaturePartitioning, FeatureCompression, FeatureMaterializedViews, FeatureTriggers, FeatureFunctions}
+	default:
+		return catalog{}, fmt.Errorf("unsupported dialect '%s'", dialect)
+	}
+	return cat, nil
+}
+
+// UnsupportedFeatures returns the features of the schema model that the catalog of a dialect and
+// server version lacks, as Fetch records them in Schema.UnsupportedFeatures before reading the
+// metadata of extensions.
+//
+// Parameters:
+//   - dialect: Dialect of the database
+//   - version: Server version of a PostgreSQL or Aurora database, as in server_version_num;
+//     ignored for other dialects
+//
+// Returns:
+//   - []string: Unsupported features (see the Feature constants)
+//   - error: An error if the dialect is not supported, or if the version is older than
+//     MinServerVersion (wrapping ErrUnsupportedServerVersion)
+func UnsupportedFeatures(dialect Dialect, version int) ([]string, error) {
+	cat, err := catalogFor(dialect, version)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(cat.unsupported), nil
+}
+
+// normalizeCockroachTable makes a table fetched from CockroachDB comparable with the same table
+// in PostgreSQL: the primary index, named "primary" by older CockroachDB releases, is renamed
+// to PostgreSQL's "<table>_pkey", and the primary key and indexes on the hidden rowid column,
+// which PostgreSQL has no equivalent for, are removed.
+//
+// Parameters:
+//   - table: Table to adjust
+func normalizeCockroachTable(table *TableInfo) {
+	visible := make(map[string]bool, len(table.Columns))
+	for _, col := range table.Columns {
+		visible[col.Name] = true
+	}
+	usesHidden := func(columns []string) bool {
+		for _, col := range columns {
+			if !visible[col] {
+				return true
+			}
+		}
+		return false
+	}
+
+	if usesHidden(table.PrimaryKeys) {
+		table.PrimaryKeys = nil
+		table.PrimaryKeyName = ""
+	}
+	var indexes []IndexInfo
+	for _, idx := range table.Indexes {
+		if usesHidden(idx.Columns) {
+			continue
+		}
+		if idx.Name == "primary" {
+			idx.Name = table.Name + "_pkey"
+		}
+		indexes = append(indexes, idx)
+	}
+	table.Indexes = indexes
+}
+
+// Catalog queries for PostgreSQL 10 and later. They read pg_catalog directly rather than the
+// slower information_schema views, and render types, indexes, and constraints with PostgreSQL's
+// own functions (format_type, pg_get_indexdef, pg_get_constraintdef), so that type modifiers,
+// index expressions, and constraint options are kept. Each takes the schema name as $1. The queries of table details also take an array of
+// table names as $2, or NULL to read the details of every table of the schema at once, and return
+// the name of the table in their first column.
+const (
+	postgresTablesQuery = `
+	SELECT
+		c.relname,
+		obj_description(c.oid, 'pg_class'),
+		pg_get_userbyid(c.relowner),
+		COALESCE(parent.relname, ''),
+		COALESCE(pg_get_partkeydef(c.oid), ''),
+		CASE WHEN c.relkind IN ('v', 'm') THEN pg_get_viewdef(c.oid) END,
+		c.relkind = 'm',
+		c.relispopulated,
+		ARRAY(
+			SELECT DISTINCT ref.relname
+			FROM pg_rewrite r
+			JOIN pg_depend d
+				ON d.classid = 'pg_rewrite'::regclass AND d.objid = r.oid AND d.refclassid = 'pg_class'::regclass
+			JOIN pg_class ref
+				ON ref.oid = d.refobjid
+			WHERE r.ev_class = c.oid
+				AND ref.oid <> c.oid
+				AND ref.relnamespace = c.relnamespace
+			ORDER BY ref.relname
+		)
+	FROM pg_class c
+	JOIN pg_namespace n
+		ON n.oid = c.relnamespace
+	LEFT JOIN pg_inherits inh
+		ON inh.inhrelid = c.oid AND c.relispartition
+	LEFT JOIN pg_class parent
+		ON parent.oid = inh.inhparent
+	WHERE n.nspname = $1
+		AND c.relkind IN ('r', 'p', 'v', 'm', 'f')
+		AND (cardinality($2::text[]) = 0 OR c.relname ~ ANY($2::text[]))
+		AND NOT c.relname ~ ANY($3::text[])
+	ORDER BY c.relname
+`
+
+	postgresColumnsQuery = `
+	SELECT
+		c.relname,
+		a.attname,
+		format_type(a.atttypid, a.atttypmod),
+		CASE WHEN a.attnotnull THEN 'NO' ELSE 'YES' END,
+		pg_get_expr(d.adbin, d.adrelid),
+		CASE WHEN a.attidentity <> '' THEN 'YES' ELSE 'NO' END,
+		col_description(a.attrelid, a.attnum),
+		'',
+		CASE WHEN a.atttypid IN ('bpchar'::regtype, 'varchar'::regtype) AND a.atttypmod > 0 THEN a.atttypmod - 4 END
+	FROM pg_attribute a
+	JOIN pg_class c
+		ON c.oid = a.attrelid
+	JOIN pg_namespace n
+		ON n.oid = c.relnamespace
+	LEFT JOIN pg_attrdef d
+		ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+	WHERE n.nspname = $1
+		AND ($2::text[] IS NULL OR c.relname = ANY($2))
+		AND c.relkind IN ('r', 'p', 'v', 'm', 'f')
+		AND a.attnum > 0
+		AND NOT a.attisdropped
+	ORDER BY c.relname, a.attnum
+`
+
+	postgres14ColumnsQuery = `
+	SELECT
+		c.relname,
+		a.attname,
+		format_type(a.atttypid, a.atttypmod),
+		CASE WHEN a.attnotnull THEN 'NO' ELSE 'YES' END,
+		pg_get_expr(d.adbin, d.adrelid),
+		CASE WHEN a.attidentity <> '' THEN 'YES' ELSE 'NO' END,
+		col_description(a.attrelid, a.attnum),
+		CASE a.attcompression WHEN 'p' THEN 'pglz' WHEN 'l' THEN 'lz4' ELSE '' END,
+		CASE WHEN a.atttypid IN ('bpchar'::regtype, 'varchar'::regtype) AND a.atttypmod > 0 THEN a.atttypmod - 4 END
+	FROM pg_attribute a
+	JOIN pg_class c
+		ON c.oid = a.attrelid
+	JOIN pg_namespace n
+		ON n.oid = c.relnamespace
+	LEFT JOIN pg_attrdef d
+		ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+	WHERE n.nspname = $1
+		AND ($2::text[] IS NULL OR c.relname = ANY($2))
+		AND c.relkind IN ('r', 'p', 'v', 'm', 'f')
+		AND a.attnum > 0
+		AND NOT a.attisdropped
+	ORDER BY c.relname, a.attnum
+`
+
+	postgresPrimaryKeysQuery = `
+	SELECT c.relname, a.attname, con.conname
+	FROM pg_constraint con
+	JOIN pg_class c
+		ON c.oid = con.conrelid
+	JOIN pg_namespace n
+		ON n.oid = c.relnamespace
+	CROSS JOIN LATERAL unnest(con.conkey) WITH ORDINALITY AS k(attnum, position)
+	JOIN pg_attribute a
+		ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+	WHERE con.contype = 'p'
+		AND n.nspname = $1
+		AND ($2::text[] IS NULL OR c.relname = ANY($2))
+	ORDER BY c.relname, k.position
+`
+
+	// Key columns are listed by name, while key expressions, whose position in indkey is zero,
+	// are printed by pg_get_indexdef along with their position. The keys are the first
+	// indnkeyatts entries of indkey, and the included columns the others
+	postgresIndexesQuery = `
+	SELECT
+		t.relname,
+		i.relname,
+		ARRAY(
+			SELECT a.attname
+			FROM unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, position)
+			JOIN pg_attribute a
+				ON a.attrelid = ix.indrelid AND a.attnum = k.attnum
+			WHERE k.position <= ix.indnkeyatts
+			ORDER BY k.position
+		),
+		ix.indisunique,
+		ARRAY(
+			SELECT k.position::int4
+			FROM unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, position)
+			WHERE k.attnum = 0 AND k.position <= ix.indnkeyatts
+			ORDER BY k.position
+		),
+		ARRAY(
+			SELECT pg_get_indexdef(ix.indexrelid, k.position::int4, true)
+			FROM unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, position)
+			WHERE k.attnum = 0 AND k.position <= ix.indnkeyatts
+			ORDER BY k.position
+		),
+		ARRAY(
+			SELECT a.attname
+			FROM unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, position)
+			JOIN pg_attribute a
+				ON a.attrelid = ix.indrelid AND a.attnum = k.attnum
+			WHERE k.position > ix.indnkeyatts
+			ORDER BY k.position
+		),
+		COALESCE(pg_get_expr(ix.indpred, ix.indrelid, true), ''),
+		am.amname
+	FROM pg_index ix
+	JOIN pg_class i
+		ON i.oid = ix.indexrelid
+	JOIN pg_am am
+		ON am.oid = i.relam
+	JOIN pg_class t
+		ON t.oid = ix.indrelid
+	JOIN pg_namespace n
+		ON n.oid = t.relnamespace
+	WHERE t.relkind IN ('r', 'm')
+		AND n.nspname = $1
+		AND ($2::text[] IS NULL OR t.relname = ANY($2))
+	ORDER BY t.relname, i.relname
+`
+
+	postgresForeignKeysQuery = `
+	SELECT
+		c.relname,
+		con.conname,
+		ARRAY(
+			SELECT a.attname
+			FROM unnest(con.conkey) WITH ORDINALITY AS k(attnum, position)
+			JOIN pg_attribute a
+				ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+			ORDER BY k.position
+		),
+		ref.relname,
+		ARRAY(
+			SELECT a.attname
+			FROM unnest(con.confkey) WITH ORDINALITY AS k(attnum, position)
+			JOIN pg_attribute a
+				ON a.attrelid = con.confrelid AND a.attnum = k.attnum
+			ORDER BY k.position
+		),
+		pg_get_constraintdef(con.oid, true)
+	FROM pg_constraint con
+	JOIN pg_class c
+		ON c.oid = con.conrelid
+	JOIN pg_namespace n
+		ON n.oid = c.relnamespace
+	JOIN pg_class ref
+		ON ref.oid = con.confrelid
+	WHERE con.contype = 'f'
+		AND n.nspname = $1
+		AND ($2::text[] IS NULL OR c.relname = ANY($2))
+	ORDER BY c.relname, con.conname
+`
+
+	// The timing, events, and level are decoded from the bits of tgtype; the WHEN condition
+	// and the called function, with its arguments, are cut from pg_get_triggerdef, which
+	// prints them as written. Triggers PostgreSQL creates to enforce constraints are skipped
+	postgresTriggersQuery = `
+	SELECT
+		c.relname,
+		t.tgname,
+		CASE
+			WHEN t.tgtype & 2 <> 0 THEN 'BEFORE'
+			WHEN t.tgtype & 64 <> 0 THEN 'INSTEAD OF'
+			ELSE 'AFTER'
+		END,
+		array_remove(ARRAY[
+			CASE WHEN t.tgtype & 4 <> 0 THEN 'INSERT' END,
+			CASE WHEN t.tgtype & 16 <> 0 THEN 'UPDATE' END,
+			CASE WHEN t.tgtype & 8 <> 0 THEN 'DELETE' END,
+			CASE WHEN t.tgtype & 32 <> 0 THEN 'TRUNCATE' END
+		], NULL),
+		ARRAY(
+			SELECT a.attname
+			FROM unnest(t.tgattr::int2[]) WITH ORDINALITY AS k(attnum, position)
+			JOIN pg_attribute a
+				ON a.attrelid = t.tgrelid AND a.attnum = k.attnum
+			ORDER BY k.position
+		),
+		CASE WHEN t.tgtype & 1 <> 0 THEN 'ROW' ELSE 'STATEMENT' END,
+		COALESCE(substring(pg_get_triggerdef(t.oid) FROM ' WHEN \((.*)\) EXECUTE (?:PROCEDURE|FUNCTION) '), ''),
+		COALESCE(substring(pg_get_triggerdef(t.oid) FROM ' EXECUTE (?:PROCEDURE|FUNCTION) (.*)$'), '')
+	FROM pg_trigger t
+	JOIN pg_class c
+		ON c.oid = t.tgrelid
+	JOIN pg_namespace n
+		ON n.oid = c.relnamespace
+	WHERE NOT t.tgisinternal
+		AND n.nspname = $1
+		AND ($2::text[] IS NULL OR c.relname = ANY($2))
+	ORDER BY c.relname, t.tgname
+`
+)
+
+// Catalog queries for PostgreSQL 9.6 and 10, where they differ from later releases. The
+// postgres96 queries are only used by PostgreSQL 9.6.
+const (
+	// Before PostgreSQL 11, indexes have no included columns, and every entry of indkey is a key
+	postgres10IndexesQuery = `
+	SELECT
+		t.relname,
+		i.relname,
+		ARRAY(
+			SELECT a.attname
+			FROM unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, position)
+			JOIN pg_attribute a
+				ON a.attrelid = ix.indrelid AND a.attnum = k.attnum
+			ORDER BY k.position
+		),
+		ix.indisunique,
+		ARRAY(
+			SELECT k.position::int4
+			FROM unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, position)
+			WHERE k.attnum = 0
+			ORDER BY k.position
+		),
+		ARRAY(
+			SELECT pg_get_indexdef(ix.indexrelid, k.position::int4, true)
+			FROM unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, position)
+			WHERE k.attnum = 0
+			ORDER BY k.position
+		),
+		ARRAY[]::text[],
+		COALESCE(pg_get_expr(ix.indpred, ix.indrelid, true), ''),
+		am.amname
+	FROM pg_index ix
+	JOIN pg_class i
+		ON i.oid = ix.indexrelid
+	JOIN pg_am am
+		ON am.oid = i.relam
+	JOIN pg_class t
+		ON t.oid = ix.indrelid
+	JOIN pg_namespace n
+		ON n.oid = t.relnamespace
+	WHERE t.relkind IN ('r', 'm')
+		AND n.nspname = $1
+		AND ($2::text[] IS NULL OR t.relname = ANY($2))
+	ORDER BY t.relname, i.relname
+`
+
+	postgres96TablesQuery = `
+	SELECT
+		c.relname,
+		obj_description(c.oid, 'pg_class'),
+		pg_get_userbyid(c.relowner),
+		'',
+		'',
+		CASE WHEN c.relkind IN ('v', 'm') THEN pg_get_viewdef(c.oid) END,
+		c.relkind = 'm',
+		c.relispopulated,
+		ARRAY(
+			SELECT DISTINCT ref.relname
+			FROM pg_rewrite r
+			JOIN pg_depend d
+				ON d.classid = 'pg_rewrite'::regclass AND d.objid = r.oid AND d.refclassid = 'pg_class'::regclass
+			JOIN pg_class ref
+				ON ref.oid = d.refobjid
+			WHERE r.ev_class = c.oid
+				AND ref.oid <> c.oid
+				AND ref.relnamespace = c.relnamespace
+			ORDER BY ref.relname
+		)
+	FROM pg_class c
+	JOIN pg_namespace n
+		ON n.oid = c.relnamespace
+	WHERE n.nspname = $1
+		AND c.relkind IN ('r', 'v', 'm', 'f')
+		AND (cardinality($2::text[]) = 0 OR c.relname ~ ANY($2::text[]))
+		AND NOT c.relname ~ ANY($3::text[])
+	ORDER BY c.relname
+`
+
+	postgres96ColumnsQuery = `
+	SELECT
+		c.relname,
+		a.attname,
+		format_type(a.atttypid, a.atttypmod),
+		CASE WHEN a.attnotnull THEN 'NO' ELSE 'YES' END,
+		pg_get_expr(d.adbin, d.adrelid),
+		'NO',
+		col_description(a.attrelid, a.attnum),
+		'',
+		CASE WHEN a.atttypid IN ('bpchar'::regtype, 'varchar'::regtype) AND a.atttypmod > 0 THEN a.atttypmod - 4 END
+	FROM pg_attribute a
+	JOIN pg_class c
+		ON c.oid = a.attrelid
+	JOIN pg_namespace n
+		ON n.oid = c.relnamespace
+	LEFT JOIN pg_attrdef d
+		ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+	WHERE n.nspname = $1
+		AND ($2::text[] IS NULL OR c.relname = ANY($2))
+		AND c.relkind IN ('r', 'v', 'm', 'f')
+		AND a.attnum > 0
+		AND NOT a.attisdropped
+	ORDER BY c.relname, a.attnum
+`
+)
+
+// Catalog queries based on information_schema, for engines whose pg_catalog lacks some of the
+// functions and columns the PostgreSQL queries rely on. They take the same parameters and return
+// the same columns as the PostgreSQL queries, leaving empty those they cannot read: the key
+// expressions, included columns, predicates, and access methods of indexes, and the definitions
+// of foreign keys.
+const (
+	informationSchemaPrimaryKeysQuery = `
+	SELECT tc.table_name, kcu.column_name, tc.constraint_name
+	FROM information_schema.table_constraints tc
+	JOIN information_schema.key_column_usage kcu
+		ON tc.constraint_name = kcu.constraint_name
+		AND tc.table_schema = kcu.table_schema
+		AND tc.table_name = kcu.table_name
+	WHERE tc.constraint_type = 'PRIMARY KEY'
+		AND tc.table_schema = $1
+		AND ($2::text[] IS NULL OR tc.table_name = ANY($2))
+	ORDER BY tc.table_name, kcu.ordinal_position
+`
+
+	informationSchemaIndexesQuery = `
+	SELECT
+		t.relname as table_name,
+		i.relname as index_name,
+		array_agg(a.attname) as column_names,
+		ix.indisunique as is_unique,
+		ARRAY[]::int4[],
+		ARRAY[]::text[],
+		ARRAY[]::text[],
+		'',
+		''
+	FROM
+		pg_class t,
+		pg_class i,
+		pg_index ix,
+		pg_attribute a,
+		pg_namespace n
+	WHERE
+		t.oid = ix.indrelid
+		AND n.oid = t.relnamespace
+		AND i.oid = ix.indexrelid
+		AND a.attrelid = t.oid
+		AND a.attnum = ANY(ix.indkey)
+		AND t.relkind = 'r'
+		AND n.nspname = $1
+		AND ($2::text[] IS NULL OR t.relname = ANY($2))
+	GROUP BY
+		t.relname,
+		i.relname,
+		ix.indisunique
+	ORDER BY
+		t.relname,
+		i.relname
+`
+
+	informationSchemaForeignKeysQuery = `
+	SELECT
+		tc.table_name,
+		tc.constraint_name,
+		array_agg(kcu.column_name) as columns,
+		ccu.table_name as referenced_table,
+		array_agg(ccu.column_name) as referenced_columns,
+		''
+	FROM
+		information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON tc.constraint_name = kcu.constraint_name
+			AND tc.table_schema = kcu.table_schema
+			AND tc.table_name = kcu.table_name
+		JOIN information_schema.constraint_column_usage ccu
+			ON ccu.constraint_name = tc.constraint_name
+	WHERE
+		tc.constraint_type = 'FOREIGN KEY'
+		AND tc.table_schema = $1
+		AND ($2::text[] IS NULL OR tc.table_name = ANY($2))
+	GROUP BY
+		tc.table_name,
+		tc.constraint_name,
+		ccu.table_name
+	ORDER BY
+		tc.table_name,
+		tc.constraint_name
+`
+)
+
+// Catalog queries for CockroachDB, where they differ from PostgreSQL.
+const (
+	cockroachTablesQuery = `
+	SELECT
+		t.table_name,
+		obj_description(c.oid, 'pg_class'),
+		pg_get_userbyid(c.relowner),
+		'',
+		'',
+		v.view_definition,
+		false,
+		false,
+		ARRAY[]::text[]
+	FROM information_schema.tables t
+	JOIN pg_class c
+		ON c.oid = (quote_ident(t.table_schema) || '.' || quote_ident(t.table_name))::regclass
+	LEFT JOIN information_schema.views v
+		ON v.table_schema = t.table_schema AND v.table_name = t.table_name
+	WHERE t.table_schema = $1
+		AND (cardinality($2::text[]) = 0 OR t.table_name ~ ANY($2::text[]))
+		AND NOT t.table_name ~ ANY($3::text[])
+	ORDER BY t.table_name
+`
+
+	cockroachColumnsQuery = `
+	SELECT
+		col.table_name,
+		col.column_name,
+		format_type(a.atttypid, a.atttypmod),
+		col.is_nullable,
+		col.column_default,
+		col.is_identity,
+		col_description(a.attrelid, a.attnum),
+		'',
+		col.character_maximum_length
+	FROM information_schema.columns col
+	JOIN pg_attribute a
+		ON a.attrelid = (quote_ident(col.table_schema) || '.' || quote_ident(col.table_name))::regclass
+		AND a.attname = col.column_name
+	WHERE col.table_schema = $1 AND ($2::text[] IS NULL OR col.table_name = ANY($2)) AND col.is_hidden = 'NO'
+	ORDER BY col.table_name, col.ordinal_position
+`
+)
+
+// Catalog queries for Amazon Redshift, where they differ from PostgreSQL. Redshift has no arrays,
+// so the queries of table details take a single table name as $2 instead, or NULL.
+const (
+	redshiftPrimaryKeysQuery = `
+	SELECT tc.table_name, kcu.column_name, tc.constraint_name
+	FROM information_schema.table_constraints tc
+	JOIN information_schema.key_column_usage kcu
+		ON tc.constraint_name = kcu.constraint_name
+		AND tc.table_schema = kcu.table_schema
+		AND tc.table_name = kcu.table_name
+	WHERE tc.constraint_type = 'PRIMARY KEY'
+		AND tc.table_schema = $1
+		AND ($2::text IS NULL OR tc.table_name = $2)
+	ORDER BY tc.table_name, kcu.ordinal_position
+`
+
+	redshiftTablesQuery = `
+	SELECT
+		t.table_name,
+		obj_description(c.oid, 'pg_class'),
+		pg_get_userbyid(c.relowner),
+		'',
+		'',
+		v.view_definition,
+		false,
+		false,
+		''
+	FROM information_schema.tables t
+	JOIN pg_namespace n
+		ON n.nspname = t.table_schema
+	JOIN pg_class c
+		ON c.relnamespace = n.oid AND c.relname = t.table_name
+	LEFT JOIN information_schema.views v
+		ON v.table_schema = t.table_schema AND v.table_name = t.table_name
+	WHERE t.table_schema = $1
+	ORDER BY t.table_name
+`
+
+	redshiftColumnsQuery = `
+	SELECT
+		col.table_name,
+		col.column_name,
+		format_type(a.atttypid, a.atttypmod),
+		col.is_nullable,
+		col.column_default,
+		'NO',
+		col_description(c.oid, col.ordinal_position),
+		'',
+		col.character_maximum_length
+	FROM information_schema.columns col
+	JOIN pg_namespace n
+		ON n.nspname = col.table_schema
+	JOIN pg_class c
+		ON c.relnamespace = n.oid AND c.relname = col.table_name
+	JOIN pg_attribute a
+		ON a.attrelid = c.oid AND a.attname = col.column_name
+	WHERE col.table_schema = $1 AND ($2::text IS NULL OR col.table_name = $2)
+	ORDER BY col.table_name, col.ordinal_position
+`
+
+	redshiftForeignKeysQuery = `
+	SELECT
+		tc.table_name,
+		tc.constraint_name,
+		listagg(kcu.column_name, ',') WITHIN GROUP (ORDER BY kcu.ordinal_position),
+		ccu.table_name,
+		listagg(ccu.column_name, ',') WITHIN GROUP (ORDER BY kcu.ordinal_position),
+		''
+	FROM
+		information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON tc.constraint_name = kcu.constraint_name
+			AND tc.table_schema = kcu.table_schema
+			AND tc.table_name = kcu.table_name
+		JOIN information_schema.constraint_column_usage ccu
+			ON ccu.constraint_name = tc.constraint_name
+	WHERE
+		tc.constraint_type = 'FOREIGN KEY'
+		AND tc.table_schema = $1
+		AND ($2::text IS NULL OR tc.table_name = $2)
+	GROUP BY
+		tc.table_name,
+		tc.constraint_name,
+		ccu.table_name
+	ORDER BY
+		tc.table_name,
+		tc.constraint_name
+`
+)
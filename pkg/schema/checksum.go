@@ -0,0 +1,200 @@
+package schema
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+)
+
+// Checksum returns a stable hash of the full definition of a table: its name, columns, keys,
+// indexes, and table-level properties, in the order they were fetched. Tables with the same
+// checksum have the same definition, so comparing them in detail cannot find differences;
+// tables with different checksums may still compare equal, for example when their columns were
+// fetched in another order or only differ in comments.
+//
+// Returns:
+//   - string: Hexadecimal SHA-256 hash of the definition
+func (t TableInfo) Checksum() string {
+	sum := t.checksum(nil)
+	return hex.EncodeToString(sum[:])
+}
+
+// SameDefinition reports whether two tables have the same checksum, without formatting either.
+//
+// Parameters:
+//   - other: Table to compare with
+//
+// Returns:
+//   - bool: True if both tables have the same definition
+func (t TableInfo) SameDefinition(other TableInfo) bool {
+	buf := make([]byte, 0, 1024)
+	return t.checksum(buf) == other.checksum(buf)
+}
+
+// Checksum returns a stable hash of the definitions of every table and sequence of a schema,
+// along with its name, whatever the order they were fetched in. Schemas with the same checksum have the
+// same tables, so a schema can be checked for changes since it was last fetched by comparing
+// checksums alone.
+//
+// Returns:
+//   - string: Hexadecimal SHA-256 hash of the schema
+func (s *Schema) Checksum() string {
+	names := make([]string, 0, len(s.Tables))
+	for name := range s.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	buf := appendString(make([]byte, 0, 1024), s.Name)
+	hash.Write(buf)
+	for _, name := range names {
+		buf = s.Tables[name].appendDefinition(buf[:0])
+		hash.Write(binary.AppendUvarint(nil, uint64(len(buf))))
+		hash.Write(buf)
+	}
+
+	// Sequences follow the tables, so that schemas without sequences keep their checksums
+	sequenceNames := make([]string, 0, len(s.Sequences))
+	for name := range s.Sequences {
+		sequenceNames = append(sequenceNames, name)
+	}
+	sort.Strings(sequenceNames)
+	for _, name := range sequenceNames {
+		buf = s.Sequences[name].appendDefinition(buf[:0])
+		hash.Write(binary.AppendUvarint(nil, uint64(len(buf))))
+		hash.Write(buf)
+	}
+
+	// Functions follow the sequences, for the same reason
+	signatures := make([]string, 0, len(s.Functions))
+	for signature := range s.Functions {
+		signatures = append(signatures, signature)
+	}
+	sort.Strings(signatures)
+	for _, signature := range signatures {
+		buf = s.Functions[signature].appendDefinition(buf[:0])
+		hash.Write(binary.AppendUvarint(nil, uint64(len(buf))))
+		hash.Write(buf)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// checksum hashes the definition of a table, encoded into a reusable buffer.
+func (t TableInfo) checksum(buf []byte) [sha256.Size]byte {
+	return sha256.Sum256(t.appendDefinition(buf[:0]))
+}
+
+// appendDefinition appends an unambiguous encoding of every field of a table to a buffer:
+// strings and lists are prefixed with their length. Fields added to TableInfo and the types it
+// holds must be added here, so that tables differing only in them get different checksums.
+//
+// Parameters:
+//   - buf: Buffer to append to
+//
+// Returns:
+//   - []byte: Buffer with the encoded table appended
+func (t TableInfo) appendDefinition(buf []byte) []byte {
+	buf = appendString(buf, t.Name)
+	buf = binary.AppendUvarint(buf, uint64(len(t.Columns)))
+	for _, col := range t.Columns {
+		buf = appendString(buf, col.Name)
+		buf = appendString(buf, col.Type)
+		buf = appendBool(buf, col.Nullable)
+		buf = appendString(buf, col.Default)
+		buf = appendBool(buf, col.IsIdentity)
+		buf = appendString(buf, col.Comment)
+		buf = appendString(buf, col.Compression)
+		buf = binary.AppendVarint(buf, int64(col.MaxLength))
+	}
+	buf = appendStrings(buf, t.PrimaryKeys)
+	buf = appendString(buf, t.PrimaryKeyName)
+	buf = binary.AppendUvarint(buf, uint64(len(t.Indexes)))
+	for _, idx := range t.Indexes {
+		buf = appendString(buf, idx.Name)
+		buf = appendStrings(buf, idx.Columns)
+		buf = appendBool(buf, idx.Unique)
+		buf = binary.AppendUvarint(buf, uint64(len(idx.Expressions)))
+		for _, expression := range idx.Expressions {
+			buf = binary.AppendVarint(buf, int64(expression.Position))
+			buf = appendString(buf, expression.Expression)
+		}
+		buf = appendStrings(buf, idx.Include)
+		buf = appendString(buf, idx.Predicate)
+		buf = appendString(buf, idx.Method)
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(t.ForeignKeys)))
+	for _, fk := range t.ForeignKeys {
+		buf = appendString(buf, fk.Name)
+		buf = appendStrings(buf, fk.Columns)
+		buf = appendString(buf, fk.ReferencedTable)
+		buf = appendStrings(buf, fk.ReferencedColumns)
+		buf = appendString(buf, fk.Definition)
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(t.Triggers)))
+	for _, trigger := range t.Triggers {
+		buf = appendString(buf, trigger.Name)
+		buf = appendString(buf, trigger.Timing)
+		buf = appendStrings(buf, trigger.Events)
+		buf = appendStrings(buf, trigger.Columns)
+		buf = appendString(buf, trigger.Level)
+		buf = appendString(buf, trigger.When)
+		buf = appendString(buf, trigger.Function)
+	}
+	buf = appendString(buf, t.Comment)
+	buf = appendString(buf, t.PartitionOf)
+	buf = appendString(buf, t.PartitionKey)
+	buf = appendString(buf, t.Owner)
+
+	buf = appendBool(buf, t.Hypertable != nil)
+	if h := t.Hypertable; h != nil {
+		buf = appendBool(buf, h.ContinuousAggregate)
+		buf = binary.AppendUvarint(buf, uint64(len(h.Dimensions)))
+		for _, dim := range h.Dimensions {
+			buf = appendString(buf, dim.Column)
+			buf = appendString(buf, dim.Type)
+			buf = appendString(buf, dim.Interval)
+			buf = binary.AppendVarint(buf, int64(dim.Partitions))
+		}
+		buf = appendBool(buf, h.Compression)
+		buf = appendBool(buf, h.MaterializedOnly)
+	}
+	buf = appendBool(buf, t.Distribution != nil)
+	if d := t.Distribution; d != nil {
+		buf = appendString(buf, d.Type)
+		buf = appendString(buf, d.Column)
+		buf = binary.AppendVarint(buf, int64(d.ShardCount))
+	}
+	buf = appendBool(buf, t.View != nil)
+	if v := t.View; v != nil {
+		buf = appendString(buf, v.Definition)
+		buf = appendBool(buf, v.Materialized)
+		buf = appendBool(buf, v.Populated)
+		buf = appendStrings(buf, v.References)
+	}
+	return buf
+}
+
+// appendString appends a string, prefixed with its length, to a buffer.
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// appendStrings appends a list of strings, prefixed with its length, to a buffer.
+func appendStrings(buf []byte, list []string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(list)))
+	for _, s := range list {
+		buf = appendString(buf, s)
+	}
+	return buf
+}
+
+// appendBool appends a boolean, as one byte, to a buffer.
+func appendBool(buf []byte, b bool) []byte {
+	if b {
+		return append(buf, 1)
+	}
+	return append(buf, 0)
+}
@@ -0,0 +1,241 @@
+package schema
+
+// Clone returns a deep copy of the schema, which can be modified (e.g., by filters) without
+// affecting the original. Values in Extensions are copied shallowly, as their types are only
+// known to the fetchers and comparators that use them.
+//
+// Returns:
+//   - *Schema: Independent copy of the schema
+func (s *Schema) Clone() *Schema {
+	return s.SubsetFunc(func(TableInfo) bool { return true })
+}
+
+// Subset returns a deep copy of the schema containing only the named tables. Names of tables
+// that do not exist are ignored. Fetch errors are kept for the named tables only.
+//
+// Parameters:
+//   - tableNames: Names of the tables to keep
+//
+// Returns:
+//   - *Schema: Copy of the schema restricted to the named tables
+func (s *Schema) Subset(tableNames ...string) *Schema {
+	keep := make(map[string]bool, len(tableNames))
+	for _, name := range tableNames {
+		keep[name] = true
+	}
+	return s.subset(func(name string) bool { return keep[name] })
+}
+
+// SubsetFunc returns a deep copy of the schema containing only the tables for which keep
+// returns true.
+//
+// Parameters:
+//   - keep: Function deciding whether a table is kept
+//
+// Returns:
+//   - *Schema: Copy of the schema restricted to the kept tables
+func (s *Schema) SubsetFunc(keep func(TableInfo) bool) *Schema {
+	return s.subset(func(name string) bool {
+		table, exists := s.Tables[name]
+		if !exists {
+			// Tables that failed to fetch are only known by name
+			table = TableInfo{Name: name}
+		}
+		return keep(table)
+	})
+}
+
+// subset copies the schema, keeping the tables and fetch errors whose table names pass keep.
+// Sequences and functions belong to no table, so they are all kept.
+func (s *Schema) subset(keep func(name string) bool) *Schema {
+	copied := &Schema{
+		Name:                s.Name,
+		ServerVersion:       s.ServerVersion,
+		Tables:              make(map[string]TableInfo, len(s.Tables)),
+		UnsupportedFeatures: append([]string(nil), s.UnsupportedFeatures...),
+		RowCountMode:        s.RowCountMode,
+	}
+	for name, table := range s.Tables {
+		if keep(name) {
+			copied.Tables[name] = table.Clone()
+		}
+	}
+	if s.RowCounts != nil {
+		copied.RowCounts = make(map[string]int64, len(s.RowCounts))
+		for name, count := range s.RowCounts {
+			if keep(name) {
+				copied.RowCounts[name] = count
+			}
+		}
+	}
+	if s.DataChecksums != nil {
+		copied.DataChecksums = make(map[string]DataChecksum, len(s.DataChecksums))
+		for name, checksum := range s.DataChecksums {
+			if keep(name) {
+				checksum.Columns = append([]string(nil), checksum.Columns...)
+				copied.DataChecksums[name] = checksum
+			}
+		}
+	}
+	if s.Sizes != nil {
+		copied.Sizes = make(map[string]TableSize, len(s.Sizes))
+		for name, size := range s.Sizes {
+			if keep(name) {
+				copied.Sizes[name] = size.clone()
+			}
+		}
+	}
+	for _, fetchErr := range s.Errors {
+		if keep(fetchErr.Table) {
+			copied.Errors = append(copied.Errors, fetchErr)
+		}
+	}
+	if s.Sequences != nil {
+		copied.Sequences = make(map[string]SequenceInfo, len(s.Sequences))
+		for name, seq := range s.Sequences {
+			copied.Sequences[name] = seq
+		}
+	}
+	if s.Functions != nil {
+		copied.Functions = make(map[string]FunctionInfo, len(s.Functions))
+		for signature, fn := range s.Functions {
+			copied.Functions[signature] = fn
+		}
+	}
+	if s.Extensions != nil {
+		copied.Extensions = make(map[string]any, len(s.Extensions))
+		for kind, value := range s.Extensions {
+			copied.Extensions[kind] = value
+		}
+	}
+	return copied
+}
+
+// Merge combines several schemas into a new one, for example to compare a fleet of databases
+// against the union of their tables. Tables are deep copied; when several schemas define the
+// same table (or sequence, function, extension kind, row count, data checksum, or size), or one of them failed to fetch it, the latest
+// schema wins.
+// The merged schema takes its name and server version from the first schema, and lacks every
+// feature that any of the schemas lacks.
+//
+// Parameters:
+//   - schemas: Schemas to merge, in increasing order of precedence
+//
+// Returns:
+//   - *Schema: Merged schema
+func Merge(schemas ...*Schema) *Schema {
+	merged := NewSchema()
+	for i, s := range schemas {
+		if i == 0 {
+			merged.Name = s.Name
+			merged.ServerVersion = s.ServerVersion
+		}
+		failed := s.FailedTables()
+		var kept []FetchError
+		for _, fetchErr := range merged.Errors {
+			_, defined := s.Tables[fetchErr.Table]
+			_, failedAgain := failed[fetchErr.Table]
+			if !defined && !failedAgain {
+				kept = append(kept, fetchErr)
+			}
+		}
+		merged.Errors = append(kept, s.Errors...)
+		for _, feature := range s.UnsupportedFeatures {
+			if merged.Supports(feature) {
+				merged.UnsupportedFeatures = append(merged.UnsupportedFeatures, feature)
+			}
+		}
+
+		for name := range failed {
+			delete(merged.Tables, name)
+		}
+		for name, table := range s.Tables {
+			merged.Tables[name] = table.Clone()
+		}
+		for name, count := range s.RowCounts {
+			if merged.RowCounts == nil {
+				merged.RowCounts = make(map[string]int64)
+			}
+			merged.RowCounts[name] = count
+		}
+		if s.RowCountMode != "" {
+			merged.RowCountMode = s.RowCountMode
+		}
+		for name, checksum := range s.DataChecksums {
+			if merged.DataChecksums == nil {
+				merged.DataChecksums = make(map[string]DataChecksum)
+			}
+			checksum.Columns = append([]string(nil), checksum.Columns...)
+			merged.DataChecksums[name] = checksum
+		}
+		for name, size := range s.Sizes {
+			if merged.Sizes == nil {
+				merged.Sizes = make(map[string]TableSize)
+			}
+			merged.Sizes[name] = size.clone()
+		}
+		for name, seq := range s.Sequences {
+			if merged.Sequences == nil {
+				merged.Sequences = make(map[string]SequenceInfo)
+			}
+			merged.Sequences[name] = seq
+		}
+		for signature, fn := range s.Functions {
+			if merged.Functions == nil {
+				merged.Functions = make(map[string]FunctionInfo)
+			}
+			merged.Functions[signature] = fn
+		}
+		for kind, value := range s.Extensions {
+			if merged.Extensions == nil {
+				merged.Extensions = make(map[string]any)
+			}
+			merged.Extensions[kind] = value
+		}
+	}
+	return merged
+}
+
+// Clone returns a deep copy of the table.
+//
+// Returns:
+//   - TableInfo: Independent copy of the table
+func (t TableInfo) Clone() TableInfo {
+	copied := t
+	copied.Columns = append([]ColumnInfo(nil), t.Columns...)
+	copied.PrimaryKeys = append([]string(nil), t.PrimaryKeys...)
+	copied.Indexes = nil
+	for _, idx := range t.Indexes {
+		idx.Columns = append([]string(nil), idx.Columns...)
+		idx.Expressions = append([]IndexExpression(nil), idx.Expressions...)
+		idx.Include = append([]string(nil), idx.Include...)
+		copied.Indexes = append(copied.Indexes, idx)
+	}
+	copied.ForeignKeys = nil
+	for _, fk := range t.ForeignKeys {
+		fk.Columns = append([]string(nil), fk.Columns...)
+		fk.ReferencedColumns = append([]string(nil), fk.ReferencedColumns...)
+		copied.ForeignKeys = append(copied.ForeignKeys, fk)
+	}
+	copied.Triggers = nil
+	for _, trigger := range t.Triggers {
+		trigger.Events = append([]string(nil), trigger.Events...)
+		trigger.Columns = append([]string(nil), trigger.Columns...)
+		copied.Triggers = append(copied.Triggers, trigger)
+	}
+	if t.Hypertable != nil {
+		hypertable := *t.Hypertable
+		hypertable.Dimensions = append([]DimensionInfo(nil), t.Hypertable.Dimensions...)
+		copied.Hypertable = &hypertable
+	}
+	if t.Distribution != nil {
+		distribution := *t.Distribution
+		copied.Distribution = &distribution
+	}
+	if t.View != nil {
+		view := *t.View
+		view.References = append([]string(nil), t.View.References...)
+		copied.View = &view
+	}
+	return copied
+}
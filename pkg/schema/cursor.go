@@ -0,0 +1,183 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// beginner is implemented by connections that can begin a transaction, such as *pgx.Conn,
+// *pgxpool.Pool, and pgx.Tx (which begins a savepoint). Server-side cursors and staged catalog
+// queries only live in a transaction, so they are only used on such connections.
+type beginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// beginTx begins a transaction on a connection, to hold cursors or staged catalog queries. The
+// transaction of a rate-limited connection shares its limit, each query counting as one.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//
+// Returns:
+//   - pgx.Tx: Transaction begun, or nil if conn cannot begin transactions
+//   - error: Any error that occurred while beginning the transaction
+func beginTx(ctx context.Context, conn Querier) (pgx.Tx, error) {
+	var limit *rateLimited
+	switch c := conn.(type) {
+	case *rateLimited:
+		limit, conn = c, c.conn
+	case *rateLimitedBatcher:
+		limit, conn = c.rateLimited, c.conn
+	}
+	b, ok := conn.(beginner)
+	if !ok {
+		return nil, nil
+	}
+
+	if limit != nil {
+		if err := limit.wait(ctx, 1); err != nil {
+			return nil, err
+		}
+	}
+	tx, err := b.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	if limit != nil {
+		return rateLimitedTx{Tx: tx, limit: limit}, nil
+	}
+	return tx, nil
+}
+
+// rateLimitedTx is a transaction whose queries share the limit of the rate-limited connection
+// it was begun from.
+type rateLimitedTx struct {
+	pgx.Tx
+	limit *rateLimited // Connection whose limit the queries wait for
+}
+
+// Query waits for the turn of the query, then sends it.
+func (t rateLimitedTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if err := t.limit.wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	return t.Tx.Query(ctx, sql, args...)
+}
+
+// Exec waits for the turn of the statement, then sends it.
+func (t rateLimitedTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if err := t.limit.wait(ctx, 1); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return t.Tx.Exec(ctx, sql, args...)
+}
+
+// SendBatch waits for the turns of all the queries of the batch, then sends it.
+func (t rateLimitedTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	if err := t.limit.wait(ctx, b.Len()); err != nil {
+		return failedBatch{err}
+	}
+	return t.Tx.SendBatch(ctx, b)
+}
+
+// queryCursor declares a server-side cursor for a query and returns its rows, which are fetched
+// size rows at a time as they are read, so that neither the client nor a single statement has to
+// handle the whole result at once.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - tx: Transaction to declare the cursor in
+//   - name: Name of the cursor, unique within the transaction
+//   - size: Number of rows fetched at a time
+//   - sql: Query to read
+//   - args: Arguments of the query
+//
+// Returns:
+//   - pgx.Rows: Rows of the query
+//   - error: Any error that occurred while declaring the cursor or fetching its first rows
+func queryCursor(ctx context.Context, tx pgx.Tx, name string, size int, sql string, args ...any) (pgx.Rows, error) {
+	if _, err := tx.Exec(ctx, "DECLARE "+name+" NO SCROLL CURSOR FOR "+sql, args...); err != nil {
+		return nil, err
+	}
+	cursor := &cursorRows{ctx: ctx, tx: tx, fetch: fmt.Sprintf("FETCH %d FROM %s", size, name), size: size}
+	if err := cursor.fetchNext(); err != nil {
+		return nil, err
+	}
+	return cursor, nil
+}
+
+// cursorRows are the rows of a server-side cursor, read one FETCH at a time. It implements
+// pgx.Rows by delegating to the rows of the current FETCH.
+type cursorRows struct {
+	ctx   context.Context // Context the rows are fetched with
+	tx    pgx.Tx          // Transaction the cursor was declared in
+	fetch string          // Statement fetching the next rows of the cursor
+	size  int             // Number of rows each FETCH asks for
+
+	rows pgx.Rows // Rows of the current FETCH
+	read int      // Number of rows read from the current FETCH
+	err  error    // Error fetching the next rows failed with, if any
+	done bool     // Whether the cursor has no more rows
+}
+
+// fetchNext replaces the current rows with the next rows of the cursor.
+func (c *cursorRows) fetchNext() error {
+	rows, err := c.tx.Query(c.ctx, c.fetch)
+	if err != nil {
+		return err
+	}
+	c.rows, c.read = rows, 0
+	return nil
+}
+
+// Next advances to the next row, fetching more rows of the cursor once the current ones are
+// read. A FETCH returning fewer rows than asked for ends the cursor.
+func (c *cursorRows) Next() bool {
+	for !c.done && c.err == nil {
+		if c.rows.Next() {
+			c.read++
+			return true
+		}
+		if c.rows.Err() != nil || c.read < c.size {
+			c.done = true
+			return false
+		}
+		c.err = c.fetchNext()
+	}
+	return false
+}
+
+// Close closes the rows of the current FETCH. The cursor itself is closed with its transaction.
+func (c *cursorRows) Close() { c.rows.Close() }
+
+// Err returns the error the rows failed with, if any.
+func (c *cursorRows) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.rows.Err()
+}
+
+// CommandTag returns the command tag of the current FETCH.
+func (c *cursorRows) CommandTag() pgconn.CommandTag { return c.rows.CommandTag() }
+
+// FieldDescriptions returns the fields of the rows.
+func (c *cursorRows) FieldDescriptions() []pgconn.FieldDescription {
+	return c.rows.FieldDescriptions()
+}
+
+// Scan reads the values of the current row.
+func (c *cursorRows) Scan(dest ...any) error { return c.rows.Scan(dest...) }
+
+// Values returns the decoded values of the current row.
+func (c *cursorRows) Values() ([]any, error) { return c.rows.Values() }
+
+// RawValues returns the undecoded values of the current row.
+func (c *cursorRows) RawValues() [][]byte { return c.rows.RawValues() }
+
+// Conn returns the connection the rows are fetched through.
+func (c *cursorRows) Conn() *pgx.Conn { return c.rows.Conn() }
@@ -0,0 +1,180 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Ways of checksumming the data of tables (see FetchOptions.DataChecksums).
+const (
+	DataChecksumsFull   = "full"   // Every row of each table
+	DataChecksumsSample = "sample" // The first rows of each table in primary key order
+)
+
+// DataChecksumModes lists the settings of FetchOptions.DataChecksums.
+var DataChecksumModes = []string{DataChecksumsFull, DataChecksumsSample}
+
+// DefaultDataSampleRows is the number of rows hashed per table by sampled checksums, unless
+// FetchOptions.DataSampleRows sets another.
+const DefaultDataSampleRows = 10000
+
+// DataChecksum is the checksum of the data of a table. The hashes of the rows are summed, so the
+// checksum does not depend on the order the rows are read in, and two tables holding the same
+// values in the same columns have the same checksum.
+type DataChecksum struct {
+	Rows    int64    `json:"rows"`             // Number of rows hashed
+	Hash    string   `json:"hash"`             // Sum of the md5 hashes of the text of the rows, truncated to 64 bits
+	Columns []string `json:"columns"`          // Columns hashed, in name order
+	Sample  int      `json:"sample,omitempty"` // Maximum number of rows hashed, in primary key order; zero if every row was
+}
+
+// checkDataChecksums checks that the data checksum setting is known.
+//
+// Parameters:
+//   - mode: Setting of FetchOptions.DataChecksums
+//
+// Returns:
+//   - error: An error if the setting is unknown
+func checkDataChecksums(mode string) error {
+	switch mode {
+	case "", DataChecksumsFull, DataChecksumsSample:
+		return nil
+	}
+	return fmt.Errorf("unknown data checksum mode '%s': expected %s or %s", mode, DataChecksumsFull, DataChecksumsSample)
+}
+
+// checksumData checksums the data of the fetched tables whose details are wanted. Views and
+// foreign tables are left out, as are the tables the role may not read and, for sampled
+// checksums, the tables without a primary key. The checksum of a partitioned or inheritance
+// parent includes the rows of its partitions or children, as a query on it does.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - cat: Catalog queries of the database's dialect
+//   - s: Schema whose tables have been fetched
+//   - opts: Options holding the checksum settings
+//
+// Returns:
+//   - map[string]DataChecksum: Checksums keyed by table name
+//   - error: Any error that occurred during the queries
+func checksumData(ctx context.Context, conn Querier, cat catalog, s *Schema, opts FetchOptions) (map[string]DataChecksum, error) {
+	if !cat.dataChecksums {
+		return nil, fmt.Errorf("data checksums are only computed on PostgreSQL and Aurora")
+	}
+	sample := 0
+	if opts.DataChecksums == DataChecksumsSample {
+		sample = opts.DataSampleRows
+		if sample <= 0 {
+			sample = DefaultDataSampleRows
+		}
+	}
+
+	// Only tables are checksummed, as reading a view runs its query
+	var countable []string
+	err := opts.Retry.Do(ctx, func() error {
+		countable = countable[:0]
+		return forEachRow(ctx, conn, countableTablesQuery, s.Name, func(rows scanner) error {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return err
+			}
+			if table, ok := s.Tables[name]; ok && len(table.Columns) > 0 && (opts.Details == nil || opts.Details(table)) {
+				countable = append(countable, name)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the tables to checksum: %w", err)
+	}
+	slices.Sort(countable)
+
+	checksums := make(map[string]DataChecksum, len(countable))
+	for _, name := range countable {
+		table := s.Tables[name]
+		if sample > 0 && len(table.PrimaryKeys) == 0 {
+			continue
+		}
+		checksum := DataChecksum{Columns: hashedColumns(table, opts.DataColumns[name]), Sample: sample}
+		if len(checksum.Columns) == 0 {
+			continue
+		}
+		query := dataChecksumQuery(s.Name, table, checksum.Columns, sample)
+		err := opts.Retry.Do(ctx, func() error {
+			rows, err := conn.Query(ctx, query)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			if rows.Next() {
+				if err := rows.Scan(&checksum.Rows, &checksum.Hash); err != nil {
+					return err
+				}
+			}
+			rows.Close()
+			return rows.Err()
+		})
+		if isMissingFeature(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error checksumming the data of %s: %w", name, err)
+		}
+		checksums[name] = checksum
+	}
+	return checksums, nil
+}
+
+// hashedColumns returns the columns of a table whose values are hashed, in name order.
+//
+// Parameters:
+//   - table: Table being checksummed
+//   - selected: Columns selected for the table; nil selects every column
+//
+// Returns:
+//   - []string: Selected columns the table has, in name order
+func hashedColumns(table TableInfo, selected []string) []string {
+	var columns []string
+	for _, col := range table.Columns {
+		if selected == nil || slices.Contains(selected, col.Name) {
+			columns = append(columns, col.Name)
+		}
+	}
+	slices.Sort(columns)
+	return columns
+}
+
+// dataChecksumQuery returns the query checksumming the data of a table: the number of rows and
+// the sum of the first 64 bits of the md5 hash of the text of each row, as a decimal.
+//
+// Parameters:
+//   - schemaName: PostgreSQL schema of the table
+//   - table: Table to checksum
+//   - columns: Columns hashed, in name order
+//   - sample: Number of rows hashed in primary key order; zero hashes every row
+//
+// Returns:
+//   - string: Query returning the number of rows and the checksum
+func dataChecksumQuery(schemaName string, table TableInfo, columns []string, sample int) string {
+	quote := func(names []string) string {
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			quoted[i] = pgx.Identifier{name}.Sanitize()
+		}
+		return strings.Join(quoted, ", ")
+	}
+
+	from := pgx.Identifier{schemaName, table.Name}.Sanitize()
+	if sample > 0 {
+		from = fmt.Sprintf("(SELECT %s FROM %s ORDER BY %s LIMIT %s) AS sample",
+			quote(columns), from, quote(table.PrimaryKeys), strconv.Itoa(sample))
+	}
+	return fmt.Sprintf("SELECT count(*), COALESCE(sum(('x' || substr(md5(ROW(%s)::text), 1, 16))::bit(64)::bigint), 0)::text FROM %s",
+		quote(columns), from)
+}
@@ -0,0 +1,236 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Classes of error returned (wrapped) by Fetch and Connect, so that callers can tell them apart
+// with errors.Is and react accordingly. The original error remains in the chain, so errors.As
+// can still extract the underlying *pgconn.PgError.
+var (
+	ErrConnectionFailed         = errors.New("connection failed")          // The database could not be reached, or the connection was lost
+	ErrInsufficientPrivileges   = errors.New("insufficient privileges")    // The role lacks the privileges to read the catalog
+	ErrUnsupportedServerVersion = errors.New("unsupported server version") // The server lacks a catalog feature the queries rely on (see versionGatedObjects)
+	ErrQueryTimeout             = errors.New("query timeout")              // The server cancelled a catalog query at its statement_timeout or lock_timeout
+)
+
+// ErrConnectionPooler (see pooler.go) is another class of error returned by Fetch and Connect.
+
+// SQLSTATE codes used to classify server errors.
+const (
+	sqlStateInsufficientPrivilege = "42501" // insufficient_privilege
+	sqlStateUndefinedFunction     = "42883" // undefined_function
+	sqlStateUndefinedColumn       = "42703" // undefined_column
+	sqlStateUndefinedTable        = "42P01" // undefined_table
+	sqlStateFeatureNotSupported   = "0A000" // feature_not_supported
+	sqlStateClassConnection       = "08"    // Class 08: connection exception
+	sqlStateQueryCanceled         = "57014" // query_canceled, raised at statement_timeout
+	sqlStateLockNotAvailable      = "55P03" // lock_not_available, raised at lock_timeout
+)
+
+// versionGatedObjects are the catalog relations, columns, and functions used by the catalog
+// queries that some supported servers lack, because they were added or removed by a release of
+// PostgreSQL or are missing from an engine based on it. A query failing on an undefined object
+// means that the server is not the version or engine the queries were chosen for only when the
+// object is one of them; any other undefined object, such as a table dropped while it was read,
+// is an ordinary error.
+var versionGatedObjects = map[string]bool{
+	"pg_sequence":        true, // Added in PostgreSQL 10, with its seq* columns
+	"seqtypid":           true,
+	"attidentity":        true, // Added in PostgreSQL 10
+	"relispartition":     true, // Added in PostgreSQL 10
+	"pg_get_partkeydef":  true, // Added in PostgreSQL 10
+	"indnkeyatts":        true, // Added in PostgreSQL 11
+	"prokind":            true, // Added in PostgreSQL 11
+	"proisagg":           true, // Removed in PostgreSQL 11
+	"attcompression":     true, // Added in PostgreSQL 14
+	"pg_get_functiondef": true, // Missing from CockroachDB and Redshift
+	"pg_get_triggerdef":  true, // Missing from CockroachDB and Redshift
+}
+
+// Connect opens a connection to a PostgreSQL database with the timeouts of session, wrapping
+// any failure in ErrConnectionFailed. Behind a pooler sharing server sessions between
+// transactions (see SessionSettings.Pooler), the connection sends no named prepared statements
+// and sets no timeouts.
+//
+// Parameters:
+//   - ctx: Context bounding the connection attempt
+//   - connString: PostgreSQL connection string
+//   - session: Timeouts set on the session
+//
+// Returns:
+//   - *pgx.Conn: Open connection
+//   - error: An error wrapping ErrConnectionFailed (or ErrInsufficientPrivileges if the role may not connect)
+func Connect(ctx context.Context, connString string, session SessionSettings) (*pgx.Conn, error) {
+	if err := session.checkPooler(); err != nil {
+		return nil, err
+	}
+	config, err := pgx.ParseConfig(connString)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+
+	conn, err := connectAdapted(ctx, config, session)
+	if err != nil {
+		classified := classifyError(err)
+		if classified == err {
+			classified = fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+		}
+		return nil, classified
+	}
+	return conn, nil
+}
+
+// ConnectPool opens a pool of up to maxConns connections to a PostgreSQL database, as needed to
+// fetch with FetchOptions.Concurrency, and checks that the database can be reached. Every
+// connection of the pool is given the timeouts of session, and adapted to poolers as those of
+// Connect. Failures are wrapped like those of Connect.
+//
+// Parameters:
+//   - ctx: Context bounding the connection attempt
+//   - connString: PostgreSQL connection string
+//   - maxConns: Maximum number of connections of the pool
+//   - session: Timeouts set on each session of the pool
+//
+// Returns:
+//   - *pgxpool.Pool: Open pool
+//   - error: An error wrapping ErrConnectionFailed (or ErrInsufficientPrivileges if the role may not connect)
+func ConnectPool(ctx context.Context, connString string, maxConns int, session SessionSettings) (*pgxpool.Pool, error) {
+	if err := session.checkPooler(); err != nil {
+		return nil, err
+	}
+	config, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+	config.MaxConns = int32(maxConns)
+
+	pooled, probe := session.transactionPooling(&config.ConnConfig.Config)
+	pool, err := openPool(ctx, config, session.adapt(config.ConnConfig, pooled, probe))
+	if probe {
+		if pgBouncer, refused := probeOutcome(&config.ConnConfig.Config, err); pgBouncer || refused {
+			pool, err = openPool(ctx, config, session.adapt(config.ConnConfig, pgBouncer, false))
+		}
+	}
+	if err != nil {
+		classified := classifyError(err)
+		if classified == err {
+			classified = fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+		}
+		return nil, classified
+	}
+	return pool, nil
+}
+
+// openPool opens a pool and checks that the database can be reached.
+//
+// Parameters:
+//   - ctx: Context bounding the connection attempt
+//   - config: Settings of the pool, left unchanged
+//   - connConfig: Settings of the connections of the pool
+//
+// Returns:
+//   - *pgxpool.Pool: Open pool
+//   - error: Any error that occurred while connecting
+func openPool(ctx context.Context, config *pgxpool.Config, connConfig *pgx.ConnConfig) (*pgxpool.Pool, error) {
+	config = config.Copy()
+	config.ConnConfig = connConfig
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err == nil {
+		err = pool.Ping(ctx)
+	}
+	if err != nil {
+		if pool != nil {
+			pool.Close()
+		}
+		return nil, err
+	}
+	return pool, nil
+}
+
+// classifyError wraps an error in the error class matching its cause. Errors of no known class,
+// including cancellations, are returned unchanged.
+//
+// Parameters:
+//   - err: Error to classify
+//
+// Returns:
+//   - error: The error, wrapped in ErrConnectionFailed, ErrConnectionPooler, ErrInsufficientPrivileges, ErrUnsupportedServerVersion, or ErrQueryTimeout if applicable
+func classifyError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case isPoolerError(pgErr):
+			return fmt.Errorf("%w: %w", ErrConnectionPooler, err)
+		case pgErr.Code == sqlStateInsufficientPrivilege:
+			return fmt.Errorf("%w: %w", ErrInsufficientPrivileges, err)
+		case pgErr.Code == sqlStateFeatureNotSupported, isUndefinedObject(pgErr) && namesVersionGatedObject(pgErr):
+			return fmt.Errorf("%w: %w", ErrUnsupportedServerVersion, err)
+		case strings.HasPrefix(pgErr.Code, sqlStateClassConnection):
+			return fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+		case pgErr.Code == sqlStateQueryCanceled, pgErr.Code == sqlStateLockNotAvailable:
+			return fmt.Errorf("%w: %w", ErrQueryTimeout, err)
+		}
+		return err
+	}
+
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	if errors.As(err, &connectErr) || errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || pgconn.SafeToRetry(err) {
+		return fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	}
+	return err
+}
+
+// isUndefinedObject reports whether a server error is about an undefined relation, column, or
+// function.
+func isUndefinedObject(pgErr *pgconn.PgError) bool {
+	return pgErr.Code == sqlStateUndefinedTable || pgErr.Code == sqlStateUndefinedColumn || pgErr.Code == sqlStateUndefinedFunction
+}
+
+// namesVersionGatedObject reports whether the message of a server error names one of
+// versionGatedObjects, as in `column p.prokind does not exist` or
+// `relation "pg_sequence" does not exist`.
+func namesVersionGatedObject(pgErr *pgconn.PgError) bool {
+	words := strings.FieldsFunc(pgErr.Message, func(r rune) bool {
+		return r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return slices.ContainsFunc(words, func(word string) bool {
+		return versionGatedObjects[word]
+	})
+}
+
+// isMissingFeature reports whether an error means that a catalog object the query relies on does
+// not exist or cannot be read, so that the feature it describes can be skipped. Unlike
+// classifyError, any undefined object counts, such as the catalog of an extension that is not
+// installed.
+//
+// Parameters:
+//   - err: Error returned by a catalog query
+//
+// Returns:
+//   - bool: True if the error is an insufficient privilege, undefined object, or unsupported feature error
+func isMissingFeature(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && isUndefinedObject(pgErr) {
+		return true
+	}
+	classified := classifyError(err)
+	return errors.Is(classified, ErrInsufficientPrivileges) || errors.Is(classified, ErrUnsupportedServerVersion)
+}
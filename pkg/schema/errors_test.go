@@ -0,0 +1,39 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestClassifyErrorUndefinedObjects(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         *pgconn.PgError
+		unsupported bool
+	}{
+		{"column added in a later release", &pgconn.PgError{Code: sqlStateUndefinedColumn, Message: "column p.prokind does not exist"}, true},
+		{"relation added in a later release", &pgconn.PgError{Code: sqlStateUndefinedTable, Message: `relation "pg_sequence" does not exist`}, true},
+		{"function missing from the engine", &pgconn.PgError{Code: sqlStateUndefinedFunction, Message: "function pg_get_functiondef(oid) does not exist"}, true},
+		{"feature not supported", &pgconn.PgError{Code: sqlStateFeatureNotSupported, Message: "unimplemented: this syntax"}, true},
+		{"table dropped while read", &pgconn.PgError{Code: sqlStateUndefinedTable, Message: `relation "public.orders" does not exist`}, false},
+		{"column dropped while read", &pgconn.PgError{Code: sqlStateUndefinedColumn, Message: `column "total" does not exist`}, false},
+		{"name containing a gated name", &pgconn.PgError{Code: sqlStateUndefinedTable, Message: `relation "pg_sequence_archive" does not exist`}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			classified := classifyError(tt.err)
+			if got := errors.Is(classified, ErrUnsupportedServerVersion); got != tt.unsupported {
+				t.Errorf("classifyError(%q) is ErrUnsupportedServerVersion = %v, want %v", tt.err.Message, got, tt.unsupported)
+			}
+			var pgErr *pgconn.PgError
+			if !errors.As(classified, &pgErr) || pgErr != tt.err {
+				t.Errorf("classifyError(%q) lost the original error: %v", tt.err.Message, classified)
+			}
+			if !isMissingFeature(tt.err) {
+				t.Errorf("isMissingFeature(%q) = false, want true", tt.err.Message)
+			}
+		})
+	}
+}
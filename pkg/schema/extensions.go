@@ -0,0 +1,305 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+)
+
+// HypertableInfo describes a TimescaleDB hypertable, or a continuous aggregate (a view
+// materialized into a hypertable of its own).
+type HypertableInfo struct {
+	ContinuousAggregate bool            `json:"continuous_aggregate,omitempty"` // Whether the table is a continuous aggregate rather than a hypertable
+	Dimensions          []DimensionInfo `json:"dimensions,omitempty"`           // Dimensions the hypertable is partitioned into chunks by, in order
+	Compression         bool            `json:"compression,omitempty"`          // Whether native compression is enabled
+	MaterializedOnly    bool            `json:"materialized_only,omitempty"`    // Whether a continuous aggregate only returns materialized data
+}
+
+// DimensionInfo describes one dimension a hypertable is partitioned by.
+type DimensionInfo struct {
+	Column     string `json:"column"`               // Name of the partitioning column
+	Type       string `json:"type"`                 // Kind of dimension: "time" or "space"
+	Interval   string `json:"interval,omitempty"`   // Chunk interval of a time dimension (e.g., "7 days")
+	Partitions int    `json:"partitions,omitempty"` // Number of partitions of a space dimension
+}
+
+// String formats the dimension as shown in differences (e.g., "created_at (time, 7 days)").
+func (d DimensionInfo) String() string {
+	if d.Type == "space" {
+		return fmt.Sprintf("%s (space, %d partitions)", d.Column, d.Partitions)
+	}
+	return fmt.Sprintf("%s (%s, %s)", d.Column, d.Type, d.Interval)
+}
+
+// DistributionInfo describes how Citus spreads a table across the nodes of a cluster.
+type DistributionInfo struct {
+	Type       string `json:"type"`                  // Kind of Citus table: "distributed", "reference", or "local"
+	Column     string `json:"column,omitempty"`      // Distribution column of a distributed table
+	ShardCount int    `json:"shard_count,omitempty"` // Number of shards of a distributed table
+}
+
+// extensionInfo holds the TimescaleDB and Citus metadata of a schema, read before the details
+// of its tables.
+type extensionInfo struct {
+	internal     map[string]bool              // Chunk and shard tables, which are left out of the schema
+	hypertables  map[string]*HypertableInfo   // Hypertables and continuous aggregates, keyed by name
+	distribution map[string]*DistributionInfo // Citus tables, keyed by name
+	unsupported  []string                     // Extensions whose metadata could not be read (FeatureTimescale, FeatureCitus)
+}
+
+// fetchExtensionInfo reads the TimescaleDB and Citus metadata of a schema, if those extensions
+// are installed in the database. Metadata that cannot be read because the installed release of
+// an extension lacks it, or because it is not readable by the current role, is skipped and the
+// extension recorded as unsupported, rather than failing the fetch.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - schemaName: PostgreSQL schema to read the metadata of
+//
+// Returns:
+//   - extensionInfo: Metadata of the schema; empty if neither extension is installed
+//   - error: Any error that occurred during the queries
+func fetchExtensionInfo(ctx context.Context, conn Querier, schemaName string) (extensionInfo, error) {
+	info := extensionInfo{
+		internal:     make(map[string]bool),
+		hypertables:  make(map[string]*HypertableInfo),
+		distribution: make(map[string]*DistributionInfo),
+	}
+
+	installed, err := installedExtensions(ctx, conn)
+	if err != nil {
+		return info, err
+	}
+	if installed["timescaledb"] {
+		if err := fetchTimescaleInfo(ctx, conn, schemaName, &info); isMissingFeature(err) {
+			info.unsupported = append(info.unsupported, FeatureTimescale)
+		} else if err != nil {
+			return info, fmt.Errorf("error fetching TimescaleDB metadata: %w", err)
+		}
+	}
+	if installed["citus"] {
+		if err := fetchCitusInfo(ctx, conn, schemaName, &info); isMissingFeature(err) {
+			info.unsupported = append(info.unsupported, FeatureCitus)
+		} else if err != nil {
+			return info, fmt.Errorf("error fetching Citus metadata: %w", err)
+		}
+	}
+	return info, nil
+}
+
+// installedExtensions returns which of the extensions this package knows about are installed.
+func installedExtensions(ctx context.Context, conn Querier) (map[string]bool, error) {
+	rows, err := conn.Query(ctx, "SELECT extname FROM pg_catalog.pg_extension WHERE extname IN ('timescaledb', 'citus')")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching extensions: %w", err)
+	}
+	defer rows.Close()
+
+	installed := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("error scanning extension: %w", err)
+		}
+		installed[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating extensions: %w", err)
+	}
+	return installed, nil
+}
+
+// fetchTimescaleInfo reads the chunks, hypertables, and continuous aggregates of a schema.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - schemaName: PostgreSQL schema to read the metadata of
+//   - info: Metadata the results are added to
+//
+// Returns:
+//   - error: Any error that occurred during the queries
+func fetchTimescaleInfo(ctx context.Context, conn Querier, schemaName string, info *extensionInfo) error {
+	// Chunks normally live in _timescaledb_internal, but can be placed in any schema
+	err := forEachRow(ctx, conn, timescaleChunksQuery, schemaName, func(rows scanner) error {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		info.internal[name] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = forEachRow(ctx, conn, timescaleHypertablesQuery, schemaName, func(rows scanner) error {
+		var name string
+		var hypertable HypertableInfo
+		if err := rows.Scan(&name, &hypertable.Compression); err != nil {
+			return err
+		}
+		info.hypertables[name] = &hypertable
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = forEachRow(ctx, conn, timescaleDimensionsQuery, schemaName, func(rows scanner) error {
+		var name string
+		var dim DimensionInfo
+		if err := rows.Scan(&name, &dim.Column, &dim.Type, &dim.Interval, &dim.Partitions); err != nil {
+			return err
+		}
+		if hypertable, exists := info.hypertables[name]; exists {
+			hypertable.Dimensions = append(hypertable.Dimensions, dim)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return forEachRow(ctx, conn, timescaleContinuousAggregatesQuery, schemaName, func(rows scanner) error {
+		var name string
+		aggregate := HypertableInfo{ContinuousAggregate: true}
+		if err := rows.Scan(&name, &aggregate.MaterializedOnly, &aggregate.Compression); err != nil {
+			return err
+		}
+		info.hypertables[name] = &aggregate
+		return nil
+	})
+}
+
+// fetchCitusInfo reads the shards and the distribution of the Citus tables of a schema.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - schemaName: PostgreSQL schema to read the metadata of
+//   - info: Metadata the results are added to
+//
+// Returns:
+//   - error: Any error that occurred during the queries
+func fetchCitusInfo(ctx context.Context, conn Querier, schemaName string, info *extensionInfo) error {
+	// Shards are only visible as tables on worker nodes, named after their table and shard id
+	err := forEachRow(ctx, conn, citusShardsQuery, schemaName, func(rows scanner) error {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		info.internal[name] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return forEachRow(ctx, conn, citusTablesQuery, schemaName, func(rows scanner) error {
+		var name string
+		var distribution DistributionInfo
+		if err := rows.Scan(&name, &distribution.Type, &distribution.Column, &distribution.ShardCount); err != nil {
+			return err
+		}
+		info.distribution[name] = &distribution
+		return nil
+	})
+}
+
+// apply records the extension metadata of a table on it.
+//
+// Parameters:
+//   - table: Table to update
+func (e extensionInfo) apply(table *TableInfo) {
+	table.Hypertable = e.hypertables[table.Name]
+	table.Distribution = e.distribution[table.Name]
+}
+
+// scanner is the part of pgx.Rows used to read a row.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// forEachRow runs a query taking the schema name as $1 and calls fn on each row.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - query: Query to run
+//   - schemaName: PostgreSQL schema passed to the query
+//   - fn: Function reading one row
+//
+// Returns:
+//   - error: Any error that occurred during the query or returned by fn
+func forEachRow(ctx context.Context, conn Querier, query, schemaName string, fn func(rows scanner) error) error {
+	rows, err := conn.Query(ctx, query, schemaName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Catalog queries for TimescaleDB and Citus metadata. Each takes the schema name as $1.
+const (
+	timescaleChunksQuery = `
+	SELECT chunk_name
+	FROM timescaledb_information.chunks
+	WHERE chunk_schema = $1
+`
+
+	timescaleHypertablesQuery = `
+	SELECT hypertable_name, compression_enabled
+	FROM timescaledb_information.hypertables
+	WHERE hypertable_schema = $1
+`
+
+	timescaleDimensionsQuery = `
+	SELECT
+		hypertable_name,
+		column_name,
+		lower(dimension_type),
+		COALESCE(time_interval::text, integer_interval::text, ''),
+		COALESCE(num_partitions, 0)
+	FROM timescaledb_information.dimensions
+	WHERE hypertable_schema = $1
+	ORDER BY hypertable_name, dimension_number
+`
+
+	timescaleContinuousAggregatesQuery = `
+	SELECT view_name, materialized_only, compression_enabled
+	FROM timescaledb_information.continuous_aggregates
+	WHERE view_schema = $1
+`
+
+	citusShardsQuery = `
+	SELECT c.relname || '_' || s.shardid
+	FROM pg_dist_shard s
+	JOIN pg_class c ON c.oid = s.logicalrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE n.nspname = $1
+`
+
+	citusTablesQuery = `
+	SELECT
+		c.relname,
+		CASE
+			WHEN p.partmethod = 'h' THEN 'distributed'
+			WHEN p.repmodel = 't' THEN 'reference'
+			ELSE 'local'
+		END,
+		COALESCE(column_to_column_name(p.logicalrelid, p.partkey), ''),
+		(SELECT count(*) FROM pg_dist_shard s WHERE s.logicalrelid = p.logicalrelid AND p.partmethod = 'h')::int
+	FROM pg_dist_partition p
+	JOIN pg_class c ON c.oid = p.logicalrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE n.nspname = $1
+`
+)
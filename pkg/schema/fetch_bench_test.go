@@ -0,0 +1,60 @@
+package schema_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/guriandoro/pg_schema_check/pkg/bench"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// BenchmarkFetch fetches generated schemas from a bench.Catalog, which answers the catalog
+// queries from memory, at each of bench.TableCounts:
+//   - All reads a whole schema with schema.Fetch, with one query per kind of object;
+//   - Stream reads it DefaultBatchSize tables at a time with schema.StreamTables;
+//   - Filtered reads only 20 of its tables, selected with FetchOptions.IncludeTables.
+func BenchmarkFetch(b *testing.B) {
+	for _, tables := range bench.TableCounts() {
+		catalog := bench.NewCatalog(bench.Generate(tables, nil))
+
+		b.Run(fmt.Sprintf("All/%d", tables), func(b *testing.B) {
+			defer bench.TrackPeakHeap(b)()
+			for i := 0; i < b.N; i++ {
+				if _, err := schema.Fetch(context.Background(), catalog, bench.CatalogOptions); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("Stream/%d", tables), func(b *testing.B) {
+			defer bench.TrackPeakHeap(b)()
+			for i := 0; i < b.N; i++ {
+				stream, err := schema.StreamTables(context.Background(), catalog, bench.CatalogOptions, schema.DefaultBatchSize)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for {
+					_, ok, err := stream.Next(context.Background())
+					if err != nil {
+						b.Fatal(err)
+					}
+					if !ok {
+						break
+					}
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("Filtered/%d", tables), func(b *testing.B) {
+			opts := bench.CatalogOptions
+			opts.IncludeTables = []string{"t0000[01]?"}
+			defer bench.TrackPeakHeap(b)()
+			for i := 0; i < b.N; i++ {
+				if _, err := schema.Fetch(context.Background(), catalog, opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Fetcher is implemented by every source a Schema can be read from, such as a live database,
+// a snapshot file, or a fixed schema used in tests. The comparison and the CLI only depend on
+// this interface, so new sources can be plugged in without changing them.
+type Fetcher interface {
+	// Fetch reads the schema from the source.
+	Fetch(ctx context.Context) (*Schema, error)
+}
+
+// TableLister is implemented by Fetchers that can list the tables of a schema without fetching
+// their details, such as PgxFetcher.
+type TableLister interface {
+	// ListTables lists the tables of the schema with their table-level properties only.
+	ListTables(ctx context.Context) ([]TableInfo, error)
+}
+
+// TableStreamer is implemented by the fetchers that can also read their tables a batch at a
+// time, so that schemas too large to hold in memory can be compared (see TableStream), such as
+// PgxFetcher.
+type TableStreamer interface {
+	// StreamTables lists the tables of the schema and returns a stream reading their details
+	// batchSize tables at a time.
+	StreamTables(ctx context.Context, batchSize int) (TableStream, error)
+}
+
+// PgxFetcher is a Fetcher that reads the schema from a live PostgreSQL database through a
+// pgx connection, pool, or transaction.
+type PgxFetcher struct {
+	Conn    Querier      // Active PostgreSQL connection or pool
+	Options FetchOptions // Options controlling what is fetched
+}
+
+// NewPgxFetcher creates a Fetcher that reads the schema through the given connection.
+//
+// Parameters:
+//   - conn: Active PostgreSQL connection or pool
+//   - opts: Options controlling what is fetched
+//
+// Returns:
+//   - *PgxFetcher: Fetcher for the database behind the connection
+func NewPgxFetcher(conn Querier, opts FetchOptions) *PgxFetcher {
+	return &PgxFetcher{Conn: conn, Options: opts}
+}
+
+// NewCockroachFetcher creates a Fetcher that reads the schema of a CockroachDB cluster through
+// the given connection, using the CockroachDB catalog queries without detecting the dialect.
+// The fetched schema can be compared with another CockroachDB schema or with a PostgreSQL one.
+//
+// Parameters:
+//   - conn: Active connection or pool to the cluster
+//   - opts: Options controlling what is fetched; opts.Dialect is overridden
+//
+// Returns:
+//   - *PgxFetcher: Fetcher for the cluster behind the connection
+func NewCockroachFetcher(conn Querier, opts FetchOptions) *PgxFetcher {
+	opts.Dialect = DialectCockroach
+	return NewPgxFetcher(conn, opts)
+}
+
+// Fetch reads the schema from the database.
+func (f *PgxFetcher) Fetch(ctx context.Context) (*Schema, error) {
+	return Fetch(ctx, f.Conn, f.Options)
+}
+
+// ListTables lists the tables of the schema, without their details.
+func (f *PgxFetcher) ListTables(ctx context.Context) ([]TableInfo, error) {
+	return ListTables(ctx, f.Conn, f.Options)
+}
+
+// StreamTables lists the tables of the schema and returns a stream reading their details a batch
+// at a time.
+func (f *PgxFetcher) StreamTables(ctx context.Context, batchSize int) (TableStream, error) {
+	stream, err := StreamTables(ctx, f.Conn, f.Options, batchSize)
+	if err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// StaticFetcher is a Fetcher that always returns the same schema. It is mainly useful to
+// test code that consumes a Fetcher without a live database.
+type StaticFetcher struct {
+	Schema *Schema // Schema returned by Fetch
+}
+
+// Fetch returns the fixed schema.
+func (f StaticFetcher) Fetch(ctx context.Context) (*Schema, error) {
+	return f.Schema, nil
+}
+
+// StdlibFetcher is a Fetcher that reads the schema through a database/sql pool opened with
+// the pgx driver (github.com/jackc/pgx/v5/stdlib), including pools wrapped by sqlx.
+type StdlibFetcher struct {
+	DB      *sql.DB      // Connection pool using the pgx driver
+	Options FetchOptions // Options controlling what is fetched
+}
+
+// Fetch reads the schema from the database.
+func (f StdlibFetcher) Fetch(ctx context.Context) (*Schema, error) {
+	return FetchDB(ctx, f.DB, f.Options)
+}
+
+// FetchDB retrieves the complete schema information through a database/sql pool opened with
+// the pgx driver. A connection is taken from the pool for the duration of the fetch, so tables
+// are fetched one by one whatever opts.Concurrency says.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - db: Connection pool using the pgx driver
+//   - opts: Options controlling what is fetched
+//
+// Returns:
+//   - *Schema: Complete schema information
+//   - error: Any error that occurred during the fetch operation, including a pool using another driver
+func FetchDB(ctx context.Context, db *sql.DB, opts FetchOptions) (*Schema, error) {
+	opts.Concurrency = 1
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, classifyError(fmt.Errorf("error acquiring connection: %w", err))
+	}
+	defer conn.Close()
+
+	// The pgx connection can only be used while the raw driver connection is held
+	var fetched *Schema
+	err = conn.Raw(func(driverConn any) error {
+		stdlibConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("database/sql pool does not use the pgx driver (got %T)", driverConn)
+		}
+
+		var err error
+		fetched, err = Fetch(ctx, stdlibConn.Conn(), opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return fetched, nil
+}
@@ -0,0 +1,128 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+)
+
+// FunctionInfo describes a function or procedure: its signature and its definition.
+type FunctionInfo struct {
+	Name       string `json:"name"`            // Name of the function or procedure
+	Arguments  string `json:"arguments"`       // Types of its arguments, as pg_get_function_identity_arguments prints them (e.g., "integer, text")
+	Definition string `json:"definition"`      // CREATE OR REPLACE statement of the function, as pg_get_functiondef prints it
+	Owner      string `json:"owner,omitempty"` // Name of the role that owns the function
+}
+
+// Signature returns the name of the function with the types of its arguments (e.g.,
+// "audit_changes(text)"), which identifies it among the overloads of its name and keys
+// Schema.Functions.
+//
+// Returns:
+//   - string: Signature of the function
+func (f FunctionInfo) Signature() string {
+	return f.Name + "(" + f.Arguments + ")"
+}
+
+// readFunctions reads the functions and procedures of a schema whose names the table filters
+// keep, leaving out aggregates and the functions of extensions.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - cat: Catalog queries of the database's dialect
+//   - schemaName: PostgreSQL schema to read the functions of
+//   - opts: Options holding the table filters, applied to the names of the functions, and the retries
+//
+// Returns:
+//   - map[string]FunctionInfo: Functions keyed by signature, or nil if the dialect has none
+//   - error: Any error that occurred during the query
+func readFunctions(ctx context.Context, conn Querier, cat catalog, schemaName string, opts FetchOptions) (map[string]FunctionInfo, error) {
+	if cat.functionsQuery == "" {
+		return nil, nil
+	}
+	patterns, err := patternsOf(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var functions map[string]FunctionInfo
+	err = opts.Retry.Do(ctx, func() error {
+		functions = make(map[string]FunctionInfo)
+		rows, err := conn.Query(ctx, cat.functionsQuery, schemaName, patterns.include, patterns.exclude)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var fn FunctionInfo
+			if err := rows.Scan(&fn.Name, &fn.Arguments, &fn.Definition, &fn.Owner); err != nil {
+				return err
+			}
+			functions[fn.Signature()] = fn
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading functions: %w", err)
+	}
+	return functions, nil
+}
+
+// Catalog queries of functions. Each takes the schema name as $1, and the include and exclude
+// patterns of the table filters as $2 and $3. Aggregates are left out, as pg_get_functiondef
+// cannot print them, and so are the functions an extension creates.
+const (
+	postgresFunctionsQuery = `
+	SELECT
+		p.proname,
+		pg_get_function_identity_arguments(p.oid),
+		pg_get_functiondef(p.oid),
+		pg_get_userbyid(p.proowner)
+	FROM pg_proc p
+	JOIN pg_namespace n
+		ON n.oid = p.pronamespace
+	WHERE n.nspname = $1
+		AND p.prokind <> 'a'
+		AND NOT EXISTS (
+			SELECT 1 FROM pg_depend d
+			WHERE d.classid = 'pg_proc'::regclass
+				AND d.objid = p.oid
+				AND d.deptype = 'e'
+		)
+		AND (cardinality($2::text[]) = 0 OR p.proname ~ ANY($2::text[]))
+		AND NOT p.proname ~ ANY($3::text[])
+	ORDER BY p.proname, 2
+`
+
+	// PostgreSQL 10 and older have no prokind, and mark aggregates with proisagg
+	postgres10FunctionsQuery = `
+	SELECT
+		p.proname,
+		pg_get_function_identity_arguments(p.oid),
+		pg_get_functiondef(p.oid),
+		pg_get_userbyid(p.proowner)
+	FROM pg_proc p
+	JOIN pg_namespace n
+		ON n.oid = p.pronamespace
+	WHERE n.nspname = $1
+		AND NOT p.proisagg
+		AND NOT EXISTS (
+			SELECT 1 FROM pg_depend d
+			WHERE d.classid = 'pg_proc'::regclass
+				AND d.objid = p.oid
+				AND d.deptype = 'e'
+		)
+		AND (cardinality($2::text[]) = 0 OR p.proname ~ ANY($2::text[]))
+		AND NOT p.proname ~ ANY($3::text[])
+	ORDER BY p.proname, 2
+`
+)
+
+// appendDefinition appends an unambiguous encoding of every field of a function to a buffer,
+// as TableInfo.appendDefinition does for tables.
+func (f FunctionInfo) appendDefinition(buf []byte) []byte {
+	buf = appendString(buf, f.Name)
+	buf = appendString(buf, f.Arguments)
+	buf = appendString(buf, f.Definition)
+	return appendString(buf, f.Owner)
+}
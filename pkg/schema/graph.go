@@ -0,0 +1,238 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Kinds of object in a dependency graph.
+const (
+	ObjectTable      = "table"       // A table
+	ObjectView       = "view"        // A view or materialized view
+	ObjectIndex      = "index"       // An index of a table or materialized view
+	ObjectForeignKey = "foreign_key" // A foreign key constraint of a table
+)
+
+// ObjectRef identifies an object of a schema in a dependency graph.
+type ObjectRef struct {
+	Kind  string // Kind of object (ObjectTable, ObjectView, ObjectIndex, ObjectForeignKey)
+	Table string // Name of the table or view the object is, or belongs to
+	Name  string // Name of the object within its table; empty for tables
+}
+
+// TableRef returns the reference to a table.
+//
+// Parameters:
+//   - name: Name of the table
+//
+// Returns:
+//   - ObjectRef: Reference to the table
+func TableRef(name string) ObjectRef {
+	return ObjectRef{Kind: ObjectTable, Table: name}
+}
+
+// ViewRef returns the reference to a view or materialized view.
+//
+// Parameters:
+//   - name: Name of the view
+//
+// Returns:
+//   - ObjectRef: Reference to the view
+func ViewRef(name string) ObjectRef {
+	return ObjectRef{Kind: ObjectView, Table: name}
+}
+
+// relationRef returns the reference to a table or view of a schema, by its kind.
+func relationRef(s *Schema, name string) ObjectRef {
+	if s.Tables[name].View != nil {
+		return ViewRef(name)
+	}
+	return TableRef(name)
+}
+
+// String formats the reference for display (e.g., "foreign_key orders.fk_orders_customer").
+func (r ObjectRef) String() string {
+	if r.Name == "" {
+		return r.Kind + " " + r.Table
+	}
+	return r.Kind + " " + r.Table + "." + r.Name
+}
+
+// DependencyGraph records which objects of a schema depend on which others: an index depends on
+// its table, a foreign key on its table and on the table it references, a partition on its
+// parent table, and a view on the tables and views its query reads. It answers questions such as "what is affected if table X differs", and orders
+// objects so that each one comes after the objects it depends on.
+type DependencyGraph struct {
+	objects      map[ObjectRef]bool        // Every object of the schema
+	dependencies map[ObjectRef][]ObjectRef // Objects each object depends on, in insertion order
+	dependents   map[ObjectRef][]ObjectRef // Objects depending on each object, in insertion order
+}
+
+// DependencyGraph builds the dependency graph of the tables and views of the schema and their
+// indexes and foreign keys. Objects a foreign key, partition, or view refers to but that are not
+// part of the schema are left out of the graph, as are the dependencies of views whose
+// references are unknown (see ViewInfo.References).
+//
+// Returns:
+//   - *DependencyGraph: Dependency graph of the schema
+func (s *Schema) DependencyGraph() *DependencyGraph {
+	g := &DependencyGraph{
+		objects:      make(map[ObjectRef]bool),
+		dependencies: make(map[ObjectRef][]ObjectRef),
+		dependents:   make(map[ObjectRef][]ObjectRef),
+	}
+
+	names := make([]string, 0, len(s.Tables))
+	for name := range s.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		g.objects[relationRef(s, name)] = true
+	}
+	for _, name := range names {
+		table := s.Tables[name]
+		if table.PartitionOf != "" {
+			g.addDependency(TableRef(name), TableRef(table.PartitionOf))
+		}
+		if table.View != nil {
+			for _, referenced := range table.View.References {
+				g.addDependency(ViewRef(name), relationRef(s, referenced))
+			}
+		}
+		for _, idx := range table.Indexes {
+			ref := ObjectRef{Kind: ObjectIndex, Table: name, Name: idx.Name}
+			g.objects[ref] = true
+			g.addDependency(ref, relationRef(s, name))
+		}
+		for _, fk := range table.ForeignKeys {
+			ref := ObjectRef{Kind: ObjectForeignKey, Table: name, Name: fk.Name}
+			g.objects[ref] = true
+			g.addDependency(ref, TableRef(name))
+			if fk.ReferencedTable != name {
+				g.addDependency(ref, TableRef(fk.ReferencedTable))
+			}
+		}
+	}
+	return g
+}
+
+// addDependency records that an object depends on another, if both are part of the graph.
+func (g *DependencyGraph) addDependency(object, dependency ObjectRef) {
+	if !g.objects[object] || !g.objects[dependency] {
+		return
+	}
+	g.dependencies[object] = append(g.dependencies[object], dependency)
+	g.dependents[dependency] = append(g.dependents[dependency], object)
+}
+
+// Objects returns every object of the graph, sorted by table, kind, and name.
+//
+// Returns:
+//   - []ObjectRef: Objects of the graph
+func (g *DependencyGraph) Objects() []ObjectRef {
+	objects := make([]ObjectRef, 0, len(g.objects))
+	for ref := range g.objects {
+		objects = append(objects, ref)
+	}
+	sortRefs(objects)
+	return objects
+}
+
+// Dependencies returns the objects an object directly depends on.
+//
+// Parameters:
+//   - ref: Object to look up
+//
+// Returns:
+//   - []ObjectRef: Direct dependencies, sorted; empty if the object is not part of the graph
+func (g *DependencyGraph) Dependencies(ref ObjectRef) []ObjectRef {
+	deps := append([]ObjectRef(nil), g.dependencies[ref]...)
+	sortRefs(deps)
+	return deps
+}
+
+// Dependents returns the objects that depend on an object, directly or through other objects.
+// For a table, these are its indexes and foreign keys, the foreign keys of other tables
+// referencing it, its partitions, and the views reading it, with theirs.
+//
+// Parameters:
+//   - ref: Object to look up
+//
+// Returns:
+//   - []ObjectRef: Direct and indirect dependents, sorted; empty if the object is not part of the graph
+func (g *DependencyGraph) Dependents(ref ObjectRef) []ObjectRef {
+	seen := map[ObjectRef]bool{ref: true}
+	var dependents []ObjectRef
+	queue := []ObjectRef{ref}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range g.dependents[current] {
+			if seen[dependent] {
+				continue
+			}
+			seen[dependent] = true
+			dependents = append(dependents, dependent)
+			queue = append(queue, dependent)
+		}
+	}
+	sortRefs(dependents)
+	return dependents
+}
+
+// Order returns every object of the graph so that each one comes after the objects it depends
+// on, which is the order to create them in; the reverse order is the order to drop them in.
+// Objects are grouped by their depth in the graph, and sorted by table, kind, and name within
+// each depth.
+//
+// Returns:
+//   - []ObjectRef: Objects in dependency order
+//   - error: An error if the dependencies form a cycle
+func (g *DependencyGraph) Order() ([]ObjectRef, error) {
+	remaining := make(map[ObjectRef]int, len(g.objects))
+	var ready []ObjectRef
+	for ref := range g.objects {
+		remaining[ref] = len(g.dependencies[ref])
+		if remaining[ref] == 0 {
+			ready = append(ready, ref)
+		}
+	}
+
+	// Objects are emitted level by level: those without dependencies first, then those whose
+	// dependencies were all emitted in earlier levels
+	ordered := make([]ObjectRef, 0, len(g.objects))
+	for len(ready) > 0 {
+		sortRefs(ready)
+		ordered = append(ordered, ready...)
+		var next []ObjectRef
+		for _, ref := range ready {
+			for _, dependent := range g.dependents[ref] {
+				remaining[dependent]--
+				if remaining[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		ready = next
+	}
+
+	if len(ordered) != len(g.objects) {
+		return nil, fmt.Errorf("dependency cycle among %d objects", len(g.objects)-len(ordered))
+	}
+	return ordered, nil
+}
+
+// sortRefs sorts references by table, kind, and name.
+func sortRefs(refs []ObjectRef) {
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Table != refs[j].Table {
+			return refs[i].Table < refs[j].Table
+		}
+		if refs[i].Kind != refs[j].Kind {
+			return refs[i].Kind < refs[j].Kind
+		}
+		return refs[i].Name < refs[j].Name
+	})
+}
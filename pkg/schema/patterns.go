@@ -0,0 +1,137 @@
+package schema
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// tablePatterns are the table filters of FetchOptions, translated into regular expressions that
+// the catalog queries apply.
+type tablePatterns struct {
+	include []string // Regular expressions of the tables to list; empty lists all tables
+	exclude []string // Regular expressions of the tables left out
+
+	includeRE, excludeRE []*regexp.Regexp // Compiled include and exclude, for the dialects filtered in Go
+}
+
+// patternsOf translates the table filters of the options into regular expressions.
+//
+// Parameters:
+//   - opts: Options holding the glob patterns of IncludeTables and ExcludeTables
+//
+// Returns:
+//   - tablePatterns: Regular expressions matching the same tables as the patterns
+//   - error: An error if any of the patterns is malformed
+func patternsOf(opts FetchOptions) (tablePatterns, error) {
+	patterns := tablePatterns{include: []string{}, exclude: []string{}}
+	for _, pattern := range opts.IncludeTables {
+		re, err := globToRegexp(pattern)
+		if err != nil {
+			return tablePatterns{}, fmt.Errorf("invalid include pattern '%s': %w", pattern, err)
+		}
+		patterns.include = append(patterns.include, re)
+		patterns.includeRE = append(patterns.includeRE, regexp.MustCompile(re))
+	}
+	for _, pattern := range opts.ExcludeTables {
+		re, err := globToRegexp(pattern)
+		if err != nil {
+			return tablePatterns{}, fmt.Errorf("invalid exclude pattern '%s': %w", pattern, err)
+		}
+		patterns.exclude = append(patterns.exclude, re)
+		patterns.excludeRE = append(patterns.excludeRE, regexp.MustCompile(re))
+	}
+	return patterns, nil
+}
+
+// match reports whether a table name passes the patterns, for the dialects whose catalog
+// queries cannot apply them.
+//
+// Parameters:
+//   - tableName: Name of the table to check
+//
+// Returns:
+//   - bool: True if the table is kept
+func (p tablePatterns) match(tableName string) bool {
+	matches := func(expressions []*regexp.Regexp) bool {
+		for _, re := range expressions {
+			if re.MatchString(tableName) {
+				return true
+			}
+		}
+		return false
+	}
+	return (len(p.includeRE) == 0 || matches(p.includeRE)) && !matches(p.excludeRE)
+}
+
+// globToRegexp translates a glob pattern, with the syntax of path.Match, into an anchored
+// regular expression matching the same names. The expression only uses syntax shared by the
+// regular expressions of PostgreSQL, CockroachDB, and Go.
+//
+// Parameters:
+//   - pattern: Glob pattern to translate
+//
+// Returns:
+//   - string: Equivalent regular expression
+//   - error: An error if the pattern is malformed
+func globToRegexp(pattern string) (string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return "", err
+	}
+
+	var re strings.Builder
+	re.WriteString("^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '*':
+			re.WriteString("[^/]*")
+		case '?':
+			re.WriteString("[^/]")
+		case '\\':
+			i++
+			re.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case '[':
+			// Character classes are copied with their punctuation escaped, since the syntax
+			// is validated above
+			re.WriteString("[")
+			i++
+			if runes[i] == '^' {
+				re.WriteString("^")
+				i++
+			}
+			for ; runes[i] != ']'; i++ {
+				if runes[i] == '-' {
+					re.WriteString("-")
+					continue
+				}
+				if runes[i] == '\\' {
+					i++
+				}
+				re.WriteString(classChar(runes[i]))
+			}
+			re.WriteString("]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	re.WriteString("$")
+	return re.String(), nil
+}
+
+// classChar returns a character as it is written in a character class of a regular expression:
+// letters, digits, and other characters of names as is, and punctuation escaped.
+//
+// Parameters:
+//   - r: Character to write
+//
+// Returns:
+//   - string: Character, escaped if needed
+func classChar(r rune) string {
+	if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return string(r)
+	}
+	return `\` + string(r)
+}
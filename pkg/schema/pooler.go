@@ -0,0 +1,215 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Connection poolers Connect and ConnectPool can adapt to (see SessionSettings.Pooler).
+const (
+	PoolerNone        = "none"        // The database is reached directly, or through a pooler keeping sessions
+	PoolerTransaction = "transaction" // The database is reached through a pooler sharing server sessions between transactions
+	PoolerAuto        = "auto"        // PgBouncer is detected when connecting, and adapted to as to transaction pooling
+)
+
+// Poolers lists the settings of SessionSettings.Pooler.
+var Poolers = []string{PoolerAuto, PoolerNone, PoolerTransaction}
+
+// ErrConnectionPooler is returned (wrapped) by Fetch and Connect when the connection pooler in
+// front of the database, such as PgBouncer, refused the session or lost track of its state: it
+// has too many clients or waited too long for a server session, or a prepared statement of the
+// session ran on another server session.
+var ErrConnectionPooler = errors.New("connection pooler error")
+
+// poolerProbe is the startup parameter Connect sends to detect PgBouncer. PostgreSQL accepts any
+// parameter whose name has a dot as a custom setting, whereas PgBouncer refuses to connect with
+// startup parameters it does not know.
+const poolerProbe = "schema_check.pooler_probe"
+
+// SQLSTATE codes of the errors of sessions whose prepared statements were lost or mixed up by a
+// pooler.
+const (
+	sqlStateInvalidStatementName  = "26000" // invalid_sql_statement_name: prepared statement does not exist
+	sqlStateDuplicatePreparedStmt = "42P05" // duplicate_prepared_statement: prepared statement already exists
+	sqlStateProtocolViolation     = "08P01" // protocol_violation, also sent by PgBouncer when it refuses a client
+)
+
+// pgBouncerUnsupportedStartupParam begins the message of PgBouncer refusing the probe.
+const pgBouncerUnsupportedStartupParam = "unsupported startup parameter"
+
+// pgBouncerErrors are the beginnings of the messages of the errors PgBouncer itself sends.
+var pgBouncerErrors = []string{
+	pgBouncerUnsupportedStartupParam,
+	"no more connections allowed",
+	"query_wait_timeout",
+	"server login has been failing",
+	"pgbouncer cannot connect to server",
+	"client_login_timeout",
+	"no such database",
+}
+
+// detectedPoolers remembers, by host and port, whether PgBouncer was detected, so that each
+// endpoint is only probed once per process.
+var detectedPoolers sync.Map
+
+// poolerKey returns the key of an endpoint in detectedPoolers.
+func poolerKey(config *pgconn.Config) string {
+	return fmt.Sprintf("%s:%d", config.Host, config.Port)
+}
+
+// PoolerDetected reports what an earlier Connect or ConnectPool with PoolerAuto found in front
+// of the endpoint of a connection string.
+//
+// Parameters:
+//   - connString: PostgreSQL connection string
+//
+// Returns:
+//   - bool: True if PgBouncer was detected, so sessions are pooled by transaction
+//   - bool: True if the endpoint was probed at all in this process
+func PoolerDetected(connString string) (detected, probed bool) {
+	config, err := pgconn.ParseConfig(connString)
+	if err != nil {
+		return false, false
+	}
+	value, probed := detectedPoolers.Load(poolerKey(config))
+	if !probed {
+		return false, false
+	}
+	return value.(bool), true
+}
+
+// transactionPooling works out whether the sessions of an endpoint are pooled by transaction.
+//
+// Parameters:
+//   - config: Connection settings of the endpoint
+//
+// Returns:
+//   - bool: True if the sessions are known to be pooled by transaction
+//   - bool: True if the endpoint should be probed for PgBouncer, as it was never probed
+func (s SessionSettings) transactionPooling(config *pgconn.Config) (pooled, probe bool) {
+	switch s.Pooler {
+	case PoolerTransaction:
+		return true, false
+	case PoolerAuto:
+		if detected, ok := detectedPoolers.Load(poolerKey(config)); ok {
+			return detected.(bool), false
+		}
+		return false, true
+	}
+	return false, false
+}
+
+// checkPooler checks that the pooler setting is known.
+//
+// Returns:
+//   - error: An error if the pooler setting is unknown
+func (s SessionSettings) checkPooler() error {
+	switch s.Pooler {
+	case "", PoolerNone, PoolerTransaction, PoolerAuto:
+		return nil
+	}
+	return fmt.Errorf("unknown pooler '%s': expected one of %s", s.Pooler, strings.Join(Poolers, ", "))
+}
+
+// adapt returns a copy of connection settings adjusted to the pooling of sessions. With
+// transaction pooling, queries are sent without named prepared statements, which would be
+// looked up on other server sessions, and no session settings are set, as they would stay on a
+// server session shared with other clients. Otherwise, the timeouts of the session are set when
+// it starts, and the probe for PgBouncer is added if asked for.
+//
+// Parameters:
+//   - config: Connection settings to adjust, left unchanged
+//   - pooled: Whether sessions are pooled by transaction
+//   - probe: Whether to probe for PgBouncer
+//
+// Returns:
+//   - *pgx.ConnConfig: Adjusted copy of the settings
+func (s SessionSettings) adapt(config *pgx.ConnConfig, pooled, probe bool) *pgx.ConnConfig {
+	adapted := config.Copy()
+	if pooled {
+		adapted.DefaultQueryExecMode = pgx.QueryExecModeExec
+		return adapted
+	}
+	adapted.AfterConnect = s.afterConnect(adapted.AfterConnect)
+	if probe {
+		adapted.RuntimeParams[poolerProbe] = "on"
+	}
+	return adapted
+}
+
+// probeOutcome interprets the outcome of a connection attempt made with the probe for PgBouncer,
+// and remembers what was detected.
+//
+// Parameters:
+//   - config: Connection settings of the endpoint
+//   - err: Error of the connection attempt, or nil if it succeeded
+//
+// Returns:
+//   - bool: True if PgBouncer refused the probe, so the connection must be retried with transaction pooling
+//   - bool: True if the server refused the probe otherwise, so the connection must be retried without it
+func probeOutcome(config *pgconn.Config, err error) (pgBouncer, refused bool) {
+	var pgErr *pgconn.PgError
+	switch {
+	case err == nil:
+		detectedPoolers.Store(poolerKey(config), false)
+	case errors.As(err, &pgErr) && pgErr.Code == sqlStateProtocolViolation && strings.HasPrefix(pgErr.Message, pgBouncerUnsupportedStartupParam):
+		detectedPoolers.Store(poolerKey(config), true)
+		return true, false
+	case errors.As(err, &pgErr) && strings.Contains(pgErr.Message, poolerProbe):
+		// Servers other than PostgreSQL may refuse custom settings
+		detectedPoolers.Store(poolerKey(config), false)
+		return false, true
+	}
+	return false, false
+}
+
+// isPoolerError reports whether a server error was sent by PgBouncer, or means that a pooler
+// lost track of the prepared statements of the session.
+//
+// Parameters:
+//   - pgErr: Error sent by the server or the pooler
+//
+// Returns:
+//   - bool: True if the error comes from the pooler
+func isPoolerError(pgErr *pgconn.PgError) bool {
+	if pgErr.Code == sqlStateInvalidStatementName || pgErr.Code == sqlStateDuplicatePreparedStmt {
+		return strings.Contains(pgErr.Message, "prepared statement")
+	}
+	if pgErr.Code != sqlStateProtocolViolation && !strings.HasPrefix(pgErr.Code, sqlStateClassConnection) {
+		return false
+	}
+	for _, prefix := range pgBouncerErrors {
+		if strings.HasPrefix(pgErr.Message, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// connectAdapted opens a connection adapted to the pooling of sessions of the endpoint, probing
+// it for PgBouncer the first time with PoolerAuto.
+//
+// Parameters:
+//   - ctx: Context bounding the connection attempts
+//   - config: Connection settings
+//   - session: Timeouts and pooling of the session
+//
+// Returns:
+//   - *pgx.Conn: Open connection
+//   - error: Any error that occurred while connecting
+func connectAdapted(ctx context.Context, config *pgx.ConnConfig, session SessionSettings) (*pgx.Conn, error) {
+	pooled, probe := session.transactionPooling(&config.Config)
+	conn, err := pgx.ConnectConfig(ctx, session.adapt(config, pooled, probe))
+	if probe {
+		if pgBouncer, refused := probeOutcome(&config.Config, err); pgBouncer || refused {
+			conn, err = pgx.ConnectConfig(ctx, session.adapt(config, pgBouncer, false))
+		}
+	}
+	return conn, err
+}
@@ -0,0 +1,122 @@
+package schema
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// rateLimited is a Querier sending at most a given number of queries per second through another
+// one. It is safe for concurrent use if the underlying Querier is: the queries of all goroutines
+// share the limit.
+type rateLimited struct {
+	conn     Querier       // Querier the queries are sent through
+	interval time.Duration // Time between two queries
+
+	mu   sync.Mutex // Guards next
+	next time.Time  // Earliest time the next query can be sent
+}
+
+// rateLimitedBatcher is a rateLimited Querier whose underlying Querier is also a Batcher.
+type rateLimitedBatcher struct {
+	*rateLimited
+	batcher Batcher // Underlying Querier, as a Batcher
+}
+
+// RateLimit returns a Querier sending at most qps queries per second through conn, so that
+// reading the catalog of a busy production server does not compete with its load. Queries
+// waiting for their turn are cancelled with their context. If conn is a Batcher, so is the
+// result: batches are sent whole once the limit allows all of their queries.
+//
+// Parameters:
+//   - conn: Active PostgreSQL connection or pool
+//   - qps: Maximum number of queries per second; zero or negative means no limit
+//
+// Returns:
+//   - Querier: Rate-limited connection, or conn itself if there is no limit
+func RateLimit(conn Querier, qps float64) Querier {
+	if qps <= 0 {
+		return conn
+	}
+	limited := &rateLimited{conn: conn, interval: time.Duration(float64(time.Second) / qps)}
+	if batcher, ok := conn.(Batcher); ok {
+		return &rateLimitedBatcher{rateLimited: limited, batcher: batcher}
+	}
+	return limited
+}
+
+// Query waits for the turn of the query, then sends it.
+func (q *rateLimited) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if err := q.wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	return q.conn.Query(ctx, sql, args...)
+}
+
+// SendBatch waits for the turns of all the queries of the batch, then sends it.
+func (q *rateLimitedBatcher) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	if err := q.wait(ctx, b.Len()); err != nil {
+		return failedBatch{err}
+	}
+	return q.batcher.SendBatch(ctx, b)
+}
+
+// wait blocks until n more queries can be sent, reserving their turns.
+//
+// Parameters:
+//   - ctx: Context cancelling the wait
+//   - n: Number of queries about to be sent
+//
+// Returns:
+//   - error: The context's error if it was cancelled before the turn came
+func (q *rateLimited) wait(ctx context.Context, n int) error {
+	q.mu.Lock()
+	now := time.Now()
+	start := q.next
+	if start.Before(now) {
+		start = now
+	}
+	q.next = start.Add(time.Duration(n) * q.interval)
+	q.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// failedBatch is the result of a batch that could not be sent, failing with its error.
+type failedBatch struct {
+	err error // Error the batch failed with
+}
+
+// Exec returns the error of the batch.
+func (b failedBatch) Exec() (pgconn.CommandTag, error) { return pgconn.CommandTag{}, b.err }
+
+// Query returns the error of the batch.
+func (b failedBatch) Query() (pgx.Rows, error) { return nil, b.err }
+
+// QueryRow returns a row whose Scan fails with the error of the batch.
+func (b failedBatch) QueryRow() pgx.Row { return failedRow(b) }
+
+// Close returns the error of the batch.
+func (b failedBatch) Close() error { return b.err }
+
+// failedRow is a pgx.Row whose Scan fails with an error.
+type failedRow struct {
+	err error // Error Scan fails with
+}
+
+// Scan returns the error of the row.
+func (r failedRow) Scan(dest ...any) error { return r.err }
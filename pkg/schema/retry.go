@@ -0,0 +1,131 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// RetryPolicy retries operations that fail with transient errors (see IsTransient), waiting
+// exponentially longer between attempts. The zero value performs a single attempt.
+type RetryPolicy struct {
+	MaxAttempts    int           // Total number of attempts, including the first; values below 2 disable retries
+	InitialBackoff time.Duration // Wait before the first retry, doubled before each following one
+	MaxBackoff     time.Duration // Upper bound of the wait between attempts; zero means no bound
+
+	// OnRetry, if not nil, is called before waiting to retry, with the number of the failed
+	// attempt, the wait, and the error it failed with.
+	OnRetry func(attempt int, wait time.Duration, err error)
+
+	closed func() bool // Reports whether the single connection the operations run on is closed; nil when they run on a pool
+}
+
+// DefaultRetryPolicy is a policy suitable for busy servers and flaky networks: three attempts,
+// waiting half a second and then one second.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+}
+
+// Do runs fn until it succeeds, fails with an error that is not transient, or the attempts
+// are exhausted. Waiting between attempts stops early when the context is cancelled. Fetching
+// also stops retrying once the connection or transaction it was given is closed, such as after a
+// connection reset, since no further attempt could succeed on it; a pool retries on another
+// connection.
+//
+// Parameters:
+//   - ctx: Context bounding the attempts and the waits between them
+//   - fn: Operation to run
+//
+// Returns:
+//   - error: The error of the last attempt, or nil if an attempt succeeded
+func (p RetryPolicy) Do(ctx context.Context, fn func() error) error {
+	wait := p.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !IsTransient(err) || ctx.Err() != nil || (p.closed != nil && p.closed()) {
+			return err
+		}
+
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, wait, err)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		wait *= 2
+		if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+			wait = p.MaxBackoff
+		}
+	}
+}
+
+// on returns the policy retrying operations on conn. Failures such as a connection reset or an
+// unexpected EOF close a single connection, on which every further attempt would fail, so the
+// attempts on a *pgx.Conn, pgx.Tx, or connection acquired from a pool stop once it is closed; a
+// pool itself acquires another connection for each attempt, so its attempts go on.
+//
+// Parameters:
+//   - conn: Connection, transaction, or pool the operations run on
+//
+// Returns:
+//   - RetryPolicy: Copy of the policy for operations on conn
+func (p RetryPolicy) on(conn Querier) RetryPolicy {
+	switch c := conn.(type) {
+	case *pgx.Conn:
+		p.closed = c.IsClosed
+	case interface{ Conn() *pgx.Conn }: // pgx.Tx, and connections and transactions of a pool
+		p.closed = func() bool {
+			return c.Conn() == nil || c.Conn().IsClosed()
+		}
+	}
+	return p
+}
+
+// SQLSTATE codes of server errors that may succeed when retried.
+var transientSQLStates = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"55P03": true, // lock_not_available
+	"53300": true, // too_many_connections
+	"57P03": true, // cannot_connect_now (e.g., the server is starting up)
+}
+
+// IsTransient reports whether an error may go away when the operation is retried: network
+// failures, dropped connections, and server errors such as serialization failures or too many
+// connections. Authentication failures, missing privileges, and cancellations are not transient.
+//
+// Parameters:
+//   - err: Error to check
+//
+// Returns:
+//   - bool: True if the operation is worth retrying
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return transientSQLStates[pgErr.Code] || strings.HasPrefix(pgErr.Code, sqlStateClassConnection)
+	}
+
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	return errors.As(err, &connectErr) || errors.As(err, &netErr) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || pgconn.SafeToRetry(err)
+}
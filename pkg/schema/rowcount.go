@@ -0,0 +1,156 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Ways of counting the rows of tables (see FetchOptions.RowCounts).
+const (
+	RowCountsEstimate = "estimate" // The planner's estimates, from pg_class.reltuples
+	RowCountsExact    = "exact"    // count(*) of each table
+)
+
+// RowCountModes lists the settings of FetchOptions.RowCounts.
+var RowCountModes = []string{RowCountsEstimate, RowCountsExact}
+
+// checkRowCounts checks that the row count setting is known.
+//
+// Parameters:
+//   - mode: Setting of FetchOptions.RowCounts
+//
+// Returns:
+//   - error: An error if the setting is unknown
+func checkRowCounts(mode string) error {
+	switch mode {
+	case "", RowCountsEstimate, RowCountsExact:
+		return nil
+	}
+	return fmt.Errorf("unknown row count mode '%s': expected %s or %s", mode, RowCountsEstimate, RowCountsExact)
+}
+
+// countRows counts the rows of tables of a schema. Views and foreign tables are left out, as
+// are tables whose rows cannot be counted: those never vacuumed or analyzed when estimating (on
+// PostgreSQL 14 and later, which tell them apart from empty tables), and those the role may not
+// read when counting exactly. The count of a partitioned or inheritance parent includes its
+// partitions or children, as count(*) on it does.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - cat: Catalog queries of the database's dialect
+//   - schemaName: PostgreSQL schema of the tables
+//   - tables: Names of the tables to count
+//   - mode: RowCountsEstimate or RowCountsExact
+//   - retry: Retries of the queries failing with transient errors
+//
+// Returns:
+//   - map[string]int64: Number of rows keyed by table name
+//   - error: Any error that occurred during the queries
+func countRows(ctx context.Context, conn Querier, cat catalog, schemaName string, tables []string, mode string, retry RetryPolicy) (map[string]int64, error) {
+	wanted := make(map[string]bool, len(tables))
+	for _, name := range tables {
+		wanted[name] = true
+	}
+
+	counts := make(map[string]int64, len(tables))
+	if mode == RowCountsEstimate {
+		if !cat.rowEstimates {
+			return nil, fmt.Errorf("row count estimates are only read from PostgreSQL and Aurora; count the rows exactly instead")
+		}
+		err := retry.Do(ctx, func() error {
+			return forEachRow(ctx, conn, rowEstimatesQuery, schemaName, func(rows scanner) error {
+				var name string
+				var estimate *int64
+				if err := rows.Scan(&name, &estimate); err != nil {
+					return err
+				}
+				if wanted[name] && estimate != nil {
+					counts[name] = *estimate
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error estimating row counts: %w", err)
+		}
+		return counts, nil
+	}
+
+	// Only tables are counted, as counting a view runs its query
+	var countable []string
+	err := retry.Do(ctx, func() error {
+		countable = countable[:0]
+		return forEachRow(ctx, conn, countableTablesQuery, schemaName, func(rows scanner) error {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return err
+			}
+			if wanted[name] {
+				countable = append(countable, name)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the tables to count: %w", err)
+	}
+	for _, name := range countable {
+		var count int64
+		err := retry.Do(ctx, func() error {
+			return queryValue(ctx, conn, "SELECT count(*) FROM "+pgx.Identifier{schemaName, name}.Sanitize(), &count)
+		})
+		if isMissingFeature(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error counting the rows of %s: %w", name, err)
+		}
+		counts[name] = count
+	}
+	return counts, nil
+}
+
+// Catalog queries of row counts. Each takes the schema name as $1.
+const (
+	// Estimates of tables, summed over the partitions or children of parents; NULL when a table
+	// was never vacuumed or analyzed (reltuples is -1 from PostgreSQL 14)
+	rowEstimatesQuery = `
+	WITH RECURSIVE tree AS (
+		SELECT c.oid AS root, c.oid AS relid
+		FROM pg_class c
+		JOIN pg_namespace n
+			ON n.oid = c.relnamespace
+		WHERE n.nspname = $1
+			AND c.relkind IN ('r', 'p')
+		UNION ALL
+		SELECT tree.root, inh.inhrelid
+		FROM tree
+		JOIN pg_inherits inh
+			ON inh.inhparent = tree.relid
+	)
+	SELECT
+		root.relname,
+		CASE
+			WHEN bool_or(c.relkind = 'r' AND c.reltuples < 0) THEN NULL
+			ELSE COALESCE(sum(c.reltuples) FILTER (WHERE c.relkind = 'r'), 0)
+		END::bigint
+	FROM tree
+	JOIN pg_class root
+		ON root.oid = tree.root
+	JOIN pg_class c
+		ON c.oid = tree.relid
+	GROUP BY root.relname
+`
+
+	countableTablesQuery = `
+	SELECT c.relname
+	FROM pg_class c
+	JOIN pg_namespace n
+		ON n.oid = c.relnamespace
+	WHERE n.nspname = $1
+		AND c.relkind IN ('r', 'p')
+`
+)
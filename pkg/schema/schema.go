@@ -1,55 +1,221 @@
 // Package schema provides functionality to interact with and analyze PostgreSQL database schemas.
 // It includes types and functions to fetch and represent table structures, columns, indexes, and constraints.
+//
+// Use Fetch to read a schema from a live database. The Schema type and the types of its objects
+// are part of the supported API (see the root schemacheck package), with their JSON field names:
+// fields may be added as more of the catalog is read, but none is removed or renamed.
 package schema
 
 import (
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
 
 	"github.com/jackc/pgx/v5"
 )
 
 // TableInfo represents the complete structure of a PostgreSQL table, including its columns,
-// primary keys, indexes, and foreign key relationships.
+// primary keys, indexes, foreign key relationships, and triggers.
 type TableInfo struct {
-	Name        string            // Name of the table
-	Columns     []ColumnInfo      // List of columns in the table
-	PrimaryKeys []string          // Names of columns that form the primary key
-	Indexes     []IndexInfo       // List of indexes defined on the table
-	ForeignKeys []ForeignKeyInfo  // List of foreign key constraints
+	Name           string            `json:"name"`                       // Name of the table
+	Columns        []ColumnInfo      `json:"columns,omitempty"`          // List of columns in the table
+	PrimaryKeys    []string          `json:"primary_keys,omitempty"`     // Names of columns that form the primary key
+	PrimaryKeyName string            `json:"primary_key_name,omitempty"` // Name of the primary key constraint, such as orders_pkey
+	Indexes        []IndexInfo       `json:"indexes,omitempty"`          // List of indexes defined on the table
+	ForeignKeys    []ForeignKeyInfo  `json:"foreign_keys,omitempty"`     // List of foreign key constraints
+	Triggers       []TriggerInfo     `json:"triggers,omitempty"`         // List of triggers defined on the table, by name
+	Comment        string            `json:"comment,omitempty"`          // COMMENT attached to the table, if any
+	PartitionOf    string            `json:"partition_of,omitempty"`     // Name of the parent table if this table is a partition
+	PartitionKey   string            `json:"partition_key,omitempty"`    // Partition strategy and key if this table is partitioned (e.g., "RANGE (created_at)")
+	Owner          string            `json:"owner,omitempty"`            // Name of the role that owns the table
+	Hypertable     *HypertableInfo   `json:"hypertable,omitempty"`       // TimescaleDB settings, if the table is a hypertable or continuous aggregate
+	Distribution   *DistributionInfo `json:"distribution,omitempty"`     // Citus settings, if the table is distributed or a reference table
+	View           *ViewInfo         `json:"view,omitempty"`             // Definition of the view, if the table is a view or materialized view
 }
 
 // ColumnInfo represents a single column in a PostgreSQL table, including its data type,
 // nullability, default value, and identity status.
 type ColumnInfo struct {
-	Name       string // Name of the column
-	Type       string // PostgreSQL data type of the column
-	Nullable   bool   // Whether the column can contain NULL values
-	Default    string // Default value expression for the column
-	IsIdentity bool   // Whether the column is an identity column (auto-incrementing)
+	Name        string `json:"name"`                  // Name of the column
+	Type        string `json:"type"`                  // PostgreSQL data type of the column
+	Nullable    bool   `json:"nullable"`              // Whether the column can contain NULL values
+	Default     string `json:"default,omitempty"`     // Default value expression for the column
+	IsIdentity  bool   `json:"is_identity,omitempty"` // Whether the column is an identity column (auto-incrementing)
+	Comment     string `json:"comment,omitempty"`     // COMMENT attached to the column, if any
+	Compression string `json:"compression,omitempty"` // Compression method set on the column (e.g., "lz4"), if any; PostgreSQL 14 and later
+	MaxLength   int    `json:"max_length,omitempty"`  // Declared length of a character or character varying column; zero if unlimited
 }
 
-// IndexInfo represents a database index, including its name, the columns it covers,
-// and whether it enforces uniqueness.
+// IndexInfo represents a database index, including its name, the columns and expressions it is
+// keyed on, the columns it includes, and whether it enforces uniqueness.
 type IndexInfo struct {
-	Name    string   // Name of the index
-	Columns []string // Names of columns included in the index
-	Unique  bool     // Whether the index enforces uniqueness
+	Name        string            `json:"name"`                  // Name of the index
+	Columns     []string          `json:"columns"`               // Names of the key columns of the index, in order, without its key expressions
+	Unique      bool              `json:"unique,omitempty"`      // Whether the index enforces uniqueness
+	Expressions []IndexExpression `json:"expressions,omitempty"` // Key expressions of the index (e.g., "lower(email)"), in order
+	Include     []string          `json:"include,omitempty"`     // Names of the non-key columns of the INCLUDE clause; PostgreSQL 11 and later
+	Predicate   string            `json:"predicate,omitempty"`   // Condition of a partial index (e.g., "deleted_at IS NULL"), if any
+	Method      string            `json:"method,omitempty"`      // Access method of the index (e.g., "btree", "gin"); empty if unknown
+}
+
+// IndexExpression is a key of an index on an expression rather than a column.
+type IndexExpression struct {
+	Position   int    `json:"position"`   // Position of the expression among the keys of the index, from 1
+	Expression string `json:"expression"` // Expression, as pg_get_indexdef prints it
+}
+
+// Keys returns the keys of the index in order: the names of its key columns and its key
+// expressions, the latter in parentheses as CREATE INDEX declares them.
+//
+// Returns:
+//   - []string: Keys of the index
+func (idx IndexInfo) Keys() []string {
+	return idx.FormatKeys(func(name string) string { return name })
+}
+
+// FormatKeys returns the keys of the index in order, as Keys does, with the names of its key
+// columns formatted by a function (e.g., to quote them).
+//
+// Parameters:
+//   - column: Function formatting the name of a key column
+//
+// Returns:
+//   - []string: Keys of the index
+func (idx IndexInfo) FormatKeys(column func(name string) string) []string {
+	keys := make([]string, 0, len(idx.Columns)+len(idx.Expressions))
+	columns, expressions := idx.Columns, idx.Expressions
+	for len(columns) > 0 || len(expressions) > 0 {
+		if len(expressions) > 0 && (expressions[0].Position <= len(keys)+1 || len(columns) == 0) {
+			keys = append(keys, "("+expressions[0].Expression+")")
+			expressions = expressions[1:]
+			continue
+		}
+		keys = append(keys, column(columns[0]))
+		columns = columns[1:]
+	}
+	return keys
 }
 
 // ForeignKeyInfo represents a foreign key constraint that links columns in one table
 // to columns in another table.
 type ForeignKeyInfo struct {
-	Name              string   // Name of the foreign key constraint
-	Columns           []string // Names of columns in the current table
-	ReferencedTable   string   // Name of the table being referenced
-	ReferencedColumns []string // Names of columns in the referenced table
+	Name              string   `json:"name"`                 // Name of the foreign key constraint
+	Columns           []string `json:"columns"`              // Names of columns in the current table
+	ReferencedTable   string   `json:"referenced_table"`     // Name of the table being referenced
+	ReferencedColumns []string `json:"referenced_columns"`   // Names of columns in the referenced table
+	Definition        string   `json:"definition,omitempty"` // Definition of the constraint, as pg_get_constraintdef prints it; empty if unknown
+}
+
+// Options returns the part of the constraint's definition following the referenced columns: its
+// match type, referential actions, and deferrability (e.g., "ON DELETE CASCADE DEFERRABLE").
+// Unlike the whole definition, it does not depend on how the referenced table is qualified.
+//
+// Returns:
+//   - string: Options of the constraint; empty if it has none, or if its definition is unknown
+func (fk ForeignKeyInfo) Options() string {
+	_, rest, found := strings.Cut(fk.Definition, " REFERENCES ")
+	if !found {
+		return ""
+	}
+
+	// Skip the referenced table and the list of referenced columns, which ends at the first
+	// closing parenthesis outside of a quoted name
+	quoted := false
+	for i := 0; i < len(rest); i++ {
+		switch {
+		case rest[i] == '"':
+			quoted = !quoted
+		case rest[i] == ')' && !quoted:
+			return strings.TrimSpace(rest[i+1:])
+		}
+	}
+	return ""
+}
+
+// TriggerInfo represents a trigger: when it fires, on which events, how often, and the function
+// it calls.
+type TriggerInfo struct {
+	Name     string   `json:"name"`              // Name of the trigger
+	Timing   string   `json:"timing"`            // When the trigger fires: "BEFORE", "AFTER", or "INSTEAD OF"
+	Events   []string `json:"events"`            // Events the trigger fires on: "INSERT", "UPDATE", "DELETE", and "TRUNCATE", in that order
+	Columns  []string `json:"columns,omitempty"` // Columns of an UPDATE OF trigger, which only fires on updates of these columns
+	Level    string   `json:"level"`             // Whether the trigger fires for each "ROW" or each "STATEMENT"
+	When     string   `json:"when,omitempty"`    // Condition of the WHEN clause, if any (e.g., "new.total > 0")
+	Function string   `json:"function"`          // Function the trigger calls, with its arguments (e.g., "audit_changes('orders')")
+}
+
+// ViewInfo describes a view or materialized view, which is listed among the tables of its schema
+// along with its columns and, for a materialized view, its indexes.
+type ViewInfo struct {
+	Definition   string   `json:"definition"`             // Query of the view, as pg_get_viewdef returns it
+	Materialized bool     `json:"materialized,omitempty"` // Whether the view is a materialized view
+	Populated    bool     `json:"populated,omitempty"`    // Whether a materialized view holds data: it was created or last refreshed WITH DATA
+	References   []string `json:"references,omitempty"`   // Names of the tables and views of the same schema the query reads, sorted; empty if unknown
 }
 
 // Schema represents a complete database schema, containing all tables and their relationships.
 type Schema struct {
-	Tables map[string]TableInfo // Map of table names to their complete information
+	Name                string                  `json:"name"`                           // Name of the PostgreSQL schema (namespace) the tables belong to
+	Tables              map[string]TableInfo    `json:"tables"`                         // Map of table names to their complete information
+	ServerVersion       int                     `json:"server_version,omitempty"`       // Version of the PostgreSQL server the schema was fetched from, as in server_version_num; zero if unknown
+	Extensions          map[string]any          `json:"extensions,omitempty"`           // Objects of custom kinds keyed by kind, filled by custom fetchers for custom comparators
+	Errors              []FetchError            `json:"errors,omitempty"`               // Tables whose details could not be fetched, which are missing from Tables
+	UnsupportedFeatures []string                `json:"unsupported_features,omitempty"` // Features the server lacks or whose metadata could not be read (see the Feature constants)
+	RowCounts           map[string]int64        `json:"row_counts,omitempty"`           // Number of rows of the tables, keyed by name, when FetchOptions.RowCounts asks for them; tables whose count is unknown are absent
+	RowCountMode        string                  `json:"row_count_mode,omitempty"`       // How RowCounts were counted: RowCountsEstimate or RowCountsExact
+	DataChecksums       map[string]DataChecksum `json:"data_checksums,omitempty"`       // Checksums of the data of the tables, keyed by name, when FetchOptions.DataChecksums asks for them; tables not checksummed are absent
+	Sizes               map[string]TableSize    `json:"sizes,omitempty"`                // Sizes on disk of the tables and their indexes, keyed by table name, when FetchOptions.Sizes asks for them
+	Sequences           map[string]SequenceInfo `json:"sequences,omitempty"`            // Sequences of the schema kept by the table filters, keyed by name
+	Functions           map[string]FunctionInfo `json:"functions,omitempty"`            // Functions and procedures of the schema kept by the table filters, keyed by signature (see FunctionInfo.Signature)
+}
+
+// Features of the schema model that some servers lack, as recorded in Schema.UnsupportedFeatures.
+// The corresponding properties are left empty in schemas fetched from those servers.
+const (
+	FeaturePartitioning      = "partitioning"       // Declarative partitioning (PostgreSQL 10 and later)
+	FeatureIdentity          = "identity"           // Identity columns (PostgreSQL 10 and later)
+	FeatureCompression       = "compression"        // Per-column compression methods (PostgreSQL 14 and later)
+	FeatureIndexes           = "indexes"            // Indexes (absent from Amazon Redshift)
+	FeatureTimescale         = "timescaledb"        // TimescaleDB metadata, when the extension is installed but its metadata cannot be read
+	FeatureCitus             = "citus"              // Citus metadata, when the extension is installed but its metadata cannot be read
+	FeatureMaterializedViews = "materialized_views" // Materialized views (not read from CockroachDB and Amazon Redshift)
+	FeatureSequences         = "sequences"          // Sequences (absent from Amazon Redshift)
+	FeatureTriggers          = "triggers"           // Triggers (not read from CockroachDB and Amazon Redshift)
+	FeatureFunctions         = "functions"          // Functions and procedures (not read from CockroachDB and Amazon Redshift)
+)
+
+// Supports reports whether the server the schema was fetched from supports a feature.
+//
+// Parameters:
+//   - feature: One of the Feature constants
+//
+// Returns:
+//   - bool: False if the feature is listed in UnsupportedFeatures
+func (s *Schema) Supports(feature string) bool {
+	return !slices.Contains(s.UnsupportedFeatures, feature)
+}
+
+// FetchError records a table whose details could not be fetched (e.g., for lack of privileges,
+// or because it was dropped during the fetch). The message is kept as text so that it survives
+// being saved in a snapshot.
+type FetchError struct {
+	Table   string `json:"table"`   // Name of the table that could not be fetched
+	Message string `json:"message"` // Error that occurred while fetching it
+}
+
+// FailedTables returns the names of the tables whose details could not be fetched.
+//
+// Returns:
+//   - map[string]string: Error messages keyed by table name
+func (s *Schema) FailedTables() map[string]string {
+	failed := make(map[string]string, len(s.Errors))
+	for _, fetchErr := range s.Errors {
+		failed[fetchErr.Table] = fetchErr.Message
+	}
+	return failed
 }
 
 // NewSchema creates and returns a new empty Schema instance.
@@ -60,40 +226,663 @@ func NewSchema() *Schema {
 	}
 }
 
-// FetchSchema retrieves the complete schema information from a PostgreSQL database.
-// It queries the information_schema to get details about all tables, their columns,
-// constraints, and relationships.
+// DefaultSchemaName is the PostgreSQL schema that is fetched when FetchOptions does not name one.
+const DefaultSchemaName = "public"
+
+// Querier is the subset of a PostgreSQL connection that fetching a schema needs. It is
+// satisfied by *pgx.Conn, *pgxpool.Pool, and pgx.Tx; database/sql pools opened with the pgx
+// driver can be used through FetchDB or StdlibFetcher.
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// Batcher is implemented by connections that can pipeline several queries in one round trip,
+// such as *pgx.Conn, *pgxpool.Pool, and pgx.Tx. Fetching sends the catalog queries of table
+// details as a batch when its Querier is also a Batcher.
+type Batcher interface {
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// FetchOptions controls what Fetch reads from the database. The zero value is ready to use.
+//
+// When tables are fetched one by one (see Fetch), a Concurrency above one fetches the details of
+// several tables at once, each through its own queries on conn, which must then be safe for concurrent use (such as *pgxpool.Pool with
+// at least Concurrency connections); a *pgx.Conn or pgx.Tx is not.
+type FetchOptions struct {
+	SchemaName    string      // PostgreSQL schema (namespace) to fetch; empty means DefaultSchemaName
+	StopOnError   bool        // Whether to abort on the first table that cannot be fetched, instead of recording it in Schema.Errors
+	Retry         RetryPolicy // Retries of catalog queries failing with transient errors, until a single connection is lost; the zero value does not retry
+	Dialect       Dialect     // Database engine the schema is fetched from; empty detects it from the server version
+	ServerVersion int         // Version of a PostgreSQL or Aurora server, as in server_version_num; zero detects it
+	Concurrency   int         // Number of tables whose details are fetched at once when fetching table by table; zero or one means one
+
+	// Number of rows read at a time through server-side cursors by the queries reading the
+	// columns, keys, and indexes of many tables at once, which bounds the memory a FETCH needs
+	// on both ends and how long any single statement runs. The cursors are declared in a
+	// transaction, so they are only used on connections that can begin one (*pgx.Conn,
+	// *pgxpool.Pool, pgx.Tx, or RateLimit of one of them). Zero reads each query in one go.
+	CursorSize int
+
+	// Whether StreamTables runs the queries reading the columns, keys, and indexes of tables
+	// once for the whole schema into temporary tables, which each batch of tables is then read
+	// from, instead of running the catalog joins again for each batch. It only applies to
+	// PostgreSQL and Aurora when there is more than one batch, needs a connection that can begin
+	// a transaction (see CursorSize), which the stream holds until it is read or closed, and is
+	// skipped on servers that cannot create temporary tables, such as read-only replicas.
+	Stage bool
+
+	// Whether the number of rows of the tables whose details are wanted is read into
+	// Schema.RowCounts: RowCountsEstimate reads the planner's estimates (pg_class.reltuples), which
+	// costs one query but is only as fresh as the last VACUUM or ANALYZE of each table, and
+	// RowCountsExact runs count(*) on each table, which reads every row. Empty counts nothing.
+	// StreamTables counts no rows.
+	RowCounts string
+
+	// Whether the data of the tables whose details are wanted is checksummed into
+	// Schema.DataChecksums: DataChecksumsFull hashes every row, and DataChecksumsSample the first
+	// DataSampleRows rows in primary key order (DefaultDataSampleRows if zero), leaving out the
+	// tables without a primary key. DataColumns lists the columns hashed for some tables, keyed by
+	// table name; the other tables have every column hashed. Empty checksums nothing, and so does
+	// StreamTables.
+	DataChecksums  string
+	DataSampleRows int
+	DataColumns    map[string][]string
+
+	// Whether the sizes of the tables and of their indexes, and the estimated bloat of the tables,
+	// are read into Schema.Sizes, with two queries. StreamTables reads no sizes.
+	Sizes bool
+
+	// Glob patterns, with the syntax of path.Match, of the tables to fetch and of the tables left
+	// out. They are applied by the catalog query listing the tables, so that the tables left out
+	// are never read. Empty IncludeTables fetches every table not excluded.
+	IncludeTables []string
+	ExcludeTables []string
+
+	// Reports whether the details (columns, keys, and indexes) of a listed table are wanted; nil
+	// wants them for every table. Tables whose details are not wanted are still listed in
+	// Schema.Tables, with their table-level properties only, which is enough to report them as
+	// missing or extra. The table passed has its table-level properties only.
+	Details func(table TableInfo) bool
+
+	// Details of tables known to be unchanged since they were fetched (e.g., from a change log),
+	// keyed by name. Listed tables found here are not fetched again, and their details are taken
+	// from here; their table-level properties (comment, owner, partitioning) are still read afresh.
+	Reuse map[string]TableInfo
+
+	// Hooks called as the fetch progresses, so that embedding applications can report progress.
+	// Any of them can be nil.
+	OnPhaseStart   func(phase string)                        // Called when a phase starts (PhaseListTables, PhaseTableDetails, PhaseRowCounts, PhaseDataChecksum, PhaseSizes)
+	OnTableFetched func(table TableInfo, fetched, total int) // Called after each table's details have been fetched
+}
+
+// Phases of a fetch, as reported to FetchOptions.OnPhaseStart.
+const (
+	PhaseListTables   = "list-tables"   // Listing the tables of the schema
+	PhaseTableDetails = "table-details" // Fetching the columns, keys, and indexes of each table
+	PhaseRowCounts    = "row-counts"    // Counting the rows of the tables, with FetchOptions.RowCounts
+	PhaseDataChecksum = "data-checksum" // Checksumming the data of the tables, with FetchOptions.DataChecksums
+	PhaseSizes        = "sizes"         // Reading the sizes of the tables, with FetchOptions.Sizes
+)
+
+// phaseStart calls the OnPhaseStart hook, if set.
+func (o FetchOptions) phaseStart(phase string) {
+	if o.OnPhaseStart != nil {
+		o.OnPhaseStart(phase)
+	}
+}
+
+// filtersTables reports whether IncludeTables or ExcludeTables leave tables out of the listing,
+// whose details can then not be read without naming the tables.
+func (o FetchOptions) filtersTables() bool {
+	return len(o.IncludeTables) > 0 || len(o.ExcludeTables) > 0
+}
+
+// FetchSchema retrieves the complete schema information of the public schema from a PostgreSQL database.
+// It is equivalent to calling Fetch with the zero FetchOptions.
 //
 // Parameters:
 //   - ctx: Context for the database operation
-//   - conn: Active PostgreSQL connection
+//   - conn: Active PostgreSQL connection or pool
 //
 // Returns:
 //   - *Schema: Complete schema information
 //   - error: Any error that occurred during the fetch operation
-func FetchSchema(ctx context.Context, conn *pgx.Conn) (*Schema, error) {
+func FetchSchema(ctx context.Context, conn Querier) (*Schema, error) {
+	return Fetch(ctx, conn, FetchOptions{})
+}
+
+// Fetch retrieves the complete schema information from a PostgreSQL database.
+// It queries the information_schema and pg_catalog to get details about all tables,
+// their columns, constraints, and relationships, with one query per kind of object for the
+// whole schema. If those queries fail, the tables are fetched one by one instead: a table whose
+// details cannot be fetched is recorded in Schema.Errors and left out of Schema.Tables, and the
+// fetch continues with the remaining tables, unless opts.StopOnError is set.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - opts: Options controlling what is fetched
+//
+// Returns:
+//   - *Schema: Complete schema information
+//   - error: Any error that occurred during the fetch operation, wrapping ErrConnectionFailed,
+//     ErrConnectionPooler, ErrInsufficientPrivileges, ErrUnsupportedServerVersion, or ErrQueryTimeout when
+//     its cause is known
+func Fetch(ctx context.Context, conn Querier, opts FetchOptions) (*Schema, error) {
+	opts.Retry = opts.Retry.on(conn)
+	s, err := fetch(ctx, conn, opts)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	return s, nil
+}
+
+// ListTables lists the tables of a schema with their table-level properties (comment, owner,
+// partitioning, and TimescaleDB and Citus settings) but without their columns, keys, and
+// indexes, as a cheap first step before fetching the details of only some of them with
+// FetchOptions.Details.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - opts: Options controlling what is fetched
+//
+// Returns:
+//   - []TableInfo: Tables of the schema, ordered by name
+//   - error: Any error that occurred during the queries, classified like those of Fetch
+func ListTables(ctx context.Context, conn Querier, opts FetchOptions) ([]TableInfo, error) {
+	opts.Retry = opts.Retry.on(conn)
+	_, _, tables, ext, err := list(ctx, conn, opts)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	for i := range tables {
+		ext.apply(&tables[i])
+	}
+	return tables, nil
+}
+
+// ListSchemas lists the PostgreSQL schemas (namespaces) of a database that the role may use,
+// leaving out the system schemas (pg_catalog, information_schema, and the pg_toast and temporary
+// schemas).
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//
+// Returns:
+//   - []string: Names of the schemas, in name order
+//   - error: Any error that occurred during the query, classified like those of Fetch
+func ListSchemas(ctx context.Context, conn Querier) ([]string, error) {
+	rows, err := conn.Query(ctx, listSchemasQuery)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("error scanning schema name: %w", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, classifyError(err)
+	}
+	return names, nil
+}
+
+// listSchemasQuery lists the schemas the role may use, without the system schemas
+const listSchemasQuery = `
+	SELECT nspname
+	FROM pg_catalog.pg_namespace
+	WHERE nspname NOT LIKE 'pg\_%'
+		AND nspname <> 'information_schema'
+		AND has_schema_privilege(oid, 'USAGE')
+	ORDER BY nspname
+`
+
+// fetch implements Fetch, returning errors before they are classified.
+func fetch(ctx context.Context, conn Querier, opts FetchOptions) (*Schema, error) {
+	schema, cat, tables, ext, err := list(ctx, conn, opts)
+	if err != nil {
+		return nil, err
+	}
+	schemaName := schema.Name
+
+	// Now that the initial query is complete, fetch detailed info for the tables with one query
+	// per kind of object, leaving out the tables whose details are reused or not wanted
+	opts.phaseStart(PhaseTableDetails)
+	toFetch := slices.DeleteFunc(slices.Clone(tables), func(table TableInfo) bool {
+		_, reused := opts.Reuse[table.Name]
+		return reused || (opts.Details != nil && !opts.Details(table))
+	})
+	details, err := readDetails(ctx, conn, cat, schemaName, toFetch, len(toFetch) == len(tables) && !opts.filtersTables(), opts)
+	if err != nil {
+		return nil, err
+	}
+	fetched := make(map[string]tableDetails, len(toFetch))
+	for i, table := range toFetch {
+		fetched[table.Name] = details[i]
+	}
+
+	for _, table := range tables {
+		result, ok := fetched[table.Name]
+		if !ok {
+			// Tables whose details are not wanted are kept with their name only
+			result.info, ok = opts.Reuse[table.Name]
+			if !ok {
+				result.info = TableInfo{Name: table.Name}
+			}
+		}
+		if result.err != nil {
+			schema.Errors = append(schema.Errors, FetchError{Table: table.Name, Message: result.err.Error()})
+			continue
+		}
+		schema.Tables[table.Name] = withProperties(result.info, table, ext)
+	}
+
+	schema.Sequences, err = readSequences(ctx, conn, cat, schemaName, opts)
+	if err != nil {
+		return nil, err
+	}
+	schema.Functions, err = readFunctions(ctx, conn, cat, schemaName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows are counted once the details are read, as exact counts can take long
+	if opts.RowCounts != "" {
+		opts.phaseStart(PhaseRowCounts)
+		names := make([]string, 0, len(tables))
+		for _, table := range tables {
+			if opts.Details == nil || opts.Details(table) {
+				names = append(names, table.Name)
+			}
+		}
+		schema.RowCounts, err = countRows(ctx, conn, cat, schemaName, names, opts.RowCounts, opts.Retry)
+		if err != nil {
+			return nil, err
+		}
+		schema.RowCountMode = opts.RowCounts
+	}
+	if opts.DataChecksums != "" {
+		opts.phaseStart(PhaseDataChecksum)
+		schema.DataChecksums, err = checksumData(ctx, conn, cat, schema, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.Sizes {
+		opts.phaseStart(PhaseSizes)
+		schema.Sizes, err = readSizes(ctx, conn, cat, schema, opts.Retry)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return schema, nil
+}
+
+// withProperties completes the details of a table with its table-level properties.
+//
+// Parameters:
+//   - info: Columns, keys, and indexes of the table
+//   - listed: Table as listed, with its table-level properties
+//   - ext: TimescaleDB and Citus metadata of the schema
+//
+// Returns:
+//   - TableInfo: Complete table
+func withProperties(info, listed TableInfo, ext extensionInfo) TableInfo {
+	info.Comment = listed.Comment
+	info.PartitionOf = listed.PartitionOf
+	info.PartitionKey = listed.PartitionKey
+	info.Owner = listed.Owner
+	info.View = listed.View
+	ext.apply(&info)
+	return info
+}
+
+// list detects the dialect and server version of a database, and lists the tables of a schema.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - opts: Options controlling what is fetched
+//
+// Returns:
+//   - *Schema: Schema without tables, with its name, server version, and unsupported features set
+//   - catalog: Catalog queries of the database's dialect and version
+//   - []TableInfo: Tables of the schema, with their table-level properties only, ordered by name
+//   - extensionInfo: TimescaleDB and Citus metadata of the schema
+//   - error: Any error that occurred during the queries, once retries are exhausted
+func list(ctx context.Context, conn Querier, opts FetchOptions) (*Schema, catalog, []TableInfo, extensionInfo, error) {
 	schema := NewSchema()
+	schemaName := opts.SchemaName
+	if schemaName == "" {
+		schemaName = DefaultSchemaName
+	}
+	schema.Name = schemaName
+
+	// Malformed table patterns and unknown row count and checksum modes are reported before any query
+	patterns, err := patternsOf(opts)
+	if err != nil {
+		return nil, catalog{}, nil, extensionInfo{}, err
+	}
+	if err := checkRowCounts(opts.RowCounts); err != nil {
+		return nil, catalog{}, nil, extensionInfo{}, err
+	}
+	if err := checkDataChecksums(opts.DataChecksums); err != nil {
+		return nil, catalog{}, nil, extensionInfo{}, err
+	}
+
+	// Choose the catalog queries of the database's dialect
+	dialect := opts.Dialect
+	if dialect == "" {
+		err := opts.Retry.Do(ctx, func() error {
+			var err error
+			dialect, err = DetectDialect(ctx, conn)
+			return err
+		})
+		if err != nil {
+			return nil, catalog{}, nil, extensionInfo{}, err
+		}
+	}
+
+	// PostgreSQL and Aurora catalogs change between releases, so the queries also depend on the version
+	version := opts.ServerVersion
+	if version == 0 && (dialect == DialectPostgres || dialect == DialectAurora) {
+		err := opts.Retry.Do(ctx, func() error {
+			var err error
+			version, err = DetectServerVersion(ctx, conn)
+			return err
+		})
+		if err != nil {
+			return nil, catalog{}, nil, extensionInfo{}, err
+		}
+	}
+	schema.ServerVersion = version
+
+	cat, err := catalogFor(dialect, version)
+	if err != nil {
+		return nil, catalog{}, nil, extensionInfo{}, err
+	}
+	schema.UnsupportedFeatures = append(schema.UnsupportedFeatures, cat.unsupported...)
+
+	// List the tables first, retrying transient failures
+	opts.phaseStart(PhaseListTables)
+	var tables []TableInfo
+	err = opts.Retry.Do(ctx, func() error {
+		var err error
+		tables, err = listTables(ctx, conn, cat, schemaName, patterns)
+		return err
+	})
+	if err != nil {
+		return nil, catalog{}, nil, extensionInfo{}, err
+	}
+
+	// Read the TimescaleDB and Citus metadata, and leave out the chunk and shard tables those
+	// extensions manage, which are implementation details of their hypertables and distributed tables
+	var ext extensionInfo
+	if cat.extensions {
+		err = opts.Retry.Do(ctx, func() error {
+			var err error
+			ext, err = fetchExtensionInfo(ctx, conn, schemaName)
+			return err
+		})
+		if err != nil {
+			return nil, catalog{}, nil, extensionInfo{}, err
+		}
+		schema.UnsupportedFeatures = append(schema.UnsupportedFeatures, ext.unsupported...)
+		tables = slices.DeleteFunc(tables, func(table TableInfo) bool {
+			return ext.internal[table.Name]
+		})
+	}
+
+	return schema, cat, tables, ext, nil
+}
+
+// tableDetails is the outcome of fetching the details of one table.
+type tableDetails struct {
+	info TableInfo // Columns, keys, and indexes of the table
+	err  error     // Error the table could not be fetched with, if any
+}
+
+// readDetails fetches the details of tables with one query per kind of object. If that fails,
+// it falls back to fetching the tables one by one, so that only the tables whose details cannot
+// be read are left out, unless opts.StopOnError is set or the fetch was cancelled.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - cat: Catalog queries of the database's dialect
+//   - schemaName: Name of the PostgreSQL schema the tables belong to
+//   - tables: Tables to fetch the details of
+//   - all: Whether tables are all the tables of the schema, whose details are then read without naming them
+//   - opts: Options controlling the fetch
+//
+// Returns:
+//   - []tableDetails: Details of each table, in the order of tables
+//   - error: The error that stopped the fetch, if it was cancelled or failed with opts.StopOnError
+func readDetails(ctx context.Context, conn Querier, cat catalog, schemaName string, tables []TableInfo, all bool, opts FetchOptions) ([]tableDetails, error) {
+	if len(tables) == 0 {
+		return nil, nil
+	}
+
+	details, err := fetchAllDetails(ctx, conn, cat, schemaName, tables, all, opts)
+	if err == nil {
+		return details, nil
+	}
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("fetch cancelled: %w", ctx.Err())
+	}
+	if opts.StopOnError {
+		return nil, fmt.Errorf("error fetching table info: %w", err)
+	}
+	return fetchDetails(ctx, conn, cat, schemaName, tables, opts)
+}
+
+// fetchAllDetails fetches the details of tables with one query per kind of object, and
+// assembles them in memory.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - cat: Catalog queries of the database's dialect
+//   - schemaName: Name of the PostgreSQL schema the tables belong to
+//   - tables: Tables to fetch the details of
+//   - all: Whether tables are all the tables of the schema, whose details are then read without naming them
+//   - opts: Options controlling the fetch
+//
+// Returns:
+//   - []tableDetails: Details of each table, in the order of tables
+//   - error: Any error that occurred during the queries, once retries are exhausted
+func fetchAllDetails(ctx context.Context, conn Querier, cat catalog, schemaName string, tables []TableInfo, all bool, opts FetchOptions) ([]tableDetails, error) {
+	var names []string
+	if !all {
+		names = make([]string, len(tables))
+		for i, table := range tables {
+			names[i] = table.Name
+		}
+	}
+
+	var details []tableDetails
+	err := opts.Retry.Do(ctx, func() error {
+		details = make([]tableDetails, len(tables))
+		byName := make(map[string]*TableInfo, len(tables))
+		for i, table := range tables {
+			details[i].info.Name = table.Name
+			byName[table.Name] = &details[i].info
+		}
+		return readTableDetails(ctx, conn, cat, schemaName, names, byName, opts.CursorSize)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range details {
+		if cat.normalize != nil {
+			cat.normalize(&details[i].info)
+		}
+		if opts.OnTableFetched != nil {
+			opts.OnTableFetched(details[i].info, i+1, len(details))
+		}
+	}
+	return details, nil
+}
+
+// fetchDetails fetches the details of the tables, up to opts.Concurrency at a time. A table
+// whose details cannot be fetched has its error recorded in its result, unless opts.StopOnError
+// is set, in which case the first such error stops the fetch and is returned.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool, safe for concurrent use if opts.Concurrency is above one
+//   - cat: Catalog queries of the database's dialect
+//   - schemaName: Name of the PostgreSQL schema the tables belong to
+//   - tables: Tables to fetch the details of
+//   - opts: Options controlling the fetch
+//
+// Returns:
+//   - []tableDetails: Details of each table, in the order of tables
+//   - error: The error that stopped the fetch, if it was cancelled or a table failed with opts.StopOnError
+func fetchDetails(ctx context.Context, conn Querier, cat catalog, schemaName string, tables []TableInfo, opts FetchOptions) ([]tableDetails, error) {
+	workers := min(max(opts.Concurrency, 1), len(tables))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	details := make([]tableDetails, len(tables))
+	indexes := make(chan int)
+	var (
+		mu      sync.Mutex // Guards fetched and stopErr, and serializes the OnTableFetched hook
+		fetched int        // Number of tables fetched so far, including failed ones
+		stopErr error      // Error that stopped the fetch, if any
+	)
+	stop := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if stopErr == nil {
+			stopErr = err
+		}
+		cancel()
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				table := tables[i]
+				var tableInfo TableInfo
+				err := opts.Retry.Do(ctx, func() error {
+					var err error
+					tableInfo, err = fetchTableInfo(ctx, conn, cat, schemaName, table.Name)
+					return err
+				})
+				if err != nil {
+					// A cancelled fetch is never partial, whatever the options
+					if opts.StopOnError || ctx.Err() != nil {
+						stop(fmt.Errorf("error fetching table info for %s: %w", table.Name, err))
+						continue
+					}
+					details[i].err = err
+				} else {
+					if cat.normalize != nil {
+						cat.normalize(&tableInfo)
+					}
+					details[i].info = tableInfo
+				}
+
+				// Failed tables count towards the progress, but are not reported to the hook
+				mu.Lock()
+				fetched++
+				if opts.OnTableFetched != nil && err == nil {
+					opts.OnTableFetched(tableInfo, fetched, len(tables))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := range tables {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if stopErr != nil {
+		return nil, stopErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("fetch cancelled: %w", err)
+	}
+	return details, nil
+}
 
-	// Query to fetch all table names from the public schema
-	rows, err := conn.Query(ctx, `
-		SELECT table_name 
-		FROM information_schema.tables 
-		WHERE table_schema = 'public'
-		ORDER BY table_name
-	`)
+// listTables lists the tables of a schema, with their table-level properties (comment,
+// partitioning, owner, and view definition and references) but without their columns, keys, and indexes.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - cat: Catalog queries of the database's dialect
+//   - schemaName: PostgreSQL schema to list the tables of
+//   - patterns: Table filters, applied by the query unless the dialect has no arrays
+//
+// Returns:
+//   - []TableInfo: Tables of the schema kept by the filters, ordered by name
+//   - error: Any error that occurred during the query
+func listTables(ctx context.Context, conn Querier, cat catalog, schemaName string, patterns tablePatterns) ([]TableInfo, error) {
+	// Query to fetch the table names from the schema, along with their comments,
+	// partitioning details, owners, and view definitions and references
+	args := []any{schemaName}
+	if !cat.noArrays {
+		args = append(args, patterns.include, patterns.exclude)
+	}
+	rows, err := conn.Query(ctx, cat.tablesQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching tables: %w", err)
 	}
 	defer rows.Close()
 
-	// Collect all table names first
-	var tableNames []string
+	// Collect the tables
+	var tables []TableInfo
 	for rows.Next() {
-		var tableName string
-		if err := rows.Scan(&tableName); err != nil {
+		var table TableInfo
+		var comment, viewDefinition sql.NullString
+		var materialized, populated bool
+		var references []string
+		var referenceList string
+		dest := []any{&table.Name, &comment, &table.Owner, &table.PartitionOf, &table.PartitionKey, &viewDefinition, &materialized, &populated, &references}
+		if cat.noArrays {
+			dest[len(dest)-1] = &referenceList
+		}
+		if err := rows.Scan(dest...); err != nil {
 			return nil, fmt.Errorf("error scanning table name: %w", err)
 		}
-		tableNames = append(tableNames, tableName)
+		table.Comment = comment.String
+		if referenceList != "" {
+			references = strings.Split(referenceList, ",")
+		}
+		if viewDefinition.Valid {
+			table.View = &ViewInfo{Definition: viewDefinition.String, Materialized: materialized, Populated: materialized && populated}
+			if len(references) > 0 {
+				table.View.References = references
+			}
+		}
+		if cat.noArrays && !patterns.match(table.Name) {
+			continue
+		}
+		tables = append(tables, table)
 	}
 
 	// Check for any errors that occurred during iteration
@@ -101,188 +890,253 @@ func FetchSchema(ctx context.Context, conn *pgx.Conn) (*Schema, error) {
 		return nil, fmt.Errorf("error iterating table names: %w", err)
 	}
 
-	// Now that the initial query is complete, fetch detailed info for each table
-	for _, tableName := range tableNames {
-		tableInfo, err := fetchTableInfo(ctx, conn, tableName)
-		if err != nil {
-			return nil, fmt.Errorf("error fetching table info for %s: %w", tableName, err)
-		}
-
-		schema.Tables[tableName] = tableInfo
-	}
-
-	return schema, nil
+	return tables, nil
 }
 
 // fetchTableInfo retrieves detailed information about a specific table, including its columns,
-// primary keys, indexes, and foreign key constraints.
+// primary keys, indexes, foreign key constraints, and triggers.
 //
 // Parameters:
 //   - ctx: Context for the database operation
-//   - conn: Active PostgreSQL connection
+//   - conn: Active PostgreSQL connection or pool
+//   - cat: Catalog queries of the database's dialect
+//   - schemaName: Name of the PostgreSQL schema the table belongs to
 //   - tableName: Name of the table to fetch information for
 //
 // Returns:
 //   - TableInfo: Complete information about the table
 //   - error: Any error that occurred during the fetch operation
-func fetchTableInfo(ctx context.Context, conn *pgx.Conn, tableName string) (TableInfo, error) {
+func fetchTableInfo(ctx context.Context, conn Querier, cat catalog, schemaName, tableName string) (TableInfo, error) {
 	tableInfo := TableInfo{
 		Name: tableName,
 	}
+	err := readTableDetails(ctx, conn, cat, schemaName, []string{tableName}, map[string]*TableInfo{tableName: &tableInfo}, 0)
+	return tableInfo, err
+}
 
-	// Fetch column information including data types, nullability, defaults, and identity status
-	rows, err := conn.Query(ctx, `
-		SELECT 
-			column_name,
-			data_type,
-			is_nullable,
-			column_default,
-			is_identity
-		FROM information_schema.columns
-		WHERE table_schema = 'public' AND table_name = $1
-		ORDER BY ordinal_position
-	`, tableName)
+// readTableDetails reads the columns, primary keys, indexes, foreign key constraints, and
+// triggers of tables, with one query per kind of object. The details of the named tables, or of every table
+// of the schema at once, are read; rows of tables that are not being read are ignored.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - cat: Catalog queries of the database's dialect
+//   - schemaName: Name of the PostgreSQL schema the tables belong to
+//   - tableNames: Names of the tables to read, or nil to read every table of the schema
+//   - tables: Tables the details are added to, keyed by name
+//   - cursorSize: Number of rows read at a time through server-side cursors; zero reads each query in one go
+//
+// Returns:
+//   - error: Any error that occurred during the queries
+func readTableDetails(ctx context.Context, conn Querier, cat catalog, schemaName string, tableNames []string, tables map[string]*TableInfo, cursorSize int) error {
+	// Dialects without arrays read the details of one table, or else of every table
+	var tableFilter any
+	switch {
+	case tableNames == nil:
+	case !cat.noArrays:
+		tableFilter = tableNames
+	case len(tableNames) == 1:
+		tableFilter = tableNames[0]
+	}
+
+	// Large results are read through cursors on connections that can begin a transaction;
+	// connections that pipeline queries otherwise send all of them at once, then read their
+	// results in order. Each result is closed before the next one is read
+	query := conn.Query
+	var tx pgx.Tx
+	if cursorSize > 0 {
+		var err error
+		if tx, err = beginTx(ctx, conn); err != nil {
+			return err
+		}
+	}
+	if tx != nil {
+		// The transaction only holds the cursors, and is rolled back even if the fetch was cancelled
+		defer tx.Rollback(context.WithoutCancel(ctx))
+		cursors := 0
+		query = func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+			cursors++
+			return queryCursor(ctx, tx, fmt.Sprintf("schema_check_details_%d", cursors), cursorSize, sql, args...)
+		}
+	} else if batcher, ok := conn.(Batcher); ok {
+		batch := &pgx.Batch{}
+		for _, q := range []string{cat.columnsQuery, cat.primaryKeysQuery, cat.indexesQuery, cat.foreignKeysQuery, cat.triggersQuery} {
+			if q != "" {
+				batch.Queue(q, schemaName, tableFilter)
+			}
+		}
+		results := batcher.SendBatch(ctx, batch)
+		defer results.Close()
+		query = func(context.Context, string, ...any) (pgx.Rows, error) {
+			return results.Query()
+		}
+	}
+
+	// Fetch column information including data types, nullability, defaults, identity status, comments,
+	// compression methods, and length limits
+	rows, err := query(ctx, cat.columnsQuery, schemaName, tableFilter)
 	if err != nil {
-		return tableInfo, fmt.Errorf("error fetching columns: %w", err)
+		return fmt.Errorf("error fetching columns: %w", err)
 	}
 	defer rows.Close()
 
-	// Process each column and add it to the table information
+	// Process each column and add it to its table
 	for rows.Next() {
+		var table string
 		var col ColumnInfo
 		var nullable string
 		var defaultVal sql.NullString
 		var identity string
-		if err := rows.Scan(&col.Name, &col.Type, &nullable, &defaultVal, &identity); err != nil {
-			return tableInfo, fmt.Errorf("error scanning column: %w", err)
+		var comment sql.NullString
+		var maxLength sql.NullInt32
+		if err := rows.Scan(&table, &col.Name, &col.Type, &nullable, &defaultVal, &identity, &comment, &col.Compression, &maxLength); err != nil {
+			return fmt.Errorf("error scanning column: %w", err)
 		}
+		col.MaxLength = int(maxLength.Int32)
 		col.Nullable = nullable == "YES"
 		col.IsIdentity = identity == "YES"
+		col.Comment = comment.String
 		if defaultVal.Valid {
 			col.Default = defaultVal.String
 		} else {
 			col.Default = ""
 		}
-		tableInfo.Columns = append(tableInfo.Columns, col)
+		if tableInfo, exists := tables[table]; exists {
+			tableInfo.Columns = append(tableInfo.Columns, col)
+		}
 	}
 
 	// Check for any errors that occurred during iteration
 	if err := rows.Err(); err != nil {
-		return tableInfo, fmt.Errorf("error iterating columns: %w", err)
+		return fmt.Errorf("error iterating columns: %w", err)
 	}
+	rows.Close()
 
 	// Fetch primary key information
-	pkRows, err := conn.Query(ctx, `
-		SELECT kcu.column_name
-		FROM information_schema.table_constraints tc
-		JOIN information_schema.key_column_usage kcu
-			ON tc.constraint_name = kcu.constraint_name
-		WHERE tc.constraint_type = 'PRIMARY KEY'
-			AND tc.table_schema = 'public'
-			AND tc.table_name = $1
-		ORDER BY kcu.ordinal_position
-	`, tableName)
+	pkRows, err := query(ctx, cat.primaryKeysQuery, schemaName, tableFilter)
 	if err != nil {
-		return tableInfo, fmt.Errorf("error fetching primary keys: %w", err)
+		return fmt.Errorf("error fetching primary keys: %w", err)
 	}
 	defer pkRows.Close()
 
 	// Process each primary key column
 	for pkRows.Next() {
-		var colName string
-		if err := pkRows.Scan(&colName); err != nil {
-			return tableInfo, fmt.Errorf("error scanning primary key: %w", err)
+		var table, colName, constraintName string
+		if err := pkRows.Scan(&table, &colName, &constraintName); err != nil {
+			return fmt.Errorf("error scanning primary key: %w", err)
+		}
+		if tableInfo, exists := tables[table]; exists {
+			tableInfo.PrimaryKeys = append(tableInfo.PrimaryKeys, colName)
+			tableInfo.PrimaryKeyName = constraintName
 		}
-		tableInfo.PrimaryKeys = append(tableInfo.PrimaryKeys, colName)
 	}
 
 	// Check for any errors that occurred during iteration
 	if err := pkRows.Err(); err != nil {
-		return tableInfo, fmt.Errorf("error iterating primary keys: %w", err)
-	}
-
-	// Fetch index information including index names, columns, and uniqueness
-	indexRows, err := conn.Query(ctx, `
-		SELECT
-			i.relname as index_name,
-			array_agg(a.attname) as column_names,
-			ix.indisunique as is_unique
-		FROM
-			pg_class t,
-			pg_class i,
-			pg_index ix,
-			pg_attribute a
-		WHERE
-			t.oid = ix.indrelid
-			AND i.oid = ix.indexrelid
-			AND a.attrelid = t.oid
-			AND a.attnum = ANY(ix.indkey)
-			AND t.relkind = 'r'
-			AND t.relname = $1
-		GROUP BY
-			i.relname,
-			ix.indisunique
-		ORDER BY
-			i.relname
-	`, tableName)
-	if err != nil {
-		return tableInfo, fmt.Errorf("error fetching indexes: %w", err)
+		return fmt.Errorf("error iterating primary keys: %w", err)
 	}
-	defer indexRows.Close()
+	pkRows.Close()
 
-	// Process each index
-	for indexRows.Next() {
-		var idx IndexInfo
-		if err := indexRows.Scan(&idx.Name, &idx.Columns, &idx.Unique); err != nil {
-			return tableInfo, fmt.Errorf("error scanning index: %w", err)
+	// Fetch index information including index names, keys, uniqueness, included columns,
+	// predicates, and access methods, unless the dialect has no indexes
+	if cat.indexesQuery != "" {
+		indexRows, err := query(ctx, cat.indexesQuery, schemaName, tableFilter)
+		if err != nil {
+			return fmt.Errorf("error fetching indexes: %w", err)
 		}
-		tableInfo.Indexes = append(tableInfo.Indexes, idx)
-	}
+		defer indexRows.Close()
 
-	// Check for any errors that occurred during iteration
-	if err := indexRows.Err(); err != nil {
-		return tableInfo, fmt.Errorf("error iterating indexes: %w", err)
+		// Process each index
+		for indexRows.Next() {
+			var table string
+			var idx IndexInfo
+			var positions []int32
+			var expressions []string
+			if err := indexRows.Scan(&table, &idx.Name, &idx.Columns, &idx.Unique, &positions, &expressions,
+				&idx.Include, &idx.Predicate, &idx.Method); err != nil {
+				return fmt.Errorf("error scanning index: %w", err)
+			}
+			for i, expression := range expressions {
+				idx.Expressions = append(idx.Expressions, IndexExpression{Position: int(positions[i]), Expression: expression})
+			}
+			if len(idx.Include) == 0 {
+				idx.Include = nil
+			}
+			if tableInfo, exists := tables[table]; exists {
+				tableInfo.Indexes = append(tableInfo.Indexes, idx)
+			}
+		}
+
+		// Check for any errors that occurred during iteration
+		if err := indexRows.Err(); err != nil {
+			return fmt.Errorf("error iterating indexes: %w", err)
+		}
+		indexRows.Close()
 	}
 
 	// Fetch foreign key information including referenced tables and columns
-	fkRows, err := conn.Query(ctx, `
-		SELECT
-			tc.constraint_name,
-			array_agg(kcu.column_name) as columns,
-			ccu.table_name as referenced_table,
-			array_agg(ccu.column_name) as referenced_columns
-		FROM
-			information_schema.table_constraints tc
-			JOIN information_schema.key_column_usage kcu
-				ON tc.constraint_name = kcu.constraint_name
-			JOIN information_schema.constraint_column_usage ccu
-				ON ccu.constraint_name = tc.constraint_name
-		WHERE
-			tc.constraint_type = 'FOREIGN KEY'
-			AND tc.table_schema = 'public'
-			AND tc.table_name = $1
-		GROUP BY
-			tc.constraint_name,
-			ccu.table_name
-	`, tableName)
+	fkRows, err := query(ctx, cat.foreignKeysQuery, schemaName, tableFilter)
 	if err != nil {
-		return tableInfo, fmt.Errorf("error fetching foreign keys: %w", err)
+		return fmt.Errorf("error fetching foreign keys: %w", err)
 	}
 	defer fkRows.Close()
 
 	// Process each foreign key constraint
 	for fkRows.Next() {
+		var table string
 		var fk ForeignKeyInfo
-		if err := fkRows.Scan(&fk.Name, &fk.Columns, &fk.ReferencedTable, &fk.ReferencedColumns); err != nil {
-			return tableInfo, fmt.Errorf("error scanning foreign key: %w", err)
+		if cat.noArrays {
+			var columns, referencedColumns string
+			if err := fkRows.Scan(&table, &fk.Name, &columns, &fk.ReferencedTable, &referencedColumns, &fk.Definition); err != nil {
+				return fmt.Errorf("error scanning foreign key: %w", err)
+			}
+			fk.Columns = strings.Split(columns, ",")
+			fk.ReferencedColumns = strings.Split(referencedColumns, ",")
+		} else if err := fkRows.Scan(&table, &fk.Name, &fk.Columns, &fk.ReferencedTable, &fk.ReferencedColumns, &fk.Definition); err != nil {
+			return fmt.Errorf("error scanning foreign key: %w", err)
+		}
+		if tableInfo, exists := tables[table]; exists {
+			tableInfo.ForeignKeys = append(tableInfo.ForeignKeys, fk)
 		}
-		tableInfo.ForeignKeys = append(tableInfo.ForeignKeys, fk)
 	}
 
 	// Check for any errors that occurred during iteration
 	if err := fkRows.Err(); err != nil {
-		return tableInfo, fmt.Errorf("error iterating foreign keys: %w", err)
+		return fmt.Errorf("error iterating foreign keys: %w", err)
 	}
+	fkRows.Close()
 
-	return tableInfo, nil
-} 
\ No newline at end of file
+	// Fetch trigger information including timing, events, level, condition, and function,
+	// unless the dialect has no triggers
+	if cat.triggersQuery != "" {
+		triggerRows, err := query(ctx, cat.triggersQuery, schemaName, tableFilter)
+		if err != nil {
+			return fmt.Errorf("error fetching triggers: %w", err)
+		}
+		defer triggerRows.Close()
+
+		// Process each trigger
+		for triggerRows.Next() {
+			var table string
+			var trigger TriggerInfo
+			if err := triggerRows.Scan(&table, &trigger.Name, &trigger.Timing, &trigger.Events, &trigger.Columns,
+				&trigger.Level, &trigger.When, &trigger.Function); err != nil {
+				return fmt.Errorf("error scanning trigger: %w", err)
+			}
+			if len(trigger.Columns) == 0 {
+				trigger.Columns = nil
+			}
+			if tableInfo, exists := tables[table]; exists {
+				tableInfo.Triggers = append(tableInfo.Triggers, trigger)
+			}
+		}
+
+		// Check for any errors that occurred during iteration
+		if err := triggerRows.Err(); err != nil {
+			return fmt.Errorf("error iterating triggers: %w", err)
+		}
+	}
+
+	return nil
+}
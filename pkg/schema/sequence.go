@@ -0,0 +1,151 @@
+package schema
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+)
+
+// SequenceInfo describes a sequence: the values it generates and how it generates them.
+type SequenceInfo struct {
+	Name      string `json:"name"`                // Name of the sequence
+	DataType  string `json:"data_type,omitempty"` // Data type of the sequence (e.g., "bigint"); empty if unknown, as on PostgreSQL 9.6
+	Start     int64  `json:"start"`               // First value of the sequence
+	Increment int64  `json:"increment"`           // Value added to the current value to get the next one
+	Min       int64  `json:"min"`                 // Smallest value the sequence can generate
+	Max       int64  `json:"max"`                 // Largest value the sequence can generate
+	Cache     int64  `json:"cache,omitempty"`     // Number of values preallocated by each session; zero if unknown, as on PostgreSQL 9.6 and CockroachDB
+	Cycle     bool   `json:"cycle,omitempty"`     // Whether the sequence wraps around when it reaches its limit
+	Owner     string `json:"owner,omitempty"`     // Name of the role that owns the sequence
+}
+
+// readSequences reads the sequences of a schema whose names the table filters keep, including
+// those backing serial and identity columns.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - cat: Catalog queries of the database's dialect
+//   - schemaName: PostgreSQL schema to read the sequences of
+//   - opts: Options holding the table filters, applied to the names of the sequences, and the retries
+//
+// Returns:
+//   - map[string]SequenceInfo: Sequences keyed by name, or nil if the dialect has none
+//   - error: Any error that occurred during the query
+func readSequences(ctx context.Context, conn Querier, cat catalog, schemaName string, opts FetchOptions) (map[string]SequenceInfo, error) {
+	if cat.sequencesQuery == "" {
+		return nil, nil
+	}
+	patterns, err := patternsOf(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var sequences map[string]SequenceInfo
+	err = opts.Retry.Do(ctx, func() error {
+		sequences = make(map[string]SequenceInfo)
+		rows, err := conn.Query(ctx, cat.sequencesQuery, schemaName, patterns.include, patterns.exclude)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var seq SequenceInfo
+			if err := rows.Scan(&seq.Name, &seq.DataType, &seq.Start, &seq.Increment, &seq.Min, &seq.Max, &seq.Cache, &seq.Cycle, &seq.Owner); err != nil {
+				return err
+			}
+			sequences[seq.Name] = seq
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading sequences: %w", err)
+	}
+	return sequences, nil
+}
+
+// Catalog queries of sequences. Each takes the schema name as $1, and the include and exclude
+// patterns of the table filters as $2 and $3.
+const (
+	postgresSequencesQuery = `
+	SELECT
+		c.relname,
+		format_type(s.seqtypid, NULL),
+		s.seqstart,
+		s.seqincrement,
+		s.seqmin,
+		s.seqmax,
+		s.seqcache,
+		s.seqcycle,
+		pg_get_userbyid(c.relowner)
+	FROM pg_sequence s
+	JOIN pg_class c
+		ON c.oid = s.seqrelid
+	JOIN pg_namespace n
+		ON n.oid = c.relnamespace
+	WHERE n.nspname = $1
+		AND (cardinality($2::text[]) = 0 OR c.relname ~ ANY($2::text[]))
+		AND NOT c.relname ~ ANY($3::text[])
+	ORDER BY c.relname
+`
+
+	// PostgreSQL 9.6 has no pg_sequence catalog, and reports every sequence as bigint in
+	// information_schema, so the data type and cache size are left unknown
+	postgres96SequencesQuery = `
+	SELECT
+		s.sequence_name,
+		'',
+		s.start_value::bigint,
+		s.increment::bigint,
+		s.minimum_value::bigint,
+		s.maximum_value::bigint,
+		0::bigint,
+		s.cycle_option = 'YES',
+		pg_get_userbyid(c.relowner)
+	FROM information_schema.sequences s
+	JOIN pg_namespace n
+		ON n.nspname = s.sequence_schema
+	JOIN pg_class c
+		ON c.relnamespace = n.oid AND c.relname = s.sequence_name
+	WHERE s.sequence_schema = $1
+		AND (cardinality($2::text[]) = 0 OR s.sequence_name ~ ANY($2::text[]))
+		AND NOT s.sequence_name ~ ANY($3::text[])
+	ORDER BY s.sequence_name
+`
+
+	// CockroachDB caches sequence values per node rather than per session, and does not
+	// report it in information_schema, so the cache size is left unknown
+	cockroachSequencesQuery = `
+	SELECT
+		s.sequence_name,
+		s.data_type,
+		s.start_value::bigint,
+		s.increment::bigint,
+		s.minimum_value::bigint,
+		s.maximum_value::bigint,
+		0::bigint,
+		s.cycle_option = 'YES',
+		pg_get_userbyid(c.relowner)
+	FROM information_schema.sequences s
+	JOIN pg_namespace n
+		ON n.nspname = s.sequence_schema
+	JOIN pg_class c
+		ON c.relnamespace = n.oid AND c.relname = s.sequence_name
+	WHERE s.sequence_schema = $1
+		AND (cardinality($2::text[]) = 0 OR s.sequence_name ~ ANY($2::text[]))
+		AND NOT s.sequence_name ~ ANY($3::text[])
+	ORDER BY s.sequence_name
+`
+)
+
+// appendDefinition appends an unambiguous encoding of every field of a sequence to a buffer,
+// as TableInfo.appendDefinition does for tables.
+func (s SequenceInfo) appendDefinition(buf []byte) []byte {
+	buf = appendString(buf, s.Name)
+	buf = appendString(buf, s.DataType)
+	for _, value := range []int64{s.Start, s.Increment, s.Min, s.Max, s.Cache} {
+		buf = binary.AppendVarint(buf, value)
+	}
+	buf = appendBool(buf, s.Cycle)
+	return appendString(buf, s.Owner)
+}
@@ -0,0 +1,98 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SessionSettings are the timeouts set on every session opened by Connect and ConnectPool, so
+// that reading the catalog of a production server never waits for long behind DDL, nor holds
+// its locks for long, and how the sessions adapt to a connection pooler. Zero values leave the
+// server's setting unchanged. The zero value changes nothing.
+//
+// Sessions behind a pooler sharing server sessions between transactions, such as PgBouncer in
+// transaction mode, cannot set timeouts, as they would stay on server sessions later used by
+// other clients: the timeouts are not set, and the pooler's own limits apply instead.
+type SessionSettings struct {
+	StatementTimeout                time.Duration // statement_timeout: time limit of each catalog query
+	LockTimeout                     time.Duration // lock_timeout: time limit of waiting for a lock, such as one held by concurrent DDL
+	IdleInTransactionSessionTimeout time.Duration // idle_in_transaction_session_timeout: time after which the server ends a session left idle in a transaction
+	Pooler                          string        // Connection pooler in front of the database: PoolerAuto, PoolerNone, or PoolerTransaction; empty for PoolerNone
+}
+
+// DefaultSessionSettings are settings suitable for busy production servers: catalog queries
+// run for at most five minutes, give up after waiting ten seconds for a lock, and sessions left
+// idle in a transaction are ended after a minute. PgBouncer is detected when connecting.
+var DefaultSessionSettings = SessionSettings{
+	StatementTimeout:                5 * time.Minute,
+	LockTimeout:                     10 * time.Second,
+	IdleInTransactionSessionTimeout: time.Minute,
+	Pooler:                          PoolerAuto,
+}
+
+// SQLSTATE codes of the errors returned by servers that do not know a setting.
+const (
+	sqlStateUndefinedObject = "42704" // undefined_object: unrecognized configuration parameter
+	sqlStateCantChangeParam = "55P02" // cant_change_runtime_param
+)
+
+// apply sets the timeouts on a new session. Settings the server does not know, such as
+// lock_timeout on Redshift, are skipped.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Session the timeouts are set on
+//
+// Returns:
+//   - error: Any error that occurred while setting a known timeout
+func (s SessionSettings) apply(ctx context.Context, conn *pgconn.PgConn) error {
+	settings := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"statement_timeout", s.StatementTimeout},
+		{"lock_timeout", s.LockTimeout},
+		{"idle_in_transaction_session_timeout", s.IdleInTransactionSessionTimeout},
+	}
+	for _, setting := range settings {
+		if setting.value <= 0 {
+			continue
+		}
+
+		// Timeouts are given in milliseconds, rounded up so that they are never disabled
+		milliseconds := (setting.value + time.Millisecond - 1) / time.Millisecond
+		_, err := conn.Exec(ctx, fmt.Sprintf("SET %s = %d", setting.name, milliseconds)).ReadAll()
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && (pgErr.Code == sqlStateUndefinedObject || pgErr.Code == sqlStateCantChangeParam ||
+			pgErr.Code == sqlStateFeatureNotSupported) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error setting %s: %w", setting.name, err)
+		}
+	}
+	return nil
+}
+
+// afterConnect returns a hook that sets the timeouts on each new session, after running any
+// hook already configured.
+//
+// Parameters:
+//   - previous: Hook already configured, or nil
+//
+// Returns:
+//   - pgconn.AfterConnectFunc: Hook setting the timeouts
+func (s SessionSettings) afterConnect(previous pgconn.AfterConnectFunc) pgconn.AfterConnectFunc {
+	return func(ctx context.Context, conn *pgconn.PgConn) error {
+		if previous != nil {
+			if err := previous(ctx, conn); err != nil {
+				return err
+			}
+		}
+		return s.apply(ctx, conn)
+	}
+}
@@ -0,0 +1,135 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+)
+
+// TableSize is the size on disk of a table and of its indexes.
+type TableSize struct {
+	Bytes   int64            `json:"bytes"`             // Total size of the table, with its indexes and TOAST data (pg_total_relation_size)
+	Bloat   int64            `json:"bloat,omitempty"`   // Estimated size of the dead rows and free space of the table itself; zero if none or unknown
+	Indexes map[string]int64 `json:"indexes,omitempty"` // Size of each index of the table, keyed by name
+}
+
+// readSizes reads the size of the tables of a schema and of their indexes, and estimates the
+// bloat of the tables: the pages they take beyond those their live rows need at their average
+// width (from pg_stats) and fill factor. Tables never analyzed, or whose statistics the role may
+// not read, have no bloat estimate. Partitioned parents hold no data, so their partitions are
+// sized instead.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - cat: Catalog queries of the database's dialect
+//   - s: Schema whose tables have been listed
+//   - retry: Retries of the queries failing with transient errors
+//
+// Returns:
+//   - map[string]TableSize: Sizes keyed by table name
+//   - error: Any error that occurred during the queries
+func readSizes(ctx context.Context, conn Querier, cat catalog, s *Schema, retry RetryPolicy) (map[string]TableSize, error) {
+	if !cat.sizes {
+		return nil, fmt.Errorf("sizes are only read from PostgreSQL and Aurora")
+	}
+
+	var sizes map[string]TableSize
+	err := retry.Do(ctx, func() error {
+		sizes = make(map[string]TableSize)
+		err := forEachRow(ctx, conn, tableSizesQuery, s.Name, func(rows scanner) error {
+			var name string
+			var size TableSize
+			var bloat *int64
+			if err := rows.Scan(&name, &size.Bytes, &bloat); err != nil {
+				return err
+			}
+			if _, ok := s.Tables[name]; ok {
+				if bloat != nil {
+					size.Bloat = *bloat
+				}
+				sizes[name] = size
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return forEachRow(ctx, conn, indexSizesQuery, s.Name, func(rows scanner) error {
+			var table, index string
+			var bytes int64
+			if err := rows.Scan(&table, &index, &bytes); err != nil {
+				return err
+			}
+			if size, ok := sizes[table]; ok {
+				if size.Indexes == nil {
+					size.Indexes = make(map[string]int64)
+				}
+				size.Indexes[index] = bytes
+				sizes[table] = size
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading table sizes: %w", err)
+	}
+	return sizes, nil
+}
+
+// Catalog queries of sizes. Each takes the schema name as $1.
+const (
+	// Total size and estimated bloat of tables: the bytes of the heap beyond the pages that
+	// reltuples rows of the average width need, with 24 bytes of header per row and page
+	tableSizesQuery = `
+	SELECT
+		c.relname,
+		pg_total_relation_size(c.oid),
+		CASE
+			WHEN c.reltuples > 0 AND w.width IS NOT NULL THEN
+				greatest(c.relpages - ceil(c.reltuples * (w.width + 24) /
+					((current_setting('block_size')::int - 24) * coalesce(f.fillfactor, 100) / 100.0)), 0)::bigint
+					* current_setting('block_size')::bigint
+		END
+	FROM pg_class c
+	JOIN pg_namespace n
+		ON n.oid = c.relnamespace
+	LEFT JOIN LATERAL (
+		SELECT sum(s.avg_width) AS width
+		FROM pg_stats s
+		WHERE s.schemaname = n.nspname
+			AND s.tablename = c.relname
+	) w ON true
+	LEFT JOIN LATERAL (
+		SELECT substring(o FROM 'fillfactor=([0-9]+)')::int AS fillfactor
+		FROM unnest(c.reloptions) o
+		WHERE o LIKE 'fillfactor=%'
+	) f ON true
+	WHERE n.nspname = $1
+		AND c.relkind IN ('r', 'm')
+`
+
+	indexSizesQuery = `
+	SELECT t.relname, i.relname, pg_relation_size(i.oid)
+	FROM pg_index x
+	JOIN pg_class i
+		ON i.oid = x.indexrelid
+	JOIN pg_class t
+		ON t.oid = x.indrelid
+	JOIN pg_namespace n
+		ON n.oid = t.relnamespace
+	WHERE n.nspname = $1
+		AND i.relkind = 'i'
+`
+)
+
+// clone returns a deep copy of the size.
+func (s TableSize) clone() TableSize {
+	if s.Indexes != nil {
+		indexes := make(map[string]int64, len(s.Indexes))
+		for name, bytes := range s.Indexes {
+			indexes[name] = bytes
+		}
+		s.Indexes = indexes
+	}
+	return s
+}
@@ -0,0 +1,129 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// stagedQueries are the catalog queries of table details that can be staged, with the name of
+// their temporary table and the number of columns they return.
+var stagedQueries = []struct {
+	table   string                     // Temporary table the query is staged in
+	columns int                        // Number of columns the query returns
+	query   func(cat *catalog) *string // Query of a catalog, replaced by the staged one
+}{
+	{"schema_check_columns", 9, func(cat *catalog) *string { return &cat.columnsQuery }},
+	{"schema_check_primary_keys", 3, func(cat *catalog) *string { return &cat.primaryKeysQuery }},
+	{"schema_check_indexes", 9, func(cat *catalog) *string { return &cat.indexesQuery }},
+	{"schema_check_foreign_keys", 6, func(cat *catalog) *string { return &cat.foreignKeysQuery }},
+	{"schema_check_triggers", 8, func(cat *catalog) *string { return &cat.triggersQuery }},
+}
+
+// stage holds the results of the catalog queries of table details for every table of a schema,
+// run once into temporary tables, so that reading the details of each batch of tables does not
+// plan and run the catalog joins again. The temporary tables live in a transaction, dropped
+// when the stage is closed.
+type stage struct {
+	tx  pgx.Tx  // Transaction holding the temporary tables
+	cat catalog // Catalog whose queries of table details read the temporary tables
+}
+
+// newStage runs the catalog queries of table details for every table of a schema into temporary
+// tables. Staging is an optimization only: servers that cannot create temporary tables, such as
+// read-only replicas, and dialects without them are read without staging.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - cat: Catalog queries of the database's dialect
+//   - schemaName: Name of the PostgreSQL schema whose tables are staged
+//
+// Returns:
+//   - *stage: Staged queries, or nil if they could not be staged
+//   - error: The context's error if it was cancelled while staging
+func newStage(ctx context.Context, conn Querier, cat catalog, schemaName string) (*stage, error) {
+	if !cat.staging {
+		return nil, nil
+	}
+	tx, err := beginTx(ctx, conn)
+	if err != nil || tx == nil {
+		return nil, ctx.Err()
+	}
+
+	s := &stage{tx: tx, cat: cat}
+	for _, staged := range stagedQueries {
+		query := staged.query(&s.cat)
+		if *query == "" {
+			continue
+		}
+		if err := s.create(ctx, staged.table, staged.columns, *query, schemaName); err != nil {
+			s.close()
+			return nil, ctx.Err()
+		}
+		*query = stagedQuery(staged.table, staged.columns)
+	}
+	return s, nil
+}
+
+// create runs a catalog query for every table of a schema into a temporary table, indexed by
+// table name. Rows keep the order of the query in their position column.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - table: Name of the temporary table
+//   - columns: Number of columns the query returns
+//   - query: Catalog query to stage
+//   - schemaName: Name of the PostgreSQL schema whose tables are staged
+//
+// Returns:
+//   - error: Any error that occurred while creating the table
+func (s *stage) create(ctx context.Context, table string, columns int, query, schemaName string) error {
+	create := fmt.Sprintf(`CREATE TEMPORARY TABLE %s ON COMMIT DROP AS
+	SELECT $1::text AS schema_name, row_number() OVER () AS position, q.*
+	FROM (%s) AS q(%s)`, table, query, stagedColumns(columns))
+	if _, err := s.tx.Exec(ctx, create, schemaName, nil); err != nil {
+		return fmt.Errorf("error staging %s: %w", table, err)
+	}
+	if _, err := s.tx.Exec(ctx, fmt.Sprintf("CREATE INDEX ON %s (table_name)", table)); err != nil {
+		return fmt.Errorf("error indexing %s: %w", table, err)
+	}
+	return nil
+}
+
+// stagedQuery returns the query reading the details of tables from a temporary table, taking
+// the same arguments as the catalog query it replaces.
+//
+// Parameters:
+//   - table: Name of the temporary table
+//   - columns: Number of columns the catalog query returns
+//
+// Returns:
+//   - string: Query reading the temporary table
+func stagedQuery(table string, columns int) string {
+	return fmt.Sprintf(`
+	SELECT %s
+	FROM %s
+	WHERE schema_name = $1
+		AND ($2::text[] IS NULL OR table_name = ANY($2))
+	ORDER BY position
+`, stagedColumns(columns), table)
+}
+
+// stagedColumns returns the names of the columns of a staged query: table_name for the name of
+// the table, which every catalog query of table details returns first, and c2, c3, and so on.
+func stagedColumns(columns int) string {
+	names := []string{"table_name"}
+	for i := 2; i <= columns; i++ {
+		names = append(names, fmt.Sprintf("c%d", i))
+	}
+	return strings.Join(names, ", ")
+}
+
+// close drops the temporary tables by ending their transaction, whatever the context of the
+// fetch.
+func (s *stage) close() {
+	s.tx.Rollback(context.Background())
+}
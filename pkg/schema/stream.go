@@ -0,0 +1,261 @@
+package schema
+
+import (
+	"context"
+	"sort"
+)
+
+// TableStream reads the tables of a schema one at a time, in name order, so that schemas with
+// too many tables to hold in memory at once can be compared table by table (see
+// compare.CompareTableStreams).
+type TableStream interface {
+	// Outline returns the schema with the table-level properties of every table (comment, owner,
+	// partitioning), but not necessarily their columns, keys, and indexes. Tables found so far
+	// to be impossible to fetch are recorded in its Errors.
+	Outline() *Schema
+
+	// Next returns the next table with all its details, in the byte order of table names.
+	// Tables that cannot be fetched are skipped and recorded in Outline().Errors. It returns
+	// false once every table has been returned.
+	Next(ctx context.Context) (TableInfo, bool, error)
+}
+
+// DefaultBatchSize is the number of tables whose details a PgxTableStream reads at once when no
+// batch size is given.
+const DefaultBatchSize = 1000
+
+// schemaStream is a TableStream over a schema held in memory.
+type schemaStream struct {
+	schema *Schema  // Schema whose tables are streamed
+	names  []string // Names of the tables, sorted
+	next   int      // Index in names of the next table to return
+}
+
+// NewSchemaStream returns a TableStream over a schema already held in memory, such as one read
+// from a snapshot file.
+//
+// Parameters:
+//   - s: Schema whose tables are streamed
+//
+// Returns:
+//   - TableStream: Stream of the tables of the schema
+func NewSchemaStream(s *Schema) TableStream {
+	names := make([]string, 0, len(s.Tables))
+	for name := range s.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return &schemaStream{schema: s, names: names}
+}
+
+// Collect reads every table of a stream, returning the schema of its outline with the tables
+// read in full, as Fetch would have. It is the opposite of NewSchemaStream.
+//
+// Parameters:
+//   - ctx: Context for the database operations of the stream
+//   - stream: Stream to read
+//
+// Returns:
+//   - *Schema: Schema holding every table of the stream, and the tables it could not fetch in Errors
+//   - error: The error that stopped the stream, if any
+func Collect(ctx context.Context, stream TableStream) (*Schema, error) {
+	outline := stream.Outline()
+	tables := make(map[string]TableInfo, len(outline.Tables))
+	for {
+		table, ok, err := stream.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		tables[table.Name] = table
+	}
+
+	// Errors are only complete once the stream has been read
+	collected := *outline
+	collected.Tables = tables
+	return &collected, nil
+}
+
+// Outline returns the schema itself.
+func (s *schemaStream) Outline() *Schema {
+	return s.schema
+}
+
+// Next returns the next table of the schema.
+func (s *schemaStream) Next(ctx context.Context) (TableInfo, bool, error) {
+	if s.next == len(s.names) {
+		return TableInfo{}, false, nil
+	}
+	table := s.schema.Tables[s.names[s.next]]
+	s.next++
+	return table, true, nil
+}
+
+// PgxTableStream is a TableStream reading the tables of a schema from a live database. The
+// tables are listed up front, and their details are read a batch at a time as the stream is
+// read, so that only the table-level properties of every table and the details of one batch of
+// tables are held in memory.
+type PgxTableStream struct {
+	conn      Querier       // Active PostgreSQL connection or pool
+	cat       catalog       // Catalog queries of the database's dialect
+	opts      FetchOptions  // Options controlling what is fetched
+	ext       extensionInfo // TimescaleDB and Citus metadata of the schema
+	outline   *Schema       // Schema with the table-level properties of its tables
+	listed    []TableInfo   // Tables as listed, sorted by name
+	batchSize int           // Number of tables whose details are read at once
+	batch     []TableInfo   // Tables of the current batch not returned yet, with their details
+	next      int           // Index in listed of the first table of the next batch
+	stage     *stage        // Staged queries of table details with FetchOptions.Stage, until the stream ends
+}
+
+// StreamTables lists the tables of a schema and returns a stream reading their details a batch
+// of tables at a time, with one query per kind of object and batch. Like Fetch, it falls back to
+// reading the tables of a batch one by one if those queries fail. Tables that
+// FetchOptions.Details leaves out are returned with their table-level properties only, and those
+// found in FetchOptions.Reuse with the details found there. Amazon Redshift cannot restrict the queries to a batch, so the
+// details of all its tables are read at once.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection or pool
+//   - opts: Options controlling what is fetched
+//   - batchSize: Number of tables whose details are read at once; zero or negative means DefaultBatchSize
+//
+// Returns:
+//   - *PgxTableStream: Stream of the tables of the schema
+//   - error: Any error that occurred while listing the tables, classified like those of Fetch
+func StreamTables(ctx context.Context, conn Querier, opts FetchOptions, batchSize int) (*PgxTableStream, error) {
+	opts.Retry = opts.Retry.on(conn)
+	outline, cat, tables, ext, err := list(ctx, conn, opts)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	// Tables are returned in byte order whatever the collation the catalog sorted them with
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name < tables[j].Name
+	})
+	for _, table := range tables {
+		outline.Tables[table.Name] = withProperties(TableInfo{Name: table.Name}, table, ext)
+	}
+	if outline.Sequences, err = readSequences(ctx, conn, cat, outline.Name, opts); err != nil {
+		return nil, classifyError(err)
+	}
+	if outline.Functions, err = readFunctions(ctx, conn, cat, outline.Name, opts); err != nil {
+		return nil, classifyError(err)
+	}
+
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	if cat.noArrays {
+		batchSize = max(len(tables), 1)
+	}
+	stream := &PgxTableStream{
+		conn:      conn,
+		cat:       cat,
+		opts:      opts,
+		ext:       ext,
+		outline:   outline,
+		listed:    tables,
+		batchSize: batchSize,
+	}
+
+	// Staging only pays off when the details are read in several batches
+	if opts.Stage && len(tables) > batchSize {
+		stream.stage, err = newStage(ctx, conn, cat, outline.Name)
+		if err != nil {
+			return nil, classifyError(err)
+		}
+	}
+	return stream, nil
+}
+
+// Outline returns the schema with the table-level properties of its tables.
+func (s *PgxTableStream) Outline() *Schema {
+	return s.outline
+}
+
+// Next returns the next table, reading the details of the next batch of tables if needed.
+func (s *PgxTableStream) Next(ctx context.Context) (TableInfo, bool, error) {
+	for len(s.batch) == 0 {
+		if s.next == len(s.listed) {
+			s.Close()
+			return TableInfo{}, false, nil
+		}
+		if err := s.readBatch(ctx); err != nil {
+			s.Close()
+			return TableInfo{}, false, classifyError(err)
+		}
+	}
+
+	table := s.batch[0]
+	s.batch = s.batch[1:]
+	return table, true, nil
+}
+
+// readBatch reads the details of the next batch of tables, recording those that cannot be read
+// in the outline's Errors.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//
+// Returns:
+//   - error: The error that stopped the fetch, if it was cancelled or failed with FetchOptions.StopOnError
+func (s *PgxTableStream) readBatch(ctx context.Context) error {
+	end := min(s.next+s.batchSize, len(s.listed))
+	listed := s.listed[s.next:end]
+	var wanted []TableInfo
+	for _, table := range listed {
+		if _, reused := s.opts.Reuse[table.Name]; reused {
+			continue
+		}
+		if s.opts.Details == nil || s.opts.Details(table) {
+			wanted = append(wanted, table)
+		}
+	}
+	conn, cat, opts := s.conn, s.cat, s.opts
+	if s.stage != nil {
+		// The temporary tables are only visible to the transaction holding them
+		conn, cat, opts.Concurrency = s.stage.tx, s.stage.cat, 1
+		opts.Retry = opts.Retry.on(conn)
+	}
+	details, err := readDetails(ctx, conn, cat, s.outline.Name, wanted, len(wanted) == len(s.listed) && !s.opts.filtersTables(), opts)
+	if err != nil {
+		return err
+	}
+	s.next = end
+
+	s.batch = make([]TableInfo, 0, len(listed))
+	fetched := 0
+	for _, table := range listed {
+		info, reused := s.opts.Reuse[table.Name]
+		if !reused {
+			info = TableInfo{Name: table.Name}
+		}
+		if fetched < len(wanted) && wanted[fetched].Name == table.Name {
+			result := details[fetched]
+			fetched++
+			if result.err != nil {
+				s.outline.Errors = append(s.outline.Errors, FetchError{Table: table.Name, Message: result.err.Error()})
+				continue
+			}
+			info = result.info
+		}
+		s.batch = append(s.batch, withProperties(info, table, s.ext))
+	}
+	return nil
+}
+
+// Close releases the connection holding the staged queries of FetchOptions.Stage, if any. It is
+// called once the stream has been read or has failed, and only needs to be called when a stream
+// is abandoned midway. Close always returns nil.
+func (s *PgxTableStream) Close() error {
+	if s.stage != nil {
+		s.stage.close()
+		s.stage = nil
+	}
+	return nil
+}
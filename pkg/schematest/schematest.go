@@ -0,0 +1,295 @@
+// Package schematest provides helpers for schema-contract tests written with the Go testing
+// package: it starts throwaway PostgreSQL instances in containers (with testcontainers, so
+// Docker or a compatible runtime must be available), loads DDL fixtures into fresh databases,
+// and asserts that two schemas match with the comparison engine of schema-check.
+//
+// A typical test applies the migrations of an application to one database, and checks the result
+// against the expected schema, given as DDL or as a snapshot file:
+//
+//	func TestMigrations(t *testing.T) {
+//		pg := schematest.Start(t, schematest.Options{})
+//		migrated := pg.Database(t)
+//		migrated.LoadFiles(t, "migrations/001_init.sql", "migrations/002_orders.sql")
+//		expected := pg.Database(t, schematest.ReadFixture(t, "testdata/schema.sql"))
+//		schematest.RequireMatches(t, expected.Schema(t), migrated.Schema(t))
+//	}
+//
+// An instance is terminated when the test that started it ends. Starting one takes a few
+// seconds, so tests sharing an instance should create a Database each rather than start their own.
+package schematest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/report"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+	"github.com/guriandoro/pg_schema_check/pkg/snapshot"
+	"github.com/jackc/pgx/v5"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// DefaultImage is the container image started when Options does not name one.
+const DefaultImage = "postgres:16-alpine"
+
+// startupTimeout bounds how long Start waits for the server to accept connections.
+const startupTimeout = 2 * time.Minute
+
+// Options controls the instances started by Start. The zero value is ready to use.
+type Options struct {
+	Image             string   // Container image of PostgreSQL, such as postgres:13 to test against an older release; empty uses DefaultImage
+	Fixtures          []string // Paths of SQL files run in the default database once the server is up, in order
+	SkipWithoutDocker bool     // Whether to skip the test, instead of failing it, when the container cannot be started
+}
+
+// Instance is a throwaway PostgreSQL server running in a container.
+type Instance struct {
+	ConnString string // Connection string of the default database (postgres) as the superuser
+}
+
+// databases numbers the databases created by Instance.Database, so that their names are unique.
+var databases atomic.Int64
+
+// Start starts a PostgreSQL server in a container, and terminates it when the test ends. The
+// test fails (or is skipped, with Options.SkipWithoutDocker) if the server cannot be started.
+//
+// Parameters:
+//   - t: Test the server is started for
+//   - opts: Image and fixtures of the server
+//
+// Returns:
+//   - *Instance: Running server
+func Start(t testing.TB, opts Options) *Instance {
+	t.Helper()
+	ctx := context.Background()
+
+	image := opts.Image
+	if image == "" {
+		image = DefaultImage
+	}
+	container, err := postgres.RunContainer(ctx,
+		testcontainers.WithImage(image),
+		postgres.WithDatabase("postgres"),
+		postgres.WithUsername("postgres"),
+		postgres.WithPassword("postgres"),
+		// The server restarts once its initialization scripts have run
+		testcontainers.WithWaitStrategy(wait.ForLog("database system is ready to accept connections").
+			WithOccurrence(2).WithStartupTimeout(startupTimeout)),
+	)
+	if err != nil {
+		if opts.SkipWithoutDocker {
+			t.Skipf("schematest: cannot start PostgreSQL container %s: %v", image, err)
+		}
+		t.Fatalf("schematest: error starting PostgreSQL container %s: %v", image, err)
+	}
+	t.Cleanup(func() {
+		if err := container.Terminate(context.Background()); err != nil {
+			t.Logf("schematest: error terminating PostgreSQL container: %v", err)
+		}
+	})
+
+	connString, err := container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("schematest: error getting connection string: %v", err)
+	}
+	db := &Database{Name: "postgres", ConnString: connString}
+	db.LoadFiles(t, opts.Fixtures...)
+	return &Instance{ConnString: connString}
+}
+
+// Database creates a new, empty database on the server and runs DDL in it.
+//
+// Parameters:
+//   - t: Test the database is created for
+//   - ddl: SQL scripts run in the database, in order; each can hold several statements
+//
+// Returns:
+//   - *Database: Created database
+func (i *Instance) Database(t testing.TB, ddl ...string) *Database {
+	t.Helper()
+	name := fmt.Sprintf("schematest_%d", databases.Add(1))
+	withConn(t, i.ConnString, func(ctx context.Context, conn *pgx.Conn) error {
+		_, err := conn.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{name}.Sanitize())
+		return err
+	})
+
+	connURL, err := url.Parse(i.ConnString)
+	if err != nil {
+		t.Fatalf("schematest: error parsing connection string: %v", err)
+	}
+	connURL.Path = "/" + name
+	db := &Database{Name: name, ConnString: connURL.String()}
+	db.Exec(t, ddl...)
+	return db
+}
+
+// Database is a database of a throwaway server.
+type Database struct {
+	Name       string // Name of the database
+	ConnString string // Connection string of the database as the superuser
+}
+
+// Exec runs SQL scripts in the database, failing the test if one of them fails.
+//
+// Parameters:
+//   - t: Test the scripts are run for
+//   - scripts: SQL scripts to run, in order; each can hold several statements
+func (d *Database) Exec(t testing.TB, scripts ...string) {
+	t.Helper()
+	if len(scripts) == 0 {
+		return
+	}
+	withConn(t, d.ConnString, func(ctx context.Context, conn *pgx.Conn) error {
+		for n, script := range scripts {
+			// Statements without arguments are sent with the simple protocol, which runs
+			// several statements at once
+			if _, err := conn.Exec(ctx, script); err != nil {
+				return fmt.Errorf("error running script %d: %w", n+1, err)
+			}
+		}
+		return nil
+	})
+}
+
+// LoadFiles runs SQL files in the database, such as migrations, failing the test if one of them
+// cannot be read or fails.
+//
+// Parameters:
+//   - t: Test the files are run for
+//   - paths: Paths of the SQL files, run in order
+func (d *Database) LoadFiles(t testing.TB, paths ...string) {
+	t.Helper()
+	for _, path := range paths {
+		d.Exec(t, ReadFixture(t, path))
+	}
+}
+
+// Schema fetches the schema of the database.
+//
+// Parameters:
+//   - t: Test the schema is fetched for
+//   - opts: Options controlling what is fetched, such as FetchOptions.SchemaName; none fetches public
+//
+// Returns:
+//   - *schema.Schema: Fetched schema
+func (d *Database) Schema(t testing.TB, opts ...schema.FetchOptions) *schema.Schema {
+	t.Helper()
+	var options schema.FetchOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	options.StopOnError = true
+
+	var fetched *schema.Schema
+	withConn(t, d.ConnString, func(ctx context.Context, conn *pgx.Conn) error {
+		var err error
+		fetched, err = schema.Fetch(ctx, conn, options)
+		return err
+	})
+	return fetched
+}
+
+// ReadFixture reads a SQL file, failing the test if it cannot be read.
+//
+// Parameters:
+//   - t: Test the file is read for
+//   - path: Path of the file
+//
+// Returns:
+//   - string: Content of the file
+func ReadFixture(t testing.TB, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("schematest: error reading fixture: %v", err)
+	}
+	return string(data)
+}
+
+// LoadSnapshot reads a schema from a snapshot file, such as one saved with schema-check snapshot
+// from a reference database, failing the test if it cannot be read.
+//
+// Parameters:
+//   - t: Test the snapshot is read for
+//   - path: Path of the snapshot file
+//
+// Returns:
+//   - *schema.Schema: Schema of the snapshot
+func LoadSnapshot(t testing.TB, path string) *schema.Schema {
+	t.Helper()
+	snap, err := snapshot.ReadFile(path)
+	if err != nil {
+		t.Fatalf("schematest: %v", err)
+	}
+	return snap.Schema
+}
+
+// AssertMatches compares two schemas, and marks the test as failed, reporting the differences,
+// if they do not match. Differences of every severity count; use compare.WithSeverityMap to
+// ignore some types.
+//
+// Parameters:
+//   - t: Test the schemas are compared for
+//   - want: Expected schema, compared as the source
+//   - got: Actual schema, compared as the target
+//   - opts: Options of the comparison
+//
+// Returns:
+//   - bool: True if the schemas match
+func AssertMatches(t testing.TB, want, got *schema.Schema, opts ...compare.Option) bool {
+	t.Helper()
+	differences := compare.CompareSchemas(want, got, opts...)
+	if len(differences) == 0 {
+		return true
+	}
+	var text bytes.Buffer
+	if err := differences.Render(&text, report.Text{}); err != nil {
+		t.Errorf("schematest: schemas differ (%d differences), and the differences could not be rendered: %v", len(differences), err)
+		return false
+	}
+	t.Errorf("schematest: schemas differ (%d differences):\n%s", len(differences), text.String())
+	return false
+}
+
+// RequireMatches is AssertMatches, ending the test at once if the schemas do not match.
+//
+// Parameters:
+//   - t: Test the schemas are compared for
+//   - want: Expected schema, compared as the source
+//   - got: Actual schema, compared as the target
+//   - opts: Options of the comparison
+func RequireMatches(t testing.TB, want, got *schema.Schema, opts ...compare.Option) {
+	t.Helper()
+	if !AssertMatches(t, want, got, opts...) {
+		t.FailNow()
+	}
+}
+
+// withConn connects to a database for the duration of a function, failing the test if the
+// connection or the function fails.
+//
+// Parameters:
+//   - t: Test the connection is opened for
+//   - connString: Connection string of the database
+//   - fn: Function run with the connection
+func withConn(t testing.TB, connString string, fn func(ctx context.Context, conn *pgx.Conn) error) {
+	t.Helper()
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, connString)
+	if err != nil {
+		t.Fatalf("schematest: error connecting to database: %v", err)
+	}
+	defer conn.Close(ctx)
+	if err := fn(ctx, conn); err != nil {
+		t.Fatalf("schematest: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package schematest_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/patch"
+	"github.com/guriandoro/pg_schema_check/pkg/schematest"
+	"github.com/jackc/pgx/v5"
+)
+
+// shopDDL creates the schema both sides of the tests start from.
+const shopDDL = `
+CREATE TABLE customers (
+	id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	email text NOT NULL UNIQUE
+);
+CREATE TABLE orders (
+	id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	customer_id bigint NOT NULL REFERENCES customers (id),
+	status text NOT NULL DEFAULT 'new',
+	total numeric(12, 2)
+);
+CREATE INDEX idx_orders_customer ON orders (customer_id);
+CREATE VIEW open_orders AS SELECT id, customer_id FROM orders WHERE status = 'new';
+CREATE FUNCTION order_count(customer bigint) RETURNS bigint LANGUAGE sql STABLE
+	AS $$ SELECT count(*) FROM orders WHERE customer_id = customer $$;
+`
+
+// start starts a server for a test, skipping the test in short mode or without Docker.
+func start(t *testing.T) *schematest.Instance {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("schematest: starting PostgreSQL containers is skipped in short mode")
+	}
+	return schematest.Start(t, schematest.Options{SkipWithoutDocker: true})
+}
+
+func TestCompareFetchedSchemas(t *testing.T) {
+	pg := start(t)
+	expected := pg.Database(t, shopDDL)
+
+	t.Run("same DDL written differently", func(t *testing.T) {
+		reformatted := pg.Database(t, `
+			CREATE TABLE customers (id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY, email text NOT NULL UNIQUE);
+			CREATE TABLE orders (
+				id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+				customer_id bigint NOT NULL REFERENCES customers (id),
+				status text NOT NULL DEFAULT 'new',
+				total numeric(12, 2)
+			);
+			CREATE INDEX idx_orders_customer ON orders USING btree (customer_id);
+			CREATE VIEW open_orders AS
+				SELECT id, customer_id
+				FROM orders
+				WHERE (status = 'new');
+			CREATE FUNCTION order_count(customer bigint) RETURNS bigint LANGUAGE sql STABLE AS $$
+				-- Orders of a customer
+				select COUNT(*) from orders where (customer_id = customer);
+			$$;
+		`)
+		schematest.AssertMatches(t, expected.Schema(t), reformatted.Schema(t))
+	})
+
+	t.Run("drifted", func(t *testing.T) {
+		drifted := pg.Database(t, shopDDL, `
+			ALTER TABLE orders DROP COLUMN total;
+			ALTER TABLE orders ALTER COLUMN status DROP NOT NULL;
+			DROP INDEX idx_orders_customer;
+			CREATE OR REPLACE VIEW open_orders AS SELECT id, customer_id FROM orders WHERE status IN ('new', 'held');
+			CREATE OR REPLACE FUNCTION order_count(customer bigint) RETURNS bigint LANGUAGE sql STABLE
+				AS $$ SELECT count(*) FROM orders $$;
+			CREATE TABLE notes (id bigint);
+		`)
+
+		var got []string
+		for _, diff := range compare.CompareSchemas(expected.Schema(t), drifted.Schema(t)) {
+			got = append(got, diff.Type)
+		}
+		want := []string{
+			"ExtraTable", "ColumnNullableMismatch", "MissingColumn", "MissingIndex", "ViewDefinitionMismatch",
+			"FunctionDefinitionMismatch",
+		}
+		if !sameTypes(got, want) {
+			t.Errorf("difference types = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestPatchReconcilesSchemas(t *testing.T) {
+	pg := start(t)
+	source := pg.Database(t, shopDDL, `
+		ALTER TABLE orders ADD COLUMN shipped_at timestamptz;
+		CREATE INDEX idx_orders_status ON orders (status) WHERE status <> 'done';
+		CREATE TABLE refunds (
+			id bigint PRIMARY KEY,
+			order_id bigint NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+			amount numeric(12, 2) NOT NULL
+		);
+		CREATE OR REPLACE VIEW open_orders AS SELECT id, customer_id, shipped_at FROM orders WHERE status = 'new';
+	`)
+	target := pg.Database(t, shopDDL, `
+		ALTER TABLE orders ALTER COLUMN total TYPE numeric(10, 2);
+		ALTER TABLE orders ALTER COLUMN status DROP DEFAULT;
+		CREATE TABLE legacy_orders (id bigint);
+	`)
+
+	sourceSchema, targetSchema := source.Schema(t), target.Schema(t)
+	differences := compare.CompareSchemas(sourceSchema, targetSchema)
+	p := patch.FromDifferences(differences, sourceSchema, targetSchema, compare.DirectionSourceToTarget)
+	if len(p.Unsupported) > 0 {
+		t.Fatalf("patch left %d differences for review: %v", len(p.Unsupported), p.Unsupported)
+	}
+	statements, err := p.Statements()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, target.ConnString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close(ctx)
+	for _, statement := range statements {
+		if _, err := conn.Exec(ctx, statement); err != nil {
+			t.Fatalf("error applying %q: %v", statement, err)
+		}
+	}
+
+	schematest.AssertMatches(t, sourceSchema, target.Schema(t))
+}
+
+// sameTypes reports whether two lists of difference types hold the same types, in any order.
+func sameTypes(got, want []string) bool {
+	count := func(types []string) map[string]int {
+		counts := make(map[string]int)
+		for _, diffType := range types {
+			counts[diffType]++
+		}
+		return counts
+	}
+	return reflect.DeepEqual(count(got), count(want))
+}
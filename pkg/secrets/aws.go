@@ -0,0 +1,98 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// awsClients holds a Secrets Manager client per region, as loading the AWS configuration can
+// take requests to the instance metadata service.
+var awsClients = struct {
+	sync.Mutex
+	byRegion map[string]*secretsmanager.Client
+}{byRegion: make(map[string]*secretsmanager.Client)}
+
+// readAWS reads a secret from AWS Secrets Manager: its string value, or its binary value if it
+// has no string value.
+//
+// Parameters:
+//   - ctx: Context for the request
+//   - id: ARN or name of the secret; secrets named by ARN are read in the region of the ARN,
+//     and secrets named by name in the region of the AWS configuration (such as AWS_REGION)
+//
+// Returns:
+//   - string: Value of the secret
+//   - error: Any error that occurred while loading the AWS configuration or reading the secret
+func readAWS(ctx context.Context, id string) (string, error) {
+	region := ""
+	if strings.HasPrefix(id, "arn:") {
+		var err error
+		if region, err = awsRegion(id); err != nil {
+			return "", err
+		}
+	}
+	client, err := awsClient(ctx, region)
+	if err != nil {
+		return "", err
+	}
+	output, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+	if err != nil {
+		return "", fmt.Errorf("error reading secret %s from AWS Secrets Manager: %w", id, err)
+	}
+	if output.SecretString != nil {
+		return *output.SecretString, nil
+	}
+	return string(output.SecretBinary), nil
+}
+
+// awsClient returns the Secrets Manager client of a region, loading the AWS configuration the
+// first time the region is used.
+//
+// Parameters:
+//   - ctx: Context for loading the configuration
+//   - region: Region of the client; empty for that of the AWS configuration
+//
+// Returns:
+//   - *secretsmanager.Client: Client of the region
+//   - error: Any error that occurred while loading the AWS configuration
+func awsClient(ctx context.Context, region string) (*secretsmanager.Client, error) {
+	awsClients.Lock()
+	defer awsClients.Unlock()
+	if client, ok := awsClients.byRegion[region]; ok {
+		return client, nil
+	}
+
+	var options []func(*config.LoadOptions) error
+	if region != "" {
+		options = append(options, config.WithRegion(region))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("error loading AWS configuration: %w", err)
+	}
+	client := secretsmanager.NewFromConfig(cfg)
+	awsClients.byRegion[region] = client
+	return client, nil
+}
+
+// awsRegion returns the region of the ARN of a secret.
+//
+// Parameters:
+//   - arn: ARN of the secret (arn:PARTITION:secretsmanager:REGION:ACCOUNT:secret:NAME)
+//
+// Returns:
+//   - string: Region of the secret
+//   - error: An error if the ARN is not that of a Secrets Manager secret
+func awsRegion(arn string) (string, error) {
+	parts := strings.SplitN(arn, ":", 7)
+	if len(parts) != 7 || parts[2] != "secretsmanager" || parts[3] == "" || parts[5] != "secret" || parts[6] == "" {
+		return "", fmt.Errorf("invalid secret ARN %q: expected arn:aws:secretsmanager:REGION:ACCOUNT:secret:NAME", arn)
+	}
+	return parts[3], nil
+}
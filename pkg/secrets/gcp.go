@@ -0,0 +1,104 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sync"
+
+	"golang.org/x/oauth2/google"
+)
+
+// gcpEndpoint is the base URL of the GCP Secret Manager API.
+const gcpEndpoint = "https://secretmanager.googleapis.com/v1/"
+
+// gcpSecretName matches the resource names of GCP secrets and of their versions.
+var gcpSecretName = regexp.MustCompile(`^projects/[^/]+/secrets/[^/]+(/versions/[^/]+)?$`)
+
+// gcpClient is the HTTP client authenticated with the application default credentials, created
+// the first time a GCP secret is read.
+var gcpClient struct {
+	once   sync.Once
+	client *http.Client
+	err    error
+}
+
+// readGCP reads a version of a secret from GCP Secret Manager.
+//
+// Parameters:
+//   - ctx: Context for the request
+//   - name: Resource name of the secret (projects/PROJECT/secrets/SECRET), for its latest
+//     version, or of a version (projects/PROJECT/secrets/SECRET/versions/VERSION)
+//
+// Returns:
+//   - string: Value of the secret
+//   - error: Any error that occurred while finding credentials or reading the secret
+func readGCP(ctx context.Context, name string) (string, error) {
+	version, err := gcpVersionName(name)
+	if err != nil {
+		return "", err
+	}
+	gcpClient.once.Do(func() {
+		gcpClient.client, gcpClient.err = google.DefaultClient(context.Background(), "https://www.googleapis.com/auth/cloud-platform")
+	})
+	if gcpClient.err != nil {
+		return "", fmt.Errorf("error finding GCP credentials: %w", gcpClient.err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpEndpoint+version+":access", nil)
+	if err != nil {
+		return "", fmt.Errorf("error reading secret %s from GCP Secret Manager: %w", name, err)
+	}
+	response, err := gcpClient.client.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("error reading secret %s from GCP Secret Manager: %w", name, err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("error reading secret %s from GCP Secret Manager: %w", name, err)
+	}
+
+	var result struct {
+		Payload struct {
+			Data string `json:"data"` // Value of the secret, base64-encoded
+		} `json:"payload"`
+		Error struct {
+			Message string `json:"message"` // Description of the error
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("error reading secret %s from GCP Secret Manager: unexpected response (status %s)", name, response.Status)
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error reading secret %s from GCP Secret Manager: %s: %s", name, response.Status, result.Error.Message)
+	}
+	value, err := base64.StdEncoding.DecodeString(result.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("error decoding secret %s from GCP Secret Manager: %w", name, err)
+	}
+	return string(value), nil
+}
+
+// gcpVersionName returns the resource name of the version of a GCP secret to read.
+//
+// Parameters:
+//   - name: Resource name of the secret or of one of its versions
+//
+// Returns:
+//   - string: Resource name of the version: the one given, or the latest
+//   - error: An error if the name is not that of a secret or version
+func gcpVersionName(name string) (string, error) {
+	match := gcpSecretName.FindStringSubmatch(name)
+	if match == nil {
+		return "", fmt.Errorf("invalid secret name %q: expected projects/PROJECT/secrets/SECRET[/versions/VERSION]", name)
+	}
+	if match[1] == "" {
+		return name + "/versions/latest", nil
+	}
+	return name, nil
+}
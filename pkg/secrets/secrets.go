@@ -0,0 +1,137 @@
+// Package secrets provides functionality to read credentials from cloud secret managers, AWS
+// Secrets Manager and GCP Secret Manager, so that configuration files can refer to secrets by
+// their ARN or resource name instead of holding them.
+//
+// Credentials to access the secret managers are found the way their own tools find them: the
+// environment, shared configuration files, and the instance metadata of EC2, ECS, and EKS (IAM
+// roles for service accounts) on AWS; application default credentials, including GKE workload
+// identity, on GCP.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Kinds of secret manager.
+const (
+	KindAWS = "aws" // AWS Secrets Manager, by secret ARN or name
+	KindGCP = "gcp" // GCP Secret Manager, by resource name
+)
+
+// Kinds lists the kinds of secret manager.
+var Kinds = []string{KindAWS, KindGCP}
+
+// IsKnownKind reports whether a name is one of the kinds of secret manager.
+//
+// Parameters:
+//   - kind: Name to check
+//
+// Returns:
+//   - bool: True if the name is a kind of secret manager
+func IsKnownKind(kind string) bool {
+	for _, known := range Kinds {
+		if known == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Read reads a secret from a secret manager. The reference is the ARN or name of the secret
+// for AWS (arn:aws:secretsmanager:us-east-1:123456789012:secret:prod/db-AbCdEf or prod/db), and
+// its resource name for GCP (projects/my-project/secrets/db-password, optionally followed by
+// /versions/N; the latest version otherwise). Either can end with #FIELD to read a field of a
+// secret holding a JSON object, as AWS stores database credentials (#password).
+//
+// Parameters:
+//   - ctx: Context for the request to the secret manager
+//   - kind: Kind of secret manager: aws or gcp
+//   - ref: Reference to the secret, with an optional #FIELD
+//
+// Returns:
+//   - string: Value of the secret, or of its field
+//   - error: An error if the secret cannot be read or has no such field
+func Read(ctx context.Context, kind, ref string) (string, error) {
+	if err := Check(kind, ref); err != nil {
+		return "", err
+	}
+	id, field, _ := strings.Cut(ref, "#")
+
+	var value string
+	var err error
+	switch kind {
+	case KindAWS:
+		value, err = readAWS(ctx, id)
+	case KindGCP:
+		value, err = readGCP(ctx, id)
+	}
+	if err != nil {
+		return "", err
+	}
+	if field == "" {
+		return value, nil
+	}
+	return jsonField(id, value, field)
+}
+
+// Check checks that a reference to a secret is well formed, without reading it.
+//
+// Parameters:
+//   - kind: Kind of secret manager: aws or gcp
+//   - ref: Reference to the secret, with an optional #FIELD
+//
+// Returns:
+//   - error: An error if the kind is unknown or the reference malformed
+func Check(kind, ref string) error {
+	id, field, hasField := strings.Cut(ref, "#")
+	if id == "" {
+		return fmt.Errorf("secret reference ${%s:%s} names no secret", kind, ref)
+	}
+	if hasField && field == "" {
+		return fmt.Errorf("secret reference ${%s:%s} names an empty field", kind, ref)
+	}
+	switch kind {
+	case KindAWS:
+		if strings.HasPrefix(id, "arn:") {
+			if _, err := awsRegion(id); err != nil {
+				return err
+			}
+		}
+	case KindGCP:
+		if _, err := gcpVersionName(id); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown secret manager %q: expected one of %s", kind, strings.Join(Kinds, ", "))
+	}
+	return nil
+}
+
+// jsonField returns a field of a secret holding a JSON object.
+//
+// Parameters:
+//   - id: Name of the secret, for errors
+//   - value: Value of the secret
+//   - field: Name of the field
+//
+// Returns:
+//   - string: Value of the field; numbers and booleans as they are written in the object
+//   - error: An error if the secret is not a JSON object or has no such field
+func jsonField(id, value, field string) (string, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(value), &object); err != nil {
+		return "", fmt.Errorf("error reading field %s of secret %s: secret is not a JSON object", field, id)
+	}
+	raw, ok := object[field]
+	if !ok {
+		return "", fmt.Errorf("error reading field %s of secret %s: no such field", field, id)
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+	return string(raw), nil
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/patch"
+	"github.com/guriandoro/pg_schema_check/pkg/server/schemacheckv1"
+	"github.com/guriandoro/pg_schema_check/pkg/snapshot"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcService implements the SchemaCheck gRPC service on top of the handler of the HTTP service.
+type grpcService struct {
+	schemacheckv1.UnimplementedSchemaCheckServer
+	h *handler // Shared fetching and comparison logic
+}
+
+// NewGRPCService creates the SchemaCheck gRPC service, to be registered on a grpc.Server with
+// schemacheckv1.RegisterSchemaCheckServer. Options.MaxBodyBytes does not apply to it: the size
+// of messages is limited by the grpc.Server instead (see grpc.MaxRecvMsgSize).
+//
+// Parameters:
+//   - opts: Options controlling what requests can do
+//
+// Returns:
+//   - schemacheckv1.SchemaCheckServer: Implementation of the service
+func NewGRPCService(opts Options) schemacheckv1.SchemaCheckServer {
+	return &grpcService{h: &handler{opts: opts}}
+}
+
+// Snapshot fetches the schema of a database and returns it as a snapshot document.
+func (s *grpcService) Snapshot(ctx context.Context, req *schemacheckv1.SnapshotRequest) (*schemacheckv1.SnapshotResponse, error) {
+	ctx, cancel := s.h.withTimeout(ctx)
+	defer cancel()
+
+	fetched, err := s.h.fetch(ctx, "db", sideOf(req.GetDb()), req.GetSchema())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	format := snapshot.FormatJSON
+	if req.GetBinary() {
+		format = snapshot.FormatBinary
+	}
+	var buf bytes.Buffer
+	if err := snapshot.Write(&buf, fetched, format); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &schemacheckv1.SnapshotResponse{Snapshot: buf.Bytes(), TableCount: int32(len(fetched.Tables))}, nil
+}
+
+// Compare returns the differences between two schemas.
+func (s *grpcService) Compare(ctx context.Context, req *schemacheckv1.CompareRequest) (*schemacheckv1.CompareResponse, error) {
+	ctx, cancel := s.h.withTimeout(ctx)
+	defer cancel()
+
+	_, _, differences, err := s.h.run(ctx, compareRequestOf(req))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	summary := make(map[string]int32)
+	for severity, count := range differences.CountBySeverity() {
+		summary[severity] = int32(count)
+	}
+	return &schemacheckv1.CompareResponse{Differences: differencesOf(differences), Summary: summary}, nil
+}
+
+// GenerateMigration returns the SQL statements reconciling two schemas, as written by --sql.
+func (s *grpcService) GenerateMigration(ctx context.Context, req *schemacheckv1.CompareRequest) (*schemacheckv1.GenerateMigrationResponse, error) {
+	ctx, cancel := s.h.withTimeout(ctx)
+	defer cancel()
+
+	source, target, differences, err := s.h.run(ctx, compareRequestOf(req))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	p := patch.FromDifferences(differences, source, target, req.GetDirection())
+	statements, err := p.Statements()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &schemacheckv1.GenerateMigrationResponse{Statements: statements, Unsupported: differencesOf(p.Unsupported)}, nil
+}
+
+// sideOf converts one side of a gRPC request.
+//
+// Parameters:
+//   - side: Side of the gRPC request; nil gives an empty side, which is rejected by fetch
+//
+// Returns:
+//   - Side: Equivalent side of an HTTP request
+func sideOf(side *schemacheckv1.Side) Side {
+	return Side{
+		Database:   side.GetDatabase(),
+		Connection: side.GetConnection(),
+		Snapshot:   json.RawMessage(side.GetSnapshot()),
+	}
+}
+
+// compareRequestOf converts a gRPC comparison request.
+//
+// Parameters:
+//   - req: Request of Compare or GenerateMigration
+//
+// Returns:
+//   - CompareRequest: Equivalent HTTP request
+func compareRequestOf(req *schemacheckv1.CompareRequest) CompareRequest {
+	return CompareRequest{
+		Source:         sideOf(req.GetSource()),
+		Target:         sideOf(req.GetTarget()),
+		SchemaName:     req.GetSchema(),
+		Direction:      req.GetDirection(),
+		CompareOwners:  req.GetCompareOwners(),
+		IgnoreCase:     req.GetIgnoreCase(),
+		IgnoreDefaults: req.GetIgnoreDefaults(),
+		Severity:       req.GetSeverity(),
+	}
+}
+
+// differencesOf converts differences into their gRPC messages.
+//
+// Parameters:
+//   - differences: Differences to convert
+//
+// Returns:
+//   - []*schemacheckv1.Difference: Converted differences, in the same order
+func differencesOf(differences []compare.Difference) []*schemacheckv1.Difference {
+	converted := make([]*schemacheckv1.Difference, 0, len(differences))
+	for _, diff := range differences {
+		converted = append(converted, &schemacheckv1.Difference{
+			Type:        diff.Type,
+			Table:       diff.Table,
+			Description: diff.Description,
+			Severity:    diff.Severity,
+			ObjectKind:  diff.ObjectKind,
+			SchemaName:  diff.SchemaName,
+			ObjectName:  diff.ObjectName,
+			SubObject:   diff.SubObject,
+			SourceValue: diff.SourceValue,
+			TargetValue: diff.TargetValue,
+			Parent:      diff.Parent,
+		})
+	}
+	return converted
+}
+
+// grpcError converts the error of a failed request into a gRPC status, with the code matching
+// the HTTP status the HTTP service would have responded with.
+//
+// Parameters:
+//   - err: Error the request failed with
+//
+// Returns:
+//   - error: gRPC status error
+func grpcError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return status.Error(codes.Canceled, err.Error())
+	}
+
+	code := codes.Unavailable
+	switch statusOf(err) {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusGatewayTimeout:
+		code = codes.DeadlineExceeded
+	}
+	return status.Error(code, err.Error())
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/report"
+	"github.com/guriandoro/pg_schema_check/pkg/results"
+)
+
+// Pair is a configured comparison that requests can list and run by name.
+type Pair struct {
+	Name   string `json:"name"`   // Name of the comparison, such as its environment
+	Source string `json:"source"` // Source of the comparison, with its password masked
+	Target string `json:"target"` // Target of the comparison, with its password masked
+}
+
+// PairRunner runs the comparison of a configured pair on request.
+//
+// Parameters:
+//   - ctx: Context of the request, bounded by Options.Timeout
+//   - pair: Name of the pair
+//
+// Returns:
+//   - compare.DiffResult: Differences found
+//   - error: Any error that occurred while comparing
+type PairRunner func(ctx context.Context, pair string) (compare.DiffResult, error)
+
+// pairStatus is a configured pair with a summary of its latest result, as listed by GET /pairs.
+type pairStatus struct {
+	Pair
+	Latest *results.Entry `json:"latest,omitempty"` // Summary of the latest result, if the pair has one
+}
+
+// defaultHistory is the number of entries returned by GET /pairs/{name}/history without a limit.
+const defaultHistory = 100
+
+// reportTypes are the content types of the reports downloaded in each format, and the extension
+// of their file names.
+var reportTypes = map[string][2]string{
+	"text":               {"text/plain; charset=utf-8", "txt"},
+	"json":               {"application/json", "json"},
+	"html":               {"text/html; charset=utf-8", "html"},
+	"markdown":           {"text/markdown; charset=utf-8", "md"},
+	"gitlab-codequality": {"application/json", "json"},
+}
+
+// listPairs serves GET /pairs, listing the configured pairs and their latest results.
+func (h *handler) listPairs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, &requestError{http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method)})
+		return
+	}
+	statuses := make([]pairStatus, 0, len(h.opts.Pairs))
+	for _, pair := range h.opts.Pairs {
+		statuses = append(statuses, h.status(pair))
+	}
+	writeJSON(w, statuses)
+}
+
+// pair serves the endpoints of a configured pair: GET /pairs/{name}, POST /pairs/{name}/run,
+// GET /pairs/{name}/result, GET /pairs/{name}/report, and GET /pairs/{name}/history.
+func (h *handler) pair(w http.ResponseWriter, r *http.Request) {
+	name, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/pairs/"), "/")
+	pair, ok := h.lookupPair(name)
+	if !ok {
+		writeError(w, &requestError{http.StatusNotFound, fmt.Errorf("unknown pair '%s'", name)})
+		return
+	}
+
+	method := http.MethodGet
+	if action == "run" {
+		method = http.MethodPost
+	}
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, &requestError{http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method)})
+		return
+	}
+
+	switch action {
+	case "":
+		writeJSON(w, h.status(pair))
+	case "run":
+		h.runPair(w, r, pair)
+	case "result":
+		if result, ok := h.latest(w, pair); ok {
+			writeJSON(w, result)
+		}
+	case "report":
+		h.downloadReport(w, r, pair)
+	case "history":
+		h.history(w, r, pair)
+	default:
+		writeError(w, &requestError{http.StatusNotFound, fmt.Errorf("unknown endpoint '%s'", action)})
+	}
+}
+
+// runPair runs the comparison of a pair, records its result, and returns it. A run that fails is
+// recorded too, and reported with the status of its error.
+func (h *handler) runPair(w http.ResponseWriter, r *http.Request, pair Pair) {
+	if h.opts.RunPair == nil {
+		writeError(w, &requestError{http.StatusNotImplemented, fmt.Errorf("pairs are not run on request here")})
+		return
+	}
+
+	ctx, cancel := h.withTimeout(r.Context())
+	defer cancel()
+
+	started := time.Now()
+	differences, err := h.opts.RunPair(ctx, pair.Name)
+	if errors.Is(ctx.Err(), context.Canceled) {
+		// The caller went away; the run is not recorded
+		return
+	}
+	if h.opts.Metrics != nil {
+		h.opts.Metrics.ObserveRun(pair.Name, started, differences, err)
+	}
+	result := results.NewResult(pair.Name, started, differences, err)
+	if saveErr := h.results.Save(result); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, result)
+}
+
+// downloadReport serves the latest result of a pair as a report file, in the format of its
+// format parameter (any format of report.Lookup; html by default).
+func (h *handler) downloadReport(w http.ResponseWriter, r *http.Request, pair Pair) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "html"
+	}
+	renderer, err := report.Lookup(format)
+	if err != nil {
+		writeError(w, &requestError{http.StatusBadRequest, err})
+		return
+	}
+	result, ok := h.latest(w, pair)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := result.Differences.Render(&buf, renderer); err != nil {
+		writeError(w, &requestError{http.StatusInternalServerError, err})
+		return
+	}
+	contentType, extension := "application/octet-stream", format
+	if known, ok := reportTypes[format]; ok {
+		contentType, extension = known[0], known[1]
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s-%s.%s", pair.Name, result.Started.UTC().Format("20060102T150405Z"), extension)))
+	w.Write(buf.Bytes())
+}
+
+// history serves the latest entries of the history of a pair, oldest first, as many as its
+// limit parameter asks for (100 by default).
+func (h *handler) history(w http.ResponseWriter, r *http.Request, pair Pair) {
+	limit := defaultHistory
+	if text := r.URL.Query().Get("limit"); text != "" {
+		var err error
+		if limit, err = strconv.Atoi(text); err != nil || limit < 1 {
+			writeError(w, &requestError{http.StatusBadRequest, fmt.Errorf("invalid limit '%s'", text)})
+			return
+		}
+	}
+	entries, err := h.results.History(pair.Name, limit)
+	if err != nil {
+		writeError(w, &requestError{http.StatusInternalServerError, err})
+		return
+	}
+	if entries == nil {
+		entries = []results.Entry{}
+	}
+	writeJSON(w, entries)
+}
+
+// latest returns the latest result of a pair, responding with 404 Not Found if it has none.
+func (h *handler) latest(w http.ResponseWriter, pair Pair) (results.Result, bool) {
+	result, ok, err := h.results.Latest(pair.Name)
+	if err != nil {
+		writeError(w, &requestError{http.StatusInternalServerError, err})
+		return result, false
+	}
+	if !ok {
+		writeError(w, &requestError{http.StatusNotFound, fmt.Errorf("pair '%s' has not been compared yet", pair.Name)})
+	}
+	return result, ok
+}
+
+// status returns a pair with the summary of its latest result. A result that cannot be read is
+// left out, as if the pair had none.
+func (h *handler) status(pair Pair) pairStatus {
+	status := pairStatus{Pair: pair}
+	if result, ok, err := h.results.Latest(pair.Name); ok && err == nil {
+		entry := result.Entry()
+		status.Latest = &entry
+	}
+	return status
+}
+
+// lookupPair returns the configured pair with a name.
+func (h *handler) lookupPair(name string) (Pair, bool) {
+	for _, pair := range h.opts.Pairs {
+		if pair.Name == name {
+			return pair, true
+		}
+	}
+	return Pair{}, false
+}
+
+// writeJSON responds with a value encoded as JSON.
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
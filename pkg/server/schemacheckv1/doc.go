@@ -0,0 +1,5 @@
+// Package schemacheckv1 holds the protobuf messages and the gRPC service of schema-check,
+// generated from schemacheck.proto. The service is implemented by server.NewGRPCService.
+package schemacheckv1
+
+//go:generate protoc --proto_path=../../.. --go_out=../../.. --go_opt=paths=source_relative --go-grpc_out=../../.. --go-grpc_opt=paths=source_relative pkg/server/schemacheckv1/schemacheck.proto
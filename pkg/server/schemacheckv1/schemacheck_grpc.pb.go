@@ -0,0 +1,183 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: pkg/server/schemacheckv1/schemacheck.proto
+
+package schemacheckv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	SchemaCheck_Snapshot_FullMethodName          = "/schemacheck.v1.SchemaCheck/Snapshot"
+	SchemaCheck_Compare_FullMethodName           = "/schemacheck.v1.SchemaCheck/Compare"
+	SchemaCheck_GenerateMigration_FullMethodName = "/schemacheck.v1.SchemaCheck/GenerateMigration"
+)
+
+// SchemaCheckClient is the client API for SchemaCheck service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SchemaCheckClient interface {
+	Snapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*SnapshotResponse, error)
+	Compare(ctx context.Context, in *CompareRequest, opts ...grpc.CallOption) (*CompareResponse, error)
+	GenerateMigration(ctx context.Context, in *CompareRequest, opts ...grpc.CallOption) (*GenerateMigrationResponse, error)
+}
+
+type schemaCheckClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSchemaCheckClient(cc grpc.ClientConnInterface) SchemaCheckClient {
+	return &schemaCheckClient{cc}
+}
+
+func (c *schemaCheckClient) Snapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*SnapshotResponse, error) {
+	out := new(SnapshotResponse)
+	err := c.cc.Invoke(ctx, SchemaCheck_Snapshot_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *schemaCheckClient) Compare(ctx context.Context, in *CompareRequest, opts ...grpc.CallOption) (*CompareResponse, error) {
+	out := new(CompareResponse)
+	err := c.cc.Invoke(ctx, SchemaCheck_Compare_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *schemaCheckClient) GenerateMigration(ctx context.Context, in *CompareRequest, opts ...grpc.CallOption) (*GenerateMigrationResponse, error) {
+	out := new(GenerateMigrationResponse)
+	err := c.cc.Invoke(ctx, SchemaCheck_GenerateMigration_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SchemaCheckServer is the server API for SchemaCheck service.
+// All implementations must embed UnimplementedSchemaCheckServer
+// for forward compatibility
+type SchemaCheckServer interface {
+	Snapshot(context.Context, *SnapshotRequest) (*SnapshotResponse, error)
+	Compare(context.Context, *CompareRequest) (*CompareResponse, error)
+	GenerateMigration(context.Context, *CompareRequest) (*GenerateMigrationResponse, error)
+	mustEmbedUnimplementedSchemaCheckServer()
+}
+
+// UnimplementedSchemaCheckServer must be embedded to have forward compatible implementations.
+type UnimplementedSchemaCheckServer struct {
+}
+
+func (UnimplementedSchemaCheckServer) Snapshot(context.Context, *SnapshotRequest) (*SnapshotResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Snapshot not implemented")
+}
+func (UnimplementedSchemaCheckServer) Compare(context.Context, *CompareRequest) (*CompareResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Compare not implemented")
+}
+func (UnimplementedSchemaCheckServer) GenerateMigration(context.Context, *CompareRequest) (*GenerateMigrationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GenerateMigration not implemented")
+}
+func (UnimplementedSchemaCheckServer) mustEmbedUnimplementedSchemaCheckServer() {}
+
+// UnsafeSchemaCheckServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SchemaCheckServer will
+// result in compilation errors.
+type UnsafeSchemaCheckServer interface {
+	mustEmbedUnimplementedSchemaCheckServer()
+}
+
+func RegisterSchemaCheckServer(s grpc.ServiceRegistrar, srv SchemaCheckServer) {
+	s.RegisterService(&SchemaCheck_ServiceDesc, srv)
+}
+
+func _SchemaCheck_Snapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SchemaCheckServer).Snapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SchemaCheck_Snapshot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SchemaCheckServer).Snapshot(ctx, req.(*SnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SchemaCheck_Compare_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompareRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SchemaCheckServer).Compare(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SchemaCheck_Compare_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SchemaCheckServer).Compare(ctx, req.(*CompareRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SchemaCheck_GenerateMigration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompareRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SchemaCheckServer).GenerateMigration(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SchemaCheck_GenerateMigration_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SchemaCheckServer).GenerateMigration(ctx, req.(*CompareRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SchemaCheck_ServiceDesc is the grpc.ServiceDesc for SchemaCheck service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SchemaCheck_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "schemacheck.v1.SchemaCheck",
+	HandlerType: (*SchemaCheckServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Snapshot",
+			Handler:    _SchemaCheck_Snapshot_Handler,
+		},
+		{
+			MethodName: "Compare",
+			Handler:    _SchemaCheck_Compare_Handler,
+		},
+		{
+			MethodName: "GenerateMigration",
+			Handler:    _SchemaCheck_GenerateMigration_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "pkg/server/schemacheckv1/schemacheck.proto",
+}
@@ -0,0 +1,394 @@
+// Package server exposes schema comparison as an HTTP and a gRPC service, so that platform teams
+// can embed it in internal developer portals and orchestration systems without wrapping the
+// command-line tool.
+//
+// The handler returned by NewHandler serves POST /compare. Its body is a CompareRequest naming
+// the two schemas to compare, each given as a database or as an inline snapshot, and the
+// response is the JSON report of the differences, as written by schema-check --format json.
+// It can also serve configured pairs of databases, which requests run by name and whose latest
+// results are kept.
+//
+// NewGRPCService implements the SchemaCheck service of schemacheckv1, which also snapshots
+// databases and generates migrations.
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/metrics"
+	"github.com/guriandoro/pg_schema_check/pkg/report"
+	"github.com/guriandoro/pg_schema_check/pkg/results"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+	"github.com/guriandoro/pg_schema_check/pkg/snapshot"
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultMaxBodyBytes is the size limit of request bodies when Options does not set one.
+const DefaultMaxBodyBytes = 32 << 20
+
+// Options controls what requests to the handler can do. The zero value only accepts snapshots.
+type Options struct {
+	Connections            map[string]string      // Databases that requests can refer to by name, as connection strings keyed by name
+	AllowConnectionStrings bool                   // Whether requests can give connection strings of their own, in addition to Connections
+	Timeout                time.Duration          // Time limit of each request, fetching included; zero means no limit
+	MaxBodyBytes           int64                  // Size limit of request bodies; zero means DefaultMaxBodyBytes
+	Retry                  schema.RetryPolicy     // Retries of connections and catalog queries failing with transient errors
+	Session                schema.SessionSettings // Timeouts set on the sessions opened to the databases
+	Metrics                *metrics.Metrics       // Metrics the comparisons are recorded in, served on /metrics by the HTTP handler; nil disables them
+	Pairs                  []Pair                 // Configured comparisons that requests can list and run by name, served under /pairs
+	RunPair                PairRunner             // Runs the comparison of a configured pair on request; nil only serves the results recorded by others
+	Results                *results.Store         // Latest results of the configured pairs; nil keeps them in memory
+}
+
+// Side is one side of a comparison request. Exactly one of its fields must be set.
+type Side struct {
+	Database   string          `json:"database,omitempty"`   // Name of one of Options.Connections
+	Connection string          `json:"connection,omitempty"` // Connection string; only accepted with Options.AllowConnectionStrings
+	Snapshot   json.RawMessage `json:"snapshot,omitempty"`   // Snapshot, as written in JSON by schema-check snapshot (or in binary format, over gRPC)
+}
+
+// CompareRequest is the body of a POST /compare request.
+type CompareRequest struct {
+	Source         Side              `json:"source"`                    // Source schema to compare from
+	Target         Side              `json:"target"`                    // Target schema to compare against
+	SchemaName     string            `json:"schema,omitempty"`          // PostgreSQL schema fetched from databases; empty means public
+	Direction      string            `json:"direction,omitempty"`       // Which side is authoritative (see compare.Options.Direction)
+	CompareOwners  bool              `json:"compare_owners,omitempty"`  // Whether to report tables, views, sequences, and functions owned by different roles
+	IgnoreCase     bool              `json:"ignore_case,omitempty"`     // Whether object names are compared case-insensitively
+	IgnoreDefaults bool              `json:"ignore_defaults,omitempty"` // Whether column default values are left out of the comparison
+	Severity       map[string]string `json:"severity,omitempty"`        // Severity overrides keyed by difference type
+}
+
+// errorResponse is the body of the responses to failed requests.
+type errorResponse struct {
+	Error string `json:"error"` // Why the request failed
+}
+
+// requestError is an error with the HTTP status it should be reported with.
+type requestError struct {
+	status int   // HTTP status of the response
+	err    error // Cause of the failure
+}
+
+// Error returns the message of the underlying error.
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+// handler serves the comparison endpoints.
+type handler struct {
+	opts    Options        // Limits of the requests
+	results *results.Store // Latest results of the configured pairs
+}
+
+// NewHandler creates the HTTP handler of the comparison service. It can be mounted on any
+// path prefix with http.StripPrefix.
+//
+// With Options.Pairs, it also serves the configured pairs: GET /pairs lists them with a summary
+// of their latest result, POST /pairs/{name}/run compares a pair and records the result, GET
+// /pairs/{name}/result returns the latest result, GET /pairs/{name}/report?format=html
+// downloads it as a report in any of the output formats, GET /pairs/{name}/history returns the
+// summaries of the latest runs, and GET / serves a dashboard of the pairs for web browsers.
+//
+// Parameters:
+//   - opts: Options controlling what requests can do
+//
+// Returns:
+//   - http.Handler: Handler serving POST /compare, the pairs, and GET /metrics with Options.Metrics
+func NewHandler(opts Options) http.Handler {
+	mux := http.NewServeMux()
+	h := &handler{opts: opts, results: opts.Results}
+	mux.HandleFunc("/compare", h.compare)
+	if opts.Metrics != nil {
+		mux.Handle("/metrics", opts.Metrics.Handler())
+	}
+	if len(opts.Pairs) > 0 {
+		if h.results == nil {
+			h.results, _ = results.OpenStore("")
+		}
+		mux.HandleFunc("/pairs", h.listPairs)
+		mux.HandleFunc("/pairs/", h.pair)
+		mux.HandleFunc("/", h.dashboard)
+	}
+	return mux
+}
+
+// compare serves POST /compare.
+func (h *handler) compare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, &requestError{http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method)})
+		return
+	}
+
+	maxBytes := h.opts.MaxBodyBytes
+	if maxBytes == 0 {
+		maxBytes = DefaultMaxBodyBytes
+	}
+	var req CompareRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, &requestError{http.StatusBadRequest, fmt.Errorf("error decoding request: %w", err)})
+		return
+	}
+
+	ctx, cancel := h.withTimeout(r.Context())
+	defer cancel()
+
+	_, _, differences, err := h.run(ctx, req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	report.JSON{}.Render(differences, w)
+}
+
+// withTimeout bounds a request by the time limit of the options, if any.
+//
+// Parameters:
+//   - ctx: Context of the request
+//
+// Returns:
+//   - context.Context: Context bounded by Options.Timeout
+//   - context.CancelFunc: Function releasing the resources of the context
+func (h *handler) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if h.opts.Timeout > 0 {
+		return context.WithTimeout(ctx, h.opts.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// run fetches both sides of a request and compares them, recording the comparison in the
+// metrics unless the caller went away.
+//
+// Parameters:
+//   - ctx: Context bounding the request
+//   - req: Comparison to run
+//
+// Returns:
+//   - *schema.Schema: Source schema
+//   - *schema.Schema: Target schema
+//   - compare.DiffResult: Differences found
+//   - error: Any error that occurred, wrapped in a requestError when its status is known
+func (h *handler) run(ctx context.Context, req CompareRequest) (*schema.Schema, *schema.Schema, compare.DiffResult, error) {
+	if !compare.IsValidDirection(req.Direction) {
+		return nil, nil, nil, &requestError{http.StatusBadRequest, fmt.Errorf("unknown direction '%s'", req.Direction)}
+	}
+
+	started := time.Now()
+	pair := pairOf(req)
+	source, target, differences, err := h.compareSides(ctx, pair, req)
+	if h.opts.Metrics != nil && !errors.Is(ctx.Err(), context.Canceled) {
+		h.opts.Metrics.ObserveRun(pair, started, differences, err)
+	}
+	return source, target, differences, err
+}
+
+// compareSides fetches and compares the two sides of a request.
+//
+// Parameters:
+//   - ctx: Context of the request
+//   - pair: Name of the pair of sides, as recorded in the metrics
+//   - req: Request naming the sides
+//
+// Returns:
+//   - *schema.Schema: Source schema
+//   - *schema.Schema: Target schema
+//   - compare.DiffResult: Differences found
+//   - error: Any error that occurred while fetching or comparing the schemas
+func (h *handler) compareSides(ctx context.Context, pair string, req CompareRequest) (*schema.Schema, *schema.Schema, compare.DiffResult, error) {
+	// Both sides are fetched at once; when one fails, the other is cancelled
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var target *schema.Schema
+	var targetErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if target, targetErr = h.timedFetch(fetchCtx, pair, "target", req.Target, req.SchemaName); targetErr != nil {
+			cancel()
+		}
+	}()
+	source, err := h.timedFetch(fetchCtx, pair, "source", req.Source, req.SchemaName)
+	if err != nil {
+		cancel()
+	}
+	<-done
+
+	// The side that failed is reported, rather than the cancellation it caused on the other side
+	if errors.Is(err, context.Canceled) && targetErr != nil && !errors.Is(targetErr, context.Canceled) {
+		err = nil
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if targetErr != nil {
+		return nil, nil, nil, targetErr
+	}
+
+	differences, err := compare.CompareSchemasContext(ctx, source, target,
+		compare.Options{
+			CompareOwners:  req.CompareOwners,
+			Direction:      req.Direction,
+			IgnoreCase:     req.IgnoreCase,
+			IgnoreDefaults: req.IgnoreDefaults,
+		},
+		compare.WithSeverityMap(req.Severity),
+	)
+	if err != nil && ctx.Err() == nil {
+		return nil, nil, nil, &requestError{http.StatusBadRequest, err}
+	}
+	return source, target, differences, err
+}
+
+// timedFetch fetches one side of a request, recording the time it took in the metrics if it
+// succeeded.
+//
+// Parameters:
+//   - ctx: Context of the request
+//   - pair: Name of the pair of sides the side belongs to
+//   - label: Name of the side (source or target)
+//   - side: Side to fetch
+//   - schemaName: PostgreSQL schema fetched from databases
+//
+// Returns:
+//   - *schema.Schema: Schema of the side
+//   - error: Any error that occurred while fetching it
+func (h *handler) timedFetch(ctx context.Context, pair, label string, side Side, schemaName string) (*schema.Schema, error) {
+	started := time.Now()
+	s, err := h.fetch(ctx, label, side, schemaName)
+	if err == nil && h.opts.Metrics != nil {
+		h.opts.Metrics.ObserveFetch(pair, label, time.Since(started))
+	}
+	return s, err
+}
+
+// pairOf names the pair of sides of a request in the metrics, as source->target, each side being
+// named by its database, or as snapshot or connection, so that connection strings are never
+// exposed.
+//
+// Parameters:
+//   - req: Request naming the sides
+//
+// Returns:
+//   - string: Name of the pair
+func pairOf(req CompareRequest) string {
+	return sideName(req.Source) + "->" + sideName(req.Target)
+}
+
+// sideName names a side of a request in the metrics.
+func sideName(side Side) string {
+	switch {
+	case side.Database != "":
+		return side.Database
+	case side.Connection != "":
+		return "connection"
+	default:
+		return "snapshot"
+	}
+}
+
+// fetch reads the schema of one side of a request.
+//
+// Parameters:
+//   - ctx: Context bounding the request
+//   - label: Name of the side ("source" or "target"), used in error messages
+//   - side: Side to fetch
+//   - schemaName: PostgreSQL schema fetched from databases
+//
+// Returns:
+//   - *schema.Schema: Fetched schema
+//   - error: Any error that occurred, wrapped in a requestError when its status is known
+func (h *handler) fetch(ctx context.Context, label string, side Side, schemaName string) (*schema.Schema, error) {
+	set := 0
+	for _, given := range []bool{side.Database != "", side.Connection != "", len(side.Snapshot) > 0} {
+		if given {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, &requestError{http.StatusBadRequest, fmt.Errorf("%s: exactly one of database, connection, and snapshot must be given", label)}
+	}
+
+	if len(side.Snapshot) > 0 {
+		snap, err := snapshot.Read(bytes.NewReader(side.Snapshot))
+		if err != nil {
+			return nil, &requestError{http.StatusBadRequest, fmt.Errorf("%s: %w", label, err)}
+		}
+		return snap.Schema, nil
+	}
+
+	connString := side.Connection
+	if side.Database != "" {
+		var exists bool
+		if connString, exists = h.opts.Connections[side.Database]; !exists {
+			return nil, &requestError{http.StatusNotFound, fmt.Errorf("%s: unknown database '%s'", label, side.Database)}
+		}
+	} else if !h.opts.AllowConnectionStrings {
+		return nil, &requestError{http.StatusForbidden, fmt.Errorf("%s: connection strings are not accepted; use a database name or a snapshot", label)}
+	}
+
+	var conn *pgx.Conn
+	err := h.opts.Retry.Do(ctx, func() error {
+		var err error
+		conn, err = schema.Connect(ctx, connString, h.opts.Session)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", label, err)
+	}
+	defer conn.Close(context.Background())
+
+	s, err := schema.NewPgxFetcher(conn, schema.FetchOptions{SchemaName: schemaName, Retry: h.opts.Retry}).Fetch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", label, err)
+	}
+	return s, nil
+}
+
+// writeError writes the response to a failed request, with the status given by statusOf.
+//
+// Parameters:
+//   - w: Response writer
+//   - err: Error the request failed with
+func writeError(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusOf(err))
+	json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+}
+
+// statusOf returns the HTTP status a failed request is reported with. Errors without a known
+// status are reported as 504 Gateway Timeout if the request ran out of time or a catalog query
+// hit a session timeout, and as 502 Bad Gateway otherwise, since they come from the databases
+// being compared.
+//
+// Parameters:
+//   - err: Error the request failed with
+//
+// Returns:
+//   - int: HTTP status of the response
+func statusOf(err error) int {
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &reqErr):
+		return reqErr.status
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, schema.ErrQueryTimeout):
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
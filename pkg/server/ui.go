@@ -0,0 +1,33 @@
+package server
+
+import (
+	"embed"
+	"fmt"
+	"net/http"
+)
+
+// uiFiles holds the dashboard, a single page reading the pairs endpoints.
+//
+//go:embed ui/index.html
+var uiFiles embed.FS
+
+// dashboard serves the dashboard on GET /, showing the configured pairs, their drift status,
+// the trend of their differences, and the differences of their latest result by table.
+func (h *handler) dashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		writeError(w, &requestError{http.StatusNotFound, fmt.Errorf("unknown endpoint '%s'", r.URL.Path)})
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, &requestError{http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method)})
+		return
+	}
+	page, err := uiFiles.ReadFile("ui/index.html")
+	if err != nil {
+		writeError(w, &requestError{http.StatusInternalServerError, err})
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
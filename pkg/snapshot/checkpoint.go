@@ -0,0 +1,173 @@
+package snapshot
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// checkpointHeader is the first line of a checkpoint file, identifying the fetch it records.
+type checkpointHeader struct {
+	Version int    `json:"checkpoint_version"` // Version of the checkpoint format
+	Key     string `json:"key"`                // Key of the fetch, such as a cache key of its database and options
+}
+
+// checkpointVersion is the version of the checkpoint format written by this release.
+const checkpointVersion = 1
+
+// Checkpoint records the progress of a long fetch in a file, one table per line as its details
+// are fetched, so that a fetch interrupted midway (e.g., by a network failure at table 80,000 of
+// 100,000) can resume where it stopped instead of starting over: the tables recorded are passed
+// to the next fetch as schema.FetchOptions.Reuse. Each table is written as soon as it is added,
+// and a line cut short by a crash is ignored when the checkpoint is opened again.
+//
+// A Checkpoint is not safe for concurrent use; the OnTableFetched hook of schema.FetchOptions,
+// which it is meant to be fed from, is never called concurrently.
+type Checkpoint struct {
+	path   string                      // Path of the checkpoint file
+	file   *os.File                    // Checkpoint file, open for appending
+	tables map[string]schema.TableInfo // Tables recorded by previous runs, keyed by name
+}
+
+// OpenCheckpoint opens a checkpoint file, creating it if needed. The tables it records are kept
+// if it was written for the same key, and discarded otherwise, so that a checkpoint is never
+// resumed against another database or with other options.
+//
+// Parameters:
+//   - path: Path of the checkpoint file
+//   - key: Key of the fetch whose progress is recorded
+//
+// Returns:
+//   - *Checkpoint: Open checkpoint
+//   - error: Any error that occurred while reading or creating the file
+func OpenCheckpoint(path, key string) (*Checkpoint, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening checkpoint file: %w", err)
+	}
+
+	tables, end, err := readCheckpoint(file, key)
+	if err == nil && tables == nil {
+		// New, foreign, or unreadable checkpoints start over with a header of their own
+		tables = map[string]schema.TableInfo{}
+		end, err = writeCheckpointHeader(file, key)
+	}
+	if err == nil {
+		// A line cut short by a crash is overwritten by the next table
+		if err = file.Truncate(end); err == nil {
+			_, err = file.Seek(end, io.SeekStart)
+		}
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error reading checkpoint file %s: %w", path, err)
+	}
+	return &Checkpoint{path: path, file: file, tables: tables}, nil
+}
+
+// readCheckpoint reads the tables recorded in a checkpoint file.
+//
+// Parameters:
+//   - file: Checkpoint file, positioned at its start
+//   - key: Key of the fetch whose progress is recorded
+//
+// Returns:
+//   - map[string]schema.TableInfo: Tables recorded, or nil if the file is empty or records another fetch
+//   - int64: Offset of the end of the last complete line
+//   - error: Any error that occurred while reading the file
+func readCheckpoint(file *os.File, key string) (map[string]schema.TableInfo, int64, error) {
+	reader := bufio.NewReader(file)
+	var (
+		end    int64
+		header checkpointHeader
+		tables map[string]schema.TableInfo
+	)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// A last line without its newline was cut short
+			return tables, end, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if tables == nil {
+			if json.Unmarshal(line, &header) != nil || header.Version != checkpointVersion || header.Key != key {
+				return nil, 0, nil
+			}
+			tables = map[string]schema.TableInfo{}
+		} else {
+			var table schema.TableInfo
+			if json.Unmarshal(line, &table) != nil {
+				return tables, end, nil
+			}
+			tables[table.Name] = table
+		}
+		end += int64(len(line))
+	}
+}
+
+// writeCheckpointHeader empties a checkpoint file and writes its header.
+//
+// Parameters:
+//   - file: Checkpoint file
+//   - key: Key of the fetch whose progress is recorded
+//
+// Returns:
+//   - int64: Offset of the end of the header
+//   - error: Any error that occurred while writing the file
+func writeCheckpointHeader(file *os.File, key string) (int64, error) {
+	line, err := json.Marshal(checkpointHeader{Version: checkpointVersion, Key: key})
+	if err != nil {
+		return 0, err
+	}
+	line = append(line, '\n')
+	if _, err := file.WriteAt(line, 0); err != nil {
+		return 0, err
+	}
+	return int64(len(line)), nil
+}
+
+// Tables returns the tables recorded by previous runs, keyed by name, to be reused by the next
+// fetch through schema.FetchOptions.Reuse.
+func (c *Checkpoint) Tables() map[string]schema.TableInfo {
+	return c.tables
+}
+
+// Add records that the details of a table have been fetched.
+//
+// Parameters:
+//   - table: Table, with the details fetched
+//
+// Returns:
+//   - error: Any error that occurred while writing the file
+func (c *Checkpoint) Add(table schema.TableInfo) error {
+	line, err := json.Marshal(table)
+	if err != nil {
+		return fmt.Errorf("error encoding checkpoint: %w", err)
+	}
+	if _, err := c.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error writing checkpoint file: %w", err)
+	}
+	return nil
+}
+
+// Close closes the checkpoint file, keeping it for the next run to resume from.
+func (c *Checkpoint) Close() error {
+	return c.file.Close()
+}
+
+// Remove closes and deletes the checkpoint file, once the fetch it recorded is complete.
+func (c *Checkpoint) Remove() error {
+	c.file.Close()
+	if err := os.Remove(c.path); err != nil {
+		return fmt.Errorf("error removing checkpoint file: %w", err)
+	}
+	return nil
+}
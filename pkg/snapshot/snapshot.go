@@ -0,0 +1,429 @@
+// Package snapshot provides functionality to save a fetched schema to a file and load it back,
+// so that schemas can be compared against databases that are no longer reachable or against
+// past states of the same database.
+//
+// Snapshots are written in a stable JSON format, or optionally in a compact binary (gob)
+// format. Both embed a format version and are decoded strictly, so that a snapshot written by
+// one release remains readable by future releases, and a snapshot from a newer release is
+// rejected instead of being misread. When the format changes incompatibly, FormatVersion is
+// increased and a migration upgrading the previous version is added to migrations, so that
+// snapshots written by older releases are upgraded to the current model when they are read.
+//
+// Snapshot files whose names end in .gz or .zst are compressed with gzip or zstd. Compressed
+// snapshots are recognized by their content when read, whatever their name.
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+	"github.com/klauspost/compress/zstd"
+)
+
+// FormatVersion is the version of the snapshot format written by this release.
+const FormatVersion = 1
+
+// ErrUnsupportedVersion is returned (wrapped) when a snapshot has a format version that this
+// release cannot read, either because it is missing or because it was written by a newer release.
+var ErrUnsupportedVersion = errors.New("unsupported snapshot format version")
+
+// Migration upgrades a decoded JSON snapshot document from one format version to the next. It
+// modifies the document in place; the format version is updated by the caller.
+type Migration func(doc map[string]any) error
+
+// migrations holds the upgrade of each past format version to the following one, keyed by the
+// version it upgrades from. Every version below FormatVersion must have an entry.
+var migrations = map[int]Migration{}
+
+// Format identifies the encoding of a snapshot.
+type Format string
+
+// Supported snapshot encodings.
+const (
+	FormatJSON   Format = "json"   // Human-readable, stable JSON
+	FormatBinary Format = "binary" // Compact gob encoding
+)
+
+// binaryMagic prefixes every binary snapshot, so that the encoding can be detected when reading.
+var binaryMagic = []byte("PGSCSNAP")
+
+// Compression identifies how a snapshot file is compressed.
+type Compression string
+
+// Supported compressions.
+const (
+	CompressionNone Compression = ""     // Not compressed
+	CompressionGzip Compression = "gzip" // Compressed with gzip, in files ending in .gz
+	CompressionZstd Compression = "zstd" // Compressed with zstd, in files ending in .zst or .zstd
+)
+
+// Magic numbers starting compressed streams, recognized by Read.
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Snapshot is the envelope written to snapshot files.
+type Snapshot struct {
+	FormatVersion int            `json:"format_version"` // Version of the snapshot format
+	CreatedAt     time.Time      `json:"created_at"`     // When the snapshot was taken
+	Schema        *schema.Schema `json:"schema"`         // Snapshotted schema
+}
+
+// Write encodes a schema as a snapshot in the given format.
+//
+// Parameters:
+//   - w: Writer the snapshot is written to
+//   - s: Schema to save
+//   - format: Encoding to use
+//
+// Returns:
+//   - error: Any error that occurred while encoding or writing the snapshot
+func Write(w io.Writer, s *schema.Schema, format Format) error {
+	snap := Snapshot{
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		Schema:        s,
+	}
+
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(snap); err != nil {
+			return fmt.Errorf("error encoding snapshot: %w", err)
+		}
+	case FormatBinary:
+		if _, err := w.Write(binaryMagic); err != nil {
+			return fmt.Errorf("error writing snapshot: %w", err)
+		}
+		if err := gob.NewEncoder(w).Encode(snap); err != nil {
+			return fmt.Errorf("error encoding snapshot: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown snapshot format '%s'", format)
+	}
+
+	return nil
+}
+
+// Read decodes a snapshot, detecting whether it is in JSON or binary format, and whether it is
+// compressed with gzip or zstd.
+//
+// Parameters:
+//   - r: Reader the snapshot is read from
+//
+// Returns:
+//   - *Snapshot: Decoded snapshot
+//   - error: An error if the snapshot is malformed, contains unknown fields, or uses an unsupported format version
+//     (wrapping ErrUnsupportedVersion)
+func Read(r io.Reader) (*Snapshot, error) {
+	buffered := bufio.NewReader(r)
+	var snap Snapshot
+
+	// Compressed snapshots are decompressed as they are read
+	header, _ := buffered.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		decompressed, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("error decompressing snapshot: %w", err)
+		}
+		defer decompressed.Close()
+		buffered = bufio.NewReader(decompressed)
+	case bytes.HasPrefix(header, zstdMagic):
+		decompressed, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("error decompressing snapshot: %w", err)
+		}
+		defer decompressed.Close()
+		buffered = bufio.NewReader(decompressed)
+	}
+
+	header, _ = buffered.Peek(len(binaryMagic))
+	if bytes.Equal(header, binaryMagic) {
+		// gob matches fields by name and tolerates added and removed fields, so binary
+		// snapshots of past versions decode directly into the current model
+		if _, err := buffered.Discard(len(binaryMagic)); err != nil {
+			return nil, fmt.Errorf("error reading snapshot: %w", err)
+		}
+		if err := gob.NewDecoder(buffered).Decode(&snap); err != nil {
+			return nil, fmt.Errorf("error decoding binary snapshot: %w", err)
+		}
+		if err := checkVersion(snap.FormatVersion); err != nil {
+			return nil, err
+		}
+		snap.FormatVersion = FormatVersion
+	} else {
+		data, err := io.ReadAll(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("error reading snapshot: %w", err)
+		}
+		if data, err = upgrade(data); err != nil {
+			return nil, err
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&snap); err != nil {
+			return nil, fmt.Errorf("error decoding snapshot: %w", err)
+		}
+	}
+
+	if snap.Schema == nil {
+		return nil, fmt.Errorf("snapshot contains no schema")
+	}
+	if snap.Schema.Tables == nil {
+		snap.Schema.Tables = make(map[string]schema.TableInfo)
+	}
+
+	return &snap, nil
+}
+
+// upgrade checks the format version of a JSON snapshot and applies the migrations needed to
+// bring it to FormatVersion. Snapshots already in the current version are returned unchanged.
+//
+// Parameters:
+//   - data: Encoded JSON snapshot
+//
+// Returns:
+//   - []byte: Encoded JSON snapshot in the current format version
+//   - error: An error if the snapshot is malformed, its version is unsupported, or a migration fails
+func upgrade(data []byte) ([]byte, error) {
+	var envelope struct {
+		FormatVersion int `json:"format_version"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("error decoding snapshot: %w", err)
+	}
+	if err := checkVersion(envelope.FormatVersion); err != nil {
+		return nil, err
+	}
+	if envelope.FormatVersion == FormatVersion {
+		return data, nil
+	}
+
+	var doc map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("error decoding snapshot: %w", err)
+	}
+	for version := envelope.FormatVersion; version < FormatVersion; version++ {
+		migrate, exists := migrations[version]
+		if !exists {
+			return nil, fmt.Errorf("%w: no upgrade from version %d to %d", ErrUnsupportedVersion, version, version+1)
+		}
+		if err := migrate(doc); err != nil {
+			return nil, fmt.Errorf("error upgrading snapshot from format version %d: %w", version, err)
+		}
+		doc["format_version"] = version + 1
+	}
+
+	upgraded, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding upgraded snapshot: %w", err)
+	}
+	return upgraded, nil
+}
+
+// checkVersion rejects snapshots whose format version this release cannot read.
+//
+// Parameters:
+//   - version: Format version found in the snapshot
+//
+// Returns:
+//   - error: An error if the version is missing or newer than FormatVersion
+func checkVersion(version int) error {
+	switch {
+	case version <= 0:
+		return fmt.Errorf("%w: snapshot has no format version", ErrUnsupportedVersion)
+	case version > FormatVersion:
+		return fmt.Errorf("%w: version %d was written by a newer release (this release reads up to version %d)", ErrUnsupportedVersion, version, FormatVersion)
+	}
+	return nil
+}
+
+// FormatForPath chooses the snapshot format from a file extension, ignoring any compression
+// extension: ".bin" and ".snap" files are binary, anything else is JSON.
+//
+// Parameters:
+//   - path: Path of the snapshot file
+//
+// Returns:
+//   - Format: Format to write the file in
+func FormatForPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(withoutCompression(path))) {
+	case ".bin", ".snap":
+		return FormatBinary
+	}
+	return FormatJSON
+}
+
+// IsSnapshotPath reports whether a path names a snapshot file rather than a connection string,
+// based on its extension, which can be followed by a compression extension (e.g., app.json.zst).
+//
+// Parameters:
+//   - path: Path or connection string to check
+//
+// Returns:
+//   - bool: True if the path has a snapshot file extension
+func IsSnapshotPath(path string) bool {
+	switch strings.ToLower(filepath.Ext(withoutCompression(path))) {
+	case ".json", ".bin", ".snap":
+		return !strings.Contains(path, "://")
+	}
+	return false
+}
+
+// CompressionForPath chooses the compression of a snapshot file from its extension: ".gz" files
+// are compressed with gzip, ".zst" and ".zstd" files with zstd, and others are not compressed.
+//
+// Parameters:
+//   - path: Path of the snapshot file
+//
+// Returns:
+//   - Compression: Compression to write the file with
+func CompressionForPath(path string) Compression {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz":
+		return CompressionGzip
+	case ".zst", ".zstd":
+		return CompressionZstd
+	}
+	return CompressionNone
+}
+
+// withoutCompression removes the compression extension, if any, from a path.
+func withoutCompression(path string) string {
+	if CompressionForPath(path) == CompressionNone {
+		return path
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path))
+}
+
+// WriteFile saves a schema to a snapshot file, in the format chosen by FormatForPath and with
+// the compression chosen by CompressionForPath.
+//
+// Parameters:
+//   - path: Path of the snapshot file to create
+//   - s: Schema to save
+//
+// Returns:
+//   - error: Any error that occurred while writing the file
+func WriteFile(path string, s *schema.Schema) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating snapshot file: %w", err)
+	}
+	if err := WriteForPath(file, path, s); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// WriteForPath encodes a schema as WriteFile saves it to a path, in the format and with the
+// compression chosen by the name of the path, so that snapshots can be written elsewhere than
+// to local files, such as to object storage.
+//
+// Parameters:
+//   - w: Writer the snapshot is written to
+//   - path: Path or URI whose name chooses the format and compression
+//   - s: Schema to save
+//
+// Returns:
+//   - error: Any error that occurred while encoding, compressing, or writing the snapshot
+func WriteForPath(w io.Writer, path string, s *schema.Schema) error {
+	compressed, err := compress(w, CompressionForPath(path))
+	if err != nil {
+		return err
+	}
+	err = Write(compressed, s, FormatForPath(path))
+	if closeErr := compressed.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("error compressing snapshot: %w", closeErr)
+	}
+	return err
+}
+
+// compress returns a writer compressing what is written to it into w. Closing it flushes the
+// compressed stream, but does not close w.
+//
+// Parameters:
+//   - w: Writer the compressed stream is written to
+//   - compression: Compression to use
+//
+// Returns:
+//   - io.WriteCloser: Compressing writer, or w itself with a no-op Close if not compressing
+//   - error: An error if the compression is unknown
+func compress(w io.Writer, compression Compression) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionNone:
+		return nopCloser{w}, nil
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		compressed, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, fmt.Errorf("error compressing snapshot: %w", err)
+		}
+		return compressed, nil
+	}
+	return nil, fmt.Errorf("unknown snapshot compression '%s'", compression)
+}
+
+// nopCloser is a writer whose Close does nothing.
+type nopCloser struct {
+	io.Writer
+}
+
+// Close does nothing.
+func (nopCloser) Close() error { return nil }
+
+// ReadFile loads a snapshot file written by WriteFile.
+//
+// Parameters:
+//   - path: Path of the snapshot file
+//
+// Returns:
+//   - *Snapshot: Decoded snapshot
+//   - error: Any error that occurred while reading or decoding the file
+func ReadFile(path string) (*Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening snapshot file: %w", err)
+	}
+	defer file.Close()
+
+	snap, err := Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return snap, nil
+}
+
+// Fetcher is a schema.Fetcher that reads the schema from a snapshot file.
+type Fetcher struct {
+	Path string // Path of the snapshot file
+}
+
+// Fetch reads the schema from the snapshot file.
+func (f Fetcher) Fetch(ctx context.Context) (*schema.Schema, error) {
+	snap, err := ReadFile(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	return snap.Schema, nil
+}
@@ -0,0 +1,43 @@
+package snapshot_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/guriandoro/pg_schema_check/pkg/bench"
+	"github.com/guriandoro/pg_schema_check/pkg/snapshot"
+)
+
+// BenchmarkSnapshot encodes generated schemas as binary snapshots (Write), and decodes them
+// (Read), at each of bench.TableCounts.
+func BenchmarkSnapshot(b *testing.B) {
+	for _, tables := range bench.TableCounts() {
+		s := bench.Generate(tables, nil)
+
+		b.Run(fmt.Sprintf("Write/%d", tables), func(b *testing.B) {
+			defer bench.TrackPeakHeap(b)()
+			for i := 0; i < b.N; i++ {
+				if err := snapshot.Write(io.Discard, s, snapshot.FormatBinary); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("Read/%d", tables), func(b *testing.B) {
+			var encoded bytes.Buffer
+			if err := snapshot.Write(&encoded, s, snapshot.FormatBinary); err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(encoded.Len()))
+			defer bench.TrackPeakHeap(b)()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := snapshot.Read(bytes.NewReader(encoded.Bytes())); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Package sqlnorm normalizes SQL text, such as the definitions of views, the bodies of
+// functions, and the conditions of triggers and partial indexes, so that definitions can be
+// compared by their meaning rather than by their text. Servers of different versions, pg_dump,
+// and people format the same definition differently: with other whitespace, line breaks,
+// comments, keyword case, identifier quoting, or redundant parentheses.
+//
+// Normalize parses the text with PostgreSQL's own parser (through pg_query_go, which needs cgo)
+// and prints the parse tree back, so two texts have the same normal form when they have the
+// same parse tree. Parentheses only survive where the tree needs them, so (a + b) * c and
+// a + b * c keep different normal forms. Text the parser rejects, such as the body of a
+// PL/pgSQL function or a statement of another dialect, falls back to a token normal form that
+// only drops whitespace, comments, and the case of keywords and identifiers, and keeps every
+// parenthesis.
+package sqlnorm
+
+import (
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+)
+
+// Normalize returns the normal form of SQL text: one or more statements, or a bare expression
+// such as the WHEN condition of a trigger or the predicate of an index, printed back from its
+// parse tree. The bodies of CREATE FUNCTION and CREATE PROCEDURE statements, which the parser
+// keeps as string constants, are normalized in turn when they are written in SQL or PL/pgSQL;
+// bodies in other languages are kept as written. Text the parser rejects is normalized by its
+// tokens instead.
+//
+// Parameters:
+//   - sql: SQL text to normalize
+//
+// Returns:
+//   - string: Normal form of the text; two texts with the same normal form are the same statement
+func Normalize(sql string) string {
+	if normal, ok := deparse(sql); ok {
+		return normal
+	}
+
+	// Expressions are parsed as the target of a SELECT
+	if normal, ok := deparse("SELECT " + sql); ok && strings.HasPrefix(normal, "SELECT ") {
+		return strings.TrimPrefix(normal, "SELECT ")
+	}
+	return normalizeTokens(sql)
+}
+
+// Equal reports whether two SQL texts are the same statement once normalized.
+//
+// Parameters:
+//   - a: First SQL text
+//   - b: Second SQL text
+//
+// Returns:
+//   - bool: True if both texts have the same normal form
+func Equal(a, b string) bool {
+	return a == b || Normalize(a) == Normalize(b)
+}
+
+// deparse parses SQL text and prints its parse tree back, with the bodies of functions
+// normalized.
+//
+// Parameters:
+//   - sql: SQL text to parse
+//
+// Returns:
+//   - string: Text printed from the parse tree
+//   - bool: False if the text could not be parsed or printed
+func deparse(sql string) (string, bool) {
+	tree, err := pg_query.Parse(sql)
+	if err != nil || len(tree.Stmts) == 0 {
+		return "", false
+	}
+	for _, stmt := range tree.Stmts {
+		if fn := stmt.Stmt.GetCreateFunctionStmt(); fn != nil {
+			normalizeBody(fn)
+		}
+	}
+	normal, err := pg_query.Deparse(tree)
+	if err != nil {
+		return "", false
+	}
+	return normal, true
+}
+
+// normalizeBody replaces the body of a CREATE FUNCTION or CREATE PROCEDURE statement given as a
+// string constant with its normal form: parsed as statements for SQL functions, or by its
+// tokens for PL/pgSQL ones. Bodies in other languages, such as PL/Python, whose whitespace can
+// matter, and bodies written as BEGIN ATOMIC, which are part of the parse tree, are left alone.
+func normalizeBody(fn *pg_query.CreateFunctionStmt) {
+	var language string
+	var body *pg_query.String
+	for _, option := range fn.Options {
+		def := option.GetDefElem()
+		if def == nil {
+			continue
+		}
+		switch def.Defname {
+		case "language":
+			language = strings.ToLower(def.Arg.GetString_().GetSval())
+		case "as":
+			// C functions have an object file and a symbol rather than a body
+			if items := def.Arg.GetList().GetItems(); len(items) == 1 {
+				body = items[0].GetString_()
+			}
+		}
+	}
+	if body == nil {
+		return
+	}
+
+	switch language {
+	case "sql":
+		if normal, ok := deparse(body.Sval); ok {
+			body.Sval = normal
+			return
+		}
+		body.Sval = normalizeTokens(body.Sval)
+	case "plpgsql":
+		body.Sval = normalizeTokens(body.Sval)
+	}
+}
@@ -0,0 +1,183 @@
+package sqlnorm_test
+
+import (
+	"testing"
+
+	"github.com/guriandoro/pg_schema_check/pkg/sqlnorm"
+)
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want bool
+	}{
+		{
+			name: "redundant parentheses",
+			a:    "((a)) + (b)",
+			b:    "a + b",
+			want: true,
+		},
+		{
+			name: "parentheses that change precedence",
+			a:    "(a + b) * c",
+			b:    "a + b * c",
+			want: false,
+		},
+		{
+			name: "view definition from pg_get_viewdef and hand-written DDL",
+			a: ` SELECT orders.id,
+    orders.total
+   FROM orders
+  WHERE (orders.status = 'new'::text);`,
+			b:    "select orders.id, orders.total from orders where orders.status = 'new'::text",
+			want: true,
+		},
+		{
+			name: "view definitions with different tables",
+			a:    "SELECT orders.id FROM orders",
+			b:    "SELECT orders.id FROM public.orders",
+			want: false,
+		},
+		{
+			name: "PL/pgSQL body with comments and mixed case",
+			a: `CREATE FUNCTION touch() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+    -- Record the time of the change
+    NEW.Updated_At := now(); /* set by the trigger */
+    RETURN NEW;
+END;
+$$`,
+			b:    "create function touch() returns trigger language plpgsql as $body$begin new.updated_at := NOW(); return new; end;$body$",
+			want: true,
+		},
+		{
+			name: "PL/pgSQL bodies with different statements",
+			a:    "CREATE FUNCTION touch() RETURNS trigger LANGUAGE plpgsql AS $$BEGIN RETURN NEW; END$$",
+			b:    "CREATE FUNCTION touch() RETURNS trigger LANGUAGE plpgsql AS $$BEGIN RETURN OLD; END$$",
+			want: false,
+		},
+		{
+			name: "quoted identifier that needs no quotes",
+			a:    `SELECT "id" FROM "orders"`,
+			b:    "SELECT id FROM orders",
+			want: true,
+		},
+		{
+			name: "quoted identifier that needs its quotes",
+			a:    `SELECT id FROM "Orders"`,
+			b:    "SELECT id FROM orders",
+			want: false,
+		},
+		{
+			name: "string constants in other case",
+			a:    "status = 'New'",
+			b:    "status = 'new'",
+			want: false,
+		},
+		{
+			name: "string constants in a PL/pgSQL body in other case",
+			a:    "CREATE FUNCTION f() RETURNS text LANGUAGE plpgsql AS $$BEGIN RETURN 'New'; END$$",
+			b:    "CREATE FUNCTION f() RETURNS text LANGUAGE plpgsql AS $$BEGIN RETURN 'new'; END$$",
+			want: false,
+		},
+		{
+			name: "unterminated string constant",
+			a:    "SELECT 'open",
+			b:    "select  'open",
+			want: true,
+		},
+		{
+			name: "unterminated string constants in other case",
+			a:    "SELECT 'Open",
+			b:    "SELECT 'open",
+			want: false,
+		},
+		{
+			name: "unterminated quoted identifier",
+			a:    `SELECT "Open`,
+			b:    `SELECT "open`,
+			want: false,
+		},
+		{
+			name: "empty input",
+			a:    "",
+			b:    "",
+			want: true,
+		},
+		{
+			name: "empty input and a comment",
+			a:    "",
+			b:    "-- nothing",
+			want: true,
+		},
+		{
+			name: "empty input and a statement",
+			a:    "",
+			b:    "SELECT 1",
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sqlnorm.Equal(tt.a, tt.b); got != tt.want {
+				t.Errorf("Equal(%q, %q) = %v, want %v (normal forms %q and %q)",
+					tt.a, tt.b, got, tt.want, sqlnorm.Normalize(tt.a), sqlnorm.Normalize(tt.b))
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "empty input",
+			sql:  "",
+			want: "",
+		},
+		{
+			name: "whitespace and comments only",
+			sql:  "  /* nested /* comment */ */\n-- line comment\n",
+			want: "",
+		},
+		{
+			name: "expression",
+			sql:  "((price)) * (quantity + 1)",
+			want: "price * (quantity + 1)",
+		},
+		{
+			name: "unterminated string constant",
+			sql:  "IF x = 'Open",
+			want: "if x = 'Open",
+		},
+		{
+			name: "unterminated quoted identifier",
+			sql:  `IF "Open`,
+			want: `if "Open`,
+		},
+		{
+			name: "unterminated dollar-quoted string",
+			sql:  "DO $body$ BEGIN NULL",
+			want: "do $$begin null$$",
+		},
+		{
+			name: "tokens the parser rejects",
+			sql:  "IF \"Total\" != \"total\" THEN RAISE 'Mismatch'; END IF;",
+			want: `if "Total" <> total then raise 'Mismatch' ; end if`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sqlnorm.Normalize(tt.sql); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.sql, got, tt.want)
+			}
+		})
+	}
+}
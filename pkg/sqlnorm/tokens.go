@@ -0,0 +1,276 @@
+package sqlnorm
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind classifies the tokens of SQL text.
+type tokenKind int
+
+const (
+	tokenWord   tokenKind = iota // Keyword or unquoted identifier, lowercased
+	tokenQuoted                  // Quoted identifier
+	tokenString                  // String constant, including its prefix (E, B, X, or U&) and quotes
+	tokenDollar                  // Dollar-quoted string constant
+	tokenNumber                  // Numeric constant
+	tokenOpen                    // Opening parenthesis
+	tokenClose                   // Closing parenthesis
+	tokenOther                   // Operator or other punctuation
+)
+
+// token is a lexical token of SQL text.
+type token struct {
+	kind tokenKind // Kind of the token
+	text string    // Normal form of the token
+}
+
+// normalizeTokens returns the normal form of SQL text the parser does not accept, such as the
+// body of a PL/pgSQL function: its tokens separated by single spaces, without comments or a
+// trailing semicolon, with keywords and unquoted identifiers lowercased, quoted identifiers that
+// need no quotes unquoted, != written <>, and the content of dollar-quoted strings normalized in
+// turn, with $$ as their delimiter. String constants, quoted identifiers that need their quotes,
+// and parentheses are kept as written, so that texts with the same normal form have the same
+// meaning, although texts with the same meaning may have different normal forms.
+//
+// Parameters:
+//   - sql: SQL text to normalize
+//
+// Returns:
+//   - string: Normal form of the text
+func normalizeTokens(sql string) string {
+	tokens := tokenize(sql)
+	for len(tokens) > 0 && tokens[len(tokens)-1].text == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+
+	var b strings.Builder
+	for i, tok := range tokens {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(tok.text)
+	}
+	return b.String()
+}
+
+// tokenize splits SQL text into its tokens, in their normal form, dropping whitespace and
+// comments. Unterminated strings, identifiers, and comments extend to the end of the text.
+//
+// Parameters:
+//   - sql: SQL text to split
+//
+// Returns:
+//   - []token: Tokens of the text, in order
+func tokenize(sql string) []token {
+	var tokens []token
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			i++
+
+		case strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				return tokens
+			}
+			i += end + 1
+
+		case strings.HasPrefix(sql[i:], "/*"):
+			i = skipBlockComment(sql, i)
+
+		case c == '\'':
+			end := quotedEnd(sql, i, '\'')
+			tokens = append(tokens, token{tokenString, sql[i:end]})
+			i = end
+
+		case (c == 'e' || c == 'E' || c == 'b' || c == 'B' || c == 'x' || c == 'X' || c == 'n' || c == 'N') &&
+			i+1 < len(sql) && sql[i+1] == '\'':
+			end := escapedEnd(sql, i+1, c == 'e' || c == 'E')
+			tokens = append(tokens, token{tokenString, strings.ToUpper(sql[i:i+1]) + sql[i+1:end]})
+			i = end
+
+		case (c == 'u' || c == 'U') && strings.HasPrefix(sql[i+1:], "&'"):
+			end := quotedEnd(sql, i+2, '\'')
+			tokens = append(tokens, token{tokenString, "U&" + sql[i+2:end]})
+			i = end
+
+		case c == '"':
+			end := quotedEnd(sql, i, '"')
+			tokens = append(tokens, quotedIdentifier(sql[i:end]))
+			i = end
+
+		case c == '$' && dollarTag(sql[i:]) != "":
+			tag := dollarTag(sql[i:])
+			body := sql[i+len(tag):]
+			end := strings.Index(body, tag)
+			if end < 0 {
+				end = len(body)
+			}
+			tokens = append(tokens, token{tokenDollar, "$$" + normalizeTokens(body[:end]) + "$$"})
+			i += len(tag) + min(end+len(tag), len(body))
+
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(sql) && sql[i+1] >= '0' && sql[i+1] <= '9':
+			end := i + 1
+			for end < len(sql) && (isWordByte(sql[end]) || sql[end] == '.' ||
+				(sql[end] == '+' || sql[end] == '-') && (sql[end-1] == 'e' || sql[end-1] == 'E')) {
+				end++
+			}
+			tokens = append(tokens, token{tokenNumber, strings.ToLower(sql[i:end])})
+			i = end
+
+		case isWordStart(sql, i):
+			end := i
+			for end < len(sql) {
+				if isWordByte(sql[end]) || sql[end] == '$' {
+					end++
+					continue
+				}
+				r, size := utf8.DecodeRuneInString(sql[end:])
+				if r < utf8.RuneSelf || !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				end += size
+			}
+			tokens = append(tokens, token{tokenWord, strings.ToLower(sql[i:end])})
+			i = end
+
+		case c == '(':
+			tokens = append(tokens, token{tokenOpen, "("})
+			i++
+
+		case c == ')':
+			tokens = append(tokens, token{tokenClose, ")"})
+			i++
+
+		case strings.IndexByte(operatorChars, c) >= 0:
+			end := i + 1
+			for end < len(sql) && strings.IndexByte(operatorChars, sql[end]) >= 0 &&
+				!strings.HasPrefix(sql[end:], "--") && !strings.HasPrefix(sql[end:], "/*") {
+				end++
+			}
+			// As in PostgreSQL, an operator ends in + or - only if it has one of ~!@#%^&|`?
+			for end > i+1 && (sql[end-1] == '+' || sql[end-1] == '-') && !strings.ContainsAny(sql[i:end], "~!@#%^&|`?") {
+				end--
+			}
+			op := sql[i:end]
+			if op == "!=" {
+				op = "<>"
+			}
+			tokens = append(tokens, token{tokenOther, op})
+			i = end
+
+		default:
+			_, size := utf8.DecodeRuneInString(sql[i:])
+			tokens = append(tokens, token{tokenOther, sql[i : i+size]})
+			i += size
+		}
+	}
+	return tokens
+}
+
+// operatorChars are the characters PostgreSQL operators are made of.
+const operatorChars = "+-*/<>=~!@#%^&|`?:"
+
+// isWordByte reports whether an ASCII character can continue a keyword or identifier.
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// isWordStart reports whether a keyword or identifier starts at a position of SQL text.
+func isWordStart(sql string, i int) bool {
+	c := sql[i]
+	if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(sql[i:])
+	return r >= utf8.RuneSelf && unicode.IsLetter(r)
+}
+
+// skipBlockComment returns the position after a block comment starting at a position of SQL
+// text. Block comments nest, as in PostgreSQL.
+func skipBlockComment(sql string, start int) int {
+	depth := 0
+	for i := start; i < len(sql)-1; i++ {
+		switch {
+		case sql[i] == '/' && sql[i+1] == '*':
+			depth++
+			i++
+		case sql[i] == '*' && sql[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(sql)
+}
+
+// quotedEnd returns the position after a string constant or quoted identifier starting at a
+// position of SQL text, where doubled quotes stand for the quote itself.
+func quotedEnd(sql string, start int, quote byte) int {
+	for i := start + 1; i < len(sql); i++ {
+		if sql[i] == quote {
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(sql)
+}
+
+// escapedEnd returns the position after a string constant starting at a position of SQL text,
+// where backslashes also escape quotes if the constant has the E prefix.
+func escapedEnd(sql string, start int, backslashes bool) int {
+	if !backslashes {
+		return quotedEnd(sql, start, '\'')
+	}
+	for i := start + 1; i < len(sql); i++ {
+		switch {
+		case sql[i] == '\\':
+			i++
+		case sql[i] == '\'':
+			if i+1 < len(sql) && sql[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(sql)
+}
+
+// dollarTag returns the delimiter of the dollar-quoted string SQL text starts with (e.g., "$$"
+// or "$body$"), or empty if it does not start with one.
+func dollarTag(sql string) string {
+	for i := 1; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case c == '$':
+			return sql[:i+1]
+		case isWordByte(c) && !(i == 1 && c >= '0' && c <= '9'):
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// quotedIdentifier returns the token of a quoted identifier, without its quotes if it would
+// mean the same unquoted: all lowercase letters, digits, and underscores, not starting with a digit.
+func quotedIdentifier(quoted string) token {
+	name := strings.TrimSuffix(strings.TrimPrefix(quoted, `"`), `"`)
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		return token{tokenQuoted, quoted}
+	}
+	for i := 0; i < len(name); i++ {
+		if c := name[i]; !(c == '_' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9') {
+			return token{tokenQuoted, quoted}
+		}
+	}
+	return token{tokenWord, name}
+}
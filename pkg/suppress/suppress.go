@@ -0,0 +1,184 @@
+// Package suppress provides functionality to suppress differences with expression-based rules,
+// for cases that the table filters and severity overrides cannot express. Rules are written in
+// the expr language (https://expr-lang.org) and evaluated against each difference. Rules can
+// carry an expiry date, after which they stop suppressing so that accepted drift is revisited.
+package suppress
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+)
+
+// Rule represents a single suppression rule. A difference is suppressed when the rule's
+// expression evaluates to true for it.
+//
+// The expression can refer to the whole difference as diff (e.g., diff.Type) and to its
+// fields through the shorthands type, table, description, and severity, for example:
+//
+//	diff.Type == "ExtraIndex" && table startsWith "archive_"
+type Rule struct {
+	Expr    string `yaml:"expr"`              // Expression that selects the differences to suppress
+	Reason  string `yaml:"reason,omitempty"`  // Why the differences are accepted
+	Owner   string `yaml:"owner,omitempty"`   // Person or team responsible for the accepted drift
+	Expires string `yaml:"expires,omitempty"` // Last day (YYYY-MM-DD) the rule applies; empty never expires
+}
+
+// DateFormat is the layout of the expiry date of a rule.
+const DateFormat = "2006-01-02"
+
+// env is the environment that rule expressions are evaluated against.
+type env struct {
+	Diff        compare.Difference `expr:"diff"`
+	Type        string             `expr:"type"`
+	Table       string             `expr:"table"`
+	Description string             `expr:"description"`
+	Severity    string             `expr:"severity"`
+}
+
+// Suppressor holds a set of compiled suppression rules.
+type Suppressor struct {
+	rules    []Rule        // Rules that are still in effect, in the order they were given
+	programs []*vm.Program // Compiled expression of each rule in effect
+	expired  []Rule        // Rules whose expiry date has passed
+}
+
+// New compiles the given rules into a Suppressor. Rules whose expiry date has passed are
+// not applied, and can be listed with Expired.
+//
+// Parameters:
+//   - rules: Suppression rules to compile
+//   - now: Current time, used to decide which rules have expired
+//
+// Returns:
+//   - *Suppressor: Suppressor ready to be applied to differences
+//   - error: An error if any of the expressions is invalid or does not return a boolean,
+//     or if any expiry date is malformed
+func New(rules []Rule, now time.Time) (*Suppressor, error) {
+	s := &Suppressor{}
+	for _, rule := range rules {
+		program, err := expr.Compile(rule.Expr, expr.Env(env{}), expr.AsBool())
+		if err != nil {
+			return nil, fmt.Errorf("invalid suppression rule '%s': %w", rule.Expr, err)
+		}
+
+		expired, err := rule.IsExpired(now)
+		if err != nil {
+			return nil, err
+		}
+		if expired {
+			s.expired = append(s.expired, rule)
+			continue
+		}
+
+		s.rules = append(s.rules, rule)
+		s.programs = append(s.programs, program)
+	}
+	return s, nil
+}
+
+// IsExpired reports whether the rule's expiry date has passed. A rule stays in effect
+// until the end of its expiry date.
+//
+// Parameters:
+//   - now: Current time
+//
+// Returns:
+//   - bool: True if the rule has expired
+//   - error: An error if the expiry date is malformed
+func (r Rule) IsExpired(now time.Time) (bool, error) {
+	if r.Expires == "" {
+		return false, nil
+	}
+
+	expires, err := time.ParseInLocation(DateFormat, r.Expires, now.Location())
+	if err != nil {
+		return false, fmt.Errorf("invalid expiry date '%s' in suppression rule '%s': %w", r.Expires, r.Expr, err)
+	}
+	return !now.Before(expires.AddDate(0, 0, 1)), nil
+}
+
+// RuleFor returns a rule suppressing exactly one difference: the differences of its type about
+// the same table and sub-object, as when drift is accepted one difference at a time.
+//
+// Parameters:
+//   - diff: Difference to suppress
+//
+// Returns:
+//   - Rule: Rule matching the difference, with no reason, owner, or expiry date
+func RuleFor(diff compare.Difference) Rule {
+	expression := "diff.Type == " + strconv.Quote(diff.Type) + " && diff.Table == " + strconv.Quote(diff.Table)
+	if diff.SubObject != "" {
+		expression += " && diff.SubObject == " + strconv.Quote(diff.SubObject)
+	}
+	return Rule{Expr: expression}
+}
+
+// Expired returns the rules that were not applied because their expiry date has passed.
+//
+// Returns:
+//   - []Rule: Expired rules, in the order they were given
+func (s *Suppressor) Expired() []Rule {
+	return s.expired
+}
+
+// Apply removes the differences matched by any of the rules.
+//
+// Parameters:
+//   - differences: Differences to check against the rules
+//
+// Returns:
+//   - []Difference: Differences that were not suppressed
+//   - int: Number of differences that were suppressed
+//   - error: Any error that occurred while evaluating the rules
+func (s *Suppressor) Apply(differences []compare.Difference) ([]compare.Difference, int, error) {
+	var kept []compare.Difference
+	suppressed := 0
+
+	for _, diff := range differences {
+		matched, err := s.match(diff)
+		if err != nil {
+			return nil, 0, err
+		}
+		if matched {
+			suppressed++
+			continue
+		}
+		kept = append(kept, diff)
+	}
+
+	return kept, suppressed, nil
+}
+
+// match reports whether any of the rules matches the given difference.
+//
+// Parameters:
+//   - diff: Difference to check
+//
+// Returns:
+//   - bool: True if the difference should be suppressed
+//   - error: Any error that occurred while evaluating the rules
+func (s *Suppressor) match(diff compare.Difference) (bool, error) {
+	vars := env{
+		Diff:        diff,
+		Type:        diff.Type,
+		Table:       diff.Table,
+		Description: diff.Description,
+		Severity:    diff.Severity,
+	}
+
+	for i, program := range s.programs {
+		result, err := expr.Run(program, vars)
+		if err != nil {
+			return false, fmt.Errorf("error evaluating suppression rule '%s': %w", s.rules[i].Expr, err)
+		}
+		if result.(bool) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,74 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/guriandoro/pg_schema_check/pkg/patch"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// definition renders the definition of a table as DDL, for the side-by-side view: the table with
+// its columns and primary key, then its other indexes and its foreign keys, and its owner,
+// partitioning, and comment as SQL comments.
+//
+// Parameters:
+//   - s: Schema the table is looked up in
+//   - name: Name of the table
+//
+// Returns:
+//   - []string: Lines of the definition, or a single line telling that the table is absent
+func definition(s *schema.Schema, name string) []string {
+	table, ok := s.Tables[name]
+	if !ok {
+		return []string{"-- no table " + name}
+	}
+
+	statements := []patch.Operation{patch.CreateTable{Table: name, Schema: s.Name, Info: table}}
+	if table.View != nil {
+		statements = []patch.Operation{patch.CreateView{Table: name, Schema: s.Name, View: *table.View}}
+	}
+	for _, idx := range table.Indexes {
+		if !backsPrimaryKey(table, idx) {
+			statements = append(statements, patch.CreateIndex{Table: name, Schema: s.Name, Index: idx})
+		}
+	}
+	for _, fk := range table.ForeignKeys {
+		statements = append(statements, patch.AddForeignKey{Table: name, Schema: s.Name, ForeignKey: fk})
+	}
+
+	var lines []string
+	for _, op := range statements {
+		statement, err := patch.SQL(op)
+		if err != nil {
+			continue
+		}
+		lines = append(lines, strings.Split(statement, "\n")...)
+	}
+	if table.PartitionKey != "" {
+		lines = append(lines, "-- partitioned by "+table.PartitionKey)
+	}
+	if table.PartitionOf != "" {
+		lines = append(lines, "-- partition of "+table.PartitionOf)
+	}
+	if table.Owner != "" {
+		lines = append(lines, "-- owner "+table.Owner)
+	}
+	if table.Comment != "" {
+		lines = append(lines, "-- comment "+strings.ReplaceAll(table.Comment, "\n", " "))
+	}
+	return lines
+}
+
+// backsPrimaryKey reports whether an index is the one backing the primary key of its table,
+// which the CREATE TABLE statement already shows.
+func backsPrimaryKey(table schema.TableInfo, idx schema.IndexInfo) bool {
+	if !idx.Unique || len(idx.Columns) != len(table.PrimaryKeys) || len(idx.Expressions) > 0 || idx.Predicate != "" {
+		return false
+	}
+	for i, col := range table.PrimaryKeys {
+		if idx.Columns[i] != col {
+			return false
+		}
+	}
+	return len(table.PrimaryKeys) > 0
+}
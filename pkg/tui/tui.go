@@ -0,0 +1,621 @@
+// Package tui provides an interactive terminal browser of the differences found by a comparison.
+// Differences are listed in groups, by schema, table, or type of difference; each can be opened
+// to see the definitions of its table on both sides next to each other, and differences can be
+// selected to be accepted (as suppression rules) or exported as a migration, through callbacks
+// supplied by the caller.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// Ways of grouping the differences, in the order the tab key cycles through them.
+const (
+	GroupTable  = "table"  // One group per table
+	GroupType   = "type"   // One group per type of difference
+	GroupSchema = "schema" // One group per PostgreSQL schema
+)
+
+// groupings lists the ways of grouping the differences, in the order the tab key cycles
+// through them.
+var groupings = []string{GroupTable, GroupType, GroupSchema}
+
+// Options configures the browser.
+type Options struct {
+	Source  *schema.Schema                                       // Schema of the source, for the definitions shown; nil leaves them out
+	Target  *schema.Schema                                       // Schema of the target, for the definitions shown; nil leaves them out
+	GroupBy string                                               // Initial grouping of the differences: GroupTable (the default), GroupType, or GroupSchema
+	Accept  func(differences compare.DiffResult) (string, error) // Accepts differences, returning a message telling where; nil disables accepting
+	Export  func(differences compare.DiffResult) (string, error) // Exports the migration of differences, returning a message telling where; nil disables exporting
+}
+
+// Run shows the browser in the terminal until the user quits it or the context ends.
+//
+// Parameters:
+//   - ctx: Context whose end closes the browser
+//   - differences: Differences to browse
+//   - opts: Schemas shown and actions available
+//
+// Returns:
+//   - error: Any error that occurred while driving the terminal
+func Run(ctx context.Context, differences compare.DiffResult, opts Options) error {
+	m := newModel(differences, opts)
+	_, err := tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(ctx)).Run()
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// item is a difference listed by the browser.
+type item struct {
+	diff     compare.Difference // Difference
+	selected bool               // Whether the difference is selected for accepting or exporting
+}
+
+// row is a line of the list: the heading of a group, or a difference of an expanded group.
+type row struct {
+	group string  // Name of the group the row belongs to
+	items []*item // Differences of the group, for headings
+	item  *item   // Difference, for difference rows; nil for headings
+}
+
+// Result messages of the actions run outside of Update.
+type (
+	acceptedMsg struct {
+		items   []*item // Differences accepted
+		message string  // Where they were accepted
+		err     error   // Error that prevented accepting them
+	}
+	exportedMsg struct {
+		message string // Where the migration was written
+		err     error  // Error that prevented writing it
+	}
+)
+
+// Styles of the parts of the screen.
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+	headingStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	cursorStyle   = lipgloss.NewStyle().Reverse(true)
+	hintStyle     = lipgloss.NewStyle().Faint(true)
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	sourceOnly    = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	targetOnly    = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	severityStyle = map[string]lipgloss.Style{
+		compare.SeverityError:   lipgloss.NewStyle().Foreground(lipgloss.Color("9")),
+		compare.SeverityWarning: lipgloss.NewStyle().Foreground(lipgloss.Color("11")),
+		compare.SeverityInfo:    lipgloss.NewStyle().Foreground(lipgloss.Color("14")),
+	}
+)
+
+// model is the state of the browser.
+type model struct {
+	opts      Options
+	items     []*item         // Differences not accepted yet
+	groupBy   string          // Current grouping
+	collapsed map[string]bool // Groups whose differences are hidden, reset when the grouping changes
+	rows      []row           // Lines of the list
+	cursor    int             // Row under the cursor
+	offset    int             // First row shown
+	detail    *item           // Difference whose definitions are shown; nil shows the list
+	scroll    int             // First line of the definitions shown
+	confirm   []*item         // Differences waiting for the confirmation of their acceptance
+	help      bool            // Whether the keys are listed
+	status    string          // Outcome of the last action
+	failed    bool            // Whether the last action failed
+	width     int             // Width of the terminal
+	height    int             // Height of the terminal
+}
+
+// newModel returns the browser of a list of differences, grouped as the options say.
+func newModel(differences compare.DiffResult, opts Options) *model {
+	m := &model{opts: opts, groupBy: GroupTable, collapsed: map[string]bool{}, width: 80, height: 24}
+	for _, g := range groupings {
+		if opts.GroupBy == g {
+			m.groupBy = g
+		}
+	}
+	for _, diff := range differences {
+		m.items = append(m.items, &item{diff: diff})
+	}
+	m.rebuild()
+	return m
+}
+
+// groupOf returns the group a difference belongs to in the current grouping.
+func (m *model) groupOf(diff compare.Difference) string {
+	switch m.groupBy {
+	case GroupType:
+		return diff.Type
+	case GroupSchema:
+		if diff.SchemaName == "" {
+			return "(no schema)"
+		}
+		return diff.SchemaName
+	}
+	return diff.Table
+}
+
+// rebuild lays out the rows of the list from the differences, in groups sorted by name and
+// differences sorted by table, type, and object.
+func (m *model) rebuild() {
+	var current row
+	if m.cursor < len(m.rows) {
+		current = m.rows[m.cursor]
+	}
+
+	groups := map[string][]*item{}
+	var names []string
+	for _, it := range m.items {
+		name := m.groupOf(it.diff)
+		if groups[name] == nil {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], it)
+	}
+	sort.Strings(names)
+
+	m.rows = m.rows[:0]
+	for _, name := range names {
+		items := groups[name]
+		sort.SliceStable(items, func(i, j int) bool {
+			a, b := items[i].diff, items[j].diff
+			if a.Table != b.Table {
+				return a.Table < b.Table
+			}
+			if a.Type != b.Type {
+				return a.Type < b.Type
+			}
+			return a.SubObject < b.SubObject
+		})
+		m.rows = append(m.rows, row{group: name, items: items})
+		if !m.collapsed[name] {
+			for _, it := range items {
+				m.rows = append(m.rows, row{group: name, item: it})
+			}
+		}
+	}
+
+	// Keep the cursor on its difference, or else on the heading of its group, as when the group
+	// was collapsed
+	m.cursor = min(m.cursor, max(len(m.rows)-1, 0))
+	heading := -1
+	for i, r := range m.rows {
+		if current.item != nil && r.item == current.item {
+			m.cursor = i
+			return
+		}
+		if r.item == nil && r.group == current.group && heading < 0 {
+			heading = i
+		}
+	}
+	if heading >= 0 {
+		m.cursor = heading
+	}
+}
+
+// chosen returns the differences an action applies to: the selected ones, or else the one
+// under the cursor, or every difference of the group under the cursor.
+func (m *model) chosen() []*item {
+	var chosen []*item
+	for _, it := range m.items {
+		if it.selected {
+			chosen = append(chosen, it)
+		}
+	}
+	if len(chosen) > 0 || len(m.rows) == 0 {
+		return chosen
+	}
+	if r := m.rows[m.cursor]; r.item != nil {
+		return []*item{r.item}
+	}
+	return m.rows[m.cursor].items
+}
+
+// differencesOf returns the differences of items.
+func differencesOf(items []*item) compare.DiffResult {
+	differences := make(compare.DiffResult, 0, len(items))
+	for _, it := range items {
+		differences = append(differences, it.diff)
+	}
+	return differences
+}
+
+// Init starts the browser; it has nothing to do before the first key.
+func (m *model) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles a key, a resize of the terminal, or the outcome of an action.
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		if msg.Width > 0 && msg.Height > 0 {
+			m.width, m.height = msg.Width, msg.Height
+		}
+	case acceptedMsg:
+		m.setStatus(msg.message, msg.err)
+		if msg.err == nil {
+			accepted := make(map[*item]bool, len(msg.items))
+			for _, it := range msg.items {
+				accepted[it] = true
+			}
+			kept := m.items[:0]
+			for _, it := range m.items {
+				if !accepted[it] {
+					kept = append(kept, it)
+				}
+			}
+			m.items = kept
+			m.rebuild()
+		}
+	case exportedMsg:
+		m.setStatus(msg.message, msg.err)
+	case tea.KeyMsg:
+		return m, m.handleKey(msg.String())
+	}
+	return m, nil
+}
+
+// setStatus shows the outcome of an action.
+func (m *model) setStatus(message string, err error) {
+	m.status, m.failed = message, err != nil
+	if err != nil {
+		m.status = err.Error()
+	}
+}
+
+// handleKey acts on a key.
+//
+// Parameters:
+//   - key: Name of the key, as tea.KeyMsg.String returns it
+//
+// Returns:
+//   - tea.Cmd: Action to run outside of Update, if any
+func (m *model) handleKey(key string) tea.Cmd {
+	if key == "ctrl+c" {
+		return tea.Quit
+	}
+	if m.confirm != nil {
+		items := m.confirm
+		m.confirm = nil
+		if key != "y" && key != "Y" {
+			m.setStatus("Nothing was accepted.", nil)
+			return nil
+		}
+		accept := m.opts.Accept
+		return func() tea.Msg {
+			message, err := accept(differencesOf(items))
+			return acceptedMsg{items: items, message: message, err: err}
+		}
+	}
+	if m.detail != nil {
+		return m.handleDetailKey(key)
+	}
+
+	page := max(m.listHeight()-1, 1)
+	switch key {
+	case "q", "esc":
+		return tea.Quit
+	case "?":
+		m.help = !m.help
+	case "up", "k":
+		m.move(-1)
+	case "down", "j":
+		m.move(1)
+	case "pgup", "ctrl+b":
+		m.move(-page)
+	case "pgdown", "ctrl+f":
+		m.move(page)
+	case "home", "g":
+		m.move(-len(m.rows))
+	case "end", "G":
+		m.move(len(m.rows))
+	case "tab":
+		for i, g := range groupings {
+			if g == m.groupBy {
+				m.groupBy = groupings[(i+1)%len(groupings)]
+				break
+			}
+		}
+		m.collapsed = map[string]bool{}
+		m.cursor = 0
+		m.rebuild()
+	case "enter", "right", "l":
+		if len(m.rows) == 0 {
+			break
+		}
+		if r := m.rows[m.cursor]; r.item != nil {
+			m.detail, m.scroll = r.item, 0
+		} else {
+			m.collapsed[r.group] = !m.collapsed[r.group]
+			m.rebuild()
+		}
+	case "left", "h":
+		if len(m.rows) > 0 {
+			m.collapsed[m.rows[m.cursor].group] = true
+			m.rebuild()
+		}
+	case " ", "x":
+		if len(m.rows) == 0 {
+			break
+		}
+		if r := m.rows[m.cursor]; r.item != nil {
+			r.item.selected = !r.item.selected
+		} else {
+			all := true
+			for _, it := range r.items {
+				all = all && it.selected
+			}
+			for _, it := range r.items {
+				it.selected = !all
+			}
+		}
+		m.move(1)
+	case "a":
+		if m.opts.Accept == nil {
+			m.setStatus("Accepting is not available.", nil)
+			break
+		}
+		if chosen := m.chosen(); len(chosen) > 0 {
+			m.confirm = chosen
+			m.setStatus(fmt.Sprintf("Accept %s as suppression rules? (y/n)", count(len(chosen))), nil)
+		}
+	case "e":
+		if m.opts.Export == nil {
+			m.setStatus("Exporting is not available.", nil)
+			break
+		}
+		if chosen := m.chosen(); len(chosen) > 0 {
+			export := m.opts.Export
+			return func() tea.Msg {
+				message, err := export(differencesOf(chosen))
+				return exportedMsg{message: message, err: err}
+			}
+		}
+	}
+	return nil
+}
+
+// handleDetailKey acts on a key while the definitions of a difference are shown.
+func (m *model) handleDetailKey(key string) tea.Cmd {
+	switch key {
+	case "q":
+		return tea.Quit
+	case "esc", "enter", "left", "h", "backspace":
+		m.detail = nil
+	case "up", "k":
+		m.scroll = max(m.scroll-1, 0)
+	case "down", "j":
+		m.scroll++
+	case "pgup", "ctrl+b":
+		m.scroll = max(m.scroll-max(m.height-8, 1), 0)
+	case "pgdown", "ctrl+f":
+		m.scroll += max(m.height-8, 1)
+	case " ", "x":
+		m.detail.selected = !m.detail.selected
+	}
+	return nil
+}
+
+// move moves the cursor by a number of rows, scrolling the list to keep it visible.
+func (m *model) move(delta int) {
+	m.cursor = min(max(m.cursor+delta, 0), max(len(m.rows)-1, 0))
+	height := m.listHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+height {
+		m.offset = m.cursor - height + 1
+	}
+}
+
+// listHeight returns the number of rows of the list that fit on the screen, below the title
+// and above the status and the keys.
+func (m *model) listHeight() int {
+	reserved := 4
+	if m.help {
+		reserved += len(helpLines)
+	}
+	return max(m.height-reserved, 1)
+}
+
+// helpLines lists the keys of the browser.
+var helpLines = []string{
+	"↑/↓ j/k  move          PgUp/PgDn  page        Home/End  first/last",
+	"enter    open difference, or expand or collapse group      ←  collapse group",
+	"space    select difference or group         tab  group by table, type, or schema",
+	"a        accept selected (or current) as suppression rules",
+	"e        export migration of selected (or current)         q  quit",
+}
+
+// View draws the screen.
+func (m *model) View() string {
+	if m.detail != nil {
+		return m.detailView()
+	}
+
+	var b strings.Builder
+	selected := 0
+	for _, it := range m.items {
+		if it.selected {
+			selected++
+		}
+	}
+	b.WriteString(titleStyle.Render(fit(fmt.Sprintf("schema-check: %s, %d selected, grouped by %s", count(len(m.items)), selected, m.groupBy), m.width)))
+	b.WriteString("\n")
+
+	height := m.listHeight()
+	m.offset = min(m.offset, max(len(m.rows)-height, 0))
+	if len(m.rows) == 0 {
+		b.WriteString(fit("No differences left.", m.width) + "\n")
+		height--
+	}
+	for i := m.offset; i < min(m.offset+height, len(m.rows)); i++ {
+		line := m.rowLine(m.rows[i])
+		if i == m.cursor {
+			line = cursorStyle.Render(fit(line, m.width))
+		} else if m.rows[i].item == nil {
+			line = headingStyle.Render(fit(line, m.width))
+		} else {
+			line = fit(line, m.width)
+		}
+		b.WriteString(line + "\n")
+	}
+	for i := min(m.offset+height, len(m.rows)) - m.offset; i < height; i++ {
+		b.WriteString("\n")
+	}
+
+	b.WriteString(m.statusLine() + "\n")
+	if m.help {
+		for _, line := range helpLines {
+			b.WriteString(hintStyle.Render(fit(line, m.width)) + "\n")
+		}
+	}
+	b.WriteString(hintStyle.Render(fit("enter open  space select  a accept  e export  tab group  ? help  q quit", m.width)))
+	return b.String()
+}
+
+// rowLine returns the text of a row of the list, before it is styled.
+func (m *model) rowLine(r row) string {
+	if r.item == nil {
+		marker := "▾"
+		if m.collapsed[r.group] {
+			marker = "▸"
+		}
+		return fmt.Sprintf("%s %s (%d)", marker, r.group, len(r.items))
+	}
+	check := "[ ]"
+	if r.item.selected {
+		check = "[x]"
+	}
+	// The group already tells the table or the type
+	diff := r.item.diff
+	subject := diff.Type + " " + diff.Table
+	switch m.groupBy {
+	case GroupTable:
+		subject = diff.Type
+	case GroupType:
+		subject = diff.Table
+	}
+	if diff.SubObject != "" {
+		subject += " " + diff.SubObject
+	}
+	return fmt.Sprintf("  %s %-7s %-32s %s", check, diff.Severity, subject, strings.ReplaceAll(diff.Description, "\n", " "))
+}
+
+// statusLine returns the outcome of the last action, styled as an error if it failed.
+func (m *model) statusLine() string {
+	if m.failed {
+		return errorStyle.Render(fit(m.status, m.width))
+	}
+	return fit(m.status, m.width)
+}
+
+// detailView draws a difference with the definitions of its table on both sides, next to each
+// other. Lines found on one side only are highlighted: in the colour of removals on the source
+// side and of additions on the target side.
+func (m *model) detailView() string {
+	diff := m.detail.diff
+	var b strings.Builder
+	check := ""
+	if m.detail.selected {
+		check = " [selected]"
+	}
+	severity := severityStyle[diff.Severity].Render(diff.Severity)
+	b.WriteString(titleStyle.Render(fit(fmt.Sprintf("%s on %s%s", diff.Type, diff.Table, check), m.width-len(diff.Severity)-1)) + " " + severity + "\n")
+	header := []string{strings.ReplaceAll(diff.Description, "\n", " ")}
+	if diff.SourceValue != "" || diff.TargetValue != "" {
+		header = append(header, fmt.Sprintf("Source: %s    Target: %s", orNone(diff.SourceValue), orNone(diff.TargetValue)))
+	}
+	for _, line := range header {
+		b.WriteString(fit(line, m.width) + "\n")
+	}
+	b.WriteString("\n")
+
+	var source, target []string
+	if m.opts.Source != nil {
+		source = definition(m.opts.Source, diff.Table)
+	}
+	if m.opts.Target != nil {
+		target = definition(m.opts.Target, diff.Table)
+	}
+	inSource, inTarget := lineSet(source), lineSet(target)
+	half := max((m.width-3)/2, 1)
+	b.WriteString(titleStyle.Render(fit("Source", half)) + " │ " + titleStyle.Render(fit("Target", half)) + "\n")
+
+	height := max(m.height-len(header)-6, 1)
+	lines := max(len(source), len(target))
+	m.scroll = min(m.scroll, max(lines-height, 0))
+	for i := m.scroll; i < m.scroll+height; i++ {
+		left, right := fit("", half), fit("", half)
+		if i < len(source) {
+			left = fit(source[i], half)
+			if !inTarget[normalize(source[i])] {
+				left = sourceOnly.Render(left)
+			}
+		}
+		if i < len(target) {
+			right = fit(target[i], half)
+			if !inSource[normalize(target[i])] {
+				right = targetOnly.Render(right)
+			}
+		}
+		b.WriteString(left + " │ " + right + "\n")
+	}
+	b.WriteString(m.statusLine() + "\n")
+	b.WriteString(hintStyle.Render(fit("↑/↓ scroll  space select  esc back  q quit", m.width)))
+	return b.String()
+}
+
+// lineSet returns the set of lines of a definition, normalized.
+func lineSet(lines []string) map[string]bool {
+	set := make(map[string]bool, len(lines))
+	for _, line := range lines {
+		set[normalize(line)] = true
+	}
+	return set
+}
+
+// normalize returns a line of a definition without its indentation and trailing comma, so that
+// a column is the same line on both sides whether or not it is the last one of its table.
+func normalize(line string) string {
+	return strings.TrimSuffix(strings.TrimSpace(line), ",")
+}
+
+// orNone returns a value, or "(none)" when it is empty.
+func orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
+
+// count returns "1 difference" or "N differences".
+func count(n int) string {
+	if n == 1 {
+		return "1 difference"
+	}
+	return fmt.Sprintf("%d differences", n)
+}
+
+// fit truncates or pads a line to a width, so that lines never wrap and columns line up.
+func fit(line string, width int) string {
+	runes := []rune(line)
+	if len(runes) > width {
+		if width <= 0 {
+			return ""
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return line + strings.Repeat(" ", width-len(runes))
+}
@@ -0,0 +1,88 @@
+// Package schemacheck is the entry point for using pg_schema_check as a library. It bundles the
+// three steps performed by the schema-check CLI — fetching schemas, comparing them, and reporting
+// the differences — so that the common case needs a single import:
+//
+//	source, err := schemacheck.Fetch(ctx, sourceConn, schemacheck.FetchOptions{})
+//	// handle err
+//	target, err := schemacheck.Fetch(ctx, targetConn, schemacheck.FetchOptions{})
+//	// handle err
+//	differences, err := schemacheck.Compare(ctx, source, target, schemacheck.Options{})
+//	// handle err
+//	err = schemacheck.Report(os.Stdout, differences, "text")
+//
+// The packages under pkg/ remain available for finer control (filters, severities, suppression
+// rules, snapshots, and sync SQL).
+//
+// This package and the packages schema, compare, report, and patch under pkg/ are the supported
+// API, which follows semantic versioning: within a major version, it is only ever extended, never
+// changed incompatibly. Their package documentation lists what it covers in each of them. Other
+// packages under pkg/ serve the CLI and may change in any release.
+package schemacheck
+
+import (
+	"context"
+	"io"
+
+	"github.com/guriandoro/pg_schema_check/pkg/compare"
+	"github.com/guriandoro/pg_schema_check/pkg/report"
+	"github.com/guriandoro/pg_schema_check/pkg/schema"
+)
+
+// Aliases of the types used by the functions of this package, so that callers do not need to
+// import the underlying packages for the common case.
+type (
+	Schema       = schema.Schema       // Schema fetched from a database
+	Querier      = schema.Querier      // Connection, pool, or transaction the schema is fetched through
+	FetchOptions = schema.FetchOptions // Options controlling what is fetched
+	Options      = compare.Options     // Options controlling the comparison
+	Difference   = compare.Difference  // Single difference between two schemas
+	DiffResult   = compare.DiffResult  // Differences found by a comparison
+)
+
+// Fetch reads the schema of a database. See schema.Fetch.
+//
+// Parameters:
+//   - ctx: Context for the database operation
+//   - conn: Active PostgreSQL connection, pool, or transaction
+//   - opts: Options controlling what is fetched
+//
+// Returns:
+//   - *Schema: Complete schema information
+//   - error: Any error that occurred during the fetch operation
+func Fetch(ctx context.Context, conn Querier, opts FetchOptions) (*Schema, error) {
+	return schema.Fetch(ctx, conn, opts)
+}
+
+// Compare compares two schemas and returns their differences, each with its default severity.
+// See compare.CompareSchemasContext.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the comparison
+//   - source: The source schema to compare from
+//   - target: The target schema to compare against
+//   - opts: Options controlling the comparison
+//
+// Returns:
+//   - DiffResult: Differences found between the schemas
+//   - error: The context's error if the comparison was cancelled
+func Compare(ctx context.Context, source, target *Schema, opts Options) (DiffResult, error) {
+	return compare.CompareSchemasContext(ctx, source, target, opts)
+}
+
+// Report writes the differences to w in one of the registered output formats ("text", "json",
+// "html", or any format added with report.Register).
+//
+// Parameters:
+//   - w: Writer the report is written to
+//   - differences: Differences to report
+//   - format: Name of the output format
+//
+// Returns:
+//   - error: An error if the format is unknown, or any error that occurred while writing
+func Report(w io.Writer, differences DiffResult, format string) error {
+	renderer, err := report.Lookup(format)
+	if err != nil {
+		return err
+	}
+	return differences.Render(w, renderer)
+}